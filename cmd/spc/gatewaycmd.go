@@ -167,6 +167,7 @@ func gatewaycmd() {
 	}
 	fmt.Println("Address:", info.NetAddress)
 	fmt.Println("Active peers:", len(info.Peers))
+	fmt.Println("Banned peers:", len(info.Bans))
 	fmt.Println("Max download speed:", info.MaxDownloadSpeed)
 	fmt.Println("Max upload speed:", info.MaxUploadSpeed)
 }
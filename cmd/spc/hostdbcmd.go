@@ -63,8 +63,10 @@ func printScoreBreakdown(info *api.HostdbHostsGET) {
 	fmt.Fprintf(w, "\t\tCollateral:\t %.3f\n", info.ScoreBreakdown.CollateralAdjustment/1e108)
 	fmt.Fprintf(w, "\t\tDuration:\t %.3f\n", info.ScoreBreakdown.DurationAdjustment)
 	fmt.Fprintf(w, "\t\tInteraction:\t %.3f\n", info.ScoreBreakdown.InteractionAdjustment)
+	fmt.Fprintf(w, "\t\tLatency:\t %.3f\n", info.ScoreBreakdown.LatencyAdjustment)
 	fmt.Fprintf(w, "\t\tPrice:\t %.3f\n", info.ScoreBreakdown.PriceAdjustment*1e27)
 	fmt.Fprintf(w, "\t\tStorage:\t %.3f\n", info.ScoreBreakdown.StorageRemainingAdjustment)
+	fmt.Fprintf(w, "\t\tThroughput:\t %.3f\n", info.ScoreBreakdown.ThroughputAdjustment)
 	fmt.Fprintf(w, "\t\tUptime:\t %.3f\n", info.ScoreBreakdown.UptimeAdjustment)
 	fmt.Fprintf(w, "\t\tVersion:\t %.3f\n", info.ScoreBreakdown.VersionAdjustment)
 	fmt.Fprintf(w, "\t\tConversion Rate:\t %.3f\n", info.ScoreBreakdown.ConversionRate)
@@ -433,6 +435,9 @@ func hostdbviewcmd(pubkey string) {
 	fmt.Println("  Recent Failed Interactions:       ", info.Entry.RecentFailedInteractions)
 	fmt.Println("  Recent Successful Interactions:   ", info.Entry.RecentSuccessfulInteractions)
 	fmt.Printf("  Overall Uptime:                    %.3f\n", uptimeRatio)
+	fmt.Println("  Latency:                          ", info.Entry.Latency)
+	fmt.Println("  Download Throughput:              ", bandwidthUnit(info.Entry.DownloadThroughput*8))
+	fmt.Println("  Upload Throughput:                ", bandwidthUnit(info.Entry.UploadThroughput*8))
 
 	fmt.Println()
 }
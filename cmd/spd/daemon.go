@@ -20,6 +20,7 @@ import (
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/node/api/server"
 	"gitlab.com/scpcorp/ScPrime/profile"
+	"gitlab.com/scpcorp/ScPrime/types"
 )
 
 // passwordPrompt securely reads a password from stdin.
@@ -223,6 +224,22 @@ func startDaemon(config Config) (err error) {
 	// startDaemonCmd(cmd *cobra.Command, _ []string).
 	nodeParams.PoolConfig = config.MiningPoolConfig
 
+	// Add the consensus snapshot which is imported on the first start.
+	nodeParams.ConsensusSnapshot = config.Spd.ConsensusSnapshot
+	if config.Spd.ConsensusSnapshotCommitment != "" {
+		err = nodeParams.ConsensusSnapshotCommitment.LoadString(config.Spd.ConsensusSnapshotCommitment)
+		if err != nil {
+			return errors.AddContext(err, "failed to parse consensus snapshot commitment")
+		}
+	}
+
+	// Enable the pruned mode of the consensus set.
+	nodeParams.ConsensusPruneDepth = types.BlockHeight(config.Spd.ConsensusPruneDepth)
+
+	// Route outbound connections through a SOCKS5 proxy.
+	nodeParams.SOCKS5Proxy = config.Spd.SOCKS5Proxy
+	nodeParams.AllowOnion = config.Spd.AllowOnion
+
 	// Start and run the server.
 	srv, err := server.New(config.Spd.APIaddr, config.Spd.RequiredUserAgent, config.APIPassword, nodeParams, loadStart)
 	if err != nil {
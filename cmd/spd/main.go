@@ -52,6 +52,13 @@ type Config struct {
 		DataDir    string
 
 		OnlyFirstDir bool
+
+		ConsensusSnapshot           string
+		ConsensusSnapshotCommitment string
+		ConsensusPruneDepth         uint64
+
+		SOCKS5Proxy string
+		AllowOnion  bool
 	}
 
 	MiningPoolConfig config.MiningPoolConfig
@@ -195,6 +202,11 @@ func main() {
 	root.Flags().BoolVarP(&globalConfig.Spd.TempPassword, "temp-password", "", false, "enter a temporary API password during startup")
 	root.Flags().BoolVarP(&globalConfig.Spd.AllowAPIBind, "disable-api-security", "", false, "allow spd to listen on a non-localhost address (DANGEROUS)")
 	root.Flags().BoolVarP(&globalConfig.Spd.OnlyFirstDir, "only-first-dir", "", false, "ignore all storage directories except first")
+	root.Flags().StringVarP(&globalConfig.Spd.ConsensusSnapshot, "consensus-snapshot", "", "", "import the consensus snapshot at this path if there is no consensus database yet")
+	root.Flags().StringVarP(&globalConfig.Spd.ConsensusSnapshotCommitment, "consensus-snapshot-commitment", "", "", "commitment the consensus snapshot needs to match")
+	root.Flags().Uint64VarP(&globalConfig.Spd.ConsensusPruneDepth, "consensus-prune-depth", "", 0, "only keep this many recent blocks in the consensus database, 0 keeps all blocks")
+	root.Flags().StringVarP(&globalConfig.Spd.SOCKS5Proxy, "socks5-proxy", "", "", "route outbound peer, host and Host API connections through this SOCKS5 proxy, e.g. Tor at 127.0.0.1:9050")
+	root.Flags().BoolVarP(&globalConfig.Spd.AllowOnion, "allow-onion", "", false, "accept .onion addresses for peers and hosts, requires --socks5-proxy")
 
 	// If globalConfig.Spd.DataDir is not set, use the environment variable provided.
 	if globalConfig.Spd.DataDir == "" {
@@ -42,6 +42,9 @@ const (
 	// AlertIDRenterContractRenewalError is the id of the alert that is
 	// registered if at least once contract renewal or refresh failed
 	AlertIDRenterContractRenewalError = "contract-renewal-error"
+	// AlertIDRenterSpendingForecast is the id of the alert that is registered
+	// if the projected spending of the current period exceeds the allowance.
+	AlertIDRenterSpendingForecast = "spending-forecast"
 	// AlertIDGatewayOffline is the id of the alert that is registered upon a
 	// call to 'gateway.Offline' if the value returned is 'false' and
 	// unregistered when it returns 'true'.
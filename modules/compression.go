@@ -0,0 +1,119 @@
+package modules
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// CompressionNone means that the data of a file is stored as-is. It is the
+	// zero value so that siafiles created before compression was supported
+	// are treated as uncompressed.
+	CompressionNone = CompressionType{0, 0, 0, 0, 0, 0, 0, 0}
+	// CompressionDeflate compresses chunks using DEFLATE as specified in RFC
+	// 1951.
+	CompressionDeflate = CompressionType{0, 0, 0, 0, 0, 0, 0, 1}
+)
+
+var (
+	// ErrInvalidCompressionType is returned upon encountering an unknown
+	// compression type.
+	ErrInvalidCompressionType = errors.New("provided compression type is invalid")
+)
+
+type (
+	// CompressionType is an identifier for the codecs that can be used to
+	// compress the chunks of a file before they are erasure coded and
+	// encrypted.
+	CompressionType [8]byte
+)
+
+// String creates a string representation of a CompressionType that can be
+// converted into a type with FromString.
+func (ct CompressionType) String() string {
+	switch ct {
+	case CompressionNone:
+		return "none"
+	case CompressionDeflate:
+		return "deflate"
+	default:
+		return ""
+	}
+}
+
+// FromString reads a CompressionType from a string. The empty string is
+// interpreted as CompressionNone.
+func (ct *CompressionType) FromString(s string) error {
+	switch s {
+	case "", "none":
+		*ct = CompressionNone
+	case "deflate":
+		*ct = CompressionDeflate
+	default:
+		return ErrInvalidCompressionType
+	}
+	return nil
+}
+
+// IsValidCompressionType returns true if ct is a known CompressionType and
+// false otherwise.
+func IsValidCompressionType(ct CompressionType) bool {
+	switch ct {
+	case CompressionNone, CompressionDeflate:
+		return true
+	default:
+		return false
+	}
+}
+
+// CompressChunk compresses the logical data of a single chunk using the
+// provided compression type. The output is deterministic for a given input
+// which allows for repairing a chunk from its logical data.
+func CompressChunk(ct CompressionType, data []byte) ([]byte, error) {
+	switch ct {
+	case CompressionNone:
+		return data, nil
+	case CompressionDeflate:
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrInvalidCompressionType
+	}
+}
+
+// DecompressChunk decompresses a chunk that was compressed with CompressChunk.
+// maxSize is the maximum size of the logical data of the chunk and is used to
+// protect against corrupted or malicious input.
+func DecompressChunk(ct CompressionType, data []byte, maxSize uint64) ([]byte, error) {
+	switch ct {
+	case CompressionNone:
+		return data, nil
+	case CompressionDeflate:
+		r := flate.NewReader(bytes.NewReader(data))
+		defer r.Close()
+		var buf bytes.Buffer
+		n, err := io.Copy(&buf, io.LimitReader(r, int64(maxSize)+1))
+		if err != nil {
+			return nil, err
+		}
+		if uint64(n) > maxSize {
+			return nil, fmt.Errorf("decompressed chunk exceeds max size of %v bytes", maxSize)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrInvalidCompressionType
+	}
+}
@@ -0,0 +1,70 @@
+package modules
+
+import (
+	"bytes"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+)
+
+// TestCompressionTypeString tests the conversion of CompressionTypes to and
+// from strings.
+func TestCompressionTypeString(t *testing.T) {
+	for _, ct := range []CompressionType{CompressionNone, CompressionDeflate} {
+		var ct2 CompressionType
+		if err := ct2.FromString(ct.String()); err != nil {
+			t.Fatal(err)
+		}
+		if ct != ct2 {
+			t.Fatalf("expected %v but got %v", ct, ct2)
+		}
+	}
+	// The empty string is the same as none.
+	var ct CompressionType
+	if err := ct.FromString(""); err != nil || ct != CompressionNone {
+		t.Fatal("empty string should be parsed as CompressionNone", err)
+	}
+	// Unknown types should fail.
+	if err := ct.FromString("zip"); err != ErrInvalidCompressionType {
+		t.Fatal("expected ErrInvalidCompressionType but got", err)
+	}
+	if IsValidCompressionType(CompressionType{1}) {
+		t.Fatal("unknown compression type shouldn't be valid")
+	}
+}
+
+// TestCompressChunk tests compressing and decompressing chunks.
+func TestCompressChunk(t *testing.T) {
+	// Compressible data should shrink.
+	data := bytes.Repeat([]byte("log line\n"), 1000)
+	for _, ct := range []CompressionType{CompressionNone, CompressionDeflate} {
+		compressed, err := CompressChunk(ct, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct != CompressionNone && len(compressed) >= len(data) {
+			t.Fatalf("%v: data wasn't compressed %v >= %v", ct, len(compressed), len(data))
+		}
+		decompressed, err := DecompressChunk(ct, compressed, uint64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, decompressed) {
+			t.Fatalf("%v: decompressed data doesn't match", ct)
+		}
+	}
+	// Compression should be deterministic.
+	data = fastrand.Bytes(1000)
+	c1, err1 := CompressChunk(CompressionDeflate, data)
+	c2, err2 := CompressChunk(CompressionDeflate, data)
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+	if !bytes.Equal(c1, c2) {
+		t.Fatal("compression isn't deterministic")
+	}
+	// Decompressing data exceeding the max size should fail.
+	if _, err := DecompressChunk(CompressionDeflate, c1, uint64(len(data)-1)); err == nil {
+		t.Fatal("expected decompression to fail")
+	}
+}
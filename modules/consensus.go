@@ -206,6 +206,23 @@ type (
 		Adjusted  types.Currency
 	}
 
+	// A ConsensusSnapshot describes an export of the consensus set at a
+	// height. The commitment is the hash of the exported state and is used to
+	// verify a snapshot before importing it.
+	ConsensusSnapshot struct {
+		Height     types.BlockHeight `json:"height"`
+		BlockID    types.BlockID     `json:"blockid"`
+		Commitment crypto.Hash       `json:"commitment"`
+	}
+
+	// An UnconfirmedTransactionSource provides the unconfirmed transactions
+	// that blocks relayed by peers in compact form are rebuilt from. It is
+	// implemented by the transaction pool.
+	UnconfirmedTransactionSource interface {
+		// TransactionList returns all unconfirmed transactions.
+		TransactionList() []types.Transaction
+	}
+
 	// A ConsensusSet accepts blocks and builds an understanding of network
 	// consensus.
 	ConsensusSet interface {
@@ -247,6 +264,11 @@ type (
 		// blockchain.
 		CurrentBlock() types.Block
 
+		// ExportSnapshot writes a snapshot of the consensus set at the current
+		// height to dst. The snapshot can be imported by a new node to skip
+		// the initial blockchain download.
+		ExportSnapshot(dst string) (ConsensusSnapshot, error)
+
 		// Height returns the current height of consensus.
 		Height() types.BlockHeight
 
@@ -263,6 +285,12 @@ type (
 		// risk of mining invalid blocks.
 		MinimumValidChildTimestamp(types.BlockID) (types.Timestamp, bool)
 
+		// SetUnconfirmedTransactionSource sets the source of the unconfirmed
+		// transactions that blocks relayed by peers in compact form are
+		// rebuilt from. Without a source all transactions of a compact block
+		// are requested from the peer.
+		SetUnconfirmedTransactionSource(UnconfirmedTransactionSource)
+
 		// StorageProofSegment returns the segment to be used in the storage proof for
 		// a given file contract.
 		StorageProofSegment(types.FileContractID) (uint64, error)
@@ -297,6 +325,16 @@ func (cc *ConsensusChange) AppendDiffs(diffs ConsensusChangeDiffs) {
 	cc.SiafundPoolDiffs = append(cc.SiafundPoolDiffs, diffs.SiafundPoolDiffs...)
 }
 
+// SkipsBlocks returns true if cc doesn't continue from the provided height.
+// This happens once for subscribers of a consensus set that was bootstrapped
+// from a snapshot or pruned, which are brought from the genesis block straight
+// to the blocks retained by the snapshot or to the current block. Subscribers
+// that track the block height should continue counting from cc.OldHeight in
+// that case.
+func (cc ConsensusChange) SkipsBlocks(height types.BlockHeight) bool {
+	return len(cc.RevertedBlocks) == 0 && len(cc.AppliedBlocks) > 0 && cc.OldHeight != height && cc.AppliedBlocks[0].ID() != types.GenesisID
+}
+
 // MarshalSia implements encoding.SiaMarshaler.
 func (cc ConsensusChange) MarshalSia(w io.Writer) error {
 	return encoding.NewEncoder(w).EncodeAll(
@@ -11,20 +11,63 @@ import (
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/types"
 
+	"gitlab.com/NebulousLabs/encoding"
 	bolt "go.etcd.io/bbolt"
 )
 
 var (
-	errDoSBlock       = errors.New("block is known to be invalid")
-	errNoBlockMap     = errors.New("block map is not in database")
-	errNonLinearChain = errors.New("block set is not a contiguous chain")
-	errOrphan         = errors.New("block has no known parent")
+	errBadNonce             = errors.New("block does not meet nonce requirements")
+	errCheckpointMismatch   = errors.New("block does not match the checkpoint at its height")
+	errDoSBlock             = errors.New("block is known to be invalid")
+	errForkBeforeCheckpoint = errors.New("block forks the blockchain before a checkpoint")
+	errNoBlockMap           = errors.New("block map is not in database")
+	errNonLinearChain       = errors.New("block set is not a contiguous chain")
+	errOrphan               = errors.New("block has no known parent")
 )
 
 // managedBroadcastBlock will broadcast a block to the consensus set's peers.
 func (cs *ConsensusSet) managedBroadcastBlock(b types.Block) {
-	// broadcast the block header to all peers
-	go cs.gateway.Broadcast("RelayHeader", b.Header(), cs.gateway.Peers())
+	// relay the block in compact form to all peers
+	go cs.threadedRelayCompactBlock(b, cs.gateway.Peers())
+}
+
+// validateCheckpoint checks that a new block at the given height doesn't
+// conflict with the checkpoints. currentHeight is the height of the current
+// block of the consensus set, whose path contains all checkpoints up to that
+// height. A new block at or below such a checkpoint would fork the blockchain
+// before the checkpoint.
+func validateCheckpoint(checkpoints types.BlockCheckpoints, id types.BlockID, height, currentHeight types.BlockHeight) error {
+	if cpID, exists := checkpoints[height]; exists && cpID != id {
+		return errCheckpointMismatch
+	}
+	if cpHeight, exists := checkpoints.Last(currentHeight); exists && height <= cpHeight {
+		return errForkBeforeCheckpoint
+	}
+	return nil
+}
+
+// dbTxBlockHeight returns the height of the current block using a dbTx.
+func dbTxBlockHeight(tx dbTx) (height types.BlockHeight, err error) {
+	bh := tx.Bucket(BlockHeight)
+	if bh == nil {
+		return 0, errors.New("block height is not in database")
+	}
+	err = encoding.Unmarshal(bh.Get(BlockHeight), &height)
+	return height, err
+}
+
+// validateHeaderCheckpoint checks a new block against the checkpoints of the
+// consensus set.
+func (cs *ConsensusSet) validateHeaderCheckpoint(tx dbTx, id types.BlockID, height types.BlockHeight) error {
+	// Skip reading the current height if there are no checkpoints.
+	if len(cs.checkpoints) == 0 {
+		return nil
+	}
+	currentHeight, err := dbTxBlockHeight(tx)
+	if err != nil {
+		return err
+	}
+	return validateCheckpoint(cs.checkpoints, id, height, currentHeight)
 }
 
 // validateHeaderAndBlock does some early, low computation verification on the
@@ -55,6 +98,16 @@ func (cs *ConsensusSet) validateHeaderAndBlock(tx dbTx, b types.Block, id types.
 	} else if err != nil {
 		return nil, err
 	}
+	// Check that the block doesn't conflict with the checkpoints.
+	err = cs.validateHeaderCheckpoint(tx, id, parent.Height+1)
+	if err != nil {
+		return nil, err
+	}
+	// Check that the ancestors of the block weren't pruned.
+	err = cs.validateRetainedParent(parent)
+	if err != nil {
+		return nil, err
+	}
 	// Check that the timestamp is not too far in the past to be acceptable.
 	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMap, parent)
 
@@ -112,9 +165,20 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 		return err
 	}
 
+	// Check that the block doesn't conflict with the checkpoints.
+	err = cs.validateHeaderCheckpoint(tx, id, parent.Height+1)
+	if err != nil {
+		return err
+	}
+	// Check that the ancestors of the block weren't pruned.
+	err = cs.validateRetainedParent(parent)
+	if err != nil {
+		return err
+	}
+
 	// Check that the nonce is a legal nonce.
 	if parent.Height+1 >= types.ASICHardforkHeight && binary.LittleEndian.Uint64(h.Nonce[:])%types.ASICHardforkFactor != 0 {
-		return errors.New("block does not meet nonce requirements")
+		return errBadNonce
 	}
 	// Check that the target of the new block is sufficient.
 	if !checkHeaderTarget(h, parent.ChildTarget) {
@@ -166,6 +230,10 @@ func (cs *ConsensusSet) addBlockToTree(tx *bolt.Tx, b types.Block, parent *proce
 	if !newNode.heavierThan(currentNode) {
 		return changeEntry{}, modules.ErrNonExtendingBlock
 	}
+	// Check that the blocks which need to be reverted weren't pruned.
+	if err := cs.validateForkDepth(tx, newNode); err != nil {
+		return changeEntry{}, err
+	}
 
 	// Fork the blockchain and put the new heaviest block at the tip of the
 	// chain.
@@ -324,6 +392,10 @@ func (cs *ConsensusSet) managedAcceptBlocks(blocks []types.Block) (blockchainExt
 	for i := 0; i < len(changes); i++ {
 		cs.updateSubscribers(changes[i])
 	}
+	// Prune the blocks which are now older than the prune depth.
+	if _, err := cs.prune(); err != nil {
+		cs.log.Println("WARN: failed to prune blocks:", err)
+	}
 	return chainExtended, nil
 }
 
@@ -999,8 +999,25 @@ func (g *mockGatewayDoesBroadcast) Broadcast(name string, obj interface{}, peers
 	g.broadcastCalled <- struct{}{}
 }
 
+// Peers is a mock implementation of modules.Gateway.Peers that returns a
+// single peer for blocks to be relayed to.
+func (g *mockGatewayDoesBroadcast) Peers() []modules.Peer {
+	return []modules.Peer{{NetAddress: "127.0.0.1:4281"}}
+}
+
+// RPC is a mock implementation of modules.Gateway.RPC that signals that a
+// block was relayed with the RelayCompactBlock RPC like Broadcast does.
+func (g *mockGatewayDoesBroadcast) RPC(addr modules.NetAddress, name string, fn modules.RPCFunc) error {
+	if name == "RelayCompactBlock" {
+		g.broadcastCalled <- struct{}{}
+		return nil
+	}
+	return g.Gateway.RPC(addr, name, fn)
+}
+
 // TestAcceptBlockBroadcasts tests that AcceptBlock broadcasts valid blocks and
-// that managedAcceptBlock does not.
+// that managedAcceptBlock does not. Blocks are broadcast by relaying them with
+// the RelayCompactBlock RPC.
 func TestAcceptBlockBroadcasts(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
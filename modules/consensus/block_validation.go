@@ -130,7 +130,7 @@ func (bv stdBlockValidator) ValidateBlock(b types.Block, id types.BlockID, minTi
 
 	// Check that the nonce is a legal nonce.
 	if height >= types.ASICHardforkHeight && binary.LittleEndian.Uint64(b.Nonce[:])%types.ASICHardforkFactor != 0 {
-		return errors.New("block does not meet nonce requirements")
+		return errBadNonce
 	}
 	// Check that the target of the new block is sufficient.
 	if !checkTarget(b, id, target) {
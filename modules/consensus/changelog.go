@@ -130,6 +130,12 @@ func (ce *changeEntry) NextEntry(tx *bolt.Tx) (nextEntry changeEntry, exists boo
 
 // createChangeLog assumes that no change log exists and creates a new one.
 func (cs *ConsensusSet) createChangeLog(tx *bolt.Tx) error {
+	return initChangeLog(tx, cs.genesisEntry())
+}
+
+// initChangeLog creates a new change log with the genesis entry as its first
+// entry.
+func initChangeLog(tx *bolt.Tx, ge changeEntry) error {
 	// Create the changelog bucket.
 	cl, err := tx.CreateBucket(ChangeLog)
 	if err != nil {
@@ -137,7 +143,6 @@ func (cs *ConsensusSet) createChangeLog(tx *bolt.Tx) error {
 	}
 
 	// Add the genesis block as the first entry of the change log.
-	ge := cs.genesisEntry()
 	geid := ge.ID()
 	cn := changeNode{
 		Entry: ge,
@@ -0,0 +1,344 @@
+package consensus
+
+// compactblock.go implements the compact block relay.
+//
+// New blocks are relayed to peers with the RelayCompactBlock RPC, which sends
+// the header and the miner payouts of the block together with a short id for
+// every transaction. Most of the transactions of a new block are usually
+// already in the transaction pool of the peer, so it can rebuild the block
+// from its pool and only requests the transactions it is missing. The short
+// ids are derived from the block id, so an attacker can't precompute
+// transactions whose short ids collide with the ones of a future block.
+//
+// If the rebuilt block doesn't match its header, which happens if two
+// transactions share a short id or if the pool contains a transaction with
+// different signatures, the full block is downloaded with the SendBlk RPC.
+// Peers which don't support the RelayCompactBlock RPC or which reject the
+// header of the compact block are sent the header with the RelayHeader RPC
+// instead.
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"gitlab.com/NebulousLabs/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+const (
+	// compactBlockMaxRejectLen is the maximum length of the reason sent by
+	// a peer which rejects a compact block.
+	compactBlockMaxRejectLen = 256
+)
+
+var (
+	errBadMissingIndices    = errors.New("peer requested invalid transaction indices of a compact block")
+	errMissingTransactions  = errors.New("peer sent the wrong number of missing transactions of a compact block")
+	errCompactBlockMismatch = errors.New("rebuilt compact block doesn't match its header")
+	errCompactBlockRejected = errors.New("peer rejected compact block")
+
+	// relayCompactBlockTimeout is the timeout for the RelayCompactBlock RPC.
+	relayCompactBlockTimeout = build.Select(build.Var{
+		Standard: 90 * time.Second,
+		Dev:      30 * time.Second,
+		Testing:  4 * time.Second,
+	}).(time.Duration)
+)
+
+type (
+	// shortTransactionID identifies a transaction within a compact block.
+	shortTransactionID [8]byte
+
+	// compactBlock is a block whose transactions are replaced by their short
+	// ids.
+	compactBlock struct {
+		Header       types.BlockHeader
+		MinerPayouts []types.SiacoinOutput
+		ShortIDs     []shortTransactionID
+	}
+
+	// compactBlockResponse is the response of the receiver of a compact
+	// block. It either contains the reason why the header of the compact
+	// block was rejected or the indices of the missing transactions.
+	compactBlockResponse struct {
+		Reject  string
+		Missing []uint64
+	}
+)
+
+// newShortTransactionID returns the short id of the transaction with the
+// provided id within the block with the provided id.
+func newShortTransactionID(blockID types.BlockID, txid types.TransactionID) (sid shortTransactionID) {
+	h := crypto.HashAll(blockID, txid)
+	copy(sid[:], h[:])
+	return sid
+}
+
+// newCompactBlock returns the compact form of b.
+func newCompactBlock(b types.Block) compactBlock {
+	cb := compactBlock{
+		Header:       b.Header(),
+		MinerPayouts: b.MinerPayouts,
+		ShortIDs:     make([]shortTransactionID, len(b.Transactions)),
+	}
+	id := cb.Header.ID()
+	for i, txn := range b.Transactions {
+		cb.ShortIDs[i] = newShortTransactionID(id, txn.ID())
+	}
+	return cb
+}
+
+// rebuild returns the block of the compact block with the transactions filled
+// in from txns, together with the indices of the transactions that are
+// missing. Short ids which match more than one of txns are treated as missing.
+func (cb compactBlock) rebuild(txns []types.Transaction) (types.Block, []uint64) {
+	b := types.Block{
+		ParentID:     cb.Header.ParentID,
+		Nonce:        cb.Header.Nonce,
+		Timestamp:    cb.Header.Timestamp,
+		MinerPayouts: cb.MinerPayouts,
+		Transactions: make([]types.Transaction, len(cb.ShortIDs)),
+	}
+	id := cb.Header.ID()
+	wanted := make(map[shortTransactionID]struct{}, len(cb.ShortIDs))
+	for _, sid := range cb.ShortIDs {
+		wanted[sid] = struct{}{}
+	}
+	matches := make(map[shortTransactionID]int)
+	found := make(map[shortTransactionID]types.Transaction)
+	for _, txn := range txns {
+		sid := newShortTransactionID(id, txn.ID())
+		if _, exists := wanted[sid]; !exists {
+			continue
+		}
+		matches[sid]++
+		found[sid] = txn
+	}
+	missing := make([]uint64, 0)
+	for i, sid := range cb.ShortIDs {
+		if matches[sid] == 1 {
+			b.Transactions[i] = found[sid]
+		} else {
+			missing = append(missing, uint64(i))
+		}
+	}
+	return b, missing
+}
+
+// SetUnconfirmedTransactionSource sets the source of the unconfirmed
+// transactions that compact blocks relayed by peers are rebuilt from.
+func (cs *ConsensusSet) SetUnconfirmedTransactionSource(source modules.UnconfirmedTransactionSource) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.txnSource = source
+}
+
+// threadedRelayCompactBlock relays b to the peers with the RelayCompactBlock
+// RPC. The header is broadcast with the RelayHeader RPC to the peers which
+// couldn't be sent the compact block, e.g. because they don't support it.
+func (cs *ConsensusSet) threadedRelayCompactBlock(b types.Block, peers []modules.Peer) {
+	if err := cs.tg.Add(); err != nil {
+		return
+	}
+	defer cs.tg.Done()
+
+	cb := newCompactBlock(b)
+	var failed []modules.Peer
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, p := range peers {
+		wg.Add(1)
+		go func(p modules.Peer) {
+			defer wg.Done()
+			err := cs.gateway.RPC(p.NetAddress, "RelayCompactBlock", cs.managedSendCompactBlock(cb, b))
+			if err != nil {
+				cs.log.Debugf("WARN: relaying compact block to peer %v failed, relaying header instead: %v", p.NetAddress, err)
+				mu.Lock()
+				failed = append(failed, p)
+				mu.Unlock()
+			}
+		}(p)
+	}
+	wg.Wait()
+	if len(failed) > 0 {
+		go cs.gateway.Broadcast("RelayHeader", cb.Header, failed)
+	}
+}
+
+// managedSendCompactBlock returns an RPCFunc that sends the compact block cb
+// of b and then the transactions the peer is missing. The returned function
+// should be used as the calling end of the RelayCompactBlock RPC.
+func (cs *ConsensusSet) managedSendCompactBlock(cb compactBlock, b types.Block) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := conn.SetDeadline(time.Now().Add(relayCompactBlockTimeout))
+		if err != nil {
+			return err
+		}
+		finishedChan := make(chan struct{})
+		defer close(finishedChan)
+		go func() {
+			select {
+			case <-cs.tg.StopChan():
+			case <-finishedChan:
+			}
+			conn.Close()
+		}()
+
+		if err := encoding.WriteObject(conn, cb); err != nil {
+			return err
+		}
+		// Peers which don't know the RPC close the connection without
+		// sending a response.
+		var resp compactBlockResponse
+		maxLen := 8*uint64(len(b.Transactions)+2) + compactBlockMaxRejectLen
+		if err := encoding.ReadObject(conn, &resp, maxLen); err != nil {
+			return err
+		}
+		if resp.Reject != "" {
+			return errors.AddContext(errCompactBlockRejected, resp.Reject)
+		}
+		missing := resp.Missing
+		if len(missing) == 0 {
+			return nil
+		}
+		txns := make([]types.Transaction, 0, len(missing))
+		for i, index := range missing {
+			if index >= uint64(len(b.Transactions)) || (i > 0 && index <= missing[i-1]) {
+				cs.managedPenalizePeer(conn.RPCAddr(), nil, errBadMissingIndices)
+				return errBadMissingIndices
+			}
+			txns = append(txns, b.Transactions[index])
+		}
+		return encoding.WriteObject(conn, txns)
+	}
+}
+
+// threadedRPCRelayCompactBlock is an RPC that accepts a compact block from a
+// peer. The block is rebuilt from the unconfirmed transactions and the
+// transactions that are missing are requested from the peer.
+func (cs *ConsensusSet) threadedRPCRelayCompactBlock(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(relayCompactBlockTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	wg := new(sync.WaitGroup)
+	defer func() {
+		go func() {
+			wg.Wait()
+			cs.tg.Done()
+		}()
+	}()
+
+	// Decode the compact block from the connection.
+	var cb compactBlock
+	err = encoding.ReadObject(conn, &cb, types.BlockSizeLimit)
+	if err != nil {
+		return err
+	}
+	addr := conn.RPCAddr()
+	id := cb.Header.ID()
+
+	// Validate the header the same way the RelayHeader RPC does. The peer
+	// doesn't need to send any transactions if the block isn't wanted. Known
+	// blocks and orphans are acknowledged with an empty response, any other
+	// header is rejected so that the peer falls back to the RelayHeader RPC.
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		return cs.validateHeader(boltTxWrapper{tx}, cb.Header)
+	})
+	source := cs.txnSource
+	cs.mu.RUnlock()
+	if err != nil {
+		var resp compactBlockResponse
+		if err != modules.ErrBlockKnown && err != errOrphan {
+			resp.Reject = err.Error()
+			if len(resp.Reject) > compactBlockMaxRejectLen {
+				resp.Reject = resp.Reject[:compactBlockMaxRejectLen]
+			}
+		}
+		if writeErr := encoding.WriteObject(conn, resp); writeErr != nil {
+			return errors.Compose(err, writeErr)
+		}
+		if err == errOrphan {
+			// The gateway calls are made in a separate goroutine for the
+			// same reasons as in threadedRPCRelayHeader.
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := cs.gateway.RPC(addr, "SendBlocks", cs.managedReceiveBlocks)
+				if err != nil {
+					cs.log.Debugln("WARN: failed to get parents of orphan compact block:", err)
+				}
+			}()
+			return nil
+		}
+		cs.managedPenalizePeer(addr, nil, err)
+		return err
+	}
+
+	// Rebuild the block and request the missing transactions.
+	var txns []types.Transaction
+	if source != nil {
+		txns = source.TransactionList()
+	}
+	b, missing := cb.rebuild(txns)
+	if err := encoding.WriteObject(conn, compactBlockResponse{Missing: missing}); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		var received []types.Transaction
+		if err := encoding.ReadObject(conn, &received, types.BlockSizeLimit); err != nil {
+			return err
+		}
+		if len(received) != len(missing) {
+			cs.managedPenalizePeer(addr, nil, errMissingTransactions)
+			return errMissingTransactions
+		}
+		for i, index := range missing {
+			b.Transactions[index] = received[i]
+		}
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Fall back to downloading the full block if the rebuilt block
+		// doesn't match its header.
+		if b.ID() != id {
+			cs.log.Debugf("INFO: %v, requesting block %v from peer %v", errCompactBlockMismatch, id, addr)
+			err := cs.gateway.RPC(addr, "SendBlk", cs.managedReceiveBlock(id))
+			if err != nil {
+				cs.log.Debugln("WARN: failed to get compact block's corresponding block:", err)
+			}
+			return
+		}
+		chainExtended, err := cs.managedAcceptBlocks([]types.Block{b})
+		if chainExtended {
+			cs.managedBroadcastBlock(b)
+		}
+		if err != nil && err != modules.ErrBlockKnown {
+			cs.managedPenalizePeer(addr, []types.Block{b}, err)
+			cs.log.Debugln("WARN: failed to accept compact block:", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,184 @@
+package consensus
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestCompactBlockRebuild checks that compact blocks are rebuilt from the
+// provided transactions and that the indices of the missing transactions are
+// returned.
+func TestCompactBlockRebuild(t *testing.T) {
+	txns := make([]types.Transaction, 4)
+	for i := range txns {
+		txns[i] = types.Transaction{ArbitraryData: [][]byte{{byte(i)}}}
+	}
+	b := types.Block{
+		ParentID:     types.BlockID{1},
+		Timestamp:    types.CurrentTimestamp(),
+		MinerPayouts: []types.SiacoinOutput{{Value: types.NewCurrency64(1)}},
+		Transactions: txns[:3],
+	}
+	cb := newCompactBlock(b)
+	if cb.Header.ID() != b.ID() || len(cb.ShortIDs) != len(b.Transactions) {
+		t.Fatal("bad compact block:", cb)
+	}
+
+	// Without transactions all of them are missing.
+	_, missing := cb.rebuild(nil)
+	if len(missing) != 3 || missing[0] != 0 || missing[2] != 2 {
+		t.Fatal("expected all transactions to be missing:", missing)
+	}
+
+	// Unrelated transactions are ignored and only the missing ones are
+	// returned.
+	rebuilt, missing := cb.rebuild([]types.Transaction{txns[3], txns[2], txns[0]})
+	if len(missing) != 1 || missing[0] != 1 {
+		t.Fatal("expected the second transaction to be missing:", missing)
+	}
+	rebuilt.Transactions[1] = txns[1]
+	if rebuilt.ID() != b.ID() {
+		t.Fatal("rebuilt block doesn't match the original block")
+	}
+
+	// Short ids are different for every block.
+	b.Nonce = types.BlockNonce{1}
+	if newCompactBlock(b).ShortIDs[0] == cb.ShortIDs[0] {
+		t.Fatal("short ids don't depend on the block")
+	}
+}
+
+// TestIntegrationRelayCompactBlock checks that blocks are relayed in compact
+// form and rebuilt from the transaction pool of the peer, which requests the
+// transactions it is missing.
+func TestIntegrationRelayCompactBlock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	cst1, err := createConsensusSetTester(t.Name() + "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst1.Close()
+	cst2, err := blankConsensusSetTester(t.Name()+"2", cst1.cs.staticDeps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst2.Close()
+
+	// Mature some more miner payouts so that the wallet can fund multiple
+	// transactions.
+	for i := 0; i < 3; i++ {
+		if _, err := cst1.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Create a transaction before the testers are connected so that it
+	// doesn't reach the transaction pool of cst2.
+	if _, err := cst1.wallet.SendSiacoins(types.SiacoinPrecision, randAddress()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Connect the testers and wait for cst2 to synchronize.
+	if err := cst2.gateway.Connect(cst1.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if cst1.cs.dbCurrentBlockID() != cst2.cs.dbCurrentBlockID() {
+			return errors.New("testers are not synchronized")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a transaction which is relayed to cst2.
+	if _, err := cst1.wallet.SendSiacoins(types.SiacoinPrecision, randAddress()); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if len(cst2.tpool.TransactionList()) == 0 {
+			return errors.New("transaction wasn't relayed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cst2 can only rebuild a part of the next block from its pool.
+	b, _ := cst1.miner.FindBlock()
+	_, missing := newCompactBlock(b).rebuild(cst2.tpool.TransactionList())
+	if len(missing) == 0 || len(missing) == len(b.Transactions) {
+		t.Fatalf("expected %v of %v transactions to be missing", len(missing), len(b.Transactions))
+	}
+
+	// The block is relayed to cst2 after cst1 accepts it.
+	if err := cst1.cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if cst2.cs.dbCurrentBlockID() != b.ID() {
+			return errors.New("compact block wasn't relayed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRelayCompactBlockReject checks that the sender of a compact block is
+// told when its header is rejected, so that it can fall back to the
+// RelayHeader RPC, and that known blocks aren't rejected.
+func TestRelayCompactBlockReject(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// relay sends the compact block of b to the consensus set and returns the
+	// error of the sender.
+	relay := func(b types.Block) error {
+		p1, p2 := net.Pipe()
+		defer p1.Close()
+		defer p2.Close()
+		go cst.cs.threadedRPCRelayCompactBlock(mockPeerConn{p2})
+		return cst.cs.managedSendCompactBlock(newCompactBlock(b), b)(mockPeerConn{p1})
+	}
+
+	// A block in the extreme future is rejected.
+	block, target, err := cst.miner.BlockForWork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	block.Timestamp = types.CurrentTimestamp() + types.ExtremeFutureThreshold + 10
+	future, _ := cst.miner.SolveBlock(block, target)
+	if err := relay(future); !errors.Contains(err, errCompactBlockRejected) || !strings.Contains(err.Error(), ErrExtremeFutureTimestamp.Error()) {
+		t.Fatal("expected compact block to be rejected, got", err)
+	}
+
+	// A known block isn't rejected.
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := relay(b); err != nil {
+		t.Fatal(err)
+	}
+}
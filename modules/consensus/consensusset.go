@@ -70,6 +70,23 @@ type ConsensusSet struct {
 	// the genesis block, meaning the PoW is not very expensive.
 	dosBlocks map[types.BlockID]struct{}
 
+	// checkpoints are the block IDs which are known to be part of the
+	// canonical blockchain. Blocks which conflict with a checkpoint are
+	// rejected.
+	checkpoints types.BlockCheckpoints
+
+	// txnSource provides the unconfirmed transactions that compact blocks
+	// are rebuilt from, see compactblock.go.
+	txnSource modules.UnconfirmedTransactionSource
+
+	// pruneDepth is the number of blocks of the current path which are kept
+	// in the block map in pruned mode. It is 0 if pruning is disabled.
+	// blockMapBase is the height of the oldest block after the genesis block
+	// in the block map, or 0 if the block map contains every block of the
+	// current path.
+	pruneDepth   types.BlockHeight
+	blockMapBase types.BlockHeight
+
 	// checkingConsistency is a bool indicating whether or not a consistency
 	// check is in progress. The consistency check logic call itself, resulting
 	// in infinite loops. This bool prevents that while still allowing for full
@@ -116,6 +133,8 @@ func consensusSetBlockingStartup(gateway modules.Gateway, persistDir string, dep
 
 		dosBlocks: make(map[types.BlockID]struct{}),
 
+		checkpoints: types.Checkpoints,
+
 		marshaler:       stdMarshaler{},
 		blockRuleHelper: stdBlockRuleHelper{},
 		blockValidator:  NewBlockValidator(),
@@ -172,12 +191,18 @@ func consensusSetAsyncStartup(cs *ConsensusSet, bootstrap bool) error {
 	// Register RPCs
 	cs.gateway.RegisterRPC("SendBlocks", cs.rpcSendBlocks)
 	cs.gateway.RegisterRPC("RelayHeader", cs.threadedRPCRelayHeader)
+	cs.gateway.RegisterRPC("RelayCompactBlock", cs.threadedRPCRelayCompactBlock)
 	cs.gateway.RegisterRPC("SendBlk", cs.rpcSendBlk)
+	cs.gateway.RegisterRPC("SendHeaders", cs.rpcSendHeaders)
+	cs.gateway.RegisterRPC("SendBodies", cs.rpcSendBodies)
 	cs.gateway.RegisterConnectCall("SendBlocks", cs.threadedReceiveBlocks)
 	err := cs.tg.OnStop(func() error {
 		cs.gateway.UnregisterRPC("SendBlocks")
 		cs.gateway.UnregisterRPC("RelayHeader")
+		cs.gateway.UnregisterRPC("RelayCompactBlock")
 		cs.gateway.UnregisterRPC("SendBlk")
+		cs.gateway.UnregisterRPC("SendHeaders")
+		cs.gateway.UnregisterRPC("SendBodies")
 		cs.gateway.UnregisterConnectCall("SendBlocks")
 		return nil
 	})
@@ -131,10 +131,9 @@ func (cs *ConsensusSet) getBlockTotals(tx *bolt.Tx, id types.BlockID) (totalTime
 	return
 }
 
-// storeBlockTotals computes the new total time and total target for the current
-// block and stores that new time in the database. It also returns the new
-// totals.
-func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockHeight, currentBlockID types.BlockID, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target, err error) {
+// blockTotals computes the new total time and total target for the current
+// block from the totals of its parent.
+func blockTotals(currentHeight types.BlockHeight, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target) {
 	// Reset the prevTotalTime to a delta of zero just before the hardfork.
 
 	// For each value, first multiply by the decay, and then add in the new
@@ -148,6 +147,14 @@ func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockH
 		newTotalTime = types.ASICHardforkTotalTime
 		newTotalTarget = types.ASICHardforkTotalTarget
 	}
+	return newTotalTime, newTotalTarget
+}
+
+// storeBlockTotals computes the new total time and total target for the current
+// block and stores that new time in the database. It also returns the new
+// totals.
+func (cs *ConsensusSet) storeBlockTotals(tx *bolt.Tx, currentHeight types.BlockHeight, currentBlockID types.BlockID, prevTotalTime int64, parentTimestamp, currentTimestamp types.Timestamp, prevTotalTarget, targetOfCurrentBlock types.Target) (newTotalTime int64, newTotalTarget types.Target, err error) {
+	newTotalTime, newTotalTarget = blockTotals(currentHeight, prevTotalTime, parentTimestamp, currentTimestamp, prevTotalTarget, targetOfCurrentBlock)
 
 	// Store the new total time and total target in the database at the
 	// appropriate id.
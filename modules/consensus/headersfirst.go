@@ -0,0 +1,650 @@
+package consensus
+
+// headersfirst.go implements the headers-first synchronization which is used
+// during the initial blockchain download.
+//
+// The consensus set first downloads the header chain of a peer with the
+// SendHeaders RPC. Every header is checked against the checkpoints, the
+// timestamp rules and the target computed by the difficulty adjustment, which
+// only needs the headers and the totals of the block the chain builds on. The
+// blocks are only downloaded if the header chain has more cumulative work than
+// the current chain, so a peer can't make the consensus set download and
+// validate a long chain with little work. The blocks are then downloaded in
+// batches from several outbound peers in parallel with the SendBodies RPC and
+// added to the consensus set in order.
+//
+// Only the genesis block is checkpointed so far (see types/checkpoints.go), so
+// the cumulative work check is what protects the consensus set from low-work
+// chains.
+//
+// Peers which don't support the SendHeaders RPC are synchronized with the
+// SendBlocks RPC instead.
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/threadgroup"
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+var (
+	errBodyMismatch            = errors.New("peer sent blocks which don't match the requested ids")
+	errBodyPeersFailed         = errors.New("no peer could send the blocks of the header chain")
+	errHeadersFirstUnsupported = errors.New("peer doesn't support headers-first synchronization")
+	errLowWorkChain            = errors.New("peer sent a longer header chain with less work than the current chain")
+	errTooManyHeaders          = errors.New("peer sent more headers than allowed in a batch")
+
+	// MaxCatchUpHeaders is the maximum number of headers that are sent in a
+	// single batch of the SendHeaders RPC.
+	MaxCatchUpHeaders = build.Select(build.Var{
+		Standard: types.BlockHeight(1000),
+		Dev:      types.BlockHeight(200),
+		Testing:  types.BlockHeight(5),
+	}).(types.BlockHeight)
+
+	// maxBodyPeers is the maximum number of peers blocks are downloaded from
+	// in parallel during headers-first synchronization.
+	maxBodyPeers = build.Select(build.Var{
+		Standard: 8,
+		Dev:      4,
+		Testing:  3,
+	}).(int)
+)
+
+type (
+	// headerNode is a header of a header chain together with the values the
+	// consensus set would compute for the corresponding processed block.
+	headerNode struct {
+		header      types.BlockHeader
+		id          types.BlockID
+		height      types.BlockHeight
+		depth       types.Target
+		childTarget types.Target
+		totalTime   int64
+		totalTarget types.Target
+	}
+
+	// headerChain validates a chain of headers which builds on a block of the
+	// consensus set.
+	headerChain struct {
+		cs          *ConsensusSet
+		checkpoints types.BlockCheckpoints
+
+		// currentHeight is the height of the consensus set when the download
+		// of the headers started.
+		currentHeight types.BlockHeight
+
+		// base is the block of the consensus set the chain builds on and
+		// ancestors contains the timestamps of base and its parents, starting
+		// with base.
+		base      headerNode
+		ancestors []types.Timestamp
+
+		nodes []headerNode
+	}
+)
+
+// newHeaderChain creates a header chain which builds on the block with the
+// provided id.
+func (cs *ConsensusSet) newHeaderChain(tx *bolt.Tx, id types.BlockID) (*headerChain, error) {
+	pb, err := getBlockMap(tx, id)
+	if err == errNilItem {
+		return nil, errOrphan
+	} else if err != nil {
+		return nil, err
+	}
+	if tx.Bucket(BucketOak).Get(id[:]) == nil {
+		return nil, errors.New("block totals of the parent of the header chain are missing")
+	}
+	totalTime, totalTarget := cs.getBlockTotals(tx, id)
+	hc := &headerChain{
+		cs:            cs,
+		checkpoints:   cs.checkpoints,
+		currentHeight: blockHeight(tx),
+		base: headerNode{
+			header:      pb.Block.Header(),
+			id:          id,
+			height:      pb.Height,
+			depth:       pb.Depth,
+			childTarget: pb.ChildTarget,
+			totalTime:   totalTime,
+			totalTarget: totalTarget,
+		},
+	}
+
+	// Collect the timestamps needed for the minimum timestamp and, before the
+	// oak hardfork, for the target adjustment. The parent id and timestamp
+	// are read from the encoded block without decoding the processed block.
+	numAncestors := types.BlockHeight(types.MedianTimestampWindow)
+	if pb.Height < types.OakHardforkBlock && types.TargetWindow+1 > numAncestors {
+		numAncestors = types.TargetWindow + 1
+	}
+	blockMap := tx.Bucket(BlockMap)
+	for i := types.BlockHeight(0); i < numAncestors && id != (types.BlockID{}); i++ {
+		pbBytes := blockMap.Get(id[:])
+		if pbBytes == nil {
+			// Blocks before a snapshot are not available.
+			break
+		}
+		copy(id[:], pbBytes[:32])
+		hc.ancestors = append(hc.ancestors, types.Timestamp(encoding.DecUint64(pbBytes[40:48])))
+	}
+	return hc, nil
+}
+
+// tip returns the last node of the chain.
+func (hc *headerChain) tip() headerNode {
+	if len(hc.nodes) == 0 {
+		return hc.base
+	}
+	return hc.nodes[len(hc.nodes)-1]
+}
+
+// timestamp returns the timestamp of the block at the given height of the
+// chain.
+func (hc *headerChain) timestamp(height types.BlockHeight) types.Timestamp {
+	if height > hc.base.height {
+		return hc.nodes[height-hc.base.height-1].header.Timestamp
+	}
+	i := int(hc.base.height - height)
+	if i >= len(hc.ancestors) {
+		i = len(hc.ancestors) - 1
+	}
+	return hc.ancestors[i]
+}
+
+// minimumValidChildTimestamp returns the earliest timestamp the child of the
+// tip can have. It matches stdBlockRuleHelper.minimumValidChildTimestamp.
+func (hc *headerChain) minimumValidChildTimestamp() types.Timestamp {
+	height := hc.tip().height
+	windowTimes := make(types.TimestampSlice, types.MedianTimestampWindow)
+	for i := range windowTimes {
+		if types.BlockHeight(i) > height {
+			// Use the genesis block timestamp for all remaining times.
+			windowTimes[i] = windowTimes[i-1]
+			continue
+		}
+		windowTimes[i] = hc.timestamp(height - types.BlockHeight(i))
+	}
+	sort.Sort(windowTimes)
+	return windowTimes[len(windowTimes)/2]
+}
+
+// childTargetPreOak returns the child target of a node before the oak
+// hardfork. It matches setChildTarget.
+func (hc *headerChain) childTargetPreOak(n, parent headerNode) types.Target {
+	if n.height%(types.TargetWindow/2) != 0 {
+		return parent.childTarget
+	}
+	windowSize := types.TargetWindow
+	if n.height < windowSize {
+		windowSize = n.height
+	}
+	timePassed := n.header.Timestamp - hc.timestamp(n.height-windowSize)
+	expectedTimePassed := types.BlockFrequency * windowSize
+	base := big.NewRat(int64(timePassed), int64(expectedTimePassed))
+	adjustedRatTarget := new(big.Rat).Mul(parent.childTarget.Rat(), clampTargetAdjustment(base))
+	return types.RatToTarget(adjustedRatTarget)
+}
+
+// add validates the header and appends it to the chain.
+func (hc *headerChain) add(h types.BlockHeader) error {
+	parent := hc.tip()
+	if h.ParentID != parent.id {
+		return errNonLinearChain
+	}
+	n := headerNode{
+		header: h,
+		id:     h.ID(),
+		height: parent.height + 1,
+		depth:  parent.depth.AddDifficulties(parent.childTarget),
+	}
+
+	// Check the header against the checkpoints.
+	if err := validateCheckpoint(hc.checkpoints, n.id, n.height, hc.currentHeight); err != nil {
+		return err
+	}
+	// Check that the nonce is a legal nonce.
+	if n.height >= types.ASICHardforkHeight && binary.LittleEndian.Uint64(h.Nonce[:])%types.ASICHardforkFactor != 0 {
+		return errBadNonce
+	}
+	// Check that the target of the header is sufficient.
+	if !checkHeaderTarget(h, parent.childTarget) {
+		return modules.ErrBlockUnsolved
+	}
+	// Check that the timestamp is neither too far in the past nor in the
+	// extreme future.
+	if hc.minimumValidChildTimestamp() > h.Timestamp {
+		return ErrEarlyTimestamp
+	}
+	if h.Timestamp > types.CurrentTimestamp()+types.ExtremeFutureThreshold {
+		return ErrExtremeFutureTimestamp
+	}
+
+	// Compute the totals and the child target the same way newChild does.
+	n.totalTime, n.totalTarget = blockTotals(n.height, parent.totalTime, parent.header.Timestamp, h.Timestamp, parent.totalTarget, parent.childTarget)
+	if parent.height < types.OakHardforkBlock {
+		n.childTarget = hc.childTargetPreOak(n, parent)
+	} else {
+		n.childTarget = hc.cs.childTargetOak(parent.totalTime, parent.totalTarget, parent.childTarget, parent.height, parent.header.Timestamp)
+	}
+	hc.nodes = append(hc.nodes, n)
+	return nil
+}
+
+// rpcSendHeaders is the receiving end of the SendHeaders RPC. Like
+// rpcSendBlocks, it uses the 32 input block IDs to find the first block the
+// caller is missing and then sends the headers of the current path from that
+// block onwards, in batches of up to 'MaxCatchUpHeaders' headers followed by a
+// boolean indicating whether more headers are available.
+func (cs *ConsensusSet) rpcSendHeaders(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// Read a list of blocks known to the requester and find the most recent
+	// block from the current path.
+	var knownBlocks [32]types.BlockID
+	err = encoding.ReadObject(conn, &knownBlocks, 32*crypto.HashSize)
+	if err != nil {
+		return err
+	}
+	found := false
+	var start types.BlockHeight
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) (err error) {
+		start, found, err = findSyncStart(tx, knownBlocks)
+		return err
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if !found {
+		if err := encoding.WriteObject(conn, []types.BlockHeader{}); err != nil {
+			return err
+		}
+		return encoding.WriteObject(conn, false)
+	}
+
+	// Send the caller all of the headers that they are missing.
+	moreAvailable := true
+	for moreAvailable {
+		var headers []types.BlockHeader
+		cs.mu.RLock()
+		err = cs.db.View(func(tx *bolt.Tx) error {
+			height := blockHeight(tx)
+			for i := start; i <= height && i < start+MaxCatchUpHeaders; i++ {
+				id, err := getPath(tx, i)
+				if err != nil {
+					return err
+				}
+				pb, err := getBlockMap(tx, id)
+				if err != nil {
+					return err
+				}
+				headers = append(headers, pb.Block.Header())
+			}
+			moreAvailable = start+MaxCatchUpHeaders <= height
+			start += MaxCatchUpHeaders
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if err := encoding.WriteObject(conn, headers); err != nil {
+			return err
+		}
+		if err := encoding.WriteObject(conn, moreAvailable); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// managedReceiveHeaders is the calling end of the SendHeaders RPC. It returns
+// the validated header chain of the peer, which is nil if the peer doesn't
+// have any blocks the consensus set is missing.
+func (cs *ConsensusSet) managedReceiveHeaders(conn modules.PeerConn) (_ *headerChain, err error) {
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+
+	// Peers which don't know the RPC close the connection without sending
+	// anything.
+	received := false
+	defer func() {
+		if err != nil && !received && !isTimeoutErr(err) {
+			err = errors.Compose(errHeadersFirstUnsupported, err)
+		}
+	}()
+
+	var history [32]types.BlockID
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		history = blockHistory(tx)
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout)); err != nil {
+		return nil, err
+	}
+	if err := encoding.WriteObject(conn, history); err != nil {
+		return nil, err
+	}
+
+	var hc *headerChain
+	moreAvailable := true
+	for moreAvailable {
+		var headers []types.BlockHeader
+		if err := encoding.ReadObject(conn, &headers, uint64(MaxCatchUpHeaders)*types.BlockHeaderSize+8); err != nil {
+			return nil, err
+		}
+		if err := encoding.ReadObject(conn, &moreAvailable, 1); err != nil {
+			return nil, err
+		}
+		received = true
+		if types.BlockHeight(len(headers)) > MaxCatchUpHeaders {
+			return nil, errTooManyHeaders
+		}
+		// Extend the deadline for every batch, the peer is making progress.
+		if err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout)); err != nil {
+			return nil, err
+		}
+		if len(headers) == 0 {
+			continue
+		}
+
+		if hc == nil {
+			cs.mu.RLock()
+			err = cs.db.View(func(tx *bolt.Tx) error {
+				var err error
+				hc, err = cs.newHeaderChain(tx, headers[0].ParentID)
+				return err
+			})
+			cs.mu.RUnlock()
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, h := range headers {
+			if err := hc.add(h); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return hc, nil
+}
+
+// rpcSendBodies is the receiving end of the SendBodies RPC. It sends the
+// blocks with the requested IDs.
+func (cs *ConsensusSet) rpcSendBodies(conn modules.PeerConn) error {
+	err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout))
+	if err != nil {
+		return err
+	}
+	finishedChan := make(chan struct{})
+	defer close(finishedChan)
+	go func() {
+		select {
+		case <-cs.tg.StopChan():
+		case <-finishedChan:
+		}
+		conn.Close()
+	}()
+	err = cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	var ids []types.BlockID
+	err = encoding.ReadObject(conn, &ids, uint64(MaxCatchUpBlocks)*crypto.HashSize+8)
+	if err != nil {
+		return err
+	}
+	if types.BlockHeight(len(ids)) > MaxCatchUpBlocks {
+		return errors.New("too many blocks requested")
+	}
+	blocks := make([]types.Block, 0, len(ids))
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			blocks = append(blocks, pb.Block)
+		}
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return encoding.WriteObject(conn, blocks)
+}
+
+// managedReceiveBodies returns an RPCFunc that requests the blocks with the
+// provided ids and stores them in blocks. The returned function should be
+// used as the calling end of the SendBodies RPC.
+func (cs *ConsensusSet) managedReceiveBodies(ids []types.BlockID, blocks *[]types.Block) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		if err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout)); err != nil {
+			return err
+		}
+		if err := encoding.WriteObject(conn, ids); err != nil {
+			return err
+		}
+		var received []types.Block
+		if err := encoding.ReadObject(conn, &received, uint64(MaxCatchUpBlocks)*types.BlockSizeLimit); err != nil {
+			return err
+		}
+		// The id of a block commits to its transactions, so matching ids
+		// mean that the blocks match the validated headers.
+		if len(received) != len(ids) {
+			return errBodyMismatch
+		}
+		for i := range received {
+			if received[i].ID() != ids[i] {
+				return errBodyMismatch
+			}
+		}
+		*blocks = received
+		return nil
+	}
+}
+
+// managedDownloadBodies downloads the blocks of the header chain in batches
+// from addr and up to maxBodyPeers-1 other outbound peers in parallel, and
+// adds them to the consensus set in order.
+func (cs *ConsensusSet) managedDownloadBodies(addr modules.NetAddress, nodes []headerNode) error {
+	var batches [][]types.BlockID
+	for i := 0; i < len(nodes); i += int(MaxCatchUpBlocks) {
+		end := i + int(MaxCatchUpBlocks)
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		batch := make([]types.BlockID, 0, end-i)
+		for _, n := range nodes[i:end] {
+			batch = append(batch, n.id)
+		}
+		batches = append(batches, batch)
+	}
+	peers := []modules.NetAddress{addr}
+	for _, p := range cs.gateway.Peers() {
+		if len(peers) >= maxBodyPeers {
+			break
+		}
+		if !p.Inbound && p.NetAddress != addr {
+			peers = append(peers, p.NetAddress)
+		}
+	}
+
+	// Every peer downloads batches until it fails once. A failed batch is
+	// queued again for the remaining peers. The queue has room for every
+	// batch, so queueing never blocks.
+	results := make([][]types.Block, len(batches))
+	sources := make([]modules.NetAddress, len(batches))
+	done := make([]chan struct{}, len(batches))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	queue := make(chan int, len(batches))
+	stop := make(chan struct{})
+	defer close(stop)
+	var wg sync.WaitGroup
+	for _, peer := range peers {
+		wg.Add(1)
+		go func(peer modules.NetAddress) {
+			defer wg.Done()
+			for {
+				var i int
+				select {
+				case <-stop:
+					return
+				case <-cs.tg.StopChan():
+					return
+				case i = <-queue:
+				}
+				var blocks []types.Block
+				err := cs.gateway.RPC(peer, "SendBodies", cs.managedReceiveBodies(batches[i], &blocks))
+				if err != nil {
+					cs.log.Debugf("WARN: failed to download blocks from %v: %v", peer, err)
+					cs.managedPenalizePeer(peer, nil, err)
+					queue <- i
+					return
+				}
+				results[i] = blocks
+				sources[i] = peer
+				close(done[i])
+			}
+		}(peer)
+	}
+	workersDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(workersDone)
+	}()
+
+	// Only download a limited number of batches ahead of the batch that is
+	// added next to limit memory usage.
+	window := 2 * len(peers)
+	queued := 0
+	for i := range batches {
+		for ; queued < len(batches) && queued < i+window; queued++ {
+			queue <- queued
+		}
+		select {
+		case <-done[i]:
+		case <-workersDone:
+			select {
+			case <-done[i]:
+			default:
+				return errBodyPeersFailed
+			}
+		case <-cs.tg.StopChan():
+			return threadgroup.ErrStopped
+		}
+		_, err := cs.managedAcceptBlocks(results[i])
+		if err != nil && err != modules.ErrNonExtendingBlock && err != modules.ErrBlockKnown {
+			cs.managedPenalizePeer(sources[i], results[i], err)
+			return err
+		}
+		results[i] = nil
+	}
+	return nil
+}
+
+// managedSyncHeadersFirst synchronizes the consensus set with the peer at addr
+// by downloading and validating its header chain before downloading the
+// blocks. The blocks are only downloaded if the header chain has more work
+// than the current chain.
+func (cs *ConsensusSet) managedSyncHeadersFirst(addr modules.NetAddress) error {
+	var hc *headerChain
+	err := cs.gateway.RPC(addr, "SendHeaders", func(conn modules.PeerConn) error {
+		var err error
+		hc, err = cs.managedReceiveHeaders(conn)
+		return err
+	})
+	if err != nil {
+		cs.managedPenalizePeer(addr, nil, err)
+		return err
+	}
+	if hc == nil || len(hc.nodes) == 0 {
+		return nil
+	}
+
+	// Compare the work of the header chain to the current chain.
+	tip := hc.tip()
+	var heavier bool
+	var currentHeight types.BlockHeight
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		current := currentProcessedBlock(tx)
+		currentHeight = current.Height
+		heavier = (&processedBlock{Depth: tip.depth}).heavierThan(current)
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	if !heavier {
+		// A peer which is behind on another fork is fine, but a longer chain
+		// with less work is not.
+		if tip.height > currentHeight {
+			cs.managedPenalizePeer(addr, nil, errLowWorkChain)
+			return errLowWorkChain
+		}
+		return nil
+	}
+	return cs.managedDownloadBodies(addr, hc.nodes)
+}
+
+// managedSyncWithPeer synchronizes the consensus set with the peer at addr.
+// Headers-first synchronization is used unless the peer doesn't support it.
+func (cs *ConsensusSet) managedSyncWithPeer(addr modules.NetAddress) error {
+	err := cs.managedSyncHeadersFirst(addr)
+	if errors.Contains(err, errHeadersFirstUnsupported) {
+		cs.log.Debugf("INFO: peer %v doesn't support headers-first synchronization: %v", addr, err)
+		return cs.gateway.RPC(addr, "SendBlocks", cs.managedReceiveBlocks)
+	}
+	return err
+}
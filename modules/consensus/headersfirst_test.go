@@ -0,0 +1,329 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestHeaderChainMatchesConsensus checks that a header chain computes the same
+// targets, depths and totals as the consensus set, before and after the oak
+// hardfork.
+func TestHeaderChainMatchesConsensus(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	for cst.cs.Height() < types.OakHardforkBlock+types.TargetWindow/2+5 {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = cst.cs.db.View(func(tx *bolt.Tx) error {
+		hc, err := cst.cs.newHeaderChain(tx, types.GenesisID)
+		if err != nil {
+			return err
+		}
+		for h := types.BlockHeight(1); h <= blockHeight(tx); h++ {
+			id, err := getPath(tx, h)
+			if err != nil {
+				return err
+			}
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return err
+			}
+			if err := hc.add(pb.Block.Header()); err != nil {
+				t.Fatalf("header at height %v was rejected: %v", h, err)
+			}
+			n := hc.tip()
+			totalTime, totalTarget := cst.cs.getBlockTotals(tx, id)
+			if n.height != pb.Height || n.depth != pb.Depth || n.childTarget != pb.ChildTarget {
+				t.Fatalf("header node at height %v doesn't match the processed block", h)
+			}
+			if n.totalTime != totalTime || n.totalTarget != totalTarget {
+				t.Fatalf("header node totals at height %v don't match the consensus set", h)
+			}
+		}
+
+		// A header that doesn't build on the tip is rejected.
+		if err := hc.add(types.GenesisBlock.Header()); err != errNonLinearChain {
+			t.Fatal("expected errNonLinearChain, got", err)
+		}
+		// An unsolved header is rejected.
+		unsolved := types.BlockHeader{
+			ParentID:  hc.tip().id,
+			Timestamp: types.CurrentTimestamp(),
+		}
+		for checkHeaderTarget(unsolved, hc.tip().childTarget) {
+			unsolved.Nonce[0]++
+		}
+		if err := hc.add(unsolved); err != modules.ErrBlockUnsolved {
+			t.Fatal("expected ErrBlockUnsolved, got", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestValidateCheckpoint probes validateCheckpoint.
+func TestValidateCheckpoint(t *testing.T) {
+	checkpoints := types.BlockCheckpoints{10: types.BlockID{1}}
+	tests := []struct {
+		id            types.BlockID
+		height        types.BlockHeight
+		currentHeight types.BlockHeight
+		err           error
+	}{
+		// Blocks before the checkpoint are fine until the checkpoint is
+		// reached.
+		{types.BlockID{2}, 5, 9, nil},
+		{types.BlockID{1}, 10, 9, nil},
+		{types.BlockID{2}, 10, 9, errCheckpointMismatch},
+		{types.BlockID{2}, 11, 10, nil},
+		// Blocks at or before a checkpoint the current chain reached fork the
+		// blockchain.
+		{types.BlockID{2}, 5, 10, errForkBeforeCheckpoint},
+		{types.BlockID{1}, 10, 20, errForkBeforeCheckpoint},
+		{types.BlockID{2}, 11, 20, nil},
+	}
+	for i, test := range tests {
+		if err := validateCheckpoint(checkpoints, test.id, test.height, test.currentHeight); err != test.err {
+			t.Errorf("%v: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+// TestAcceptBlockCheckpoint checks that the consensus set rejects blocks which
+// conflict with its checkpoints.
+func TestAcceptBlockCheckpoint(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// A block at a checkpoint height with a different id is rejected.
+	cst.cs.mu.Lock()
+	cst.cs.checkpoints = types.BlockCheckpoints{cst.cs.dbBlockHeight() + 1: {1}}
+	cst.cs.mu.Unlock()
+	b, err := cst.miner.FindBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cst.cs.AcceptBlock(b); err != errCheckpointMismatch {
+		t.Fatal("expected errCheckpointMismatch, got", err)
+	}
+
+	// A block matching the checkpoint is accepted.
+	cst.cs.mu.Lock()
+	cst.cs.checkpoints = types.BlockCheckpoints{cst.cs.dbBlockHeight() + 1: b.ID()}
+	cst.cs.mu.Unlock()
+	if err := cst.cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// A competing block before a checkpoint the chain reached is rejected
+	// before its proof of work is checked.
+	c, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.mu.Lock()
+	cst.cs.checkpoints = types.BlockCheckpoints{cst.cs.dbBlockHeight(): c.ID()}
+	cst.cs.mu.Unlock()
+	fork := b
+	fork.Timestamp++
+	if err := cst.cs.AcceptBlock(fork); err != errForkBeforeCheckpoint {
+		t.Fatal("expected errForkBeforeCheckpoint, got", err)
+	}
+}
+
+// TestValidateHeaderCheckpoint checks that headers which conflict with the
+// checkpoints of the consensus set are rejected.
+func TestValidateHeaderCheckpoint(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// The compiled in checkpoints start with the genesis block.
+	if types.Checkpoints[0] != types.GenesisID {
+		t.Fatal("genesis block isn't checkpointed")
+	}
+
+	b, err := cst.miner.FindBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateHeader := func() error {
+		cst.cs.mu.Lock()
+		defer cst.cs.mu.Unlock()
+		return cst.cs.db.View(func(tx *bolt.Tx) error {
+			return cst.cs.validateHeader(boltTxWrapper{tx}, b.Header())
+		})
+	}
+
+	// A header at a checkpoint height with a different id is rejected.
+	cst.cs.mu.Lock()
+	cst.cs.checkpoints = types.BlockCheckpoints{cst.cs.dbBlockHeight() + 1: {1}}
+	cst.cs.mu.Unlock()
+	if err := validateHeader(); err != errCheckpointMismatch {
+		t.Fatal("expected errCheckpointMismatch, got", err)
+	}
+
+	// A header matching the checkpoint is accepted.
+	cst.cs.mu.Lock()
+	cst.cs.checkpoints = types.BlockCheckpoints{cst.cs.dbBlockHeight() + 1: b.ID()}
+	cst.cs.mu.Unlock()
+	if err := validateHeader(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSyncHeadersFirst checks that a consensus set synchronizes with a peer
+// using headers-first synchronization, and falls back to SendBlocks for peers
+// which don't support it.
+func TestSyncHeadersFirst(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	remoteCST, err := blankConsensusSetTester(filepath.Join(t.Name(), "remote"), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remoteCST.Close()
+	localCST, err := blankConsensusSetTester(filepath.Join(t.Name(), "local"), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer localCST.Close()
+	if err := localCST.cs.gateway.Connect(remoteCST.cs.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the OnConnectRPCs to finish.
+	time.Sleep(100 * time.Millisecond)
+
+	// Mine enough blocks on the remote peer to need several batches of
+	// headers and blocks.
+	mine := func(cst *consensusSetTester, n types.BlockHeight) {
+		for i := types.BlockHeight(0); i < n; i++ {
+			b, err := cst.miner.FindBlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cst.cs.managedAcceptBlocks([]types.Block{b}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	mine(remoteCST, 3*MaxCatchUpHeaders+1)
+	remoteAddr := remoteCST.cs.gateway.Address()
+	if err := localCST.cs.managedSyncHeadersFirst(remoteAddr); err != nil {
+		t.Fatal(err)
+	}
+	if localCST.cs.CurrentBlock().ID() != remoteCST.cs.CurrentBlock().ID() {
+		t.Fatal("local consensus set didn't catch up with the remote consensus set")
+	}
+	// Synchronizing again is a no-op.
+	if err := localCST.cs.managedSyncHeadersFirst(remoteAddr); err != nil {
+		t.Fatal(err)
+	}
+
+	// A peer on a fork with less work is ignored.
+	mine(localCST, 2)
+	mine(remoteCST, 1)
+	localID := localCST.cs.CurrentBlock().ID()
+	if err := localCST.cs.managedSyncHeadersFirst(remoteAddr); err != nil {
+		t.Fatal(err)
+	}
+	if localCST.cs.CurrentBlock().ID() != localID {
+		t.Fatal("local consensus set switched to a chain with less work")
+	}
+
+	// A heavier fork causes a reorg.
+	mine(remoteCST, 2*MaxCatchUpBlocks)
+	if err := localCST.cs.managedSyncHeadersFirst(remoteAddr); err != nil {
+		t.Fatal(err)
+	}
+	if localCST.cs.CurrentBlock().ID() != remoteCST.cs.CurrentBlock().ID() {
+		t.Fatal("local consensus set didn't reorg to the heavier chain")
+	}
+
+	// Peers without the SendHeaders RPC are synchronized with SendBlocks.
+	remoteCST.cs.gateway.UnregisterRPC("SendHeaders")
+	defer remoteCST.cs.gateway.RegisterRPC("SendHeaders", remoteCST.cs.rpcSendHeaders)
+	mine(remoteCST, MaxCatchUpBlocks+1)
+	if err := localCST.cs.managedSyncHeadersFirst(remoteAddr); !errors.Contains(err, errHeadersFirstUnsupported) {
+		t.Fatal("expected errHeadersFirstUnsupported, got", err)
+	}
+	if err := localCST.cs.managedSyncWithPeer(remoteAddr); err != nil {
+		t.Fatal(err)
+	}
+	if localCST.cs.CurrentBlock().ID() != remoteCST.cs.CurrentBlock().ID() {
+		t.Fatal("local consensus set didn't fall back to SendBlocks")
+	}
+}
+
+// TestSyncHeadersFirstCheckpoint checks that headers-first synchronization
+// rejects a header chain which conflicts with a checkpoint.
+func TestSyncHeadersFirstCheckpoint(t *testing.T) {
+	if testing.Short() || !build.VLONG {
+		t.SkipNow()
+	}
+	remoteCST, err := blankConsensusSetTester(filepath.Join(t.Name(), "remote"), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remoteCST.Close()
+	localCST, err := blankConsensusSetTester(filepath.Join(t.Name(), "local"), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer localCST.Close()
+	if err := localCST.cs.gateway.Connect(remoteCST.cs.gateway.Address()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		b, err := remoteCST.miner.FindBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := remoteCST.cs.managedAcceptBlocks([]types.Block{b}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	localCST.cs.mu.Lock()
+	localCST.cs.checkpoints = types.BlockCheckpoints{5: {1}}
+	localCST.cs.mu.Unlock()
+	err = localCST.cs.managedSyncHeadersFirst(remoteCST.cs.gateway.Address())
+	if err != errCheckpointMismatch {
+		t.Fatal("expected errCheckpointMismatch, got", err)
+	}
+	if localCST.cs.Height() != 0 {
+		t.Fatal("blocks of a header chain conflicting with a checkpoint were downloaded")
+	}
+}
@@ -78,6 +78,7 @@ func (cs *ConsensusSet) loadDB() error {
 		if genesisID != cs.blockRoot.Block.ID() {
 			return errors.New("blockchain has wrong genesis block")
 		}
+		cs.blockMapBase = oldestBlockHeight(tx)
 		return nil
 	})
 }
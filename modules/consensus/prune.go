@@ -0,0 +1,371 @@
+package consensus
+
+// prune.go implements the pruned mode of the consensus set, which deletes the
+// blocks and diffs of the current path which are older than the prune depth
+// from the block map.
+//
+// Everything else is kept: the block path, which is needed to compute storage
+// proof segments, the oak totals and the utxo set, the file contracts and the
+// siafund pool. The prune depth can't be lower than MinPruneDepth, which keeps
+// enough blocks to revert any plausible reorg. Blocks forking the blockchain
+// before the oldest block in the block map are rejected.
+//
+// Subscribers which start from the genesis block receive the genesis block
+// followed by a single change applying the current block, see
+// modules.ConsensusChange.SkipsBlocks. Instead of the diffs of the pruned
+// blocks, this change contains the diffs between the state after the genesis
+// block and the current state, so a wallet rescan still finds every unspent
+// output. Subscribers which are behind the pruned blocks receive
+// modules.ErrInvalidConsensusChangeID and need to rescan.
+
+import (
+	"bytes"
+	"fmt"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"gitlab.com/NebulousLabs/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+const (
+	// pruneBatchSize is the maximum number of blocks which are pruned in a
+	// single database transaction.
+	pruneBatchSize = 1000
+)
+
+var (
+	// Pruned is a database bucket which only exists if blocks of the consensus
+	// set were pruned.
+	Pruned = []byte("Pruned")
+
+	// FieldPrunedBase is a field in the Pruned bucket containing the height
+	// of the oldest block after the genesis block which wasn't pruned.
+	FieldPrunedBase = []byte("Base")
+
+	// MinPruneDepth is the minimum number of blocks of the current path which
+	// are kept in pruned mode.
+	MinPruneDepth = build.Select(build.Var{
+		Standard: types.BlockHeight(1000),
+		Dev:      types.BlockHeight(100),
+		Testing:  types.BlockHeight(20),
+	}).(types.BlockHeight)
+
+	errPrunedBlocks     = errors.New("the requested blocks were pruned")
+	errPrunedFork       = errors.New("block forks the blockchain before the oldest retained block")
+	errPruneDepthTooLow = errors.New("prune depth is too low")
+)
+
+// prunedBase returns the height of the oldest block after the genesis block
+// which wasn't pruned. It is 0 if no blocks were pruned.
+func prunedBase(tx *bolt.Tx) types.BlockHeight {
+	b := tx.Bucket(Pruned)
+	if b == nil {
+		return 0
+	}
+	var base types.BlockHeight
+	err := encoding.Unmarshal(b.Get(FieldPrunedBase), &base)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return base
+}
+
+// oldestBlockHeight returns the height of the oldest block after the genesis
+// block in the block map. It is 0 if the block map contains every block of the
+// current path.
+func oldestBlockHeight(tx *bolt.Tx) types.BlockHeight {
+	base := snapshotBase(tx)
+	if pb := prunedBase(tx); pb > base {
+		base = pb
+	}
+	return base
+}
+
+// pruneBlocks deletes up to maxBlocks blocks of the current path which are
+// more than depth blocks below the current block from the block map. It
+// returns the new oldest block height and whether all blocks that are old
+// enough have been pruned.
+func pruneBlocks(tx *bolt.Tx, depth, maxBlocks types.BlockHeight) (types.BlockHeight, bool, error) {
+	base := oldestBlockHeight(tx)
+	height := blockHeight(tx)
+	if depth == 0 || height < depth {
+		return base, true, nil
+	}
+	// Blocks before the oak hardfork are never pruned because the difficulty
+	// adjustment before the hardfork needs the full target window.
+	target := height - depth + 1
+	if target <= types.OakHardforkBlock {
+		return base, true, nil
+	}
+	start := base
+	if start == 0 {
+		start = 1
+	}
+	if start >= target {
+		return base, true, nil
+	}
+	end := target
+	if end-start > maxBlocks {
+		end = start + maxBlocks
+	}
+
+	blockMap := tx.Bucket(BlockMap)
+	blockMapV2 := tx.Bucket(BlockMapV2)
+	for h := start; h < end; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return base, false, err
+		}
+		if err := blockMap.Delete(id[:]); err != nil {
+			return base, false, err
+		}
+		if err := blockMapV2.Delete(id[:]); err != nil {
+			return base, false, err
+		}
+	}
+	b, err := tx.CreateBucketIfNotExists(Pruned)
+	if err != nil {
+		return base, false, err
+	}
+	if err := b.Put(FieldPrunedBase, encoding.Marshal(end)); err != nil {
+		return base, false, err
+	}
+	return end, end == target, nil
+}
+
+// prune prunes the next batch of blocks if pruned mode is enabled. It returns
+// true if there are no more blocks to prune.
+func (cs *ConsensusSet) prune() (done bool, err error) {
+	if cs.pruneDepth == 0 {
+		return true, nil
+	}
+	var base types.BlockHeight
+	err = cs.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		base, done, err = pruneBlocks(tx, cs.pruneDepth, pruneBatchSize)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	cs.blockMapBase = base
+	return done, nil
+}
+
+// managedPrune prunes all blocks which are older than the prune depth.
+func (cs *ConsensusSet) managedPrune() error {
+	for {
+		cs.mu.Lock()
+		done, err := cs.prune()
+		cs.mu.Unlock()
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-cs.tg.StopChan():
+			return errors.New("consensus set was stopped during pruning")
+		default:
+		}
+	}
+}
+
+// SetPruneDepth enables the pruned mode of the consensus set. Only the most
+// recent depth blocks of the current path are kept in the database, older
+// blocks are pruned right away and whenever the blockchain grows. A depth of 0
+// disables pruning, blocks which were already pruned remain pruned.
+func (cs *ConsensusSet) SetPruneDepth(depth types.BlockHeight) error {
+	if depth != 0 && depth < MinPruneDepth {
+		return errors.AddContext(errPruneDepthTooLow, fmt.Sprintf("minimum prune depth is %v", MinPruneDepth))
+	}
+	if err := cs.tg.Add(); err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	cs.mu.Lock()
+	cs.pruneDepth = depth
+	cs.mu.Unlock()
+	return cs.managedPrune()
+}
+
+// validateRetainedParent checks that the ancestors of a new block which are
+// needed to validate it weren't pruned.
+func (cs *ConsensusSet) validateRetainedParent(parent *processedBlockV2) error {
+	if cs.blockMapBase > 0 && parent.Height < cs.blockMapBase+types.BlockHeight(types.MedianTimestampWindow) {
+		return errPrunedFork
+	}
+	return nil
+}
+
+// validateForkDepth checks that the blocks which need to be reverted to apply
+// pb weren't pruned.
+func (cs *ConsensusSet) validateForkDepth(tx *bolt.Tx, pb *processedBlockV2) error {
+	if cs.blockMapBase == 0 {
+		return nil
+	}
+	for {
+		id, err := getPath(tx, pb.Height)
+		if err == nil && id == pb.Block.ID() {
+			break
+		}
+		pb, err = getBlockMap(tx, pb.Block.ParentID)
+		if err != nil {
+			return errPrunedFork
+		}
+	}
+	if pb.Height+1 < cs.blockMapBase {
+		return errPrunedFork
+	}
+	return nil
+}
+
+// entryPruned returns true if a block of the change entry was pruned.
+func entryPruned(tx *bolt.Tx, ce changeEntry) bool {
+	blockMap := tx.Bucket(BlockMap)
+	for _, id := range append(ce.RevertedBlocks, ce.AppliedBlocks...) {
+		if blockMap.Get(id[:]) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// computePrunedConsensusChange computes the consensus change which brings a
+// subscriber from the genesis block to the current block of a consensus set
+// with pruned blocks. Its diffs are the differences between the state after
+// the genesis block and the current state.
+func (cs *ConsensusSet) computePrunedConsensusChange(tx *bolt.Tx) (modules.ConsensusChange, error) {
+	var cd modules.ConsensusChangeDiffs
+
+	// The outputs created by the genesis block are only reverted if they were
+	// spent, all other outputs are applied.
+	genesisSiacoinOutputs := make(map[types.SiacoinOutputID]struct{})
+	for _, scod := range cs.blockRoot.SiacoinOutputDiffs {
+		genesisSiacoinOutputs[scod.ID] = struct{}{}
+		if !isSiacoinOutput(tx, scod.ID) {
+			scod.Direction = modules.DiffRevert
+			cd.SiacoinOutputDiffs = append(cd.SiacoinOutputDiffs, scod)
+		}
+	}
+	err := tx.Bucket(SiacoinOutputs).ForEach(func(k, v []byte) error {
+		var id types.SiacoinOutputID
+		copy(id[:], k)
+		if _, exists := genesisSiacoinOutputs[id]; exists {
+			return nil
+		}
+		var sco types.SiacoinOutput
+		if err := encoding.Unmarshal(v, &sco); err != nil {
+			return err
+		}
+		cd.SiacoinOutputDiffs = append(cd.SiacoinOutputDiffs, modules.SiacoinOutputDiff{
+			Direction:     modules.DiffApply,
+			ID:            id,
+			SiacoinOutput: sco,
+		})
+		return nil
+	})
+	if err != nil {
+		return modules.ConsensusChange{}, err
+	}
+
+	genesisSiafundOutputs := make(map[types.SiafundOutputID]struct{})
+	for _, sfod := range cs.blockRoot.SiafundOutputDiffs {
+		genesisSiafundOutputs[sfod.ID] = struct{}{}
+		if tx.Bucket(SiafundOutputs).Get(sfod.ID[:]) == nil {
+			sfod.Direction = modules.DiffRevert
+			cd.SiafundOutputDiffs = append(cd.SiafundOutputDiffs, sfod)
+		}
+	}
+	err = tx.Bucket(SiafundOutputs).ForEach(func(k, v []byte) error {
+		var id types.SiafundOutputID
+		copy(id[:], k)
+		if _, exists := genesisSiafundOutputs[id]; exists {
+			return nil
+		}
+		var sfo types.SiafundOutput
+		if err := encoding.Unmarshal(v, &sfo); err != nil {
+			return err
+		}
+		cd.SiafundOutputDiffs = append(cd.SiafundOutputDiffs, modules.SiafundOutputDiff{
+			Direction:     modules.DiffApply,
+			ID:            id,
+			SiafundOutput: sfo,
+		})
+		return nil
+	})
+	if err != nil {
+		return modules.ConsensusChange{}, err
+	}
+
+	err = tx.Bucket(FileContracts).ForEach(func(k, v []byte) error {
+		var id types.FileContractID
+		copy(id[:], k)
+		var fc types.FileContract
+		if err := encoding.Unmarshal(v, &fc); err != nil {
+			return err
+		}
+		cd.FileContractDiffs = append(cd.FileContractDiffs, modules.FileContractDiff{
+			Direction:    modules.DiffApply,
+			ID:           id,
+			FileContract: fc,
+		})
+		return nil
+	})
+	if err != nil {
+		return modules.ConsensusChange{}, err
+	}
+
+	// The delayed siacoin outputs are stored in one bucket per maturity
+	// height.
+	err = tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !bytes.HasPrefix(name, prefixDSCO) {
+			return nil
+		}
+		maturityHeight := types.BlockHeight(encoding.DecUint64(name[len(prefixDSCO):]))
+		return b.ForEach(func(k, v []byte) error {
+			var id types.SiacoinOutputID
+			copy(id[:], k)
+			var sco types.SiacoinOutput
+			if err := encoding.Unmarshal(v, &sco); err != nil {
+				return err
+			}
+			cd.DelayedSiacoinOutputDiffs = append(cd.DelayedSiacoinOutputDiffs, modules.DelayedSiacoinOutputDiff{
+				Direction:      modules.DiffApply,
+				ID:             id,
+				SiacoinOutput:  sco,
+				MaturityHeight: maturityHeight,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return modules.ConsensusChange{}, err
+	}
+
+	cd.SiafundPoolDiffs = []modules.SiafundPoolDiff{{
+		Direction: modules.DiffApply,
+		Previous:  types.ZeroCurrency,
+		Adjusted:  getSiafundPool(tx),
+	}}
+
+	pb := currentProcessedBlock(tx)
+	var id modules.ConsensusChangeID
+	copy(id[:], tx.Bucket(ChangeLog).Get(ChangeLogTailID))
+	cc := modules.ConsensusChange{
+		ID:                   id,
+		AppliedBlocks:        []types.Block{pb.Block},
+		AppliedDiffs:         []modules.ConsensusChangeDiffs{cd},
+		ConsensusChangeDiffs: cd,
+		ChildTarget:          pb.ChildTarget,
+		Synced:               cs.synced,
+		TryTransactionSet:    cs.tryTransactionSet,
+		OldHeight:            pb.Height - 1,
+		NewHeight:            pb.Height,
+	}
+	cc.MinimumValidChildTimestamp = cs.blockRuleHelper.minimumValidChildTimestamp(tx.Bucket(BlockMap), pb)
+	return cc, nil
+}
@@ -0,0 +1,220 @@
+package consensus
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// stateSubscriber applies the diffs of the consensus changes it receives.
+type stateSubscriber struct {
+	siacoinOutputs map[types.SiacoinOutputID]types.SiacoinOutput
+	siafundOutputs map[types.SiafundOutputID]types.SiafundOutput
+	fileContracts  map[types.FileContractID]types.FileContract
+	dscos          map[types.SiacoinOutputID]types.SiacoinOutput
+	siafundPool    types.Currency
+	height         types.BlockHeight
+}
+
+// newStateSubscriber returns an empty stateSubscriber.
+func newStateSubscriber() *stateSubscriber {
+	return &stateSubscriber{
+		siacoinOutputs: make(map[types.SiacoinOutputID]types.SiacoinOutput),
+		siafundOutputs: make(map[types.SiafundOutputID]types.SiafundOutput),
+		fileContracts:  make(map[types.FileContractID]types.FileContract),
+		dscos:          make(map[types.SiacoinOutputID]types.SiacoinOutput),
+	}
+}
+
+// ProcessConsensusChange applies the diffs of cc.
+func (ss *stateSubscriber) ProcessConsensusChange(cc modules.ConsensusChange) {
+	for _, d := range cc.SiacoinOutputDiffs {
+		if d.Direction == modules.DiffApply {
+			ss.siacoinOutputs[d.ID] = d.SiacoinOutput
+		} else {
+			delete(ss.siacoinOutputs, d.ID)
+		}
+	}
+	for _, d := range cc.SiafundOutputDiffs {
+		if d.Direction == modules.DiffApply {
+			ss.siafundOutputs[d.ID] = d.SiafundOutput
+		} else {
+			delete(ss.siafundOutputs, d.ID)
+		}
+	}
+	for _, d := range cc.FileContractDiffs {
+		if d.Direction == modules.DiffApply {
+			ss.fileContracts[d.ID] = d.FileContract
+		} else {
+			delete(ss.fileContracts, d.ID)
+		}
+	}
+	for _, d := range cc.DelayedSiacoinOutputDiffs {
+		if d.Direction == modules.DiffApply {
+			ss.dscos[d.ID] = d.SiacoinOutput
+		} else {
+			delete(ss.dscos, d.ID)
+		}
+	}
+	for _, d := range cc.SiafundPoolDiffs {
+		if d.Direction == modules.DiffApply {
+			ss.siafundPool = d.Adjusted
+		} else {
+			ss.siafundPool = d.Previous
+		}
+	}
+	ss.height = cc.NewHeight
+}
+
+// TestPruneBlocks checks that a pruned consensus set deletes old blocks, keeps
+// the block path and accepts new blocks.
+func TestPruneBlocks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	for cst.cs.Height() <= types.OakHardforkBlock+2*MinPruneDepth {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cst.cs.SetPruneDepth(MinPruneDepth - 1); !errors.Contains(err, errPruneDepthTooLow) {
+		t.Fatal("expected errPruneDepthTooLow, got", err)
+	}
+	if err := cst.cs.SetPruneDepth(MinPruneDepth); err != nil {
+		t.Fatal(err)
+	}
+	checkPruned := func() {
+		t.Helper()
+		height := cst.cs.Height()
+		base := height - MinPruneDepth + 1
+		if cst.cs.blockMapBase != base {
+			t.Fatalf("expected oldest block at height %v, got %v", base, cst.cs.blockMapBase)
+		}
+		if _, exists := cst.cs.BlockAtHeight(0); !exists {
+			t.Fatal("genesis block was pruned")
+		}
+		if _, exists := cst.cs.BlockAtHeight(base - 1); exists {
+			t.Fatal("block before the prune depth wasn't pruned")
+		}
+		if _, exists := cst.cs.BlockAtHeight(base); !exists {
+			t.Fatal("block within the prune depth was pruned")
+		}
+		err := cst.cs.db.View(func(tx *bolt.Tx) error {
+			for h := types.BlockHeight(0); h <= height; h++ {
+				if _, err := getPath(tx, h); err != nil {
+					t.Fatal("block path is missing height", h)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPruned()
+
+	// New blocks are accepted and the oldest blocks keep getting pruned.
+	for i := 0; i < 3; i++ {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPruned()
+
+	// Blocks whose ancestors were pruned are rejected before their proof of
+	// work is checked.
+	b := types.Block{
+		ParentID:  types.GenesisID,
+		Timestamp: types.CurrentTimestamp(),
+	}
+	if err := cst.cs.AcceptBlock(b); err != errPrunedFork {
+		t.Fatal("expected errPrunedFork, got", err)
+	}
+
+	// Peers which are missing pruned blocks aren't sent any blocks.
+	err = cst.cs.db.View(func(tx *bolt.Tx) error {
+		var known [32]types.BlockID
+		known[0] = types.GenesisID
+		if _, _, err := findSyncStart(tx, known); err != errPrunedBlocks {
+			t.Fatal("expected errPrunedBlocks, got", err)
+		}
+		known[0] = currentBlockID(tx)
+		known[1] = types.GenesisID
+		if _, found, err := findSyncStart(tx, known); found || err != nil {
+			t.Fatal("expected a synced peer, got", found, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPrunedSubscribe checks that a subscriber of a pruned consensus set
+// which starts from the genesis block ends up with the same state as a
+// subscriber which received every block.
+func TestPrunedSubscribe(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+	for cst.cs.Height() <= types.OakHardforkBlock+2*MinPruneDepth {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	full := newStateSubscriber()
+	if err := cst.cs.ConsensusSetSubscribe(full, modules.ConsensusChangeBeginning, nil); err != nil {
+		t.Fatal(err)
+	}
+	ms := newMockSubscriber()
+	if err := cst.cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, nil); err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.Unsubscribe(&ms)
+
+	if err := cst.cs.SetPruneDepth(MinPruneDepth); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cst.miner.AddBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The pruned subscriber receives the genesis block and the current state.
+	pruned := newStateSubscriber()
+	if err := cst.cs.ConsensusSetSubscribe(pruned, modules.ConsensusChangeBeginning, nil); err != nil {
+		t.Fatal(err)
+	}
+	if pruned.height != cst.cs.Height() || full.height != cst.cs.Height() {
+		t.Fatal("subscribers have the wrong height", pruned.height, full.height, cst.cs.Height())
+	}
+	if !reflect.DeepEqual(pruned, full) {
+		t.Fatal("state of the pruned subscriber differs")
+	}
+
+	// Subscribers which are behind the pruned blocks need to rescan.
+	err = cst.cs.ConsensusSetSubscribe(newStateSubscriber(), ms.updates[1].ID, nil)
+	if err != modules.ErrInvalidConsensusChangeID {
+		t.Fatal("expected ErrInvalidConsensusChangeID, got", err)
+	}
+	// Subscribers within the prune depth continue as usual.
+	recent := ms.updates[len(ms.updates)-1].ID
+	if err := cst.cs.ConsensusSetSubscribe(newStateSubscriber(), recent, nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,425 @@
+package consensus
+
+// snapshot.go implements exporting and importing snapshots of the consensus
+// set, which allows a new node to skip the initial blockchain download.
+//
+// A snapshot contains every bucket of the consensus database except for the
+// changelog, which includes the utxo set, the file contracts, the siafund pool
+// and the oak totals. Only the genesis block and the most recent blocks of the
+// current path are kept in the block map. They are enough to validate new
+// blocks and to handle reorgs which don't go deeper than the snapshot.
+//
+// The snapshot is a stream of length prefixed objects: a header, one record
+// per bucket followed by the records of its key value pairs, an empty record
+// and finally the commitment, which is the hash of all previous bytes. The
+// buckets and keys are exported in byte order, so two nodes with the same
+// current block produce the same commitment. A snapshot is only imported if
+// its commitment matches a commitment supplied by the user.
+//
+// Subscribers of an imported consensus set receive the genesis block followed
+// by a single change applying the retained blocks, see
+// modules.ConsensusChange.SkipsBlocks. Modules which need the full history,
+// like the explorer, need a consensus set synced from genesis.
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"gitlab.com/NebulousLabs/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/persist"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+const (
+	// snapshotMaxRecordSize is the maximum size of a single record of a
+	// snapshot.
+	snapshotMaxRecordSize = 1 << 26
+
+	// snapshotBatchSize is the number of records which are imported in a
+	// single database transaction.
+	snapshotBatchSize = 10000
+)
+
+var (
+	// Snapshot is a database bucket which only exists if the consensus set
+	// was imported from a snapshot.
+	Snapshot = []byte("Snapshot")
+
+	// FieldSnapshotBase is a field in the Snapshot bucket containing the
+	// height of the oldest block after the genesis block in the block map.
+	FieldSnapshotBase = []byte("Base")
+
+	// ErrConsensusDatabaseExists is returned when importing a snapshot into a
+	// directory which already contains a consensus database.
+	ErrConsensusDatabaseExists = errors.New("consensus database already exists")
+
+	errSnapshotCorrupted      = errors.New("snapshot is corrupted")
+	errSnapshotMismatch       = errors.New("snapshot commitment doesn't match the expected commitment")
+	errSnapshotNoCommitment   = errors.New("a commitment needs to be provided to import a snapshot")
+	errSnapshotTooShort       = errors.New("blockchain is too short to create a snapshot")
+	errSnapshotWrongSpecifier = errors.New("file is not a consensus snapshot")
+
+	// snapshotSpecifier is the specifier at the beginning of every snapshot.
+	snapshotSpecifier = types.NewSpecifier("ConsensusSnap1")
+
+	// snapshotRetainedBlocks is the number of blocks of the current path
+	// which are included in a snapshot.
+	snapshotRetainedBlocks = build.Select(build.Var{
+		Standard: types.BlockHeight(1000),
+		Dev:      types.BlockHeight(100),
+		Testing:  types.BlockHeight(20),
+	}).(types.BlockHeight)
+)
+
+type (
+	// snapshotHeader is the first object of a snapshot.
+	snapshotHeader struct {
+		Specifier types.Specifier
+		Height    types.BlockHeight
+		BlockID   types.BlockID
+	}
+
+	// snapshotRecord is a key value pair of a bucket. A record without a key
+	// creates the bucket and a record without a bucket ends the snapshot.
+	snapshotRecord struct {
+		Bucket []byte
+		Key    []byte
+		Value  []byte
+	}
+)
+
+// snapshotBase returns the height of the oldest block after the genesis block
+// in the block map. It is 0 if the consensus set wasn't imported from a
+// snapshot.
+func snapshotBase(tx *bolt.Tx) types.BlockHeight {
+	b := tx.Bucket(Snapshot)
+	if b == nil {
+		return 0
+	}
+	var base types.BlockHeight
+	err := encoding.Unmarshal(b.Get(FieldSnapshotBase), &base)
+	if build.DEBUG && err != nil {
+		panic(err)
+	}
+	return base
+}
+
+// skipSnapshotBucket returns true if a bucket isn't part of a snapshot.
+func skipSnapshotBucket(name []byte) bool {
+	return bytes.Equal(name, []byte("Metadata")) ||
+		bytes.Equal(name, ChangeLog) ||
+		bytes.Equal(name, Snapshot) ||
+		bytes.Equal(name, Pruned)
+}
+
+// exportSnapshot writes a snapshot of the consensus set to w.
+func exportSnapshot(tx *bolt.Tx, w io.Writer) (modules.ConsensusSnapshot, error) {
+	height := blockHeight(tx)
+	if height <= types.OakHardforkBlock || height < snapshotRetainedBlocks {
+		return modules.ConsensusSnapshot{}, errSnapshotTooShort
+	}
+	base := height - snapshotRetainedBlocks + 1
+	if base < oldestBlockHeight(tx) {
+		return modules.ConsensusSnapshot{}, errSnapshotTooShort
+	}
+
+	// Collect the blocks which are kept in the block map.
+	retained := map[types.BlockID]struct{}{
+		types.GenesisID: {},
+	}
+	for h := base; h <= height; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return modules.ConsensusSnapshot{}, err
+		}
+		retained[id] = struct{}{}
+	}
+	isRetained := func(k []byte) bool {
+		var id types.BlockID
+		copy(id[:], k)
+		_, exists := retained[id]
+		return len(k) == len(id) && exists
+	}
+
+	h := crypto.NewHash()
+	bw := bufio.NewWriter(w)
+	mw := io.MultiWriter(bw, h)
+	header := snapshotHeader{
+		Specifier: snapshotSpecifier,
+		Height:    height,
+		BlockID:   currentBlockID(tx),
+	}
+	if err := encoding.WriteObject(mw, header); err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if skipSnapshotBucket(name) {
+			return nil
+		}
+		// Empty file contract expiration buckets are left behind by
+		// reverted contracts. They are skipped so the commitment doesn't
+		// depend on the history of the node.
+		if bytes.HasPrefix(name, prefixFCEX) {
+			if k, _ := b.Cursor().First(); k == nil {
+				return nil
+			}
+		}
+		keep := func([]byte) bool { return true }
+		switch {
+		case bytes.Equal(name, BlockMap), bytes.Equal(name, BlockMapV2):
+			keep = isRetained
+		case bytes.Equal(name, BucketOak):
+			keep = func(k []byte) bool {
+				return bytes.Equal(k, FieldOakInit) || isRetained(k)
+			}
+		}
+
+		if err := encoding.WriteObject(mw, snapshotRecord{Bucket: name}); err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return errors.New("unexpected nested bucket in " + string(name))
+			}
+			if !keep(k) {
+				return nil
+			}
+			return encoding.WriteObject(mw, snapshotRecord{Bucket: name, Key: k, Value: v})
+		})
+	})
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	if err := encoding.WriteObject(mw, snapshotRecord{}); err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+
+	snap := modules.ConsensusSnapshot{
+		Height:  header.Height,
+		BlockID: header.BlockID,
+	}
+	copy(snap.Commitment[:], h.Sum(nil))
+	if err := encoding.WriteObject(bw, snap.Commitment); err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	return snap, bw.Flush()
+}
+
+// importSnapshot reads a snapshot from r into an empty database and verifies
+// it against the expected commitment.
+func importSnapshot(db *persist.BoltDatabase, r io.Reader, commitment crypto.Hash) (modules.ConsensusSnapshot, error) {
+	h := crypto.NewHash()
+	br := bufio.NewReader(r)
+	tr := io.TeeReader(br, h)
+
+	var header snapshotHeader
+	if err := encoding.ReadObject(tr, &header, snapshotMaxRecordSize); err != nil {
+		return modules.ConsensusSnapshot{}, errors.Compose(errSnapshotCorrupted, err)
+	}
+	if header.Specifier != snapshotSpecifier {
+		return modules.ConsensusSnapshot{}, errSnapshotWrongSpecifier
+	}
+	// Check that the snapshot can be verified before reading it.
+	if commitment == (crypto.Hash{}) {
+		return modules.ConsensusSnapshot{}, errSnapshotNoCommitment
+	}
+
+	// Import the records in batches to limit the size of a transaction.
+	for done := false; !done; {
+		err := db.Update(func(tx *bolt.Tx) error {
+			for i := 0; i < snapshotBatchSize; i++ {
+				var rec snapshotRecord
+				if err := encoding.ReadObject(tr, &rec, snapshotMaxRecordSize); err != nil {
+					return errors.Compose(errSnapshotCorrupted, err)
+				}
+				if len(rec.Bucket) == 0 {
+					done = true
+					return nil
+				}
+				if skipSnapshotBucket(rec.Bucket) {
+					return errSnapshotCorrupted
+				}
+				if len(rec.Key) == 0 {
+					if _, err := tx.CreateBucket(rec.Bucket); err != nil {
+						return errors.Compose(errSnapshotCorrupted, err)
+					}
+					continue
+				}
+				b := tx.Bucket(rec.Bucket)
+				if b == nil {
+					return errSnapshotCorrupted
+				}
+				if err := b.Put(rec.Key, rec.Value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return modules.ConsensusSnapshot{}, err
+		}
+	}
+
+	// Verify the commitment.
+	snap := modules.ConsensusSnapshot{
+		Height:  header.Height,
+		BlockID: header.BlockID,
+	}
+	copy(snap.Commitment[:], h.Sum(nil))
+	var trailer crypto.Hash
+	if err := encoding.ReadObject(br, &trailer, crypto.HashSize); err != nil {
+		return modules.ConsensusSnapshot{}, errors.Compose(errSnapshotCorrupted, err)
+	}
+	if trailer != snap.Commitment {
+		return modules.ConsensusSnapshot{}, errSnapshotCorrupted
+	}
+	if snap.Commitment != commitment {
+		return modules.ConsensusSnapshot{}, errSnapshotMismatch
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		return finalizeSnapshot(tx, header)
+	})
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	return snap, nil
+}
+
+// finalizeSnapshot checks that an imported snapshot matches its header and
+// creates the changelog.
+func finalizeSnapshot(tx *bolt.Tx, header snapshotHeader) error {
+	for _, bucket := range [][]byte{BlockHeight, BlockMap, BlockPath, BucketOak, SiafundPool} {
+		if tx.Bucket(bucket) == nil {
+			return errSnapshotCorrupted
+		}
+	}
+	height := blockHeight(tx)
+	if height != header.Height || height < snapshotRetainedBlocks {
+		return errSnapshotCorrupted
+	}
+	if id, err := getPath(tx, 0); err != nil || id != types.GenesisID {
+		return errors.New("snapshot has wrong genesis block")
+	}
+	if id, err := getPath(tx, height); err != nil || id != header.BlockID {
+		return errSnapshotCorrupted
+	}
+
+	// Check that the retained blocks form the current path.
+	base := height - snapshotRetainedBlocks + 1
+	var applied []types.BlockID
+	for h := base; h <= height; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return errSnapshotCorrupted
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil || pb.Height != h || pb.Block.ID() != id {
+			return errSnapshotCorrupted
+		}
+		if len(applied) > 0 && pb.Block.ParentID != applied[len(applied)-1] {
+			return errSnapshotCorrupted
+		}
+		if tx.Bucket(BucketOak).Get(id[:]) == nil {
+			return errSnapshotCorrupted
+		}
+		applied = append(applied, id)
+	}
+
+	// The changelog starts with the genesis block followed by the retained
+	// blocks.
+	err := initChangeLog(tx, changeEntry{AppliedBlocks: []types.BlockID{types.GenesisID}})
+	if err != nil {
+		return err
+	}
+	if err := appendChangeLog(tx, changeEntry{AppliedBlocks: applied}); err != nil {
+		return err
+	}
+	b, err := tx.CreateBucket(Snapshot)
+	if err != nil {
+		return err
+	}
+	return b.Put(FieldSnapshotBase, encoding.Marshal(base))
+}
+
+// ExportSnapshot writes a snapshot of the consensus set at the current height
+// to dst.
+func (cs *ConsensusSet) ExportSnapshot(dst string) (_ modules.ConsensusSnapshot, err error) {
+	if err := cs.tg.Add(); err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	defer cs.tg.Done()
+
+	f, err := os.Create(dst)
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	defer func() {
+		err = errors.Compose(err, f.Close())
+		if err != nil {
+			err = errors.Compose(err, os.Remove(dst))
+		}
+	}()
+
+	var snap modules.ConsensusSnapshot
+	cs.mu.RLock()
+	err = cs.db.View(func(tx *bolt.Tx) error {
+		var err error
+		snap, err = exportSnapshot(tx, f)
+		return err
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	if err := f.Sync(); err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	cs.log.Printf("Exported snapshot at height %v with commitment %v to %v", snap.Height, snap.Commitment, dst)
+	return snap, nil
+}
+
+// ImportSnapshot creates the consensus database in persistDir from the
+// snapshot read from r. The snapshot is only imported if it matches the
+// provided commitment. The consensus set continues to sync normally from the
+// height of the snapshot once it is loaded.
+func ImportSnapshot(persistDir string, r io.Reader, commitment crypto.Hash) (_ modules.ConsensusSnapshot, err error) {
+	filename := filepath.Join(persistDir, DatabaseFilename)
+	if _, err := os.Stat(filename); err == nil {
+		return modules.ConsensusSnapshot{}, ErrConsensusDatabaseExists
+	} else if !os.IsNotExist(err) {
+		return modules.ConsensusSnapshot{}, err
+	}
+	if err := os.MkdirAll(persistDir, 0700); err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+
+	// Import the snapshot into a temporary database which replaces the
+	// consensus database once it is verified.
+	tmpFilename := filename + "_snapshot"
+	if err := os.RemoveAll(tmpFilename); err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	db, err := persist.OpenDatabase(dbMetadata, tmpFilename)
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	snap, err := importSnapshot(db, r, commitment)
+	err = errors.Compose(err, db.Close())
+	if err != nil {
+		return modules.ConsensusSnapshot{}, errors.Compose(errors.AddContext(err, "failed to import snapshot"), os.Remove(tmpFilename))
+	}
+	if err := os.Rename(tmpFilename, filename); err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	return snap, nil
+}
@@ -0,0 +1,158 @@
+package consensus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/gateway"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestSnapshot checks that a consensus set imported from a snapshot matches
+// the exporting consensus set and can continue to accept blocks.
+func TestSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := createConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cst.Close()
+
+	// Snapshots of a short blockchain are rejected.
+	if _, err := cst.cs.ExportSnapshot(filepath.Join(cst.persistDir, "short")); err != errSnapshotTooShort {
+		t.Fatal("expected errSnapshotTooShort, got", err)
+	}
+	for cst.cs.Height() <= types.OakHardforkBlock+snapshotRetainedBlocks {
+		if _, err := cst.miner.AddBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Exporting twice should result in the same snapshot.
+	path := filepath.Join(cst.persistDir, "snapshot")
+	snap, err := cst.cs.ExportSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Height != cst.cs.Height() || snap.BlockID != cst.cs.CurrentBlock().ID() {
+		t.Fatal("wrong snapshot", snap)
+	}
+	snap2, err := cst.cs.ExportSnapshot(path + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap2 != snap {
+		t.Fatal("snapshots differ", snap, snap2)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Snapshots are only imported with a matching commitment.
+	dir := filepath.Join(cst.persistDir, "imported", modules.ConsensusDir)
+	if _, err := ImportSnapshot(dir, bytes.NewReader(data), crypto.Hash{}); !errors.Contains(err, errSnapshotNoCommitment) {
+		t.Fatal("expected errSnapshotNoCommitment, got", err)
+	}
+	if _, err := ImportSnapshot(dir, bytes.NewReader(data), crypto.Hash{1}); err == nil {
+		t.Fatal("expected snapshot with wrong commitment to be rejected")
+	}
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)/2]++
+	if _, err := ImportSnapshot(dir, bytes.NewReader(corrupted), snap.Commitment); err == nil {
+		t.Fatal("expected corrupted snapshot to be rejected")
+	}
+	if _, err := os.Stat(filepath.Join(dir, DatabaseFilename)); !os.IsNotExist(err) {
+		t.Fatal("rejected snapshot created a database", err)
+	}
+	imported, err := ImportSnapshot(dir, bytes.NewReader(data), snap.Commitment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != snap {
+		t.Fatal("wrong imported snapshot", imported, snap)
+	}
+	if _, err := ImportSnapshot(dir, bytes.NewReader(data), snap.Commitment); err != ErrConsensusDatabaseExists {
+		t.Fatal("expected ErrConsensusDatabaseExists, got", err)
+	}
+
+	// Load the imported consensus set.
+	g, err := gateway.New("127.0.0.1:0", false, filepath.Join(cst.persistDir, "imported", modules.GatewayDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	cs, errChan := New(g, false, dir)
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	defer cs.Close()
+	if cs.Height() != snap.Height || cs.CurrentBlock().ID() != snap.BlockID {
+		t.Fatal("imported consensus set has wrong current block")
+	}
+	checksum := func(cs *ConsensusSet) (h crypto.Hash) {
+		_ = cs.db.View(func(tx *bolt.Tx) error {
+			h = consensusChecksum(tx)
+			return nil
+		})
+		return
+	}
+	if checksum(cs) != checksum(cst.cs) {
+		t.Fatal("imported consensus set differs")
+	}
+
+	// Blocks before the snapshot aren't available.
+	if _, exists := cs.BlockAtHeight(1); exists {
+		t.Fatal("block before the snapshot shouldn't exist")
+	}
+
+	// Both consensus sets should accept the same block.
+	b, err := cst.miner.AddBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	if checksum(cs) != checksum(cst.cs) {
+		t.Fatal("consensus sets differ after accepting a block")
+	}
+
+	// Subscribers receive the genesis block followed by the retained blocks.
+	ms := newMockSubscriber()
+	if err := cs.ConsensusSetSubscribe(&ms, modules.ConsensusChangeBeginning, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(ms.updates) != 3 {
+		t.Fatal("expected 3 consensus changes, got", len(ms.updates))
+	}
+	if cc := ms.updates[1]; len(cc.AppliedBlocks) != int(snapshotRetainedBlocks) || cc.NewHeight != snap.Height {
+		t.Fatal("wrong consensus change for the snapshot", len(cc.AppliedBlocks), cc.NewHeight)
+	}
+	if cc := ms.updates[2]; cc.NewHeight != snap.Height+1 || cc.AppliedBlocks[0].ID() != b.ID() {
+		t.Fatal("wrong consensus change for the new block")
+	}
+
+	// Snapshots of both consensus sets should be identical.
+	snap, err = cst.cs.ExportSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap2, err = cs.ExportSnapshot(path + "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap2 != snap {
+		t.Fatal("snapshot of the imported consensus set differs", snap, snap2)
+	}
+}
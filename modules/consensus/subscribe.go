@@ -214,6 +214,9 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 
 	// Send all remaining consensus changes to the subscriber.
 	latestChangeID := entry.ID()
+	subscriberChangeID := start
+	genesisEntry := cs.genesisEntry()
+	genesisID := genesisEntry.ID()
 	for exists {
 		// Send changes in batches of 100 so that we don't hold the
 		// lock for too long.
@@ -226,11 +229,28 @@ func (cs *ConsensusSet) managedInitializeSubscribe(subscriber modules.ConsensusS
 					return siasync.ErrStopped
 				default:
 				}
+				// If the blocks of the entry were pruned, a subscriber
+				// which only has the genesis block receives the current
+				// state instead. Any other subscriber needs to rescan.
+				if cs.blockMapBase > 0 && entryPruned(tx, entry) {
+					if subscriberChangeID != genesisID {
+						return modules.ErrInvalidConsensusChangeID
+					}
+					cc, err := cs.computePrunedConsensusChange(tx)
+					if err != nil {
+						return err
+					}
+					subscriber.ProcessConsensusChange(cc)
+					latestChangeID = cc.ID
+					exists = false
+					break
+				}
 				cc, err := cs.computeConsensusChange(tx, entry)
 				if err != nil {
 					return err
 				}
 				subscriber.ProcessConsensusChange(cc)
+				subscriberChangeID = entry.ID()
 				entry, exists = entry.NextEntry(tx)
 			}
 			return nil
@@ -90,6 +90,60 @@ func isTimeoutErr(err error) bool {
 	return (err.Error() == "Read timeout" || err.Error() == "Write timeout")
 }
 
+// invalidBlockErrs are the errors which show that a block or header relayed
+// by a peer is invalid.
+var invalidBlockErrs = []error{
+	errBadNonce,
+	errCheckpointMismatch,
+	errDoSBlock,
+	modules.ErrBlockUnsolved,
+	ErrBadMinerPayouts,
+	ErrEarlyTimestamp,
+	ErrLargeBlock,
+}
+
+// managedPenalizePeer penalizes the peer at addr if err shows that it relayed
+// invalid blocks or headers or deviated from the protocol. Timeouts aren't
+// penalized since they can be caused by our own connection. blocks are the
+// blocks received from the peer, their invalidity is detected by looking them
+// up in the dosBlocks.
+func (cs *ConsensusSet) managedPenalizePeer(addr modules.NetAddress, blocks []types.Block, err error) {
+	if err == nil {
+		return
+	}
+	var penalty modules.PeerPenalty
+	switch {
+	case errors.Contains(err, errBodyMismatch), errors.Contains(err, errLowWorkChain),
+		errors.Contains(err, errNonLinearChain), errors.Contains(err, errTooManyHeaders),
+		errors.Contains(err, errBadMissingIndices), errors.Contains(err, errMissingTransactions):
+		penalty = modules.PenaltyProtocolViolation
+	case cs.managedInvalidBlocks(blocks, err):
+		penalty = modules.PenaltyInvalidBlock
+	default:
+		return
+	}
+	cs.log.Debugf("INFO: penalizing peer %v for %v: %v", addr, penalty.Reason, err)
+	cs.gateway.PenalizePeer(addr, penalty)
+}
+
+// managedInvalidBlocks returns true if err shows that a block or header is
+// invalid or if any of the blocks is known to be invalid.
+func (cs *ConsensusSet) managedInvalidBlocks(blocks []types.Block, err error) bool {
+	for _, invalidErr := range invalidBlockErrs {
+		if errors.Contains(err, invalidErr) {
+			return true
+		}
+	}
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	for _, b := range blocks {
+		if _, exists := cs.dosBlocks[b.ID()]; exists {
+			return true
+		}
+	}
+	return false
+}
+
 // blockHistory returns up to 32 block ids, starting with recent blocks and
 // then proving exponentially increasingly less recent blocks. The genesis
 // block is always included as the last block. This block history can be used
@@ -132,6 +186,40 @@ func blockHistory(tx *bolt.Tx) (blockIDs [32]types.BlockID) {
 	return blockIDs
 }
 
+// findSyncStart finds the most recent block of knownBlocks in the current path
+// and returns the height of its child, which is the first block the requesting
+// peer is missing. found is false if no block is found or if the requesting
+// peer already has the current block. errPrunedBlocks is returned if the
+// blocks the requesting peer is missing are no longer in the block map.
+func findSyncStart(tx *bolt.Tx, knownBlocks [32]types.BlockID) (start types.BlockHeight, found bool, err error) {
+	csHeight := blockHeight(tx)
+	base := oldestBlockHeight(tx)
+	for _, id := range knownBlocks {
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			continue
+		}
+		pathID, err := getPath(tx, pb.Height)
+		if err != nil {
+			continue
+		}
+		if pathID != pb.Block.ID() {
+			continue
+		}
+		if pb.Height == csHeight {
+			break
+		}
+		// If the consensus set was imported from a snapshot or pruned, the
+		// blocks before the oldest block in the block map can't be sent.
+		if pb.Height+1 < base {
+			return 0, false, errPrunedBlocks
+		}
+		// Start from the child of the common block.
+		return pb.Height + 1, true, nil
+	}
+	return 0, false, nil
+}
+
 // managedReceiveBlocks is the calling end of the SendBlocks RPC, without the
 // threadgroup wrapping.
 func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr error) {
@@ -160,6 +248,9 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 		if isTimeoutErr(returnErr) && stalled {
 			returnErr = errSendBlocksStalled
 		}
+		if returnErr == errSendBlocksStalled {
+			cs.managedPenalizePeer(conn.RPCAddr(), nil, returnErr)
+		}
 	}()
 
 	// Get blockIDs to send.
@@ -199,7 +290,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 				panic("blockchain extension reporting is incorrect")
 			}
 			fullBlock := cs.managedCurrentBlock() // TODO: Add cacheing, replace this line by looking at the cache.
-			go cs.gateway.Broadcast("RelayHeader", fullBlock.Header(), cs.gateway.Peers())
+			cs.managedBroadcastBlock(fullBlock)
 		}
 	}()
 
@@ -233,6 +324,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 		// sharing is implemented, block already in database should also be
 		// ignored.
 		if acceptErr != nil && acceptErr != modules.ErrNonExtendingBlock && acceptErr != modules.ErrBlockKnown {
+			cs.managedPenalizePeer(conn.RPCAddr(), newBlocks, acceptErr)
 			return acceptErr
 		}
 	}
@@ -298,31 +390,10 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 	// Find the most recent block from knownBlocks in the current path.
 	found := false
 	var start types.BlockHeight
-	var csHeight types.BlockHeight
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
-		csHeight = blockHeight(tx)
-		for _, id := range knownBlocks {
-			pb, err := getBlockMap(tx, id)
-			if err != nil {
-				continue
-			}
-			pathID, err := getPath(tx, pb.Height)
-			if err != nil {
-				continue
-			}
-			if pathID != pb.Block.ID() {
-				continue
-			}
-			if pb.Height == csHeight {
-				break
-			}
-			found = true
-			// Start from the child of the common block.
-			start = pb.Height + 1
-			break
-		}
-		return nil
+	err = cs.db.View(func(tx *bolt.Tx) (err error) {
+		start, found, err = findSyncStart(tx, knownBlocks)
+		return err
 	})
 	cs.mu.RUnlock()
 	if err != nil {
@@ -452,6 +523,7 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 		}()
 		return nil
 	} else if err != nil {
+		cs.managedPenalizePeer(conn.RPCAddr(), nil, err)
 		return err
 	}
 
@@ -534,6 +606,7 @@ func (cs *ConsensusSet) managedReceiveBlock(id types.BlockID) modules.RPCFunc {
 			cs.managedBroadcastBlock(block)
 		}
 		if err != nil {
+			cs.managedPenalizePeer(conn.RPCAddr(), []types.Block{block}, err)
 			return err
 		}
 		return nil
@@ -542,7 +615,8 @@ func (cs *ConsensusSet) managedReceiveBlock(id types.BlockID) modules.RPCFunc {
 
 // managedInitialBlockchainDownload performs the IBD on outbound peers. Blocks
 // are downloaded from one peer at a time in 5 minute intervals, so as to
-// prevent any one peer from significantly slowing down IBD.
+// prevent any one peer from significantly slowing down IBD. Peers which
+// support it are synchronized headers-first, see headersfirst.go.
 //
 // NOTE: IBD will succeed right now when each peer has a different blockchain.
 // The height and the block id of the remote peers' current blocks are not
@@ -580,7 +654,7 @@ func (cs *ConsensusSet) managedInitialBlockchainDownload() error {
 
 				// Request blocks from the peer. The error returned will only be
 				// 'nil' if there are no more blocks to receive.
-				err = cs.gateway.RPC(p.NetAddress, "SendBlocks", cs.managedReceiveBlocks)
+				err = cs.managedSyncWithPeer(p.NetAddress)
 				if err == nil {
 					numOutboundSynced++
 					// In this case, 'return nil' is equivalent to skipping to
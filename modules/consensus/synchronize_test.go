@@ -196,6 +196,17 @@ func (g *mockGatewayCountBroadcasts) Broadcast(name string, obj interface{}, pee
 	g.Gateway.Broadcast(name, obj, peers)
 }
 
+// RPC is a mock implementation of modules.Gateway.RPC that counts blocks
+// relayed with the RelayCompactBlock RPC as broadcasts.
+func (g *mockGatewayCountBroadcasts) RPC(addr modules.NetAddress, name string, fn modules.RPCFunc) error {
+	if name == "RelayCompactBlock" {
+		g.mu.Lock()
+		g.numBroadcasts++
+		g.mu.Unlock()
+	}
+	return g.Gateway.RPC(addr, name, fn)
+}
+
 // TestSendBlocksBroadcastsOnce tests that the SendBlocks RPC call only
 // Broadcasts one block, no matter how many blocks are sent. In the case 0
 // blocks are sent, tests that Broadcast is never called.
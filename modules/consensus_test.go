@@ -0,0 +1,32 @@
+package modules
+
+import (
+	"testing"
+
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestConsensusChangeSkipsBlocks probes the SkipsBlocks method of
+// ConsensusChange.
+func TestConsensusChangeSkipsBlocks(t *testing.T) {
+	b := types.Block{ParentID: types.BlockID{1}}
+	tests := []struct {
+		cc     ConsensusChange
+		height types.BlockHeight
+		skips  bool
+	}{
+		// The genesis block doesn't skip blocks.
+		{ConsensusChange{AppliedBlocks: []types.Block{types.GenesisBlock}, OldHeight: ^types.BlockHeight(0)}, 0, false},
+		// A change continuing from the provided height doesn't skip blocks.
+		{ConsensusChange{AppliedBlocks: []types.Block{b}, OldHeight: 5, NewHeight: 6}, 5, false},
+		// Reorgs don't skip blocks.
+		{ConsensusChange{RevertedBlocks: []types.Block{b}, AppliedBlocks: []types.Block{b}, OldHeight: 5, NewHeight: 5}, 5, false},
+		// A change continuing from a different height skips blocks.
+		{ConsensusChange{AppliedBlocks: []types.Block{b}, OldHeight: 40, NewHeight: 41}, 0, true},
+	}
+	for i, test := range tests {
+		if skips := test.cc.SkipsBlocks(test.height); skips != test.skips {
+			t.Errorf("%v: expected %v, got %v", i, test.skips, skips)
+		}
+	}
+}
@@ -223,9 +223,9 @@ func (pd *ProductionDependencies) Destruct() {
 }
 
 // DialTimeout creates a tcp connection to a certain address with the specified
-// timeout.
+// timeout, going through the global SOCKS5 proxy if one is set.
 func (*ProductionDependencies) DialTimeout(addr NetAddress, timeout time.Duration) (net.Conn, error) {
-	return net.DialTimeout("tcp", string(addr), timeout)
+	return GlobalProxy.Dial(&net.Dialer{Timeout: timeout}, addr)
 }
 
 // Disrupt can be used to inject specific behavior into a module by overwriting
@@ -45,6 +45,70 @@ type (
 		TotalRevisionVolume types.Currency `json:"totalrevisionvolume"`
 	}
 
+	// AddressBalance contains the balances of an address and the number of
+	// its unspent outputs.
+	AddressBalance struct {
+		UnlockHash         types.UnlockHash `json:"unlockhash"`
+		Siacoins           types.Currency   `json:"siacoins"`
+		SiafundsA          types.Currency   `json:"siafundsa"`
+		SiafundsB          types.Currency   `json:"siafundsb"`
+		SiacoinOutputCount uint64           `json:"siacoinoutputcount"`
+		SiafundOutputCount uint64           `json:"siafundoutputcount"`
+	}
+
+	// AddressSiacoinOutput is an unspent siacoin output of an address.
+	AddressSiacoinOutput struct {
+		ID    types.SiacoinOutputID `json:"id"`
+		Value types.Currency        `json:"value"`
+	}
+
+	// AddressSiafundOutput is an unspent siafund output of an address.
+	AddressSiafundOutput struct {
+		ID         types.SiafundOutputID `json:"id"`
+		Value      types.Currency        `json:"value"`
+		ClaimStart types.Currency        `json:"claimstart"`
+		SiafundB   bool                  `json:"siafundb"`
+	}
+
+	// ExplorerStats contains aggregate statistics about the distribution of
+	// siacoins and siafunds at the latest block. The supplies only include
+	// unspent outputs which have matured.
+	ExplorerStats struct {
+		Height         types.BlockHeight `json:"height"`
+		SiacoinSupply  types.Currency    `json:"siacoinsupply"`
+		SiafundASupply types.Currency    `json:"siafundasupply"`
+		SiafundBSupply types.Currency    `json:"siafundbsupply"`
+		AddressCount   uint64            `json:"addresscount"`
+
+		// Factoids about the burn address. BurnedSiacoins is the current
+		// balance of the burn address, TotalBurned and TotalUnburned are the
+		// siacoins which were ever sent to and spent from it.
+		BurnedSiacoins types.Currency `json:"burnedsiacoins"`
+		TotalBurned    types.Currency `json:"totalburned"`
+		TotalUnburned  types.Currency `json:"totalunburned"`
+
+		// Factoids about subsidies and claims. DevSubsidiesBurned is the part
+		// of the dev subsidies which was sent to the burn address.
+		DevSubsidies       types.Currency `json:"devsubsidies"`
+		DevSubsidiesBurned types.Currency `json:"devsubsidiesburned"`
+		SiafundClaims      types.Currency `json:"siafundclaims"`
+		SiafundBLostClaims types.Currency `json:"siafundblostclaims"`
+	}
+
+	// RichList contains the addresses with the highest balances of every
+	// asset sorted by their balance.
+	RichList struct {
+		Siacoins  []RichListEntry `json:"siacoins"`
+		SiafundsA []RichListEntry `json:"siafundsa"`
+		SiafundsB []RichListEntry `json:"siafundsb"`
+	}
+
+	// RichListEntry is an address and its balance of an asset.
+	RichListEntry struct {
+		UnlockHash types.UnlockHash `json:"unlockhash"`
+		Value      types.Currency   `json:"value"`
+	}
+
 	// Explorer tracks the blockchain and provides tools for gathering
 	// statistics and finding objects or patterns within the blockchain.
 	Explorer interface {
@@ -101,6 +165,25 @@ type (
 		// the provided siafund output id.
 		SiafundOutputID(types.SiafundOutputID) []types.TransactionID
 
+		// AddressBalance returns the balances of the provided unlock hash.
+		AddressBalance(types.UnlockHash) AddressBalance
+
+		// AddressSiacoinOutputs returns up to limit unspent siacoin outputs
+		// of the provided unlock hash, skipping the first offset outputs.
+		AddressSiacoinOutputs(uh types.UnlockHash, offset, limit uint64) []AddressSiacoinOutput
+
+		// AddressSiafundOutputs returns up to limit unspent siafund outputs
+		// of the provided unlock hash, skipping the first offset outputs.
+		AddressSiafundOutputs(uh types.UnlockHash, offset, limit uint64) []AddressSiafundOutput
+
+		// Stats returns aggregate statistics about the distribution of
+		// siacoins and siafunds.
+		Stats() ExplorerStats
+
+		// RichList returns the n addresses with the highest balance of every
+		// asset.
+		RichList(n uint64) RichList
+
 		Close() error
 	}
 )
@@ -0,0 +1,243 @@
+package explorer
+
+import (
+	"gitlab.com/NebulousLabs/encoding"
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// The explorer maintains the balances and the unspent outputs of every
+// address. They are updated from the siacoin and siafund output diffs of each
+// consensus change, which means that they always match the utxo set of the
+// consensus set. bucketAddressBalances maps an unlock hash to its
+// addressBalance. bucketAddressSiacoinOutputs and bucketAddressSiafundOutputs
+// contain a nested bucket for every unlock hash which maps the ids of its
+// unspent outputs to their values.
+
+type (
+	// addressBalance is the persisted balance of an address.
+	addressBalance struct {
+		Siacoins           types.Currency
+		SiafundsA          types.Currency
+		SiafundsB          types.Currency
+		SiacoinOutputCount uint64
+		SiafundOutputCount uint64
+	}
+
+	// addressSiafundOutput is a persisted unspent siafund output of an
+	// address. Whether the output is an SPF-B output is stored as well since
+	// it can't be derived from the output itself.
+	addressSiafundOutput struct {
+		Value      types.Currency
+		ClaimStart types.Currency
+		SiafundB   bool
+	}
+)
+
+// AddressBalance returns the balances of the provided unlock hash.
+func (e *Explorer) AddressBalance(uh types.UnlockHash) modules.AddressBalance {
+	var ab addressBalance
+	err := e.db.View(dbGetAndDecode(bucketAddressBalances, uh, &ab))
+	if err != nil {
+		ab = addressBalance{}
+	}
+	return modules.AddressBalance{
+		UnlockHash:         uh,
+		Siacoins:           ab.Siacoins,
+		SiafundsA:          ab.SiafundsA,
+		SiafundsB:          ab.SiafundsB,
+		SiacoinOutputCount: ab.SiacoinOutputCount,
+		SiafundOutputCount: ab.SiafundOutputCount,
+	}
+}
+
+// AddressSiacoinOutputs returns up to limit unspent siacoin outputs of the
+// provided unlock hash, skipping the first offset outputs.
+func (e *Explorer) AddressSiacoinOutputs(uh types.UnlockHash, offset, limit uint64) []modules.AddressSiacoinOutput {
+	outputs := []modules.AddressSiacoinOutput{}
+	err := e.db.View(dbForEachAddressOutput(bucketAddressSiacoinOutputs, uh, offset, limit, func(k, v []byte) error {
+		var sco modules.AddressSiacoinOutput
+		if err := encoding.Unmarshal(k, &sco.ID); err != nil {
+			return err
+		}
+		if err := encoding.Unmarshal(v, &sco.Value); err != nil {
+			return err
+		}
+		outputs = append(outputs, sco)
+		return nil
+	}))
+	if err != nil {
+		return []modules.AddressSiacoinOutput{}
+	}
+	return outputs
+}
+
+// AddressSiafundOutputs returns up to limit unspent siafund outputs of the
+// provided unlock hash, skipping the first offset outputs.
+func (e *Explorer) AddressSiafundOutputs(uh types.UnlockHash, offset, limit uint64) []modules.AddressSiafundOutput {
+	outputs := []modules.AddressSiafundOutput{}
+	err := e.db.View(dbForEachAddressOutput(bucketAddressSiafundOutputs, uh, offset, limit, func(k, v []byte) error {
+		var id types.SiafundOutputID
+		if err := encoding.Unmarshal(k, &id); err != nil {
+			return err
+		}
+		var sfo addressSiafundOutput
+		if err := encoding.Unmarshal(v, &sfo); err != nil {
+			return err
+		}
+		outputs = append(outputs, modules.AddressSiafundOutput{
+			ID:         id,
+			Value:      sfo.Value,
+			ClaimStart: sfo.ClaimStart,
+			SiafundB:   sfo.SiafundB,
+		})
+		return nil
+	}))
+	if err != nil {
+		return []modules.AddressSiafundOutput{}
+	}
+	return outputs
+}
+
+// dbForEachAddressOutput returns a 'func(*bolt.Tx) error' that calls fn for up
+// to limit outputs of the nested bucket of uh, skipping the first offset
+// outputs. A limit of 0 means no limit.
+func dbForEachAddressOutput(bucket []byte, uh types.UnlockHash, offset, limit uint64, fn func(k, v []byte) error) func(*bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket).Bucket(encoding.Marshal(uh))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		var n uint64
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if n < offset {
+				n++
+				continue
+			}
+			if limit > 0 && n >= offset+limit {
+				break
+			}
+			if err := fn(k, v); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	}
+}
+
+// These functions panic on error. The panic will be caught by
+// ProcessConsensusChange.
+
+// dbGetAddressBalance returns the balance of an address.
+func dbGetAddressBalance(tx *bolt.Tx, uh types.UnlockHash) addressBalance {
+	var ab addressBalance
+	err := dbGetAndDecode(bucketAddressBalances, uh, &ab)(tx)
+	if err == errNotExist {
+		return addressBalance{}
+	}
+	assertNil(err)
+	return ab
+}
+
+// dbPutAddressBalance updates the balance of an address from old to ab.
+// Addresses without any outputs are removed.
+func dbPutAddressBalance(tx *bolt.Tx, uh types.UnlockHash, old, ab addressBalance) {
+	dbUpdateBalanceStats(tx, uh, old, ab)
+	if ab.SiacoinOutputCount == 0 && ab.SiafundOutputCount == 0 {
+		mustDelete(tx.Bucket(bucketAddressBalances), uh)
+		return
+	}
+	mustPut(tx.Bucket(bucketAddressBalances), uh, ab)
+}
+
+// Add/Remove unspent siacoin output of an address
+func dbAddAddressSiacoinOutput(tx *bolt.Tx, id types.SiacoinOutputID, sco types.SiacoinOutput) {
+	// Remove a previous version of the output first.
+	dbRemoveAddressSiacoinOutput(tx, id, sco.UnlockHash)
+
+	b, err := tx.Bucket(bucketAddressSiacoinOutputs).CreateBucketIfNotExists(encoding.Marshal(sco.UnlockHash))
+	assertNil(err)
+	mustPut(b, id, sco.Value)
+
+	old := dbGetAddressBalance(tx, sco.UnlockHash)
+	ab := old
+	ab.Siacoins = ab.Siacoins.Add(sco.Value)
+	ab.SiacoinOutputCount++
+	dbPutAddressBalance(tx, sco.UnlockHash, old, ab)
+}
+func dbRemoveAddressSiacoinOutput(tx *bolt.Tx, id types.SiacoinOutputID, uh types.UnlockHash) {
+	b := tx.Bucket(bucketAddressSiacoinOutputs).Bucket(encoding.Marshal(uh))
+	if b == nil {
+		return
+	}
+	valBytes := b.Get(encoding.Marshal(id))
+	if valBytes == nil {
+		return
+	}
+	var value types.Currency
+	assertNil(encoding.Unmarshal(valBytes, &value))
+	mustDelete(b, id)
+	if bucketIsEmpty(b) {
+		assertNil(tx.Bucket(bucketAddressSiacoinOutputs).DeleteBucket(encoding.Marshal(uh)))
+	}
+
+	old := dbGetAddressBalance(tx, uh)
+	ab := old
+	ab.Siacoins = ab.Siacoins.Sub(value)
+	ab.SiacoinOutputCount--
+	dbPutAddressBalance(tx, uh, old, ab)
+}
+
+// Add/Remove unspent siafund output of an address
+func dbAddAddressSiafundOutput(tx *bolt.Tx, id types.SiafundOutputID, sfo types.SiafundOutput, siafundB bool) {
+	// Remove a previous version of the output first.
+	dbRemoveAddressSiafundOutput(tx, id, sfo.UnlockHash)
+
+	b, err := tx.Bucket(bucketAddressSiafundOutputs).CreateBucketIfNotExists(encoding.Marshal(sfo.UnlockHash))
+	assertNil(err)
+	mustPut(b, id, addressSiafundOutput{
+		Value:      sfo.Value,
+		ClaimStart: sfo.ClaimStart,
+		SiafundB:   siafundB,
+	})
+
+	old := dbGetAddressBalance(tx, sfo.UnlockHash)
+	ab := old
+	if siafundB {
+		ab.SiafundsB = ab.SiafundsB.Add(sfo.Value)
+	} else {
+		ab.SiafundsA = ab.SiafundsA.Add(sfo.Value)
+	}
+	ab.SiafundOutputCount++
+	dbPutAddressBalance(tx, sfo.UnlockHash, old, ab)
+}
+func dbRemoveAddressSiafundOutput(tx *bolt.Tx, id types.SiafundOutputID, uh types.UnlockHash) {
+	b := tx.Bucket(bucketAddressSiafundOutputs).Bucket(encoding.Marshal(uh))
+	if b == nil {
+		return
+	}
+	valBytes := b.Get(encoding.Marshal(id))
+	if valBytes == nil {
+		return
+	}
+	var sfo addressSiafundOutput
+	assertNil(encoding.Unmarshal(valBytes, &sfo))
+	mustDelete(b, id)
+	if bucketIsEmpty(b) {
+		assertNil(tx.Bucket(bucketAddressSiafundOutputs).DeleteBucket(encoding.Marshal(uh)))
+	}
+
+	old := dbGetAddressBalance(tx, uh)
+	ab := old
+	if sfo.SiafundB {
+		ab.SiafundsB = ab.SiafundsB.Sub(sfo.Value)
+	} else {
+		ab.SiafundsA = ab.SiafundsA.Sub(sfo.Value)
+	}
+	ab.SiafundOutputCount--
+	dbPutAddressBalance(tx, uh, old, ab)
+}
@@ -0,0 +1,162 @@
+package explorer
+
+import (
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestAddressBalance checks that the explorer tracks the balances and unspent
+// outputs of addresses.
+func TestAddressBalance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The balances of the wallet's addresses should add up to the wallet's
+	// balance.
+	balance, err := et.wallet.ConfirmedBalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := et.wallet.AllAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var siacoins, siafunds types.Currency
+	for _, addr := range addrs {
+		ab := et.explorer.AddressBalance(addr)
+		siacoins = siacoins.Add(ab.Siacoins)
+		siafunds = siafunds.Add(ab.SiafundsA).Add(ab.SiafundsB)
+	}
+	if !siacoins.Equals(balance.CoinBalance) {
+		t.Fatalf("siacoin balance doesn't match: %v != %v", siacoins, balance.CoinBalance)
+	}
+	if !siafunds.Equals(balance.FundBalance.Add(balance.FundbBalance)) {
+		t.Fatalf("siafund balance doesn't match: %v != %v", siafunds, balance.FundBalance.Add(balance.FundbBalance))
+	}
+
+	// Send coins to a new address in three transactions.
+	uc, err := et.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := uc.UnlockHash()
+	amount := types.SiacoinPrecision.Mul64(100)
+	for i := 0; i < 3; i++ {
+		if _, err := et.wallet.SendSiacoins(amount, addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, _ := et.miner.FindBlock()
+	if err := et.cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+	ab := et.explorer.AddressBalance(addr)
+	if !ab.Siacoins.Equals(amount.Mul64(3)) || ab.SiacoinOutputCount != 3 || ab.UnlockHash != addr {
+		t.Fatal("wrong address balance", ab)
+	}
+	outputs := et.explorer.AddressSiacoinOutputs(addr, 0, 0)
+	if len(outputs) != 3 {
+		t.Fatal("expected 3 outputs but got", len(outputs))
+	}
+	for _, sco := range outputs {
+		if !sco.Value.Equals(amount) {
+			t.Fatal("wrong output value", sco.Value)
+		}
+		if _, exists := et.explorer.SiacoinOutput(sco.ID); !exists {
+			t.Fatal("unknown output", sco.ID)
+		}
+	}
+
+	// Check the pagination.
+	page := et.explorer.AddressSiacoinOutputs(addr, 1, 1)
+	if len(page) != 1 || page[0].ID != outputs[1].ID {
+		t.Fatal("wrong page", page)
+	}
+	if page := et.explorer.AddressSiacoinOutputs(addr, 2, 5); len(page) != 1 || page[0].ID != outputs[2].ID {
+		t.Fatal("wrong page", page)
+	}
+	if page := et.explorer.AddressSiacoinOutputs(addr, 3, 5); len(page) != 0 {
+		t.Fatal("wrong page", page)
+	}
+	if outputs := et.explorer.AddressSiafundOutputs(addr, 0, 0); len(outputs) != 0 {
+		t.Fatal("address shouldn't have siafund outputs", outputs)
+	}
+
+	// Removing the outputs again should remove the address.
+	err = et.explorer.db.Update(func(tx *bolt.Tx) error {
+		for _, sco := range outputs {
+			dbRemoveAddressSiacoinOutput(tx, sco.ID, addr)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab = et.explorer.AddressBalance(addr)
+	if !ab.Siacoins.IsZero() || ab.SiacoinOutputCount != 0 {
+		t.Fatal("wrong address balance", ab)
+	}
+	if outputs := et.explorer.AddressSiacoinOutputs(addr, 0, 0); len(outputs) != 0 {
+		t.Fatal("outputs weren't removed", outputs)
+	}
+	err = et.explorer.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(bucketAddressSiacoinOutputs).Bucket(addr[:]) != nil {
+			t.Error("bucket of address wasn't removed")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestAddressIndexRebuild checks that an explorer database without the address
+// index is rebuilt on startup.
+func TestAddressIndexRebuild(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := et.wallet.AllAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	balances := make(map[types.UnlockHash]types.Currency)
+	for _, addr := range addrs {
+		balances[addr] = et.explorer.AddressBalance(addr).Siacoins
+	}
+
+	// Drop the address index and restart the explorer.
+	err = et.explorer.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(bucketAddressBalances)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := et.explorer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	et.explorer, err = New(et.cs, et.explorer.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range addrs {
+		if ab := et.explorer.AddressBalance(addr); !ab.Siacoins.Equals(balances[addr]) {
+			t.Fatalf("wrong balance after rebuild: %v != %v", ab.Siacoins, balances[addr])
+		}
+	}
+	if facts := et.explorer.LatestBlockFacts(); facts.Height != et.cs.Height() {
+		t.Fatal("explorer wasn't rebuilt", facts.Height, et.cs.Height())
+	}
+}
@@ -10,6 +10,9 @@ import (
 
 var (
 	// database buckets
+	bucketAddressBalances       = []byte("AddressBalances")
+	bucketAddressSiacoinOutputs = []byte("AddressSiacoinOutputs")
+	bucketAddressSiafundOutputs = []byte("AddressSiafundOutputs")
 	bucketBlockFacts            = []byte("BlockFacts")
 	bucketBlockIDs              = []byte("BlockIDs")
 	bucketBlocksDifficulty      = []byte("BlocksDifficulty")
@@ -17,19 +20,23 @@ var (
 	bucketFileContractHistories = []byte("FileContractHistories")
 	bucketFileContractIDs       = []byte("FileContractIDs")
 	// bucketInternal is used to store values internal to the explorer
-	bucketInternal         = []byte("Internal")
-	bucketSiacoinOutputIDs = []byte("SiacoinOutputIDs")
-	bucketSiacoinOutputs   = []byte("SiacoinOutputs")
-	bucketSiafundOutputIDs = []byte("SiafundOutputIDs")
-	bucketSiafundOutputs   = []byte("SiafundOutputs")
-	bucketTransactionIDs   = []byte("TransactionIDs")
-	bucketUnlockHashes     = []byte("UnlockHashes")
+	bucketInternal          = []byte("Internal")
+	bucketRichListSiacoins  = []byte("RichListSiacoins")
+	bucketRichListSiafundsA = []byte("RichListSiafundsA")
+	bucketRichListSiafundsB = []byte("RichListSiafundsB")
+	bucketSiacoinOutputIDs  = []byte("SiacoinOutputIDs")
+	bucketSiacoinOutputs    = []byte("SiacoinOutputs")
+	bucketSiafundOutputIDs  = []byte("SiafundOutputIDs")
+	bucketSiafundOutputs    = []byte("SiafundOutputs")
+	bucketTransactionIDs    = []byte("TransactionIDs")
+	bucketUnlockHashes      = []byte("UnlockHashes")
 
 	errNotExist = errors.New("entry does not exist")
 
 	// keys for bucketInternal
 	internalBlockHeight  = []byte("BlockHeight")
 	internalRecentChange = []byte("RecentChange")
+	internalStats        = []byte("Stats")
 )
 
 // These functions all return a 'func(*bolt.Tx) error', which, allows them to
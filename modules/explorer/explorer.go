@@ -18,6 +18,10 @@ const (
 
 var (
 	errNilCS = errors.New("explorer cannot use a nil consensus set")
+
+	// errSkippedBlocks is returned when the consensus set was bootstrapped
+	// from a snapshot, as the explorer needs to process every block.
+	errSkippedBlocks = errors.New("explorer cannot use a consensus set bootstrapped from a snapshot")
 )
 
 type (
@@ -35,6 +35,9 @@ func (e *Explorer) initPersist() error {
 	// Initialize the database
 	err = e.db.Update(func(tx *bolt.Tx) error {
 		buckets := [][]byte{
+			bucketAddressBalances,
+			bucketAddressSiacoinOutputs,
+			bucketAddressSiafundOutputs,
 			bucketBlockFacts,
 			bucketBlockIDs,
 			bucketBlocksDifficulty,
@@ -42,6 +45,9 @@ func (e *Explorer) initPersist() error {
 			bucketFileContractHistories,
 			bucketFileContractIDs,
 			bucketInternal,
+			bucketRichListSiacoins,
+			bucketRichListSiafundsA,
+			bucketRichListSiafundsB,
 			bucketSiacoinOutputIDs,
 			bucketSiacoinOutputs,
 			bucketSiafundOutputIDs,
@@ -49,6 +55,26 @@ func (e *Explorer) initPersist() error {
 			bucketTransactionIDs,
 			bucketUnlockHashes,
 		}
+
+		// Databases created before the address index and the statistics
+		// were added need to be rebuilt from scratch to populate them.
+		// Dropping the buckets resets the explorer to the beginning of the
+		// blockchain.
+		rebuild := false
+		for _, b := range [][]byte{bucketAddressBalances, bucketRichListSiacoins, bucketRichListSiafundsA, bucketRichListSiafundsB} {
+			rebuild = rebuild || tx.Bucket(b) == nil
+		}
+		if tx.Bucket(bucketInternal) != nil && rebuild {
+			for _, b := range buckets {
+				if tx.Bucket(b) == nil {
+					continue
+				}
+				if err := tx.DeleteBucket(b); err != nil {
+					return err
+				}
+			}
+		}
+
 		for _, b := range buckets {
 			_, err := tx.CreateBucketIfNotExists(b)
 			if err != nil {
@@ -62,6 +88,7 @@ func (e *Explorer) initPersist() error {
 		}{
 			{internalBlockHeight, encoding.Marshal(types.BlockHeight(0))},
 			{internalRecentChange, encoding.Marshal(modules.ConsensusChangeID{})},
+			{internalStats, encoding.Marshal(explorerStats{})},
 		}
 		b := tx.Bucket(bucketInternal)
 		for _, d := range internalDefaults {
@@ -0,0 +1,257 @@
+package explorer
+
+import (
+	"bytes"
+	"math/big"
+
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// The explorer maintains aggregate statistics about the distribution of coins
+// and funds. The supply and the number of addresses are updated together with
+// the address balances. The cumulative statistics like the number of burned
+// coins are updated for every applied and reverted block using the diffs of
+// that block. The statistics are stored in bucketInternal.
+//
+// For every asset there is a rich list bucket which contains a key for every
+// address with a non-zero balance. The key is the balance encoded as a
+// fixed-size big-endian integer followed by the unlock hash, so iterating over
+// the bucket backwards returns the addresses sorted by their balance.
+
+const (
+	// richListValueSize is the size of the balance prefix of a rich list key.
+	richListValueSize = 32
+)
+
+type (
+	// explorerStats are the persisted aggregate statistics of the explorer.
+	explorerStats struct {
+		SiacoinSupply      types.Currency
+		SiafundASupply     types.Currency
+		SiafundBSupply     types.Currency
+		AddressCount       uint64
+		TotalBurned        types.Currency
+		TotalUnburned      types.Currency
+		DevSubsidies       types.Currency
+		DevSubsidiesBurned types.Currency
+		SiafundClaims      types.Currency
+		SiafundBLostClaims types.Currency
+	}
+)
+
+// Stats returns the aggregate statistics of the explorer.
+func (e *Explorer) Stats() modules.ExplorerStats {
+	var stats explorerStats
+	var height types.BlockHeight
+	var burned addressBalance
+	err := e.db.View(func(tx *bolt.Tx) error {
+		if err := dbGetInternal(internalBlockHeight, &height)(tx); err != nil {
+			return err
+		}
+		if err := dbGetInternal(internalStats, &stats)(tx); err != nil {
+			return err
+		}
+		err := dbGetAndDecode(bucketAddressBalances, types.BurnAddressUnlockHash, &burned)(tx)
+		if err == errNotExist {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		build.Critical(err)
+	}
+	return modules.ExplorerStats{
+		Height:             height,
+		SiacoinSupply:      stats.SiacoinSupply,
+		SiafundASupply:     stats.SiafundASupply,
+		SiafundBSupply:     stats.SiafundBSupply,
+		AddressCount:       stats.AddressCount,
+		BurnedSiacoins:     burned.Siacoins,
+		TotalBurned:        stats.TotalBurned,
+		TotalUnburned:      stats.TotalUnburned,
+		DevSubsidies:       stats.DevSubsidies,
+		DevSubsidiesBurned: stats.DevSubsidiesBurned,
+		SiafundClaims:      stats.SiafundClaims,
+		SiafundBLostClaims: stats.SiafundBLostClaims,
+	}
+}
+
+// RichList returns the n addresses with the highest balance of every asset.
+func (e *Explorer) RichList(n uint64) modules.RichList {
+	rl := modules.RichList{
+		Siacoins:  []modules.RichListEntry{},
+		SiafundsA: []modules.RichListEntry{},
+		SiafundsB: []modules.RichListEntry{},
+	}
+	err := e.db.View(func(tx *bolt.Tx) error {
+		for _, list := range []struct {
+			bucket  []byte
+			entries *[]modules.RichListEntry
+		}{
+			{bucketRichListSiacoins, &rl.Siacoins},
+			{bucketRichListSiafundsA, &rl.SiafundsA},
+			{bucketRichListSiafundsB, &rl.SiafundsB},
+		} {
+			c := tx.Bucket(list.bucket).Cursor()
+			for k, _ := c.Last(); k != nil && uint64(len(*list.entries)) < n; k, _ = c.Prev() {
+				*list.entries = append(*list.entries, decodeRichListKey(k))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		build.Critical(err)
+	}
+	return rl
+}
+
+// richListKey returns the rich list key of an address with the given balance.
+func richListKey(value types.Currency, uh types.UnlockHash) []byte {
+	key := make([]byte, richListValueSize+len(uh))
+	value.Big().FillBytes(key[:richListValueSize])
+	copy(key[richListValueSize:], uh[:])
+	return key
+}
+
+// decodeRichListKey decodes a rich list key into a rich list entry.
+func decodeRichListKey(key []byte) modules.RichListEntry {
+	var entry modules.RichListEntry
+	entry.Value = types.NewCurrency(new(big.Int).SetBytes(key[:richListValueSize]))
+	copy(entry.UnlockHash[:], key[richListValueSize:])
+	return entry
+}
+
+// blockStats returns the siacoins which were sent to and spent from the burn
+// address by a block and the siafund claims which were paid out by it. dir is
+// the direction of the diffs which created outputs, which is DiffApply for
+// applied blocks and DiffRevert for reverted blocks.
+func blockStats(block types.Block, diffs modules.ConsensusChangeDiffs, dir modules.DiffDirection) (burned, unburned, claims, lostClaims types.Currency) {
+	for _, scod := range diffs.SiacoinOutputDiffs {
+		if scod.SiacoinOutput.UnlockHash != types.BurnAddressUnlockHash {
+			continue
+		}
+		if scod.Direction == dir {
+			burned = burned.Add(scod.SiacoinOutput.Value)
+		} else {
+			unburned = unburned.Add(scod.SiacoinOutput.Value)
+		}
+	}
+
+	// The claims of the siafund inputs are paid out as delayed outputs.
+	// SPF-B inputs have a second output for the part of the claim which is
+	// lost.
+	claimIDs := make(map[types.SiacoinOutputID]bool)
+	for _, txn := range block.Transactions {
+		for _, sfi := range txn.SiafundInputs {
+			claimIDs[sfi.ParentID.SiaClaimOutputID()] = false
+			claimIDs[sfi.ParentID.SiaClaimSecondOutputID()] = true
+		}
+	}
+	for _, dscod := range diffs.DelayedSiacoinOutputDiffs {
+		lost, exists := claimIDs[dscod.ID]
+		if !exists || dscod.Direction != dir {
+			continue
+		}
+		if lost {
+			lostClaims = lostClaims.Add(dscod.SiacoinOutput.Value)
+		} else {
+			claims = claims.Add(dscod.SiacoinOutput.Value)
+		}
+	}
+	return
+}
+
+// These functions panic on error. The panic will be caught by
+// ProcessConsensusChange.
+
+// dbGetStats returns the aggregate statistics.
+func dbGetStats(tx *bolt.Tx) explorerStats {
+	var stats explorerStats
+	assertNil(dbGetInternal(internalStats, &stats)(tx))
+	return stats
+}
+
+// dbPutStats updates the aggregate statistics.
+func dbPutStats(tx *bolt.Tx, stats explorerStats) {
+	assertNil(dbSetInternal(internalStats, stats)(tx))
+}
+
+// dbUpdateBalanceStats updates the rich lists, the supply and the number of
+// addresses after the balance of an address changed from old to new.
+func dbUpdateBalanceStats(tx *bolt.Tx, uh types.UnlockHash, old, new addressBalance) {
+	for _, list := range []struct {
+		bucket   []byte
+		old, new types.Currency
+	}{
+		{bucketRichListSiacoins, old.Siacoins, new.Siacoins},
+		{bucketRichListSiafundsA, old.SiafundsA, new.SiafundsA},
+		{bucketRichListSiafundsB, old.SiafundsB, new.SiafundsB},
+	} {
+		oldKey, newKey := richListKey(list.old, uh), richListKey(list.new, uh)
+		if bytes.Equal(oldKey, newKey) {
+			continue
+		}
+		b := tx.Bucket(list.bucket)
+		if !list.old.IsZero() {
+			assertNil(b.Delete(oldKey))
+		}
+		if !list.new.IsZero() {
+			assertNil(b.Put(newKey, nil))
+		}
+	}
+
+	stats := dbGetStats(tx)
+	stats.SiacoinSupply = stats.SiacoinSupply.Add(new.Siacoins).Sub(old.Siacoins)
+	stats.SiafundASupply = stats.SiafundASupply.Add(new.SiafundsA).Sub(old.SiafundsA)
+	stats.SiafundBSupply = stats.SiafundBSupply.Add(new.SiafundsB).Sub(old.SiafundsB)
+	oldExists := old.SiacoinOutputCount != 0 || old.SiafundOutputCount != 0
+	newExists := new.SiacoinOutputCount != 0 || new.SiafundOutputCount != 0
+	if newExists && !oldExists {
+		stats.AddressCount++
+	} else if oldExists && !newExists {
+		stats.AddressCount--
+	}
+	dbPutStats(tx, stats)
+}
+
+// dbApplyBlockStats adds the cumulative statistics of an applied block.
+func dbApplyBlockStats(tx *bolt.Tx, block types.Block, diffs modules.ConsensusChangeDiffs, height types.BlockHeight) {
+	burned, unburned, claims, lostClaims := blockStats(block, diffs, modules.DiffApply)
+	stats := dbGetStats(tx)
+	stats.TotalBurned = stats.TotalBurned.Add(burned)
+	stats.TotalUnburned = stats.TotalUnburned.Add(unburned)
+	stats.SiafundClaims = stats.SiafundClaims.Add(claims)
+	stats.SiafundBLostClaims = stats.SiafundBLostClaims.Add(lostClaims)
+	devSubsidy := types.CalculateDevSubsidy(height)
+	stats.DevSubsidies = stats.DevSubsidies.Add(devSubsidy)
+	if devSubsidyBurned(height) {
+		stats.DevSubsidiesBurned = stats.DevSubsidiesBurned.Add(devSubsidy)
+	}
+	dbPutStats(tx, stats)
+}
+
+// dbRevertBlockStats removes the cumulative statistics of a reverted block.
+func dbRevertBlockStats(tx *bolt.Tx, block types.Block, diffs modules.ConsensusChangeDiffs, height types.BlockHeight) {
+	burned, unburned, claims, lostClaims := blockStats(block, diffs, modules.DiffRevert)
+	stats := dbGetStats(tx)
+	stats.TotalBurned = stats.TotalBurned.Sub(burned)
+	stats.TotalUnburned = stats.TotalUnburned.Sub(unburned)
+	stats.SiafundClaims = stats.SiafundClaims.Sub(claims)
+	stats.SiafundBLostClaims = stats.SiafundBLostClaims.Sub(lostClaims)
+	devSubsidy := types.CalculateDevSubsidy(height)
+	stats.DevSubsidies = stats.DevSubsidies.Sub(devSubsidy)
+	if devSubsidyBurned(height) {
+		stats.DevSubsidiesBurned = stats.DevSubsidiesBurned.Sub(devSubsidy)
+	}
+	dbPutStats(tx, stats)
+}
+
+// devSubsidyBurned returns true if the dev subsidy of a block at the given
+// height is sent to the burn address.
+func devSubsidyBurned(height types.BlockHeight) bool {
+	return types.BurnAddressBlockHeight != 0 && height >= types.BurnAddressBlockHeight
+}
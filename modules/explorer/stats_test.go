@@ -0,0 +1,112 @@
+package explorer
+
+import (
+	"reflect"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestExplorerStats checks that the aggregate statistics and the rich lists
+// match the address index.
+func TestExplorerStats(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	et, err := createExplorerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Burn some coins. Depending on the height, the dev subsidies are burned
+	// as well.
+	before := et.explorer.Stats()
+	amount := types.SiacoinPrecision.Mul64(100)
+	if _, err := et.wallet.SendSiacoins(amount, types.BurnAddressUnlockHash); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := et.miner.FindBlock()
+	if err := et.cs.AcceptBlock(b); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := et.explorer.Stats()
+	if stats.Height != et.cs.Height() {
+		t.Fatal("wrong height", stats.Height, et.cs.Height())
+	}
+	if stats.TotalBurned.Sub(before.TotalBurned).Cmp(amount) < 0 {
+		t.Fatal("burned coins weren't counted", stats.TotalBurned, before.TotalBurned)
+	}
+	if !stats.BurnedSiacoins.Equals(stats.TotalBurned.Sub(stats.TotalUnburned)) {
+		t.Fatal("wrong burn stats", stats.BurnedSiacoins, stats.TotalBurned, stats.TotalUnburned)
+	}
+	if ab := et.explorer.AddressBalance(types.BurnAddressUnlockHash); !ab.Siacoins.Equals(stats.BurnedSiacoins) {
+		t.Fatal("wrong burned siacoins", stats.BurnedSiacoins, ab.Siacoins)
+	}
+	var devSubsidies types.Currency
+	for height := types.BlockHeight(0); height <= stats.Height; height++ {
+		devSubsidies = devSubsidies.Add(types.CalculateDevSubsidy(height))
+	}
+	if !stats.DevSubsidies.Equals(devSubsidies) {
+		t.Fatal("wrong dev subsidies", stats.DevSubsidies, devSubsidies)
+	}
+
+	// The rich lists should contain every address with a balance, sorted by
+	// the balance, and add up to the supplies.
+	rl := et.explorer.RichList(stats.AddressCount)
+	lists := []struct {
+		name    string
+		entries []modules.RichListEntry
+		supply  types.Currency
+		balance func(modules.AddressBalance) types.Currency
+	}{
+		{"siacoins", rl.Siacoins, stats.SiacoinSupply, func(ab modules.AddressBalance) types.Currency { return ab.Siacoins }},
+		{"siafunds a", rl.SiafundsA, stats.SiafundASupply, func(ab modules.AddressBalance) types.Currency { return ab.SiafundsA }},
+		{"siafunds b", rl.SiafundsB, stats.SiafundBSupply, func(ab modules.AddressBalance) types.Currency { return ab.SiafundsB }},
+	}
+	for _, list := range lists {
+		var sum types.Currency
+		for i, entry := range list.entries {
+			if entry.Value.IsZero() {
+				t.Fatalf("%v: rich list contains address without balance", list.name)
+			}
+			if i > 0 && list.entries[i-1].Value.Cmp(entry.Value) < 0 {
+				t.Fatalf("%v: rich list isn't sorted", list.name)
+			}
+			if b := list.balance(et.explorer.AddressBalance(entry.UnlockHash)); !b.Equals(entry.Value) {
+				t.Fatalf("%v: wrong balance in rich list: %v != %v", list.name, entry.Value, b)
+			}
+			sum = sum.Add(entry.Value)
+		}
+		if !sum.Equals(list.supply) {
+			t.Fatalf("%v: rich list doesn't add up to the supply: %v != %v", list.name, sum, list.supply)
+		}
+	}
+	if stats.SiacoinSupply.IsZero() || len(rl.Siacoins) < 2 {
+		t.Fatal("expected multiple addresses with siacoins", len(rl.Siacoins))
+	}
+	if top := et.explorer.RichList(1); len(top.Siacoins) != 1 || top.Siacoins[0].UnlockHash != rl.Siacoins[0].UnlockHash {
+		t.Fatal("wrong top holder", top.Siacoins)
+	}
+
+	// Dropping a rich list should rebuild the explorer with the same stats.
+	err = et.explorer.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(bucketRichListSiacoins)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := et.explorer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	et.explorer, err = New(et.cs, et.explorer.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt := et.explorer.Stats(); !reflect.DeepEqual(rebuilt, stats) {
+		t.Fatal("stats changed after rebuild", rebuilt, stats)
+	}
+}
@@ -32,12 +32,18 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 		if err != nil {
 			return err
 		}
+		if cc.SkipsBlocks(blockheight) {
+			return errSkippedBlocks
+		}
 
 		// Update cumulative stats for reverted blocks.
-		for _, block := range cc.RevertedBlocks {
+		for i, block := range cc.RevertedBlocks {
 			bid := block.ID()
 			tbid := types.TransactionID(bid)
 
+			if i < len(cc.RevertedDiffs) {
+				dbRevertBlockStats(tx, block, cc.RevertedDiffs[i], blockheight)
+			}
 			blockheight--
 			dbRemoveBlockID(tx, bid)
 			dbRemoveTransactionID(tx, tbid) // Miner payouts are a transaction
@@ -123,17 +129,23 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 		}
 
 		// Update cumulative stats for applied blocks.
-		for _, block := range cc.AppliedBlocks {
+		for i, block := range cc.AppliedBlocks {
 			bid := block.ID()
 			tbid := types.TransactionID(bid)
 
 			// special handling for genesis block
 			if bid == types.GenesisID {
 				dbAddGenesisBlock(tx)
+				if i < len(cc.AppliedDiffs) {
+					dbApplyBlockStats(tx, block, cc.AppliedDiffs[i], 0)
+				}
 				continue
 			}
 
 			blockheight++
+			if i < len(cc.AppliedDiffs) {
+				dbApplyBlockStats(tx, block, cc.AppliedDiffs[i], blockheight)
+			}
 			dbAddBlockID(tx, bid, blockheight)
 			dbAddTransactionID(tx, tbid, blockheight) // Miner payouts are a transaction
 
@@ -220,17 +232,25 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			}
 		}
 
-		// Update stats according to SiacoinOutputDiffs
+		// Update stats and address balances according to SiacoinOutputDiffs
 		for _, scod := range cc.SiacoinOutputDiffs {
 			if scod.Direction == modules.DiffApply {
 				dbAddSiacoinOutput(tx, scod.ID, scod.SiacoinOutput)
+				dbAddAddressSiacoinOutput(tx, scod.ID, scod.SiacoinOutput)
+			} else {
+				dbRemoveAddressSiacoinOutput(tx, scod.ID, scod.SiacoinOutput.UnlockHash)
 			}
 		}
 
-		// Update stats according to SiafundOutputDiffs
+		// Update stats and address balances according to SiafundOutputDiffs
 		for _, sfod := range cc.SiafundOutputDiffs {
 			if sfod.Direction == modules.DiffApply {
 				dbAddSiafundOutput(tx, sfod.ID, sfod.SiafundOutput)
+				isB, err := e.cs.IsSiafundBOutput(sfod.ID)
+				assertNil(err)
+				dbAddAddressSiafundOutput(tx, sfod.ID, sfod.SiafundOutput, isB)
+			} else {
+				dbRemoveAddressSiafundOutput(tx, sfod.ID, sfod.SiafundOutput.UnlockHash)
 			}
 		}
 
@@ -36,6 +36,24 @@ var (
 	}).([]NetAddress)
 )
 
+var (
+	// PenaltyInvalidBlock is applied to peers that relay blocks or headers
+	// which fail validation. A single invalid block is enough to ban a peer.
+	PenaltyInvalidBlock = PeerPenalty{Reason: "invalid block", Score: 100}
+
+	// PenaltyInvalidTransaction is applied to peers that relay transaction
+	// sets which are invalid on their own.
+	PenaltyInvalidTransaction = PeerPenalty{Reason: "invalid transaction", Score: 10}
+
+	// PenaltyProtocolViolation is applied to peers that send malformed
+	// requests.
+	PenaltyProtocolViolation = PeerPenalty{Reason: "protocol violation", Score: 20}
+
+	// PenaltyBandwidthAbuse is applied to peers that send objects exceeding
+	// the size allowed by an RPC.
+	PenaltyBandwidthAbuse = PeerPenalty{Reason: "bandwidth abuse", Score: 20}
+)
+
 type (
 	// Peer contains all the info necessary to Broadcast to a peer.
 	Peer struct {
@@ -45,6 +63,21 @@ type (
 		Version    string     `json:"version"`
 	}
 
+	// PeerPenalty describes misbehavior of a peer. The score of a peer is the
+	// decaying sum of its penalties, and peers whose score exceeds the ban
+	// threshold of the gateway are temporarily banned.
+	PeerPenalty struct {
+		Reason string
+		Score  float64
+	}
+
+	// PeerBan is a temporary ban of a host which misbehaved.
+	PeerBan struct {
+		Host   string    `json:"host"`
+		Reason string    `json:"reason"`
+		Expiry time.Time `json:"expiry"`
+	}
+
 	// A PeerConn is the connection type used when communicating with peers during
 	// an RPC. It is identical to a net.Conn with the additional RPCAddr method.
 	// This method acts as an identifier for peers and is the address that the
@@ -111,6 +144,14 @@ type (
 		// Address returns the Gateway's address.
 		Address() NetAddress
 
+		// PenalizePeer raises the score of the peer with the given address.
+		// Peers whose score exceeds the ban threshold are disconnected and
+		// temporarily banned.
+		PenalizePeer(NetAddress, PeerPenalty)
+
+		// PeerBans returns the active temporary bans of the gateway.
+		PeerBans() ([]PeerBan, error)
+
 		// Peers returns the addresses that the Gateway is currently connected
 		// to.
 		Peers() []Peer
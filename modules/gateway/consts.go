@@ -40,6 +40,10 @@ const (
 	// codebase were made that weren't backwards compatible. This might include
 	// changes to the protocol or hardforks.
 	minimumAcceptablePeerVersion = "1.8.0"
+
+	// peerBanThreshold is the score at which a peer is banned. Peer scores
+	// are raised by the penalties defined in the modules package.
+	peerBanThreshold = 100
 )
 
 var (
@@ -222,6 +226,24 @@ var (
 	}).(time.Duration)
 )
 
+var (
+	// peerBanDuration defines how long a peer whose score reached
+	// peerBanThreshold stays banned.
+	peerBanDuration = build.Select(build.Var{
+		Standard: 24 * time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  time.Minute,
+	}).(time.Duration)
+
+	// peerScoreHalfLife defines the time after which the score of a peer has
+	// decayed to half of its value.
+	peerScoreHalfLife = build.Select(build.Var{
+		Standard: time.Hour,
+		Dev:      5 * time.Minute,
+		Testing:  10 * time.Second,
+	}).(time.Duration)
+)
+
 var (
 	// minPeersForIPDiscovery is the minimum number of peer connections we wait
 	// for before we try to discover our public ip from them. It is also the
@@ -127,6 +127,10 @@ type Gateway struct {
 
 	// blocklist are peers that the gateway shouldn't connect to
 	//
+	// scores are the misbehavior scores of peers, and bans are the peers
+	// which are temporarily banned because their score got too high. Both are
+	// keyed by scoreKey.
+	//
 	// nodes is the set of all known nodes (i.e. potential peers).
 	//
 	// peers are the nodes that the gateway is currently connected to.
@@ -143,6 +147,8 @@ type Gateway struct {
 	// added which handles clean-shutdown for the peers, without blocking
 	// threads.Flush() calls.
 	blocklist map[string]struct{}
+	scores    map[string]peerScore
+	bans      map[string]modules.PeerBan
 	nodes     map[modules.NetAddress]*node
 	peers     map[modules.NetAddress]*peer
 	peerTG    threadgroup.ThreadGroup
@@ -371,6 +377,8 @@ func NewCustomGateway(addr string, bootstrap bool, persistDir string, deps modul
 		initRPCs: make(map[string]modules.RPCFunc),
 
 		blocklist: make(map[string]struct{}),
+		scores:    make(map[string]peerScore),
+		bans:      make(map[string]modules.PeerBan),
 		nodes:     make(map[modules.NetAddress]*node),
 		peers:     make(map[modules.NetAddress]*peer),
 
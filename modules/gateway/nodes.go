@@ -28,6 +28,15 @@ type node struct {
 	WasOutboundPeer bool               `json:"wasoutboundpeer"`
 }
 
+// isIPOrOnion returns true if the host of addr is an IP address, or a .onion
+// address while onion addresses are allowed.
+func isIPOrOnion(addr modules.NetAddress) bool {
+	if addr.IsOnion() {
+		return modules.GlobalProxy.AllowOnion()
+	}
+	return net.ParseIP(addr.Host()) != nil
+}
+
 // addNode adds an address to the set of nodes on the network.
 func (g *Gateway) addNode(addr modules.NetAddress) error {
 	if addr == g.myAddr {
@@ -36,7 +45,7 @@ func (g *Gateway) addNode(addr modules.NetAddress) error {
 		return errNodeExists
 	} else if addr.IsStdValid() != nil {
 		return errors.New("address is not valid: " + string(addr))
-	} else if net.ParseIP(addr.Host()) == nil {
+	} else if !isIPOrOnion(addr) {
 		return errors.New("address must be an IP address: " + string(addr))
 	}
 	g.nodes[addr] = &node{
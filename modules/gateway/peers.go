@@ -17,6 +17,7 @@ import (
 )
 
 var (
+	errPeerBanned       = errors.New("peer is temporarily banned")
 	errPeerExists       = errors.New("already connected to this peer")
 	errPeerRejectedConn = errors.New("peer rejected connection")
 )
@@ -165,6 +166,14 @@ func (g *Gateway) threadedAcceptConn(conn net.Conn) {
 		conn.Close()
 		return
 	}
+	g.mu.RLock()
+	banned := g.isBanned(addr)
+	g.mu.RUnlock()
+	if banned {
+		g.log.Debugf("INFO: %v was rejected. (banned)", addr)
+		conn.Close()
+		return
+	}
 	remoteVersion, err := acceptVersionHandshake(conn, build.Version)
 	if err != nil {
 		g.log.Debugf("INFO: %v wanted to connect but version handshake failed: %v", addr, err)
@@ -220,10 +229,6 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 		g.log.Debugln("Unable to Accept Connection with Peer. Conn, err:", conn.RemoteAddr(), conn.LocalAddr(), err)
 		return err
 	}
-	if err := exchangeOurHeader(conn, ourHeader); err != nil {
-		g.log.Debugln("Unable to Accept Connection with Peer. Conn, err:", conn.RemoteAddr(), conn.LocalAddr(), err)
-		return err
-	}
 
 	// Get the remote address on which the connecting peer is listening on.
 	// This means we need to combine the incoming connections ip address with
@@ -231,6 +236,20 @@ func (g *Gateway) managedAcceptConnPeer(conn net.Conn, remoteVersion string) err
 	remoteIP := modules.NetAddress(conn.RemoteAddr().String()).Host()
 	remotePort := remoteHeader.NetAddress.Port()
 	remoteAddr := modules.NetAddress(net.JoinHostPort(remoteIP, remotePort))
+
+	// Reject banned peers before sending our header, so that they don't
+	// consider themselves connected.
+	g.mu.RLock()
+	banned := g.isBanned(remoteAddr)
+	g.mu.RUnlock()
+	if banned {
+		g.log.Debugln("Unable to Accept Connection with Peer. Conn, err:", conn.RemoteAddr(), conn.LocalAddr(), errPeerBanned)
+		return errPeerBanned
+	}
+	if err := exchangeOurHeader(conn, ourHeader); err != nil {
+		g.log.Debugln("Unable to Accept Connection with Peer. Conn, err:", conn.RemoteAddr(), conn.LocalAddr(), err)
+		return err
+	}
 	g.log.Debugln("Making connection with remote peer", remoteAddr)
 
 	// Accept the peer.
@@ -446,7 +465,7 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 		g.log.Debugln("Unable to connect to", addr, "error:", err)
 		return err
 	}
-	if net.ParseIP(addr.Host()) == nil {
+	if !isIPOrOnion(addr) {
 		err := errors.New("address must be an IP address")
 		g.log.Debugln("Unable to connect to", addr, "error:", err)
 		return err
@@ -457,6 +476,13 @@ func (g *Gateway) managedConnect(addr modules.NetAddress) error {
 		return err
 	}
 	g.mu.RLock()
+	banned := g.isBanned(addr)
+	g.mu.RUnlock()
+	if banned {
+		g.log.Debugln("Unable to connect to", addr, "error:", errPeerBanned)
+		return errPeerBanned
+	}
+	g.mu.RLock()
 	_, exists := g.peers[addr]
 	g.mu.RUnlock()
 	if exists {
@@ -565,7 +591,7 @@ func (g *Gateway) Disconnect(addr modules.NetAddress) error {
 
 // ConnectManual is a wrapper for the Connect function. It is specifically used
 // if a user wants to connect to a node manually. This also removes the node
-// from the blocklist.
+// from the blocklist and lifts its ban.
 func (g *Gateway) ConnectManual(addr modules.NetAddress) error {
 	g.log.Debugln("Attempting to Manually Connect to", addr)
 	g.mu.Lock()
@@ -575,6 +601,11 @@ func (g *Gateway) ConnectManual(addr modules.NetAddress) error {
 		delete(g.blocklist, addr.Host())
 		err = g.saveSync()
 	}
+	if g.isBanned(addr) {
+		g.log.Debugln("Lifting the ban of", addr, "due to Manually trying to Connect")
+		delete(g.bans, scoreKey(addr))
+		err = build.ComposeErrors(err, g.saveSync())
+	}
 	g.mu.Unlock()
 	return build.ComposeErrors(err, g.Connect(addr))
 }
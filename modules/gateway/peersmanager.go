@@ -1,6 +1,9 @@
 package gateway
 
 import (
+	"sort"
+	"time"
+
 	"gitlab.com/NebulousLabs/fastrand"
 
 	"gitlab.com/scpcorp/ScPrime/build"
@@ -160,7 +163,8 @@ func (g *Gateway) permanentPeerManager(closedChan chan struct{}) {
 }
 
 // buildPeerManagerNodeList returns the gateway's node list in the order that
-// permanentPeerManager should attempt to connect to them.
+// permanentPeerManager should attempt to connect to them. Banned nodes are
+// left out and nodes with lower scores are tried first.
 func (g *Gateway) buildPeerManagerNodeList() []modules.NetAddress {
 	// flatten the node map, inserting in random order
 	nodes := make([]modules.NetAddress, len(g.nodes))
@@ -170,6 +174,15 @@ func (g *Gateway) buildPeerManagerNodeList() []modules.NetAddress {
 		perm = perm[1:]
 	}
 
+	// remove the banned nodes
+	unbanned := nodes[:0]
+	for _, node := range nodes {
+		if !g.isBanned(node) {
+			unbanned = append(unbanned, node)
+		}
+	}
+	nodes = unbanned
+
 	// swap the outbound nodes to the front of the list
 	numOutbound := 0
 	for i, node := range nodes {
@@ -178,5 +191,11 @@ func (g *Gateway) buildPeerManagerNodeList() []modules.NetAddress {
 			numOutbound++
 		}
 	}
+
+	// order the nodes by score, keeping the order of nodes with equal scores
+	now := time.Now()
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return g.score(nodes[i], now) < g.score(nodes[j], now)
+	})
 	return nodes
 }
@@ -37,6 +37,9 @@ type (
 
 		// blocklisted IPs
 		Blocklist []string
+
+		// temporarily banned peers
+		Bans []modules.PeerBan
 	}
 )
 
@@ -94,6 +97,13 @@ func (g *Gateway) load() error {
 	for _, ip := range g.persist.Blocklist {
 		g.blocklist[ip] = struct{}{}
 	}
+	// create map from bans, dropping the expired ones
+	now := time.Now()
+	for _, ban := range g.persist.Bans {
+		if now.Before(ban.Expiry) {
+			g.bans[ban.Host] = ban
+		}
+	}
 	return nil
 }
 
@@ -104,6 +114,11 @@ func (g *Gateway) saveSync() error {
 	for ip := range g.blocklist {
 		g.persist.Blocklist = append(g.persist.Blocklist, ip)
 	}
+	g.pruneScores()
+	g.persist.Bans = make([]modules.PeerBan, 0, len(g.bans))
+	for _, ban := range g.bans {
+		g.persist.Bans = append(g.persist.Bans, ban)
+	}
 	return persist.SaveJSON(persistMetadata, g.persist, filepath.Join(g.persistDir, persistFilename))
 }
 
@@ -130,6 +145,7 @@ func (g *Gateway) threadedSaveLoop() {
 			defer g.threads.Done()
 
 			g.mu.Lock()
+			g.pruneScores()
 			err = g.saveSyncNodes()
 			g.mu.Unlock()
 			if err != nil {
@@ -210,6 +210,9 @@ func (g *Gateway) threadedHandleConn(conn modules.PeerConn) {
 		return
 	}
 	if err := encoding.ReadObject(conn, &id, 8); err != nil {
+		if isOversized(err) {
+			g.PenalizePeer(conn.RPCAddr(), modules.PenaltyProtocolViolation)
+		}
 		return
 	}
 	// call registered handler for this ID
@@ -232,6 +235,9 @@ func (g *Gateway) threadedHandleConn(conn modules.PeerConn) {
 	if err != nil {
 		g.log.Debugf("WARN: incoming RPC \"%v\" from conn %v failed: %v", id, conn.RPCAddr(), err)
 	}
+	if isOversized(err) {
+		g.PenalizePeer(conn.RPCAddr(), modules.PenaltyBandwidthAbuse)
+	}
 	// Log the amount of time it took the handler to do the RPC.
 	g.log.Debugf("%s RPC time: %v", id, time.Since(startRPCTime).Round(time.Millisecond))
 }
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+)
+
+// peerScore is the misbehavior score of a peer. The score decays over time,
+// halving every peerScoreHalfLife.
+type peerScore struct {
+	score   float64
+	updated time.Time
+}
+
+// at returns the decayed score at time t.
+func (ps peerScore) at(t time.Time) float64 {
+	elapsed := t.Sub(ps.updated)
+	if elapsed <= 0 {
+		return ps.score
+	}
+	return ps.score * math.Exp2(-float64(elapsed)/float64(peerScoreHalfLife))
+}
+
+// scoreKey returns the key under which the score and the ban of addr are
+// tracked. Peers are identified by their host like in the blocklist, except
+// for local peers which commonly share a host and are told apart by port. A
+// per-host key is intended: a peer can pick any port, so a per-address ban
+// could be evaded by reconnecting on a different one. Peers behind the same
+// NAT share a ban, which is why only invalid data and protocol violations
+// that a peer actually sent are penalized, never timeouts.
+func scoreKey(addr modules.NetAddress) string {
+	if addr.IsLocal() {
+		return string(addr)
+	}
+	return addr.Host()
+}
+
+// isOversized returns true if err was caused by a peer sending an object
+// which exceeds the size allowed by the reader. The encoding package doesn't
+// export an error for this case.
+func isOversized(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "exceeds maxLen")
+}
+
+// isBanned returns true if addr is banned.
+func (g *Gateway) isBanned(addr modules.NetAddress) bool {
+	ban, exists := g.bans[scoreKey(addr)]
+	return exists && time.Now().Before(ban.Expiry)
+}
+
+// score returns the current score of addr.
+func (g *Gateway) score(addr modules.NetAddress, now time.Time) float64 {
+	ps, exists := g.scores[scoreKey(addr)]
+	if !exists {
+		return 0
+	}
+	return ps.at(now)
+}
+
+// penalizePeer adds the penalty to the score of addr and bans it if its score
+// reaches peerBanThreshold. Banned peers are disconnected but stay in the node
+// list, which skips them until the ban expires.
+func (g *Gateway) penalizePeer(addr modules.NetAddress, penalty modules.PeerPenalty) error {
+	if g.isBanned(addr) {
+		return nil
+	}
+	now := time.Now()
+	key := scoreKey(addr)
+	ps := g.scores[key]
+	ps.score = ps.at(now) + penalty.Score
+	ps.updated = now
+	g.scores[key] = ps
+	g.log.Debugf("INFO: peer %v was penalized for %v, score is now %.2f", addr, penalty.Reason, ps.score)
+	if ps.score < peerBanThreshold {
+		return nil
+	}
+
+	// Ban the peer and disconnect from it.
+	delete(g.scores, key)
+	g.bans[key] = modules.PeerBan{
+		Host:   key,
+		Reason: penalty.Reason,
+		Expiry: now.Add(peerBanDuration),
+	}
+	g.log.Printf("INFO: banned %v until %v: %v", key, now.Add(peerBanDuration).Format(time.RFC3339), penalty.Reason)
+	var err error
+	for peerAddr, peer := range g.peers {
+		if scoreKey(peerAddr) == key {
+			err = errors.Compose(err, peer.sess.Close())
+			delete(g.peers, peerAddr)
+		}
+	}
+	return errors.Compose(err, g.saveSync())
+}
+
+// pruneScores removes expired bans and scores which have decayed to
+// insignificance.
+func (g *Gateway) pruneScores() {
+	now := time.Now()
+	for key, ban := range g.bans {
+		if !now.Before(ban.Expiry) {
+			delete(g.bans, key)
+		}
+	}
+	for key, ps := range g.scores {
+		if ps.at(now) < 1 {
+			delete(g.scores, key)
+		}
+	}
+}
+
+// PenalizePeer raises the score of the peer with the given address. Peers
+// whose score reaches peerBanThreshold are disconnected and banned for
+// peerBanDuration.
+func (g *Gateway) PenalizePeer(addr modules.NetAddress, penalty modules.PeerPenalty) {
+	if err := g.threads.Add(); err != nil {
+		return
+	}
+	defer g.threads.Done()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.penalizePeer(addr, penalty); err != nil {
+		g.log.Println("WARN: failed to ban peer:", err)
+	}
+}
+
+// PeerBans returns the active bans of the gateway.
+func (g *Gateway) PeerBans() ([]modules.PeerBan, error) {
+	if err := g.threads.Add(); err != nil {
+		return nil, err
+	}
+	defer g.threads.Done()
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	now := time.Now()
+	bans := make([]modules.PeerBan, 0, len(g.bans))
+	for _, ban := range g.bans {
+		if now.Before(ban.Expiry) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Host < bans[j].Host
+	})
+	return bans, nil
+}
@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+)
+
+// TestPeerScoreDecay checks that peer scores halve every peerScoreHalfLife.
+func TestPeerScoreDecay(t *testing.T) {
+	now := time.Now()
+	ps := peerScore{score: 80, updated: now}
+	if ps.at(now) != 80 {
+		t.Fatal("score decayed without time passing:", ps.at(now))
+	}
+	if s := ps.at(now.Add(peerScoreHalfLife)); math.Abs(s-40) > 1e-9 {
+		t.Fatal("expected score 40 after one half-life, got", s)
+	}
+	if s := ps.at(now.Add(2 * peerScoreHalfLife)); math.Abs(s-20) > 1e-9 {
+		t.Fatal("expected score 20 after two half-lives, got", s)
+	}
+}
+
+// TestBuildPeerManagerNodeListScores checks that the peer manager skips
+// banned nodes and tries nodes with lower scores first.
+func TestBuildPeerManagerNodeListScores(t *testing.T) {
+	now := time.Now()
+	g := &Gateway{
+		nodes: map[modules.NetAddress]*node{
+			"1.1.1.1:4281": {NetAddress: "1.1.1.1:4281", WasOutboundPeer: true},
+			"2.2.2.2:4281": {NetAddress: "2.2.2.2:4281", WasOutboundPeer: false},
+			"3.3.3.3:4281": {NetAddress: "3.3.3.3:4281", WasOutboundPeer: true},
+			"4.4.4.4:4281": {NetAddress: "4.4.4.4:4281", WasOutboundPeer: false},
+		},
+		scores: map[string]peerScore{
+			"1.1.1.1": {score: 50, updated: now},
+		},
+		bans: map[string]modules.PeerBan{
+			"4.4.4.4": {Host: "4.4.4.4", Expiry: now.Add(time.Hour)},
+		},
+	}
+	for i := 0; i < 10; i++ {
+		nodelist := g.buildPeerManagerNodeList()
+		if len(nodelist) != 3 {
+			t.Fatal("expected the banned node to be skipped:", nodelist)
+		}
+		if nodelist[0] != "3.3.3.3:4281" || nodelist[2] != "1.1.1.1:4281" {
+			t.Fatal("bad nodelist:", nodelist)
+		}
+	}
+
+	// Expired bans are ignored.
+	g.bans["4.4.4.4"] = modules.PeerBan{Host: "4.4.4.4", Expiry: now.Add(-time.Second)}
+	if nodelist := g.buildPeerManagerNodeList(); len(nodelist) != 4 {
+		t.Fatal("expected the node with the expired ban in the list:", nodelist)
+	}
+}
+
+// TestPenalizePeer checks that peers are banned once their score reaches
+// the ban threshold and that the bans are persisted.
+func TestPenalizePeer(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	g1 := newNamedTestingGateway(t, "1")
+	defer g1.Close()
+	g2 := newNamedTestingGateway(t, "2")
+	defer g2.Close()
+	if err := connectToNode(g1, g2, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Minor penalties don't ban the peer.
+	g1.PenalizePeer(g2.Address(), modules.PenaltyInvalidTransaction)
+	if bans, err := g1.PeerBans(); err != nil || len(bans) != 0 {
+		t.Fatal("expected no bans, got", bans, err)
+	}
+	if len(g1.Peers()) != 1 {
+		t.Fatal("peer was disconnected")
+	}
+
+	// Relaying an invalid block gets the peer banned and disconnected.
+	g1.PenalizePeer(g2.Address(), modules.PenaltyInvalidBlock)
+	bans, err := g1.PeerBans()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bans) != 1 || bans[0].Host != string(g2.Address()) || bans[0].Reason != modules.PenaltyInvalidBlock.Reason {
+		t.Fatal("unexpected bans:", bans)
+	}
+	err = build.Retry(50, 100*time.Millisecond, func() error {
+		if len(g1.Peers()) != 0 || len(g2.Peers()) != 0 {
+			return errPeerExists
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("banned peer wasn't disconnected")
+	}
+
+	// Banned peers can't connect and aren't connected to.
+	if err := g1.Connect(g2.Address()); err != errPeerBanned {
+		t.Fatal("expected errPeerBanned, got", err)
+	}
+	if err := g2.Connect(g1.Address()); err == nil {
+		t.Fatal("banned peer was able to connect")
+	}
+	if len(g1.Peers()) != 0 {
+		t.Fatal("banned peer was accepted")
+	}
+
+	// The ban survives a restart.
+	if err := g1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	g1, err = New(string(g1.myAddr), false, g1.persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g1.Close()
+	if bans, err := g1.PeerBans(); err != nil || len(bans) != 1 || bans[0].Host != string(g2.Address()) {
+		t.Fatal("ban wasn't persisted:", bans, err)
+	}
+
+	// Manually connecting to the peer lifts the ban.
+	if err := connectToNode(g1, g2, true); err != nil {
+		t.Fatal(err)
+	}
+	if bans, err := g1.PeerBans(); err != nil || len(bans) != 0 {
+		t.Fatal("expected the ban to be lifted, got", bans, err)
+	}
+}
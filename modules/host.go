@@ -1,9 +1,11 @@
 package modules
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -536,6 +538,10 @@ func GetHostApiClient(pk types.SiaPublicKey) *http.Client {
 			return http.ErrUseLastResponse
 		},
 		Transport: &http2.Transport{
+			// Dial through the global SOCKS5 proxy if one is set.
+			DialTLSContext: func(ctx context.Context, _, addr string, cfg *tls.Config) (net.Conn, error) {
+				return GlobalProxy.DialTLSContext(ctx, NetAddress(addr), cfg)
+			},
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: true, //nolint:gosec // Not to require root CA signature.
 				VerifyConnection: func(state tls.ConnectionState) error {
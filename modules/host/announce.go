@@ -31,6 +31,14 @@ func (h *Host) staticVerifyAnnouncementAddress(addr modules.NetAddress) error {
 	if addr.IsLocal() && build.Release == "standard" {
 		return errors.New("announcement requested with local net address")
 	}
+	// Onion addresses don't resolve to any IPs but can be announced if they
+	// are allowed.
+	if addr.IsOnion() {
+		if !modules.GlobalProxy.AllowOnion() {
+			return modules.ErrOnionNotAllowed
+		}
+		return nil
+	}
 	// Make sure that the host resolves to 1 or 2 IPs and if it resolves to 2
 	// the type should be different.
 	ips, err := h.dependencies.LookupIP(addr.Host())
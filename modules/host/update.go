@@ -139,6 +139,10 @@ func (h *Host) ProcessConsensusChange(cc modules.ConsensusChange) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	if cc.SkipsBlocks(h.blockHeight) {
+		h.blockHeight = cc.OldHeight
+	}
+
 	// Wrap the whole parsing into a single large database tx to keep things
 	// efficient.
 	var actionItems []types.FileContractID
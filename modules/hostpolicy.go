@@ -0,0 +1,114 @@
+package modules
+
+import (
+	"net"
+	"strings"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+var (
+	// ErrUnknownHostGroup is returned if a host policy references a host
+	// group which is not defined by the policy.
+	ErrUnknownHostGroup = errors.New("host policy references an unknown host group")
+)
+
+type (
+	// HostPolicy restricts the set of hosts the renter is willing to form
+	// contracts with and upload to beyond the hostdb's filter mode. A host
+	// needs to pass the filter mode and the policy to be used.
+	//
+	// A host is rejected if it matches any of the blocked nets, groups or
+	// regions. If any of the allowed lists of a category is not empty, the host
+	// also needs to match at least one entry of that category. Hosts with a
+	// score adjustment below the corresponding non-zero minimum are rejected as
+	// well.
+	HostPolicy struct {
+		// BlockedNets and AllowedNets are CIDR ranges like "10.0.0.0/8".
+		BlockedNets []string `json:"blockednets"`
+		AllowedNets []string `json:"allowednets"`
+
+		// HostGroups are named sets of hosts which can be referenced by the
+		// BlockedGroups and AllowedGroups.
+		HostGroups    map[string][]types.SiaPublicKey `json:"hostgroups"`
+		BlockedGroups []string                        `json:"blockedgroups"`
+		AllowedGroups []string                        `json:"allowedgroups"`
+
+		// BlockedRegions and AllowedRegions are either a country like "DE" or
+		// a country and region like "DE/BY". The regions of the hosts are
+		// looked up in the region data file of the hostdb.
+		BlockedRegions []string `json:"blockedregions"`
+		AllowedRegions []string `json:"allowedregions"`
+
+		// MinScores are the minimum adjustments of a host's score breakdown.
+		MinScores HostScoreThresholds `json:"minscores"`
+	}
+
+	// HostScoreThresholds contains the minimum values of the components of a
+	// HostScoreBreakdown. A zero value disables the threshold.
+	HostScoreThresholds struct {
+		AgeAdjustment              float64 `json:"ageadjustment"`
+		BasePriceAdjustment        float64 `json:"basepriceadjustment"`
+		BurnAdjustment             float64 `json:"burnadjustment"`
+		CollateralAdjustment       float64 `json:"collateraladjustment"`
+		DurationAdjustment         float64 `json:"durationadjustment"`
+		InteractionAdjustment      float64 `json:"interactionadjustment"`
+		LatencyAdjustment          float64 `json:"latencyadjustment"`
+		PriceAdjustment            float64 `json:"priceadjustment"`
+		StorageRemainingAdjustment float64 `json:"storageremainingadjustment"`
+		ThroughputAdjustment       float64 `json:"throughputadjustment"`
+		UptimeAdjustment           float64 `json:"uptimeadjustment"`
+		VersionAdjustment          float64 `json:"versionadjustment"`
+	}
+)
+
+// Empty returns true if the policy doesn't restrict any hosts.
+func (hp HostPolicy) Empty() bool {
+	return len(hp.BlockedNets) == 0 && len(hp.AllowedNets) == 0 &&
+		len(hp.BlockedGroups) == 0 && len(hp.AllowedGroups) == 0 &&
+		len(hp.BlockedRegions) == 0 && len(hp.AllowedRegions) == 0 &&
+		hp.MinScores == HostScoreThresholds{}
+}
+
+// Validate checks the policy for invalid CIDR ranges, references to unknown
+// host groups and malformed regions.
+func (hp HostPolicy) Validate() error {
+	for _, cidr := range append(append([]string{}, hp.BlockedNets...), hp.AllowedNets...) {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return errors.AddContext(err, "invalid CIDR range")
+		}
+	}
+	for _, group := range append(append([]string{}, hp.BlockedGroups...), hp.AllowedGroups...) {
+		if _, exists := hp.HostGroups[group]; !exists {
+			return errors.AddContext(ErrUnknownHostGroup, group)
+		}
+	}
+	for _, region := range append(append([]string{}, hp.BlockedRegions...), hp.AllowedRegions...) {
+		if region == "" || strings.Count(region, "/") > 1 {
+			return errors.New("invalid region: " + region)
+		}
+	}
+	return nil
+}
+
+// Satisfied returns true if all the components of the breakdown are at least
+// as high as the corresponding non-zero thresholds.
+func (hst HostScoreThresholds) Satisfied(sb HostScoreBreakdown) bool {
+	check := func(min, adjustment float64) bool {
+		return min == 0 || adjustment >= min
+	}
+	return check(hst.AgeAdjustment, sb.AgeAdjustment) &&
+		check(hst.BasePriceAdjustment, sb.BasePriceAdjustment) &&
+		check(hst.BurnAdjustment, sb.BurnAdjustment) &&
+		check(hst.CollateralAdjustment, sb.CollateralAdjustment) &&
+		check(hst.DurationAdjustment, sb.DurationAdjustment) &&
+		check(hst.InteractionAdjustment, sb.InteractionAdjustment) &&
+		check(hst.LatencyAdjustment, sb.LatencyAdjustment) &&
+		check(hst.PriceAdjustment, sb.PriceAdjustment) &&
+		check(hst.StorageRemainingAdjustment, sb.StorageRemainingAdjustment) &&
+		check(hst.ThroughputAdjustment, sb.ThroughputAdjustment) &&
+		check(hst.UptimeAdjustment, sb.UptimeAdjustment) &&
+		check(hst.VersionAdjustment, sb.VersionAdjustment)
+}
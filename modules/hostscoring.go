@@ -0,0 +1,101 @@
+package modules
+
+import (
+	"math"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+const (
+	// MaxHostScoringWeight is the largest weight that can be assigned to a
+	// component of the host score. Larger weights would cause the adjustments
+	// to overflow.
+	MaxHostScoringWeight = 2
+)
+
+var (
+	// DefaultHostScoringWeights are the weights used by the hostdb if the
+	// renter didn't configure any custom weights. They don't change the
+	// adjustments and don't impose any price ceilings.
+	DefaultHostScoringWeights = HostScoringWeights{
+		AgeWeight:              1,
+		CollateralWeight:       1,
+		InteractionWeight:      1,
+		LatencyWeight:          1,
+		PriceWeight:            1,
+		StorageRemainingWeight: 1,
+		ThroughputWeight:       1,
+		UptimeWeight:           1,
+		VersionWeight:          1,
+	}
+
+	// ErrInvalidHostScoringWeight is returned if a weight is negative or
+	// larger than MaxHostScoringWeight.
+	ErrInvalidHostScoringWeight = errors.New("host scoring weights need to be between 0 and 2")
+
+	// ErrZeroHostScoringWeights is returned if all the weights are zero.
+	ErrZeroHostScoringWeights = errors.New("at least one host scoring weight needs to be non-zero")
+)
+
+// HostScoringWeights configures how the hostdb scores hosts. Every adjustment
+// of a host's score is raised to the power of its weight. A weight of 1 keeps
+// the adjustment as it is, a weight of 0 ignores the adjustment and a weight
+// of 2 makes it count twice as much. Hosts which charge more than a non-zero
+// price ceiling get the lowest possible score.
+type HostScoringWeights struct {
+	AgeWeight              float64 `json:"ageweight"`
+	CollateralWeight       float64 `json:"collateralweight"`
+	InteractionWeight      float64 `json:"interactionweight"`
+	LatencyWeight          float64 `json:"latencyweight"`
+	PriceWeight            float64 `json:"priceweight"`
+	StorageRemainingWeight float64 `json:"storageremainingweight"`
+	ThroughputWeight       float64 `json:"throughputweight"`
+	UptimeWeight           float64 `json:"uptimeweight"`
+	VersionWeight          float64 `json:"versionweight"`
+
+	MaxContractPrice          types.Currency `json:"maxcontractprice"`
+	MaxDownloadBandwidthPrice types.Currency `json:"maxdownloadbandwidthprice"`
+	MaxStoragePrice           types.Currency `json:"maxstorageprice"`
+	MaxUploadBandwidthPrice   types.Currency `json:"maxuploadbandwidthprice"`
+}
+
+// Validate checks that all the weights are within the allowed range and that
+// at least one of them is non-zero.
+func (hsw HostScoringWeights) Validate() error {
+	weights := []float64{
+		hsw.AgeWeight,
+		hsw.CollateralWeight,
+		hsw.InteractionWeight,
+		hsw.LatencyWeight,
+		hsw.PriceWeight,
+		hsw.StorageRemainingWeight,
+		hsw.ThroughputWeight,
+		hsw.UptimeWeight,
+		hsw.VersionWeight,
+	}
+	var nonZero bool
+	for _, w := range weights {
+		if math.IsNaN(w) || w < 0 || w > MaxHostScoringWeight {
+			return ErrInvalidHostScoringWeight
+		}
+		nonZero = nonZero || w != 0
+	}
+	if !nonZero {
+		return ErrZeroHostScoringWeights
+	}
+	return nil
+}
+
+// ExceedsPriceCeilings returns true if the host charges more than any of the
+// non-zero price ceilings.
+func (hsw HostScoringWeights) ExceedsPriceCeilings(settings HostExternalSettings) bool {
+	exceeds := func(ceiling, price types.Currency) bool {
+		return !ceiling.IsZero() && price.Cmp(ceiling) > 0
+	}
+	return exceeds(hsw.MaxContractPrice, settings.ContractPrice) ||
+		exceeds(hsw.MaxDownloadBandwidthPrice, settings.DownloadBandwidthPrice) ||
+		exceeds(hsw.MaxStoragePrice, settings.StoragePrice) ||
+		exceeds(hsw.MaxUploadBandwidthPrice, settings.UploadBandwidthPrice)
+}
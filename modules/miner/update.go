@@ -257,6 +257,9 @@ func (m *Miner) ProcessConsensusChange(cc modules.ConsensusChange) {
 	defer m.mu.Unlock()
 
 	// Update the miner's understanding of the block height.
+	if cc.SkipsBlocks(m.persist.Height) {
+		m.persist.Height = cc.OldHeight
+	}
 	for _, block := range cc.RevertedBlocks {
 		// Only doing the block check if the height is above zero saves hashing
 		// and saves a nontrivial amount of time during IBD.
@@ -93,6 +93,10 @@ type (
 		// Close closes the Pool.
 		Close() error
 
+		// Hashrate returns the estimated hashrate of the pool in hashes per
+		// second.
+		Hashrate() float64
+
 		// Returns the number of open tcp connections the pool currently is servicing
 		NumConnections() int
 
@@ -127,3 +127,13 @@ var (
 // values.
 func init() {
 }
+
+var (
+	// hashrateWindow is the period of time over which the accepted shares are
+	// averaged to estimate the hashrate of the pool.
+	hashrateWindow = build.Select(build.Var{
+		Standard: time.Minute * 10,
+		Dev:      time.Minute * 5,
+		Testing:  time.Second * 30,
+	}).(time.Duration)
+)
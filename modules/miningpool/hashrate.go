@@ -0,0 +1,56 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// hashrateMeter estimates the hashrate of the pool from the difficulty of
+	// the shares accepted within the last hashrateWindow.
+	hashrateMeter struct {
+		shares []meteredShare
+		mu     sync.Mutex
+	}
+
+	// meteredShare is an accepted share tracked by the hashrateMeter. The
+	// difficulty of a share is the expected number of hashes it took to find
+	// it.
+	meteredShare struct {
+		difficulty uint64
+		time       time.Time
+	}
+)
+
+// managedAddShare adds an accepted share to the meter.
+func (hm *hashrateMeter) managedAddShare(difficulty uint64, t time.Time) {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	hm.shares = append(hm.shares, meteredShare{
+		difficulty: difficulty,
+		time:       t,
+	})
+	hm.prune(t)
+}
+
+// managedHashrate returns the estimated number of hashes per second.
+func (hm *hashrateMeter) managedHashrate(now time.Time) float64 {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	hm.prune(now)
+	var hashes float64
+	for _, share := range hm.shares {
+		hashes += float64(share.difficulty)
+	}
+	return hashes / hashrateWindow.Seconds()
+}
+
+// prune removes the shares which are older than hashrateWindow.
+func (hm *hashrateMeter) prune(now time.Time) {
+	cutoff := now.Add(-hashrateWindow)
+	i := 0
+	for i < len(hm.shares) && hm.shares[i].time.Before(cutoff) {
+		i++
+	}
+	hm.shares = hm.shares[i:]
+}
@@ -0,0 +1,33 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+// TestHashrateMeter checks that the hashrateMeter averages the shares within
+// the hashrateWindow.
+func TestHashrateMeter(t *testing.T) {
+	var hm hashrateMeter
+	now := time.Now()
+	if hr := hm.managedHashrate(now); hr != 0 {
+		t.Fatal("expected hashrate to be 0 but was", hr)
+	}
+
+	// Add a share which is outside of the window and two shares inside.
+	hm.managedAddShare(1e9, now.Add(-2*hashrateWindow))
+	hm.managedAddShare(100, now.Add(-hashrateWindow/2))
+	hm.managedAddShare(200, now)
+	expected := 300 / hashrateWindow.Seconds()
+	if hr := hm.managedHashrate(now); hr != expected {
+		t.Fatalf("expected hashrate to be %v but was %v", expected, hr)
+	}
+
+	// Once the window moved past the shares, the hashrate drops to 0.
+	if hr := hm.managedHashrate(now.Add(2 * hashrateWindow)); hr != 0 {
+		t.Fatal("expected hashrate to be 0 but was", hr)
+	}
+	if len(hm.shares) != 0 {
+		t.Fatal("shares weren't pruned", len(hm.shares))
+	}
+}
@@ -167,6 +167,7 @@ type Pool struct {
 	shiftChan      chan bool
 	shiftTimestamp time.Time
 	clients        map[string]*Client //client name to client pointer mapping
+	hashrate       hashrateMeter
 
 	clientSetupMutex deadlock.Mutex
 	runningMutex     deadlock.RWMutex
@@ -563,6 +564,11 @@ func (p *Pool) NumConnections() int {
 	return 0
 }
 
+// Hashrate returns the estimated hashrate of the pool in hashes per second.
+func (p *Pool) Hashrate() float64 {
+	return p.hashrate.managedHashrate(time.Now())
+}
+
 // NumConnectionsOpened returns the total number of tcp connections from clients the
 // pool has opened since startup
 func (p *Pool) NumConnectionsOpened() uint64 {
@@ -135,6 +135,7 @@ func (w *Worker) IncrementShares(sessionDifficulty float64, reward float64) {
 	}
 
 	w.s.Shift().IncrementShares(share)
+	p.hashrate.managedAddShare(siaSessionDifficulty, share.time)
 }
 
 // IncrementInvalidShares adds a record of an invalid share submission
@@ -60,6 +60,12 @@ func (na NetAddress) IsLoopback() bool {
 	return false
 }
 
+// IsOnion returns true if the NetAddress is a Tor onion service address.
+// Onion addresses can only be dialed through a SOCKS5 proxy.
+func (na NetAddress) IsOnion() bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(na.Host(), ".")), ".onion")
+}
+
 // IsLocal returns true if the input IP address belongs to a local address
 // range such as 192.168.x.x or 127.x.x.x
 func (na NetAddress) IsLocal() bool {
@@ -330,3 +330,29 @@ func TestIsLocal(t *testing.T) {
 		}
 	}
 }
+
+// TestIsOnion checks that only .onion addresses are reported as onion
+// addresses.
+func TestIsOnion(t *testing.T) {
+	t.Parallel()
+
+	testSet := []struct {
+		query           NetAddress
+		desiredResponse bool
+	}{
+		{"expyuzz4wqqyqhjn.onion:4281", true},
+		{"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:4282", true},
+		{"EXPYUZZ4WQQYQHJN.ONION:4281", true},
+		{"expyuzz4wqqyqhjn.onion.:4281", true},
+		{"expyuzz4wqqyqhjn.onion", false},
+		{"onion.com:4281", false},
+		{"hn.com:8811", false},
+		{"12.34.45.64:7777", false},
+		{"", false},
+	}
+	for _, test := range testSet {
+		if test.query.IsOnion() != test.desiredResponse {
+			t.Error("test failed:", test, test.query.IsOnion())
+		}
+	}
+}
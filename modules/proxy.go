@@ -0,0 +1,119 @@
+package modules
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+
+	"golang.org/x/net/proxy"
+)
+
+var (
+	// ErrOnionNotAllowed is returned when dialing a .onion address without
+	// onion addresses being allowed.
+	ErrOnionNotAllowed = errors.New("onion addresses are not allowed, they require a SOCKS5 proxy with onion addresses enabled")
+
+	// GlobalProxy is the global object for routing outbound peer, host and
+	// Host API connections through a SOCKS5 proxy. It is set using the spd
+	// config.
+	GlobalProxy = new(Proxy)
+)
+
+// Proxy routes outbound connections through an optional SOCKS5 proxy such as
+// Tor. Without a proxy address connections are dialed directly.
+type Proxy struct {
+	address    string
+	allowOnion bool
+	mu         sync.RWMutex
+}
+
+// Settings returns the address of the SOCKS5 proxy and whether .onion
+// addresses are allowed.
+func (p *Proxy) Settings() (address string, allowOnion bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.address, p.allowOnion
+}
+
+// AllowOnion returns whether .onion addresses are accepted for peers and
+// hosts.
+func (p *Proxy) AllowOnion() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.allowOnion
+}
+
+// SetProxy sets the address of the SOCKS5 proxy and whether .onion addresses
+// are allowed. An empty address disables the proxy. Onion addresses can only
+// be dialed through a proxy.
+func (p *Proxy) SetProxy(address string, allowOnion bool) error {
+	if address != "" {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return errors.New("invalid SOCKS5 proxy address: " + err.Error())
+		}
+	} else if allowOnion {
+		return errors.New("onion addresses require a SOCKS5 proxy")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.address, p.allowOnion = address, allowOnion
+	return nil
+}
+
+// Dial connects to addr using dialer, going through the SOCKS5 proxy if one is
+// set. The Cancel channel and the Timeout of the dialer are respected for the
+// whole connection setup, including the proxy handshake.
+func (p *Proxy) Dial(dialer *net.Dialer, addr NetAddress) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if dialer.Timeout > 0 {
+		var timeoutCancel context.CancelFunc
+		ctx, timeoutCancel = context.WithTimeout(ctx, dialer.Timeout)
+		defer timeoutCancel()
+	}
+	if dialer.Cancel != nil {
+		go func() {
+			select {
+			case <-dialer.Cancel:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return p.DialContext(ctx, dialer, addr)
+}
+
+// DialContext connects to addr using dialer, going through the SOCKS5 proxy if
+// one is set. When a proxy is used, addr is resolved by the proxy to avoid
+// leaking DNS requests.
+func (p *Proxy) DialContext(ctx context.Context, dialer *net.Dialer, addr NetAddress) (net.Conn, error) {
+	address, allowOnion := p.Settings()
+	if addr.IsOnion() && !allowOnion {
+		return nil, ErrOnionNotAllowed
+	}
+	if address == "" {
+		return dialer.DialContext(ctx, "tcp", string(addr))
+	}
+	socks, err := proxy.SOCKS5("tcp", address, nil, dialer)
+	if err != nil {
+		return nil, err
+	}
+	return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", string(addr))
+}
+
+// DialTLSContext connects to addr like DialContext and performs a TLS
+// handshake on the connection using cfg.
+func (p *Proxy) DialTLSContext(ctx context.Context, addr NetAddress, cfg *tls.Config) (net.Conn, error) {
+	conn, err := p.DialContext(ctx, new(net.Dialer), addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
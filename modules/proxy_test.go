@@ -0,0 +1,181 @@
+package modules
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testSOCKS5Server is a minimal SOCKS5 server which connects every request to
+// target and reports the requested addresses on requests.
+func testSOCKS5Server(t *testing.T, target string) (net.Listener, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				addr, err := readSOCKS5Request(conn)
+				if err != nil {
+					return
+				}
+				requests <- addr
+				tconn, err := net.Dial("tcp", target)
+				if err != nil {
+					return
+				}
+				defer tconn.Close()
+				if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+					return
+				}
+				io.Copy(conn, tconn)
+			}(conn)
+		}
+	}()
+	return l, requests
+}
+
+// readSOCKS5Request performs the SOCKS5 method negotiation and returns the
+// address of the following connect request.
+func readSOCKS5Request(conn net.Conn) (string, error) {
+	buf := make([]byte, 262)
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return "", err
+	}
+	if _, err := conn.Write([]byte{5, 0}); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil {
+		return "", err
+	}
+	var host string
+	switch buf[3] {
+	case 1:
+		if _, err := io.ReadFull(conn, buf[:net.IPv4len]); err != nil {
+			return "", err
+		}
+		host = net.IP(buf[:net.IPv4len]).String()
+	case 3:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return "", err
+		}
+		n := int(buf[0])
+		if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+			return "", err
+		}
+		host = string(buf[:n])
+	default:
+		return "", errors.New("unsupported address type")
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return "", err
+	}
+	port := binary.BigEndian.Uint16(buf[:2])
+	return net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+// TestProxyDial checks that connections are routed through the SOCKS5 proxy
+// and that onion addresses are only dialed if they are allowed.
+func TestProxyDial(t *testing.T) {
+	t.Parallel()
+
+	// Create a target that greets every connection.
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("hello"))
+			conn.Close()
+		}
+	}()
+	socks, requests := testSOCKS5Server(t, target.Addr().String())
+	defer socks.Close()
+
+	dial := func(p *Proxy, addr NetAddress) error {
+		conn, err := p.Dial(&net.Dialer{Timeout: 5 * time.Second}, addr)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return err
+		}
+		if string(buf) != "hello" {
+			t.Fatal("unexpected greeting:", string(buf))
+		}
+		return nil
+	}
+	targetAddr := NetAddress(target.Addr().String())
+	onionAddr := NetAddress("expyuzz4wqqyqhjn.onion:4281")
+
+	// Without a proxy addresses are dialed directly and onion addresses are
+	// rejected.
+	p := new(Proxy)
+	if err := dial(p, targetAddr); err != nil {
+		t.Fatal(err)
+	}
+	if err := dial(p, onionAddr); err != ErrOnionNotAllowed {
+		t.Fatal("expected ErrOnionNotAllowed, got", err)
+	}
+	if len(requests) != 0 {
+		t.Fatal("proxy was used without being set")
+	}
+
+	// Onion addresses and invalid proxy addresses can't be set.
+	if err := p.SetProxy("", true); err == nil {
+		t.Fatal("onion addresses were allowed without a proxy")
+	}
+	if err := p.SetProxy("garbage", false); err == nil {
+		t.Fatal("invalid proxy address was accepted")
+	}
+
+	// With a proxy connections go through the proxy.
+	if err := p.SetProxy(socks.Addr().String(), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := dial(p, targetAddr); err != nil {
+		t.Fatal(err)
+	}
+	if req := <-requests; req != string(targetAddr) {
+		t.Fatal("proxy received unexpected request:", req)
+	}
+	if err := dial(p, onionAddr); err != ErrOnionNotAllowed {
+		t.Fatal("expected ErrOnionNotAllowed, got", err)
+	}
+
+	// Onion addresses are resolved by the proxy once they are allowed.
+	if err := p.SetProxy(socks.Addr().String(), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := dial(p, onionAddr); err != nil {
+		t.Fatal(err)
+	}
+	if req := <-requests; req != string(onionAddr) {
+		t.Fatal("proxy received unexpected request:", req)
+	}
+	if address, allowOnion := p.Settings(); address != socks.Addr().String() || !allowOnion {
+		t.Fatal("unexpected settings:", address, allowOnion)
+	}
+}
@@ -49,6 +49,16 @@ var (
 	// BackupKeySpecifier is a specifier that is hashed with the wallet seed to
 	// create a key for encrypting backups.
 	BackupKeySpecifier = types.NewSpecifier("backupkey")
+	// MinBackupInterval is the minimum interval between two scheduled
+	// backups.
+	MinBackupInterval = build.Select(build.Var{
+		Standard: time.Minute,
+		Dev:      time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+	// ContractBundleKeySpecifier is a specifier that is hashed with a
+	// user-supplied password to create a key for encrypting contract bundles.
+	ContractBundleKeySpecifier = types.NewSpecifier("contractbundle")
 )
 
 // DataSourceID is an identifier to uniquely identify a data source, such as for
@@ -315,6 +325,89 @@ type ContractWatchStatus struct {
 	WindowEnd                 types.BlockHeight `json:"windowend"`
 }
 
+// FileMigration contains the status of a file migration. A migration
+// re-encodes and re-encrypts the data of a file using a new erasure code or
+// cipher type.
+type FileMigration struct {
+	SiaPath      SiaPath `json:"siapath"`
+	DataPieces   int     `json:"datapieces"`
+	ParityPieces int     `json:"paritypieces"`
+	CipherType   string  `json:"ciphertype"`
+
+	// Size is the number of bytes that need to be migrated and Migrated is
+	// the number of bytes that were migrated so far.
+	Size     uint64 `json:"size"`
+	Migrated uint64 `json:"migrated"`
+
+	StartTime time.Time `json:"starttime"`
+	EndTime   time.Time `json:"endtime"`
+	Completed bool      `json:"completed"`
+	Canceled  bool      `json:"canceled"`
+	Error     string    `json:"error"`
+}
+
+// DirectoryPolicy is the redundancy and host-set policy of a siadir. A policy
+// applies to all the files within the siadir and its sub siadirs, unless a sub
+// siadir has a policy of its own. The zero value of a field means that the
+// renter's default is used.
+type DirectoryPolicy struct {
+	// DataPieces and ParityPieces are the erasure coding parameters used for
+	// new uploads. If they differ from the erasure code of an existing file,
+	// the file is re-encoded.
+	DataPieces   int `json:"datapieces"`
+	ParityPieces int `json:"paritypieces"`
+
+	// RepairThreshold is the minimum health the files of the directory are
+	// required to have. Files with a health at or above the threshold are
+	// repaired.
+	RepairThreshold float64 `json:"repairthreshold"`
+
+	// PinnedHosts restricts the hosts used for storing the files to the given
+	// subset. ExcludedHosts are never used for storing the files. Pieces
+	// stored on hosts that are not allowed by the policy don't count towards
+	// the redundancy of a file.
+	PinnedHosts   []types.SiaPublicKey `json:"pinnedhosts"`
+	ExcludedHosts []types.SiaPublicKey `json:"excludedhosts"`
+}
+
+// HasErasureCode returns true if the policy specifies erasure coding
+// parameters.
+func (dp DirectoryPolicy) HasErasureCode() bool {
+	return dp.DataPieces > 0
+}
+
+// IsSet returns true if any field of the policy is set.
+func (dp DirectoryPolicy) IsSet() bool {
+	return dp.HasErasureCode() || dp.RepairThreshold > 0 || len(dp.PinnedHosts) > 0 || len(dp.ExcludedHosts) > 0
+}
+
+// Validate checks that the policy is consistent.
+func (dp DirectoryPolicy) Validate() error {
+	if dp.DataPieces < 0 || dp.ParityPieces < 0 {
+		return errors.New("number of data and parity pieces can't be negative")
+	}
+	if dp.DataPieces == 0 && dp.ParityPieces > 0 {
+		return errors.New("parity pieces require data pieces to be set")
+	}
+	if dp.HasErasureCode() && dp.ParityPieces == 0 {
+		return errors.New("data pieces require parity pieces to be set")
+	}
+	if dp.RepairThreshold < 0 || dp.RepairThreshold > 1 {
+		return errors.New("repair threshold needs to be between 0 and 1")
+	}
+	if dp.HasErasureCode() && len(dp.PinnedHosts) > 0 && len(dp.PinnedHosts) < dp.DataPieces+dp.ParityPieces {
+		return fmt.Errorf("policy needs at least %v pinned hosts but got %v", dp.DataPieces+dp.ParityPieces, len(dp.PinnedHosts))
+	}
+	for _, pinned := range dp.PinnedHosts {
+		for _, excluded := range dp.ExcludedHosts {
+			if pinned.Equals(excluded) {
+				return fmt.Errorf("host %v can't be both pinned and excluded", pinned)
+			}
+		}
+	}
+	return nil
+}
+
 // DirectoryInfo provides information about a siadir
 type DirectoryInfo struct {
 	// The following fields are aggregate values of the siadir. These values are
@@ -334,20 +427,21 @@ type DirectoryInfo struct {
 
 	// The following fields are information specific to the siadir that is not
 	// an aggregate of the entire sub directory tree
-	Health              float64     `json:"health"`
-	LastHealthCheckTime time.Time   `json:"lasthealthchecktime"`
-	MaxHealthPercentage float64     `json:"maxhealthpercentage"`
-	MaxHealth           float64     `json:"maxhealth"`
-	MinRedundancy       float64     `json:"minredundancy"`
-	DirMode             os.FileMode `json:"mode,siamismatch"` // Field is called DirMode for fuse compatibility
-	MostRecentModTime   time.Time   `json:"mostrecentmodtime"`
-	NumFiles            uint64      `json:"numfiles"`
-	NumStuckChunks      uint64      `json:"numstuckchunks"`
-	NumSubDirs          uint64      `json:"numsubdirs"`
-	SiaPath             SiaPath     `json:"siapath"`
-	DirSize             uint64      `json:"size,siamismatch"` // Stays as 'size' in json for compatibility
-	StuckHealth         float64     `json:"stuckhealth"`
-	UID                 uint64      `json:"uid"`
+	Health              float64         `json:"health"`
+	LastHealthCheckTime time.Time       `json:"lasthealthchecktime"`
+	MaxHealthPercentage float64         `json:"maxhealthpercentage"`
+	MaxHealth           float64         `json:"maxhealth"`
+	MinRedundancy       float64         `json:"minredundancy"`
+	DirMode             os.FileMode     `json:"mode,siamismatch"` // Field is called DirMode for fuse compatibility
+	MostRecentModTime   time.Time       `json:"mostrecentmodtime"`
+	NumFiles            uint64          `json:"numfiles"`
+	NumStuckChunks      uint64          `json:"numstuckchunks"`
+	NumSubDirs          uint64          `json:"numsubdirs"`
+	Policy              DirectoryPolicy `json:"policy"`
+	SiaPath             SiaPath         `json:"siapath"`
+	DirSize             uint64          `json:"size,siamismatch"` // Stays as 'size' in json for compatibility
+	StuckHealth         float64         `json:"stuckhealth"`
+	UID                 uint64          `json:"uid"`
 }
 
 // Name implements os.FileInfo.
@@ -404,6 +498,32 @@ type FileUploadParams struct {
 	// to create a CipherKey with the given CipherType. This value override
 	// CipherType if it is set.
 	CipherKey crypto.CipherKey
+
+	// Compression is the codec used to compress each chunk before it is
+	// erasure coded and encrypted. If it is left blank, the file will be
+	// stored uncompressed.
+	Compression CompressionType
+
+	// Deduplicate enables convergent encryption for the chunks of the file
+	// which allows identical chunks of different files to share the pieces
+	// stored on the hosts.
+	Deduplicate bool
+}
+
+// PackedUploadParams contains the information used by the Renter to upload
+// many small files by packing their data into shared chunks.
+type PackedUploadParams struct {
+	// Sources are the local paths of the files. Every file needs to fit into
+	// a single sector.
+	Sources []string
+
+	// SiaPath is the directory the files are uploaded to. Every file keeps
+	// the name of its source.
+	SiaPath SiaPath
+
+	ErasureCode ErasureCoder
+	Force       bool
+	CipherType  crypto.CipherType
 }
 
 // FileInfo provides information about a file.
@@ -412,7 +532,9 @@ type FileInfo struct {
 	Available        bool              `json:"available"`
 	ChangeTime       time.Time         `json:"changetime"`
 	CipherType       string            `json:"ciphertype"`
+	Compression      string            `json:"compression"`
 	CreateTime       time.Time         `json:"createtime"`
+	Deduplicate      bool              `json:"deduplicate"`
 	Expiration       types.BlockHeight `json:"expiration"`
 	Filesize         uint64            `json:"filesize"`
 	Health           float64           `json:"health"`
@@ -423,11 +545,13 @@ type FileInfo struct {
 	FileMode         os.FileMode       `json:"mode,siamismatch"`    // Field is called FileMode for fuse compatibility
 	NumStuckChunks   uint64            `json:"numstuckchunks"`
 	OnDisk           bool              `json:"ondisk"`
+	Packed           bool              `json:"packed"`
 	Recoverable      bool              `json:"recoverable"`
 	Redundancy       float64           `json:"redundancy"`
 	Renewing         bool              `json:"renewing"`
 	Publinks         []string          `json:"publinks"`
 	SiaPath          SiaPath           `json:"siapath"`
+	StoredSize       uint64            `json:"storedsize"`
 	Stuck            bool              `json:"stuck"`
 	StuckHealth      float64           `json:"stuckhealth"`
 	UID              uint64            `json:"uid"`
@@ -480,6 +604,13 @@ type HostDBEntry struct {
 	IPNets          []string  `json:"ipnets"`
 	LastIPNetChange time.Time `json:"lastipnetchange"`
 
+	// Performance measurements taken during scans and worker jobs. They are
+	// exponentially weighted moving averages and are zero until the first
+	// measurement. The throughputs are in bytes per second.
+	Latency            time.Duration `json:"latency"`
+	DownloadThroughput uint64        `json:"downloadthroughput"`
+	UploadThroughput   uint64        `json:"uploadthroughput"`
+
 	// The public key of the host, stored separately to minimize risk of certain
 	// MitM based vulnerabilities.
 	PublicKey types.SiaPublicKey `json:"publickey"`
@@ -489,6 +620,16 @@ type HostDBEntry struct {
 	Filtered bool `json:"filtered"`
 }
 
+// HostBenchmark is a single performance measurement of a host. Zero values
+// are ignored when it is recorded.
+type HostBenchmark struct {
+	Latency       time.Duration `json:"latency"`
+	DownloadBytes uint64        `json:"downloadbytes"`
+	DownloadTime  time.Duration `json:"downloadtime"`
+	UploadBytes   uint64        `json:"uploadbytes"`
+	UploadTime    time.Duration `json:"uploadtime"`
+}
+
 // HostDBScan represents a single scan event.
 type HostDBScan struct {
 	Timestamp time.Time `json:"timestamp"`
@@ -512,8 +653,10 @@ type HostScoreBreakdown struct {
 	CollateralAdjustment       float64 `json:"collateraladjustment"`
 	DurationAdjustment         float64 `json:"durationadjustment"`
 	InteractionAdjustment      float64 `json:"interactionadjustment"`
+	LatencyAdjustment          float64 `json:"latencyadjustment"`
 	PriceAdjustment            float64 `json:"pricesmultiplier,siamismatch"`
 	StorageRemainingAdjustment float64 `json:"storageremainingadjustment"`
+	ThroughputAdjustment       float64 `json:"throughputadjustment"`
 	UptimeAdjustment           float64 `json:"uptimeadjustment"`
 	VersionAdjustment          float64 `json:"versionadjustment"`
 }
@@ -642,6 +785,13 @@ type RecoverableContract struct {
 	TxnFee types.Currency `json:"txnfee"`
 }
 
+// ContractImportError describes why a contract of a contract bundle couldn't
+// be imported.
+type ContractImportError struct {
+	ID    types.FileContractID `json:"id"`
+	Error string               `json:"error"`
+}
+
 // A RenterContract contains metadata about a file contract. It is read-only;
 // modifying a RenterContract does not modify the actual file contract.
 type RenterContract struct {
@@ -720,6 +870,46 @@ type ContractorSpending struct {
 	PreviousSpending types.Currency `json:"previousspending"`
 }
 
+// ContractorSpendingForecast projects the spending of the current billing
+// period from the spending rates of the contracts so far.
+type ContractorSpendingForecast struct {
+	// Funds are the funds of the allowance.
+	Funds types.Currency `json:"funds"`
+	// BlockHeight is the height at which the forecast was made and PeriodEnd
+	// is the height at which the current period ends.
+	BlockHeight types.BlockHeight `json:"blockheight"`
+	PeriodEnd   types.BlockHeight `json:"periodend"`
+	// Spent is the money spent on fees, downloads, uploads and storage during
+	// the current period.
+	Spent types.Currency `json:"spent"`
+	// Projected is the money expected to be spent by the end of the period if
+	// the current spending rates continue.
+	Projected types.Currency `json:"projected"`
+	// ExceedsAllowance is true if the projected spending exceeds the funds.
+	ExceedsAllowance bool `json:"exceedsallowance"`
+	// Hosts is the forecast per host, sorted by projected spending in
+	// descending order.
+	Hosts []HostSpendingForecast `json:"hosts"`
+}
+
+// HostSpendingForecast is the spending forecast of the contracts with a
+// single host.
+type HostSpendingForecast struct {
+	HostPublicKey types.SiaPublicKey `json:"hostpublickey"`
+
+	ContractFees     types.Currency `json:"contractfees"`
+	DownloadSpending types.Currency `json:"downloadspending"`
+	StorageSpending  types.Currency `json:"storagespending"`
+	UploadSpending   types.Currency `json:"uploadspending"`
+
+	ProjectedDownloadSpending types.Currency `json:"projecteddownloadspending"`
+	ProjectedStorageSpending  types.Currency `json:"projectedstoragespending"`
+	ProjectedUploadSpending   types.Currency `json:"projecteduploadspending"`
+
+	// Projected is the sum of the fees and the projected spending.
+	Projected types.Currency `json:"projected"`
+}
+
 // ContractorChurnStatus contains the current churn budgets for the Contractor's
 // churnLimiter and the aggregate churn for the current period.
 type ContractorChurnStatus struct {
@@ -739,6 +929,70 @@ type UploadedBackup struct {
 	UploadProgress float64
 }
 
+// BackupSchedule configures the renter's scheduled backups. Scheduled backups
+// are uploaded to the hosts like backups created with UploadBackup. Every
+// scheduled backup only contains the siafiles which changed since the previous
+// one and is chained to it. A chain starts with a full backup.
+type BackupSchedule struct {
+	// Interval is the time between two scheduled backups. A zero interval
+	// disables the scheduler.
+	Interval time.Duration `json:"interval"`
+
+	// FullInterval is the number of incremental backups after which a new
+	// full backup is created. A value of 0 disables incremental backups.
+	FullInterval uint64 `json:"fullinterval"`
+
+	// RetainHourly, RetainDaily and RetainWeekly are the number of hours, days
+	// and weeks for which the most recent scheduled backup is retained. Older
+	// scheduled backups are pruned from the hosts unless a retained backup
+	// depends on them. If all of them are 0, no backups are pruned.
+	RetainHourly uint64 `json:"retainhourly"`
+	RetainDaily  uint64 `json:"retaindaily"`
+	RetainWeekly uint64 `json:"retainweekly"`
+}
+
+// ScheduledBackup contains metadata about a backup created by the backup
+// scheduler.
+type ScheduledBackup struct {
+	Name         string          `json:"name"`
+	UID          [16]byte        `json:"uid"`
+	CreationDate types.Timestamp `json:"creationdate"`
+
+	// Parent is the name of the backup an incremental backup is based on. It
+	// is empty for full backups.
+	Parent string `json:"parent"`
+}
+
+// DefaultBackupSchedule is the schedule used by renters which haven't
+// configured backups yet. The scheduler is disabled by default.
+var DefaultBackupSchedule = BackupSchedule{
+	FullInterval: 24,
+	RetainHourly: 24,
+	RetainDaily:  7,
+	RetainWeekly: 4,
+}
+
+// Validate returns an error if the schedule is invalid.
+func (bs BackupSchedule) Validate() error {
+	if bs.Interval < 0 {
+		return errors.New("backup interval can't be negative")
+	}
+	if bs.Interval > 0 && bs.Interval < MinBackupInterval {
+		return fmt.Errorf("backup interval must be at least %v", MinBackupInterval)
+	}
+	return nil
+}
+
+// StreamToken is a scoped access token which allows streaming the files within
+// a directory without knowing the API password. Tokens are signed by the
+// renter, expire after a while and can be revoked.
+type StreamToken struct {
+	ID      string    `json:"id"`
+	SiaPath SiaPath   `json:"siapath"` // directory or file the token grants access to
+	Expiry  time.Time `json:"expiry"`
+	Token   string    `json:"token"`
+}
+
 type (
 	// WorkerPoolStatus contains information about the status of the workerPool
 	// and the workers
@@ -879,6 +1133,17 @@ type Renter interface {
 	// use.
 	LoadBackup(src string, secret []byte) error
 
+	// ExportContracts writes the contracts with the given ids to an encrypted
+	// bundle at dst. The bundle also contains the siafiles that have pieces
+	// stored on the contracts' hosts.
+	ExportContracts(dst string, ids []types.FileContractID, secret []byte) error
+
+	// ImportContracts loads a bundle created by ExportContracts and returns
+	// the imported contracts together with the contracts which couldn't be
+	// imported. Contracts with hosts the renter already has a contract with
+	// are not imported.
+	ImportContracts(src string, secret []byte) ([]RenterContract, []ContractImportError, error)
+
 	// InitRecoveryScan starts scanning the whole blockchain for recoverable
 	// contracts within a separate thread.
 	InitRecoveryScan() error
@@ -913,6 +1178,9 @@ type Renter interface {
 	// billing period.
 	PeriodSpending() (ContractorSpending, error)
 
+	// SpendingForecast projects the spending of the current billing period.
+	SpendingForecast() (ContractorSpendingForecast, error)
+
 	// RecoverableContracts returns the contracts that the contractor deems
 	// recoverable. That means they are not expired yet and also not part of the
 	// active contracts. Usually this should return an empty slice unless the host
@@ -943,6 +1211,20 @@ type Renter interface {
 	// BackupsOnHost returns the backups stored on the specified host.
 	BackupsOnHost(hostKey types.SiaPublicKey) ([]UploadedBackup, error)
 
+	// BackupSchedule returns the backup schedule and the scheduled backups
+	// which are currently retained, oldest first.
+	BackupSchedule() (BackupSchedule, []ScheduledBackup)
+
+	// SetBackupSchedule updates the backup schedule.
+	SetBackupSchedule(BackupSchedule) error
+
+	// RestoreBackup downloads the uploaded backup with the given name and
+	// loads it into the renter. If the backup is incremental, the backups it
+	// depends on are downloaded as well and the renter's files are restored
+	// to the state at the time of the backup. The secret is used to decrypt
+	// the backups.
+	RestoreBackup(name string, secret []byte) error
+
 	// DeleteFile deletes a file entry from the renter.
 	DeleteFile(siaPath SiaPath) error
 
@@ -958,6 +1240,13 @@ type Renter interface {
 	// download is finished.
 	DownloadAsync(params RenterDownloadParameters, onComplete func(error) error) (uid DownloadID, start func() error, cancel func(), err error)
 
+	// CancelDownload cancels a download which is still in progress.
+	CancelDownload(uid DownloadID) error
+
+	// ResumeDownload resumes a failed download to a file. Only the chunks
+	// which weren't written to the destination yet are downloaded.
+	ResumeDownload(uid DownloadID) error
+
 	// ClearDownloadHistory clears the download history of the renter
 	// inclusive for before and after times.
 	ClearDownloadHistory(after, before time.Time) error
@@ -982,6 +1271,12 @@ type Renter interface {
 	// SetFilterMode sets the renter's hostdb filter mode
 	SetFilterMode(fm FilterMode, hosts []types.SiaPublicKey) error
 
+	// HostPolicy returns the renter's hostdb host policy.
+	HostPolicy() (HostPolicy, error)
+
+	// SetHostPolicy sets the renter's hostdb host policy.
+	SetHostPolicy(hp HostPolicy) error
+
 	// Host provides the DB entry and score breakdown for the requested host.
 	Host(pk types.SiaPublicKey) (HostDBEntry, bool, error)
 
@@ -1007,6 +1302,16 @@ type Renter interface {
 	// hostdb's weighting algorithm.
 	ScoreBreakdown(entry HostDBEntry) (HostScoreBreakdown, error)
 
+	// ScoreBreakdownWithWeights will return the score for a host db entry
+	// using the provided scoring weights instead of the hostdb's weights.
+	ScoreBreakdownWithWeights(entry HostDBEntry, weights HostScoringWeights) (HostScoreBreakdown, error)
+
+	// ScoringWeights returns the weights used by the hostdb to score hosts.
+	ScoringWeights() (HostScoringWeights, error)
+
+	// SetScoringWeights sets the weights used by the hostdb to score hosts.
+	SetScoringWeights(weights HostScoringWeights) error
+
 	// Settings returns the Renter's current settings.
 	Settings() (RenterSettings, error)
 
@@ -1025,13 +1330,31 @@ type Renter interface {
 	ResumeRepairsAndUploads() error
 
 	// Streamer creates a io.ReadSeeker that can be used to stream downloads
-	// from the ScPrime network and also returns the fileName of the streamed
-	// resource.
-	Streamer(siapath SiaPath, disableLocalFetch bool) (string, Streamer, error)
+	// from the ScPrime network and also returns information about the
+	// streamed resource.
+	Streamer(siapath SiaPath, disableLocalFetch bool) (StreamInfo, Streamer, error)
+
+	// CreateStreamToken creates a token which allows streaming the files
+	// within siaPath until it expires after the given validity.
+	CreateStreamToken(siaPath SiaPath, validity time.Duration) (StreamToken, error)
+
+	// RevokeStreamToken revokes the stream token with the given id.
+	RevokeStreamToken(id string) error
+
+	// StreamTokens returns the stream tokens which are currently valid.
+	StreamTokens() []StreamToken
+
+	// ValidateStreamToken returns an error if the token doesn't allow
+	// streaming the file at siaPath.
+	ValidateStreamToken(token string, siaPath SiaPath) error
 
 	// Upload uploads a file using the input parameters.
 	Upload(FileUploadParams) error
 
+	// UploadPacked uploads many small files by packing their data into shared
+	// chunks.
+	UploadPacked(PackedUploadParams) error
+
 	// UploadStreamFromReader reads from the provided reader until io.EOF is reached and
 	// upload the data to the ScPrime network.
 	UploadStreamFromReader(up FileUploadParams, reader io.Reader) error
@@ -1045,6 +1368,20 @@ type Renter interface {
 	// DirList lists the directories in a siadir
 	DirList(siaPath SiaPath) ([]DirectoryInfo, error)
 
+	// SetDirPolicy sets the redundancy and host-set policy of a directory.
+	SetDirPolicy(siaPath SiaPath, policy DirectoryPolicy) error
+
+	// CancelMigration cancels the migration of a file.
+	CancelMigration(siaPath SiaPath) error
+
+	// MigrateFile re-encodes and re-encrypts a file in the background using
+	// the given erasure code and cipher type. A nil erasure code or an invalid
+	// cipher type keep the current settings of the file.
+	MigrateFile(siaPath SiaPath, ec ErasureCoder, ct crypto.CipherType) error
+
+	// Migrations returns the status of the file migrations.
+	Migrations() []FileMigration
+
 	// WorkerPoolStatus returns the current status of the Renter's worker pool
 	WorkerPoolStatus() (WorkerPoolStatus, error)
 }
@@ -1056,6 +1393,14 @@ type Streamer interface {
 	io.Closer
 }
 
+// StreamInfo contains information about a streamed file which is required to
+// serve it with the correct caching headers.
+type StreamInfo struct {
+	Name    string    // name of the streamed resource
+	UID     string    // unique identifier of the underlying siafile
+	ModTime time.Time // time of the last content modification
+}
+
 // RenterDownloadParameters defines the parameters passed to the Renter's
 // Download method.
 type RenterDownloadParameters struct {
@@ -1116,6 +1461,12 @@ type HostDB interface {
 	// SetFilterMode sets the renter's hostdb filter mode
 	SetFilterMode(lm FilterMode, hosts []types.SiaPublicKey) error
 
+	// HostPolicy returns the host policy of the hostdb.
+	HostPolicy() (HostPolicy, error)
+
+	// SetHostPolicy sets the host policy of the hostdb.
+	SetHostPolicy(hp HostPolicy) error
+
 	// Host returns the HostDBEntry for a given host.
 	Host(pk types.SiaPublicKey) (HostDBEntry, bool, error)
 
@@ -1136,6 +1487,10 @@ type HostDB interface {
 	// IP restriction is disabled
 	IPRestriction() (int, error)
 
+	// RecordHostBenchmark adds a performance measurement to the rolling
+	// latency and throughput of a host.
+	RecordHostBenchmark(types.SiaPublicKey, HostBenchmark) error
+
 	// RandomHosts returns a set of random hosts, weighted by their estimated
 	// usefulness / attractiveness to the renter. RandomHosts will not return
 	// any offline or inactive hosts.
@@ -1150,6 +1505,17 @@ type HostDB interface {
 	// of the host.
 	ScoreBreakdown(HostDBEntry) (HostScoreBreakdown, error)
 
+	// ScoreBreakdownWithWeights returns the score breakdown of the host under
+	// the provided scoring weights.
+	ScoreBreakdownWithWeights(HostDBEntry, HostScoringWeights) (HostScoreBreakdown, error)
+
+	// ScoringWeights returns the weights used to score hosts.
+	ScoringWeights() (HostScoringWeights, error)
+
+	// SetScoringWeights sets the weights used to score hosts. It will
+	// completely rebuild the hosttree so it should be used with care.
+	SetScoringWeights(HostScoringWeights) error
+
 	// SetAllowance updates the allowance used by the hostdb for weighing hosts by
 	// updating the host weight function. It will completely rebuild the hosttree so
 	// it should be used with care.
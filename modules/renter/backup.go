@@ -12,12 +12,15 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	"gitlab.com/scpcorp/ScPrime/crypto"
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siadir"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
 
 	"gitlab.com/NebulousLabs/errors"
 	"gitlab.com/NebulousLabs/fastrand"
@@ -29,6 +32,20 @@ type backupHeader struct {
 	Version    string `json:"version"`
 	Encryption string `json:"encryption"`
 	IV         []byte `json:"iv"`
+
+	// Parent is the name of the uploaded backup an incremental backup is
+	// based on. It is empty for full backups.
+	Parent string `json:"parent,omitempty"`
+}
+
+// backupIncrement describes the backup an incremental backup is based on.
+type backupIncrement struct {
+	// parent is the name of the previous backup.
+	parent string
+	// since is the time the previous backup was created.
+	since time.Time
+	// previous is the manifest of the previous backup.
+	previous map[string]siafile.SiafileUID
 }
 
 // The following specifiers are options for the encryption of backups.
@@ -38,6 +55,12 @@ var (
 	encryptionVersion   = "1.0"
 )
 
+// backupManifestName is the name of the archive entry which lists all the
+// siafiles that existed when a backup was created. Incremental backups only
+// contain the siafiles which changed since their parent, so the manifest is
+// required to tell which files were deleted in the meantime.
+const backupManifestName = ".backupmanifest"
+
 // CreateBackup creates a backup of the renter's siafiles. If a secret is not
 // nil, the backup will be encrypted using the provided secret.
 func (r *Renter) CreateBackup(dst string, secret []byte) error {
@@ -50,11 +73,19 @@ func (r *Renter) CreateBackup(dst string, secret []byte) error {
 
 // managedCreateBackup creates a backup of the renter's siafiles. If a secret is
 // not nil, the backup will be encrypted using the provided secret.
-func (r *Renter) managedCreateBackup(dst string, secret []byte) (err error) {
+func (r *Renter) managedCreateBackup(dst string, secret []byte) error {
+	_, err := r.managedCreateBackupArchive(dst, secret, nil)
+	return err
+}
+
+// managedCreateBackupArchive creates a backup of the renter's siafiles. If inc
+// is not nil, only the siafiles that changed since the backup described by inc
+// are added. The manifest of the new backup is returned.
+func (r *Renter) managedCreateBackupArchive(dst string, secret []byte, inc *backupIncrement) (manifest map[string]siafile.SiafileUID, err error) {
 	// Create the gzip file.
 	f, err := os.Create(dst)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer func() {
 		err = errors.AddContext(errors.Compose(err, f.Close()), "Error creating backup "+dst)
@@ -67,6 +98,9 @@ func (r *Renter) managedCreateBackup(dst string, secret []byte) (err error) {
 		Version:    encryptionVersion,
 		Encryption: encryptionPlaintext,
 	}
+	if inc != nil {
+		bh.Parent = inc.parent
+	}
 
 	// Wrap it for encryption if required.
 	if secret != nil {
@@ -74,7 +108,7 @@ func (r *Renter) managedCreateBackup(dst string, secret []byte) (err error) {
 		bh.IV = fastrand.Bytes(twofish.BlockSize)
 		c, err := twofish.NewCipher(secret)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		sw := cipher.StreamWriter{
 			S: cipher.NewCTR(c, bh.IV),
@@ -85,12 +119,12 @@ func (r *Renter) managedCreateBackup(dst string, secret []byte) (err error) {
 
 	// Skip the checkum for now.
 	if _, err := f.Seek(crypto.HashSize, io.SeekStart); err != nil {
-		return err
+		return nil, err
 	}
 	// Write the header.
 	enc := json.NewEncoder(f)
 	if err := enc.Encode(bh); err != nil {
-		return err
+		return nil, err
 	}
 	// Wrap the archive in a multiwriter to hash the contents of the archive
 	// before encrypting it.
@@ -100,11 +134,15 @@ func (r *Renter) managedCreateBackup(dst string, secret []byte) (err error) {
 	gzw := gzip.NewWriter(archive)
 	// Wrap the gzip writer into a tar writer.
 	tw := tar.NewWriter(gzw)
-	// Add the files to the archive.
-	if err := r.managedTarSiaFiles(tw); err != nil {
+	// Add the files and the manifest to the archive.
+	manifest, err = r.managedTarSiaFiles(tw, inc)
+	if err == nil {
+		err = writeBackupManifest(tw, manifest)
+	}
+	if err != nil {
 		twErr := tw.Close()
 		gzwErr := gzw.Close()
-		return errors.Compose(err, twErr, gzwErr)
+		return nil, errors.Compose(err, twErr, gzwErr)
 	}
 	// Close tar writer to flush it before writing the allowance.
 	twErr := tw.Close()
@@ -112,18 +150,21 @@ func (r *Renter) managedCreateBackup(dst string, secret []byte) (err error) {
 	allowanceBytes, err := json.Marshal(r.hostContractor.Allowance())
 	if err != nil {
 		gzwErr := gzw.Close()
-		return errors.Compose(err, twErr, gzwErr)
+		return nil, errors.Compose(err, twErr, gzwErr)
 	}
 	_, err = gzw.Write(allowanceBytes)
 	if err != nil {
 		gzwErr := gzw.Close()
-		return errors.Compose(err, twErr, gzwErr)
+		return nil, errors.Compose(err, twErr, gzwErr)
 	}
 	// Close the gzip writer to flush it.
 	gzwErr := gzw.Close()
 	// Write the hash to the beginning of the file.
 	_, err = f.WriteAt(h.Sum(nil), 0)
-	return errors.Compose(err, twErr, gzwErr)
+	if err := errors.Compose(err, twErr, gzwErr); err != nil {
+		return nil, err
+	}
+	return manifest, nil
 }
 
 // LoadBackup loads the siafiles of a previously created backup into the
@@ -150,77 +191,106 @@ func (r *Renter) LoadBackup(src string, secret []byte) (err error) {
 	defer func() {
 		err = errors.AddContext(errors.Compose(err, f.Close()), "Error loading backup "+src)
 	}()
-	archive := io.Reader(f)
-
-	// Read the checksum.
-	var chks crypto.Hash
-	_, err = io.ReadFull(f, chks[:])
+	archive, _, err := openBackupArchive(f, secret)
 	if err != nil {
 		return err
 	}
-	// Read the header.
-	dec := json.NewDecoder(archive)
-	var bh backupHeader
-	if err := dec.Decode(&bh); err != nil {
-		return err
-	}
-	// Check the version number.
-	if bh.Version != encryptionVersion {
-		return errors.New("unknown version")
-	}
-	// Wrap the file in the correct streamcipher. Consider the data remaining in
-	// the decoder's buffer by using a multireader.
-	archive = io.MultiReader(dec.Buffered(), archive)
-	_, err = archive.Read(make([]byte, 1)) // Ignore first byte of buffer to get to the body of the backup
+	// Wrap the potentially encrypted reader in a gzip reader.
+	gzr, err := gzip.NewReader(archive)
 	if err != nil {
 		return err
 	}
-	archive, err = wrapReaderInCipher(io.MultiReader(archive, f), bh, secret)
-	if err != nil {
+	defer gzr.Close()
+	// Wrap the gzip reader in a tar reader.
+	tr := tar.NewReader(gzr)
+	// Untar the files.
+	if err := r.managedUntarDir(tr); err != nil {
+		return errors.AddContext(err, "failed to untar dir")
+	}
+	return r.managedLoadBackupAllowance(gzr)
+}
+
+// RestoreBackup downloads the uploaded backup with the given name and loads it
+// into the renter. Incremental backups are restored together with the backups
+// they depend on.
+func (r *Renter) RestoreBackup(name string, secret []byte) error {
+	if err := r.tg.Add(); err != nil {
 		return err
 	}
-	// Pipe the remaining file into the hasher to verify that the hash is
-	// correct.
-	h := crypto.NewHash()
-	n, err := io.Copy(h, archive)
+	defer r.tg.Done()
+
+	tmpDir, err := ioutil.TempDir("", "sia-backup")
 	if err != nil {
 		return err
 	}
-	// Verify the hash.
-	if !bytes.Equal(h.Sum(nil), chks[:]) {
-		return errors.New("checksum doesn't match")
-	}
-	// Seek back to the beginning of the body.
-	if _, err := f.Seek(-n, io.SeekCurrent); err != nil {
-		return err
+	defer os.RemoveAll(tmpDir)
+
+	// Download the backup and all its ancestors. The chain is ordered from
+	// the full backup to the requested one.
+	var chain []string
+	seen := make(map[string]struct{})
+	for name != "" {
+		if _, exists := seen[name]; exists {
+			return fmt.Errorf("backup %v depends on itself", name)
+		}
+		seen[name] = struct{}{}
+		path := filepath.Join(tmpDir, fmt.Sprintf("%v.backup", len(chain)))
+		if err := r.DownloadBackup(path, name); err != nil {
+			return errors.AddContext(err, fmt.Sprintf("failed to download backup %v", name))
+		}
+		bh, err := readBackupHeader(path)
+		if err != nil {
+			return errors.AddContext(err, fmt.Sprintf("failed to read header of backup %v", name))
+		}
+		chain = append([]string{path}, chain...)
+		name = bh.Parent
 	}
-	// Wrap the file again.
-	archive, err = wrapReaderInCipher(f, bh, secret)
+	return r.managedLoadBackupChain(chain, secret)
+}
+
+// managedLoadBackupChain loads a chain of backups which starts with a full
+// backup followed by incremental backups. Every siafile is restored from the
+// most recent backup that contains it, and siafiles which are not part of the
+// last backup's manifest are skipped.
+func (r *Renter) managedLoadBackupChain(chain []string, secret []byte) error {
+	tmpDir, err := ioutil.TempDir("", "sia-backup-chain")
 	if err != nil {
 		return err
 	}
-	// Wrap the potentially encrypted reader in a gzip reader.
-	gzr, err := gzip.NewReader(archive)
-	if err != nil {
-		return err
+	defer os.RemoveAll(tmpDir)
+
+	// Extract the backups on top of each other.
+	var manifest map[string]struct{}
+	var allowance []byte
+	for _, path := range chain {
+		manifest, allowance, err = extractBackup(path, secret, tmpDir)
+		if err != nil {
+			return errors.AddContext(err, "failed to extract backup "+path)
+		}
 	}
-	defer gzr.Close()
-	// Wrap the gzip reader in a tar reader.
-	tr := tar.NewReader(gzr)
-	// Untar the files.
-	if err := r.managedUntarDir(tr); err != nil {
+	// Stream the merged files into the renter.
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tarBackupDir(tmpDir, manifest, pw))
+	}()
+	err = r.managedUntarDir(tar.NewReader(pr))
+	err = errors.Compose(err, pr.Close())
+	if err != nil {
 		return errors.AddContext(err, "failed to untar dir")
 	}
-	// Unmarshal the allowance if available. This needs to happen after adding
-	// decryption and confirming the hash but before adding decompression.
-	dec = json.NewDecoder(gzr)
+	return r.managedLoadBackupAllowance(bytes.NewReader(allowance))
+}
+
+// managedLoadBackupAllowance decodes the allowance stored at the end of a
+// backup. If the backup contained a valid allowance and the renter doesn't have
+// an allowance yet, the backup's allowance is set.
+func (r *Renter) managedLoadBackupAllowance(src io.Reader) error {
+	dec := json.NewDecoder(src)
 	var allowance modules.Allowance
 	if err := dec.Decode(&allowance); err != nil {
 		// legacy backup without allowance
 		r.log.Println("WARN: Decoding the backup's allowance failed: ", err)
 	}
-	// If the backup contained a valid allowance and we currently don't have an
-	// allowance set, import it.
 	if !reflect.DeepEqual(allowance, modules.Allowance{}) &&
 		reflect.DeepEqual(r.hostContractor.Allowance(), modules.Allowance{}) {
 		if err := r.hostContractor.SetAllowance(allowance); err != nil {
@@ -231,11 +301,14 @@ func (r *Renter) LoadBackup(src string, secret []byte) (err error) {
 }
 
 // managedTarSiaFiles creates a tarball from the renter's siafiles and writes
-// it to dst.
-func (r *Renter) managedTarSiaFiles(tw *tar.Writer) error {
+// it to dst. If inc is not nil, siafiles which didn't change since the backup
+// described by inc are skipped. The returned manifest contains all siafiles
+// regardless.
+func (r *Renter) managedTarSiaFiles(tw *tar.Writer, inc *backupIncrement) (map[string]siafile.SiafileUID, error) {
+	manifest := make(map[string]siafile.SiafileUID)
 	// Walk over all the siafiles in in the user's home and add them to the
 	// tarball.
-	return r.staticFileSystem.Walk(modules.UserFolder, func(path string, info os.FileInfo, err error) error {
+	err := r.staticFileSystem.Walk(modules.UserFolder, func(path string, info os.FileInfo, err error) error {
 		// This error is non-nil if filepath.Walk couldn't stat a file or
 		// folder.
 		if err != nil {
@@ -271,6 +344,11 @@ func (r *Renter) managedTarSiaFiles(tw *tar.Writer) error {
 				return err
 			}
 			defer entry.Close()
+			// Skip the siafile if it didn't change since the previous backup.
+			manifest[relPath] = entry.UID()
+			if inc != nil && !entry.ChangeTime().After(inc.since) && inc.previous[relPath] == entry.UID() {
+				return nil
+			}
 			// Get a reader to read from the siafile.
 			sr, err := entry.SnapshotReader()
 			if err != nil {
@@ -325,6 +403,10 @@ func (r *Renter) managedTarSiaFiles(tw *tar.Writer) error {
 		_, err = io.Copy(tw, file)
 		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
 }
 
 // managedUntarDir untars the archive from src and writes the contents to dstFolder
@@ -443,3 +525,201 @@ func wrapReaderInCipher(r io.Reader, bh backupHeader, secret []byte) (io.Reader,
 		return nil, errors.New("unknown cipher")
 	}
 }
+
+// openBackupArchive reads the checksum and header of the backup in f and
+// returns a reader for the decrypted body of the backup. The checksum is
+// verified before returning.
+func openBackupArchive(f *os.File, secret []byte) (io.Reader, backupHeader, error) {
+	// Read the checksum.
+	var chks crypto.Hash
+	if _, err := io.ReadFull(f, chks[:]); err != nil {
+		return nil, backupHeader{}, err
+	}
+	// Read the header.
+	dec := json.NewDecoder(f)
+	var bh backupHeader
+	if err := dec.Decode(&bh); err != nil {
+		return nil, backupHeader{}, err
+	}
+	// Check the version number.
+	if bh.Version != encryptionVersion {
+		return nil, backupHeader{}, errors.New("unknown version")
+	}
+	// The body starts after the newline which terminates the header.
+	bodyOff := int64(crypto.HashSize) + dec.InputOffset() + 1
+	if _, err := f.Seek(bodyOff, io.SeekStart); err != nil {
+		return nil, backupHeader{}, err
+	}
+	// Pipe the body into the hasher to verify that the hash is correct.
+	archive, err := wrapReaderInCipher(f, bh, secret)
+	if err != nil {
+		return nil, backupHeader{}, err
+	}
+	h := crypto.NewHash()
+	if _, err := io.Copy(h, archive); err != nil {
+		return nil, backupHeader{}, err
+	}
+	if !bytes.Equal(h.Sum(nil), chks[:]) {
+		return nil, backupHeader{}, errors.New("checksum doesn't match")
+	}
+	// Seek back to the beginning of the body and wrap the file again.
+	if _, err := f.Seek(bodyOff, io.SeekStart); err != nil {
+		return nil, backupHeader{}, err
+	}
+	archive, err = wrapReaderInCipher(f, bh, secret)
+	if err != nil {
+		return nil, backupHeader{}, err
+	}
+	return archive, bh, nil
+}
+
+// readBackupHeader reads the header of the backup at path without decrypting
+// the backup.
+func readBackupHeader(path string) (_ backupHeader, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return backupHeader{}, err
+	}
+	defer func() {
+		err = errors.Compose(err, f.Close())
+	}()
+	if _, err := f.Seek(crypto.HashSize, io.SeekStart); err != nil {
+		return backupHeader{}, err
+	}
+	var bh backupHeader
+	err = json.NewDecoder(f).Decode(&bh)
+	return bh, err
+}
+
+// extractBackup extracts the backup at path into dir, overwriting existing
+// files. It returns the backup's manifest and the encoded allowance. The
+// manifest is nil for backups which were created without one.
+func extractBackup(path string, secret []byte, dir string) (manifest map[string]struct{}, allowance []byte, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		err = errors.Compose(err, f.Close())
+	}()
+	archive, _, err := openBackupArchive(f, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	gzr, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gzr.Close()
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, errors.AddContext(err, "could not get next entry in the tar archive")
+		}
+		if filepath.Base(header.Name) == backupManifestName {
+			var names []string
+			if err := json.NewDecoder(tr).Decode(&names); err != nil {
+				return nil, nil, errors.AddContext(err, "could not decode backup manifest")
+			}
+			manifest = make(map[string]struct{}, len(names))
+			for _, name := range names {
+				manifest[name] = struct{}{}
+			}
+			continue
+		}
+		//G305: File traversal when extracting zip/tar archive (gosec)
+		dst := filepath.Join(dir, header.Name) //nolint:gosec
+		if !strings.HasPrefix(dst, filepath.Clean(dir)) {
+			return nil, nil, fmt.Errorf("invalid path in backup: %v", header.Name)
+		}
+		info := header.FileInfo()
+		if info.IsDir() {
+			if err := os.MkdirAll(dst, info.Mode()|0700); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), modules.DefaultDirPerm); err != nil {
+			return nil, nil, err
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := ioutil.WriteFile(dst, b, modules.DefaultFilePerm); err != nil {
+			return nil, nil, err
+		}
+	}
+	// The allowance follows the tarball.
+	allowance, err = ioutil.ReadAll(gzr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, allowance, nil
+}
+
+// tarBackupDir writes the contents of a directory created by extractBackup to
+// w as a tarball which can be read by managedUntarDir. If manifest is not nil,
+// siafiles which are not part of the manifest are skipped.
+func tarBackupDir(dir string, manifest map[string]struct{}, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path, dir)
+		if name == "" {
+			return nil
+		}
+		if !info.IsDir() && filepath.Ext(path) == modules.SiaFileExtension && manifest != nil {
+			if _, exists := manifest[name]; !exists {
+				return nil
+			}
+		}
+		header, err := tar.FileInfoHeader(info, info.Name())
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, file)
+		return errors.Compose(err, file.Close())
+	})
+	return errors.Compose(err, tw.Close())
+}
+
+// writeBackupManifest adds the manifest of a backup to the tarball.
+func writeBackupManifest(tw *tar.Writer, manifest map[string]siafile.SiafileUID) error {
+	names := make([]string, 0, len(manifest))
+	for name := range manifest {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b, err := json.Marshal(names)
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    string(filepath.Separator) + backupManifestName,
+		Mode:    int64(modules.DefaultFilePerm),
+		Size:    int64(len(b)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(b)
+	return err
+}
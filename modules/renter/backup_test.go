@@ -0,0 +1,133 @@
+package renter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/persist"
+)
+
+// TestBackupChain tests creating a full and an incremental backup and
+// restoring them as a chain.
+func TestBackupChain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	// newFile is a helper to add a siafile to the renter.
+	newFile := func(name string) modules.SiaPath {
+		siaPath, err := modules.UserFolder.Join(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, rsc := testingFileParams()
+		err = r.staticFileSystem.NewSiaFile(siaPath, "", rsc, crypto.GenerateSiaKey(crypto.RandomCipherType()), 100, persist.DefaultDiskPermissionsTest, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return siaPath
+	}
+	// exists is a helper to check whether a siafile exists.
+	exists := func(siaPath modules.SiaPath) bool {
+		_, err := os.Stat(siaPath.SiaFileSysPath(r.staticFileSystem.Root()))
+		return err == nil
+	}
+	// siaFiles is a helper that returns the names of the siafiles in a
+	// directory created by extractBackup.
+	siaFiles := func(dir string) map[string]struct{} {
+		files := make(map[string]struct{})
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && filepath.Ext(path) == modules.SiaFileExtension {
+				files[info.Name()] = struct{}{}
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+
+	dir, err := ioutil.TempDir("", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	secret := fastrand.Bytes(32)
+
+	// Create a full backup with two files.
+	foo, bar := newFile("foo"), newFile("bar")
+	fullPath := filepath.Join(dir, "full")
+	manifest, err := r.managedCreateBackupArchive(fullPath, secret, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest) != 2 {
+		t.Fatal("expected 2 files in the manifest but got", len(manifest))
+	}
+
+	// Delete one file, add another and create an incremental backup.
+	since := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := r.staticFileSystem.DeleteFile(bar); err != nil {
+		t.Fatal(err)
+	}
+	baz := newFile("baz")
+	incPath := filepath.Join(dir, "inc")
+	inc := &backupIncrement{
+		parent:   "full",
+		since:    since,
+		previous: manifest,
+	}
+	if _, err := r.managedCreateBackupArchive(incPath, secret, inc); err != nil {
+		t.Fatal(err)
+	}
+	bh, err := readBackupHeader(incPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bh.Parent != "full" {
+		t.Fatal("wrong parent", bh.Parent)
+	}
+	// The incremental backup should only contain the new file.
+	extracted := filepath.Join(dir, "extracted")
+	incManifest, _, err := extractBackup(incPath, secret, extracted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := siaFiles(extracted); len(files) != 1 {
+		t.Fatal("expected the incremental backup to contain 1 file", files)
+	}
+	if len(incManifest) != 2 {
+		t.Fatal("expected 2 files in the manifest but got", len(incManifest))
+	}
+
+	// Delete all the files and restore the chain.
+	for _, siaPath := range []modules.SiaPath{foo, baz} {
+		if err := r.staticFileSystem.DeleteFile(siaPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.managedLoadBackupChain([]string{fullPath, incPath}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(foo) || !exists(baz) {
+		t.Fatal("files weren't restored")
+	}
+	if exists(bar) {
+		t.Fatal("deleted file was restored")
+	}
+}
@@ -0,0 +1,438 @@
+package renter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/proto"
+	"gitlab.com/scpcorp/ScPrime/persist"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+const (
+	// backupScheduleFilename is the filename of the renter's backup schedule.
+	backupScheduleFilename = "backupschedule.json"
+
+	// scheduledBackupPrefix is the prefix of the names of scheduled backups.
+	scheduledBackupPrefix = "scheduled-"
+)
+
+var (
+	// backupScheduleMetadata is the header of the persisted backup schedule.
+	backupScheduleMetadata = persist.Metadata{
+		Header:  "Renter Backup Schedule",
+		Version: "1.5.4",
+	}
+)
+
+type (
+	// backupScheduler keeps track of the renter's scheduled backups.
+	backupScheduler struct {
+		persist    backupSchedulerPersist
+		staticPath string
+		mu         sync.Mutex
+	}
+
+	// backupSchedulerPersist is the persisted state of the backupScheduler.
+	backupSchedulerPersist struct {
+		Schedule modules.BackupSchedule    `json:"schedule"`
+		Backups  []modules.ScheduledBackup `json:"backups"`
+
+		// Manifest is the manifest of the most recent scheduled backup. It is
+		// used to find the siafiles that changed since then.
+		Manifest map[string]siafile.SiafileUID `json:"manifest"`
+
+		// Pruned maps the hex encoded UIDs of pruned backups to the time they
+		// were pruned at. They are removed from hosts until they expire.
+		Pruned map[string]types.Timestamp `json:"pruned"`
+	}
+)
+
+// newBackupScheduler loads the backup schedule at the given path or creates a
+// new one with the default schedule if it doesn't exist yet.
+func newBackupScheduler(path string) (*backupScheduler, error) {
+	bs := &backupScheduler{
+		persist: backupSchedulerPersist{
+			Schedule: modules.DefaultBackupSchedule,
+		},
+		staticPath: path,
+	}
+	err := persist.LoadJSON(backupScheduleMetadata, &bs.persist, path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.AddContext(err, "failed to load backup schedule")
+	}
+	if bs.persist.Pruned == nil {
+		bs.persist.Pruned = make(map[string]types.Timestamp)
+	}
+	return bs, nil
+}
+
+// chainLength returns the number of incremental backups in the chain leading
+// up to the backup at index i.
+func chainLength(backups []modules.ScheduledBackup, i int) uint64 {
+	var n uint64
+	for ; i >= 0 && backups[i].Parent != ""; i-- {
+		n++
+	}
+	return n
+}
+
+// retainedBackups returns the names of the scheduled backups which are kept
+// according to the retention policy of the schedule. The backups need to be
+// sorted from oldest to newest. The most recent backup and all the backups a
+// retained backup depends on are always kept.
+func retainedBackups(backups []modules.ScheduledBackup, schedule modules.BackupSchedule) map[string]struct{} {
+	keep := make(map[string]struct{})
+	if len(backups) == 0 {
+		return keep
+	}
+	if schedule.RetainHourly == 0 && schedule.RetainDaily == 0 && schedule.RetainWeekly == 0 {
+		for _, b := range backups {
+			keep[b.Name] = struct{}{}
+		}
+		return keep
+	}
+	keep[backups[len(backups)-1].Name] = struct{}{}
+
+	// Keep the most recent backup of each of the last n buckets.
+	retain := func(n uint64, bucket func(time.Time) string) {
+		seen := make(map[string]struct{})
+		for i := len(backups) - 1; i >= 0 && uint64(len(seen)) < n; i-- {
+			b := bucket(time.Unix(int64(backups[i].CreationDate), 0).UTC())
+			if _, exists := seen[b]; exists {
+				continue
+			}
+			seen[b] = struct{}{}
+			keep[backups[i].Name] = struct{}{}
+		}
+	}
+	retain(schedule.RetainHourly, func(t time.Time) string { return t.Format("2006010215") })
+	retain(schedule.RetainDaily, func(t time.Time) string { return t.Format("20060102") })
+	retain(schedule.RetainWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%v-%v", year, week)
+	})
+
+	// Keep the ancestors of retained backups. Since parents are always older
+	// than their children, a single pass from newest to oldest is enough.
+	for i := len(backups) - 1; i >= 0; i-- {
+		if _, kept := keep[backups[i].Name]; kept && backups[i].Parent != "" {
+			keep[backups[i].Parent] = struct{}{}
+		}
+	}
+	return keep
+}
+
+// managedAddBackup adds a new scheduled backup and its manifest to the
+// scheduler.
+func (bs *backupScheduler) managedAddBackup(b modules.ScheduledBackup, manifest map[string]siafile.SiafileUID) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.persist.Backups = append(bs.persist.Backups, b)
+	bs.persist.Manifest = manifest
+	return bs.save()
+}
+
+// managedIsPruned returns whether the backup with the given UID was pruned.
+func (bs *backupScheduler) managedIsPruned(uid [16]byte) bool {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	_, pruned := bs.persist.Pruned[hex.EncodeToString(uid[:])]
+	return pruned
+}
+
+// managedNextBackup returns whether a scheduled backup is due at the given
+// time. If the next backup should be incremental, the returned backupIncrement
+// is not nil.
+func (bs *backupScheduler) managedNextBackup(now time.Time) (*backupIncrement, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	schedule := bs.persist.Schedule
+	if schedule.Interval == 0 {
+		return nil, false
+	}
+	if len(bs.persist.Backups) == 0 {
+		return nil, true
+	}
+	i := len(bs.persist.Backups) - 1
+	last := bs.persist.Backups[i]
+	since := time.Unix(int64(last.CreationDate), 0)
+	if now.Before(since.Add(schedule.Interval)) {
+		return nil, false
+	}
+	if schedule.FullInterval == 0 || chainLength(bs.persist.Backups, i) >= schedule.FullInterval {
+		return nil, true
+	}
+	return &backupIncrement{
+		parent:   last.Name,
+		since:    since,
+		previous: bs.persist.Manifest,
+	}, true
+}
+
+// managedPrune removes the backups which are not retained anymore and returns
+// them. It also returns the UIDs of all pruned backups which haven't expired
+// yet.
+func (bs *backupScheduler) managedPrune(now time.Time) ([]modules.ScheduledBackup, map[[16]byte]struct{}, error) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	keep := retainedBackups(bs.persist.Backups, bs.persist.Schedule)
+	var pruned []modules.ScheduledBackup
+	backups := bs.persist.Backups[:0]
+	for _, b := range bs.persist.Backups {
+		if _, kept := keep[b.Name]; kept {
+			backups = append(backups, b)
+			continue
+		}
+		pruned = append(pruned, b)
+		bs.persist.Pruned[hex.EncodeToString(b.UID[:])] = types.Timestamp(now.Unix())
+	}
+	bs.persist.Backups = backups
+
+	// Forget about backups which were pruned a long time ago.
+	uids := make(map[[16]byte]struct{}, len(bs.persist.Pruned))
+	for uidStr, prunedAt := range bs.persist.Pruned {
+		if now.Sub(time.Unix(int64(prunedAt), 0)) > prunedBackupExpiry {
+			delete(bs.persist.Pruned, uidStr)
+			continue
+		}
+		var uid [16]byte
+		if b, err := hex.DecodeString(uidStr); err == nil {
+			copy(uid[:], b)
+			uids[uid] = struct{}{}
+		}
+	}
+	return pruned, uids, bs.save()
+}
+
+// managedSchedule returns the schedule and a copy of the scheduled backups.
+func (bs *backupScheduler) managedSchedule() (modules.BackupSchedule, []modules.ScheduledBackup) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.persist.Schedule, append([]modules.ScheduledBackup{}, bs.persist.Backups...)
+}
+
+// managedSetSchedule updates the schedule.
+func (bs *backupScheduler) managedSetSchedule(schedule modules.BackupSchedule) error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.persist.Schedule = schedule
+	return bs.save()
+}
+
+// save persists the scheduler.
+func (bs *backupScheduler) save() error {
+	return persist.SaveJSON(backupScheduleMetadata, bs.persist, bs.staticPath)
+}
+
+// BackupSchedule returns the backup schedule and the retained scheduled
+// backups.
+func (r *Renter) BackupSchedule() (modules.BackupSchedule, []modules.ScheduledBackup) {
+	return r.staticBackupScheduler.managedSchedule()
+}
+
+// SetBackupSchedule updates the backup schedule.
+func (r *Renter) SetBackupSchedule(schedule modules.BackupSchedule) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	return r.staticBackupScheduler.managedSetSchedule(schedule)
+}
+
+// threadedBackupScheduler periodically creates scheduled backups and prunes
+// the ones which are not retained anymore.
+func (r *Renter) threadedBackupScheduler() {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+	for {
+		select {
+		case <-time.After(backupSchedulerSleepDuration):
+		case <-r.tg.StopChan():
+			return
+		}
+		// Can't create backups without an unlocked wallet or allowance.
+		if unlocked, _ := r.w.Unlocked(); !unlocked {
+			continue
+		}
+		if !r.hostContractor.Allowance().Active() {
+			continue
+		}
+		if err := r.managedCreateScheduledBackup(time.Now()); err != nil {
+			r.log.Println("Failed to create scheduled backup:", err)
+		}
+	}
+}
+
+// managedCreateScheduledBackup creates and uploads a scheduled backup if one
+// is due and prunes old backups afterwards.
+func (r *Renter) managedCreateScheduledBackup(now time.Time) error {
+	inc, due := r.staticBackupScheduler.managedNextBackup(now)
+	if !due {
+		return nil
+	}
+
+	// Get the wallet seed.
+	ws, _, err := r.w.PrimarySeed()
+	if err != nil {
+		return errors.AddContext(err, "failed to get wallet's primary seed")
+	}
+	// Derive the renter seed and wipe the memory once we are done using it.
+	rs := proto.DeriveRenterSeed(ws)
+	defer fastrand.Read(rs[:])
+	// Derive the secret and wipe it afterwards.
+	secret := crypto.HashAll(rs, modules.BackupKeySpecifier)
+	defer fastrand.Read(secret[:])
+
+	// Create the backup in a temporary file and upload it.
+	tmpDir, err := ioutil.TempDir("", "sia-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	name := scheduledBackupPrefix + now.UTC().Format("20060102T150405Z")
+	path := filepath.Join(tmpDir, name)
+	manifest, err := r.managedCreateBackupArchive(path, secret[:], inc)
+	if err != nil {
+		return errors.AddContext(err, "failed to create backup")
+	}
+	if err := r.managedUploadBackup(path, name); err != nil {
+		return errors.AddContext(err, "failed to upload backup")
+	}
+	sb := modules.ScheduledBackup{
+		Name:         name,
+		CreationDate: types.Timestamp(now.Unix()),
+	}
+	if inc != nil {
+		sb.Parent = inc.parent
+	}
+	id := r.mu.RLock()
+	for _, ub := range r.persist.UploadedBackups {
+		if ub.Name == name {
+			sb.UID = ub.UID
+			break
+		}
+	}
+	r.mu.RUnlock(id)
+	if err := r.staticBackupScheduler.managedAddBackup(sb, manifest); err != nil {
+		return errors.AddContext(err, "failed to save scheduled backup")
+	}
+
+	// Prune the backups which are not retained anymore.
+	pruned, prunedUIDs, err := r.staticBackupScheduler.managedPrune(now)
+	if err != nil {
+		return errors.AddContext(err, "failed to prune scheduled backups")
+	}
+	if err := r.managedRemoveSnapshots(pruned); err != nil {
+		return errors.AddContext(err, "failed to remove pruned backups")
+	}
+	if len(prunedUIDs) > 0 {
+		r.managedRemoveSnapshotsFromHosts(prunedUIDs)
+	}
+	return nil
+}
+
+// managedRemoveSnapshots removes the given scheduled backups from the renter's
+// list of uploaded backups. Backups which are still being uploaded are
+// deleted.
+func (r *Renter) managedRemoveSnapshots(backups []modules.ScheduledBackup) error {
+	if len(backups) == 0 {
+		return nil
+	}
+	remove := make(map[[16]byte]struct{}, len(backups))
+	for _, b := range backups {
+		remove[b.UID] = struct{}{}
+		sp, err := modules.BackupFolder.Join(b.Name)
+		if err != nil {
+			return err
+		}
+		err = r.staticFileSystem.DeleteFile(sp)
+		if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+			return err
+		}
+	}
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	ubs := r.persist.UploadedBackups[:0]
+	for _, ub := range r.persist.UploadedBackups {
+		if _, removed := remove[ub.UID]; !removed {
+			ubs = append(ubs, ub)
+		}
+	}
+	r.persist.UploadedBackups = ubs
+	return r.saveSync()
+}
+
+// managedRemoveSnapshotsFromHosts removes the snapshots with the given UIDs
+// from the snapshot tables of all the hosts the renter has a contract with
+// that is good for upload. Errors are logged since hosts which are
+// unavailable will be tried again after the next scheduled backup. The
+// sectors of the removed snapshots are not deleted from the hosts.
+func (r *Renter) managedRemoveSnapshotsFromHosts(uids map[[16]byte]struct{}) {
+	for _, c := range r.hostContractor.Contracts() {
+		if !c.Utility.GoodForUpload {
+			continue
+		}
+		if err := r.managedRemoveSnapshotsHost(c.HostPublicKey, uids); err != nil {
+			r.log.Debugf("Failed to remove pruned snapshots from host %v: %v", c.HostPublicKey, err)
+		}
+	}
+}
+
+// managedRemoveSnapshotsHost removes the snapshots with the given UIDs from
+// the snapshot table of a single host.
+func (r *Renter) managedRemoveSnapshotsHost(hostKey types.SiaPublicKey, uids map[[16]byte]struct{}) error {
+	session, err := r.hostContractor.Session(hostKey, r.tg.StopChan())
+	if err != nil {
+		return errors.AddContext(err, "unable to get host session")
+	}
+	defer session.Close()
+	entryTable, err := r.managedDownloadSnapshotTableRHP2(session)
+	if err != nil {
+		return errors.AddContext(err, "could not download the snapshot table")
+	}
+	newTable := make([]snapshotEntry, 0, len(entryTable))
+	for _, entry := range entryTable {
+		if _, remove := uids[entry.UID]; !remove {
+			newTable = append(newTable, entry)
+		}
+	}
+	if len(newTable) == len(entryTable) {
+		return nil // nothing to remove
+	}
+
+	// Get the wallet seed.
+	ws, _, err := r.w.PrimarySeed()
+	if err != nil {
+		return errors.AddContext(err, "failed to get wallet's primary seed")
+	}
+	// Derive the renter seed and wipe the memory once we are done using it.
+	rs := proto.DeriveRenterSeed(ws)
+	defer fastrand.Read(rs[:])
+	// Derive the secret and wipe it afterwards.
+	secret := crypto.HashAll(rs, snapshotKeySpecifier)
+	defer fastrand.Read(secret[:])
+
+	// Swap the new entry table into index 0 and delete the old one.
+	if _, err := session.Replace(encryptSnapshotTable(newTable, secret), 0, true); err != nil {
+		return errors.AddContext(err, "could not perform sector replace for the snapshot table")
+	}
+	return nil
+}
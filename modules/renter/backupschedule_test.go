@@ -0,0 +1,175 @@
+package renter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+	"gitlab.com/scpcorp/ScPrime/persist"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestRetainedBackups tests the retention policy of scheduled backups.
+func TestRetainedBackups(t *testing.T) {
+	// Create hourly backups for 3 days where every 6th backup is a full
+	// backup.
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var backups []modules.ScheduledBackup
+	for i := 0; i < 72; i++ {
+		b := modules.ScheduledBackup{
+			Name:         start.Add(time.Duration(i) * time.Hour).Format("2006010215"),
+			CreationDate: types.Timestamp(start.Add(time.Duration(i) * time.Hour).Unix()),
+		}
+		if i%6 != 0 {
+			b.Parent = backups[i-1].Name
+		}
+		backups = append(backups, b)
+	}
+
+	// Without any retention all backups are kept.
+	keep := retainedBackups(backups, modules.BackupSchedule{})
+	if len(keep) != len(backups) {
+		t.Fatalf("expected %v backups to be kept but got %v", len(backups), len(keep))
+	}
+	// Keeping a single hourly backup keeps the most recent backup and its
+	// ancestors up to the last full backup.
+	keep = retainedBackups(backups, modules.BackupSchedule{RetainHourly: 1})
+	if len(keep) != 6 {
+		t.Fatalf("expected %v backups to be kept but got %v", 6, len(keep))
+	}
+	for _, b := range backups[66:] {
+		if _, kept := keep[b.Name]; !kept {
+			t.Fatal("backup wasn't kept", b.Name)
+		}
+	}
+	// Keeping 3 daily backups keeps the last backup of each day and their
+	// ancestors.
+	keep = retainedBackups(backups, modules.BackupSchedule{RetainDaily: 3})
+	if len(keep) != 18 {
+		t.Fatalf("expected %v backups to be kept but got %v", 18, len(keep))
+	}
+	for _, i := range []int{18, 23, 42, 47, 66, 71} {
+		if _, kept := keep[backups[i].Name]; !kept {
+			t.Fatal("backup wasn't kept", backups[i].Name)
+		}
+	}
+	// Only the full backups are kept if the retention doesn't require any
+	// incremental backups.
+	for i := range backups {
+		backups[i].Parent = ""
+	}
+	keep = retainedBackups(backups, modules.BackupSchedule{RetainHourly: 2, RetainDaily: 2})
+	if len(keep) != 3 {
+		t.Fatalf("expected %v backups to be kept but got %v", 3, len(keep))
+	}
+}
+
+// TestBackupScheduler tests scheduling and pruning backups and the
+// persistence of the backupScheduler.
+func TestBackupScheduler(t *testing.T) {
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, backupScheduleFilename)
+	bs, err := newBackupScheduler(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The default schedule is disabled.
+	now := time.Now()
+	if _, due := bs.managedNextBackup(now); due {
+		t.Fatal("backup shouldn't be due with the default schedule")
+	}
+	schedule := modules.BackupSchedule{
+		Interval:     time.Hour,
+		FullInterval: 2,
+		RetainHourly: 1,
+	}
+	if err := bs.managedSetSchedule(schedule); err != nil {
+		t.Fatal(err)
+	}
+	// The first backup is a full backup.
+	inc, due := bs.managedNextBackup(now)
+	if !due || inc != nil {
+		t.Fatal("expected full backup to be due", inc, due)
+	}
+	manifest := map[string]siafile.SiafileUID{"/foo.sia": "foo"}
+	full := modules.ScheduledBackup{Name: "full", UID: [16]byte{1}, CreationDate: types.Timestamp(now.Unix())}
+	if err := bs.managedAddBackup(full, manifest); err != nil {
+		t.Fatal(err)
+	}
+	// No backup is due before the interval passed.
+	if _, due := bs.managedNextBackup(now.Add(time.Minute)); due {
+		t.Fatal("backup shouldn't be due yet")
+	}
+	// The next two backups are incremental.
+	now = now.Add(time.Hour)
+	inc, due = bs.managedNextBackup(now)
+	if !due || inc == nil || inc.parent != full.Name || inc.previous["/foo.sia"] != "foo" {
+		t.Fatal("expected incremental backup to be due", inc, due)
+	}
+	inc1 := modules.ScheduledBackup{Name: "inc1", UID: [16]byte{2}, CreationDate: types.Timestamp(now.Unix()), Parent: full.Name}
+	if err := bs.managedAddBackup(inc1, manifest); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	inc, due = bs.managedNextBackup(now)
+	if !due || inc == nil || inc.parent != inc1.Name {
+		t.Fatal("expected incremental backup to be due", inc, due)
+	}
+	inc2 := modules.ScheduledBackup{Name: "inc2", UID: [16]byte{3}, CreationDate: types.Timestamp(now.Unix()), Parent: inc1.Name}
+	if err := bs.managedAddBackup(inc2, manifest); err != nil {
+		t.Fatal(err)
+	}
+	// The chain is long enough, the next backup is a full one.
+	now = now.Add(time.Hour)
+	inc, due = bs.managedNextBackup(now)
+	if !due || inc != nil {
+		t.Fatal("expected full backup to be due", inc, due)
+	}
+	full2 := modules.ScheduledBackup{Name: "full2", UID: [16]byte{4}, CreationDate: types.Timestamp(now.Unix())}
+	if err := bs.managedAddBackup(full2, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pruning should remove the first chain.
+	pruned, uids, err := bs.managedPrune(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 3 || len(uids) != 3 {
+		t.Fatal("expected 3 pruned backups", pruned, uids)
+	}
+	if !bs.managedIsPruned(full.UID) || bs.managedIsPruned(full2.UID) {
+		t.Fatal("wrong backups were pruned")
+	}
+
+	// Reload the scheduler.
+	bs, err = newBackupScheduler(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, backups := bs.managedSchedule()
+	if s != schedule {
+		t.Fatal("schedule wasn't persisted", s)
+	}
+	if len(backups) != 1 || backups[0].Name != full2.Name {
+		t.Fatal("backups weren't persisted", backups)
+	}
+	if !bs.managedIsPruned(inc2.UID) {
+		t.Fatal("pruned backups weren't persisted")
+	}
+	// Pruned backups are forgotten after they expire.
+	_, uids, err = bs.managedPrune(now.Add(prunedBackupExpiry + time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uids) != 0 || bs.managedIsPruned(inc2.UID) {
+		t.Fatal("pruned backups should have expired", uids)
+	}
+}
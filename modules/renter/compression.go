@@ -0,0 +1,387 @@
+package renter
+
+// compression.go contains the helpers which allow for compressing files before
+// they are uploaded. A compressed file is split up into logical chunks of
+// ChunkSize bytes. Every logical chunk is compressed on its own and the
+// compressed chunks are concatenated to form the data which is then uploaded
+// like any other stream. The siafile keeps an offset index of the compressed
+// chunks which allows downloads and streams to fetch only the compressed
+// chunks which overlap with the requested range of logical data.
+//
+// The compressed data of an upload is kept in the compressedUploadsDir and
+// used as the local source of the siafile until the file reaches full health,
+// so failed or incomplete uploads can still be repaired.
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+)
+
+const (
+	// compressedUploadsDir is the directory within the renter's persist
+	// directory which contains the compressed data of uploads.
+	compressedUploadsDir = "compressed"
+)
+
+var (
+	// errCompressedRangeOutOfBounds is returned if the requested range of a
+	// compressed file is not covered by its offset index.
+	errCompressedRangeOutOfBounds = errors.New("requested range is out of bounds of the compressed file")
+)
+
+type (
+	// compressionReader is an io.Reader which reads logical chunks from an
+	// underlying reader, compresses them and returns the compressed data while
+	// building the offset index of the compressed chunks.
+	compressionReader struct {
+		staticChunkSize   uint64
+		staticCompression modules.CompressionType
+		staticSource      io.Reader
+
+		buf              []byte
+		chunks           []siafile.CompressedChunkInfo
+		err              error
+		storedSize       uint64
+		uncompressedSize uint64
+	}
+
+	// downloadDestinationDecompress is a downloadDestination which receives
+	// the compressed data of a range of compressed chunks, decompresses it and
+	// writes the requested range of logical data to the underlying writer.
+	// Since the download chunks can complete in an arbitrary order, the
+	// compressed chunks are buffered until all the prior chunks have been
+	// written.
+	downloadDestinationDecompress struct {
+		staticChunks         []siafile.CompressedChunkInfo
+		staticChunkSize      uint64
+		staticCompression    modules.CompressionType
+		staticPhysicalOffset uint64
+		staticW              io.Writer
+
+		buffers map[int][]byte
+		filled  map[int]uint64
+		next    int
+		skip    uint64
+		toWrite uint64
+		mu      sync.Mutex
+	}
+
+	// fileSectionWriteCloser is a sectionWriter which closes the underlying
+	// file when it is closed.
+	fileSectionWriteCloser struct {
+		*sectionWriter
+		f *os.File
+	}
+
+	// decompressStreamer is a modules.Streamer which wraps the streamer of the
+	// stored data of a compressed file and returns the logical data.
+	decompressStreamer struct {
+		staticChunks      []siafile.CompressedChunkInfo
+		staticChunkSize   uint64
+		staticCompression modules.CompressionType
+		staticSize        uint64
+		staticStreamer    modules.Streamer
+
+		// The most recently decompressed chunk is cached to avoid fetching it
+		// from the network again for every call to Read.
+		cachedChunk    []byte
+		cachedChunkIdx int
+
+		offset int64
+		mu     sync.Mutex
+	}
+)
+
+// newCompressionReader creates a new compressionReader.
+func newCompressionReader(r io.Reader, ct modules.CompressionType, chunkSize uint64) *compressionReader {
+	return &compressionReader{
+		staticChunkSize:   chunkSize,
+		staticCompression: ct,
+		staticSource:      r,
+	}
+}
+
+// Read implements the io.Reader interface.
+func (cr *compressionReader) Read(b []byte) (int, error) {
+	for len(cr.buf) == 0 {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		cr.compressNextChunk()
+	}
+	n := copy(b, cr.buf)
+	cr.buf = cr.buf[n:]
+	return n, nil
+}
+
+// compressNextChunk reads the next logical chunk from the source and
+// compresses it.
+func (cr *compressionReader) compressNextChunk() {
+	chunk := make([]byte, cr.staticChunkSize)
+	n, err := io.ReadFull(cr.staticSource, chunk)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if n > 0 {
+		compressed, cerr := modules.CompressChunk(cr.staticCompression, chunk[:n])
+		if cerr != nil {
+			cr.err = errors.AddContext(cerr, "failed to compress chunk")
+			return
+		}
+		cr.chunks = append(cr.chunks, siafile.CompressedChunkInfo{
+			Offset: cr.storedSize,
+			Length: uint64(len(compressed)),
+		})
+		cr.buf = compressed
+		cr.storedSize += uint64(len(compressed))
+		cr.uncompressedSize += uint64(n)
+	}
+	cr.err = err
+}
+
+// CompressedChunks returns the offset index of the chunks which have been
+// compressed so far and the total size of their logical data.
+func (cr *compressionReader) CompressedChunks() ([]siafile.CompressedChunkInfo, uint64) {
+	return cr.chunks, cr.uncompressedSize
+}
+
+// compressedRange translates a range of logical data of a compressed file into
+// the indices of the first and last compressed chunk containing that data and
+// the range of the stored data covering those chunks.
+func compressedRange(ccs []siafile.CompressedChunkInfo, chunkSize, offset, length uint64) (first, last int, storedOffset, storedLength uint64, err error) {
+	if length == 0 {
+		return 0, 0, 0, 0, errors.New("can't translate empty range")
+	}
+	first = int(offset / chunkSize)
+	last = int((offset + length - 1) / chunkSize)
+	if last >= len(ccs) {
+		return 0, 0, 0, 0, errCompressedRangeOutOfBounds
+	}
+	storedOffset = ccs[first].Offset
+	storedLength = ccs[last].Offset + ccs[last].Length - storedOffset
+	return
+}
+
+// Close implements the io.Closer interface.
+func (w *fileSectionWriteCloser) Close() error {
+	return w.f.Close()
+}
+
+// newDownloadDestinationDecompress creates a new decompressing destination for
+// the logical range [offset, offset+length) of a compressed file. The
+// download feeding the destination needs to fetch the range of stored data
+// returned by compressedRange.
+func newDownloadDestinationDecompress(w io.Writer, ct modules.CompressionType, ccs []siafile.CompressedChunkInfo, chunkSize, offset, length uint64) (*downloadDestinationDecompress, uint64, uint64, error) {
+	first, last, storedOffset, storedLength, err := compressedRange(ccs, chunkSize, offset, length)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return &downloadDestinationDecompress{
+		staticChunks:         ccs[first : last+1],
+		staticChunkSize:      chunkSize,
+		staticCompression:    ct,
+		staticPhysicalOffset: storedOffset,
+		staticW:              w,
+
+		buffers: make(map[int][]byte),
+		filled:  make(map[int]uint64),
+		skip:    offset - uint64(first)*chunkSize,
+		toWrite: length,
+	}, storedOffset, storedLength, nil
+}
+
+// Close implements the io.Closer interface. It closes the underlying writer if
+// possible.
+func (dd *downloadDestinationDecompress) Close() error {
+	if c, ok := dd.staticW.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// WritePieces recovers the compressed data from the pieces, adds it to the
+// buffers of the compressed chunks it belongs to and writes all the chunks
+// which are complete and up next to the underlying writer.
+func (dd *downloadDestinationDecompress) WritePieces(ec modules.ErasureCoder, pieces [][]byte, dataOffset uint64, writeOffset int64, length uint64) error {
+	var buf bytes.Buffer
+	err := ec.Recover(pieces, dataOffset+length, &skipWriter{writer: &buf, skip: int(dataOffset)})
+	if err != nil {
+		return errors.AddContext(err, "unable to recover compressed data")
+	}
+	data := buf.Bytes()
+	start := dd.staticPhysicalOffset + uint64(writeOffset)
+	end := start + uint64(len(data))
+
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+
+	// Find the first chunk which overlaps with the data.
+	i := sort.Search(len(dd.staticChunks), func(i int) bool {
+		cc := dd.staticChunks[i]
+		return cc.Offset+cc.Length > start
+	})
+	for ; i < len(dd.staticChunks) && dd.staticChunks[i].Offset < end; i++ {
+		cc := dd.staticChunks[i]
+		if i < dd.next {
+			continue // already written
+		}
+		if _, exists := dd.buffers[i]; !exists {
+			dd.buffers[i] = make([]byte, cc.Length)
+		}
+		// Copy the overlapping part of the data into the buffer.
+		from := start
+		if cc.Offset > from {
+			from = cc.Offset
+		}
+		to := end
+		if cc.Offset+cc.Length < to {
+			to = cc.Offset + cc.Length
+		}
+		copy(dd.buffers[i][from-cc.Offset:], data[from-start:to-start])
+		dd.filled[i] += to - from
+	}
+	return dd.writeCompleteChunks()
+}
+
+// writeCompleteChunks decompresses and writes all complete chunks which are up
+// next.
+func (dd *downloadDestinationDecompress) writeCompleteChunks() error {
+	for dd.next < len(dd.staticChunks) && dd.filled[dd.next] == dd.staticChunks[dd.next].Length {
+		chunk, err := modules.DecompressChunk(dd.staticCompression, dd.buffers[dd.next], dd.staticChunkSize)
+		if err != nil {
+			return errors.AddContext(err, "failed to decompress chunk")
+		}
+		delete(dd.buffers, dd.next)
+		delete(dd.filled, dd.next)
+		dd.next++
+
+		// Skip the logical data in front of the requested range and trim the
+		// data after it.
+		if dd.skip >= uint64(len(chunk)) {
+			dd.skip -= uint64(len(chunk))
+			continue
+		}
+		chunk = chunk[dd.skip:]
+		dd.skip = 0
+		if uint64(len(chunk)) > dd.toWrite {
+			chunk = chunk[:dd.toWrite]
+		}
+		if _, err := dd.staticW.Write(chunk); err != nil {
+			return errors.AddContext(err, "failed to write decompressed data")
+		}
+		dd.toWrite -= uint64(len(chunk))
+	}
+	return nil
+}
+
+// newDecompressStreamer wraps the streamer of the stored data of a compressed
+// file.
+func newDecompressStreamer(s modules.Streamer, snap *siafile.Snapshot) *decompressStreamer {
+	return &decompressStreamer{
+		staticChunks:      snap.CompressedChunks(),
+		staticChunkSize:   snap.ChunkSize(),
+		staticCompression: snap.CompressionType(),
+		staticSize:        snap.UncompressedSize(),
+		staticStreamer:    s,
+		cachedChunkIdx:    -1,
+	}
+}
+
+// Close closes the underlying streamer.
+func (ds *decompressStreamer) Close() error {
+	return ds.staticStreamer.Close()
+}
+
+// Read implements the io.Reader interface.
+func (ds *decompressStreamer) Read(b []byte) (int, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	if uint64(ds.offset) >= ds.staticSize {
+		return 0, io.EOF
+	}
+	chunkIndex := int(uint64(ds.offset) / ds.staticChunkSize)
+	if chunkIndex != ds.cachedChunkIdx {
+		chunk, err := ds.fetchChunk(chunkIndex)
+		if err != nil {
+			return 0, err
+		}
+		ds.cachedChunk = chunk
+		ds.cachedChunkIdx = chunkIndex
+	}
+	off := uint64(ds.offset) - uint64(chunkIndex)*ds.staticChunkSize
+	if off >= uint64(len(ds.cachedChunk)) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(b, ds.cachedChunk[off:])
+	ds.offset += int64(n)
+	return n, nil
+}
+
+// fetchChunk reads the compressed chunk with the given index from the
+// underlying streamer and decompresses it.
+func (ds *decompressStreamer) fetchChunk(chunkIndex int) ([]byte, error) {
+	if chunkIndex >= len(ds.staticChunks) {
+		return nil, errCompressedRangeOutOfBounds
+	}
+	cc := ds.staticChunks[chunkIndex]
+	if _, err := ds.staticStreamer.Seek(int64(cc.Offset), io.SeekStart); err != nil {
+		return nil, errors.AddContext(err, "failed to seek to compressed chunk")
+	}
+	compressed := make([]byte, cc.Length)
+	if _, err := io.ReadFull(ds.staticStreamer, compressed); err != nil {
+		return nil, errors.AddContext(err, "failed to read compressed chunk")
+	}
+	return modules.DecompressChunk(ds.staticCompression, compressed, ds.staticChunkSize)
+}
+
+// Seek sets the offset for the next Read to offset, interpreted according to
+// whence. The offset is relative to the logical data of the file.
+func (ds *decompressStreamer) Seek(offset int64, whence int) (int64, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = 0
+	case io.SeekCurrent:
+		newOffset = ds.offset
+	case io.SeekEnd:
+		newOffset = int64(ds.staticSize)
+	}
+	newOffset += offset
+	if newOffset < 0 {
+		return ds.offset, errors.New("cannot seek to negative offset")
+	}
+	ds.offset = newOffset
+	return newOffset, nil
+}
+
+// isCompressedUploadSource returns whether path is the compressed data of an
+// upload.
+func (r *Renter) isCompressedUploadSource(path string) bool {
+	return path != "" && filepath.Dir(path) == filepath.Join(r.persistDir, compressedUploadsDir)
+}
+
+// managedCompressedUploadSource returns the path of the compressed data of a
+// file or an empty string if the file has none.
+func (r *Renter) managedCompressedUploadSource(siaPath modules.SiaPath) (string, error) {
+	sf, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return "", err
+	}
+	defer sf.Close()
+	if path := sf.LocalPath(); r.isCompressedUploadSource(path) {
+		return path, nil
+	}
+	return "", nil
+}
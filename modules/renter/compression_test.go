@@ -0,0 +1,264 @@
+package renter
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+	"gitlab.com/scpcorp/ScPrime/persist"
+)
+
+// bytesStreamer is a modules.Streamer backed by an in-memory buffer.
+type bytesStreamer struct {
+	*bytes.Reader
+}
+
+// Close implements io.Closer.
+func (bs bytesStreamer) Close() error { return nil }
+
+// compressibleData returns data which compresses well but isn't uniform.
+func compressibleData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(fastrand.Intn(4))
+	}
+	return data
+}
+
+// compressTestData compresses data using a compressionReader and returns the
+// stored data together with the offset index.
+func compressTestData(t *testing.T, data []byte, chunkSize uint64) ([]byte, []siafile.CompressedChunkInfo) {
+	cr := newCompressionReader(bytes.NewReader(data), modules.CompressionDeflate, chunkSize)
+	stored, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ccs, uncompressedSize := cr.CompressedChunks()
+	if uncompressedSize != uint64(len(data)) {
+		t.Fatalf("expected uncompressed size %v but was %v", len(data), uncompressedSize)
+	}
+	return stored, ccs
+}
+
+// TestCompressionReader tests that the compressionReader builds a valid offset
+// index of the compressed chunks.
+func TestCompressionReader(t *testing.T) {
+	chunkSize := uint64(1000)
+	data := compressibleData(int(3*chunkSize + 10))
+	stored, ccs := compressTestData(t, data, chunkSize)
+	if len(ccs) != 4 {
+		t.Fatalf("expected 4 chunks but got %v", len(ccs))
+	}
+	if len(stored) >= len(data) {
+		t.Fatal("data wasn't compressed")
+	}
+	var offset uint64
+	for i, cc := range ccs {
+		if cc.Offset != offset {
+			t.Fatalf("chunk %v: expected offset %v but got %v", i, offset, cc.Offset)
+		}
+		offset += cc.Length
+		chunk, err := modules.DecompressChunk(modules.CompressionDeflate, stored[cc.Offset:cc.Offset+cc.Length], chunkSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		end := uint64(i+1) * chunkSize
+		if end > uint64(len(data)) {
+			end = uint64(len(data))
+		}
+		if !bytes.Equal(chunk, data[uint64(i)*chunkSize:end]) {
+			t.Fatalf("chunk %v doesn't match", i)
+		}
+	}
+	if offset != uint64(len(stored)) {
+		t.Fatal("index doesn't cover the stored data")
+	}
+	// An empty reader should produce no chunks.
+	_, ccs = compressTestData(t, nil, chunkSize)
+	if len(ccs) != 0 {
+		t.Fatal("expected no chunks", len(ccs))
+	}
+}
+
+// TestDownloadDestinationDecompress tests that the decompressing destination
+// writes the right logical data, even if the stored data arrives out of order.
+func TestDownloadDestinationDecompress(t *testing.T) {
+	chunkSize := uint64(1000)
+	data := compressibleData(int(5*chunkSize + 123))
+	stored, ccs := compressTestData(t, data, chunkSize)
+	ec, err := siafile.NewRSCode(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		offset, length uint64
+	}{
+		{0, uint64(len(data))},
+		{0, 1},
+		{chunkSize - 1, 2},
+		{chunkSize, chunkSize},
+		{123, 3 * chunkSize},
+		{uint64(len(data)) - 1, 1},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		dd, storedOffset, storedLength, err := newDownloadDestinationDecompress(&buf, modules.CompressionDeflate, ccs, chunkSize, test.offset, test.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Split the stored range into download chunks of a size which doesn't
+		// align with the compressed chunks and write them in reverse order.
+		downloadChunkSize := uint64(77)
+		var writeOffsets []uint64
+		for off := uint64(0); off < storedLength; off += downloadChunkSize {
+			writeOffsets = append(writeOffsets, off)
+		}
+		for i := len(writeOffsets) - 1; i >= 0; i-- {
+			off := writeOffsets[i]
+			end := off + downloadChunkSize
+			if end > storedLength {
+				end = storedLength
+			}
+			segment := stored[storedOffset+off : storedOffset+end]
+			pieces, err := ec.Encode(append([]byte{}, segment...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := dd.WritePieces(ec, pieces, 0, int64(off), uint64(len(segment))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(buf.Bytes(), data[test.offset:test.offset+test.length]) {
+			t.Fatalf("%v-%v: data doesn't match", test.offset, test.length)
+		}
+	}
+	// A range exceeding the file should fail.
+	_, _, _, err = newDownloadDestinationDecompress(ioutil.Discard, modules.CompressionDeflate, ccs, chunkSize, uint64(len(data)), chunkSize)
+	if err != errCompressedRangeOutOfBounds {
+		t.Fatal("expected errCompressedRangeOutOfBounds but got", err)
+	}
+}
+
+// TestDecompressStreamer tests reading and seeking within the logical data of
+// a compressed file.
+func TestDecompressStreamer(t *testing.T) {
+	chunkSize := uint64(1000)
+	data := compressibleData(int(4*chunkSize + 1))
+	stored, ccs := compressTestData(t, data, chunkSize)
+	ds := &decompressStreamer{
+		staticChunks:      ccs,
+		staticChunkSize:   chunkSize,
+		staticCompression: modules.CompressionDeflate,
+		staticSize:        uint64(len(data)),
+		staticStreamer:    bytesStreamer{bytes.NewReader(stored)},
+		cachedChunkIdx:    -1,
+	}
+	// Read the whole file.
+	readData, err := ioutil.ReadAll(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(readData, data) {
+		t.Fatal("data doesn't match")
+	}
+	// Read random ranges.
+	for i := 0; i < 20; i++ {
+		offset := fastrand.Intn(len(data))
+		length := fastrand.Intn(len(data)-offset) + 1
+		if _, err := ds.Seek(int64(offset), io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		b := make([]byte, length)
+		if _, err := io.ReadFull(ds, b); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, data[offset:offset+length]) {
+			t.Fatalf("%v-%v: data doesn't match", offset, length)
+		}
+	}
+	// Seeking to the end should result in io.EOF.
+	if off, err := ds.Seek(0, io.SeekEnd); err != nil || off != int64(len(data)) {
+		t.Fatal("unexpected seek result", off, err)
+	}
+	if _, err := ds.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatal("expected io.EOF but got", err)
+	}
+}
+
+// TestCompressedUploadSource checks that the compressed data of an upload is
+// kept as the local source of its file until the file reaches full health or
+// is deleted.
+func TestCompressedUploadSource(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	// newSource creates the compressed data of a file with the given size.
+	newSource := func(size uint64) (modules.SiaPath, string) {
+		dir := filepath.Join(r.persistDir, compressedUploadsDir)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatal(err)
+		}
+		source := filepath.Join(dir, hex.EncodeToString(fastrand.Bytes(8)))
+		if err := ioutil.WriteFile(source, fastrand.Bytes(int(size)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		siaPath, rsc := testingFileParams()
+		err := r.staticFileSystem.NewSiaFile(siaPath, source, rsc, crypto.GenerateSiaKey(crypto.RandomCipherType()), size, persist.DefaultDiskPermissionsTest, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !r.isCompressedUploadSource(source) {
+			t.Fatal("compressed data isn't recognized")
+		}
+		return siaPath, source
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	// The data of a file without any uploaded pieces is kept.
+	siaPath, source := newSource(1000)
+	if _, err := r.managedCalculateAndUpdateFileMetadata(siaPath); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(source) {
+		t.Fatal("compressed data of unhealthy file was removed")
+	}
+	// The data is removed together with the file.
+	if err := r.DeleteFile(siaPath); err != nil {
+		t.Fatal(err)
+	}
+	if exists(source) {
+		t.Fatal("compressed data of deleted file wasn't removed")
+	}
+
+	// The data of a file with full health is removed.
+	siaPath, source = newSource(0)
+	if _, err := r.managedCalculateAndUpdateFileMetadata(siaPath); err != nil {
+		t.Fatal(err)
+	}
+	if exists(source) {
+		t.Fatal("compressed data of healthy file wasn't removed")
+	}
+	if path, err := r.managedCompressedUploadSource(siaPath); err != nil || path != "" {
+		t.Fatal("local path wasn't cleared", path, err)
+	}
+}
@@ -270,6 +270,23 @@ var (
 		Standard: 5 * time.Minute,
 		Testing:  5 * time.Second,
 	}).(time.Duration)
+
+	// backupSchedulerSleepDuration defines how long the renter sleeps between
+	// checking whether a scheduled backup is due.
+	backupSchedulerSleepDuration = build.Select(build.Var{
+		Dev:      5 * time.Second,
+		Standard: time.Minute,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// prunedBackupExpiry defines how long the renter remembers pruned backups
+	// to remove them from hosts which were unavailable at the time of
+	// pruning.
+	prunedBackupExpiry = build.Select(build.Var{
+		Dev:      time.Hour,
+		Standard: 30 * 24 * time.Hour,
+		Testing:  time.Minute,
+	}).(time.Duration)
 )
 
 // Constants that tune the worker swarm.
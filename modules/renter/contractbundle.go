@@ -0,0 +1,361 @@
+package renter
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/cipher"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/NebulousLabs/encoding"
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+	"golang.org/x/crypto/twofish"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/proto"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// A contract bundle uses the same layout as a backup. It starts with the
+// checksum of the plaintext archive, followed by a backupHeader and the
+// encrypted, gzipped tarball. The tarball contains one entry per contract in
+// bundleContractsDir and the siafiles referencing the contracts' hosts in
+// bundleSiaFilesDir.
+const (
+	bundleContractsDir = "contracts"
+	bundleSiaFilesDir  = "siafiles"
+)
+
+var (
+	// errBundleSecretRequired is returned if a contract bundle is created or
+	// loaded without a secret. Bundles contain the contracts' secret keys and
+	// are therefore always encrypted.
+	errBundleSecretRequired = errors.New("contract bundles require a secret")
+
+	// errNoContractsToExport is returned if no contracts were selected for
+	// the export.
+	errNoContractsToExport = errors.New("no contracts selected for export")
+)
+
+// ExportContracts writes the contracts with the given ids, together with the
+// siafiles that reference their hosts, to an encrypted bundle at dst.
+func (r *Renter) ExportContracts(dst string, ids []types.FileContractID, secret []byte) (err error) {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	if len(secret) == 0 {
+		return errBundleSecretRequired
+	}
+	if len(ids) == 0 {
+		return errNoContractsToExport
+	}
+
+	// Export the contracts first to make sure they exist before creating the
+	// file.
+	ecs, err := r.hostContractor.ExportContracts(ids)
+	if err != nil {
+		return err
+	}
+	hosts := make(map[string]struct{}, len(ecs))
+	for _, ec := range ecs {
+		hosts[ec.HostPublicKey().String()] = struct{}{}
+	}
+
+	// Create the bundle.
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.AddContext(errors.Compose(err, f.Close()), "Error creating contract bundle "+dst)
+	}()
+	bh := backupHeader{
+		Version:    encryptionVersion,
+		Encryption: encryptionTwofish,
+		IV:         fastrand.Bytes(twofish.BlockSize),
+	}
+	c, err := twofish.NewCipher(secret)
+	if err != nil {
+		return err
+	}
+	// Skip the checksum for now and write the header.
+	if _, err := f.Seek(crypto.HashSize, io.SeekStart); err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(bh); err != nil {
+		return err
+	}
+	// Hash the plaintext before encrypting it.
+	h := crypto.NewHash()
+	archive := io.MultiWriter(cipher.StreamWriter{S: cipher.NewCTR(c, bh.IV), W: f}, h)
+	gzw := gzip.NewWriter(archive)
+	tw := tar.NewWriter(gzw)
+
+	// Add the contracts and siafiles.
+	err = writeBundleContracts(tw, ecs)
+	if err == nil {
+		err = r.managedTarBundleSiaFiles(tw, hosts)
+	}
+	twErr := tw.Close()
+	gzwErr := gzw.Close()
+	if err := errors.Compose(err, twErr, gzwErr); err != nil {
+		return err
+	}
+	// Write the hash to the beginning of the file.
+	_, err = f.WriteAt(h.Sum(nil), 0)
+	return err
+}
+
+// ImportContracts loads a contract bundle created by ExportContracts. The
+// bundle's contracts are added to the contractor and its siafiles to the
+// renter's filesystem. Siafiles that already exist are skipped. The imported
+// contracts are returned together with the contracts which couldn't be
+// imported.
+func (r *Renter) ImportContracts(src string, secret []byte) (_ []modules.RenterContract, _ []modules.ContractImportError, err error) {
+	if err := r.tg.Add(); err != nil {
+		return nil, nil, err
+	}
+	defer r.tg.Done()
+	if len(secret) == 0 {
+		return nil, nil, errBundleSecretRequired
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		err = errors.AddContext(errors.Compose(err, f.Close()), "Error loading contract bundle "+src)
+	}()
+	// Read the checksum and the header.
+	var chks crypto.Hash
+	if _, err := io.ReadFull(f, chks[:]); err != nil {
+		return nil, nil, err
+	}
+	dec := json.NewDecoder(f)
+	var bh backupHeader
+	if err := dec.Decode(&bh); err != nil {
+		return nil, nil, err
+	}
+	if bh.Version != encryptionVersion {
+		return nil, nil, errors.New("unknown version")
+	}
+	if bh.Encryption != encryptionTwofish {
+		return nil, nil, errors.New("contract bundle is not encrypted")
+	}
+	// The header is followed by a newline which is the first byte of the
+	// decoder's buffer.
+	bodyOff := int64(crypto.HashSize) + dec.InputOffset() + 1
+	if _, err := f.Seek(bodyOff, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	// Verify the checksum of the decrypted archive before using it.
+	archive, err := wrapReaderInCipher(f, bh, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	h := crypto.NewHash()
+	if _, err := io.Copy(h, archive); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(h.Sum(nil), chks[:]) {
+		return nil, nil, errors.New("checksum doesn't match, wrong secret or corrupted bundle")
+	}
+	if _, err := f.Seek(bodyOff, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	archive, err = wrapReaderInCipher(f, bh, secret)
+	if err != nil {
+		return nil, nil, err
+	}
+	gzr, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gzr.Close()
+	return r.managedUntarBundle(tar.NewReader(gzr))
+}
+
+// managedTarBundleSiaFiles adds all the siafiles which reference at least one
+// of the given hosts to the tarball.
+func (r *Renter) managedTarBundleSiaFiles(tw *tar.Writer, hosts map[string]struct{}) error {
+	userDir := r.staticFileSystem.DirPath(modules.UserFolder)
+	return r.staticFileSystem.Walk(modules.UserFolder, func(sysPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(sysPath) != modules.SiaFileExtension {
+			return nil
+		}
+		var siaPath modules.SiaPath
+		if err := siaPath.LoadSysPath(userDir, sysPath); err != nil {
+			return err
+		}
+		siaPath, err = modules.UserFolder.Join(siaPath.String())
+		if err != nil {
+			return err
+		}
+		entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+		if err != nil {
+			return err
+		}
+		defer entry.Close()
+		// Skip files without pieces on any of the exported contracts' hosts.
+		var overlaps bool
+		for _, pk := range entry.HostPublicKeys() {
+			if _, overlaps = hosts[pk.String()]; overlaps {
+				break
+			}
+		}
+		if !overlaps {
+			return nil
+		}
+		sr, err := entry.SnapshotReader()
+		if err != nil {
+			return err
+		}
+		defer sr.Close()
+		fi, err := sr.Stat()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, info.Name())
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(userDir, sysPath)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(bundleSiaFilesDir, filepath.ToSlash(rel))
+		header.Size = fi.Size()
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, sr)
+		return err
+	})
+}
+
+// managedUntarBundle reads the contracts and siafiles from a contract bundle
+// and adds them to the renter. The contracts are imported before the siafiles
+// are added and siafiles without pieces on any of the renter's hosts after the
+// import are skipped. That way a failed import doesn't leave siafiles behind
+// that can't be downloaded.
+func (r *Renter) managedUntarBundle(tr *tar.Reader) ([]modules.RenterContract, []modules.ContractImportError, error) {
+	dirsToUpdate := r.newUniqueRefreshPaths()
+	defer dirsToUpdate.callRefreshAll()
+
+	// importContracts imports the contracts read so far. The bundle contains
+	// all the contracts before the siafiles, so it is called once the first
+	// siafile or the end of the bundle is reached.
+	var ecs []proto.ExportedContract
+	var imported []modules.RenterContract
+	var failed []modules.ContractImportError
+	var hosts map[string]struct{}
+	importContracts := func() (err error) {
+		if hosts != nil {
+			return nil
+		}
+		imported, failed, err = r.hostContractor.ImportContracts(ecs)
+		if err != nil {
+			return err
+		}
+		hosts = make(map[string]struct{})
+		for _, c := range r.hostContractor.Contracts() {
+			hosts[c.HostPublicKey.String()] = struct{}{}
+		}
+		return nil
+	}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return imported, failed, errors.AddContext(err, "could not get next entry in the tar archive")
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return imported, failed, errors.AddContext(err, "could not read entry from the tar archive")
+		}
+		switch {
+		case strings.HasPrefix(header.Name, bundleContractsDir+"/"):
+			if hosts != nil {
+				return imported, failed, fmt.Errorf("contract %v follows the siafiles of the contract bundle", header.Name)
+			}
+			var ec proto.ExportedContract
+			if err := encoding.Unmarshal(b, &ec); err != nil {
+				return nil, nil, errors.AddContext(err, fmt.Sprintf("could not decode contract %v", header.Name))
+			}
+			ecs = append(ecs, ec)
+		case strings.HasPrefix(header.Name, bundleSiaFilesDir+"/"):
+			if err := importContracts(); err != nil {
+				return nil, nil, errors.AddContext(err, "could not import contracts")
+			}
+			rel := strings.TrimPrefix(header.Name, bundleSiaFilesDir+"/")
+			siaPath, err := modules.UserFolder.Join(strings.TrimSuffix(rel, modules.SiaFileExtension))
+			if err != nil {
+				return imported, failed, errors.AddContext(err, "invalid siafile path in bundle")
+			}
+			// Skip siafiles which don't have pieces on any of the renter's
+			// hosts.
+			sf, err := siafile.LoadSiaFileFromReader(bytes.NewReader(b), "", nil)
+			if err != nil {
+				return imported, failed, errors.AddContext(err, fmt.Sprintf("could not load siafile %v", siaPath))
+			}
+			var usable bool
+			for _, pk := range sf.HostPublicKeys() {
+				if _, usable = hosts[pk.String()]; usable {
+					break
+				}
+			}
+			if !usable {
+				r.log.Printf("WARN: skipping siafile %v of contract bundle since none of its contracts were imported", siaPath)
+				continue
+			}
+			if err := r.staticFileSystem.AddSiaFileFromReader(bytes.NewReader(b), siaPath); err != nil {
+				return imported, failed, errors.AddContext(err, fmt.Sprintf("could not add siafile %v", siaPath))
+			}
+			if err := dirsToUpdate.callAdd(siaPath); err != nil {
+				return imported, failed, errors.AddContext(err, fmt.Sprintf("could not add directory %v to the list of directories to be updated", siaPath))
+			}
+		default:
+			return imported, failed, fmt.Errorf("unexpected entry in contract bundle: %v", header.Name)
+		}
+	}
+	if err := importContracts(); err != nil {
+		return nil, nil, errors.AddContext(err, "could not import contracts")
+	}
+	return imported, failed, nil
+}
+
+// writeBundleContracts adds the exported contracts to the tarball.
+func writeBundleContracts(tw *tar.Writer, ecs []proto.ExportedContract) error {
+	for _, ec := range ecs {
+		b := encoding.Marshal(ec)
+		err := tw.WriteHeader(&tar.Header{
+			Name: path.Join(bundleContractsDir, ec.ID().String()),
+			Mode: int64(modules.DefaultFilePerm),
+			Size: int64(len(b)),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
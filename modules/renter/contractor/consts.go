@@ -20,6 +20,10 @@ var (
 	// AlertMSGFailedContractRenewal indicates that the contract renewal failed
 	AlertMSGFailedContractRenewal = "Contractor is attempting to renew/refresh contracts but failed"
 
+	// AlertMSGSpendingForecast indicates that the allowance is expected to
+	// run out before the end of the current period.
+	AlertMSGSpendingForecast = "The projected spending of the current period exceeds the allowance funds"
+
 	// AlertMSGWalletLockedDuringMaintenance indicates that forming/renewing a
 	// contract during contract maintenance isn't possible due to a locked wallet.
 	AlertMSGWalletLockedDuringMaintenance = "At least one contract failed to form/renew due to the wallet being locked"
@@ -93,6 +97,15 @@ var (
 		Standard: types.BlockHeight(types.BlocksPerWeek),     // 7 days
 		Testing:  types.BlockHeight(types.BlocksPerHour * 2),
 	}).(types.BlockHeight)
+
+	// forecastMinElapsedBlocks is the number of blocks a contract needs to
+	// be active before its bandwidth spending is extrapolated. Earlier rates
+	// are dominated by the first transfers after the contract was formed.
+	forecastMinElapsedBlocks = build.Select(build.Var{
+		Dev:      types.BlockHeight(10),
+		Standard: types.BlockHeight(types.BlocksPerDay),
+		Testing:  types.BlockHeight(3),
+	}).(types.BlockHeight)
 )
 
 // Constants related to the safety values for when the contractor is forming
@@ -1393,8 +1393,10 @@ func (c *Contractor) threadedContractMaintenance() {
 			c.log.Println("Collateral Adjustment: ", sb.CollateralAdjustment)
 			c.log.Println("Duration Adjustment:   ", sb.DurationAdjustment)
 			c.log.Println("Interaction Adjustment:", sb.InteractionAdjustment)
+			c.log.Println("Latency Adjustment:    ", sb.LatencyAdjustment)
 			c.log.Println("Price Adjustment:      ", sb.PriceAdjustment)
 			c.log.Println("Storage Adjustment:    ", sb.StorageRemainingAdjustment)
+			c.log.Println("Throughput Adjustment: ", sb.ThroughputAdjustment)
 			c.log.Println("Uptime Adjustment:     ", sb.UptimeAdjustment)
 			c.log.Println("Version Adjustment:    ", sb.VersionAdjustment)
 		}
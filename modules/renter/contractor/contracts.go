@@ -2,6 +2,7 @@ package contractor
 
 import (
 	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/proto"
 	"gitlab.com/scpcorp/ScPrime/types"
@@ -148,6 +149,53 @@ func (c *Contractor) ContractUtility(pk types.SiaPublicKey) (modules.ContractUti
 	return c.managedContractUtility(id)
 }
 
+// IncrementSectorRefCount adds a reference to the sector with the given root
+// in the contract with the host.
+func (c *Contractor) IncrementSectorRefCount(pk types.SiaPublicKey, root crypto.Hash) error {
+	if err := c.tg.Add(); err != nil {
+		return err
+	}
+	defer c.tg.Done()
+	sc, err := c.managedAcquireContractByPublicKey(pk)
+	if err != nil {
+		return err
+	}
+	defer c.staticContracts.Return(sc)
+	return sc.IncrementSectorRefCount(root)
+}
+
+// DecrementSectorRefCount removes a reference from the sector with the given
+// root in the contract with the host and returns the remaining number of
+// references.
+func (c *Contractor) DecrementSectorRefCount(pk types.SiaPublicKey, root crypto.Hash) (uint16, error) {
+	if err := c.tg.Add(); err != nil {
+		return 0, err
+	}
+	defer c.tg.Done()
+	sc, err := c.managedAcquireContractByPublicKey(pk)
+	if err != nil {
+		return 0, err
+	}
+	defer c.staticContracts.Return(sc)
+	return sc.DecrementSectorRefCount(root)
+}
+
+// managedAcquireContractByPublicKey acquires the contract with the host. The
+// contract needs to be returned to the contract set by the caller.
+func (c *Contractor) managedAcquireContractByPublicKey(pk types.SiaPublicKey) (*proto.SafeContract, error) {
+	c.mu.RLock()
+	id, ok := c.pubKeysToContractID[pk.String()]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, errContractNotFound
+	}
+	sc, ok := c.staticContracts.Acquire(id)
+	if !ok {
+		return nil, errContractNotFound
+	}
+	return sc, nil
+}
+
 // MarkContractBad will mark a specific contract as bad.
 func (c *Contractor) MarkContractBad(id types.FileContractID) error {
 	if err := c.tg.Add(); err != nil {
@@ -0,0 +1,94 @@
+package contractor
+
+import (
+	"fmt"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/proto"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+var (
+	// errHostContractExists is returned when importing a contract with a host
+	// that the contractor already has a contract with.
+	errHostContractExists = errors.New("already have a contract with the contract's host")
+)
+
+// ExportContracts returns the exported form of the active contracts with the
+// given ids. The contracts are not removed from the contractor.
+func (c *Contractor) ExportContracts(ids []types.FileContractID) ([]proto.ExportedContract, error) {
+	if err := c.tg.Add(); err != nil {
+		return nil, err
+	}
+	defer c.tg.Done()
+
+	ecs := make([]proto.ExportedContract, 0, len(ids))
+	for _, id := range ids {
+		ec, err := c.staticContracts.ExportContract(id)
+		if err != nil {
+			return nil, errors.AddContext(err, fmt.Sprintf("failed to export contract %v", id))
+		}
+		ecs = append(ecs, ec)
+	}
+	return ecs, nil
+}
+
+// ImportContracts adds previously exported contracts to the contractor. A
+// contract is skipped if the contractor already has a contract with the same
+// host. The imported contracts are returned together with the reasons why the
+// other contracts couldn't be imported.
+func (c *Contractor) ImportContracts(ecs []proto.ExportedContract) ([]modules.RenterContract, []modules.ContractImportError, error) {
+	if err := c.tg.Add(); err != nil {
+		return nil, nil, err
+	}
+	defer c.tg.Done()
+
+	var imported []modules.RenterContract
+	var failed []modules.ContractImportError
+	for _, ec := range ecs {
+		contract, err := c.managedImportContract(ec)
+		if err != nil {
+			failed = append(failed, modules.ContractImportError{
+				ID:    ec.ID(),
+				Error: err.Error(),
+			})
+			continue
+		}
+		imported = append(imported, contract)
+	}
+	return imported, failed, nil
+}
+
+// managedImportContract inserts a single exported contract into the contract
+// set and tells the watchdog about it.
+func (c *Contractor) managedImportContract(ec proto.ExportedContract) (modules.RenterContract, error) {
+	// Hold the lock across the insertion to avoid racing another import or a
+	// contract formation with the same host.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, exists := c.pubKeysToContractID[ec.HostPublicKey().String()]
+	if exists {
+		return modules.RenterContract{}, errHostContractExists
+	}
+	contract, err := c.staticContracts.ImportContract(ec)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+	c.pubKeysToContractID[contract.HostPublicKey.String()] = contract.ID
+
+	// Tell the watchdog to watch the contract for revisions and storage
+	// proofs. The formation transaction is unknown to this renter, so the
+	// contract is treated like a recovered one.
+	monitorContractArgs := monitorContractArgs{
+		recovered:   true,
+		fcID:        contract.ID,
+		revisionTxn: contract.Transaction,
+	}
+	err = c.staticWatchdog.callMonitorContract(monitorContractArgs)
+	if err == errAlreadyWatchingContract {
+		err = nil
+	}
+	return contract, err
+}
@@ -0,0 +1,110 @@
+package contractor
+
+import (
+	"fmt"
+	"sort"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// project extrapolates the amount spent between start and height to the end
+// of the period assuming that the spending rate stays the same. Nothing is
+// extrapolated until forecastMinElapsedBlocks have passed since start.
+func project(spent types.Currency, start, height, periodEnd types.BlockHeight) types.Currency {
+	if height < start+forecastMinElapsedBlocks || periodEnd <= height {
+		return spent
+	}
+	elapsed := uint64(height - start)
+	remaining := uint64(periodEnd - height)
+	return spent.Add(spent.Mul64(remaining).Div64(elapsed))
+}
+
+// addContractForecast adds a contract to the forecast of its host. The
+// bandwidth spending of active contracts is projected to the end of the
+// period while the spending of contracts which were already renewed is final.
+// Storage is paid up front until the end of the contract, so the storage
+// spending is already committed and isn't projected.
+func addContractForecast(hf *modules.HostSpendingForecast, contract modules.RenterContract, active bool, height, periodEnd types.BlockHeight) {
+	fees := contract.ContractFee.Add(contract.TxnFee).Add(contract.SiafundFee)
+	hf.ContractFees = hf.ContractFees.Add(fees)
+	hf.DownloadSpending = hf.DownloadSpending.Add(contract.DownloadSpending)
+	hf.StorageSpending = hf.StorageSpending.Add(contract.StorageSpending)
+	hf.UploadSpending = hf.UploadSpending.Add(contract.UploadSpending)
+
+	download, storage, upload := contract.DownloadSpending, contract.StorageSpending, contract.UploadSpending
+	if active {
+		download = project(download, contract.StartHeight, height, periodEnd)
+		upload = project(upload, contract.StartHeight, height, periodEnd)
+	}
+	hf.ProjectedDownloadSpending = hf.ProjectedDownloadSpending.Add(download)
+	hf.ProjectedStorageSpending = hf.ProjectedStorageSpending.Add(storage)
+	hf.ProjectedUploadSpending = hf.ProjectedUploadSpending.Add(upload)
+	hf.Projected = hf.Projected.Add(fees).Add(download).Add(storage).Add(upload)
+}
+
+// SpendingForecast projects the spending of the current period from the
+// spending rates of the contracts. The same contracts as for the
+// PeriodSpending are taken into account.
+func (c *Contractor) SpendingForecast() (modules.ContractorSpendingForecast, error) {
+	allContracts := c.staticContracts.ViewAll()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	forecast := modules.ContractorSpendingForecast{
+		Funds:       c.allowance.Funds,
+		BlockHeight: c.blockHeight,
+		PeriodEnd:   c.currentPeriod + c.allowance.Period,
+	}
+	hosts := make(map[string]*modules.HostSpendingForecast)
+	add := func(contract modules.RenterContract, active bool) {
+		if _, doubleSpent := c.doubleSpentContracts[contract.ID]; doubleSpent {
+			return
+		}
+		key := contract.HostPublicKey.String()
+		hf, exists := hosts[key]
+		if !exists {
+			hf = &modules.HostSpendingForecast{HostPublicKey: contract.HostPublicKey}
+			hosts[key] = hf
+		}
+		addContractForecast(hf, contract, active, forecast.BlockHeight, forecast.PeriodEnd)
+	}
+	for _, contract := range allContracts {
+		add(contract, true)
+	}
+	for _, contract := range c.oldContracts {
+		if contract.StartHeight >= c.currentPeriod && contract.ID != metricsContractID {
+			add(contract, false)
+		}
+	}
+
+	// Sum up the hosts and sort them.
+	forecast.Hosts = make([]modules.HostSpendingForecast, 0, len(hosts))
+	for _, hf := range hosts {
+		forecast.Spent = forecast.Spent.Add(hf.ContractFees).Add(hf.DownloadSpending).Add(hf.StorageSpending).Add(hf.UploadSpending)
+		forecast.Projected = forecast.Projected.Add(hf.Projected)
+		forecast.Hosts = append(forecast.Hosts, *hf)
+	}
+	sort.Slice(forecast.Hosts, func(i, j int) bool {
+		return forecast.Hosts[i].Projected.Cmp(forecast.Hosts[j].Projected) > 0
+	})
+	forecast.ExceedsAllowance = c.allowance.Active() && forecast.Projected.Cmp(forecast.Funds) > 0
+	return forecast, nil
+}
+
+// managedCheckSpendingForecast registers an alert if the allowance is
+// projected to run out before the end of the current period and unregisters
+// it otherwise.
+func (c *Contractor) managedCheckSpendingForecast() {
+	forecast, err := c.SpendingForecast()
+	if err != nil {
+		c.log.Println("Unable to compute the spending forecast:", err)
+		return
+	}
+	if !forecast.ExceedsAllowance {
+		c.staticAlerter.UnregisterAlert(modules.AlertIDRenterSpendingForecast)
+		return
+	}
+	cause := fmt.Sprintf("Projected spending of %v exceeds the allowance funds of %v by block %v", forecast.Projected.HumanString(), forecast.Funds.HumanString(), forecast.PeriodEnd)
+	c.staticAlerter.RegisterAlert(modules.AlertIDRenterSpendingForecast, AlertMSGSpendingForecast, cause, modules.SeverityWarning)
+}
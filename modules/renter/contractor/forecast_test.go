@@ -0,0 +1,91 @@
+package contractor
+
+import (
+	"testing"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestProject probes the extrapolation of spending to the end of a period.
+func TestProject(t *testing.T) {
+	spent := types.NewCurrency64(100)
+	tests := []struct {
+		start, height, periodEnd types.BlockHeight
+		expected                 uint64
+	}{
+		{10, 20, 40, 300}, // 10 blocks elapsed, 20 remaining
+		{10, 10, 40, 100}, // nothing elapsed yet
+		{10, 40, 40, 100}, // period is over
+		{10, 50, 40, 100}, // past the period
+		{0, 1, 2, 100},    // too few blocks elapsed
+		{0, 3, 6, 200},
+	}
+	for _, test := range tests {
+		projected := project(spent, test.start, test.height, test.periodEnd)
+		if !projected.Equals64(test.expected) {
+			t.Errorf("project(%v, %v, %v) should be %v but was %v", test.start, test.height, test.periodEnd, test.expected, projected)
+		}
+	}
+}
+
+// TestAddContractForecast checks that the bandwidth spending of active
+// contracts is projected while the storage spending, the spending of renewed
+// contracts and the fees are not.
+func TestAddContractForecast(t *testing.T) {
+	contract := modules.RenterContract{
+		StartHeight:      10,
+		ContractFee:      types.NewCurrency64(5),
+		TxnFee:           types.NewCurrency64(3),
+		SiafundFee:       types.NewCurrency64(2),
+		DownloadSpending: types.NewCurrency64(10),
+		StorageSpending:  types.NewCurrency64(20),
+		UploadSpending:   types.NewCurrency64(30),
+	}
+
+	var hf modules.HostSpendingForecast
+	addContractForecast(&hf, contract, true, 20, 40)
+	if !hf.ContractFees.Equals64(10) || !hf.UploadSpending.Equals64(30) {
+		t.Fatal("wrong spending", hf.ContractFees, hf.UploadSpending)
+	}
+	if !hf.ProjectedDownloadSpending.Equals64(30) || !hf.ProjectedStorageSpending.Equals64(20) || !hf.ProjectedUploadSpending.Equals64(90) {
+		t.Fatal("wrong projected spending", hf.ProjectedDownloadSpending, hf.ProjectedStorageSpending, hf.ProjectedUploadSpending)
+	}
+	if !hf.Projected.Equals64(150) {
+		t.Fatal("wrong projection", hf.Projected)
+	}
+
+	// A renewed contract adds its final spending.
+	addContractForecast(&hf, contract, false, 20, 40)
+	if !hf.ContractFees.Equals64(20) || !hf.Projected.Equals64(220) {
+		t.Fatal("wrong projection after adding renewed contract", hf.ContractFees, hf.Projected)
+	}
+}
+
+// TestForecastEarlyUpload checks that a single upload right after a contract
+// was formed doesn't make the forecast exceed the allowance.
+func TestForecastEarlyUpload(t *testing.T) {
+	// The upload prepaid the storage until the end of the period and used
+	// most of the funds.
+	funds := types.NewCurrency64(1000)
+	contract := modules.RenterContract{
+		StartHeight:     10,
+		StorageSpending: types.NewCurrency64(800),
+		UploadSpending:  types.NewCurrency64(100),
+	}
+	for height := contract.StartHeight; height < contract.StartHeight+forecastMinElapsedBlocks; height++ {
+		var hf modules.HostSpendingForecast
+		addContractForecast(&hf, contract, true, height, 1000)
+		if hf.Projected.Cmp(funds) > 0 {
+			t.Fatalf("projection %v at height %v exceeds the funds", hf.Projected, height)
+		}
+	}
+
+	// The prepaid storage isn't extrapolated later either.
+	contract.UploadSpending = types.ZeroCurrency
+	var hf modules.HostSpendingForecast
+	addContractForecast(&hf, contract, true, 500, 1000)
+	if !hf.Projected.Equals64(800) {
+		t.Fatal("storage spending was extrapolated", hf.Projected)
+	}
+}
@@ -93,6 +93,9 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 	}
 
 	c.mu.Lock()
+	if cc.SkipsBlocks(c.blockHeight) {
+		c.blockHeight = cc.OldHeight
+	}
 	for _, block := range cc.RevertedBlocks {
 		if block.ID() != types.GenesisID {
 			c.blockHeight--
@@ -164,6 +167,12 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 	numBlocksAdded := len(cc.AppliedBlocks) - len(cc.RevertedBlocks)
 	c.staticChurnLimiter.callBumpChurnBudget(numBlocksAdded, c.allowance.Period)
 
+	// Warn the user if the allowance is projected to run out before the end
+	// of the period.
+	if cc.Synced {
+		c.managedCheckSpendingForecast()
+	}
+
 	// Perform contract maintenance if our blockchain is synced. Use a separate
 	// goroutine so that the rest of the contractor is not blocked during
 	// maintenance.
@@ -206,6 +206,9 @@ func (w *watchdog) callMonitorContract(args monitorContractArgs) error {
 func (w *watchdog) callScanConsensusChange(cc modules.ConsensusChange) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	if cc.SkipsBlocks(w.blockHeight) {
+		w.blockHeight = cc.OldHeight
+	}
 	for _, block := range cc.RevertedBlocks {
 		if block.ID() != types.GenesisID {
 			w.blockHeight--
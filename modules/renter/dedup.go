@@ -0,0 +1,350 @@
+package renter
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/proto"
+	"gitlab.com/scpcorp/ScPrime/persist"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+const (
+	// dedupIndexFilename is the filename of the renter's dedup index.
+	dedupIndexFilename = "dedup.json"
+)
+
+var (
+	// dedupKeySpecifier is the specifier used for deriving the secret used to
+	// create the convergent keys of deduplicated chunks from the RenterSeed.
+	dedupKeySpecifier = types.NewSpecifier("dedupkey")
+
+	// dedupIDSpecifier is the specifier used for deriving the ID of a
+	// deduplicated chunk from the seed of its convergent key.
+	dedupIDSpecifier = types.NewSpecifier("dedupid")
+
+	// dedupIndexMetadata is the header of the persisted dedup index.
+	dedupIndexMetadata = persist.Metadata{
+		Header:  "Renter Dedup Index",
+		Version: "1.5.4",
+	}
+)
+
+type (
+	// dedupIndex maps the IDs of deduplicated chunks to the pieces which store
+	// them on the hosts. Since the chunks are encrypted with convergent keys,
+	// every chunk with the same ID consists of the same pieces.
+	dedupIndex struct {
+		entries    map[crypto.Hash]*dedupEntry
+		staticPath string
+		mu         sync.Mutex
+	}
+
+	// dedupEntry contains the known pieces of a deduplicated chunk and the
+	// chunks of siafiles which reference them.
+	dedupEntry struct {
+		Pieces [][]siafile.Piece   `json:"pieces"`
+		Refs   map[string]struct{} `json:"refs"`
+	}
+)
+
+// dedupChunkID returns the ID of a deduplicated chunk with the given convergent
+// key seed. The ID is derived from the seed using a one-way function to avoid
+// storing the seed in the index.
+func dedupChunkID(seed crypto.Hash) crypto.Hash {
+	return crypto.HashAll(dedupIDSpecifier, seed)
+}
+
+// dedupRef returns the identifier of a chunk within the dedup index. It uses
+// the file's UID to stay valid when the file is renamed.
+func dedupRef(uid siafile.SiafileUID, chunkIndex uint64) string {
+	return fmt.Sprintf("%v:%v", uid, chunkIndex)
+}
+
+// newDedupIndex loads the dedup index at the given path or creates a new one
+// if it doesn't exist yet.
+func newDedupIndex(path string) (*dedupIndex, error) {
+	di := &dedupIndex{
+		entries:    make(map[crypto.Hash]*dedupEntry),
+		staticPath: path,
+	}
+	err := persist.LoadJSON(dedupIndexMetadata, &di.entries, path)
+	if os.IsNotExist(err) {
+		return di, nil
+	}
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to load dedup index")
+	}
+	return di, nil
+}
+
+// managedAddRef adds a reference to the deduplicated chunk with the given ID
+// and merges the provided pieces into the known pieces of the chunk.
+func (di *dedupIndex) managedAddRef(id crypto.Hash, ref string, pieces [][]siafile.Piece) error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	entry, exists := di.entries[id]
+	if !exists {
+		entry = &dedupEntry{
+			Refs: make(map[string]struct{}),
+		}
+		di.entries[id] = entry
+	}
+	entry.Refs[ref] = struct{}{}
+	for len(entry.Pieces) < len(pieces) {
+		entry.Pieces = append(entry.Pieces, nil)
+	}
+	for pieceIndex, pieceSet := range pieces {
+		for _, piece := range pieceSet {
+			if !dedupHasPiece(entry.Pieces[pieceIndex], piece) {
+				entry.Pieces[pieceIndex] = append(entry.Pieces[pieceIndex], piece)
+			}
+		}
+	}
+	return di.save()
+}
+
+// managedPieces returns the known pieces of the deduplicated chunk with the
+// given ID.
+func (di *dedupIndex) managedPieces(id crypto.Hash) ([][]siafile.Piece, bool) {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	entry, exists := di.entries[id]
+	if !exists {
+		return nil, false
+	}
+	pieces := make([][]siafile.Piece, len(entry.Pieces))
+	for i := range entry.Pieces {
+		pieces[i] = append([]siafile.Piece{}, entry.Pieces[i]...)
+	}
+	return pieces, true
+}
+
+// managedRemoveRef removes a reference to the deduplicated chunk with the given
+// ID. Once a chunk isn't referenced anymore it is removed from the index.
+func (di *dedupIndex) managedRemoveRef(id crypto.Hash, ref string) error {
+	di.mu.Lock()
+	defer di.mu.Unlock()
+	entry, exists := di.entries[id]
+	if !exists {
+		return nil
+	}
+	delete(entry.Refs, ref)
+	if len(entry.Refs) == 0 {
+		delete(di.entries, id)
+	}
+	return di.save()
+}
+
+// save persists the dedup index to disk.
+func (di *dedupIndex) save() error {
+	return persist.SaveJSON(dedupIndexMetadata, di.entries, di.staticPath)
+}
+
+// dedupHasPiece returns whether the piece is contained in the set.
+func dedupHasPiece(pieceSet []siafile.Piece, piece siafile.Piece) bool {
+	for _, p := range pieceSet {
+		if p.HostPubKey.Equals(piece.HostPubKey) && p.MerkleRoot == piece.MerkleRoot {
+			return true
+		}
+	}
+	return false
+}
+
+// staticConvergentKeySeed derives the seed of the convergent key of a chunk
+// from its data pieces. The seed is keyed with a secret derived from the
+// wallet seed to prevent other parties from confirming that a renter stores a
+// certain file. It also includes the erasure coding and encryption settings
+// since only chunks with identical settings can share their pieces.
+func (r *Renter) staticConvergentKeySeed(uc *unfinishedUploadChunk) (crypto.Hash, error) {
+	// Get the wallet seed.
+	ws, _, err := r.w.PrimarySeed()
+	if err != nil {
+		return crypto.Hash{}, errors.AddContext(err, "failed to get wallet's primary seed")
+	}
+	// Derive the renter seed and wipe the memory once we are done using it.
+	rs := proto.DeriveRenterSeed(ws)
+	defer fastrand.Read(rs[:])
+	// Derive the secret and wipe it afterwards.
+	secret := crypto.HashAll(rs, dedupKeySpecifier)
+	defer fastrand.Read(secret[:])
+
+	ec := uc.fileEntry.ErasureCode()
+	pieceHashes := make([]crypto.Hash, ec.MinPieces())
+	for i := range pieceHashes {
+		pieceHashes[i] = crypto.HashBytes(uc.logicalChunkData[i])
+	}
+	return crypto.HashAll(secret, ec.Identifier(), uc.fileEntry.PieceSize(), uc.fileEntry.MasterKey().Type(), pieceHashes), nil
+}
+
+// staticSetConvergentKey sets the convergent key of a chunk of a deduplicated
+// file after its logical data was fetched and erasure coded. Only chunks
+// without any uploaded pieces can use a convergent key since existing pieces
+// are encrypted with the masterkey.
+func (r *Renter) staticSetConvergentKey(uc *unfinishedUploadChunk) error {
+	if !uc.fileEntry.Deduplicate() {
+		return nil
+	}
+	if _, ok := uc.fileEntry.ConvergentKey(uc.staticIndex); ok {
+		return nil
+	}
+	pieces, err := uc.fileEntry.Pieces(uc.staticIndex)
+	if err != nil {
+		return errors.AddContext(err, "failed to get the pieces of the chunk")
+	}
+	for _, pieceSet := range pieces {
+		if len(pieceSet) > 0 {
+			return nil
+		}
+	}
+	seed, err := r.staticConvergentKeySeed(uc)
+	if err != nil {
+		return err
+	}
+	return uc.fileEntry.SetConvergentKey(uc.staticIndex, seed)
+}
+
+// managedReuseDedupPieces adds the pieces of an identical chunk which are
+// already stored on the hosts to the chunk instead of uploading them again.
+// The memory of the pieces which don't need to be uploaded anymore is
+// returned to the memory manager.
+func (r *Renter) managedReuseDedupPieces(uc *unfinishedUploadChunk) {
+	seed, ok := uc.fileEntry.ConvergentKey(uc.staticIndex)
+	if !ok {
+		return
+	}
+	dedupPieces, ok := r.staticDedupIndex.managedPieces(dedupChunkID(seed))
+	if !ok {
+		return
+	}
+	var memoryReleased uint64
+	var piecesReused int
+	for pieceIndex, pieceSet := range dedupPieces {
+		uc.mu.Lock()
+		used := pieceIndex >= len(uc.pieceUsage) || uc.pieceUsage[pieceIndex]
+		uc.mu.Unlock()
+		if used {
+			continue
+		}
+		for _, piece := range pieceSet {
+			// Only reuse pieces on hosts which don't store a piece of the
+			// chunk yet.
+			hpk := piece.HostPubKey.String()
+			uc.mu.Lock()
+			_, unused := uc.unusedHosts[hpk]
+			uc.mu.Unlock()
+			if !unused {
+				continue
+			}
+			// Add a reference to the sector before adding it to the file to
+			// make sure it isn't garbage collected.
+			err := r.hostContractor.IncrementSectorRefCount(piece.HostPubKey, piece.MerkleRoot)
+			if err != nil {
+				r.repairLog.Printf("Unable to reuse piece %v of chunk %v of %s on host %v: %v", pieceIndex, uc.staticIndex, uc.staticSiaPath, hpk, err)
+				continue
+			}
+			err = uc.fileEntry.AddPiece(piece.HostPubKey, uc.staticIndex, uint64(pieceIndex), piece.MerkleRoot)
+			if err != nil {
+				_, decErr := r.hostContractor.DecrementSectorRefCount(piece.HostPubKey, piece.MerkleRoot)
+				r.repairLog.Printf("Unable to add reused piece %v to chunk %v of %s: %v", pieceIndex, uc.staticIndex, uc.staticSiaPath, errors.Compose(err, decErr))
+				continue
+			}
+			uc.mu.Lock()
+			uc.pieceUsage[pieceIndex] = true
+			uc.piecesCompleted++
+			delete(uc.unusedHosts, hpk)
+			uc.logicalChunkData[pieceIndex] = nil
+			uc.memoryReleased += modules.SectorSize
+			uc.mu.Unlock()
+			memoryReleased += modules.SectorSize
+			piecesReused++
+			break
+		}
+	}
+	if memoryReleased > 0 {
+		r.memoryManager.Return(memoryReleased)
+	}
+	if piecesReused > 0 {
+		r.repairLog.Printf("Reused %v deduplicated pieces for chunk %v of %s", piecesReused, uc.staticIndex, uc.staticSiaPath)
+	}
+}
+
+// managedRegisterDedupChunk adds the pieces of a completed chunk which uses a
+// convergent key to the dedup index.
+func (r *Renter) managedRegisterDedupChunk(uc *unfinishedUploadChunk) {
+	seed, ok := uc.fileEntry.ConvergentKey(uc.staticIndex)
+	if !ok {
+		return
+	}
+	pieces, err := uc.fileEntry.Pieces(uc.staticIndex)
+	if err != nil {
+		r.log.Printf("WARN: unable to get pieces of deduplicated chunk %v of %s: %v", uc.staticIndex, uc.staticSiaPath, err)
+		return
+	}
+	err = r.staticDedupIndex.managedAddRef(dedupChunkID(seed), dedupRef(uc.fileEntry.UID(), uc.staticIndex), pieces)
+	if err != nil {
+		r.log.Printf("WARN: unable to add chunk %v of %s to the dedup index: %v", uc.staticIndex, uc.staticSiaPath, err)
+	}
+}
+
+// dedupChunk is a chunk of a deleted file which uses a convergent key.
+type dedupChunk struct {
+	id     crypto.Hash
+	ref    string
+	pieces [][]siafile.Piece
+}
+
+// managedDedupChunks returns the chunks of a deduplicated file which use a
+// convergent key.
+func (r *Renter) managedDedupChunks(siaPath modules.SiaPath) ([]dedupChunk, error) {
+	entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return nil, err
+	}
+	defer entry.Close()
+	if !entry.Deduplicate() {
+		return nil, nil
+	}
+	var chunks []dedupChunk
+	for chunkIndex := uint64(0); chunkIndex < entry.NumChunks(); chunkIndex++ {
+		seed, ok := entry.ConvergentKey(chunkIndex)
+		if !ok {
+			continue
+		}
+		pieces, err := entry.Pieces(chunkIndex)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, dedupChunk{
+			id:     dedupChunkID(seed),
+			ref:    dedupRef(entry.UID(), chunkIndex),
+			pieces: pieces,
+		})
+	}
+	return chunks, nil
+}
+
+// managedReleaseDedupChunks removes the references of the chunks of a deleted
+// file from the dedup index and from the sectors on the hosts.
+func (r *Renter) managedReleaseDedupChunks(chunks []dedupChunk) {
+	for _, chunk := range chunks {
+		if err := r.staticDedupIndex.managedRemoveRef(chunk.id, chunk.ref); err != nil {
+			r.log.Printf("WARN: unable to remove %v from the dedup index: %v", chunk.ref, err)
+		}
+		for _, pieceSet := range chunk.pieces {
+			for _, piece := range pieceSet {
+				_, err := r.hostContractor.DecrementSectorRefCount(piece.HostPubKey, piece.MerkleRoot)
+				if err != nil {
+					r.log.Debugf("Unable to decrement refcount of sector %v on host %v: %v", piece.MerkleRoot, piece.HostPubKey, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package renter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// randomDedupPiece creates a random piece for testing the dedup index.
+func randomDedupPiece() siafile.Piece {
+	return siafile.Piece{
+		HostPubKey: types.SiaPublicKey{
+			Algorithm: types.SignatureEd25519,
+			Key:       fastrand.Bytes(crypto.PublicKeySize),
+		},
+		MerkleRoot: crypto.HashBytes(fastrand.Bytes(32)),
+	}
+}
+
+// TestDedupIndex tests adding and removing references to deduplicated chunks
+// and persisting the index.
+func TestDedupIndex(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, dedupIndexFilename)
+	di, err := newDedupIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := dedupChunkID(crypto.HashBytes(fastrand.Bytes(32)))
+	if _, ok := di.managedPieces(id); ok {
+		t.Fatal("new index shouldn't contain any chunks")
+	}
+
+	// Add a chunk with two pieces.
+	p1, p2, p3 := randomDedupPiece(), randomDedupPiece(), randomDedupPiece()
+	ref1 := dedupRef(siafile.SiafileUID("file1"), 0)
+	if err := di.managedAddRef(id, ref1, [][]siafile.Piece{{p1}, {p2}}); err != nil {
+		t.Fatal(err)
+	}
+	// Add a second reference to the same chunk which knows about another
+	// piece. The pieces should be merged.
+	ref2 := dedupRef(siafile.SiafileUID("file2"), 3)
+	if err := di.managedAddRef(id, ref2, [][]siafile.Piece{{p1}, {p2, p3}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]siafile.Piece{{p1}, {p2, p3}}
+	pieces, ok := di.managedPieces(id)
+	if !ok || !reflect.DeepEqual(pieces, expected) {
+		t.Fatal("pieces don't match", pieces)
+	}
+
+	// Reload the index.
+	di, err = newDedupIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pieces, ok = di.managedPieces(id)
+	if !ok || !reflect.DeepEqual(pieces, expected) {
+		t.Fatal("pieces don't match after reload", pieces)
+	}
+
+	// Remove the references again. The chunk should be removed with the last
+	// reference.
+	if err := di.managedRemoveRef(id, ref1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := di.managedPieces(id); !ok {
+		t.Fatal("chunk shouldn't be removed while it is still referenced")
+	}
+	if err := di.managedRemoveRef(id, ref2); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := di.managedPieces(id); ok {
+		t.Fatal("chunk should be removed")
+	}
+	di, err = newDedupIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(di.entries) != 0 {
+		t.Fatal("index should be empty after reload", len(di.entries))
+	}
+}
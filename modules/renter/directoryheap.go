@@ -226,14 +226,21 @@ func (dh *directoryHeap) update(d *directory) bool {
 	return true
 }
 
-// managedPushDirectory adds a directory to the directory heap
-func (dh *directoryHeap) managedPushDirectory(siaPath modules.SiaPath, metadata siadir.Metadata, explored bool) {
+// managedPushDirectory adds a directory to the directory heap. The health
+// values of the directory are scaled according to the repair threshold of the
+// directory's policy.
+//
+// NOTE: the aggregate health values are scaled by the same threshold, even
+// though sub directories might have a policy of their own. This is fine since
+// the sub directories are pushed with their own threshold once the directory
+// is explored.
+func (dh *directoryHeap) managedPushDirectory(siaPath modules.SiaPath, metadata siadir.Metadata, repairThreshold float64, explored bool) {
 	d := &directory{
-		aggregateHealth:       metadata.AggregateHealth,
-		aggregateRemoteHealth: metadata.AggregateRemoteHealth,
+		aggregateHealth:       policyHealth(metadata.AggregateHealth, repairThreshold),
+		aggregateRemoteHealth: policyHealth(metadata.AggregateRemoteHealth, repairThreshold),
 		explored:              explored,
-		health:                metadata.Health,
-		remoteHealth:          metadata.RemoteHealth,
+		health:                policyHealth(metadata.Health, repairThreshold),
+		remoteHealth:          policyHealth(metadata.RemoteHealth, repairThreshold),
 		staticSiaPath:         siaPath,
 	}
 	dh.managedPush(d)
@@ -315,7 +322,13 @@ func (r *Renter) managedPushUnexploredDirectory(siaPath modules.SiaPath) error {
 		return err
 	}
 
+	// Get the directory policy.
+	policy, err := r.managedDirectoryPolicy(siaPath)
+	if err != nil {
+		return err
+	}
+
 	// Push unexplored directory onto heap.
-	r.directoryHeap.managedPushDirectory(siaPath, metadata, false)
+	r.directoryHeap.managedPushDirectory(siaPath, metadata, policyRepairThreshold(policy), false)
 	return nil
 }
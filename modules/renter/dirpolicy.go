@@ -0,0 +1,241 @@
+package renter
+
+import (
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+)
+
+// policyAllowsHost returns whether the policy allows storing pieces on the
+// host with the given public key string.
+func policyAllowsHost(policy modules.DirectoryPolicy, hpk string) bool {
+	for _, pk := range policy.ExcludedHosts {
+		if pk.String() == hpk {
+			return false
+		}
+	}
+	if len(policy.PinnedHosts) == 0 {
+		return true
+	}
+	for _, pk := range policy.PinnedHosts {
+		if pk.String() == hpk {
+			return true
+		}
+	}
+	return false
+}
+
+// policyErasureCodeMatches returns whether the erasure code matches the
+// erasure coding parameters of the policy. A policy without erasure coding
+// parameters matches every erasure code.
+func policyErasureCodeMatches(policy modules.DirectoryPolicy, ec modules.ErasureCoder) bool {
+	if !policy.HasErasureCode() {
+		return true
+	}
+	return ec.MinPieces() == policy.DataPieces && ec.NumPieces()-ec.MinPieces() == policy.ParityPieces
+}
+
+// policyGoodForRenew returns a copy of the goodForRenew map in which the hosts
+// that the policy doesn't allow are not good for renew. Pieces on those hosts
+// then don't count towards the health of a file, which causes the repair code
+// to move them to allowed hosts.
+func policyGoodForRenew(policy modules.DirectoryPolicy, goodForRenew map[string]bool) map[string]bool {
+	if len(policy.PinnedHosts) == 0 && len(policy.ExcludedHosts) == 0 {
+		return goodForRenew
+	}
+	gfr := make(map[string]bool, len(goodForRenew))
+	for host, good := range goodForRenew {
+		gfr[host] = good && policyAllowsHost(policy, host)
+	}
+	return gfr
+}
+
+// policyHealth scales a health value of a chunk, file or directory that is
+// subject to a policy with a custom repair threshold. The scaled health
+// reaches the renter-wide RepairThreshold exactly when the health reaches the
+// threshold of the policy. That way the repair code can compare all health
+// values against RepairThreshold.
+func policyHealth(health, repairThreshold float64) float64 {
+	if repairThreshold <= 0 || repairThreshold == RepairThreshold {
+		return health
+	}
+	return health * RepairThreshold / repairThreshold
+}
+
+// policyHosts returns the subset of hosts which the policy allows storing
+// pieces on.
+func policyHosts(policy modules.DirectoryPolicy, hosts map[string]struct{}) map[string]struct{} {
+	if len(policy.PinnedHosts) == 0 && len(policy.ExcludedHosts) == 0 {
+		return hosts
+	}
+	allowed := make(map[string]struct{}, len(hosts))
+	for host := range hosts {
+		if policyAllowsHost(policy, host) {
+			allowed[host] = struct{}{}
+		}
+	}
+	return allowed
+}
+
+// policyRepairThreshold returns the repair threshold of the policy or the
+// renter-wide RepairThreshold if the policy doesn't set one.
+func policyRepairThreshold(policy modules.DirectoryPolicy) float64 {
+	if policy.RepairThreshold <= 0 {
+		return RepairThreshold
+	}
+	return policy.RepairThreshold
+}
+
+// managedDirectoryPolicy returns the policy that applies to the directory with
+// the given siapath. That is the policy of the directory itself or, if it
+// doesn't have one, the policy of the closest parent directory with a policy.
+// Directories which don't exist yet inherit the policy of their parent.
+func (r *Renter) managedDirectoryPolicy(siaPath modules.SiaPath) (modules.DirectoryPolicy, error) {
+	for {
+		dir, err := r.staticFileSystem.OpenSiaDir(siaPath)
+		if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+			return modules.DirectoryPolicy{}, errors.AddContext(err, "unable to open directory")
+		}
+		if err == nil {
+			md, err := dir.Metadata()
+			dir.Close()
+			if err != nil {
+				return modules.DirectoryPolicy{}, errors.AddContext(err, "unable to read directory metadata")
+			}
+			if md.Policy.IsSet() {
+				return md.Policy, nil
+			}
+		}
+		if siaPath.IsRoot() {
+			return modules.DirectoryPolicy{}, nil
+		}
+		siaPath, err = siaPath.Dir()
+		if err != nil {
+			return modules.DirectoryPolicy{}, err
+		}
+	}
+}
+
+// managedFilePolicy returns the policy that applies to a file.
+func (r *Renter) managedFilePolicy(siaPath modules.SiaPath) (modules.DirectoryPolicy, error) {
+	dirSiaPath, err := siaPath.Dir()
+	if err != nil {
+		return modules.DirectoryPolicy{}, err
+	}
+	return r.managedDirectoryPolicy(dirSiaPath)
+}
+
+// managedDefaultErasureCode returns the erasure code for a new upload to the
+// given siapath if the upload doesn't specify one.
+func (r *Renter) managedDefaultErasureCode(siaPath modules.SiaPath) (modules.ErasureCoder, error) {
+	policy, err := r.managedFilePolicy(siaPath)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to get directory policy")
+	}
+	if policy.HasErasureCode() {
+		return siafile.NewRSSubCode(policy.DataPieces, policy.ParityPieces, crypto.SegmentSize)
+	}
+	return siafile.NewRSSubCode(DefaultDataPieces, DefaultParityPieces, crypto.SegmentSize)
+}
+
+// threadedApplyDirectoryPolicy makes sure that the files within the directory
+// and its sub directories comply with their policy. Files with an erasure code
+// that doesn't match the policy are re-encoded and the chunks of all other
+// files are queued for repair to move pieces off hosts which aren't allowed
+// anymore.
+func (r *Renter) threadedApplyDirectoryPolicy(siaPath modules.SiaPath) {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+
+	fis, _, err := r.staticFileSystem.CachedList(siaPath, true)
+	if err != nil {
+		r.log.Printf("WARN: unable to list files of %v to apply the directory policy: %v", siaPath, err)
+		return
+	}
+	hosts := r.managedRefreshHostsAndWorkers()
+	offline, goodForRenew, _ := r.managedContractUtilityMaps()
+	for _, fi := range fis {
+		select {
+		case <-r.tg.StopChan():
+			return
+		default:
+		}
+		policy, err := r.managedFilePolicy(fi.SiaPath)
+		if err != nil {
+			r.log.Printf("WARN: unable to get policy of %v: %v", fi.SiaPath, err)
+			continue
+		}
+		entry, err := r.staticFileSystem.OpenSiaFile(fi.SiaPath)
+		if err != nil {
+			r.log.Printf("WARN: unable to open %v to apply the directory policy: %v", fi.SiaPath, err)
+			continue
+		}
+		if !policyErasureCodeMatches(policy, entry.ErasureCode()) {
+			entry.Close()
+			ec, err := siafile.NewRSSubCode(policy.DataPieces, policy.ParityPieces, crypto.SegmentSize)
+			if err != nil {
+				r.log.Printf("WARN: invalid erasure code in policy of %v: %v", fi.SiaPath, err)
+				continue
+			}
+			if err := r.managedMigrateFile(fi.SiaPath, ec, crypto.TypeInvalid); err != nil {
+				r.log.Printf("WARN: unable to migrate %v: %v", fi.SiaPath, err)
+			}
+			continue
+		}
+		r.callBuildAndPushChunks([]*filesystem.FileNode{entry}, hosts, targetUnstuckChunks, offline, policyGoodForRenew(policy, goodForRenew))
+		entry.Close()
+	}
+	select {
+	case r.uploadHeap.newUploads <- struct{}{}:
+	default:
+	}
+	// Update the health of the directories since pieces on hosts which
+	// aren't allowed anymore don't count towards it.
+	dirs := make(map[modules.SiaPath]struct{})
+	for _, fi := range fis {
+		dirSiaPath, err := fi.SiaPath.Dir()
+		if err != nil {
+			continue
+		}
+		dirs[dirSiaPath] = struct{}{}
+	}
+	for dirSiaPath := range dirs {
+		go r.callThreadedBubbleMetadata(dirSiaPath)
+	}
+}
+
+// SetDirPolicy sets the redundancy and host-set policy of a directory. Files
+// within the directory that don't comply with the new policy are re-encoded
+// or repaired in the background.
+func (r *Renter) SetDirPolicy(siaPath modules.SiaPath, policy modules.DirectoryPolicy) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+
+	// Validate the erasure code before setting the policy.
+	if err := policy.Validate(); err != nil {
+		return errors.AddContext(err, "invalid directory policy")
+	}
+	if policy.HasErasureCode() {
+		if _, err := siafile.NewRSSubCode(policy.DataPieces, policy.ParityPieces, crypto.SegmentSize); err != nil {
+			return errors.AddContext(err, "invalid erasure coding parameters")
+		}
+	}
+
+	dir, err := r.staticFileSystem.OpenSiaDir(siaPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to open directory")
+	}
+	err = dir.SetPolicy(policy)
+	if err = errors.Compose(err, dir.Close()); err != nil {
+		return errors.AddContext(err, "unable to set directory policy")
+	}
+	go r.threadedApplyDirectoryPolicy(siaPath)
+	return nil
+}
@@ -0,0 +1,191 @@
+package renter
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/siatest/dependencies"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestPolicyHosts probes the policyHosts helper.
+func TestPolicyHosts(t *testing.T) {
+	pk1 := types.Ed25519PublicKey(crypto.PublicKey{1})
+	pk2 := types.Ed25519PublicKey(crypto.PublicKey{2})
+	pk3 := types.Ed25519PublicKey(crypto.PublicKey{3})
+	hosts := map[string]struct{}{
+		pk1.String(): {},
+		pk2.String(): {},
+		pk3.String(): {},
+	}
+
+	// Without a host-set policy all hosts are allowed.
+	if allowed := policyHosts(modules.DirectoryPolicy{}, hosts); len(allowed) != len(hosts) {
+		t.Fatal("expected all hosts to be allowed", len(allowed))
+	}
+	// Excluded hosts are removed.
+	allowed := policyHosts(modules.DirectoryPolicy{ExcludedHosts: []types.SiaPublicKey{pk1}}, hosts)
+	if _, exists := allowed[pk1.String()]; exists || len(allowed) != 2 {
+		t.Fatal("excluded host wasn't removed", allowed)
+	}
+	// Only pinned hosts are kept.
+	allowed = policyHosts(modules.DirectoryPolicy{PinnedHosts: []types.SiaPublicKey{pk2}}, hosts)
+	if _, exists := allowed[pk2.String()]; !exists || len(allowed) != 1 {
+		t.Fatal("only the pinned host should be allowed", allowed)
+	}
+	// The original map shouldn't be modified.
+	if len(hosts) != 3 {
+		t.Fatal("hosts were modified")
+	}
+}
+
+// TestPolicyGoodForRenew checks that hosts which aren't allowed by a policy
+// are not good for renew.
+func TestPolicyGoodForRenew(t *testing.T) {
+	pk1 := types.Ed25519PublicKey(crypto.PublicKey{1})
+	pk2 := types.Ed25519PublicKey(crypto.PublicKey{2})
+	pk3 := types.Ed25519PublicKey(crypto.PublicKey{3})
+	goodForRenew := map[string]bool{
+		pk1.String(): true,
+		pk2.String(): true,
+		pk3.String(): false,
+	}
+
+	// Excluded hosts are not good for renew.
+	gfr := policyGoodForRenew(modules.DirectoryPolicy{ExcludedHosts: []types.SiaPublicKey{pk1}}, goodForRenew)
+	if gfr[pk1.String()] || !gfr[pk2.String()] || gfr[pk3.String()] {
+		t.Fatal("unexpected goodForRenew map", gfr)
+	}
+	// Only pinned hosts are good for renew and pinning a host doesn't make it
+	// good for renew.
+	gfr = policyGoodForRenew(modules.DirectoryPolicy{PinnedHosts: []types.SiaPublicKey{pk2, pk3}}, goodForRenew)
+	if gfr[pk1.String()] || !gfr[pk2.String()] || gfr[pk3.String()] {
+		t.Fatal("unexpected goodForRenew map", gfr)
+	}
+	// The original map shouldn't be modified.
+	if !goodForRenew[pk1.String()] {
+		t.Fatal("goodForRenew was modified")
+	}
+}
+
+// TestPolicyHealth probes the policyHealth helper.
+func TestPolicyHealth(t *testing.T) {
+	// The default threshold doesn't change the health.
+	if h := policyHealth(0.5, RepairThreshold); h != 0.5 {
+		t.Fatal("health shouldn't change", h)
+	}
+	if h := policyHealth(0.5, 0); h != 0.5 {
+		t.Fatal("health shouldn't change", h)
+	}
+	// A health at the policy's threshold is scaled to the renter's threshold.
+	threshold := RepairThreshold / 2
+	if h := policyHealth(threshold, threshold); h != RepairThreshold {
+		t.Fatalf("expected health %v but got %v", RepairThreshold, h)
+	}
+	if h := policyHealth(threshold/2, threshold); h >= RepairThreshold {
+		t.Fatal("health should be below the threshold", h)
+	}
+}
+
+// TestDirectoryPolicyInheritance checks that directories inherit the policy of
+// their closest parent with a policy.
+func TestDirectoryPolicyInheritance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTesterWithDependency(t.Name(), &dependencies.DependencyDisableRepairAndHealthLoops{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Create a directory tree.
+	foo, err := modules.NewSiaPath("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fooBar, err := modules.NewSiaPath("foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.CreateDir(fooBar, modules.DefaultDirPerm); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a policy the defaults are used.
+	ec, err := rt.renter.managedDefaultErasureCode(modules.RandomSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.MinPieces() != DefaultDataPieces || ec.NumPieces() != DefaultDataPieces+DefaultParityPieces {
+		t.Fatal("expected default erasure code", ec.MinPieces(), ec.NumPieces())
+	}
+
+	// Set a policy on foo.
+	policy := modules.DirectoryPolicy{
+		DataPieces:      2,
+		ParityPieces:    3,
+		RepairThreshold: 0.1,
+	}
+	if err := rt.renter.SetDirPolicy(foo, policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// foo/bar and files in not yet existing sub directories inherit it.
+	p, err := rt.renter.managedDirectoryPolicy(fooBar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, policy) {
+		t.Fatal("policy wasn't inherited", p)
+	}
+	fileSiaPath, err := fooBar.Join("baz/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, err = rt.renter.managedDefaultErasureCode(fileSiaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.MinPieces() != 2 || ec.NumPieces() != 5 {
+		t.Fatal("expected policy erasure code", ec.MinPieces(), ec.NumPieces())
+	}
+
+	// The root is not affected.
+	p, err = rt.renter.managedDirectoryPolicy(modules.RootSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.IsSet() {
+		t.Fatal("root shouldn't have a policy", p)
+	}
+
+	// A policy on foo/bar overrides the one on foo.
+	barPolicy := modules.DirectoryPolicy{RepairThreshold: 0.2}
+	if err := rt.renter.SetDirPolicy(fooBar, barPolicy); err != nil {
+		t.Fatal(err)
+	}
+	p, err = rt.renter.managedDirectoryPolicy(fooBar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, barPolicy) {
+		t.Fatal("policy wasn't overridden", p)
+	}
+
+	// The policy is returned in the directory info.
+	dis, err := rt.renter.DirList(foo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dis[0].Policy, policy) {
+		t.Fatal("policy missing from directory info", dis[0].Policy)
+	}
+
+	// Invalid policies are rejected.
+	if err := rt.renter.SetDirPolicy(foo, modules.DirectoryPolicy{RepairThreshold: 2}); err == nil {
+		t.Fatal("expected invalid policy to be rejected")
+	}
+}
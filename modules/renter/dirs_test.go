@@ -216,12 +216,12 @@ func TestRenterListDirectory(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(directories) != 4 {
+	if len(directories) != 5 {
 		dirlst := make([]string, 0)
 		for _, d := range directories {
 			dirlst = append(dirlst, "'"+d.Name()+"'")
 		}
-		t.Errorf("Expected 4 DirectoryInfos but got %v:%s", len(directories), dirlst)
+		t.Errorf("Expected 5 DirectoryInfos but got %v:%s", len(directories), dirlst)
 	}
 	files, err := rt.renter.FileList(modules.RootSiaPath(), false, false)
 	if err != nil {
@@ -243,8 +243,8 @@ func TestRenterListDirectory(t *testing.T) {
 			return err
 		}
 		root := directories[0]
-		if root.NumSubDirs != 3 {
-			return fmt.Errorf("Expected 3 subdirs but got %v", root.NumSubDirs)
+		if root.NumSubDirs != 4 {
+			return fmt.Errorf("Expected 4 subdirs but got %v", root.NumSubDirs)
 		}
 		if root.AggregateNumFiles != 1 {
 			return fmt.Errorf("Expected 1 file in aggregate but got %v", root.AggregateNumFiles)
@@ -272,6 +272,10 @@ func TestRenterListDirectory(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	packsDir, err := rt.renter.staticFileSystem.OpenSiaDir(modules.PackFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
 	snapshotsDir, err := rt.renter.staticFileSystem.OpenSiaDir(modules.BackupFolder)
 	if err != nil {
 		t.Fatal(err)
@@ -289,7 +293,10 @@ func TestRenterListDirectory(t *testing.T) {
 	if err = compareDirectoryInfoAndMetadata(directories[2], homeDir); err != nil {
 		t.Error(err)
 	}
-	if err = compareDirectoryInfoAndMetadata(directories[3], snapshotsDir); err != nil {
+	if err = compareDirectoryInfoAndMetadata(directories[3], packsDir); err != nil {
+		t.Error(err)
+	}
+	if err = compareDirectoryInfoAndMetadata(directories[4], snapshotsDir); err != nil {
 		t.Error(err)
 	}
 }
@@ -70,6 +70,12 @@ type (
 		disableLocalFetch bool                // Whether or not the file can be fetched from disk if available.
 		file              *siafile.Snapshot   // The file to download.
 
+		// Resumable downloads are recorded in the download queue. The
+		// completed chunks of a resumed download are skipped.
+		completedChunks map[uint64]struct{}
+		resumable       bool
+		uid             modules.DownloadID
+
 		latencyTarget time.Duration // Workers above this latency will be automatically put on standby initially.
 		length        uint64        // Length of download. Cannot be 0.
 		needsMemory   bool          // Whether new memory needs to be allocated to perform the download.
@@ -181,6 +187,24 @@ func (d *download) OnComplete(f func(error) error) {
 	d.onComplete(f)
 }
 
+// managedChunkWritten records a chunk which was written to the destination of
+// a resumable download. The destination is synced first to make sure the
+// chunk isn't lost if the renter shuts down.
+func (d *download) managedChunkWritten(chunkIndex, length uint64) {
+	if !d.staticParams.resumable {
+		return
+	}
+	if ddf, ok := d.staticParams.destination.(*downloadDestinationFile); ok {
+		if err := ddf.f.Sync(); err != nil {
+			d.r.log.Debugln("Failed to sync download destination:", err)
+			return
+		}
+	}
+	if err := d.r.staticDownloadQueue.managedChunkCompleted(d.staticUID, chunkIndex, length); err != nil {
+		d.r.log.Println("Failed to update download queue:", err)
+	}
+}
+
 // UID returns the unique identifier of the download.
 func (d *download) UID() modules.DownloadID {
 	return d.staticUID
@@ -194,7 +218,7 @@ func (r *Renter) Download(p modules.RenterDownloadParameters) (modules.DownloadI
 		return "", nil, err
 	}
 	defer r.tg.Done()
-	d, err := r.managedDownload(p)
+	d, err := r.managedDownload(p, nil)
 	if err != nil {
 		return "", nil, err
 	}
@@ -223,7 +247,7 @@ func (r *Renter) DownloadAsync(p modules.RenterDownloadParameters, f func(error)
 		return "", nil, nil, err
 	}
 	defer r.tg.Done()
-	d, err := r.managedDownload(p)
+	d, err := r.managedDownload(p, nil)
 	if err != nil {
 		return "", nil, nil, err
 	}
@@ -237,8 +261,8 @@ func (r *Renter) DownloadAsync(p modules.RenterDownloadParameters, f func(error)
 
 // managedDownload performs a file download using the passed parameters and
 // returns the download object and an error that indicates if the download
-// setup was successful.
-func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download, error) {
+// setup was successful. If qd is not nil, the queued download is resumed.
+func (r *Renter) managedDownload(p modules.RenterDownloadParameters, qd *queuedDownload) (*download, error) {
 	// Lookup the file associated with the nickname.
 	entry, err := r.staticFileSystem.OpenSiaFile(p.SiaPath)
 	if err != nil {
@@ -261,19 +285,22 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download,
 	if p.Destination != "" && !filepath.IsAbs(p.Destination) {
 		return nil, errors.New("destination must be an absolute path")
 	}
-	if p.Offset == entry.Size() && entry.Size() != 0 {
+	// For compressed files the offset and length are relative to the logical
+	// data of the file.
+	fileSize := entry.UncompressedSize()
+	if p.Offset == fileSize && fileSize != 0 {
 		return nil, errors.New("offset equals filesize")
 	}
 	// Sentinel: if length == 0, download the entire file.
 	if p.Length == 0 {
-		if p.Offset > entry.Size() {
+		if p.Offset > fileSize {
 			return nil, errors.New("offset cannot be greater than file size")
 		}
-		p.Length = entry.Size() - p.Offset
+		p.Length = fileSize - p.Offset
 	}
 	// Check whether offset and length is valid.
-	if p.Offset < 0 || p.Offset+p.Length > entry.Size() {
-		return nil, fmt.Errorf("offset and length combination invalid, max byte is at index %d", entry.Size()-1)
+	if p.Offset < 0 || p.Offset+p.Length > fileSize {
+		return nil, fmt.Errorf("offset and length combination invalid, max byte is at index %d", fileSize-1)
 	}
 
 	// Instantiate the correct downloadWriter implementation.
@@ -295,6 +322,32 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download,
 		destinationType = "file"
 	}
 
+	// Compressed files are downloaded by fetching the range of stored data
+	// which contains the compressed chunks overlapping with the requested
+	// range. The destination decompresses them and writes the requested
+	// logical data to the actual destination.
+	offset, length := p.Offset, p.Length
+	if ct := entry.CompressionType(); ct != modules.CompressionNone && length > 0 {
+		var w io.Writer
+		if isHTTPResp {
+			w = p.Httpwriter
+		} else {
+			ddf := dw.(*downloadDestinationFile)
+			w = &fileSectionWriteCloser{
+				sectionWriter: NewSectionWriter(ddf.f, 0, int64(length)),
+				f:             ddf.f,
+			}
+		}
+		dd, storedOffset, storedLength, err := newDownloadDestinationDecompress(w, ct, entry.CompressedChunks(), entry.ChunkSize(), offset, length)
+		if closer, ok := dw.(io.Closer); err != nil && ok {
+			return nil, errors.Compose(err, closer.Close())
+		} else if err != nil {
+			return nil, err
+		}
+		dw = dd
+		offset, length = storedOffset, storedLength
+	}
+
 	// If the destination is a httpWriter, we set the Content-Length in the
 	// header.
 	if isHTTPResp {
@@ -304,8 +357,42 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download,
 		}
 	}
 
+	// Packed files are downloaded from their pack.
+	dataEntry := entry
+	pack, packInfo, err := r.managedOpenPack(entry)
+	if err != nil {
+		return nil, err
+	}
+	if pack != nil {
+		defer pack.Close()
+		dataEntry = pack
+		offset += packInfo.Offset
+	}
+
+	// Downloads to uncompressed files on disk can be resumed. If the siafile
+	// changed since the queued download was started, it starts from scratch.
+	resumable := destinationType == "file" && entry.CompressionType() == modules.CompressionNone
+	rec := queuedDownload{
+		UID:              modules.DownloadID(hex.EncodeToString(fastrand.Bytes(16))),
+		SiaPath:          p.SiaPath,
+		SiaFileUID:       entry.UID(),
+		Destination:      p.Destination,
+		Offset:           p.Offset,
+		Length:           p.Length,
+		DisableDiskFetch: p.DisableDiskFetch,
+		StartTime:        time.Now(),
+	}
+	if qd != nil {
+		rec.UID = qd.UID
+		if qd.SiaFileUID == rec.SiaFileUID {
+			rec.StartTime = qd.StartTime
+			rec.CompletedChunks = qd.CompletedChunks
+			rec.Received = qd.Received
+		}
+	}
+
 	// Prepare snapshot.
-	snap, err := entry.Snapshot(p.SiaPath)
+	snap, err := dataEntry.Snapshot(p.SiaPath)
 	if err != nil {
 		return nil, err
 	}
@@ -317,10 +404,14 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download,
 		disableLocalFetch: p.DisableDiskFetch,
 		file:              snap,
 
+		completedChunks: rec.completedChunks(),
+		resumable:       resumable,
+		uid:             rec.UID,
+
 		latencyTarget: 25e3 * time.Millisecond, // TODO: high default until full latency support is added.
-		length:        p.Length,
+		length:        length,
 		needsMemory:   true,
-		offset:        p.Offset,
+		offset:        offset,
 		overdrive:     3, // TODO: moderate default until full overdrive support is added.
 		priority:      5, // TODO: moderate default until full priority support is added.
 	})
@@ -344,6 +435,27 @@ func (r *Renter) managedDownload(p modules.RenterDownloadParameters) (*download,
 		return nil
 	})
 
+	// Add resumable downloads to the download queue and update them once the
+	// download is done.
+	if resumable {
+		if err := r.staticDownloadQueue.managedAdd(rec); err != nil {
+			d.managedFail(err)
+			return nil, errors.AddContext(err, "failed to add download to the download queue")
+		}
+		d.OnComplete(func(err error) error {
+			// Downloads interrupted by a shutdown are resumed on startup.
+			select {
+			case <-r.tg.StopChan():
+				return nil
+			default:
+			}
+			if err == nil || errors.Contains(err, modules.ErrDownloadCancelled) {
+				return r.staticDownloadQueue.managedRemove(d.UID())
+			}
+			return r.staticDownloadQueue.managedFail(d.UID(), err)
+		})
+	}
+
 	// Add the download object to the download history if it's not a stream.
 	if destinationType != destinationTypeSeekStream {
 		r.downloadHistoryMu.Lock()
@@ -373,6 +485,10 @@ func (r *Renter) managedNewDownload(params downloadParams) (*download, error) {
 	}
 
 	// Create the download object.
+	uid := params.uid
+	if uid == "" {
+		uid = modules.DownloadID(hex.EncodeToString(fastrand.Bytes(16)))
+	}
 	d := &download{
 		completeChan: make(chan struct{}),
 
@@ -381,7 +497,7 @@ func (r *Renter) managedNewDownload(params downloadParams) (*download, error) {
 		destination:           params.destination,
 		destinationString:     params.destinationString,
 		staticDestinationType: params.destinationType,
-		staticUID:             modules.DownloadID(hex.EncodeToString(fastrand.Bytes(16))),
+		staticUID:             uid,
 		staticLatencyTarget:   params.latencyTarget,
 		staticLength:          params.length,
 		staticOffset:          params.offset,
@@ -454,14 +570,24 @@ func (d *download) Start() error {
 		}
 	}
 
-	// Queue the downloads for each chunk.
+	// Queue the downloads for each chunk. Chunks which were completed before
+	// the download was resumed are skipped.
 	writeOffset := int64(0) // where to write a chunk within the download destination.
 	d.chunksRemaining += maxChunk - minChunk + 1
+	for i := minChunk; i <= maxChunk; i++ {
+		if _, completed := params.completedChunks[i]; completed {
+			d.chunksRemaining--
+		}
+	}
+	if d.chunksRemaining == 0 {
+		atomic.StoreUint64(&d.atomicDataReceived, d.staticLength)
+		d.markComplete()
+		return nil
+	}
 	for i := minChunk; i <= maxChunk; i++ {
 		udc := &unfinishedDownloadChunk{
 			destination: params.destination,
 			erasureCode: params.file.ErasureCode(),
-			masterKey:   params.file.MasterKey(),
 
 			staticChunkIndex: i,
 			staticCacheID:    fmt.Sprintf("%v:%v", d.staticSiaPath, i),
@@ -509,6 +635,10 @@ func (d *download) Start() error {
 		// be written.
 		udc.staticWriteOffset = writeOffset
 		writeOffset += int64(udc.staticFetchLength)
+		if _, completed := params.completedChunks[i]; completed {
+			atomic.AddUint64(&d.atomicDataReceived, udc.staticFetchLength)
+			continue
+		}
 
 		// TODO: Currently all chunks are given overdrive. This should probably
 		// be changed once the hostdb knows how to measure host speed/latency
@@ -534,6 +664,12 @@ func (r *Renter) DownloadByUID(uid modules.DownloadID) (modules.DownloadInfo, bo
 	if !exists {
 		return modules.DownloadInfo{}, false
 	}
+	var errStr string
+	if err := d.Err(); err != nil {
+		errStr = err.Error()
+	}
+	d.mu.Lock() // Lock required for d.endTime only.
+	defer d.mu.Unlock()
 	return modules.DownloadInfo{
 		Destination:     d.destinationString,
 		DestinationType: d.staticDestinationType,
@@ -543,6 +679,7 @@ func (r *Renter) DownloadByUID(uid modules.DownloadID) (modules.DownloadInfo, bo
 
 		Completed:            d.staticComplete(),
 		EndTime:              d.endTime,
+		Error:                errStr,
 		Received:             atomic.LoadUint64(&d.atomicDataReceived),
 		StartTime:            d.staticStartTime,
 		StartTimeUnix:        d.staticStartTime.UnixNano(),
@@ -629,22 +766,25 @@ func (r *Renter) ClearDownloadHistory(after, before time.Time) error {
 		return errors.New("before timestamp can not be newer then after timestamp")
 	}
 
-	// Clear download history if both before and after timestamps are zero values
-	if before.Equal(types.EndOfTime) && after.IsZero() {
-		r.downloadHistory = make(map[modules.DownloadID]*download)
-		return nil
-	}
-
-	// Find and return downloads that are not within the given range
+	// Find and return downloads that are not within the given range. Clear
+	// download history if both before and after timestamps are zero values
+	clearAll := before.Equal(types.EndOfTime) && after.IsZero()
 	withinTimespan := func(t time.Time) bool {
 		return (t.After(after) || t.Equal(after)) && (t.Before(before) || t.Equal(before))
 	}
 	filtered := make(map[modules.DownloadID]*download)
+	var err error
 	for _, d := range r.downloadHistory {
-		if !withinTimespan(d.staticStartTime) {
+		if !clearAll && !withinTimespan(d.staticStartTime) {
 			filtered[d.UID()] = d
+			continue
+		}
+		// Failed downloads which are cleared from the history can't be
+		// resumed anymore.
+		if d.staticComplete() {
+			err = errors.Compose(err, r.staticDownloadQueue.managedRemove(d.UID()))
 		}
 	}
 	r.downloadHistory = filtered
-	return nil
+	return err
 }
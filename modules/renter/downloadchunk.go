@@ -2,6 +2,7 @@ package renter
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -38,7 +39,6 @@ type unfinishedDownloadChunk struct {
 	// Fetch + Write instructions - read only or otherwise thread safe.
 	destination downloadDestination // Where to write the recovered logical chunk.
 	erasureCode modules.ErasureCoder
-	masterKey   crypto.CipherKey
 
 	// Fetch + Write instructions - read only or otherwise thread safe.
 	staticChunkIndex  uint64                       // Required for deriving the encryption keys for each piece.
@@ -131,11 +131,34 @@ func (udc *unfinishedDownloadChunk) managedCleanUp() {
 	}
 	udc.workersStandby = udc.workersStandby[:0] // Workers have been taken off of standby.
 	udc.mu.Unlock()
+	sortWorkersByFetchTime(standbyWorkers, udc.staticPieceSize)
 	for i := 0; i < len(standbyWorkers); i++ {
 		standbyWorkers[i].callQueueDownloadChunk(udc)
 	}
 }
 
+// sortWorkersByFetchTime sorts the workers by the time their hosts are
+// estimated to need for fetching a piece of the given size, fastest first.
+// Queueing chunks in that order gives the fastest idle workers the first
+// chance to register for a piece. Workers without a cache keep their
+// position at the end.
+func sortWorkersByFetchTime(workers []*worker, pieceSize uint64) {
+	estimates := make(map[*worker]time.Duration, len(workers))
+	for _, w := range workers {
+		if cache := w.staticCache(); cache != nil {
+			estimates[w] = cache.staticHostPerformance.estimatedFetchTime(pieceSize)
+		}
+	}
+	sort.SliceStable(workers, func(i, j int) bool {
+		ei, iKnown := estimates[workers[i]]
+		ej, jKnown := estimates[workers[j]]
+		if iKnown != jKnown {
+			return iKnown
+		}
+		return ei < ej
+	})
+}
+
 // managedFinalizeRecovery sets recoveryComplete to 'true' and also marks
 // the download as complete if there are no more chunks remaining.
 func (udc *unfinishedDownloadChunk) managedFinalizeRecovery() {
@@ -221,6 +244,15 @@ func (udc *unfinishedDownloadChunk) threadedRecoverLogicalData() error {
 	// succeeds or fails.
 	defer udc.managedCleanUp()
 
+	// Fail the chunk if the download is interrupted by a dependency.
+	if udc.download.r.deps.Disrupt("InterruptDownloadChunk") {
+		err := errors.New("InterruptDownloadChunk disrupt")
+		udc.mu.Lock()
+		udc.fail(err)
+		udc.mu.Unlock()
+		return err
+	}
+
 	// Write the pieces to the requested output.
 	dataOffset := recoveredDataOffset(udc.staticFetchOffset, udc.erasureCode)
 	err := udc.destination.WritePieces(udc.erasureCode, udc.physicalChunkData, dataOffset, udc.staticWriteOffset, udc.staticFetchLength)
@@ -230,6 +262,7 @@ func (udc *unfinishedDownloadChunk) threadedRecoverLogicalData() error {
 		udc.mu.Unlock()
 		return errors.AddContext(err, "unable to write to download destination")
 	}
+	udc.download.managedChunkWritten(udc.staticChunkIndex, udc.staticFetchLength)
 	// finalize the chunk.
 	udc.managedFinalizeRecovery()
 	return nil
@@ -2,6 +2,8 @@ package renter
 
 import (
 	"testing"
+	"time"
+	"unsafe"
 
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
@@ -116,3 +118,29 @@ func TestBytesToRecover(t *testing.T) {
 	assert(640, 1281, 1920)
 	assert(641, 1280, 1920)
 }
+
+// TestSortWorkersByFetchTime checks that workers are sorted by the estimated
+// fetch time of their hosts.
+func TestSortWorkersByFetchTime(t *testing.T) {
+	newWorker := func(perf *hostPerformance) *worker {
+		w := new(worker)
+		if perf != nil {
+			w.atomicCache = unsafe.Pointer(&workerCache{staticHostPerformance: *perf})
+		}
+		return w
+	}
+	noCache := newWorker(nil)
+	slow := newWorker(&hostPerformance{latency: time.Second, downloadThroughput: 1 << 20})
+	fast := newWorker(&hostPerformance{latency: 50 * time.Millisecond, downloadThroughput: 1 << 22})
+	highLatency := newWorker(&hostPerformance{latency: 2 * time.Second, downloadThroughput: 1 << 30})
+	unmeasured := newWorker(&hostPerformance{})
+
+	workers := []*worker{noCache, slow, highLatency, fast, unmeasured}
+	sortWorkersByFetchTime(workers, 1<<22)
+	expected := []*worker{unmeasured, fast, highLatency, slow, noCache}
+	for i := range expected {
+		if workers[i] != expected[i] {
+			t.Fatalf("worker %v is out of order", i)
+		}
+	}
+}
@@ -121,7 +121,7 @@ func (r *Renter) managedBlockUntilOnline() bool {
 }
 
 // managedDistributeDownloadChunkToWorkers will take a chunk and pass it out to
-// all of the workers.
+// all of the workers, starting with the workers of the fastest hosts.
 func (r *Renter) managedDistributeDownloadChunkToWorkers(udc *unfinishedDownloadChunk) {
 	// Distribute the chunk to workers, marking the number of workers
 	// that have received the work.
@@ -129,7 +129,12 @@ func (r *Renter) managedDistributeDownloadChunkToWorkers(udc *unfinishedDownload
 	udc.mu.Lock()
 	udc.workersRemaining = len(r.staticWorkerPool.workers)
 	udc.mu.Unlock()
+	workers := make([]*worker, 0, len(r.staticWorkerPool.workers))
 	for _, worker := range r.staticWorkerPool.workers {
+		workers = append(workers, worker)
+	}
+	sortWorkersByFetchTime(workers, udc.staticPieceSize)
+	for _, worker := range workers {
 		worker.callQueueDownloadChunk(udc)
 	}
 	r.staticWorkerPool.mu.RUnlock()
@@ -261,6 +266,7 @@ func (r *Renter) managedTryFetchChunkFromDisk(chunk *unfinishedDownloadChunk) bo
 				localPath, fileName, err)
 			return err, false
 		}
+		chunk.download.managedChunkWritten(chunk.staticChunkIndex, chunk.staticFetchLength)
 		return nil, true
 	}()
 	return true
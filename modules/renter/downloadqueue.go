@@ -0,0 +1,299 @@
+package renter
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+	"gitlab.com/scpcorp/ScPrime/persist"
+)
+
+// Downloads to a file on disk are resumable. The renter keeps a record of each
+// such download in the download queue, together with the chunks which were
+// already written to the destination. Downloads which were interrupted by a
+// shutdown are resumed on startup. Downloads which failed stay in the queue
+// and can be resumed manually. A resumed download only fetches the chunks
+// which are still missing.
+
+const (
+	// downloadQueueFilename is the filename of the renter's download queue.
+	downloadQueueFilename = "downloadqueue.json"
+)
+
+var (
+	// downloadQueueMetadata is the header of the persisted download queue.
+	downloadQueueMetadata = persist.Metadata{
+		Header:  "Renter Download Queue",
+		Version: "1.5.4",
+	}
+
+	// errDownloadInProgress is returned when resuming a download which is
+	// still running.
+	errDownloadInProgress = errors.New("download is still in progress")
+
+	// errDownloadNotResumable is returned when resuming a download which is
+	// not part of the download queue.
+	errDownloadNotResumable = errors.New("download not found in the download queue")
+)
+
+type (
+	// downloadQueue keeps track of the resumable downloads.
+	downloadQueue struct {
+		downloads  map[modules.DownloadID]*queuedDownload
+		staticPath string
+		mu         sync.Mutex
+	}
+
+	// queuedDownload is the persisted state of a resumable download.
+	queuedDownload struct {
+		UID              modules.DownloadID `json:"uid"`
+		SiaPath          modules.SiaPath    `json:"siapath"`
+		SiaFileUID       siafile.SiafileUID `json:"siafileuid"`
+		Destination      string             `json:"destination"`
+		Offset           uint64             `json:"offset"`
+		Length           uint64             `json:"length"`
+		DisableDiskFetch bool               `json:"disablediskfetch"`
+		StartTime        time.Time          `json:"starttime"`
+
+		// CompletedChunks are the indices of the chunks which were written to
+		// the destination. Received is the amount of data they contain.
+		CompletedChunks []uint64 `json:"completedchunks"`
+		Received        uint64   `json:"received"`
+
+		// Error and EndTime are set if the download failed. Failed downloads
+		// are not resumed automatically.
+		Error   string    `json:"error,omitempty"`
+		EndTime time.Time `json:"endtime,omitempty"`
+	}
+)
+
+// newDownloadQueue loads the download queue at the given path or creates a new
+// one if it doesn't exist yet.
+func newDownloadQueue(path string) (*downloadQueue, error) {
+	dq := &downloadQueue{
+		downloads:  make(map[modules.DownloadID]*queuedDownload),
+		staticPath: path,
+	}
+	var downloads []*queuedDownload
+	err := persist.LoadJSON(downloadQueueMetadata, &downloads, path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.AddContext(err, "failed to load download queue")
+	}
+	for _, qd := range downloads {
+		dq.downloads[qd.UID] = qd
+	}
+	return dq, nil
+}
+
+// completedChunks returns the completed chunks of the download as a set.
+func (qd *queuedDownload) completedChunks() map[uint64]struct{} {
+	chunks := make(map[uint64]struct{}, len(qd.CompletedChunks))
+	for _, chunkIndex := range qd.CompletedChunks {
+		chunks[chunkIndex] = struct{}{}
+	}
+	return chunks
+}
+
+// managedAdd adds a download to the queue, replacing a previous download with
+// the same UID.
+func (dq *downloadQueue) managedAdd(qd queuedDownload) error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	dq.downloads[qd.UID] = &qd
+	return dq.save()
+}
+
+// managedChunkCompleted records that a chunk of the download was written to
+// its destination.
+func (dq *downloadQueue) managedChunkCompleted(uid modules.DownloadID, chunkIndex, received uint64) error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	qd, exists := dq.downloads[uid]
+	if !exists {
+		return nil
+	}
+	qd.CompletedChunks = append(qd.CompletedChunks, chunkIndex)
+	qd.Received += received
+	return dq.save()
+}
+
+// managedDownload returns a copy of the queued download with the given UID.
+func (dq *downloadQueue) managedDownload(uid modules.DownloadID) (queuedDownload, bool) {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	qd, exists := dq.downloads[uid]
+	if !exists {
+		return queuedDownload{}, false
+	}
+	cpy := *qd
+	cpy.CompletedChunks = append([]uint64{}, qd.CompletedChunks...)
+	return cpy, true
+}
+
+// managedDownloads returns copies of all the queued downloads sorted by their
+// start time.
+func (dq *downloadQueue) managedDownloads() []queuedDownload {
+	dq.mu.Lock()
+	downloads := make([]queuedDownload, 0, len(dq.downloads))
+	for _, qd := range dq.downloads {
+		cpy := *qd
+		cpy.CompletedChunks = append([]uint64{}, qd.CompletedChunks...)
+		downloads = append(downloads, cpy)
+	}
+	dq.mu.Unlock()
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].StartTime.Before(downloads[j].StartTime)
+	})
+	return downloads
+}
+
+// managedFail marks a queued download as failed.
+func (dq *downloadQueue) managedFail(uid modules.DownloadID, err error) error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	qd, exists := dq.downloads[uid]
+	if !exists {
+		return nil
+	}
+	qd.Error = err.Error()
+	qd.EndTime = time.Now()
+	return dq.save()
+}
+
+// managedRemove removes a download from the queue.
+func (dq *downloadQueue) managedRemove(uid modules.DownloadID) error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+	if _, exists := dq.downloads[uid]; !exists {
+		return nil
+	}
+	delete(dq.downloads, uid)
+	return dq.save()
+}
+
+// save persists the download queue.
+func (dq *downloadQueue) save() error {
+	downloads := make([]*queuedDownload, 0, len(dq.downloads))
+	for _, qd := range dq.downloads {
+		downloads = append(downloads, qd)
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].StartTime.Before(downloads[j].StartTime)
+	})
+	return persist.SaveJSON(downloadQueueMetadata, downloads, dq.staticPath)
+}
+
+// CancelDownload cancels the download with the given UID if it is still in
+// progress.
+func (r *Renter) CancelDownload(uid modules.DownloadID) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	r.downloadHistoryMu.Lock()
+	d, exists := r.downloadHistory[uid]
+	r.downloadHistoryMu.Unlock()
+	if !exists || d.staticComplete() {
+		return errors.New("no download in progress with the given id")
+	}
+	d.managedCancel()
+	return nil
+}
+
+// ResumeDownload resumes a download from the download queue which failed.
+// Only the chunks which weren't written to the destination yet are
+// downloaded.
+func (r *Renter) ResumeDownload(uid modules.DownloadID) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	r.downloadHistoryMu.Lock()
+	d, exists := r.downloadHistory[uid]
+	r.downloadHistoryMu.Unlock()
+	if exists && !d.staticComplete() {
+		return errDownloadInProgress
+	}
+	qd, exists := r.staticDownloadQueue.managedDownload(uid)
+	if !exists {
+		return errDownloadNotResumable
+	}
+	d, err := r.managedDownload(modules.RenterDownloadParameters{
+		Async:            true,
+		Destination:      qd.Destination,
+		DisableDiskFetch: qd.DisableDiskFetch,
+		Length:           qd.Length,
+		Offset:           qd.Offset,
+		SiaPath:          qd.SiaPath,
+	}, &qd)
+	if err != nil {
+		return err
+	}
+	return d.Start()
+}
+
+// managedLoadDownloadQueue adds the failed downloads of the download queue to
+// the download history. They can be resumed using ResumeDownload.
+func (r *Renter) managedLoadDownloadQueue() {
+	for _, qd := range r.staticDownloadQueue.managedDownloads() {
+		if qd.Error != "" {
+			r.managedAddFailedDownload(qd)
+		}
+	}
+}
+
+// managedAddFailedDownload adds a failed download from the download queue to
+// the download history.
+func (r *Renter) managedAddFailedDownload(qd queuedDownload) {
+	d := &download{
+		atomicDataReceived: qd.Received,
+		completeChan:       make(chan struct{}),
+		err:                errors.New(qd.Error),
+
+		endTime:         qd.EndTime,
+		staticStartTime: qd.StartTime,
+
+		destinationString:     qd.Destination,
+		staticDestinationType: "file",
+		staticLength:          qd.Length,
+		staticOffset:          qd.Offset,
+		staticSiaPath:         qd.SiaPath,
+		staticUID:             qd.UID,
+
+		r: r,
+	}
+	d.markComplete()
+	r.downloadHistoryMu.Lock()
+	r.downloadHistory[qd.UID] = d
+	r.downloadHistoryMu.Unlock()
+}
+
+// threadedResumeDownloads resumes the downloads of the download queue which
+// were interrupted by a shutdown.
+func (r *Renter) threadedResumeDownloads() {
+	if err := r.tg.Add(); err != nil {
+		return
+	}
+	defer r.tg.Done()
+	for _, qd := range r.staticDownloadQueue.managedDownloads() {
+		if qd.Error != "" {
+			continue
+		}
+		err := r.ResumeDownload(qd.UID)
+		if err == nil {
+			continue
+		}
+		r.log.Printf("Failed to resume download %v of %v: %v", qd.UID, qd.SiaPath, err)
+		if err := r.staticDownloadQueue.managedFail(qd.UID, err); err != nil {
+			r.log.Println("Failed to update download queue:", err)
+		}
+		if qd, exists := r.staticDownloadQueue.managedDownload(qd.UID); exists {
+			r.managedAddFailedDownload(qd)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package renter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/persist"
+)
+
+// TestDownloadQueue tests the persistence of the download queue.
+func TestDownloadQueue(t *testing.T) {
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, downloadQueueFilename)
+	dq, err := newDownloadQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add two downloads and complete some chunks.
+	qd1 := queuedDownload{UID: "foo", SiaPath: modules.RandomSiaPath(), StartTime: time.Now()}
+	qd2 := queuedDownload{UID: "bar", SiaPath: modules.RandomSiaPath(), StartTime: time.Now().Add(time.Second)}
+	if err := dq.managedAdd(qd1); err != nil {
+		t.Fatal(err)
+	}
+	if err := dq.managedAdd(qd2); err != nil {
+		t.Fatal(err)
+	}
+	if err := dq.managedChunkCompleted(qd1.UID, 2, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := dq.managedChunkCompleted(qd1.UID, 0, 50); err != nil {
+		t.Fatal(err)
+	}
+	if err := dq.managedFail(qd2.UID, modules.ErrDownloadCancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload the queue.
+	dq, err = newDownloadQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloads := dq.managedDownloads()
+	if len(downloads) != 2 || downloads[0].UID != qd1.UID || downloads[1].UID != qd2.UID {
+		t.Fatal("downloads weren't persisted", downloads)
+	}
+	qd, exists := dq.managedDownload(qd1.UID)
+	if !exists {
+		t.Fatal("download doesn't exist")
+	}
+	if qd.Received != 150 || len(qd.completedChunks()) != 2 || qd.Error != "" {
+		t.Fatal("wrong download state", qd)
+	}
+	qd, _ = dq.managedDownload(qd2.UID)
+	if qd.Error != modules.ErrDownloadCancelled.Error() || qd.EndTime.IsZero() {
+		t.Fatal("download should have failed", qd)
+	}
+
+	// Remove a download.
+	if err := dq.managedRemove(qd1.UID); err != nil {
+		t.Fatal(err)
+	}
+	dq, err = newDownloadQueue(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := dq.managedDownload(qd1.UID); exists {
+		t.Fatal("download wasn't removed")
+	}
+}
+
+// TestResumeDownload tests resuming a download from the download queue which
+// doesn't have any chunks left to download.
+func TestResumeDownload(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+	r := rt.renter
+
+	// Add a single chunk file to the renter.
+	siaPath, rsc := testingFileParams()
+	err = r.staticFileSystem.NewSiaFile(siaPath, "", rsc, crypto.GenerateSiaKey(crypto.RandomCipherType()), 100, persist.DefaultDiskPermissionsTest, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := entry.UID()
+	if err := entry.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Resuming an unknown download should fail.
+	if err := r.ResumeDownload("foo"); err != errDownloadNotResumable {
+		t.Fatal("expected errDownloadNotResumable but got", err)
+	}
+
+	// Queue a failed download which already completed its only chunk.
+	dst := filepath.Join(rt.dir, "dst")
+	qd := queuedDownload{
+		UID:             "foo",
+		SiaPath:         siaPath,
+		SiaFileUID:      uid,
+		Destination:     dst,
+		Length:          100,
+		StartTime:       time.Now(),
+		CompletedChunks: []uint64{0},
+		Received:        100,
+	}
+	if err := r.staticDownloadQueue.managedAdd(qd); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.staticDownloadQueue.managedFail(qd.UID, modules.ErrDownloadCancelled); err != nil {
+		t.Fatal(err)
+	}
+	r.managedLoadDownloadQueue()
+	di, exists := r.DownloadByUID(qd.UID)
+	if !exists || !di.Completed || di.Received != qd.Received {
+		t.Fatal("failed download wasn't added to the history", di)
+	}
+
+	// Resume the download. It should complete right away and be removed from
+	// the queue.
+	if err := r.ResumeDownload(qd.UID); err != nil {
+		t.Fatal(err)
+	}
+	di, exists = r.DownloadByUID(qd.UID)
+	if !exists || !di.Completed || di.Received != qd.Length {
+		t.Fatal("download didn't complete", di)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := r.staticDownloadQueue.managedDownload(qd.UID); exists {
+		t.Fatal("download wasn't removed from the queue")
+	}
+}
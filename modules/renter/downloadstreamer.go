@@ -469,26 +469,30 @@ func (s *streamer) Seek(offset int64, whence int) (int64, error) {
 
 // Streamer creates a modules.Streamer that can be used to stream downloads from
 // the sia network.
-func (r *Renter) Streamer(siaPath modules.SiaPath, disableLocalFetch bool) (string, modules.Streamer, error) {
+func (r *Renter) Streamer(siaPath modules.SiaPath, disableLocalFetch bool) (modules.StreamInfo, modules.Streamer, error) {
 	if err := r.tg.Add(); err != nil {
-		return "", nil, err
+		return modules.StreamInfo{}, nil, err
 	}
 	defer r.tg.Done()
 
 	// Lookup the file associated with the nickname.
 	node, err := r.staticFileSystem.OpenSiaFile(siaPath)
 	if err != nil {
-		return "", nil, err
+		return modules.StreamInfo{}, nil, err
 	}
 	defer node.Close()
 
 	// Create the streamer
-	snap, err := node.Snapshot(siaPath)
+	s, err := r.managedStreamerByNode(node, siaPath, disableLocalFetch)
 	if err != nil {
-		return "", nil, err
+		return modules.StreamInfo{}, nil, err
+	}
+	info := modules.StreamInfo{
+		Name:    siaPath.String(),
+		UID:     string(node.UID()),
+		ModTime: node.ModTime(),
 	}
-	s := r.managedStreamer(snap, disableLocalFetch)
-	return siaPath.String(), s, nil
+	return info, s, nil
 }
 
 // StreamerByNode will open a streamer for the renter, taking a FileNode as
@@ -502,12 +506,30 @@ func (r *Renter) StreamerByNode(node *filesystem.FileNode, disableLocalFetch boo
 
 	// Grab the current SiaPath of the FileNode and then create a snapshot.
 	sp := r.staticFileSystem.FileSiaPath(node)
-	snap, err := node.Snapshot(sp)
+	return r.managedStreamerByNode(node, sp, disableLocalFetch)
+}
+
+// managedStreamerByNode creates a streamer for the file with the given
+// SiaPath. Packed files are streamed from their pack.
+func (r *Renter) managedStreamerByNode(node *filesystem.FileNode, siaPath modules.SiaPath, disableLocalFetch bool) (modules.Streamer, error) {
+	pack, packInfo, err := r.managedOpenPack(node)
 	if err != nil {
 		return nil, err
 	}
-	s := r.managedStreamer(snap, disableLocalFetch)
-	return s, nil
+	if pack != nil {
+		defer pack.Close()
+		snap, err := pack.Snapshot(siaPath)
+		if err != nil {
+			return nil, err
+		}
+		s := r.managedStreamer(snap, disableLocalFetch)
+		return newPackedStreamer(s, packInfo.Offset, node.Size()), nil
+	}
+	snap, err := node.Snapshot(siaPath)
+	if err != nil {
+		return nil, err
+	}
+	return r.managedStreamer(snap, disableLocalFetch), nil
 }
 
 // managedStreamer creates a streamer from a siafile snapshot and starts filling
@@ -523,5 +545,10 @@ func (r *Renter) managedStreamer(snapshot *siafile.Snapshot, disableLocalFetch b
 		targetCacheSize:         initialStreamerCacheSize,
 	}
 	go s.threadedFillCache()
+
+	// Compressed files are streamed by decompressing the stored data.
+	if snapshot.CompressionType() != modules.CompressionNone {
+		return newDecompressStreamer(s, snapshot)
+	}
 	return s
 }
@@ -1,7 +1,10 @@
 package renter
 
 import (
+	"os"
+
 	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
 
 	"gitlab.com/NebulousLabs/errors"
 )
@@ -15,11 +18,40 @@ func (r *Renter) DeleteFile(siaPath modules.SiaPath) error {
 	}
 	defer r.tg.Done()
 
+	// Remember the deduplicated chunks of the file to release their
+	// references once the file is gone.
+	dedupChunks, err := r.managedDedupChunks(siaPath)
+	if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+		return errors.AddContext(err, "unable to get deduplicated chunks of siafile")
+	}
+
+	// Remember the pack of a packed file to release it once the file is gone.
+	uid, packInfo, packed, err := r.managedPackOfFile(siaPath)
+	if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+		return errors.AddContext(err, "unable to get pack of siafile")
+	}
+
+	// Remember the compressed data of a compressed upload to remove it once
+	// the file is gone.
+	compressedSource, err := r.managedCompressedUploadSource(siaPath)
+	if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+		return errors.AddContext(err, "unable to get compressed data of siafile")
+	}
+
 	// Perform the delete operation.
 	err = r.staticFileSystem.DeleteFile(siaPath)
 	if err != nil {
 		return errors.AddContext(err, "unable to delete siafile from filesystem")
 	}
+	if compressedSource != "" {
+		if err := os.Remove(compressedSource); err != nil {
+			r.log.Println("WARN: unable to remove compressed data of deleted file:", err)
+		}
+	}
+	r.managedReleaseDedupChunks(dedupChunks)
+	if packed {
+		r.managedReleasePack(uid, packInfo)
+	}
 
 	// Update the filesystem metadata.
 	//
@@ -45,16 +77,25 @@ func (r *Renter) FileList(siaPath modules.SiaPath, recursive, cached bool) ([]mo
 	defer r.tg.Done()
 	var fis []modules.FileInfo
 	var err error
+	var offlineMap, goodForRenewMap map[string]bool
+	var contractsMap map[string]modules.RenterContract
 	if cached {
 		fis, _, err = r.staticFileSystem.CachedList(siaPath, recursive)
 	} else {
-		offlineMap, goodForRenewMap, contractsMap := r.managedContractUtilityMaps()
+		offlineMap, goodForRenewMap, contractsMap = r.managedContractUtilityMaps()
 		fis, _, err = r.staticFileSystem.List(siaPath, recursive, offlineMap, goodForRenewMap, contractsMap)
 	}
 	if err != nil {
 		return nil, err
 	}
-	return fis, err
+	packs := make(map[modules.SiaPath]modules.FileInfo)
+	for i := range fis {
+		fis[i], err = r.managedPackedFileInfo(fis[i], packs, offlineMap, goodForRenewMap, contractsMap, cached)
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to get the fileinfo of packed file")
+		}
+	}
+	return fis, nil
 }
 
 // File returns file from siaPath queried by user.
@@ -69,7 +110,7 @@ func (r *Renter) File(siaPath modules.SiaPath) (modules.FileInfo, error) {
 	if err != nil {
 		return modules.FileInfo{}, errors.AddContext(err, "unable to get the fileinfo from the filesystem")
 	}
-	return fi, nil
+	return r.managedPackedFileInfo(fi, make(map[modules.SiaPath]modules.FileInfo), offline, goodForRenew, contracts, false)
 }
 
 // FileCached returns file from siaPath queried by user, using cached values for
@@ -79,7 +120,11 @@ func (r *Renter) FileCached(siaPath modules.SiaPath) (modules.FileInfo, error) {
 		return modules.FileInfo{}, err
 	}
 	defer r.tg.Done()
-	return r.staticFileSystem.CachedFileInfo(siaPath)
+	fi, err := r.staticFileSystem.CachedFileInfo(siaPath)
+	if err != nil {
+		return modules.FileInfo{}, err
+	}
+	return r.managedPackedFileInfo(fi, make(map[modules.SiaPath]modules.FileInfo), nil, nil, nil, true)
 }
 
 // RenameFile takes an existing file and changes the nickname. The original
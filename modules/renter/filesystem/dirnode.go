@@ -136,6 +136,17 @@ func (n *DirNode) Path() (string, error) {
 	return sd.Path(), nil
 }
 
+// SetPolicy is a wrapper for SiaDir.SetPolicy.
+func (n *DirNode) SetPolicy(policy modules.DirectoryPolicy) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sd, err := n.siaDir()
+	if err != nil {
+		return err
+	}
+	return sd.SetPolicy(policy)
+}
+
 // UpdateBubbledMetadata is a wrapper for SiaDir.UpdateBubbledMetadata.
 func (n *DirNode) UpdateBubbledMetadata(md siadir.Metadata) error {
 	n.mu.Lock()
@@ -611,6 +622,7 @@ func (n *DirNode) managedInfo(siaPath modules.SiaPath) (modules.DirectoryInfo, e
 		NumFiles:            metadata.NumFiles,
 		NumStuckChunks:      metadata.NumStuckChunks,
 		NumSubDirs:          metadata.NumSubDirs,
+		Policy:              metadata.Policy,
 		DirSize:             metadata.Size,
 		StuckHealth:         metadata.StuckHealth,
 		SiaPath:             siaPath,
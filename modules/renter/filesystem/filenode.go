@@ -94,14 +94,17 @@ func (n *FileNode) managedFileInfo(siaPath modules.SiaPath, offline map[string]b
 		return modules.FileInfo{}, errors.AddContext(err, "failed to get upload progress and bytes")
 	}
 	maxHealth := math.Max(health, stuckHealth)
+	_, packed := n.PackedFile()
 	fileInfo := modules.FileInfo{
 		AccessTime:       n.AccessTime(),
 		Available:        redundancy >= 1,
 		ChangeTime:       n.ChangeTime(),
 		CipherType:       n.MasterKey().Type().String(),
+		Compression:      n.CompressionType().String(),
 		CreateTime:       n.CreateTime(),
+		Deduplicate:      n.Deduplicate(),
 		Expiration:       n.Expiration(contracts),
-		Filesize:         n.Size(),
+		Filesize:         n.UncompressedSize(),
 		Health:           health,
 		LocalPath:        localPath,
 		MaxHealth:        maxHealth,
@@ -109,11 +112,13 @@ func (n *FileNode) managedFileInfo(siaPath modules.SiaPath, offline map[string]b
 		ModificationTime: n.ModTime(),
 		NumStuckChunks:   numStuckChunks,
 		OnDisk:           onDisk,
+		Packed:           packed,
 		Recoverable:      onDisk || redundancy >= 1,
 		Redundancy:       redundancy,
 		Renewing:         true,
 		Publinks:         n.Metadata().Publinks,
 		SiaPath:          siaPath,
+		StoredSize:       n.Size(),
 		Stuck:            numStuckChunks > 0,
 		StuckHealth:      stuckHealth,
 		UID:              n.staticUID,
@@ -123,6 +128,53 @@ func (n *FileNode) managedFileInfo(siaPath modules.SiaPath, offline map[string]b
 	return fileInfo, nil
 }
 
+// managedReplace renames the fNode's underlying file, replacing the file with
+// the same name within the new parent. Open instances of the replaced file are
+// marked as deleted.
+func (n *FileNode) managedReplace(newName string, oldParent, newParent *DirNode) error {
+	// Lock the parents. If they are the same, only lock one.
+	if oldParent.staticUID == newParent.staticUID {
+		oldParent.node.mu.Lock()
+		defer oldParent.node.mu.Unlock()
+	} else {
+		oldParent.node.mu.Lock()
+		defer oldParent.node.mu.Unlock()
+		newParent.node.mu.Lock()
+		defer newParent.node.mu.Unlock()
+	}
+	n.node.mu.Lock()
+	defer n.node.mu.Unlock()
+	// Check that newParent doesn't have a folder with that name.
+	if _, exists := newParent.directories[newName]; exists {
+		return ErrExists
+	}
+	if fi, err := os.Stat(filepath.Join(newParent.absPath(), newName)); err == nil && fi.IsDir() {
+		return ErrExists
+	}
+	newPath := filepath.Join(newParent.absPath(), newName) + modules.SiaFileExtension
+	// Replace the file.
+	err := n.SiaFile.Replace(newPath)
+	if err != nil {
+		return err
+	}
+	// Mark the replaced file as deleted if it is open.
+	if replaced, exists := newParent.files[newName]; exists && replaced.SiaFile != n.SiaFile {
+		replaced.node.mu.Lock()
+		replaced.UnmanagedSetDeleted(true)
+		newParent.removeFile(replaced)
+		replaced.node.mu.Unlock()
+	}
+	// Remove file from old parent and add it to new parent.
+	oldParent.removeFile(n)
+	// Update parent and name.
+	n.parent = newParent
+	*n.name = newName
+	*n.path = newPath
+	// Add file to new parent.
+	n.parent.files[*n.name] = n
+	return nil
+}
+
 // managedRename renames the fNode's underlying file.
 func (n *FileNode) managedRename(newName string, oldParent, newParent *DirNode) error {
 	// Lock the parents. If they are the same, only lock one.
@@ -177,14 +229,20 @@ func (n *FileNode) staticCachedInfo(siaPath modules.SiaPath) (modules.FileInfo,
 		onDisk = err == nil
 	}
 	maxHealth := math.Max(md.CachedHealth, md.CachedStuckHealth)
+	filesize := uint64(md.FileSize)
+	if md.CompressionType != modules.CompressionNone {
+		filesize = uint64(md.UncompressedSize)
+	}
 	fileInfo := modules.FileInfo{
 		AccessTime:       md.AccessTime,
 		Available:        md.CachedUserRedundancy >= 1,
 		ChangeTime:       md.ChangeTime,
 		CipherType:       md.StaticMasterKeyType.String(),
+		Compression:      md.CompressionType.String(),
 		CreateTime:       md.CreateTime,
+		Deduplicate:      md.Deduplicate,
 		Expiration:       md.CachedExpiration,
-		Filesize:         uint64(md.FileSize),
+		Filesize:         filesize,
 		Health:           md.CachedHealth,
 		LocalPath:        localPath,
 		MaxHealth:        maxHealth,
@@ -192,11 +250,13 @@ func (n *FileNode) staticCachedInfo(siaPath modules.SiaPath) (modules.FileInfo,
 		ModificationTime: md.ModTime,
 		NumStuckChunks:   md.NumStuckChunks,
 		OnDisk:           onDisk,
+		Packed:           !md.PackedFile.Pack.IsRoot(),
 		Recoverable:      onDisk || md.CachedUserRedundancy >= 1,
 		Redundancy:       md.CachedUserRedundancy,
 		Renewing:         true,
 		Publinks:         md.Publinks,
 		SiaPath:          siaPath,
+		StoredSize:       uint64(md.FileSize),
 		Stuck:            md.NumStuckChunks > 0,
 		StuckHealth:      md.CachedStuckHealth,
 		UID:              n.staticUID,
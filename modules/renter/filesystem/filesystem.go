@@ -461,6 +461,46 @@ func (fs *FileSystem) RenameFile(oldSiaPath, newSiaPath modules.SiaPath) error {
 	return sf.managedRename(newSiaPath.Name(), oldDir, newDir)
 }
 
+// ReplaceFile renames the file with srcSiaPath to dstSiaPath, replacing the
+// file at dstSiaPath atomically.
+func (fs *FileSystem) ReplaceFile(srcSiaPath, dstSiaPath modules.SiaPath) error {
+	// Open SiaDir for file at src location.
+	srcDirSiaPath, err := srcSiaPath.Dir()
+	if err != nil {
+		return err
+	}
+	srcDir, err := fs.managedOpenSiaDir(srcDirSiaPath)
+	if err != nil {
+		return err
+	}
+	defer srcDir.Close()
+	// Open the file.
+	sf, err := srcDir.managedOpenFile(srcSiaPath.Name())
+	if err == ErrNotExist {
+		return ErrNotExist
+	}
+	if err != nil {
+		return errors.AddContext(err, "failed to open file for replacing")
+	}
+	defer sf.Close()
+
+	// Create and Open SiaDir for file at dst location.
+	dstDirSiaPath, err := dstSiaPath.Dir()
+	if err != nil {
+		return err
+	}
+	if err := fs.NewSiaDir(dstDirSiaPath, sf.managedMode()); err != nil {
+		return errors.AddContext(err, fmt.Sprintf("failed to create SiaDir %v for SiaFile %v", dstDirSiaPath.String(), srcSiaPath.String()))
+	}
+	dstDir, err := fs.managedOpenSiaDir(dstDirSiaPath)
+	if err != nil {
+		return err
+	}
+	defer dstDir.Close()
+	// Replace the file.
+	return sf.managedReplace(dstSiaPath.Name(), srcDir, dstDir)
+}
+
 // RenameDir takes an existing directory and changes the path. The original
 // directory must exist, and there must not be any directory that already has
 // the replacement path.  All sia files within directory will also be renamed
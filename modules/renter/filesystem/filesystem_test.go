@@ -706,6 +706,58 @@ func TestRenameFile(t *testing.T) {
 	sf.Close()
 }
 
+// TestReplaceFile tests if replacing a file works as expected.
+func TestReplaceFile(t *testing.T) {
+	if testing.Short() && !build.VLONG {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// Create filesystem.
+	root := filepath.Join(testDir(t.Name()), "fs-root")
+	fs := newTestFileSystem(root)
+	// Add two files to the root dir.
+	foo := newSiaPath("foo")
+	bar := newSiaPath("bar")
+	fs.addTestSiaFile(foo)
+	fs.addTestSiaFile(bar)
+	// Remember the UID of foo and keep bar open.
+	sf, err := fs.OpenSiaFile(foo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid := sf.UID()
+	sf.Close()
+	sfBar, err := fs.OpenSiaFile(bar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sfBar.Close()
+	// Replace bar with foo.
+	if err := fs.ReplaceFile(foo, bar); err != nil {
+		t.Fatal(err)
+	}
+	// foo should be gone and bar should be the former foo.
+	if _, err := fs.OpenSiaFile(foo); err != ErrNotExist {
+		t.Fatal("expected ErrNotExist but got:", err)
+	}
+	sf, err = fs.OpenSiaFile(bar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.UID() != uid {
+		t.Fatal("bar wasn't replaced")
+	}
+	sf.Close()
+	// The open instance of the replaced file should be deleted.
+	if !sfBar.Deleted() {
+		t.Fatal("replaced file should be marked as deleted")
+	}
+	// Replacing a file with a non-existent file should fail.
+	if err := fs.ReplaceFile(foo, bar); err != ErrNotExist {
+		t.Fatal("expected ErrNotExist but got:", err)
+	}
+}
+
 // TestThreadedAccess tests rapidly opening and closing files and directories
 // from multiple threads to check the locking conventions.
 func TestThreadedAccess(t *testing.T) {
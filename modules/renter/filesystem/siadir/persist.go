@@ -305,6 +305,19 @@ func (sd *SiaDir) SetPath(targetPath string) error {
 	return nil
 }
 
+// SetPolicy sets the redundancy and host-set policy of the SiaDir and saves
+// the change to disk.
+func (sd *SiaDir) SetPolicy(policy modules.DirectoryPolicy) error {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if err := policy.Validate(); err != nil {
+		return errors.AddContext(err, "invalid directory policy")
+	}
+	md := sd.metadata
+	md.Policy = policy
+	return sd.updateMetadata(md)
+}
+
 // UpdateBubbledMetadata updates the SiaDir Metadata that is bubbled and saves
 // the changes to disk. For fields that are not bubbled, this method sets them
 // to the current values in the SiaDir metadata
@@ -312,6 +325,7 @@ func (sd *SiaDir) UpdateBubbledMetadata(metadata Metadata) error {
 	sd.mu.Lock()
 	defer sd.mu.Unlock()
 	metadata.Mode = sd.metadata.Mode
+	metadata.Policy = sd.metadata.Policy
 	metadata.Version = sd.metadata.Version
 	return sd.updateMetadata(metadata)
 }
@@ -365,6 +379,7 @@ func (sd *SiaDir) updateMetadata(metadata Metadata) error {
 	sd.metadata.Size = metadata.Size
 	sd.metadata.StuckHealth = metadata.StuckHealth
 
+	sd.metadata.Policy = metadata.Policy
 	sd.metadata.Version = metadata.Version
 
 	// Testing check to ensure new fields aren't missed
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"gitlab.com/NebulousLabs/fastrand"
@@ -81,6 +82,9 @@ func equalMetadatas(md, md2 Metadata) error {
 	if md.StuckHealth != md2.StuckHealth {
 		return fmt.Errorf("StuckHealths not equal, %v and %v", md.StuckHealth, md2.StuckHealth)
 	}
+	if !reflect.DeepEqual(md.Policy, md2.Policy) {
+		return fmt.Errorf("Policies not equal, %v and %v", md.Policy, md2.Policy)
+	}
 
 	return nil
 }
@@ -86,6 +86,10 @@ type (
 		Size                uint64      `json:"size"`
 		StuckHealth         float64     `json:"stuckhealth"`
 
+		// Policy is the redundancy and host-set policy of the siadir. It is
+		// set by the user and not bubbled.
+		Policy modules.DirectoryPolicy `json:"policy"`
+
 		// Version is the used version of the header file.
 		Version string `json:"version"`
 	}
@@ -3,13 +3,16 @@ package siadir
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
 	"gitlab.com/NebulousLabs/errors"
 	"gitlab.com/NebulousLabs/fastrand"
 
+	"gitlab.com/scpcorp/ScPrime/crypto"
 	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
 )
 
 // checkMetadataInit is a helper that verifies that the metadata was initialized
@@ -258,6 +261,68 @@ func TestUpdateMetadata(t *testing.T) {
 	}
 }
 
+// TestSetPolicy probes the SetPolicy method
+func TestSetPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create new siaDir
+	rootDir, err := newRootDir(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	siaPath, err := modules.NewSiaPath("TestDir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	siaDirSysPath := siaPath.SiaDirSysPath(rootDir)
+	wal, _ := newTestWAL()
+	siaDir, err := New(siaDirSysPath, rootDir, modules.DefaultDirPerm, wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if siaDir.Metadata().Policy.IsSet() {
+		t.Fatal("new siadir shouldn't have a policy")
+	}
+
+	// An invalid policy should be rejected.
+	err = siaDir.SetPolicy(modules.DirectoryPolicy{ParityPieces: 10})
+	if err == nil {
+		t.Fatal("invalid policy should be rejected")
+	}
+
+	// Set a valid policy.
+	policy := modules.DirectoryPolicy{
+		DataPieces:      2,
+		ParityPieces:    4,
+		RepairThreshold: 0.1,
+		ExcludedHosts:   []types.SiaPublicKey{types.Ed25519PublicKey(crypto.PublicKey{1})},
+	}
+	if err := siaDir.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// The policy should survive bubbling.
+	md := randomMetadata()
+	if err := siaDir.UpdateBubbledMetadata(md); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(siaDir.Metadata().Policy, policy) {
+		t.Fatal("policy was changed by bubble", siaDir.Metadata().Policy)
+	}
+
+	// The policy should be persisted.
+	siaDir, err = LoadSiaDir(siaDirSysPath, modules.ProdDependencies, wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(siaDir.Metadata().Policy, policy) {
+		t.Fatal("policy wasn't persisted", siaDir.Metadata().Policy)
+	}
+}
+
 // TestSiaDirDelete verifies the SiaDir performs as expected after a delete
 func TestSiaDirDelete(t *testing.T) {
 	if testing.Short() {
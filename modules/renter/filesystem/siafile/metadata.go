@@ -31,6 +31,20 @@ type (
 		Status uint8                   `json:"status"` // Status of combined chunk
 	}
 
+	// CompressedChunkInfo describes where the compressed data of a logical
+	// chunk of a compressed SiaFile is located within the stored data.
+	CompressedChunkInfo struct {
+		Offset uint64 `json:"offset"` // Offset of the compressed chunk within the stored data
+		Length uint64 `json:"length"` // Length of the compressed chunk
+	}
+
+	// PackedFileInfo describes where the data of a packed SiaFile is located
+	// within the pack it was packed into.
+	PackedFileInfo struct {
+		Pack   modules.SiaPath `json:"pack"`   // SiaPath of the pack
+		Offset uint64          `json:"offset"` // Offset of the file's data within the pack
+	}
+
 	// SiafileUID is a unique identifier for siafile which is used to track
 	// siafiles even after renaming them.
 	SiafileUID string
@@ -60,6 +74,29 @@ type (
 		PartialChunks       []PartialChunkInfo `json:"partialchunks"`       // information about the partial chunk.
 		HasPartialChunk     bool               `json:"haspartialchunk"`     // indicates whether this file is supposed to have a partial chunk or not
 
+		// Fields for compression. If a file is compressed, every logical chunk
+		// of ChunkSize bytes is compressed on its own and the compressed chunks
+		// are concatenated to form the data which is actually erasure coded,
+		// encrypted and uploaded. CompressedChunks is the offset index into
+		// that data which allows for random access to the logical data.
+		CompressionType  modules.CompressionType `json:"compressiontype"`  // codec used to compress the chunks
+		CompressedChunks []CompressedChunkInfo   `json:"compressedchunks"` // offset index of the compressed chunks
+		UncompressedSize int64                   `json:"uncompressedsize"` // logical size of a compressed file
+
+		// Fields for deduplication. New chunks of a deduplicated file are
+		// encrypted with a convergent key derived from their content instead
+		// of the masterkey. That way identical chunks of different files result
+		// in identical pieces which can be shared on the hosts. ConvergentKeys
+		// contains the seed of the convergent key of every chunk or the zero
+		// hash for chunks which are encrypted with the masterkey.
+		Deduplicate    bool          `json:"deduplicate"`    // determines whether new chunks use convergent encryption
+		ConvergentKeys []crypto.Hash `json:"convergentkeys"` // seeds of the convergent keys of the chunks
+
+		// Fields for packing. The data of a packed file is stored within a
+		// pack, a SiaFile which contains the data of many small files. A
+		// packed file doesn't have any chunks of its own.
+		PackedFile PackedFileInfo `json:"packedfile"` // location of the file's data within its pack
+
 		// The following fields are the usual unix timestamps of files.
 		ModTime    time.Time `json:"modtime"`    // time of last content modification
 		ChangeTime time.Time `json:"changetime"` // time of last metadata modification
@@ -203,6 +240,28 @@ func (sf *SiaFile) PartialChunks() []PartialChunkInfo {
 	return sf.staticMetadata.PartialChunks
 }
 
+// CompressedChunks returns the offset index of a compressed file's chunks.
+func (sf *SiaFile) CompressedChunks() []CompressedChunkInfo {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.staticMetadata.CompressedChunks
+}
+
+// CompressionType returns the codec used to compress the file's chunks.
+func (sf *SiaFile) CompressionType() modules.CompressionType {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.staticMetadata.CompressionType
+}
+
+// ConvergentKey returns the seed of the convergent key of a chunk. The bool
+// is false if the chunk is encrypted with the masterkey.
+func (sf *SiaFile) ConvergentKey(chunkIndex uint64) (crypto.Hash, bool) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return convergentKeySeed(sf.staticMetadata.ConvergentKeys, chunkIndex)
+}
+
 // CreateTime returns the CreateTime timestamp of the file.
 func (sf *SiaFile) CreateTime() time.Time {
 	sf.mu.RLock()
@@ -215,6 +274,14 @@ func (sf *SiaFile) ChunkSize() uint64 {
 	return sf.staticChunkSize()
 }
 
+// Deduplicate returns whether new chunks of the file are encrypted with
+// convergent keys.
+func (sf *SiaFile) Deduplicate() bool {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.staticMetadata.Deduplicate
+}
+
 // HasPartialChunk returns whether this file is supposed to have a partial chunk
 // or not.
 func (sf *SiaFile) HasPartialChunk() bool {
@@ -242,6 +309,15 @@ func (sf *SiaFile) MasterKey() crypto.CipherKey {
 	return sf.staticMasterKey()
 }
 
+// PieceKey returns the key used to encrypt the piece at pieceIndex of the
+// chunk at chunkIndex.
+func (sf *SiaFile) PieceKey(chunkIndex, pieceIndex uint64) crypto.CipherKey {
+	sf.mu.RLock()
+	seed, ok := convergentKeySeed(sf.staticMetadata.ConvergentKeys, chunkIndex)
+	sf.mu.RUnlock()
+	return pieceKey(sf.staticMasterKey(), seed, ok, chunkIndex, pieceIndex)
+}
+
 // Metadata returns the SiaFile's metadata, resolving any fields related to
 // partial chunks.
 func (sf *SiaFile) Metadata() Metadata {
@@ -274,6 +350,14 @@ func (sf *SiaFile) NumStuckChunks() uint64 {
 	return sf.numStuckChunks()
 }
 
+// PackedFile returns the location of the file's data within its pack and
+// whether the file is packed at all.
+func (sf *SiaFile) PackedFile() (PackedFileInfo, bool) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	return sf.staticMetadata.PackedFile, sf.isPacked()
+}
+
 // PieceSize returns the size of a single piece of the file.
 func (sf *SiaFile) PieceSize() uint64 {
 	return sf.staticMetadata.StaticPieceSize
@@ -285,7 +369,16 @@ func (sf *SiaFile) PieceSize() uint64 {
 func (sf *SiaFile) Rename(newSiaFilePath string) error {
 	sf.mu.Lock()
 	defer sf.mu.Unlock()
-	return sf.rename(newSiaFilePath)
+	return sf.rename(newSiaFilePath, false)
+}
+
+// Replace changes the name of the file to a new one, replacing the file that
+// currently exists at the new location. The replaced file is deleted within
+// the same wal transaction which makes the replacement atomic.
+func (sf *SiaFile) Replace(newSiaFilePath string) error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return sf.rename(newSiaFilePath, true)
 }
 
 // backup creates a deep-copy of a Metadata.
@@ -311,6 +404,10 @@ func (md Metadata) backup() (b Metadata) {
 	b.LocalPath = md.LocalPath
 	b.DisablePartialChunk = md.DisablePartialChunk
 	b.HasPartialChunk = md.HasPartialChunk
+	b.CompressionType = md.CompressionType
+	b.UncompressedSize = md.UncompressedSize
+	b.Deduplicate = md.Deduplicate
+	b.PackedFile = md.PackedFile
 	b.ModTime = md.ModTime
 	b.ChangeTime = md.ChangeTime
 	b.AccessTime = md.AccessTime
@@ -341,6 +438,18 @@ func (md Metadata) backup() (b Metadata) {
 		b.PartialChunks = make([]PartialChunkInfo, len(md.PartialChunks), cap(md.PartialChunks))
 		copy(b.PartialChunks, md.PartialChunks)
 	}
+	if md.CompressedChunks == nil {
+		b.CompressedChunks = nil
+	} else {
+		b.CompressedChunks = make([]CompressedChunkInfo, len(md.CompressedChunks), cap(md.CompressedChunks))
+		copy(b.CompressedChunks, md.CompressedChunks)
+	}
+	if md.ConvergentKeys == nil {
+		b.ConvergentKeys = nil
+	} else {
+		b.ConvergentKeys = make([]crypto.Hash, len(md.ConvergentKeys), cap(md.ConvergentKeys))
+		copy(b.ConvergentKeys, md.ConvergentKeys)
+	}
 	if md.Publinks == nil {
 		b.Publinks = nil
 	} else {
@@ -365,6 +474,12 @@ func (md *Metadata) restore(b Metadata) {
 	md.DisablePartialChunk = b.DisablePartialChunk
 	md.PartialChunks = b.PartialChunks
 	md.HasPartialChunk = b.HasPartialChunk
+	md.CompressionType = b.CompressionType
+	md.CompressedChunks = b.CompressedChunks
+	md.UncompressedSize = b.UncompressedSize
+	md.Deduplicate = b.Deduplicate
+	md.ConvergentKeys = b.ConvergentKeys
+	md.PackedFile = b.PackedFile
 	md.ModTime = b.ModTime
 	md.ChangeTime = b.ChangeTime
 	md.AccessTime = b.AccessTime
@@ -417,7 +532,7 @@ func (md *Metadata) equals(b Metadata) bool {
 // rename changes the name of the file to a new one. To guarantee that renaming
 // the file is atomic across all operating systems, we create a wal transaction
 // that moves over all the chunks one-by-one and deletes the src file.
-func (sf *SiaFile) rename(newSiaFilePath string) (err error) {
+func (sf *SiaFile) rename(newSiaFilePath string, replace bool) (err error) {
 	if sf.deleted {
 		return errors.New("can't rename deleted siafile")
 	}
@@ -431,7 +546,9 @@ func (sf *SiaFile) rename(newSiaFilePath string) (err error) {
 		}
 	}(sf.staticMetadata.backup())
 	// Check if file exists at new location.
-	if _, err := os.Stat(newSiaFilePath); err == nil {
+	_, err = os.Stat(newSiaFilePath)
+	exists := err == nil
+	if exists && !replace {
 		return ErrPathOverload
 	}
 	// Create path to renamed location.
@@ -442,6 +559,10 @@ func (sf *SiaFile) rename(newSiaFilePath string) (err error) {
 	}
 	// Create the delete update before changing the path to the new one.
 	updates := []writeaheadlog.Update{sf.createDeleteUpdate()}
+	// Delete the replaced file before writing the renamed one.
+	if exists {
+		updates = append(updates, createDeleteUpdate(newSiaFilePath))
+	}
 	// Load all the chunks.
 	chunks := make([]chunk, 0, sf.numChunks)
 	err = sf.iterateChunksReadonly(func(chunk chunk) error {
@@ -472,6 +593,121 @@ func (sf *SiaFile) rename(newSiaFilePath string) (err error) {
 	return createAndApplyTransaction(sf.wal, updates...)
 }
 
+// SetCompression sets the codec used to compress the file's chunks. It can
+// only be called on files which don't contain any data yet.
+func (sf *SiaFile) SetCompression(ct modules.CompressionType) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if !modules.IsValidCompressionType(ct) {
+		return modules.ErrInvalidCompressionType
+	}
+	if sf.staticMetadata.FileSize != 0 {
+		return errors.New("can't change the compression of a file that contains data")
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+	sf.staticMetadata.CompressionType = ct
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
+// SetCompressedChunks sets the offset index of a compressed file's chunks
+// together with the logical size of the file.
+func (sf *SiaFile) SetCompressedChunks(ccs []CompressedChunkInfo, uncompressedSize uint64) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.staticMetadata.CompressionType == modules.CompressionNone {
+		return errors.New("can't set compressed chunks of an uncompressed file")
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+	sf.staticMetadata.CompressedChunks = append([]CompressedChunkInfo{}, ccs...)
+	sf.staticMetadata.UncompressedSize = int64(uncompressedSize)
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
+// SetConvergentKey sets the seed of the convergent key of a chunk. The key of
+// a chunk can't be changed once it is set.
+func (sf *SiaFile) SetConvergentKey(chunkIndex uint64, seed crypto.Hash) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if seed == (crypto.Hash{}) {
+		return errors.New("convergent key seed can't be empty")
+	}
+	if chunkIndex >= uint64(sf.numChunks) {
+		return fmt.Errorf("chunk index %v out of bounds (%v)", chunkIndex, sf.numChunks)
+	}
+	if oldSeed, ok := convergentKeySeed(sf.staticMetadata.ConvergentKeys, chunkIndex); ok {
+		if oldSeed != seed {
+			return errors.New("chunk already uses a different convergent key")
+		}
+		return nil
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+	keys := append([]crypto.Hash{}, sf.staticMetadata.ConvergentKeys...)
+	for uint64(len(keys)) <= chunkIndex {
+		keys = append(keys, crypto.Hash{})
+	}
+	keys[chunkIndex] = seed
+	sf.staticMetadata.ConvergentKeys = keys
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
+// SetDeduplicate sets whether new chunks of the file are encrypted with
+// convergent keys. Chunks which already use a convergent key keep it.
+func (sf *SiaFile) SetDeduplicate(deduplicate bool) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+	sf.staticMetadata.Deduplicate = deduplicate
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	return sf.createAndApplyTransaction(updates...)
+}
+
 // SetMode sets the filemode of the sia file.
 func (sf *SiaFile) SetMode(mode os.FileMode) (err error) {
 	sf.mu.Lock()
@@ -494,6 +730,46 @@ func (sf *SiaFile) SetMode(mode os.FileMode) (err error) {
 	return sf.createAndApplyTransaction(updates...)
 }
 
+// SetPackedFile turns an empty SiaFile into a packed file of the given size
+// whose data is located within a pack.
+func (sf *SiaFile) SetPackedFile(info PackedFileInfo, fileSize uint64) (err error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.deleted {
+		return errors.AddContext(ErrDeleted, "can't pack deleted file")
+	}
+	if info.Pack.IsRoot() {
+		return errors.New("pack of a packed file can't be the root")
+	}
+	if sf.isPacked() || sf.staticMetadata.FileSize != 0 {
+		return errors.New("only empty files can be packed")
+	}
+	if sf.staticMetadata.CompressionType != modules.CompressionNone {
+		return errors.New("compressed files can't be packed")
+	}
+	// backup the changed metadata before changing it. Revert the change on
+	// error.
+	defer func(backup Metadata) {
+		if err != nil {
+			sf.staticMetadata.restore(backup)
+		}
+	}(sf.staticMetadata.backup())
+	sf.staticMetadata.PackedFile = info
+	sf.staticMetadata.FileSize = int64(fileSize)
+
+	// Save changes to metadata to disk.
+	updates, err := sf.saveMetadataUpdates()
+	if err != nil {
+		return err
+	}
+	if err := sf.createAndApplyTransaction(updates...); err != nil {
+		return err
+	}
+	// The data of a packed file is stored in its pack.
+	sf.numChunks = 0
+	return nil
+}
+
 // SetLastHealthCheckTime sets the LastHealthCheckTime in memory to the current
 // time but does not update and write to disk.
 //
@@ -535,6 +811,17 @@ func (sf *SiaFile) Size() uint64 {
 	return uint64(sf.staticMetadata.FileSize)
 }
 
+// UncompressedSize returns the logical size of the file. For uncompressed
+// files that is the same as Size.
+func (sf *SiaFile) UncompressedSize() uint64 {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if sf.staticMetadata.CompressionType == modules.CompressionNone {
+		return uint64(sf.staticMetadata.FileSize)
+	}
+	return uint64(sf.staticMetadata.UncompressedSize)
+}
+
 // UpdateUniqueID creates a new random uid for the SiaFile.
 func (sf *SiaFile) UpdateUniqueID() {
 	sf.staticMetadata.UniqueID = uniqueID()
@@ -561,6 +848,11 @@ func (sf *SiaFile) UpdateAccessTime() (err error) {
 	return sf.createAndApplyTransaction(updates...)
 }
 
+// isPacked returns whether the file's data is located within a pack.
+func (sf *SiaFile) isPacked() bool {
+	return !sf.staticMetadata.PackedFile.Pack.IsRoot()
+}
+
 // numStuckChunks returns the number of stuck chunks recorded in the file's
 // metadata.
 func (sf *SiaFile) numStuckChunks() uint64 {
@@ -594,6 +886,38 @@ func (sf *SiaFile) staticMasterKey() crypto.CipherKey {
 	return sk
 }
 
+// convergentKeySeed returns the seed of the convergent key of a chunk from the
+// provided seeds.
+func convergentKeySeed(seeds []crypto.Hash, chunkIndex uint64) (crypto.Hash, bool) {
+	if chunkIndex >= uint64(len(seeds)) || seeds[chunkIndex] == (crypto.Hash{}) {
+		return crypto.Hash{}, false
+	}
+	return seeds[chunkIndex], true
+}
+
+// pieceKey derives the key of a piece. Pieces of chunks without a convergent
+// key are encrypted with a key derived from the masterkey. Otherwise the key
+// is derived from the convergent key independently of the chunk's index to
+// make sure that the same data always results in the same pieces.
+func pieceKey(mk crypto.CipherKey, seed crypto.Hash, convergent bool, chunkIndex, pieceIndex uint64) crypto.CipherKey {
+	if !convergent {
+		return mk.Derive(chunkIndex, pieceIndex)
+	}
+	// Expand the seed to the entropy size of the masterkey's cipher.
+	entropy := make([]byte, 0, len(mk.Key())+crypto.HashSize)
+	for i := uint64(0); len(entropy) < len(mk.Key()); i++ {
+		h := crypto.HashAll(seed, i)
+		entropy = append(entropy, h[:]...)
+	}
+	ck, err := crypto.NewSiaKey(mk.Type(), entropy[:len(mk.Key())])
+	if err != nil {
+		// This should never happen since the entropy has the same size as
+		// the entropy of a valid masterkey of the same type.
+		panic(errors.AddContext(err, "failed to create convergent key of siafile"))
+	}
+	return ck.Derive(0, pieceIndex)
+}
+
 // uniqueID creates a random unique SiafileUID.
 func uniqueID() SiafileUID {
 	return SiafileUID(persist.UID())
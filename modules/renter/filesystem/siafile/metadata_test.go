@@ -1,6 +1,7 @@
 package siafile
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,7 @@ import (
 	"gitlab.com/NebulousLabs/errors"
 	"gitlab.com/NebulousLabs/fastrand"
 
+	"gitlab.com/scpcorp/ScPrime/crypto"
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/types"
 	"gitlab.com/scpcorp/writeaheadlog"
@@ -122,6 +124,17 @@ func TestBackupRestoreMetadata(t *testing.T) {
 		if fastrand.Intn(2) == 0 { // 50% chance to be not nil
 			sf.staticMetadata.PartialChunks = make([]PartialChunkInfo, fastrand.Intn(10))
 		}
+		sf.staticMetadata.CompressionType = modules.CompressionDeflate
+		sf.staticMetadata.CompressedChunks = nil
+		if fastrand.Intn(2) == 0 { // 50% chance to be not nil
+			sf.staticMetadata.CompressedChunks = make([]CompressedChunkInfo, fastrand.Intn(10))
+		}
+		sf.staticMetadata.UncompressedSize = int64(fastrand.Intn(100))
+		sf.staticMetadata.Deduplicate = !sf.staticMetadata.Deduplicate
+		sf.staticMetadata.ConvergentKeys = nil
+		if fastrand.Intn(2) == 0 { // 50% chance to be not nil
+			sf.staticMetadata.ConvergentKeys = make([]crypto.Hash, fastrand.Intn(10))
+		}
 		sf.staticMetadata.ModTime = time.Now()
 		sf.staticMetadata.ChangeTime = time.Now()
 		sf.staticMetadata.AccessTime = time.Now()
@@ -156,3 +169,209 @@ func TestBackupRestoreMetadata(t *testing.T) {
 		t.Fatalf("metadata wasn't restored successfully %v %v", mdBefore, sf.staticMetadata)
 	}
 }
+
+// TestCompressionMetadata tests setting the compression related fields of the
+// metadata and that they are persisted.
+func TestCompressionMetadata(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create an empty file.
+	siaFilePath, _, source, rc, sk, _, _, fileMode := newTestFileParams(1, false)
+	sf, wal, _ := customTestFileAndWAL(siaFilePath, source, rc, sk, 0, 0, fileMode)
+
+	// Uncompressed files report their size as the uncompressed size and can't
+	// have compressed chunks.
+	if sf.CompressionType() != modules.CompressionNone {
+		t.Fatal("new file shouldn't be compressed")
+	}
+	if err := sf.SetCompressedChunks([]CompressedChunkInfo{{}}, 1); err == nil {
+		t.Fatal("shouldn't be able to set compressed chunks of uncompressed file")
+	}
+	if err := sf.SetCompression(modules.CompressionType{1, 2, 3}); !errors.Contains(err, modules.ErrInvalidCompressionType) {
+		t.Fatal("expected ErrInvalidCompressionType but got", err)
+	}
+
+	// Set the compression and the chunks.
+	if err := sf.SetCompression(modules.CompressionDeflate); err != nil {
+		t.Fatal(err)
+	}
+	ccs := []CompressedChunkInfo{{Offset: 0, Length: 10}, {Offset: 10, Length: 20}}
+	if err := sf.SetCompressedChunks(ccs, 12345); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload the file and check the fields.
+	sf, err := LoadSiaFile(sf.siaFilePath, wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.CompressionType() != modules.CompressionDeflate {
+		t.Fatal("wrong compression type", sf.CompressionType())
+	}
+	if !reflect.DeepEqual(sf.CompressedChunks(), ccs) {
+		t.Fatal("compressed chunks don't match", sf.CompressedChunks())
+	}
+	if sf.UncompressedSize() != 12345 {
+		t.Fatal("wrong uncompressed size", sf.UncompressedSize())
+	}
+
+	// The snapshot should contain the same information.
+	snap, err := sf.Snapshot(modules.RandomSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.CompressionType() != modules.CompressionDeflate || snap.UncompressedSize() != 12345 || !reflect.DeepEqual(snap.CompressedChunks(), ccs) {
+		t.Fatal("snapshot doesn't match file")
+	}
+
+	// Once the file contains data, the compression can't be changed anymore.
+	if err := sf.SetFileSize(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.SetCompression(modules.CompressionNone); err == nil {
+		t.Fatal("shouldn't be able to change compression of file with data")
+	}
+}
+
+// TestConvergentKeys tests setting the convergent keys of a file's chunks and
+// deriving the piece keys from them.
+func TestConvergentKeys(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	siaFilePath, _, source, rc, sk, fileSize, numChunks, fileMode := newTestFileParams(2, false)
+	sf, wal, _ := customTestFileAndWAL(siaFilePath, source, rc, sk, fileSize, numChunks, fileMode)
+
+	// New files aren't deduplicated and chunks use the masterkey.
+	if sf.Deduplicate() {
+		t.Fatal("new file shouldn't be deduplicated")
+	}
+	if _, ok := sf.ConvergentKey(0); ok {
+		t.Fatal("chunk shouldn't have a convergent key")
+	}
+	if !bytes.Equal(sf.PieceKey(1, 2).Key(), sk.Derive(1, 2).Key()) {
+		t.Fatal("piece key should be derived from the masterkey")
+	}
+	if err := sf.SetDeduplicate(true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Give the first two chunks the same convergent key.
+	seed := crypto.HashBytes(fastrand.Bytes(32))
+	for chunkIndex := uint64(0); chunkIndex < 2; chunkIndex++ {
+		if err := sf.SetConvergentKey(chunkIndex, seed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Setting the same key again is a no-op, changing it isn't allowed.
+	if err := sf.SetConvergentKey(0, seed); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.SetConvergentKey(0, crypto.HashBytes(seed[:])); err == nil {
+		t.Fatal("shouldn't be able to change the convergent key")
+	}
+	if err := sf.SetConvergentKey(uint64(sf.NumChunks()), seed); err == nil {
+		t.Fatal("shouldn't be able to set the key of a chunk out of bounds")
+	}
+
+	// Reload the file and check that identical chunks use identical keys
+	// independently of their index.
+	sf, err := LoadSiaFile(sf.siaFilePath, wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sf.Deduplicate() {
+		t.Fatal("file should be deduplicated")
+	}
+	if s, ok := sf.ConvergentKey(1); !ok || s != seed {
+		t.Fatal("wrong convergent key", s, ok)
+	}
+	if !bytes.Equal(sf.PieceKey(0, 3).Key(), sf.PieceKey(1, 3).Key()) {
+		t.Fatal("chunks with the same convergent key should use the same piece keys")
+	}
+	if bytes.Equal(sf.PieceKey(0, 3).Key(), sf.PieceKey(0, 4).Key()) {
+		t.Fatal("pieces of a chunk should use different keys")
+	}
+	if bytes.Equal(sf.PieceKey(0, 3).Key(), sk.Derive(0, 3).Key()) {
+		t.Fatal("convergent piece key shouldn't match the masterkey derived key")
+	}
+	if numChunks > 2 && !bytes.Equal(sf.PieceKey(2, 3).Key(), sk.Derive(2, 3).Key()) {
+		t.Fatal("chunks without convergent key should use the masterkey")
+	}
+
+	// The snapshot should use the same keys.
+	snap, err := sf.Snapshot(modules.RandomSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for chunkIndex := uint64(0); chunkIndex < sf.NumChunks(); chunkIndex++ {
+		if !bytes.Equal(snap.PieceKey(chunkIndex, 1).Key(), sf.PieceKey(chunkIndex, 1).Key()) {
+			t.Fatal("snapshot piece key doesn't match", chunkIndex)
+		}
+	}
+}
+
+// TestPackedFileMetadata tests turning an empty file into a packed file.
+func TestPackedFileMetadata(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Files with chunks can't be packed.
+	pack := modules.RandomSiaPath()
+	sf := newBlankTestFile()
+	if err := sf.SetPackedFile(PackedFileInfo{Pack: pack}, 1); err == nil {
+		t.Fatal("shouldn't be able to pack a file with chunks")
+	}
+
+	// Create an empty file.
+	siaFilePath, _, source, rc, sk, _, _, fileMode := newTestFileParams(1, false)
+	sf, wal, _ := customTestFileAndWAL(siaFilePath, source, rc, sk, 0, 0, fileMode)
+	if _, packed := sf.PackedFile(); packed {
+		t.Fatal("new file shouldn't be packed")
+	}
+	if err := sf.SetPackedFile(PackedFileInfo{}, 1); err == nil {
+		t.Fatal("shouldn't be able to pack a file into the root")
+	}
+
+	// Pack the file.
+	info := PackedFileInfo{Pack: pack, Offset: 4096}
+	if err := sf.SetPackedFile(info, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.SetPackedFile(info, 100); err == nil {
+		t.Fatal("shouldn't be able to pack a file twice")
+	}
+
+	// Reload the file and check the fields.
+	sf, err := LoadSiaFile(sf.siaFilePath, wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	packedInfo, packed := sf.PackedFile()
+	if !packed || !reflect.DeepEqual(packedInfo, info) {
+		t.Fatal("packed file info doesn't match", packedInfo)
+	}
+	if sf.Size() != 100 || sf.NumChunks() != 0 {
+		t.Fatal("unexpected size or number of chunks", sf.Size(), sf.NumChunks())
+	}
+
+	// A packed file reports full redundancy and upload progress since it
+	// doesn't have any chunks of its own.
+	r, _, err := sf.Redundancy(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := float64(rc.NumPieces()) / float64(rc.MinPieces()); r != expected {
+		t.Fatalf("expected redundancy %v but got %v", expected, r)
+	}
+	if progress, _, err := sf.UploadProgressAndBytes(); err != nil || progress != 100 {
+		t.Fatal("unexpected upload progress", progress, err)
+	}
+}
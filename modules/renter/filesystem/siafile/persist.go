@@ -308,6 +308,10 @@ func loadSiaFileFromReader(r io.ReadSeeker, path string, wal *writeaheadlog.WAL,
 	if len(sf.staticMetadata.PartialChunks) > 0 {
 		sf.numChunks = sf.numChunks - 1 + len(sf.staticMetadata.PartialChunks)
 	}
+	// Packed files don't have any chunks of their own.
+	if sf.isPacked() {
+		sf.numChunks = 0
+	}
 	return sf, nil
 }
 
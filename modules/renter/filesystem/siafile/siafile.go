@@ -793,6 +793,14 @@ func (sf *SiaFile) Redundancy(offlineMap map[string]bool, goodForRenewMap map[st
 		sf.staticMetadata.CachedRedundancy = r
 		sf.staticMetadata.CachedUserRedundancy = ur
 	}()
+	if sf.isPacked() {
+		// A packed file doesn't have any chunks. Its redundancy is the
+		// redundancy of its pack which is tracked by the renter.
+		ec := sf.staticMetadata.staticErasureCode
+		r = float64(ec.NumPieces()) / float64(ec.MinPieces())
+		ur = r
+		return
+	}
 	if sf.staticMetadata.FileSize == 0 {
 		// TODO change this once tiny files are supported.
 		if sf.numChunks != 1 {
@@ -1380,7 +1388,7 @@ func (sf *SiaFile) uploadProgressAndBytes() (float64, uint64, error) {
 	if err != nil {
 		return 0, 0, err
 	}
-	if sf.staticMetadata.FileSize == 0 {
+	if sf.staticMetadata.FileSize == 0 || sf.isPacked() {
 		// Update cache.
 		sf.staticMetadata.CachedUploadProgress = 100
 		return 100, uploaded, nil
@@ -17,18 +17,22 @@ type (
 	// can be accessed without locking at the cost of being a frozen readonly
 	// representation of a siafile which only exists in memory.
 	Snapshot struct {
-		staticChunks          []Chunk
-		staticFileSize        int64
-		staticPieceSize       uint64
-		staticErasureCode     modules.ErasureCoder
-		staticHasPartialChunk bool
-		staticMasterKey       crypto.CipherKey
-		staticMode            os.FileMode
-		staticPubKeyTable     []HostPublicKey
-		staticSiaPath         modules.SiaPath
-		staticLocalPath       string
-		staticPartialChunks   []PartialChunkInfo
-		staticUID             SiafileUID
+		staticChunks           []Chunk
+		staticCompressedChunks []CompressedChunkInfo
+		staticCompressionType  modules.CompressionType
+		staticConvergentKeys   []crypto.Hash
+		staticFileSize         int64
+		staticPieceSize        uint64
+		staticErasureCode      modules.ErasureCoder
+		staticHasPartialChunk  bool
+		staticMasterKey        crypto.CipherKey
+		staticMode             os.FileMode
+		staticPubKeyTable      []HostPublicKey
+		staticSiaPath          modules.SiaPath
+		staticLocalPath        string
+		staticPartialChunks    []PartialChunkInfo
+		staticUID              SiafileUID
+		staticUncompressedSize int64
 	}
 )
 
@@ -107,6 +111,29 @@ func (s *Snapshot) ChunkIndexByOffset(offset uint64) (chunkIndex uint64, off uin
 	return
 }
 
+// CompressedChunks returns the offset index of a compressed file's chunks.
+func (s *Snapshot) CompressedChunks() []CompressedChunkInfo {
+	return s.staticCompressedChunks
+}
+
+// CompressionType returns the codec used to compress the file's chunks.
+func (s *Snapshot) CompressionType() modules.CompressionType {
+	return s.staticCompressionType
+}
+
+// ConvergentKey returns the seed of the convergent key of a chunk. The bool
+// is false if the chunk is encrypted with the masterkey.
+func (s *Snapshot) ConvergentKey(chunkIndex uint64) (crypto.Hash, bool) {
+	return convergentKeySeed(s.staticConvergentKeys, chunkIndex)
+}
+
+// PieceKey returns the key used to encrypt the piece at pieceIndex of the
+// chunk at chunkIndex.
+func (s *Snapshot) PieceKey(chunkIndex, pieceIndex uint64) crypto.CipherKey {
+	seed, ok := convergentKeySeed(s.staticConvergentKeys, chunkIndex)
+	return pieceKey(s.staticMasterKey, seed, ok, chunkIndex, pieceIndex)
+}
+
 // ChunkSize returns the size of a single chunk of the file.
 func (s *Snapshot) ChunkSize() uint64 {
 	return s.staticPieceSize * uint64(s.staticErasureCode.MinPieces())
@@ -193,6 +220,15 @@ func (s *Snapshot) UID() SiafileUID {
 	return s.staticUID
 }
 
+// UncompressedSize returns the logical size of the file. For uncompressed
+// files that is the same as Size.
+func (s *Snapshot) UncompressedSize() uint64 {
+	if s.staticCompressionType == modules.CompressionNone {
+		return uint64(s.staticFileSize)
+	}
+	return uint64(s.staticUncompressedSize)
+}
+
 func (sf *SiaFile) readlockChunks() ([]chunk, error) {
 	// Copy chunks.
 	chunks := make([]chunk, 0, sf.numChunks)
@@ -270,20 +306,30 @@ func (sf *SiaFile) readlockSnapshot(sp modules.SiaPath, chunks []chunk) (*Snapsh
 	hasPartial := sf.staticMetadata.HasPartialChunk
 	pcs := sf.staticMetadata.PartialChunks
 	localPath := sf.staticMetadata.LocalPath
+	ct := sf.staticMetadata.CompressionType
+	ccs := make([]CompressedChunkInfo, len(sf.staticMetadata.CompressedChunks))
+	copy(ccs, sf.staticMetadata.CompressedChunks)
+	uncompressedSize := sf.staticMetadata.UncompressedSize
+	cks := make([]crypto.Hash, len(sf.staticMetadata.ConvergentKeys))
+	copy(cks, sf.staticMetadata.ConvergentKeys)
 
 	return &Snapshot{
-		staticChunks:          exportedChunks,
-		staticPartialChunks:   pcs,
-		staticHasPartialChunk: hasPartial,
-		staticFileSize:        fileSize,
-		staticPieceSize:       sf.staticMetadata.StaticPieceSize,
-		staticErasureCode:     sf.staticMetadata.staticErasureCode,
-		staticMasterKey:       mk,
-		staticMode:            mode,
-		staticPubKeyTable:     pkt,
-		staticSiaPath:         sp,
-		staticLocalPath:       localPath,
-		staticUID:             uid,
+		staticChunks:           exportedChunks,
+		staticCompressedChunks: ccs,
+		staticCompressionType:  ct,
+		staticConvergentKeys:   cks,
+		staticPartialChunks:    pcs,
+		staticHasPartialChunk:  hasPartial,
+		staticFileSize:         fileSize,
+		staticPieceSize:        sf.staticMetadata.StaticPieceSize,
+		staticErasureCode:      sf.staticMetadata.staticErasureCode,
+		staticMasterKey:        mk,
+		staticMode:             mode,
+		staticPubKeyTable:      pkt,
+		staticSiaPath:          sp,
+		staticLocalPath:        localPath,
+		staticUID:              uid,
+		staticUncompressedSize: uncompressedSize,
 	}, nil
 }
 
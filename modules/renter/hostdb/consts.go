@@ -7,6 +7,11 @@ import (
 )
 
 const (
+	// benchmarkWeight is the weight of a new performance measurement in the
+	// rolling latency and throughput of a host. The remaining weight is kept
+	// by the previous average.
+	benchmarkWeight = 0.2
+
 	// expectedContractFeesMultiplier is the total number of times we expect to
 	// pay the contract and transacation fees in a relationship with a host
 	// during one renew period. Users tend to fixate on the fees quite a bit, so
@@ -103,6 +108,15 @@ const (
 )
 
 var (
+	// minBenchmarkTransferSize is the minimum number of bytes a transfer needs
+	// to have for its throughput to be recorded. The duration of smaller
+	// transfers is dominated by the latency of the host.
+	minBenchmarkTransferSize = build.Select(build.Var{
+		Standard: uint64(1 << 18), // 256 KiB
+		Dev:      uint64(1 << 14), // 16 KiB
+		Testing:  uint64(1 << 10), // 1 KiB
+	}).(uint64)
+
 	// hostCheckupQuantity specifies the number of hosts that get scanned every
 	// time there is a regular scanning operation.
 	hostCheckupQuantity = build.Select(build.Var{
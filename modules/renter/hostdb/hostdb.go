@@ -70,6 +70,10 @@ type HostDB struct {
 	allowance  modules.Allowance
 	weightFunc hosttree.WeightFunc
 
+	// scoringWeights are the renter-configurable weights of the adjustments
+	// which make up the score of a host.
+	scoringWeights modules.HostScoringWeights
+
 	// txnFees are the most recent fees used in the score estimation. It is
 	// used to determine if the transaction fees have changed enough to warrant
 	// rebuilding the hosttree with an updated weight function.
@@ -99,6 +103,14 @@ type HostDB struct {
 	filteredHosts      map[string]types.SiaPublicKey
 	filterMode         modules.FilterMode
 
+	// policy restricts the hosts of the staticFilteredTree further.
+	// compiledPolicy is the policy prepared for checking hosts and is nil if
+	// the policy is empty. hostRegions are read from the hostRegionsFilename
+	// data file.
+	policy         modules.HostPolicy
+	compiledPolicy *compiledHostPolicy
+	hostRegions    []hostRegion
+
 	blockHeight types.BlockHeight
 	lastChange  modules.ConsensusChangeID
 }
@@ -109,9 +121,7 @@ var _ modules.HostDB = (*HostDB)(nil)
 // insert inserts the HostDBEntry into both hosttrees
 func (hdb *HostDB) insert(host modules.HostDBEntry) error {
 	err := hdb.staticHostTree.Insert(host)
-	_, ok := hdb.filteredHosts[host.PublicKey.String()]
-	isWhitelist := hdb.filterMode == modules.HostDBActiveWhitelist
-	if isWhitelist == ok {
+	if !hdb.filtered(host) {
 		errF := hdb.staticFilteredTree.Insert(host)
 		if errF != nil && errF != hosttree.ErrHostExists {
 			err = errors.Compose(err, errF)
@@ -120,28 +130,36 @@ func (hdb *HostDB) insert(host modules.HostDBEntry) error {
 	return err
 }
 
-// modify modifies the HostDBEntry in both hosttrees
+// modify modifies the HostDBEntry in both hosttrees. Since the host policy
+// depends on the host's settings, the host might be added to or removed from
+// the filtered hosttree.
 func (hdb *HostDB) modify(host modules.HostDBEntry) error {
 	err := hdb.staticHostTree.Modify(host)
-	_, ok := hdb.filteredHosts[host.PublicKey.String()]
-	isWhitelist := hdb.filterMode == modules.HostDBActiveWhitelist
-	if isWhitelist == ok {
-		err = errors.Compose(err, hdb.staticFilteredTree.Modify(host))
+	if hdb.staticFilteredTree == hdb.staticHostTree {
+		return err
 	}
-	return err
+	if hdb.filtered(host) {
+		errF := hdb.staticFilteredTree.Remove(host.PublicKey)
+		if errF != hosttree.ErrNoSuchHost {
+			err = errors.Compose(err, errF)
+		}
+		return err
+	}
+	errF := hdb.staticFilteredTree.Modify(host)
+	if errF == hosttree.ErrNoSuchHost {
+		errF = hdb.staticFilteredTree.Insert(host)
+	}
+	return errors.Compose(err, errF)
 }
 
 // remove removes the HostDBEntry from both hosttrees
 func (hdb *HostDB) remove(pk types.SiaPublicKey) error {
 	err := hdb.staticHostTree.Remove(pk)
-	_, ok := hdb.filteredHosts[pk.String()]
-	isWhitelist := hdb.filterMode == modules.HostDBActiveWhitelist
-	if isWhitelist == ok {
+	if hdb.staticFilteredTree != hdb.staticHostTree {
 		errF := hdb.staticFilteredTree.Remove(pk)
-		if err == nil && errF == hosttree.ErrNoSuchHost {
-			return nil
+		if errF != hosttree.ErrNoSuchHost {
+			err = errors.Compose(err, errF)
 		}
-		err = errors.Compose(err, errF)
 	}
 	return err
 }
@@ -156,8 +174,12 @@ func (hdb *HostDB) managedSetWeightFunction(wf hosttree.WeightFunc) error {
 	defer hdb.mu.Unlock()
 	hdb.weightFunc = wf
 	// Update the hosttree and also the filteredTree if they are not the same.
+	// The filteredTree is rebuilt if the host policy depends on the scores of
+	// the hosts.
 	err := hdb.staticHostTree.SetWeightFunction(wf)
-	if hdb.staticFilteredTree != hdb.staticHostTree {
+	if hdb.compiledPolicy != nil && hdb.compiledPolicy.minScores != (modules.HostScoreThresholds{}) {
+		err = errors.Compose(err, hdb.rebuildFilteredTree())
+	} else if hdb.staticFilteredTree != hdb.staticHostTree {
 		err = errors.Compose(err, hdb.staticFilteredTree.SetWeightFunction(wf))
 	}
 	return err
@@ -226,8 +248,9 @@ func hostdbBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 		staticAlerter:  modules.NewAlerter("hostdb"),
 	}
 
-	// Set the allowance, txnFees and hostweight function.
+	// Set the allowance, txnFees, scoring weights and hostweight function.
 	hdb.allowance = modules.DefaultAllowance
+	hdb.scoringWeights = modules.DefaultHostScoringWeights
 	_, hdb.txnFees = hdb.staticTpool.FeeEstimation()
 	hdb.weightFunc = hdb.managedCalculateHostWeightFn(hdb.allowance)
 
@@ -260,6 +283,12 @@ func hostdbBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 	hdb.staticHostTree = hosttree.New(hdb.weightFunc, deps.Resolver())
 	hdb.staticFilteredTree = hdb.staticHostTree
 
+	// Load the region data file used by the host policy.
+	hdb.hostRegions, err = loadHostRegions(filepath.Join(persistDir, hostRegionsFilename))
+	if err != nil {
+		hdb.staticLog.Println("WARN: unable to load region data file:", err)
+	}
+
 	// Load the prior persistence structures.
 	hdb.mu.Lock()
 	err = hdb.load()
@@ -505,26 +534,21 @@ func (hdb *HostDB) Close() error {
 }
 
 // Host returns the HostSettings associated with the specified pubkey. If no
-// matching host is found, Host returns false.  For black and white list modes,
-// the Filtered field for the HostDBEntry is set to indicate it the host is
-// being filtered from the filtered hosttree
+// matching host is found, Host returns false.  For black and white list modes
+// and the host policy, the Filtered field for the HostDBEntry is set to
+// indicate it the host is being filtered from the filtered hosttree
 func (hdb *HostDB) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool, error) {
 	if err := hdb.tg.Add(); err != nil {
 		return modules.HostDBEntry{}, false, errors.AddContext(err, "error adding hostdb threadgroup:")
 	}
 	defer hdb.tg.Done()
 
-	hdb.mu.Lock()
-	whitelist := hdb.filterMode == modules.HostDBActiveWhitelist
-	filteredHosts := hdb.filteredHosts
-	hdb.mu.Unlock()
 	host, exists := hdb.staticHostTree.Select(spk)
 	if !exists {
 		return host, exists, errHostNotFoundInTree
 	}
-	_, ok := filteredHosts[spk.String()]
-	host.Filtered = whitelist != ok
 	hdb.mu.RLock()
+	host.Filtered = hdb.filtered(host)
 	updateHostHistoricInteractions(&host, hdb.blockHeight)
 	hdb.mu.RUnlock()
 	return host, exists, nil
@@ -569,10 +593,9 @@ func (hdb *HostDB) SetFilterMode(fm modules.FilterMode, hosts []types.SiaPublicK
 			}
 		}
 		// Reset filtered fields
-		hdb.staticFilteredTree = hdb.staticHostTree
 		hdb.filteredHosts = make(map[string]types.SiaPublicKey)
 		hdb.filterMode = fm
-		return nil
+		return errors.Compose(hdb.rebuildFilteredTree(), hdb.saveSync())
 	}
 
 	// Check for no hosts submitted with whitelist enabled
@@ -581,9 +604,6 @@ func (hdb *HostDB) SetFilterMode(fm modules.FilterMode, hosts []types.SiaPublicK
 		return errors.New("cannot enable whitelist without hosts")
 	}
 
-	// Create filtered HostTree
-	hdb.staticFilteredTree = hosttree.New(hdb.weightFunc, modules.ProdDependencies.Resolver())
-
 	// Create filteredHosts map
 	filteredHosts := make(map[string]types.SiaPublicKey)
 	for _, h := range hosts {
@@ -599,22 +619,11 @@ func (hdb *HostDB) SetFilterMode(fm modules.FilterMode, hosts []types.SiaPublicK
 			hdb.staticLog.Println("Unable to mark entry as filtered:", err)
 		}
 	}
-	var allErrs error
-	allHosts := hdb.staticHostTree.All()
-	for _, host := range allHosts {
-		// Add hosts to filtered tree
-		_, ok := filteredHosts[host.PublicKey.String()]
-		if isWhitelist != ok {
-			continue
-		}
-		err := hdb.staticFilteredTree.Insert(host)
-		if err != nil {
-			allErrs = errors.Compose(allErrs, err)
-		}
-	}
 	hdb.filteredHosts = filteredHosts
 	hdb.filterMode = fm
-	return errors.Compose(allErrs, hdb.saveSync())
+
+	// Rebuild the filtered HostTree
+	return errors.Compose(hdb.rebuildFilteredTree(), hdb.saveSync())
 }
 
 // InitialScanComplete returns a boolean indicating if the initial scan of the
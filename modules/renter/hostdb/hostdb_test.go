@@ -20,6 +20,8 @@ import (
 	"gitlab.com/scpcorp/ScPrime/modules/wallet"
 	"gitlab.com/scpcorp/ScPrime/persist"
 	"gitlab.com/scpcorp/ScPrime/types"
+
+	"gitlab.com/NebulousLabs/errors"
 )
 
 // hdbTester contains a hostdb and all dependencies.
@@ -45,6 +47,7 @@ func bareHostDB() *HostDB {
 	}
 	hdb := &HostDB{
 		allowance:      modules.DefaultAllowance,
+		scoringWeights: modules.DefaultHostScoringWeights,
 		staticLog:      logger,
 		knownContracts: make(map[string]contractInfo),
 	}
@@ -592,6 +595,71 @@ func TestUpdateHistoricInteractions(t *testing.T) {
 	}
 }
 
+// TestRecordHostBenchmark checks that benchmarks are added to the rolling
+// latency and throughput of a host.
+func TestRecordHostBenchmark(t *testing.T) {
+	t.Parallel()
+	hdb := bareHostDB()
+	host := makeHostDBEntry()
+	if err := hdb.insert(host); err != nil {
+		t.Fatal(err)
+	}
+	fetch := func() modules.HostDBEntry {
+		t.Helper()
+		entry, exists := hdb.staticHostTree.Select(host.PublicKey)
+		if !exists {
+			t.Fatal("host not found")
+		}
+		return entry
+	}
+
+	// The first measurement is taken as it is.
+	err := hdb.RecordHostBenchmark(host.PublicKey, modules.HostBenchmark{
+		Latency:       100 * time.Millisecond,
+		DownloadBytes: 1 << 20,
+		DownloadTime:  time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := fetch()
+	if entry.Latency != 100*time.Millisecond || entry.DownloadThroughput != 1<<20 || entry.UploadThroughput != 0 {
+		t.Fatal("wrong initial measurements", entry.Latency, entry.DownloadThroughput, entry.UploadThroughput)
+	}
+
+	// Following measurements are averaged. Missing values don't change the
+	// averages.
+	err = hdb.RecordHostBenchmark(host.PublicKey, modules.HostBenchmark{Latency: 200 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry = fetch()
+	if entry.Latency != 120*time.Millisecond || entry.DownloadThroughput != 1<<20 {
+		t.Fatal("wrong averaged measurements", entry.Latency, entry.DownloadThroughput)
+	}
+
+	// The throughput of small transfers isn't recorded.
+	err = hdb.RecordHostBenchmark(host.PublicKey, modules.HostBenchmark{
+		DownloadBytes: minBenchmarkTransferSize - 1,
+		DownloadTime:  time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry = fetch(); entry.DownloadThroughput != 1<<20 {
+		t.Fatal("throughput of small transfer was recorded", entry.DownloadThroughput)
+	}
+	if _, exists := hdb.staticFilteredTree.Select(host.PublicKey); !exists {
+		t.Fatal("host should be part of the filtered tree")
+	}
+
+	// Unknown hosts can't be benchmarked.
+	err = hdb.RecordHostBenchmark(types.SiaPublicKey{}, modules.HostBenchmark{Latency: time.Second})
+	if !errors.Contains(err, errHostNotFoundInTree) {
+		t.Fatal("expected errHostNotFoundInTree", err)
+	}
+}
+
 // testCheckForIPViolationsResolver is a resolver for the TestTwoAddresses test.
 type testCheckForIPViolationsResolver struct{}
 
@@ -2,6 +2,7 @@ package hostdb
 
 import (
 	"math"
+	"time"
 
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/types"
@@ -130,3 +131,60 @@ func (hdb *HostDB) IncrementFailedInteractions(key types.SiaPublicKey) error {
 	hdb.staticHostTree.Modify(host)
 	return nil
 }
+
+// rollingAverage adds a new sample to an exponentially weighted moving
+// average. An average of zero means that there was no previous sample.
+func rollingAverage(average, sample float64) float64 {
+	if average == 0 {
+		return sample
+	}
+	return (1-benchmarkWeight)*average + benchmarkWeight*sample
+}
+
+// throughput returns the throughput in bytes per second of a transfer. A
+// transfer without duration or with less than minBenchmarkTransferSize bytes
+// has no throughput.
+func throughput(bytes uint64, duration time.Duration) float64 {
+	if bytes < minBenchmarkTransferSize || duration <= 0 {
+		return 0
+	}
+	return float64(bytes) / duration.Seconds()
+}
+
+// applyBenchmark adds the measurements of the benchmark to the rolling latency
+// and throughput of the host.
+func applyBenchmark(host *modules.HostDBEntry, b modules.HostBenchmark) {
+	if b.Latency > 0 {
+		host.Latency = time.Duration(rollingAverage(float64(host.Latency), float64(b.Latency)))
+	}
+	if tp := throughput(b.DownloadBytes, b.DownloadTime); tp > 0 {
+		host.DownloadThroughput = uint64(rollingAverage(float64(host.DownloadThroughput), tp))
+	}
+	if tp := throughput(b.UploadBytes, b.UploadTime); tp > 0 {
+		host.UploadThroughput = uint64(rollingAverage(float64(host.UploadThroughput), tp))
+	}
+}
+
+// recordBenchmark adds the benchmark to the host with the given key. Hosts
+// which are not part of the hostdb are ignored.
+func (hdb *HostDB) recordBenchmark(key types.SiaPublicKey, b modules.HostBenchmark) error {
+	host, haveHost := hdb.staticHostTree.Select(key)
+	if !haveHost {
+		return errHostNotFoundInTree
+	}
+	applyBenchmark(&host, b)
+	return hdb.modify(host)
+}
+
+// RecordHostBenchmark adds a performance measurement to the rolling latency
+// and throughput of a host.
+func (hdb *HostDB) RecordHostBenchmark(key types.SiaPublicKey, b modules.HostBenchmark) error {
+	if err := hdb.tg.Add(); err != nil {
+		return errors.AddContext(err, "error adding hostdb threadgroup:")
+	}
+	defer hdb.tg.Done()
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	return errors.AddContext(hdb.recordBenchmark(key, b), "unable to record host benchmark:")
+}
@@ -0,0 +1,309 @@
+package hostdb
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/hostdb/hosttree"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+const (
+	// hostRegionsFilename is the name of the optional data file in the hostdb
+	// persist dir which maps IP ranges to countries and regions. Every line
+	// has the format "cidr,country,region" where the region can be empty.
+	// Empty lines and lines starting with '#' are ignored.
+	hostRegionsFilename = "hostregions.csv"
+)
+
+type (
+	// hostRegion tags an IP range with a country and region.
+	hostRegion struct {
+		ipNet   *net.IPNet
+		country string
+		region  string
+	}
+
+	// compiledHostPolicy is a modules.HostPolicy which was prepared for
+	// efficiently checking hosts against it.
+	compiledHostPolicy struct {
+		blockedNets    []*net.IPNet
+		allowedNets    []*net.IPNet
+		blockedHosts   map[string]struct{}
+		allowedHosts   map[string]struct{}
+		blockedRegions map[string]struct{}
+		allowedRegions map[string]struct{}
+		minScores      modules.HostScoreThresholds
+		regions        []hostRegion
+	}
+)
+
+// loadHostRegions reads the region data file at path. A missing file is not
+// an error.
+func loadHostRegions(path string) ([]hostRegion, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var regions []hostRegion
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, ",")
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("invalid number of fields in line %v of region data file", line)
+		}
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, errors.AddContext(err, "invalid CIDR range in region data file")
+		}
+		hr := hostRegion{
+			ipNet:   ipNet,
+			country: strings.ToUpper(strings.TrimSpace(fields[1])),
+		}
+		if len(fields) == 3 {
+			hr.region = strings.ToUpper(strings.TrimSpace(fields[2]))
+		}
+		regions = append(regions, hr)
+	}
+	return regions, scanner.Err()
+}
+
+// parseNets parses a list of CIDR ranges.
+func parseNets(cidrs []string) ([]*net.IPNet, error) {
+	ipNets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// groupHosts returns the set of hosts which are part of the groups.
+func groupHosts(groups map[string][]types.SiaPublicKey, names []string) map[string]struct{} {
+	hosts := make(map[string]struct{})
+	for _, name := range names {
+		for _, pk := range groups[name] {
+			hosts[pk.String()] = struct{}{}
+		}
+	}
+	return hosts
+}
+
+// regionSet normalizes a list of regions into a set.
+func regionSet(regions []string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, region := range regions {
+		set[strings.ToUpper(region)] = struct{}{}
+	}
+	return set
+}
+
+// compileHostPolicy validates the policy and prepares it for checking hosts.
+// An empty policy compiles to nil.
+func compileHostPolicy(hp modules.HostPolicy, regions []hostRegion) (*compiledHostPolicy, error) {
+	if err := hp.Validate(); err != nil {
+		return nil, err
+	}
+	if hp.Empty() {
+		return nil, nil
+	}
+	blockedNets, err := parseNets(hp.BlockedNets)
+	if err != nil {
+		return nil, err
+	}
+	allowedNets, err := parseNets(hp.AllowedNets)
+	if err != nil {
+		return nil, err
+	}
+	return &compiledHostPolicy{
+		blockedNets:    blockedNets,
+		allowedNets:    allowedNets,
+		blockedHosts:   groupHosts(hp.HostGroups, hp.BlockedGroups),
+		allowedHosts:   groupHosts(hp.HostGroups, hp.AllowedGroups),
+		blockedRegions: regionSet(hp.BlockedRegions),
+		allowedRegions: regionSet(hp.AllowedRegions),
+		minScores:      hp.MinScores,
+		regions:        regions,
+	}, nil
+}
+
+// hostIPs returns the IPs of a host without resolving its address. If the
+// host announced an IP, that IP is used. Otherwise the network addresses of
+// the host's resolved subnets are used.
+func hostIPs(host modules.HostDBEntry) []net.IP {
+	if ip := net.ParseIP(host.NetAddress.Host()); ip != nil {
+		return []net.IP{ip}
+	}
+	var ips []net.IP
+	for _, ipNet := range host.IPNets {
+		ip, _, err := net.ParseCIDR(ipNet)
+		if err == nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips
+}
+
+// containsAny returns true if any of the ips is part of any of the ipNets.
+func containsAny(ipNets []*net.IPNet, ips []net.IP) bool {
+	for _, ipNet := range ipNets {
+		for _, ip := range ips {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hostRegions returns the tags of the regions the ips belong to. Every region
+// is returned both as its country and as "country/region".
+func (cp *compiledHostPolicy) hostRegions(ips []net.IP) []string {
+	var tags []string
+	for _, hr := range cp.regions {
+		for _, ip := range ips {
+			if !hr.ipNet.Contains(ip) {
+				continue
+			}
+			tags = append(tags, hr.country)
+			if hr.region != "" {
+				tags = append(tags, hr.country+"/"+hr.region)
+			}
+			break
+		}
+	}
+	return tags
+}
+
+// matchesRegion returns true if any of the tags is part of the set.
+func matchesRegion(set map[string]struct{}, tags []string) bool {
+	for _, tag := range tags {
+		if _, exists := set[tag]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed returns true if the host satisfies the policy. The weight function
+// is only called if the policy has score thresholds, which means that the
+// hostdb lock needs to be held.
+func (cp *compiledHostPolicy) allowed(host modules.HostDBEntry, wf hosttree.WeightFunc) bool {
+	// Check the host groups.
+	key := host.PublicKey.String()
+	if _, blocked := cp.blockedHosts[key]; blocked {
+		return false
+	}
+	if _, allowed := cp.allowedHosts[key]; len(cp.allowedHosts) > 0 && !allowed {
+		return false
+	}
+
+	// Check the subnets and regions.
+	ips := hostIPs(host)
+	if containsAny(cp.blockedNets, ips) {
+		return false
+	}
+	if len(cp.allowedNets) > 0 && !containsAny(cp.allowedNets, ips) {
+		return false
+	}
+	if len(cp.blockedRegions) > 0 || len(cp.allowedRegions) > 0 {
+		tags := cp.hostRegions(ips)
+		if matchesRegion(cp.blockedRegions, tags) {
+			return false
+		}
+		if len(cp.allowedRegions) > 0 && !matchesRegion(cp.allowedRegions, tags) {
+			return false
+		}
+	}
+
+	// Check the score thresholds.
+	if cp.minScores == (modules.HostScoreThresholds{}) {
+		return true
+	}
+	sb := wf(host).HostScoreBreakdown(types.ZeroCurrency, false, false, false)
+	return cp.minScores.Satisfied(sb)
+}
+
+// filtered returns true if the host is filtered out of the staticFilteredTree
+// either by the filter mode or by the host policy.
+func (hdb *HostDB) filtered(host modules.HostDBEntry) bool {
+	_, listed := hdb.filteredHosts[host.PublicKey.String()]
+	if (hdb.filterMode == modules.HostDBActiveWhitelist) != listed {
+		return true
+	}
+	return hdb.compiledPolicy != nil && !hdb.compiledPolicy.allowed(host, hdb.weightFunc)
+}
+
+// rebuildFilteredTree rebuilds the staticFilteredTree from the hosts of the
+// staticHostTree. If neither a filter mode nor a host policy is active, both
+// trees are the same.
+func (hdb *HostDB) rebuildFilteredTree() error {
+	listActive := hdb.filterMode == modules.HostDBActivateBlacklist || hdb.filterMode == modules.HostDBActiveWhitelist
+	if !listActive && hdb.compiledPolicy == nil {
+		hdb.staticFilteredTree = hdb.staticHostTree
+		return nil
+	}
+	hdb.staticFilteredTree = hosttree.New(hdb.weightFunc, modules.ProdDependencies.Resolver())
+	var allErrs error
+	for _, host := range hdb.staticHostTree.All() {
+		if hdb.filtered(host) {
+			continue
+		}
+		allErrs = errors.Compose(allErrs, hdb.staticFilteredTree.Insert(host))
+	}
+	return allErrs
+}
+
+// HostPolicy returns the host policy of the hostdb.
+func (hdb *HostDB) HostPolicy() (modules.HostPolicy, error) {
+	if err := hdb.tg.Add(); err != nil {
+		return modules.HostPolicy{}, errors.AddContext(err, "error adding hostdb threadgroup:")
+	}
+	defer hdb.tg.Done()
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.policy, nil
+}
+
+// SetHostPolicy sets the host policy of the hostdb and rebuilds the filtered
+// hosttree. The region data file is reloaded to pick up any changes.
+func (hdb *HostDB) SetHostPolicy(hp modules.HostPolicy) error {
+	if err := hdb.tg.Add(); err != nil {
+		return errors.AddContext(err, "error adding hostdb threadgroup:")
+	}
+	defer hdb.tg.Done()
+
+	regions, err := loadHostRegions(filepath.Join(hdb.persistDir, hostRegionsFilename))
+	if err != nil {
+		return errors.AddContext(err, "unable to load region data file")
+	}
+	cp, err := compileHostPolicy(hp, regions)
+	if err != nil {
+		return errors.AddContext(err, "invalid host policy")
+	}
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.hostRegions = regions
+	hdb.policy = hp
+	hdb.compiledPolicy = cp
+	return errors.Compose(hdb.rebuildFilteredTree(), hdb.saveSync())
+}
@@ -0,0 +1,206 @@
+package hostdb
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestLoadHostRegions tests parsing the region data file.
+func TestLoadHostRegions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	dir := build.TempDir("hostdb", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, hostRegionsFilename)
+
+	// A missing file is not an error.
+	regions, err := loadHostRegions(path)
+	if err != nil || len(regions) != 0 {
+		t.Fatal("expected no regions and no error", regions, err)
+	}
+
+	// Load a valid file.
+	data := "# comment\n\n1.2.3.0/24,de,by\n4.5.0.0/16, FR\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	regions, err = loadHostRegions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) != 2 {
+		t.Fatal("wrong number of regions", len(regions))
+	}
+	if regions[0].country != "DE" || regions[0].region != "BY" || regions[0].ipNet.String() != "1.2.3.0/24" {
+		t.Fatal("first region wasn't parsed correctly", regions[0])
+	}
+	if regions[1].country != "FR" || regions[1].region != "" {
+		t.Fatal("second region wasn't parsed correctly", regions[1])
+	}
+
+	// An invalid range is an error.
+	if err := ioutil.WriteFile(path, []byte("1.2.3.0/33,DE\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHostRegions(path); err == nil {
+		t.Fatal("expected invalid range to fail")
+	}
+}
+
+// TestHostPolicyFiltered checks that hosts are filtered according to the
+// different criteria of a host policy.
+func TestHostPolicyFiltered(t *testing.T) {
+	hdb := bareHostDB()
+	_, germany, err := net.ParseCIDR("1.2.3.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdb.hostRegions = []hostRegion{{ipNet: germany, country: "DE", region: "BY"}}
+
+	host1 := makeHostDBEntry()
+	host1.NetAddress = "1.2.3.4:4282"
+	host2 := makeHostDBEntry()
+	host2.NetAddress = "example.com:4282"
+	host2.IPNets = []string{"5.6.7.0/24"}
+
+	setPolicy := func(hp modules.HostPolicy) {
+		cp, err := compileHostPolicy(hp, hdb.hostRegions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hdb.policy = hp
+		hdb.compiledPolicy = cp
+	}
+	check := func(filtered1, filtered2 bool) {
+		t.Helper()
+		if hdb.filtered(host1) != filtered1 || hdb.filtered(host2) != filtered2 {
+			t.Fatalf("expected %v and %v but got %v and %v", filtered1, filtered2, hdb.filtered(host1), hdb.filtered(host2))
+		}
+	}
+
+	// Without a policy no host is filtered.
+	setPolicy(modules.HostPolicy{})
+	check(false, false)
+
+	// Block by subnet. The second host is matched by its resolved subnet.
+	setPolicy(modules.HostPolicy{BlockedNets: []string{"5.6.0.0/16"}})
+	check(false, true)
+	setPolicy(modules.HostPolicy{AllowedNets: []string{"1.2.3.4/32"}})
+	check(false, true)
+
+	// Block by group.
+	groups := map[string][]types.SiaPublicKey{"group": {host1.PublicKey}}
+	setPolicy(modules.HostPolicy{HostGroups: groups, BlockedGroups: []string{"group"}})
+	check(true, false)
+	setPolicy(modules.HostPolicy{HostGroups: groups, AllowedGroups: []string{"group"}})
+	check(false, true)
+
+	// Block by region.
+	setPolicy(modules.HostPolicy{BlockedRegions: []string{"de/by"}})
+	check(true, false)
+	setPolicy(modules.HostPolicy{AllowedRegions: []string{"DE"}})
+	check(false, true)
+
+	// Block by score. No host can have an adjustment above 1.
+	setPolicy(modules.HostPolicy{MinScores: modules.HostScoreThresholds{UptimeAdjustment: 2}})
+	check(true, true)
+
+	// The filter mode is still applied.
+	setPolicy(modules.HostPolicy{BlockedNets: []string{"5.6.0.0/16"}})
+	hdb.filterMode = modules.HostDBActivateBlacklist
+	hdb.filteredHosts = map[string]types.SiaPublicKey{host1.PublicKey.String(): host1.PublicKey}
+	check(true, true)
+
+	// Referencing unknown groups is invalid.
+	_, err = compileHostPolicy(modules.HostPolicy{BlockedGroups: []string{"unknown"}}, nil)
+	if err == nil {
+		t.Fatal("expected unknown group to be rejected")
+	}
+}
+
+// TestSetHostPolicy checks that setting a host policy updates the filtered
+// hosttree and the Filtered field of the hosts and that it is persisted.
+func TestSetHostPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	hdbt, err := newHDBTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdb := hdbt.hdb
+
+	// Add two hosts.
+	host1 := makeHostDBEntry()
+	host1.NetAddress = "1.2.3.4:4282"
+	host2 := makeHostDBEntry()
+	host2.NetAddress = "5.6.7.8:4282"
+	hdb.mu.Lock()
+	err1, err2 := hdb.insert(host1), hdb.insert(host2)
+	hdb.mu.Unlock()
+	if err1 != nil || err2 != nil {
+		t.Fatal(err1, err2)
+	}
+
+	// Block the second host.
+	hp := modules.HostPolicy{BlockedNets: []string{"5.6.7.0/24"}}
+	if err := hdb.SetHostPolicy(hp); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := hdb.staticFilteredTree.Select(host2.PublicKey); exists {
+		t.Fatal("blocked host is still part of the filtered tree")
+	}
+	if _, exists := hdb.staticFilteredTree.Select(host1.PublicKey); !exists {
+		t.Fatal("allowed host isn't part of the filtered tree")
+	}
+	if host, _, err := hdb.Host(host2.PublicKey); err != nil || !host.Filtered {
+		t.Fatal("blocked host should be filtered", err)
+	}
+
+	// Changing the host's address lifts the block.
+	host2.NetAddress = "9.9.9.9:4282"
+	hdb.mu.Lock()
+	err = hdb.modify(host2)
+	hdb.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := hdb.staticFilteredTree.Select(host2.PublicKey); !exists {
+		t.Fatal("host should have been added to the filtered tree")
+	}
+
+	// Invalid policies are rejected.
+	if err := hdb.SetHostPolicy(modules.HostPolicy{AllowedNets: []string{"invalid"}}); err == nil {
+		t.Fatal("expected invalid policy to be rejected")
+	}
+
+	// The policy is persisted.
+	if err := hdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	hdb, errChan := New(hdbt.gateway, hdbt.cs, hdbt.tpool, filepath.Join(hdbt.persistDir, modules.RenterDir))
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	defer hdb.Close()
+	loaded, err := hdb.HostPolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.BlockedNets) != 1 || loaded.BlockedNets[0] != hp.BlockedNets[0] {
+		t.Fatal("policy wasn't persisted", loaded)
+	}
+}
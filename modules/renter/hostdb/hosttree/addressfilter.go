@@ -3,6 +3,7 @@ package hosttree
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	"gitlab.com/scpcorp/ScPrime/modules"
 )
@@ -38,12 +39,36 @@ func NewFilter(resolver modules.Resolver, allowedhosts int) *Filter {
 		iprestriction: allowedhosts}
 }
 
+// unresolvedHostname returns the filter key of a host whose hostname must not
+// be resolved. Hostnames aren't resolved if a SOCKS5 proxy is set, since the
+// lookup would leak DNS requests. These hosts are filtered by their hostname
+// instead of their subnets.
+func unresolvedHostname(host modules.NetAddress) (string, bool) {
+	if address, _ := modules.GlobalProxy.Settings(); address == "" {
+		return "", false
+	}
+	hostname := host.Host()
+	if net.ParseIP(hostname) != nil {
+		return "", false
+	}
+	return "host:" + strings.TrimSuffix(strings.ToLower(hostname), "."), true
+}
+
 // Add adds a host to the filter. This will resolve the hostname into one
 // or more IP addresses, extract the subnets used by those addresses and
 // add the subnets to the filter. Add doesn't return an error, but if the
 // addresses of a host can't be resolved it will be handled as if the host
-// had no addresses associated with it.
+// had no addresses associated with it. Onion addresses are not resolved and
+// aren't associated with any subnets. If a SOCKS5 proxy is set, hostnames
+// aren't resolved either and are added to the filter as they are.
 func (af *Filter) Add(host modules.NetAddress) {
+	if host.IsOnion() {
+		return
+	}
+	if key, ok := unresolvedHostname(host); ok {
+		af.filter[key] = netEntry{used: af.filter[key].used + 1}
+		return
+	}
 	// Translate the hostname to one or multiple IPs. If the argument is an IP
 	// address LookupIP will just return that IP.
 	addresses, err := af.resolver.LookupIP(host.Host())
@@ -79,7 +104,16 @@ func (af *Filter) Add(host modules.NetAddress) {
 // that was previously added to the filter. If it is in use, or if the host is
 // associated with 2 addresses of the same type (e.g. IPv4 and IPv4) or if it
 // is associated with more than 2 addresses, Filtered will return 'true'.
+// Onion addresses are only filtered if they are not allowed. If a SOCKS5
+// proxy is set, hostnames are only filtered if the same hostname was added.
 func (af *Filter) Filtered(host modules.NetAddress) bool {
+	if host.IsOnion() {
+		return !modules.GlobalProxy.AllowOnion()
+	}
+	if key, ok := unresolvedHostname(host); ok {
+		filterEntry, exists := af.filter[key]
+		return exists && filterEntry.used >= af.iprestriction
+	}
 	// Translate the hostname to one or multiple IPs. If the argument is an IP
 	// address LookupIP will just return that IP.
 	addresses, err := af.resolver.LookupIP(host.Host())
@@ -301,3 +301,53 @@ func TestIPRestriction(t *testing.T) {
 		t.Error("host8 wasn't filtered")
 	}
 }
+
+// testNoLookupResolver is a resolver which fails the test if a hostname is
+// resolved.
+type testNoLookupResolver struct {
+	t *testing.T
+}
+
+func (r testNoLookupResolver) LookupIP(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	r.t.Fatal("hostname was resolved:", host)
+	return nil, nil
+}
+
+// TestFilterProxy checks that hostnames aren't resolved if a SOCKS5 proxy is
+// set and that they are filtered by their hostname instead.
+func TestFilterProxy(t *testing.T) {
+	if err := modules.GlobalProxy.SetProxy("127.0.0.1:9050", false); err != nil {
+		t.Fatal(err)
+	}
+	defer modules.GlobalProxy.SetProxy("", false)
+
+	filter := NewFilter(testNoLookupResolver{t}, 1)
+	host1 := modules.NetAddress("host1.com:1234")
+	host1Upper := modules.NetAddress("HOST1.com.:4321")
+	host2 := modules.NetAddress("host2.com:1234")
+	ip1 := modules.NetAddress("127.0.0.1:1234")
+	ip2 := modules.NetAddress("127.0.0.2:1234")
+
+	if filter.Filtered(host1) {
+		t.Error("host1 was filtered")
+	}
+	filter.Add(host1)
+	if !filter.Filtered(host1Upper) {
+		t.Error("host1 wasn't filtered")
+	}
+	if filter.Filtered(host2) {
+		t.Error("host2 was filtered")
+	}
+
+	// IP addresses are still filtered by their subnets.
+	if filter.Filtered(ip1) {
+		t.Error("ip1 was filtered")
+	}
+	filter.Add(ip1)
+	if !filter.Filtered(ip2) {
+		t.Error("ip2 wasn't filtered")
+	}
+}
@@ -23,8 +23,10 @@ type HostAdjustments struct {
 	CollateralAdjustment       float64
 	DurationAdjustment         float64
 	InteractionAdjustment      float64
+	LatencyAdjustment          float64
 	PriceAdjustment            float64
 	StorageRemainingAdjustment float64
+	ThroughputAdjustment       float64
 	UptimeAdjustment           float64
 	VersionAdjustment          float64
 }
@@ -74,8 +76,10 @@ func (h HostAdjustments) HostScoreBreakdown(totalScore types.Currency, ignoreAge
 		CollateralAdjustment:       h.CollateralAdjustment,
 		DurationAdjustment:         h.DurationAdjustment,
 		InteractionAdjustment:      h.InteractionAdjustment,
+		LatencyAdjustment:          h.LatencyAdjustment,
 		PriceAdjustment:            h.PriceAdjustment,
 		StorageRemainingAdjustment: h.StorageRemainingAdjustment,
+		ThroughputAdjustment:       h.ThroughputAdjustment,
 		UptimeAdjustment:           h.UptimeAdjustment,
 		VersionAdjustment:          h.VersionAdjustment,
 	}
@@ -91,8 +95,10 @@ func (h HostAdjustments) Score() types.Currency {
 		h.CollateralAdjustment *
 		h.DurationAdjustment *
 		h.InteractionAdjustment *
+		h.LatencyAdjustment *
 		h.PriceAdjustment *
 		h.StorageRemainingAdjustment *
+		h.ThroughputAdjustment *
 		h.UptimeAdjustment *
 		h.VersionAdjustment
 
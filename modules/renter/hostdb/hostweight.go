@@ -57,6 +57,15 @@ const (
 	// the bad points do not rack up very quickly.
 	interactionExponentiation = 10
 
+	// latencyExponentiation determines how heavily we penalize hosts with a
+	// latency above the latencyTarget.
+	latencyExponentiation = 2
+
+	// latencyTarget is the latency at which a host is no longer penalized.
+	// Hosts which respond faster than that don't get a bonus since the
+	// throughput matters a lot more for transferring sectors.
+	latencyTarget = 250 * time.Millisecond
+
 	// priceExponentiationLarge is the number of times that the weight is
 	// divided by the price when the price is large relative to the allowance.
 	// The exponentiation is a lot higher because we care greatly about high
@@ -81,6 +90,16 @@ const (
 	// This is necessary to prevent exploits where a host gets an unreasonable
 	// score by putting it's price way too low.
 	priceFloor = 0.2
+
+	// throughputExponentiation determines how heavily we penalize hosts with
+	// a throughput below the throughputTarget. The exponentiation is low
+	// because the measured throughput also depends on the renter's own
+	// connection.
+	throughputExponentiation = 0.5
+
+	// throughputTarget is the throughput in bytes per second at which a host
+	// is no longer penalized.
+	throughputTarget = 1 << 20
 )
 
 // basePriceAdjustments will adjust the weight of the entry according to the prices
@@ -221,6 +240,29 @@ func (hdb *HostDB) interactionAdjustments(entry modules.HostDBEntry) float64 {
 	return math.Pow(ratio, interactionExponentiation)
 }
 
+// latencyAdjustments penalizes hosts which took longer than the latencyTarget
+// to respond on average. Hosts without a measured latency are not penalized.
+func latencyAdjustments(entry modules.HostDBEntry) float64 {
+	if entry.Latency <= latencyTarget {
+		return 1
+	}
+	ratio := float64(latencyTarget) / float64(entry.Latency)
+	return math.Pow(ratio, latencyExponentiation)
+}
+
+// throughputAdjustments penalizes hosts which transferred data slower than the
+// throughputTarget on average. Both directions are considered separately and
+// directions without measurements are not penalized.
+func throughputAdjustments(entry modules.HostDBEntry) float64 {
+	adjustment := func(throughput uint64) float64 {
+		if throughput == 0 || throughput >= throughputTarget {
+			return 1
+		}
+		return math.Pow(float64(throughput)/throughputTarget, throughputExponentiation)
+	}
+	return adjustment(entry.DownloadThroughput) * adjustment(entry.UploadThroughput)
+}
+
 // priceAdjustments will adjust the weight of the entry according to the prices
 // that it has set.
 //
@@ -545,29 +587,79 @@ func (hdb *HostDB) uptimeAdjustments(entry modules.HostDBEntry) float64 {
 	return math.Pow(uptimeRatio, exp)
 }
 
+// applyWeight raises an adjustment to the power of its weight. Adjustments
+// which reject a host outright are not weighted, so even a weight of 0 can't
+// make a rejected host usable.
+func applyWeight(adjustment, weight float64) float64 {
+	if adjustment == math.SmallestNonzeroFloat64 || weight == 1 {
+		return adjustment
+	}
+	weighted := math.Pow(adjustment, weight)
+	if math.IsInf(weighted, 1) {
+		return math.MaxFloat64
+	}
+	if weighted == 0 {
+		return math.SmallestNonzeroFloat64
+	}
+	return weighted
+}
+
+// priceCeilingAdjustments rejects hosts which charge more than any of the
+// price ceilings of the weights.
+func (hdb *HostDB) priceCeilingAdjustments(entry modules.HostDBEntry, weights modules.HostScoringWeights) float64 {
+	if weights.ExceedsPriceCeilings(entry.HostExternalSettings) {
+		hdb.staticLog.Debugf("Host getting 0 score for exceeding the price ceilings: Host %v", entry.PublicKey.String())
+		return math.SmallestNonzeroFloat64
+	}
+	return 1
+}
+
 // managedCalculateHostWeightFn creates a hosttree.WeightFunc given an
-// Allowance.
+// Allowance. The scoring weights of the hostdb are applied to the
+// adjustments.
 //
 // NOTE: the hosttree.WeightFunc that is returned accesses fields of the hostdb.
 // The hostdb lock must be held while utilizing the WeightFunc
 func (hdb *HostDB) managedCalculateHostWeightFn(allowance modules.Allowance) hosttree.WeightFunc {
+	hdb.mu.RLock()
+	weights := hdb.scoringWeights
+	hdb.mu.RUnlock()
+	return hdb.managedCalculateHostWeightFnWithWeights(allowance, weights)
+}
+
+// managedCalculateHostWeightFnWithWeights creates a hosttree.WeightFunc given
+// an Allowance and the scoring weights.
+//
+// NOTE: the hosttree.WeightFunc that is returned accesses fields of the hostdb.
+// The hostdb lock must be held while utilizing the WeightFunc
+func (hdb *HostDB) managedCalculateHostWeightFnWithWeights(allowance modules.Allowance, weights modules.HostScoringWeights) hosttree.WeightFunc {
 	// Get the txnFees.
 	hdb.mu.RLock()
 	txnFees := hdb.txnFees
 	hdb.mu.RUnlock()
-	// Create the weight function.
+	return hdb.calculateHostWeightFn(allowance, weights, txnFees)
+}
+
+// calculateHostWeightFn creates a hosttree.WeightFunc given an Allowance, the
+// scoring weights and the txnFees.
+//
+// NOTE: the hosttree.WeightFunc that is returned accesses fields of the hostdb.
+// The hostdb lock must be held while utilizing the WeightFunc
+func (hdb *HostDB) calculateHostWeightFn(allowance modules.Allowance, weights modules.HostScoringWeights, txnFees types.Currency) hosttree.WeightFunc {
 	return func(entry modules.HostDBEntry) hosttree.ScoreBreakdown {
 		return hosttree.HostAdjustments{
-			AgeAdjustment:              hdb.lifetimeAdjustments(entry),
-			BasePriceAdjustment:        hdb.basePriceAdjustments(entry),
+			AgeAdjustment:              applyWeight(hdb.lifetimeAdjustments(entry), weights.AgeWeight),
+			BasePriceAdjustment:        hdb.basePriceAdjustments(entry) * hdb.priceCeilingAdjustments(entry, weights),
 			BurnAdjustment:             1,
-			CollateralAdjustment:       hdb.collateralAdjustments(entry, allowance),
+			CollateralAdjustment:       applyWeight(hdb.collateralAdjustments(entry, allowance), weights.CollateralWeight),
 			DurationAdjustment:         hdb.durationAdjustments(entry, allowance),
-			InteractionAdjustment:      hdb.interactionAdjustments(entry),
-			PriceAdjustment:            hdb.priceAdjustments(entry, allowance, txnFees),
-			StorageRemainingAdjustment: hdb.storageRemainingAdjustments(entry, allowance),
-			UptimeAdjustment:           hdb.uptimeAdjustments(entry),
-			VersionAdjustment:          versionAdjustments(entry),
+			InteractionAdjustment:      applyWeight(hdb.interactionAdjustments(entry), weights.InteractionWeight),
+			LatencyAdjustment:          applyWeight(latencyAdjustments(entry), weights.LatencyWeight),
+			PriceAdjustment:            applyWeight(hdb.priceAdjustments(entry, allowance, txnFees), weights.PriceWeight),
+			StorageRemainingAdjustment: applyWeight(hdb.storageRemainingAdjustments(entry, allowance), weights.StorageRemainingWeight),
+			ThroughputAdjustment:       applyWeight(throughputAdjustments(entry), weights.ThroughputWeight),
+			UptimeAdjustment:           applyWeight(hdb.uptimeAdjustments(entry), weights.UptimeWeight),
+			VersionAdjustment:          applyWeight(versionAdjustments(entry), weights.VersionWeight),
 		}
 	}
 }
@@ -592,6 +684,75 @@ func (hdb *HostDB) ScoreBreakdown(entry modules.HostDBEntry) (modules.HostScoreB
 	return hdb.managedScoreBreakdown(entry, false, false, false)
 }
 
+// ScoreBreakdownWithWeights computes the score breakdown of a host as if the
+// hostdb was using the provided scoring weights. The scores of the other hosts
+// are computed with the provided weights as well.
+func (hdb *HostDB) ScoreBreakdownWithWeights(entry modules.HostDBEntry, weights modules.HostScoringWeights) (modules.HostScoreBreakdown, error) {
+	if err := hdb.tg.Add(); err != nil {
+		return modules.HostScoreBreakdown{}, err
+	}
+	defer hdb.tg.Done()
+	if err := weights.Validate(); err != nil {
+		return modules.HostScoreBreakdown{}, err
+	}
+	hosts, err := hdb.ActiveHosts()
+	if err != nil {
+		return modules.HostScoreBreakdown{}, errors.AddContext(err, "error getting Active hosts:")
+	}
+	hdb.mu.RLock()
+	allowance := hdb.allowance
+	hdb.mu.RUnlock()
+	weightFunc := hdb.managedCalculateHostWeightFnWithWeights(allowance, weights)
+
+	// Compute the totalScore.
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	totalScore := types.Currency{}
+	for _, host := range hosts {
+		totalScore = totalScore.Add(weightFunc(host).Score())
+	}
+	// Compute the breakdown.
+	return weightFunc(entry).HostScoreBreakdown(totalScore, false, false, false), nil
+}
+
+// ScoringWeights returns the weights used by the hostdb to score hosts.
+func (hdb *HostDB) ScoringWeights() (modules.HostScoringWeights, error) {
+	if err := hdb.tg.Add(); err != nil {
+		return modules.HostScoringWeights{}, err
+	}
+	defer hdb.tg.Done()
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.scoringWeights, nil
+}
+
+// SetScoringWeights updates the weights used by the hostdb to score hosts by
+// updating the host weight function. It will completely rebuild the hosttree
+// so it should be used with care.
+func (hdb *HostDB) SetScoringWeights(weights modules.HostScoringWeights) error {
+	if err := hdb.tg.Add(); err != nil {
+		return err
+	}
+	defer hdb.tg.Done()
+	if err := weights.Validate(); err != nil {
+		return err
+	}
+
+	// Update the weights.
+	hdb.mu.Lock()
+	hdb.scoringWeights = weights
+	allowance := hdb.allowance
+	err := hdb.saveSync()
+	hdb.mu.Unlock()
+	if err != nil {
+		return errors.AddContext(err, "unable to persist scoring weights")
+	}
+
+	// Update the weight function.
+	wf := hdb.managedCalculateHostWeightFn(allowance)
+	return hdb.managedSetWeightFunction(wf)
+}
+
 // managedEstimatedScoreBreakdown computes the score breakdown of a host.
 // Certain adjustments can be ignored.
 func (hdb *HostDB) managedEstimatedScoreBreakdown(entry modules.HostDBEntry, allowance modules.Allowance, ignoreAge, ignoreDuration, ignoreUptime bool) (modules.HostScoreBreakdown, error) {
@@ -664,3 +664,137 @@ func TestHostWeightExtraPriceAdjustments(t *testing.T) {
 		t.Fatal("Expected score decrease with higher sector access price")
 	}
 }
+
+// TestHostWeightScoringWeights checks that the scoring weights are applied to
+// the adjustments and that the price ceilings reject expensive hosts.
+func TestHostWeightScoringWeights(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	hdb := bareHostDB()
+	hdb.blockHeight = 10000
+
+	entry := DefaultHostDBEntry
+	entry.ScanHistory = modules.HostDBScans{
+		{Timestamp: time.Now().Add(time.Hour * -100), Success: true},
+		{Timestamp: time.Now().Add(time.Hour * -80), Success: true},
+		{Timestamp: time.Now().Add(time.Hour * -60), Success: true},
+		{Timestamp: time.Now().Add(time.Hour * -40), Success: true},
+		{Timestamp: time.Now().Add(time.Hour * -20), Success: false},
+	}
+	defaultBreakdown := hdb.weightFunc(entry).HostScoreBreakdown(types.ZeroCurrency, false, false, false)
+
+	// Doubling the uptime weight squares the uptime adjustment and ignoring
+	// the price sets the price adjustment to 1.
+	weights := modules.DefaultHostScoringWeights
+	weights.UptimeWeight = 2
+	weights.PriceWeight = 0
+	wf := hdb.calculateHostWeightFn(hdb.allowance, weights, hdb.txnFees)
+	breakdown := wf(entry).HostScoreBreakdown(types.ZeroCurrency, false, false, false)
+	expectedUptime := defaultBreakdown.UptimeAdjustment * defaultBreakdown.UptimeAdjustment
+	if math.Abs(breakdown.UptimeAdjustment-expectedUptime) > 1e-12 {
+		t.Fatal("uptime weight wasn't applied", breakdown.UptimeAdjustment, expectedUptime)
+	}
+	if breakdown.PriceAdjustment != 1 {
+		t.Fatal("price weight wasn't applied", breakdown.PriceAdjustment)
+	}
+	if breakdown.CollateralAdjustment != defaultBreakdown.CollateralAdjustment {
+		t.Fatal("collateral adjustment shouldn't change")
+	}
+
+	// Adjustments which reject a host are not weighted.
+	entry.MaxDuration = 0
+	weights.UptimeWeight = 0
+	wf = hdb.calculateHostWeightFn(hdb.allowance, weights, hdb.txnFees)
+	breakdown = wf(entry).HostScoreBreakdown(types.ZeroCurrency, false, false, false)
+	if breakdown.DurationAdjustment != math.SmallestNonzeroFloat64 {
+		t.Fatal("host with insufficient duration should be rejected")
+	}
+	entry.MaxDuration = DefaultHostDBEntry.MaxDuration
+
+	// A host exceeding a price ceiling is rejected.
+	weights.MaxStoragePrice = entry.StoragePrice.Sub64(1)
+	wf = hdb.calculateHostWeightFn(hdb.allowance, weights, hdb.txnFees)
+	breakdown = wf(entry).HostScoreBreakdown(types.ZeroCurrency, false, false, false)
+	if breakdown.BasePriceAdjustment != math.SmallestNonzeroFloat64 {
+		t.Fatal("host exceeding the price ceiling should be rejected")
+	}
+	weights.MaxStoragePrice = entry.StoragePrice
+	wf = hdb.calculateHostWeightFn(hdb.allowance, weights, hdb.txnFees)
+	breakdown = wf(entry).HostScoreBreakdown(types.ZeroCurrency, false, false, false)
+	if breakdown.BasePriceAdjustment != 1 {
+		t.Fatal("host at the price ceiling shouldn't be rejected")
+	}
+
+	// Invalid weights are rejected.
+	weights.UptimeWeight = modules.MaxHostScoringWeight + 1
+	if err := weights.Validate(); err == nil {
+		t.Fatal("expected weight above the maximum to be rejected")
+	}
+	if err := (modules.HostScoringWeights{}).Validate(); err == nil {
+		t.Fatal("expected all zero weights to be rejected")
+	}
+}
+
+// TestApplyWeight probes applyWeight for edge cases.
+func TestApplyWeight(t *testing.T) {
+	if applyWeight(0.5, 2) != 0.25 {
+		t.Fatal("wrong weighted adjustment")
+	}
+	if applyWeight(0.5, 0) != 1 {
+		t.Fatal("a weight of 0 should ignore the adjustment")
+	}
+	if applyWeight(math.SmallestNonzeroFloat64, 0) != math.SmallestNonzeroFloat64 {
+		t.Fatal("rejecting adjustments shouldn't be weighted")
+	}
+	if applyWeight(1e200, 2) != math.MaxFloat64 {
+		t.Fatal("weighted adjustment should be capped")
+	}
+	if applyWeight(1e-200, 2) != math.SmallestNonzeroFloat64 {
+		t.Fatal("weighted adjustment shouldn't be zero")
+	}
+}
+
+// TestHostWeightPerformance checks that slow hosts are penalized and that
+// unmeasured hosts are not.
+func TestHostWeightPerformance(t *testing.T) {
+	t.Parallel()
+	hdb := bareHostDB()
+
+	entry := DefaultHostDBEntry
+	unmeasured := hdb.weightFunc(entry).Score()
+	if latencyAdjustments(entry) != 1 || throughputAdjustments(entry) != 1 {
+		t.Fatal("unmeasured hosts shouldn't be penalized")
+	}
+
+	// Fast hosts are not penalized either.
+	entry.Latency = latencyTarget / 2
+	entry.DownloadThroughput = throughputTarget * 2
+	entry.UploadThroughput = throughputTarget
+	if latencyAdjustments(entry) != 1 || throughputAdjustments(entry) != 1 {
+		t.Fatal("fast hosts shouldn't be penalized")
+	}
+
+	// Slow hosts are.
+	entry.Latency = latencyTarget * 2
+	if latencyAdjustments(entry) != 0.25 {
+		t.Fatal("wrong latency adjustment", latencyAdjustments(entry))
+	}
+	entry.DownloadThroughput = throughputTarget / 4
+	if throughputAdjustments(entry) != 0.5 {
+		t.Fatal("wrong throughput adjustment", throughputAdjustments(entry))
+	}
+	if hdb.weightFunc(entry).Score().Cmp(unmeasured) >= 0 {
+		t.Fatal("slow host should have a lower score than an unmeasured one")
+	}
+
+	// Weights of 0 ignore the measurements.
+	weights := modules.DefaultHostScoringWeights
+	weights.LatencyWeight = 0
+	weights.ThroughputWeight = 0
+	wf := hdb.calculateHostWeightFn(hdb.allowance, weights, hdb.txnFees)
+	if wf(entry).Score().Cmp(unmeasured) != 0 {
+		t.Fatal("ignored measurements shouldn't change the score")
+	}
+}
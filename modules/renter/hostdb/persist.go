@@ -34,6 +34,8 @@ type hdbPersist struct {
 	LastChange               modules.ConsensusChangeID
 	FilteredHosts            map[string]types.SiaPublicKey
 	FilterMode               modules.FilterMode
+	HostPolicy               modules.HostPolicy
+	ScoringWeights           modules.HostScoringWeights
 }
 
 // persistData returns the data in the hostdb that will be saved to disk.
@@ -46,6 +48,8 @@ func (hdb *HostDB) persistData() (data hdbPersist) {
 	data.LastChange = hdb.lastChange
 	data.FilteredHosts = hdb.filteredHosts
 	data.FilterMode = hdb.filterMode
+	data.HostPolicy = hdb.policy
+	data.ScoringWeights = hdb.scoringWeights
 	return data
 }
 
@@ -59,6 +63,7 @@ func (hdb *HostDB) load() error {
 	// Fetch the data from the file.
 	var data hdbPersist
 	data.FilteredHosts = make(map[string]types.SiaPublicKey)
+	data.ScoringWeights = modules.DefaultHostScoringWeights
 	err := hdb.staticDeps.LoadFile(persistMetadata, &data, filepath.Join(hdb.persistDir, persistFilename))
 	if err != nil {
 		return err
@@ -78,7 +83,27 @@ func (hdb *HostDB) load() error {
 	hdb.knownContracts = data.KnownContracts
 	hdb.filteredHosts = data.FilteredHosts
 	hdb.filterMode = data.FilterMode
-	if len(hdb.filteredHosts) > 0 {
+
+	// COMPATv1.8.4 older persist files don't contain scoring weights or
+	// only some of them, which means that the default weights are used for
+	// the missing ones.
+	if data.ScoringWeights.Validate() == nil {
+		hdb.scoringWeights = data.ScoringWeights
+		hdb.weightFunc = hdb.calculateHostWeightFn(hdb.allowance, hdb.scoringWeights, hdb.txnFees)
+		err = hdb.staticHostTree.SetWeightFunction(hdb.weightFunc)
+		if err != nil {
+			return err
+		}
+	}
+
+	cp, err := compileHostPolicy(data.HostPolicy, hdb.hostRegions)
+	if err != nil {
+		hdb.staticLog.Println("WARN: ignoring invalid host policy:", err)
+	} else {
+		hdb.policy = data.HostPolicy
+		hdb.compiledPolicy = cp
+	}
+	if len(hdb.filteredHosts) > 0 || hdb.compiledPolicy != nil {
 		hdb.staticFilteredTree = hosttree.New(hdb.weightFunc, modules.ProdDependencies.Resolver())
 	}
 
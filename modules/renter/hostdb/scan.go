@@ -332,8 +332,16 @@ func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, netErr error) {
 // staticLookupIPNets returns string representations of the CIDR subnets used by
 // the host. In case of an error we return nil. We don't really care about the
 // error because we don't update host entries if we are offline anyway. So if we
-// fail to resolve a hostname, the problem is not related to us.
+// fail to resolve a hostname, the problem is not related to us. Onion addresses
+// are not resolved to avoid leaking them to the DNS resolver. If a SOCKS5 proxy
+// is set, hostnames aren't resolved either and the host has no subnets.
 func (hdb *HostDB) staticLookupIPNets(address modules.NetAddress) (ipNets []string, err error) {
+	if address.IsOnion() {
+		return nil, nil
+	}
+	if proxy, _ := modules.GlobalProxy.Settings(); proxy != "" && net.ParseIP(address.Host()) == nil {
+		return nil, nil
+	}
 	// Lookup the IP addresses of the host.
 	addresses, err := hdb.staticDeps.Resolver().LookupIP(address.Host())
 	if err != nil {
@@ -418,7 +426,7 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 			Timeout: timeout,
 		}
 		start := time.Now()
-		conn, err := dialer.Dial("tcp", string(netAddr))
+		conn, err := modules.GlobalProxy.Dial(dialer, netAddr)
 		latency = time.Since(start)
 		if err != nil {
 			return errors.AddContext(err, "could not connect")
@@ -500,7 +508,7 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 		// closing. Additionally, we can't assign the result of Dial to conn,
 		// because if the Dial fails and conn is nil, then the deferred call to
 		// Close will segfault.
-		conn2, err := dialer.Dial("tcp", string(netAddr))
+		conn2, err := modules.GlobalProxy.Dial(dialer, netAddr)
 		if err != nil {
 			return errors.AddContext(err, "could not connect")
 		}
@@ -571,6 +579,13 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 	// delete the entry from the scan map as the scan has been successful.
 	hdb.updateEntry(entry, err)
 
+	// Add the dial latency of a successful scan to the host's rolling
+	// latency. The host might have been removed by updateEntry, in which case
+	// there is nothing to record.
+	if success {
+		_ = hdb.recordBenchmark(entry.PublicKey, modules.HostBenchmark{Latency: latency})
+	}
+
 	// Add the scan to the initialScanLatencies if it was successful.
 	if success && len(hdb.initialScanLatencies) < minScansForSpeedup {
 		hdb.initialScanLatencies = append(hdb.initialScanLatencies, latency)
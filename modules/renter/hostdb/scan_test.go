@@ -2,6 +2,7 @@ package hostdb
 
 import (
 	"errors"
+	"net"
 	"testing"
 	"time"
 
@@ -320,3 +321,56 @@ func TestUpdateEntryWithKnownHost(t *testing.T) {
 		t.Fatal("Entry did not get removed from the host tree")
 	}
 }
+
+// testNoLookupResolver is a resolver which fails the test if a hostname is
+// resolved.
+type testNoLookupResolver struct {
+	t *testing.T
+}
+
+func (r testNoLookupResolver) LookupIP(host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	r.t.Fatal("hostname was resolved:", host)
+	return nil, nil
+}
+
+// testNoLookupDeps is a custom dependency that overrides the Resolver method
+// to return a testNoLookupResolver.
+type testNoLookupDeps struct {
+	disableScanLoopDeps
+	t *testing.T
+}
+
+// Resolver returns a testNoLookupResolver.
+func (d *testNoLookupDeps) Resolver() modules.Resolver {
+	return testNoLookupResolver{d.t}
+}
+
+// TestLookupIPNetsProxy checks that staticLookupIPNets doesn't resolve
+// hostnames if a SOCKS5 proxy is set.
+func TestLookupIPNetsProxy(t *testing.T) {
+	if err := modules.GlobalProxy.SetProxy("127.0.0.1:9050", false); err != nil {
+		t.Fatal(err)
+	}
+	defer modules.GlobalProxy.SetProxy("", false)
+
+	hdb := &HostDB{staticDeps: &testNoLookupDeps{t: t}}
+	ipNets, err := hdb.staticLookupIPNets("host1.com:1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ipNets) != 0 {
+		t.Fatal("expected no subnets for a hostname, got", ipNets)
+	}
+
+	// IP addresses still have a subnet.
+	ipNets, err = hdb.staticLookupIPNets("127.0.0.1:1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ipNets) != 1 || ipNets[0] != "127.0.0.0/24" {
+		t.Fatal("unexpected subnets", ipNets)
+	}
+}
@@ -91,6 +91,9 @@ func (hdb *HostDB) ProcessConsensusChange(cc modules.ConsensusChange) {
 	defer hdb.mu.Unlock()
 
 	// Update the hostdb's understanding of the block height.
+	if cc.SkipsBlocks(hdb.blockHeight) {
+		hdb.blockHeight = cc.OldHeight
+	}
 	for _, block := range cc.RevertedBlocks {
 		// Only doing the block check if the height is above zero saves hashing
 		// and saves a nontrivial amount of time during IBD.
@@ -241,18 +241,45 @@ func (r *Renter) managedCalculateAndUpdateFileMetadata(siaPath modules.SiaPath)
 	}
 	defer sf.Close()
 
+	// The health of a packed file is the health of its pack.
+	dataEntry := sf
+	pack, _, err := r.managedOpenPack(sf)
+	if err != nil {
+		return siafile.BubbledMetadata{}, err
+	}
+	if pack != nil {
+		defer pack.Close()
+		dataEntry = pack
+	}
+
 	// Get offline and goodforrenew maps
-	hostOfflineMap, hostGoodForRenewMap, _ := r.managedRenterContractsAndUtilities([]*filesystem.FileNode{sf})
+	hostOfflineMap, hostGoodForRenewMap, _ := r.managedRenterContractsAndUtilities([]*filesystem.FileNode{dataEntry})
+
+	// Calculate file health. Pieces on hosts which the directory policy
+	// doesn't allow don't count towards the health.
+	policy, err := r.managedFilePolicy(siaPath)
+	if err != nil {
+		return siafile.BubbledMetadata{}, err
+	}
+	health, stuckHealth, _, _, numStuckChunks := dataEntry.Health(hostOfflineMap, policyGoodForRenew(policy, hostGoodForRenewMap))
 
-	// Calculate file health
-	health, stuckHealth, _, _, numStuckChunks := sf.Health(hostOfflineMap, hostGoodForRenewMap)
+	// The compressed data of an upload is only kept until the file reaches
+	// full health.
+	if localPath := sf.LocalPath(); health == 0 && r.isCompressedUploadSource(localPath) {
+		if err := sf.SetLocalPath(""); err != nil {
+			return siafile.BubbledMetadata{}, err
+		}
+		if err := os.Remove(localPath); err != nil {
+			r.log.Println("WARN: unable to remove compressed data of a fully uploaded file:", err)
+		}
+	}
 
 	// Set the LastHealthCheckTime
 	sf.SetLastHealthCheckTime()
 
 	// Calculate file Redundancy and check if local file is missing and
 	// redundancy is less than one
-	redundancy, _, err := sf.Redundancy(hostOfflineMap, hostGoodForRenewMap)
+	redundancy, _, err := dataEntry.Redundancy(hostOfflineMap, hostGoodForRenewMap)
 	if err != nil {
 		return siafile.BubbledMetadata{}, err
 	}
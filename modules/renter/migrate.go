@@ -0,0 +1,354 @@
+package renter
+
+import (
+	"encoding/hex"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
+)
+
+const (
+	// migrationSuffix is appended to the hidden name of the temporary file a
+	// file is migrated to, followed by a random string. The migrated file
+	// replaces the original file once the upload is available on the
+	// network.
+	migrationSuffix = ".migrate-"
+)
+
+var (
+	// errMigrationCanceled is returned if a migration was canceled by the
+	// user.
+	errMigrationCanceled = errors.New("migration was canceled")
+
+	// errMigrationInProgress is returned if a file is already being migrated.
+	errMigrationInProgress = errors.New("file is already being migrated")
+
+	// errNoMigration is returned if a file is not being migrated.
+	errNoMigration = errors.New("file is not being migrated")
+)
+
+type (
+	// migration is a background job which re-encodes and re-encrypts the data
+	// of a file.
+	migration struct {
+		staticSiaPath     modules.SiaPath
+		staticErasureCode modules.ErasureCoder
+		staticCipherType  crypto.CipherType
+		staticSize        uint64
+		staticStartTime   time.Time
+
+		// staticCancel is closed when the migration is canceled.
+		staticCancel chan struct{}
+
+		// The following fields are protected by mu.
+		canceled bool
+		endTime  time.Time
+		err      error
+		migrated uint64
+		mu       sync.Mutex
+	}
+
+	// migrationSet tracks the migrations of the renter. Finished migrations
+	// are kept until the same file is migrated again.
+	migrationSet struct {
+		migrations map[modules.SiaPath]*migration
+		mu         sync.Mutex
+	}
+
+	// migrationReader wraps the stream of a file that is being migrated. It
+	// tracks the progress of the migration and aborts reading once the
+	// migration is canceled.
+	migrationReader struct {
+		r io.Reader
+		m *migration
+	}
+)
+
+// newMigrationSet creates an empty migrationSet.
+func newMigrationSet() *migrationSet {
+	return &migrationSet{
+		migrations: make(map[modules.SiaPath]*migration),
+	}
+}
+
+// Read implements io.Reader.
+func (mr *migrationReader) Read(b []byte) (int, error) {
+	select {
+	case <-mr.m.staticCancel:
+		return 0, errMigrationCanceled
+	default:
+	}
+	n, err := mr.r.Read(b)
+	mr.m.mu.Lock()
+	mr.m.migrated += uint64(n)
+	mr.m.mu.Unlock()
+	return n, err
+}
+
+// managedCancel cancels the migration.
+func (m *migration) managedCancel() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.endTime.IsZero() || m.canceled {
+		return errNoMigration
+	}
+	m.canceled = true
+	close(m.staticCancel)
+	return nil
+}
+
+// managedFinish marks the migration as finished.
+func (m *migration) managedFinish(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endTime = time.Now()
+	m.err = err
+}
+
+// managedStatus returns the status of the migration.
+func (m *migration) managedStatus() modules.FileMigration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fm := modules.FileMigration{
+		SiaPath:      m.staticSiaPath,
+		DataPieces:   m.staticErasureCode.MinPieces(),
+		ParityPieces: m.staticErasureCode.NumPieces() - m.staticErasureCode.MinPieces(),
+		CipherType:   m.staticCipherType.String(),
+		Size:         m.staticSize,
+		Migrated:     m.migrated,
+		StartTime:    m.staticStartTime,
+		EndTime:      m.endTime,
+		Completed:    !m.endTime.IsZero() && m.err == nil,
+		Canceled:     m.canceled,
+	}
+	if m.err != nil {
+		fm.Error = m.err.Error()
+	}
+	return fm
+}
+
+// managedAdd adds a migration to the set. It fails if the file is already
+// being migrated.
+func (ms *migrationSet) managedAdd(m *migration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	old, exists := ms.migrations[m.staticSiaPath]
+	if exists {
+		old.mu.Lock()
+		finished := !old.endTime.IsZero()
+		old.mu.Unlock()
+		if !finished {
+			return errMigrationInProgress
+		}
+	}
+	ms.migrations[m.staticSiaPath] = m
+	return nil
+}
+
+// managedCancel cancels the migration of the file with the given siapath.
+func (ms *migrationSet) managedCancel(siaPath modules.SiaPath) error {
+	ms.mu.Lock()
+	m, exists := ms.migrations[siaPath]
+	ms.mu.Unlock()
+	if !exists {
+		return errNoMigration
+	}
+	return m.managedCancel()
+}
+
+// managedStatus returns the status of all the migrations sorted by their
+// start time.
+func (ms *migrationSet) managedStatus() []modules.FileMigration {
+	ms.mu.Lock()
+	migrations := make([]*migration, 0, len(ms.migrations))
+	for _, m := range ms.migrations {
+		migrations = append(migrations, m)
+	}
+	ms.mu.Unlock()
+
+	fms := make([]modules.FileMigration, 0, len(migrations))
+	for _, m := range migrations {
+		fms = append(fms, m.managedStatus())
+	}
+	sort.Slice(fms, func(i, j int) bool {
+		return fms[i].StartTime.Before(fms[j].StartTime)
+	})
+	return fms
+}
+
+// managedNewMigration creates a migration for a file and adds it to the
+// migration set. A nil erasure code or an invalid cipher type keep the
+// current settings of the file.
+func (r *Renter) managedNewMigration(siaPath modules.SiaPath, ec modules.ErasureCoder, ct crypto.CipherType) (*migration, error) {
+	entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to open file")
+	}
+	defer entry.Close()
+	if ec == nil {
+		ec = entry.ErasureCode()
+	}
+	if ct == crypto.TypeInvalid {
+		ct = entry.MasterKey().Type()
+	}
+	m := &migration{
+		staticSiaPath:     siaPath,
+		staticErasureCode: ec,
+		staticCipherType:  ct,
+		staticSize:        entry.UncompressedSize(),
+		staticStartTime:   time.Now(),
+		staticCancel:      make(chan struct{}),
+	}
+	if err := r.staticMigrations.managedAdd(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// managedMigrateFile migrates a file and blocks until the migrated file
+// replaced the original.
+func (r *Renter) managedMigrateFile(siaPath modules.SiaPath, ec modules.ErasureCoder, ct crypto.CipherType) error {
+	m, err := r.managedNewMigration(siaPath, ec, ct)
+	if err != nil {
+		return err
+	}
+	err = r.managedRunMigration(m)
+	m.managedFinish(err)
+	return err
+}
+
+// managedRunMigration runs a migration. The data of the file is streamed from
+// the hosts, re-encoded, re-encrypted and uploaded to a temporary file. Once
+// the upload is available on the network, the temporary file atomically
+// replaces the original file.
+func (r *Renter) managedRunMigration(m *migration) (err error) {
+	siaPath := m.staticSiaPath
+	dirSiaPath, err := siaPath.Dir()
+	if err != nil {
+		return err
+	}
+	tmpName := "." + siaPath.Name() + migrationSuffix + hex.EncodeToString(fastrand.Bytes(8))
+	tmpSiaPath, err := dirSiaPath.Join(tmpName)
+	if err != nil {
+		return err
+	}
+
+	// Open the file and a streamer for its data.
+	entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to open file")
+	}
+	up := modules.FileUploadParams{
+		Source:      entry.LocalPath(),
+		SiaPath:     tmpSiaPath,
+		ErasureCode: m.staticErasureCode,
+		CipherType:  m.staticCipherType,
+		Compression: entry.CompressionType(),
+		Deduplicate: entry.Deduplicate(),
+	}
+	s, err := r.StreamerByNode(entry, false)
+	if err != nil {
+		return errors.Compose(errors.AddContext(err, "unable to open streamer"), entry.Close())
+	}
+
+	// Clean up the temporary file if the migration fails. A file which
+	// already existed at the temporary path is never touched.
+	defer func() {
+		if err == nil || errors.Contains(err, filesystem.ErrExists) {
+			return
+		}
+		if errDel := r.DeleteFile(tmpSiaPath); errDel != nil && !errors.Contains(errDel, filesystem.ErrNotExist) {
+			err = errors.Compose(err, errors.AddContext(errDel, "unable to delete temporary file"))
+		}
+	}()
+
+	// Upload the data to the temporary file.
+	fileNode, err := r.callUploadStreamFromReader(up, &migrationReader{r: s, m: m})
+	err = errors.Compose(err, s.Close(), entry.Close())
+	if fileNode != nil {
+		err = errors.Compose(err, fileNode.Close())
+	}
+	if err != nil {
+		return errors.AddContext(err, "unable to upload migrated file")
+	}
+
+	// Check for cancellation one last time before replacing the original.
+	select {
+	case <-m.staticCancel:
+		return errMigrationCanceled
+	default:
+	}
+
+	// Replace the original file. The deduplicated chunks and the pack of the
+	// original file are released afterwards.
+	dedupChunks, err := r.managedDedupChunks(siaPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to get deduplicated chunks of the original file")
+	}
+	uid, packInfo, packed, err := r.managedPackOfFile(siaPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to get pack of the original file")
+	}
+	err = r.staticFileSystem.ReplaceFile(tmpSiaPath, siaPath)
+	if err != nil {
+		return errors.AddContext(err, "unable to replace original file")
+	}
+	r.managedReleaseDedupChunks(dedupChunks)
+	if packed {
+		r.managedReleasePack(uid, packInfo)
+	}
+	go r.callThreadedBubbleMetadata(dirSiaPath)
+	return nil
+}
+
+// CancelMigration cancels the migration of a file.
+func (r *Renter) CancelMigration(siaPath modules.SiaPath) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	return r.staticMigrations.managedCancel(siaPath)
+}
+
+// MigrateFile re-encodes and re-encrypts a file in the background using the
+// given erasure code and cipher type. A nil erasure code or an invalid cipher
+// type keep the current settings of the file.
+func (r *Renter) MigrateFile(siaPath modules.SiaPath, ec modules.ErasureCoder, ct crypto.CipherType) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	if ct != crypto.TypeInvalid && ct != crypto.TypeThreefish && ct != crypto.TypeXChaCha20 {
+		return crypto.ErrInvalidCipherType
+	}
+	m, err := r.managedNewMigration(siaPath, ec, ct)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := r.tg.Add(); err != nil {
+			m.managedFinish(err)
+			return
+		}
+		defer r.tg.Done()
+		err := r.managedRunMigration(m)
+		if err != nil {
+			r.log.Printf("WARN: migration of %v failed: %v", siaPath, err)
+		}
+		m.managedFinish(err)
+	}()
+	return nil
+}
+
+// Migrations returns the status of the file migrations.
+func (r *Renter) Migrations() []modules.FileMigration {
+	return r.staticMigrations.managedStatus()
+}
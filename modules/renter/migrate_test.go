@@ -0,0 +1,109 @@
+package renter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+)
+
+// newTestMigration creates a migration for testing.
+func newTestMigration(t *testing.T, siaPath modules.SiaPath, size uint64) *migration {
+	ec, err := siafile.NewRSSubCode(2, 3, crypto.SegmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &migration{
+		staticSiaPath:     siaPath,
+		staticErasureCode: ec,
+		staticCipherType:  crypto.TypeXChaCha20,
+		staticSize:        size,
+		staticStartTime:   time.Now(),
+		staticCancel:      make(chan struct{}),
+	}
+}
+
+// TestMigrationReader checks that the migrationReader tracks the progress of
+// a migration and stops reading once the migration is canceled.
+func TestMigrationReader(t *testing.T) {
+	data := fastrand.Bytes(100)
+	m := newTestMigration(t, modules.RandomSiaPath(), uint64(len(data)))
+	mr := &migrationReader{r: bytes.NewReader(data), m: m}
+
+	// Read half the data.
+	b := make([]byte, len(data)/2)
+	if _, err := mr.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if status := m.managedStatus(); status.Migrated != uint64(len(b)) {
+		t.Fatalf("expected %v bytes to be migrated but got %v", len(b), status.Migrated)
+	}
+
+	// Cancel the migration. Reading should fail.
+	if err := m.managedCancel(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadAll(mr); !errors.Contains(err, errMigrationCanceled) {
+		t.Fatal("expected migration to be canceled", err)
+	}
+	if err := m.managedCancel(); !errors.Contains(err, errNoMigration) {
+		t.Fatal("canceling twice should fail", err)
+	}
+	m.managedFinish(errMigrationCanceled)
+	status := m.managedStatus()
+	if !status.Canceled || status.Completed || status.Error == "" {
+		t.Fatal("unexpected status", status)
+	}
+	if status.DataPieces != 2 || status.ParityPieces != 3 || status.CipherType != crypto.TypeXChaCha20.String() {
+		t.Fatal("unexpected migration parameters", status)
+	}
+}
+
+// TestMigrationSet probes the migrationSet.
+func TestMigrationSet(t *testing.T) {
+	ms := newMigrationSet()
+	siaPath := modules.RandomSiaPath()
+
+	// Canceling an unknown migration fails.
+	if err := ms.managedCancel(siaPath); !errors.Contains(err, errNoMigration) {
+		t.Fatal("expected errNoMigration", err)
+	}
+
+	// The same file can't be migrated twice at the same time.
+	m := newTestMigration(t, siaPath, 0)
+	if err := ms.managedAdd(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := ms.managedAdd(newTestMigration(t, siaPath, 0)); !errors.Contains(err, errMigrationInProgress) {
+		t.Fatal("expected errMigrationInProgress", err)
+	}
+
+	// Once the migration is finished, it can't be canceled anymore and the
+	// file can be migrated again.
+	m.managedFinish(nil)
+	if err := ms.managedCancel(siaPath); !errors.Contains(err, errNoMigration) {
+		t.Fatal("expected errNoMigration", err)
+	}
+	if status := ms.managedStatus(); len(status) != 1 || !status[0].Completed {
+		t.Fatal("unexpected status", status)
+	}
+	if err := ms.managedAdd(newTestMigration(t, siaPath, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrations are sorted by their start time.
+	if err := ms.managedAdd(newTestMigration(t, modules.RandomSiaPath(), 0)); err != nil {
+		t.Fatal(err)
+	}
+	status := ms.managedStatus()
+	if len(status) != 2 || !status[0].SiaPath.Equals(siaPath) {
+		t.Fatal("unexpected status", status)
+	}
+}
@@ -0,0 +1,519 @@
+package renter
+
+// packing.go uploads many small files by packing their data into a shared
+// SiaFile, the pack. Every file is placed into a single sector of the pack
+// using modules.PackFiles. The packed files themselves don't have any chunks.
+// Instead their metadata points at their data within the pack. Downloads and
+// streams of packed files are served from the pack and the pack is repaired
+// like any other file.
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+	"gitlab.com/scpcorp/ScPrime/persist"
+)
+
+const (
+	// packIndexFilename is the filename of the renter's pack index.
+	packIndexFilename = "packs.json"
+)
+
+var (
+	// errNoPackedFiles is returned if a packed upload doesn't contain any
+	// files.
+	errNoPackedFiles = errors.New("no files to pack were provided")
+
+	// packIndexMetadata is the header of the persisted pack index.
+	packIndexMetadata = persist.Metadata{
+		Header:  "Renter Pack Index",
+		Version: "1.5.4",
+	}
+)
+
+type (
+	// packIndex tracks the packed files which reference the data of a pack.
+	// Once a pack isn't referenced anymore it is deleted.
+	packIndex struct {
+		packs      map[string]map[siafile.SiafileUID]struct{}
+		staticPath string
+		mu         sync.Mutex
+	}
+
+	// packSource is a local file which is packed into a pack.
+	packSource struct {
+		path    string
+		siaPath modules.SiaPath
+		mode    os.FileMode
+		offset  uint64
+		size    uint64
+	}
+
+	// packReader reads the data of a pack. It concatenates the packed files at
+	// their offsets and fills the gaps between them with zeros.
+	packReader struct {
+		sources []packSource
+		next    int
+		file    *os.File
+		offset  uint64
+	}
+
+	// packedStreamer is a modules.Streamer for a packed file. It reads the
+	// file's section of the data of its pack.
+	packedStreamer struct {
+		staticStreamer modules.Streamer
+		staticOffset   int64
+		staticSize     int64
+		offset         int64
+		mu             sync.Mutex
+	}
+)
+
+// newPackIndex loads the pack index at the given path or creates a new one if
+// it doesn't exist yet.
+func newPackIndex(path string) (*packIndex, error) {
+	pi := &packIndex{
+		packs:      make(map[string]map[siafile.SiafileUID]struct{}),
+		staticPath: path,
+	}
+	err := persist.LoadJSON(packIndexMetadata, &pi.packs, path)
+	if os.IsNotExist(err) {
+		return pi, nil
+	}
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to load pack index")
+	}
+	return pi, nil
+}
+
+// managedAddRefs adds references from the packed files with the given UIDs to
+// a pack.
+func (pi *packIndex) managedAddRefs(pack modules.SiaPath, uids []siafile.SiafileUID) error {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	refs, exists := pi.packs[pack.String()]
+	if !exists {
+		refs = make(map[siafile.SiafileUID]struct{})
+		pi.packs[pack.String()] = refs
+	}
+	for _, uid := range uids {
+		refs[uid] = struct{}{}
+	}
+	return pi.save()
+}
+
+// managedRemoveRef removes the reference of a packed file from its pack. It
+// returns whether the pack is still referenced by other packed files.
+func (pi *packIndex) managedRemoveRef(pack modules.SiaPath, uid siafile.SiafileUID) (bool, error) {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+	refs, exists := pi.packs[pack.String()]
+	if !exists {
+		return false, nil
+	}
+	delete(refs, uid)
+	if len(refs) > 0 {
+		return true, pi.save()
+	}
+	delete(pi.packs, pack.String())
+	return false, pi.save()
+}
+
+// save persists the pack index to disk.
+func (pi *packIndex) save() error {
+	return persist.SaveJSON(packIndexMetadata, pi.packs, pi.staticPath)
+}
+
+// newPackReader creates a reader for the data of a pack.
+func newPackReader(sources []packSource) *packReader {
+	sorted := append([]packSource{}, sources...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].offset < sorted[j].offset
+	})
+	return &packReader{
+		sources: sorted,
+	}
+}
+
+// Close closes the currently opened source.
+func (pr *packReader) Close() error {
+	if pr.file == nil {
+		return nil
+	}
+	err := pr.file.Close()
+	pr.file = nil
+	return err
+}
+
+// Read implements io.Reader.
+func (pr *packReader) Read(b []byte) (int, error) {
+	if pr.next >= len(pr.sources) {
+		return 0, io.EOF
+	}
+	src := pr.sources[pr.next]
+
+	// Fill the gap before the next source with zeros.
+	if pr.offset < src.offset {
+		n := src.offset - pr.offset
+		if n > uint64(len(b)) {
+			n = uint64(len(b))
+		}
+		for i := range b[:n] {
+			b[i] = 0
+		}
+		pr.offset += n
+		return int(n), nil
+	}
+
+	// Read from the source.
+	if pr.file == nil {
+		f, err := os.Open(src.path)
+		if err != nil {
+			return 0, errors.AddContext(err, "unable to open packed file")
+		}
+		pr.file = f
+	}
+	remaining := src.offset + src.size - pr.offset
+	if remaining < uint64(len(b)) {
+		b = b[:remaining]
+	}
+	n, err := pr.file.Read(b)
+	pr.offset += uint64(n)
+	if err == io.EOF && pr.offset < src.offset+src.size {
+		return n, errors.AddContext(io.ErrUnexpectedEOF, fmt.Sprintf("packed file %v shrunk", src.path))
+	} else if err != nil && err != io.EOF {
+		return n, err
+	}
+	if pr.offset == src.offset+src.size {
+		pr.next++
+		if err := pr.Close(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// newPackedStreamer creates a streamer for the section of a pack's data which
+// belongs to a packed file.
+func newPackedStreamer(s modules.Streamer, offset, size uint64) *packedStreamer {
+	return &packedStreamer{
+		staticStreamer: s,
+		staticOffset:   int64(offset),
+		staticSize:     int64(size),
+	}
+}
+
+// Close closes the underlying streamer.
+func (ps *packedStreamer) Close() error {
+	return ps.staticStreamer.Close()
+}
+
+// Read implements the io.Reader interface.
+func (ps *packedStreamer) Read(b []byte) (int, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.offset >= ps.staticSize {
+		return 0, io.EOF
+	}
+	if _, err := ps.staticStreamer.Seek(ps.staticOffset+ps.offset, io.SeekStart); err != nil {
+		return 0, errors.AddContext(err, "failed to seek within pack")
+	}
+	if remaining := ps.staticSize - ps.offset; remaining < int64(len(b)) {
+		b = b[:remaining]
+	}
+	n, err := ps.staticStreamer.Read(b)
+	ps.offset += int64(n)
+	if err == io.EOF && ps.offset < ps.staticSize {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// Seek sets the offset for the next Read to offset, interpreted according to
+// whence. The offset is relative to the packed file.
+func (ps *packedStreamer) Seek(offset int64, whence int) (int64, error) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = offset
+	case io.SeekCurrent:
+		newOffset = ps.offset + offset
+	case io.SeekEnd:
+		newOffset = ps.staticSize + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if newOffset < 0 {
+		return 0, errors.New("cannot seek to negative offset")
+	}
+	ps.offset = newOffset
+	return newOffset, nil
+}
+
+// managedOpenPack opens the pack of a packed file. The caller is responsible
+// for closing the returned node. If the file is not packed, nil is returned.
+func (r *Renter) managedOpenPack(entry *filesystem.FileNode) (*filesystem.FileNode, siafile.PackedFileInfo, error) {
+	info, packed := entry.PackedFile()
+	if !packed {
+		return nil, siafile.PackedFileInfo{}, nil
+	}
+	pack, err := r.staticFileSystem.OpenSiaFile(info.Pack)
+	if err != nil {
+		return nil, siafile.PackedFileInfo{}, errors.AddContext(err, "unable to open pack of packed file")
+	}
+	return pack, info, nil
+}
+
+// managedPackOfFile returns the UID of a file and the location of its data
+// within its pack if the file is packed.
+func (r *Renter) managedPackOfFile(siaPath modules.SiaPath) (siafile.SiafileUID, siafile.PackedFileInfo, bool, error) {
+	entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return "", siafile.PackedFileInfo{}, false, err
+	}
+	info, packed := entry.PackedFile()
+	uid := entry.UID()
+	return uid, info, packed, entry.Close()
+}
+
+// managedPackedFileInfo replaces the health related fields of a packed file's
+// FileInfo with the ones of its pack. The health of a packed file is the
+// health of its pack. Pack infos are cached in packs to avoid looking up the
+// same pack multiple times.
+func (r *Renter) managedPackedFileInfo(fi modules.FileInfo, packs map[modules.SiaPath]modules.FileInfo, offline, goodForRenew map[string]bool, contracts map[string]modules.RenterContract, cached bool) (modules.FileInfo, error) {
+	if !fi.Packed {
+		return fi, nil
+	}
+	entry, err := r.staticFileSystem.OpenSiaFile(fi.SiaPath)
+	if err != nil {
+		return modules.FileInfo{}, err
+	}
+	info, _ := entry.PackedFile()
+	if err := entry.Close(); err != nil {
+		return modules.FileInfo{}, err
+	}
+	pfi, exists := packs[info.Pack]
+	if !exists {
+		if cached {
+			pfi, err = r.staticFileSystem.CachedFileInfo(info.Pack)
+		} else {
+			pfi, err = r.staticFileSystem.FileInfo(info.Pack, offline, goodForRenew, contracts)
+		}
+		if err != nil {
+			return modules.FileInfo{}, errors.AddContext(err, "unable to get info of pack")
+		}
+		packs[info.Pack] = pfi
+	}
+	fi.Available = pfi.Available
+	fi.Expiration = pfi.Expiration
+	fi.Health = pfi.Health
+	fi.MaxHealth = pfi.MaxHealth
+	fi.MaxHealthPercent = pfi.MaxHealthPercent
+	fi.NumStuckChunks = pfi.NumStuckChunks
+	fi.Recoverable = fi.OnDisk || pfi.Recoverable
+	fi.Redundancy = pfi.Redundancy
+	fi.Stuck = pfi.Stuck
+	fi.StuckHealth = pfi.StuckHealth
+	fi.UploadProgress = pfi.UploadProgress
+	return fi, nil
+}
+
+// managedReleasePack removes the reference of a deleted packed file from its
+// pack. The pack is deleted once it isn't referenced anymore.
+func (r *Renter) managedReleasePack(uid siafile.SiafileUID, info siafile.PackedFileInfo) {
+	referenced, err := r.staticPackIndex.managedRemoveRef(info.Pack, uid)
+	if err != nil {
+		r.log.Printf("WARN: unable to remove %v from the pack index: %v", uid, err)
+	}
+	if referenced {
+		return
+	}
+	err = r.staticFileSystem.DeleteFile(info.Pack)
+	if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+		r.log.Printf("WARN: unable to delete unreferenced pack %v: %v", info.Pack, err)
+		return
+	}
+	go r.callThreadedBubbleMetadata(modules.PackFolder)
+}
+
+// managedPackSources validates the sources of a packed upload and determines
+// the siapaths of the packed files.
+func (r *Renter) managedPackSources(params modules.PackedUploadParams) ([]packSource, error) {
+	if len(params.Sources) == 0 {
+		return nil, errNoPackedFiles
+	}
+	sources := make([]packSource, 0, len(params.Sources))
+	siaPaths := make(map[modules.SiaPath]struct{})
+	for _, path := range params.Sources {
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("source %v must be an absolute path", path)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to stat input file")
+		}
+		if fi.IsDir() {
+			return nil, ErrUploadDirectory
+		}
+		if fi.Size() == 0 {
+			return nil, errors.AddContext(modules.ErrZeroSize, path)
+		}
+		if uint64(fi.Size()) > modules.SectorSize {
+			return nil, errors.AddContext(modules.ErrSizeTooLarge, path)
+		}
+		siaPath, err := params.SiaPath.Join(filepath.Base(path))
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := siaPaths[siaPath]; exists {
+			return nil, fmt.Errorf("multiple files would be uploaded to %v", siaPath)
+		}
+		siaPaths[siaPath] = struct{}{}
+		if !params.Force {
+			if _, err := r.staticFileSystem.Stat(siaPath); err == nil {
+				return nil, errors.AddContext(filesystem.ErrExists, siaPath.String())
+			}
+		}
+		sources = append(sources, packSource{
+			path:    path,
+			siaPath: siaPath,
+			mode:    fi.Mode(),
+			size:    uint64(fi.Size()),
+		})
+	}
+	return sources, nil
+}
+
+// UploadPacked uploads many small files by packing them into the sectors of a
+// shared pack. The call blocks until the pack is available on the network and
+// the packed files were created.
+func (r *Renter) UploadPacked(params modules.PackedUploadParams) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+
+	sources, err := r.managedPackSources(params)
+	if err != nil {
+		return err
+	}
+	if params.ErasureCode == nil {
+		params.ErasureCode, err = r.managedDefaultErasureCode(sources[0].siaPath)
+		if err != nil {
+			return errors.AddContext(err, "unable to determine erasure code")
+		}
+	}
+	if params.CipherType == crypto.TypeInvalid {
+		params.CipherType = crypto.TypeDefaultRenter
+	}
+
+	// Pack the files into sectors. The data of a sector is stored at the
+	// offset of the sector within the pack.
+	sizes := make(map[string]uint64, len(sources))
+	for _, src := range sources {
+		sizes[src.path] = src.size
+	}
+	placements, _, err := modules.PackFiles(sizes)
+	if err != nil {
+		return errors.AddContext(err, "unable to pack files")
+	}
+	offsets := make(map[string]uint64, len(placements))
+	for _, p := range placements {
+		offsets[p.FileID] = p.SectorIndex*modules.SectorSize + p.SectorOffset
+	}
+	for i := range sources {
+		sources[i].offset = offsets[sources[i].path]
+	}
+
+	// Upload the pack.
+	packSiaPath, err := modules.PackFolder.Join(hex.EncodeToString(fastrand.Bytes(16)))
+	if err != nil {
+		return err
+	}
+	pr := newPackReader(sources)
+	pack, err := r.callUploadStreamFromReader(modules.FileUploadParams{
+		SiaPath:             packSiaPath,
+		ErasureCode:         params.ErasureCode,
+		CipherType:          params.CipherType,
+		DisablePartialChunk: true,
+	}, pr)
+	err = errors.Compose(err, pr.Close())
+	if pack != nil {
+		err = errors.Compose(err, pack.Close())
+	}
+	if err != nil {
+		return errors.AddContext(err, "unable to upload pack")
+	}
+
+	// Create the packed files.
+	uids := make([]siafile.SiafileUID, 0, len(sources))
+	dirs := make(map[modules.SiaPath]struct{})
+	for _, src := range sources {
+		uid, err := r.managedCreatePackedFile(src, packSiaPath, params)
+		if err != nil {
+			err = errors.AddContext(err, fmt.Sprintf("unable to create packed file %v", src.siaPath))
+			// Keep the pack if some files were already created.
+			if len(uids) == 0 {
+				err = errors.Compose(err, r.staticFileSystem.DeleteFile(packSiaPath))
+			}
+			return errors.Compose(err, r.staticPackIndex.managedAddRefs(packSiaPath, uids))
+		}
+		uids = append(uids, uid)
+		dir, err := src.siaPath.Dir()
+		if err == nil {
+			dirs[dir] = struct{}{}
+		}
+	}
+	if err := r.staticPackIndex.managedAddRefs(packSiaPath, uids); err != nil {
+		return errors.AddContext(err, "unable to update pack index")
+	}
+	for dir := range dirs {
+		go r.callThreadedBubbleMetadata(dir)
+	}
+	go r.callThreadedBubbleMetadata(modules.PackFolder)
+	return nil
+}
+
+// managedCreatePackedFile creates the SiaFile of a packed file which points at
+// its data within the pack.
+func (r *Renter) managedCreatePackedFile(src packSource, pack modules.SiaPath, params modules.PackedUploadParams) (siafile.SiafileUID, error) {
+	if params.Force {
+		err := r.DeleteFile(src.siaPath)
+		if err != nil && !errors.Contains(err, filesystem.ErrNotExist) {
+			return "", errors.AddContext(err, "unable to delete existing file")
+		}
+	}
+	cipherKey := crypto.GenerateSiaKey(params.CipherType)
+	err := r.staticFileSystem.NewSiaFile(src.siaPath, src.path, params.ErasureCode, cipherKey, 0, src.mode, true)
+	if err != nil {
+		return "", err
+	}
+	entry, err := r.staticFileSystem.OpenSiaFile(src.siaPath)
+	if err != nil {
+		return "", err
+	}
+	err = entry.SetPackedFile(siafile.PackedFileInfo{
+		Pack:   pack,
+		Offset: src.offset,
+	}, src.size)
+	uid := entry.UID()
+	err = errors.Compose(err, entry.Close())
+	if err != nil {
+		return "", errors.Compose(err, r.staticFileSystem.DeleteFile(src.siaPath))
+	}
+	return uid, nil
+}
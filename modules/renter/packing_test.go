@@ -0,0 +1,155 @@
+package renter
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
+)
+
+// TestPackReader checks that the packReader places the packed files at their
+// offsets and fills the gaps with zeros.
+func TestPackReader(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create two files and place them out of order with a gap in between.
+	data1, data2 := fastrand.Bytes(100), fastrand.Bytes(50)
+	path1, path2 := filepath.Join(dir, "file1"), filepath.Join(dir, "file2")
+	if err := ioutil.WriteFile(path1, data1, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path2, data2, 0600); err != nil {
+		t.Fatal(err)
+	}
+	pr := newPackReader([]packSource{
+		{path: path2, offset: 200, size: uint64(len(data2))},
+		{path: path1, offset: 0, size: uint64(len(data1))},
+	})
+	data, err := ioutil.ReadAll(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := append(append(data1, make([]byte, 100)...), data2...)
+	if !bytes.Equal(data, expected) {
+		t.Fatal("pack data doesn't match")
+	}
+
+	// A file that shrunk after it was packed can't be read.
+	pr = newPackReader([]packSource{{path: path1, size: uint64(len(data1)) + 1}})
+	if _, err := ioutil.ReadAll(pr); err == nil {
+		t.Fatal("expected reading a shrunk file to fail")
+	}
+	if err := pr.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPackedStreamer checks that a packedStreamer only exposes the section of
+// the pack which belongs to the packed file.
+func TestPackedStreamer(t *testing.T) {
+	pack := fastrand.Bytes(1000)
+	ps := newPackedStreamer(bytesStreamer{bytes.NewReader(pack)}, 300, 200)
+
+	// Read the whole file.
+	data, err := ioutil.ReadAll(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, pack[300:500]) {
+		t.Fatal("data doesn't match")
+	}
+
+	// Seek relative to the end of the file and read the remainder.
+	off, err := ps.Seek(-50, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != 150 {
+		t.Fatal("wrong offset", off)
+	}
+	data, err = ioutil.ReadAll(ps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, pack[450:500]) {
+		t.Fatal("data doesn't match after seeking")
+	}
+
+	// Seeking to a negative offset fails.
+	if _, err := ps.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("expected seeking to a negative offset to fail")
+	}
+	if err := ps.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPackIndex tests adding and removing references to packs and persisting
+// the index.
+func TestPackIndex(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, packIndexFilename)
+	pi, err := newPackIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack, err := modules.PackFolder.Join("pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid1, uid2 := siafile.SiafileUID("file1"), siafile.SiafileUID("file2")
+	if err := pi.managedAddRefs(pack, []siafile.SiafileUID{uid1, uid2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload the index and remove the references again. The pack should only
+	// be unreferenced after the last reference was removed.
+	pi, err = newPackIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	referenced, err := pi.managedRemoveRef(pack, uid1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !referenced {
+		t.Fatal("pack should still be referenced")
+	}
+	referenced, err = pi.managedRemoveRef(pack, uid2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if referenced {
+		t.Fatal("pack shouldn't be referenced anymore")
+	}
+	if len(pi.packs) != 0 {
+		t.Fatal("pack wasn't removed from the index")
+	}
+}
@@ -203,5 +203,9 @@ func (r *Renter) managedInitPersist() error {
 	if err != nil && err != filesystem.ErrExists {
 		return err
 	}
+	err = fs.NewSiaDir(modules.PackFolder, modules.DefaultDirPerm)
+	if err != nil && err != filesystem.ErrExists {
+		return err
+	}
 	return nil
 }
@@ -29,6 +29,12 @@ const (
 	decodeMaxSizeMultiplier = 3
 )
 
+var (
+	// ErrSectorNotFound is returned when a contract doesn't cover a sector
+	// with the requested root.
+	ErrSectorNotFound = errors.New("sector is not covered by the contract")
+)
+
 // updateInsertContract is an update that inserts a contract into the
 // contractset with the given header and roots.
 type updateInsertContract struct {
@@ -340,6 +346,72 @@ func (c *SafeContract) Utility() modules.ContractUtility {
 	return c.header.Utility
 }
 
+// SectorRefCount returns the number of references to the sector with the
+// given root.
+func (c *SafeContract) SectorRefCount(root crypto.Hash) (uint16, error) {
+	secIdx, err := c.managedSectorIndex(root)
+	if err != nil {
+		return 0, err
+	}
+	return c.staticRC.callCount(secIdx)
+}
+
+// IncrementSectorRefCount adds a reference to the sector with the given root.
+// It is used when a sector is shared between multiple files.
+func (c *SafeContract) IncrementSectorRefCount(root crypto.Hash) error {
+	_, err := c.managedUpdateSectorRefCount(root, c.staticRC.callIncrement)
+	return err
+}
+
+// DecrementSectorRefCount removes a reference from the sector with the given
+// root and returns the number of remaining references. Once that number drops
+// to zero the sector is considered garbage.
+func (c *SafeContract) DecrementSectorRefCount(root crypto.Hash) (uint16, error) {
+	return c.managedUpdateSectorRefCount(root, c.staticRC.callDecrement)
+}
+
+// managedSectorIndex returns the index of the sector with the given root
+// within the contract.
+func (c *SafeContract) managedSectorIndex(root crypto.Hash) (uint64, error) {
+	if c.staticRC == nil {
+		return 0, ErrRefCounterNotExist
+	}
+	c.mu.Lock()
+	i, exists, err := c.merkleRoots.index(root)
+	c.mu.Unlock()
+	if err != nil {
+		return 0, errors.AddContext(err, "failed to read the contract's merkle roots")
+	}
+	if !exists {
+		return 0, ErrSectorNotFound
+	}
+	return uint64(i), nil
+}
+
+// managedUpdateSectorRefCount applies the refcounter update created by
+// updateFn to the counter of the sector with the given root and returns the
+// sector's new count.
+func (c *SafeContract) managedUpdateSectorRefCount(root crypto.Hash, updateFn func(uint64) (writeaheadlog.Update, error)) (_ uint16, err error) {
+	secIdx, err := c.managedSectorIndex(root)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.staticRC.callStartUpdate(); err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = errors.Compose(err, c.staticRC.callUpdateApplied())
+	}()
+	u, err := updateFn(secIdx)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.staticRC.callCreateAndApplyTransaction(u); err != nil {
+		return 0, errors.AddContext(err, "failed to apply refcounter update")
+	}
+	return c.staticRC.callCount(secIdx)
+}
+
 // makeUpdateInsertContract creates a writeaheadlog.Update to insert a new
 // contract into the contractset.
 func makeUpdateInsertContract(h contractHeader, roots []crypto.Hash) (writeaheadlog.Update, error) {
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"gitlab.com/NebulousLabs/encoding"
+	"gitlab.com/NebulousLabs/errors"
 	"gitlab.com/NebulousLabs/fastrand"
 	"gitlab.com/NebulousLabs/ratelimit"
 
@@ -976,3 +977,78 @@ func TestContractRecordCommitRenewAndClearIntent(t *testing.T) {
 		t.Fatal("contract should be locked")
 	}
 }
+
+// TestContractSectorRefCount tests adding and removing references to the
+// sectors of a contract.
+func TestContractSectorRefCount(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// create a contract set
+	dir := build.TempDir(filepath.Join("proto", t.Name()))
+	rl := ratelimit.NewRateLimit(0, 0, 0)
+	cs, err := NewContractSet(dir, rl, modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// add a contract
+	header := contractHeader{
+		Transaction: types.Transaction{
+			FileContractRevisions: []types.FileContractRevision{{
+				NewRevisionNumber:    1,
+				NewValidProofOutputs: []types.SiacoinOutput{{}, {}},
+				UnlockConditions: types.UnlockConditions{
+					PublicKeys: []types.SiaPublicKey{{}, {}},
+				},
+			}},
+		},
+	}
+	roots := []crypto.Hash{{1}, {2}}
+	c, err := cs.managedInsertContract(header, roots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := cs.managedMustAcquire(t, c.ID)
+	defer cs.Return(sc)
+
+	// Every sector starts out with a single reference.
+	for _, root := range roots {
+		count, err := sc.SectorRefCount(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatalf("expected count 1 but was %v", count)
+		}
+	}
+	// Add a reference to the second sector.
+	if err := sc.IncrementSectorRefCount(roots[1]); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := sc.SectorRefCount(roots[1]); err != nil || count != 2 {
+		t.Fatal("unexpected count", count, err)
+	}
+	if count, err := sc.SectorRefCount(roots[0]); err != nil || count != 1 {
+		t.Fatal("unexpected count", count, err)
+	}
+	// Remove both references again.
+	for i := 1; i >= 0; i-- {
+		count, err := sc.DecrementSectorRefCount(roots[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != uint16(i) {
+			t.Fatalf("expected count %v but was %v", i, count)
+		}
+	}
+	// Removing another reference should fail.
+	if _, err := sc.DecrementSectorRefCount(roots[1]); err == nil {
+		t.Fatal("expected underflow")
+	}
+	// Unknown sectors can't be referenced.
+	if err := sc.IncrementSectorRefCount(crypto.Hash{3}); !errors.Contains(err, ErrSectorNotFound) {
+		t.Fatal("expected ErrSectorNotFound but got", err)
+	}
+}
@@ -231,10 +231,10 @@ func (cs *ContractSet) NewEditor(host modules.HostDBEntry, id types.FileContract
 // initiateRevisionLoop initiates either the editor or downloader loop with
 // host, depending on which rpc was passed.
 func initiateRevisionLoop(host modules.HostDBEntry, contract *SafeContract, rpc types.Specifier, cancel <-chan struct{}, rl *ratelimit.RateLimit) (net.Conn, chan struct{}, error) {
-	c, err := (&net.Dialer{
+	c, err := modules.GlobalProxy.Dial(&net.Dialer{
 		Cancel:  cancel,
 		Timeout: 45 * time.Second, // TODO: Constant
-	}).Dial("tcp", string(host.NetAddress))
+	}, host.NetAddress)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,112 @@
+package proto
+
+import (
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+var (
+	// ErrContractExists is returned when importing a contract that is already
+	// part of the set.
+	ErrContractExists = errors.New("contract already exists in the contract set")
+
+	// ErrUnknownContract is returned when exporting a contract that is not
+	// part of the set.
+	ErrUnknownContract = errors.New("contract not found in the contract set")
+)
+
+// ExportedContract contains everything that is required to move a contract
+// from one contract set into another. This includes the renter's secret key
+// for the contract, so it needs to be handled with the same care as a seed.
+type ExportedContract struct {
+	Transaction      types.Transaction
+	SecretKey        crypto.SecretKey
+	StartHeight      types.BlockHeight
+	DownloadSpending types.Currency
+	StorageSpending  types.Currency
+	UploadSpending   types.Currency
+	TotalCost        types.Currency
+	ContractFee      types.Currency
+	TxnFee           types.Currency
+	SiafundFee       types.Currency
+	Utility          modules.ContractUtility
+	MerkleRoots      []crypto.Hash
+}
+
+// ID returns the id of the exported contract.
+func (ec ExportedContract) ID() types.FileContractID {
+	h := ec.header()
+	return h.ID()
+}
+
+// HostPublicKey returns the public key of the exported contract's host.
+func (ec ExportedContract) HostPublicKey() types.SiaPublicKey {
+	h := ec.header()
+	return h.HostPublicKey()
+}
+
+// header converts the exported contract into a contractHeader.
+func (ec ExportedContract) header() contractHeader {
+	return contractHeader{
+		Transaction:      ec.Transaction,
+		SecretKey:        ec.SecretKey,
+		StartHeight:      ec.StartHeight,
+		DownloadSpending: ec.DownloadSpending,
+		StorageSpending:  ec.StorageSpending,
+		UploadSpending:   ec.UploadSpending,
+		TotalCost:        ec.TotalCost,
+		ContractFee:      ec.ContractFee,
+		TxnFee:           ec.TxnFee,
+		SiafundFee:       ec.SiafundFee,
+		Utility:          ec.Utility,
+	}
+}
+
+// ExportContract returns the header and sector roots of the contract with the
+// given id. The contract stays in the set.
+func (cs *ContractSet) ExportContract(id types.FileContractID) (ExportedContract, error) {
+	sc, ok := cs.Acquire(id)
+	if !ok {
+		return ExportedContract{}, ErrUnknownContract
+	}
+	defer cs.Return(sc)
+
+	roots, err := sc.merkleRoots.merkleRoots()
+	if err != nil {
+		return ExportedContract{}, errors.AddContext(err, "failed to read the contract's merkle roots")
+	}
+	h := sc.header
+	return ExportedContract{
+		Transaction:      h.copyTransaction(),
+		SecretKey:        h.SecretKey,
+		StartHeight:      h.StartHeight,
+		DownloadSpending: h.DownloadSpending,
+		StorageSpending:  h.StorageSpending,
+		UploadSpending:   h.UploadSpending,
+		TotalCost:        h.TotalCost,
+		ContractFee:      h.ContractFee,
+		TxnFee:           h.TxnFee,
+		SiafundFee:       h.SiafundFee,
+		Utility:          h.Utility,
+		MerkleRoots:      roots,
+	}, nil
+}
+
+// ImportContract inserts a previously exported contract into the set. Importing
+// a contract that is already part of the set is an error.
+func (cs *ContractSet) ImportContract(ec ExportedContract) (modules.RenterContract, error) {
+	h := ec.header()
+	if err := h.validate(); err != nil {
+		return modules.RenterContract{}, errors.AddContext(err, "invalid contract")
+	}
+	cs.mu.Lock()
+	_, exists := cs.contracts[h.ID()]
+	cs.mu.Unlock()
+	if exists {
+		return modules.RenterContract{}, ErrContractExists
+	}
+	return cs.managedInsertContract(h, ec.MerkleRoots)
+}
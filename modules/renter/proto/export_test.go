@@ -0,0 +1,105 @@
+package proto
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+	"gitlab.com/NebulousLabs/ratelimit"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestExportImportContract tests moving a contract from one contract set into
+// another.
+func TestExportImportContract(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	// Create two contract sets.
+	testDir := build.TempDir(t.Name())
+	rl := ratelimit.NewRateLimit(0, 0, 0)
+	src, err := NewContractSet(testDir+"/src", rl, modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := NewContractSet(testDir+"/dst", rl, modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert a contract with a few roots into the source set.
+	header := contractHeader{
+		Transaction: types.Transaction{
+			FileContractRevisions: []types.FileContractRevision{{
+				ParentID:             types.FileContractID{1},
+				NewValidProofOutputs: []types.SiacoinOutput{{}, {}},
+				UnlockConditions: types.UnlockConditions{
+					PublicKeys: []types.SiaPublicKey{{}, {}},
+				},
+			}},
+		},
+		StartHeight:     42,
+		StorageSpending: types.NewCurrency64(100),
+		TotalCost:       types.NewCurrency64(1000),
+		Utility:         modules.ContractUtility{GoodForUpload: true, GoodForRenew: true},
+	}
+	fastrand.Read(header.SecretKey[:])
+	roots := make([]crypto.Hash, 10)
+	for i := range roots {
+		roots[i] = crypto.Hash(fastrand.Bytes(crypto.HashSize))
+	}
+	if _, err := src.managedInsertContract(header, roots); err != nil {
+		t.Fatal(err)
+	}
+
+	// Exporting an unknown contract should fail.
+	if _, err := src.ExportContract(types.FileContractID{2}); !errors.Contains(err, ErrUnknownContract) {
+		t.Fatal("expected ErrUnknownContract, got", err)
+	}
+	ec, err := src.ExportContract(header.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ec.ID() != header.ID() {
+		t.Fatal("wrong id", ec.ID(), header.ID())
+	}
+	if !reflect.DeepEqual(ec.MerkleRoots, roots) {
+		t.Fatal("exported roots don't match")
+	}
+
+	// Import the contract into the destination set.
+	rc, err := dst.ImportContract(ec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.ID != header.ID() || rc.StartHeight != header.StartHeight {
+		t.Fatal("imported contract doesn't match", rc.ID, rc.StartHeight)
+	}
+	if !rc.StorageSpending.Equals(header.StorageSpending) || rc.Utility != header.Utility {
+		t.Fatal("imported contract metadata doesn't match")
+	}
+	// The roots should be available in the destination set.
+	sc := dst.managedMustAcquire(t, header.ID())
+	importedRoots, err := sc.merkleRoots.merkleRoots()
+	dst.Return(sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(importedRoots, roots) {
+		t.Fatal("imported roots don't match")
+	}
+	// Importing the contract a second time should fail.
+	if _, err := dst.ImportContract(ec); !errors.Contains(err, ErrContractExists) {
+		t.Fatal("expected ErrContractExists, got", err)
+	}
+	// An invalid contract should be rejected.
+	if _, err := dst.ImportContract(ExportedContract{}); err == nil {
+		t.Fatal("invalid contract shouldn't be importable")
+	}
+}
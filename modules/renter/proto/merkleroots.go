@@ -43,6 +43,12 @@ type (
 		rootsFile *fileSection
 		// numMerkleRoots is the number of merkle roots in file.
 		numMerkleRoots int
+
+		// rootIndices maps the roots to the index of their first occurrence.
+		// It is built the first time a root is looked up and kept up to date
+		// by push. Replacing a root drops it since the replaced root might
+		// occur again at a later index.
+		rootIndices map[crypto.Hash]int
 	}
 
 	// cachedSubTree is a cached subTree of a merkle tree. A height of 0 means
@@ -173,6 +179,7 @@ func (mr *merkleRoots) appendRootMemory(roots ...crypto.Hash) {
 // last root and truncates the file to truncateSize after that. This ensures
 // that the operation is indempotent.
 func (mr *merkleRoots) delete(i int, lastRoot crypto.Hash, truncateSize int64) error {
+	mr.rootIndices = nil
 	// Swap the element at index i with the lastRoot. This might actually
 	// increase mr.numMerkleRoots since there is a chance that i points to an
 	// index after the end of the file. That's why the insert is executed first
@@ -226,6 +233,7 @@ func (mr *merkleRoots) insert(index int, root crypto.Hash) error {
 		return mr.push(root)
 	}
 	// Replaced the root on disk.
+	mr.rootIndices = nil
 	_, err := mr.rootsFile.WriteAt(root[:], fileOffsetFromRootIndex(index))
 	if err != nil {
 		return errors.AddContext(err, "failed to insert root on disk")
@@ -245,6 +253,23 @@ func (mr *merkleRoots) insert(index int, root crypto.Hash) error {
 	return nil
 }
 
+// index returns the index of the first occurrence of root. The second return
+// value is false if the root doesn't exist.
+func (mr *merkleRoots) index(root crypto.Hash) (int, bool, error) {
+	if mr.rootIndices == nil {
+		roots, err := mr.merkleRoots()
+		if err != nil {
+			return 0, false, err
+		}
+		mr.rootIndices = make(map[crypto.Hash]int, len(roots))
+		for i := len(roots) - 1; i >= 0; i-- {
+			mr.rootIndices[roots[i]] = i
+		}
+	}
+	i, exists := mr.rootIndices[root]
+	return i, exists, nil
+}
+
 // isIndexCached determines if the root at index i is already cached in
 // mr.cachedSubTree or if it is still in mr.uncachedRoots. It will return true
 // or false and the index of the root in the corresponding data structure.
@@ -304,6 +329,9 @@ func (mr *merkleRoots) push(root crypto.Hash) error {
 	}
 	// Add the root to the unached roots.
 	mr.appendRootMemory(root)
+	if _, exists := mr.rootIndices[root]; !exists && mr.rootIndices != nil {
+		mr.rootIndices[root] = mr.numMerkleRoots
+	}
 
 	// Increment the number of roots.
 	mr.numMerkleRoots++
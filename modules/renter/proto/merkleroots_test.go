@@ -439,3 +439,82 @@ func TestMerkleRootsRandom(t *testing.T) {
 		}
 	}
 }
+
+// TestMerkleRootsIndex tests that the index of a root is looked up correctly
+// after pushing, inserting and deleting roots.
+func TestMerkleRootsIndex(t *testing.T) {
+	dir := build.TempDir(t.Name())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Create(path.Join(dir, "file.dat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// checkIndex checks the index of root against a linear search.
+	merkleRoots := newMerkleRoots(file)
+	checkIndex := func(root crypto.Hash) {
+		t.Helper()
+		roots, err := merkleRoots.merkleRoots()
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, expectedExists := 0, false
+		for i := range roots {
+			if roots[i] == root {
+				expected, expectedExists = i, true
+				break
+			}
+		}
+		i, exists, err := merkleRoots.index(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != expectedExists || i != expected {
+			t.Fatalf("expected index %v (%v) but got %v (%v)", expected, expectedExists, i, exists)
+		}
+	}
+
+	// Push some roots including a duplicate and look them up.
+	roots := make([]crypto.Hash, 10)
+	for i := range roots {
+		fastrand.Read(roots[i][:])
+		if err := merkleRoots.push(roots[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := merkleRoots.push(roots[3]); err != nil {
+		t.Fatal(err)
+	}
+	for _, root := range roots {
+		checkIndex(root)
+	}
+	checkIndex(crypto.Hash{1})
+
+	// Roots pushed after the lookup are found.
+	newRoot := crypto.Hash{2}
+	if err := merkleRoots.push(newRoot); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(newRoot)
+
+	// Replacing a duplicated root reveals its second occurrence.
+	if err := merkleRoots.insert(3, roots[0]); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(roots[3])
+	checkIndex(roots[0])
+
+	// Deleted roots are not found anymore.
+	lastRoot, truncateSize, err := merkleRoots.prepareDelete(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := merkleRoots.delete(5, lastRoot, truncateSize); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(roots[5])
+	checkIndex(newRoot)
+}
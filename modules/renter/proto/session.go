@@ -885,10 +885,10 @@ func (cs *ContractSet) managedNewSession(host modules.HostDBEntry, currentHeight
 		host.NetAddress = modules.NetAddress(fmt.Sprintf("127.0.0.1:%s", port))
 	}
 
-	c, err := (&net.Dialer{
+	c, err := modules.GlobalProxy.Dial(&net.Dialer{
 		Cancel:  cancel,
 		Timeout: sessionDialTimeout,
-	}).Dial("tcp", string(host.NetAddress))
+	}, host.NetAddress)
 	if err != nil {
 		return nil, errors.AddContext(err, "unsuccessful dial when creating a new session")
 	}
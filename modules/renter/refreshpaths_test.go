@@ -127,8 +127,8 @@ func TestAddUniqueRefreshPaths(t *testing.T) {
 		}
 		// Check that AggregateNumSubDirs equals the length of `paths`, minus
 		// the root directory, plus the standard directories `home`,
-		// `snapshots`, and `var`.
-		numSubDirs := len(paths) - 1 + 2
+		// `packs`, `snapshots`, and `var`.
+		numSubDirs := len(paths) - 1 + 3
 		if int(di[0].AggregateNumSubDirs) != numSubDirs {
 			return fmt.Errorf("Expected AggregateNumSubDirs to be %v but got %v", numSubDirs, di[0].AggregateNumSubDirs)
 		}
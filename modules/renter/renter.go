@@ -43,6 +43,7 @@ import (
 	"gitlab.com/scpcorp/ScPrime/modules/renter/contractor"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/hostdb"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/proto"
 	"gitlab.com/scpcorp/ScPrime/persist"
 	siasync "gitlab.com/scpcorp/ScPrime/sync"
 	"gitlab.com/scpcorp/ScPrime/types"
@@ -102,6 +103,22 @@ type hostContractor interface {
 	// began.
 	CurrentPeriod() types.BlockHeight
 
+	// DecrementSectorRefCount removes a reference from a sector stored on the
+	// host and returns the remaining number of references.
+	DecrementSectorRefCount(types.SiaPublicKey, crypto.Hash) (uint16, error)
+
+	// IncrementSectorRefCount adds a reference to a sector stored on the host.
+	IncrementSectorRefCount(types.SiaPublicKey, crypto.Hash) error
+
+	// ExportContracts returns the contracts with the given ids in a form that
+	// can be imported by another renter.
+	ExportContracts([]types.FileContractID) ([]proto.ExportedContract, error)
+
+	// ImportContracts adds previously exported contracts to the contractor
+	// and returns the imported contracts and the ones which couldn't be
+	// imported.
+	ImportContracts([]proto.ExportedContract) ([]modules.RenterContract, []modules.ContractImportError, error)
+
 	// InitRecoveryScan starts scanning the whole blockchain for recoverable
 	// contracts within a separate thread.
 	InitRecoveryScan() error
@@ -110,6 +127,9 @@ type hostContractor interface {
 	// billing period.
 	PeriodSpending() (modules.ContractorSpending, error)
 
+	// SpendingForecast projects the spending of the current billing period.
+	SpendingForecast() (modules.ContractorSpendingForecast, error)
+
 	//modules.PaymentProvider
 
 	// OldContracts returns the oldContracts of the renter's hostContractor.
@@ -217,9 +237,15 @@ type Renter struct {
 	repairLog             *persist.Logger
 	staticAccountManager  *accountManager
 	staticAlerter         *modules.GenericAlerter
+	staticBackupScheduler *backupScheduler
+	staticDedupIndex      *dedupIndex
+	staticDownloadQueue   *downloadQueue
 	staticFileSystem      *filesystem.FileSystem
 	staticFuseManager     renterFuseManager
+	staticMigrations      *migrationSet
+	staticPackIndex       *packIndex
 	staticStreamBufferSet *streamBufferSet
+	staticStreamTokens    *streamTokenSet
 	tg                    threadgroup.ThreadGroup
 	tpool                 modules.TransactionPool
 	wal                   *writeaheadlog.WAL
@@ -654,6 +680,24 @@ func (r *Renter) SetFilterMode(lm modules.FilterMode, hosts []types.SiaPublicKey
 	return nil
 }
 
+// HostPolicy returns the renter's hostdb host policy
+func (r *Renter) HostPolicy() (modules.HostPolicy, error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.HostPolicy{}, err
+	}
+	defer r.tg.Done()
+	return r.hostDB.HostPolicy()
+}
+
+// SetHostPolicy sets the renter's hostdb host policy
+func (r *Renter) SetHostPolicy(hp modules.HostPolicy) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	return r.hostDB.SetHostPolicy(hp)
+}
+
 // Host returns the host associated with the given public key
 func (r *Renter) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool, error) {
 	return r.hostDB.Host(spk)
@@ -668,6 +712,30 @@ func (r *Renter) ScoreBreakdown(e modules.HostDBEntry) (modules.HostScoreBreakdo
 	return r.hostDB.ScoreBreakdown(e)
 }
 
+// ScoreBreakdownWithWeights returns the score breakdown under the provided
+// scoring weights
+func (r *Renter) ScoreBreakdownWithWeights(e modules.HostDBEntry, w modules.HostScoringWeights) (modules.HostScoreBreakdown, error) {
+	return r.hostDB.ScoreBreakdownWithWeights(e, w)
+}
+
+// ScoringWeights returns the weights used by the hostdb to score hosts
+func (r *Renter) ScoringWeights() (modules.HostScoringWeights, error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.HostScoringWeights{}, err
+	}
+	defer r.tg.Done()
+	return r.hostDB.ScoringWeights()
+}
+
+// SetScoringWeights sets the weights used by the hostdb to score hosts
+func (r *Renter) SetScoringWeights(w modules.HostScoringWeights) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	return r.hostDB.SetScoringWeights(w)
+}
+
 // EstimateHostScore returns the estimated host score
 func (r *Renter) EstimateHostScore(e modules.HostDBEntry, a modules.Allowance) (modules.HostScoreBreakdown, error) {
 	if reflect.DeepEqual(a, modules.Allowance{}) {
@@ -733,6 +801,11 @@ func (r *Renter) PeriodSpending() (modules.ContractorSpending, error) {
 	return r.hostContractor.PeriodSpending()
 }
 
+// SpendingForecast returns the host contractor's spending forecast.
+func (r *Renter) SpendingForecast() (modules.ContractorSpendingForecast, error) {
+	return r.hostContractor.SpendingForecast()
+}
+
 // RecoverableContracts returns the host contractor's recoverable contracts.
 func (r *Renter) RecoverableContracts() []modules.RecoverableContract {
 	return r.hostContractor.RecoverableContracts()
@@ -850,6 +923,7 @@ func renterBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 		bubbleUpdates:   make(map[string]bubbleStatus),
 		downloadHistory: make(map[modules.DownloadID]*download),
 
+		staticMigrations:                   newMigrationSet(),
 		staticProjectDownloadByRootManager: new(projectDownloadByRootManager),
 
 		cs:             cs,
@@ -900,6 +974,27 @@ func renterBlockingStartup(g modules.Gateway, cs modules.ConsensusSet, tpool mod
 	if err != nil {
 		return nil, err
 	}
+	r.staticDedupIndex, err = newDedupIndex(filepath.Join(r.persistDir, dedupIndexFilename))
+	if err != nil {
+		return nil, err
+	}
+	r.staticPackIndex, err = newPackIndex(filepath.Join(r.persistDir, packIndexFilename))
+	if err != nil {
+		return nil, err
+	}
+	r.staticBackupScheduler, err = newBackupScheduler(filepath.Join(r.persistDir, backupScheduleFilename))
+	if err != nil {
+		return nil, err
+	}
+	r.staticDownloadQueue, err = newDownloadQueue(filepath.Join(r.persistDir, downloadQueueFilename))
+	if err != nil {
+		return nil, err
+	}
+	r.managedLoadDownloadQueue()
+	r.staticStreamTokens, err = newStreamTokenSet(filepath.Join(r.persistDir, streamTokensFilename))
+	if err != nil {
+		return nil, err
+	}
 
 	// After persist is initialized, push the root directory onto the directory
 	// heap for the repair process.
@@ -942,6 +1037,7 @@ func renterAsyncStartup(r *Renter, cs modules.ConsensusSet) error {
 	// consensus set.
 	// Spin up the workers for the work pool.
 	go r.threadedDownloadLoop()
+	go r.threadedResumeDownloads()
 	if !r.deps.Disrupt("DisableRepairAndHealthLoops") {
 		go r.threadedUploadAndRepair()
 		go r.threadedStuckFileLoop()
@@ -950,6 +1046,8 @@ func renterAsyncStartup(r *Renter, cs modules.ConsensusSet) error {
 	if !r.deps.Disrupt("DisableSnapshotSync") {
 		go r.threadedSynchronizeSnapshots()
 	}
+	// Spin up the backup scheduler.
+	go r.threadedBackupScheduler()
 	return nil
 }
 
@@ -733,6 +733,15 @@ func (r *Renter) threadedSynchronizeSnapshots() {
 			if err != nil {
 				return err
 			}
+			// Ignore pruned snapshots which are still stored on the host.
+			// They are removed by the backup scheduler.
+			filtered := entryTable[:0]
+			for _, e := range entryTable {
+				if !r.staticBackupScheduler.managedIsPruned(e.UID) {
+					filtered = append(filtered, e)
+				}
+			}
+			entryTable = filtered
 
 			// Calculate which snapshots the host doesn't have, and which
 			// snapshots it does have that we haven't seen before.
@@ -66,6 +66,16 @@ func (r *Renter) managedDownloadSnapshotTableRHP2(session contractor.Session) ([
 	return entryTable, nil
 }
 
+// encryptSnapshotTable encodes the snapshot table into a sector and encrypts
+// it with the secret derived for snapshots.
+func encryptSnapshotTable(entryTable []snapshotEntry, secret crypto.Hash) []byte {
+	c, _ := crypto.NewSiaKey(crypto.TypeThreefish, secret[:])
+	newTable := make([]byte, modules.SectorSize)
+	copy(newTable[:16], snapshotTableSpecifier[:])
+	copy(newTable[16:], encoding.Marshal(entryTable))
+	return c.EncryptBytes(newTable)
+}
+
 // managedDownloadSnapshotTable will fetch the snapshot table from the host.
 func (r *Renter) managedDownloadSnapshotTable(host *worker) ([]snapshotEntry, error) {
 	// Get the wallet seed.
@@ -0,0 +1,288 @@
+package renter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/persist"
+)
+
+// Stream tokens allow streaming the files within a directory without the API
+// password. A token consists of a payload containing its id, the siapath it is
+// scoped to and its expiry, followed by an HMAC of the payload. The HMAC key
+// is generated randomly and never leaves the renter. Tokens can be revoked by
+// removing their id from the persisted set of active tokens.
+
+const (
+	// streamTokensFilename is the filename of the renter's stream tokens.
+	streamTokensFilename = "streamtokens.json"
+)
+
+var (
+	// streamTokensMetadata is the header of the persisted stream tokens.
+	streamTokensMetadata = persist.Metadata{
+		Header:  "Renter Stream Tokens",
+		Version: "1.5.4",
+	}
+
+	// errInvalidStreamToken is returned if a token is malformed or its
+	// signature doesn't match.
+	errInvalidStreamToken = errors.New("invalid stream token")
+
+	// errStreamTokenExpired is returned if a token has expired.
+	errStreamTokenExpired = errors.New("stream token has expired")
+
+	// errStreamTokenRevoked is returned if a token was revoked.
+	errStreamTokenRevoked = errors.New("stream token was revoked")
+
+	// errStreamTokenScope is returned if a token doesn't grant access to the
+	// requested siapath.
+	errStreamTokenScope = errors.New("stream token doesn't grant access to the requested file")
+
+	// errUnknownStreamToken is returned when revoking a token which doesn't
+	// exist.
+	errUnknownStreamToken = errors.New("stream token not found")
+)
+
+type (
+	// streamTokenSet keeps track of the active stream tokens.
+	streamTokenSet struct {
+		secret     []byte
+		tokens     map[string]streamTokenPayload
+		staticPath string
+		mu         sync.Mutex
+	}
+
+	// streamTokenPayload is the signed part of a stream token.
+	streamTokenPayload struct {
+		ID      string          `json:"id"`
+		SiaPath modules.SiaPath `json:"siapath"`
+		Expiry  int64           `json:"expiry"`
+	}
+
+	// streamTokenPersist is the persisted state of a streamTokenSet.
+	streamTokenPersist struct {
+		Secret []byte               `json:"secret"`
+		Tokens []streamTokenPayload `json:"tokens"`
+	}
+)
+
+// newStreamTokenSet loads the stream tokens at the given path or creates a new
+// set with a fresh secret if it doesn't exist yet.
+func newStreamTokenSet(path string) (*streamTokenSet, error) {
+	ts := &streamTokenSet{
+		tokens:     make(map[string]streamTokenPayload),
+		staticPath: path,
+	}
+	var p streamTokenPersist
+	err := persist.LoadJSON(streamTokensMetadata, &p, path)
+	if os.IsNotExist(err) {
+		ts.secret = fastrand.Bytes(32)
+		return ts, ts.save()
+	} else if err != nil {
+		return nil, errors.AddContext(err, "failed to load stream tokens")
+	}
+	ts.secret = p.Secret
+	for _, t := range p.Tokens {
+		ts.tokens[t.ID] = t
+	}
+	return ts, nil
+}
+
+// encode returns the signed token for the payload.
+func (ts *streamTokenSet) encode(p streamTokenPayload) string {
+	b, err := json.Marshal(p)
+	if err != nil {
+		panic(err) // marshalling the payload can't fail
+	}
+	return base64.RawURLEncoding.EncodeToString(b) + "." + base64.RawURLEncoding.EncodeToString(ts.sign(b))
+}
+
+// sign returns the HMAC of the given payload.
+func (ts *streamTokenSet) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, ts.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// streamToken converts a payload into a modules.StreamToken.
+func (ts *streamTokenSet) streamToken(p streamTokenPayload) modules.StreamToken {
+	return modules.StreamToken{
+		ID:      p.ID,
+		SiaPath: p.SiaPath,
+		Expiry:  time.Unix(p.Expiry, 0),
+		Token:   ts.encode(p),
+	}
+}
+
+// managedCreate creates a new token for siaPath which expires at expiry.
+func (ts *streamTokenSet) managedCreate(siaPath modules.SiaPath, expiry time.Time) (modules.StreamToken, error) {
+	p := streamTokenPayload{
+		ID:      hex.EncodeToString(fastrand.Bytes(16)),
+		SiaPath: siaPath,
+		Expiry:  expiry.Unix(),
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.tokens[p.ID] = p
+	if err := ts.save(); err != nil {
+		delete(ts.tokens, p.ID)
+		return modules.StreamToken{}, err
+	}
+	return ts.streamToken(p), nil
+}
+
+// managedRevoke revokes the token with the given id.
+func (ts *streamTokenSet) managedRevoke(id string) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	p, exists := ts.tokens[id]
+	if !exists {
+		return errUnknownStreamToken
+	}
+	delete(ts.tokens, id)
+	if err := ts.save(); err != nil {
+		ts.tokens[id] = p
+		return err
+	}
+	return nil
+}
+
+// managedTokens returns the tokens which haven't expired yet sorted by their
+// expiry.
+func (ts *streamTokenSet) managedTokens(now time.Time) []modules.StreamToken {
+	ts.mu.Lock()
+	tokens := make([]modules.StreamToken, 0, len(ts.tokens))
+	for _, p := range ts.tokens {
+		if p.Expiry > now.Unix() {
+			tokens = append(tokens, ts.streamToken(p))
+		}
+	}
+	ts.mu.Unlock()
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Expiry.Before(tokens[j].Expiry)
+	})
+	return tokens
+}
+
+// managedValidate returns an error if the token doesn't grant access to
+// siaPath at the given time.
+func (ts *streamTokenSet) managedValidate(token string, siaPath modules.SiaPath, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return errInvalidStreamToken
+	}
+	payload, err1 := base64.RawURLEncoding.DecodeString(parts[0])
+	sig, err2 := base64.RawURLEncoding.DecodeString(parts[1])
+	if err1 != nil || err2 != nil {
+		return errInvalidStreamToken
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if !hmac.Equal(sig, ts.sign(payload)) {
+		return errInvalidStreamToken
+	}
+	var p streamTokenPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return errInvalidStreamToken
+	}
+	if p.Expiry <= now.Unix() {
+		return errStreamTokenExpired
+	}
+	if _, exists := ts.tokens[p.ID]; !exists {
+		return errStreamTokenRevoked
+	}
+	if !siaPathWithin(siaPath, p.SiaPath) {
+		return errStreamTokenScope
+	}
+	return nil
+}
+
+// managedPruneExpired removes the expired tokens from the set.
+func (ts *streamTokenSet) managedPruneExpired(now time.Time) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	pruned := false
+	for id, p := range ts.tokens {
+		if p.Expiry <= now.Unix() {
+			delete(ts.tokens, id)
+			pruned = true
+		}
+	}
+	if !pruned {
+		return nil
+	}
+	return ts.save()
+}
+
+// save persists the stream tokens.
+func (ts *streamTokenSet) save() error {
+	p := streamTokenPersist{
+		Secret: ts.secret,
+		Tokens: make([]streamTokenPayload, 0, len(ts.tokens)),
+	}
+	for _, t := range ts.tokens {
+		p.Tokens = append(p.Tokens, t)
+	}
+	sort.Slice(p.Tokens, func(i, j int) bool {
+		return p.Tokens[i].ID < p.Tokens[j].ID
+	})
+	return persist.SaveJSON(streamTokensMetadata, p, ts.staticPath)
+}
+
+// siaPathWithin returns true if siaPath equals dir or is located within it.
+func siaPathWithin(siaPath, dir modules.SiaPath) bool {
+	if dir.IsRoot() || siaPath.Equals(dir) {
+		return true
+	}
+	return strings.HasPrefix(siaPath.Path, dir.Path+"/")
+}
+
+// CreateStreamToken creates a token which allows streaming the files within
+// siaPath until it expires after the given validity.
+func (r *Renter) CreateStreamToken(siaPath modules.SiaPath, validity time.Duration) (modules.StreamToken, error) {
+	if err := r.tg.Add(); err != nil {
+		return modules.StreamToken{}, err
+	}
+	defer r.tg.Done()
+	if validity <= 0 {
+		return modules.StreamToken{}, errors.New("validity of a stream token must be positive")
+	}
+	// Expired tokens are no longer needed to validate anything.
+	if err := r.staticStreamTokens.managedPruneExpired(time.Now()); err != nil {
+		return modules.StreamToken{}, errors.AddContext(err, "failed to prune expired stream tokens")
+	}
+	return r.staticStreamTokens.managedCreate(siaPath, time.Now().Add(validity))
+}
+
+// RevokeStreamToken revokes the stream token with the given id.
+func (r *Renter) RevokeStreamToken(id string) error {
+	if err := r.tg.Add(); err != nil {
+		return err
+	}
+	defer r.tg.Done()
+	return r.staticStreamTokens.managedRevoke(id)
+}
+
+// StreamTokens returns the stream tokens which are currently valid.
+func (r *Renter) StreamTokens() []modules.StreamToken {
+	return r.staticStreamTokens.managedTokens(time.Now())
+}
+
+// ValidateStreamToken returns an error if the token doesn't allow streaming
+// the file at siaPath.
+func (r *Renter) ValidateStreamToken(token string, siaPath modules.SiaPath) error {
+	return r.staticStreamTokens.managedValidate(token, siaPath, time.Now())
+}
@@ -0,0 +1,102 @@
+package renter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/persist"
+)
+
+// TestStreamTokenSet tests creating, validating and revoking stream tokens and
+// the persistence of the streamTokenSet.
+func TestStreamTokenSet(t *testing.T) {
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, persist.DefaultDiskPermissionsTest); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, streamTokensFilename)
+	ts, err := newStreamTokenSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a token for a directory.
+	now := time.Now()
+	tokenDir := modules.NewGlobalSiaPath("foo/bar")
+	token, err := ts.managedCreate(tokenDir, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The token grants access to the files within the directory only.
+	for _, sp := range []string{"foo/bar", "foo/bar/baz", "foo/bar/baz/qux"} {
+		if err := ts.managedValidate(token.Token, modules.NewGlobalSiaPath(sp), now); err != nil {
+			t.Fatalf("token should grant access to %v: %v", sp, err)
+		}
+	}
+	for _, sp := range []string{"foo", "foo/barbaz", "bar/foo/bar"} {
+		if err := ts.managedValidate(token.Token, modules.NewGlobalSiaPath(sp), now); err != errStreamTokenScope {
+			t.Fatalf("token shouldn't grant access to %v: %v", sp, err)
+		}
+	}
+	// Expired tokens are rejected.
+	file := modules.NewGlobalSiaPath("foo/bar/baz")
+	if err := ts.managedValidate(token.Token, file, now.Add(2*time.Hour)); err != errStreamTokenExpired {
+		t.Fatal("expected errStreamTokenExpired but got", err)
+	}
+	// Tampered tokens are rejected.
+	other, err := ts.managedCreate(modules.RootSiaPath(), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := strings.Split(other.Token, ".")[0] + "." + strings.Split(token.Token, ".")[1]
+	if err := ts.managedValidate(forged, file, now); err != errInvalidStreamToken {
+		t.Fatal("expected errInvalidStreamToken but got", err)
+	}
+	if err := ts.managedValidate("foo", file, now); err != errInvalidStreamToken {
+		t.Fatal("expected errInvalidStreamToken but got", err)
+	}
+
+	// Reload the set. The tokens should still be valid.
+	ts, err = newStreamTokenSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens := ts.managedTokens(now); len(tokens) != 2 {
+		t.Fatal("expected 2 tokens but got", len(tokens))
+	}
+	if err := ts.managedValidate(token.Token, file, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Revoke the token.
+	if err := ts.managedRevoke(token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.managedRevoke(token.ID); err != errUnknownStreamToken {
+		t.Fatal("expected errUnknownStreamToken but got", err)
+	}
+	if err := ts.managedValidate(token.Token, file, now); err != errStreamTokenRevoked {
+		t.Fatal("expected errStreamTokenRevoked but got", err)
+	}
+	ts, err = newStreamTokenSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.managedValidate(token.Token, file, now); err != errStreamTokenRevoked {
+		t.Fatal("revocation wasn't persisted", err)
+	}
+
+	// Expired tokens are pruned.
+	if err := ts.managedPruneExpired(now.Add(2 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if tokens := ts.managedTokens(now); len(tokens) != 0 {
+		t.Fatal("expected expired tokens to be pruned", tokens)
+	}
+}
@@ -10,13 +10,15 @@ package renter
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"gitlab.com/scpcorp/ScPrime/build"
 	"gitlab.com/scpcorp/ScPrime/crypto"
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
-	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
 
 	"gitlab.com/NebulousLabs/errors"
 )
@@ -60,7 +62,17 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 
 	// Fill in any missing upload params with sensible defaults.
 	if up.ErasureCode == nil {
-		up.ErasureCode, _ = siafile.NewRSSubCode(DefaultDataPieces, DefaultParityPieces, crypto.SegmentSize)
+		up.ErasureCode, err = r.managedDefaultErasureCode(up.SiaPath)
+		if err != nil {
+			return errors.AddContext(err, "unable to determine erasure code")
+		}
+	}
+
+	// Compressed files are uploaded from a stream of compressed chunks. The
+	// stored data of such a file doesn't match the local file which means that
+	// it can't be repaired from disk and the source is not tracked.
+	if up.Compression != modules.CompressionNone {
+		return r.managedUploadCompressed(up)
 	}
 
 	// Check that we have contracts to upload to. We need at least data +
@@ -98,6 +110,11 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	if err != nil {
 		return errors.AddContext(err, "could not open the new sia file")
 	}
+	if up.Deduplicate {
+		if err := entry.SetDeduplicate(true); err != nil {
+			return errors.Compose(errors.AddContext(err, "could not enable deduplication"), entry.Close())
+		}
+	}
 
 	// No need to upload zero-byte files.
 	if sourceInfo.Size() == 0 {
@@ -123,3 +140,86 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	}
 	return nil
 }
+
+// managedUploadCompressed uploads the source of the upload params as a
+// compressed file. The source is compressed into a file in the
+// compressedUploadsDir which is uploaded in the background, so the method
+// returns as soon as the upload is queued like an uncompressed upload. The
+// compressed data is the local source of the siafile until it reaches full
+// health, which allows the repair loop to finish a failed upload.
+func (r *Renter) managedUploadCompressed(up modules.FileUploadParams) (err error) {
+	if !modules.IsValidCompressionType(up.Compression) {
+		return modules.ErrInvalidCompressionType
+	}
+	file, err := os.Open(up.Source)
+	if err != nil {
+		return errors.AddContext(err, "unable to open the source file")
+	}
+	defer file.Close()
+
+	// The uncompressed source can't be used for repairs. The compressed data
+	// is tracked instead once it exists.
+	up.Source = ""
+	if up.CipherType == (crypto.CipherType{}) {
+		up.CipherType = crypto.TypeDefaultRenter
+	}
+	fileNode, err := r.managedInitUploadStream(up)
+	if err != nil {
+		return errors.AddContext(err, "unable to create compressed file")
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Compose(err, fileNode.Close())
+		}
+	}()
+
+	// Compress the source and persist the offset index of the compressed
+	// chunks before the data is uploaded.
+	dir := filepath.Join(r.persistDir, compressedUploadsDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return errors.AddContext(err, "unable to create compressed uploads directory")
+	}
+	tmp, err := ioutil.TempFile(dir, "upload-")
+	if err != nil {
+		return errors.AddContext(err, "unable to create compressed file")
+	}
+	removeTmp := func() error {
+		return errors.Compose(tmp.Close(), os.Remove(tmp.Name()))
+	}
+	cr := newCompressionReader(file, up.Compression, fileNode.ChunkSize())
+	if _, err := io.Copy(tmp, cr); err != nil {
+		return errors.Compose(errors.AddContext(err, "unable to compress the source file"), removeTmp())
+	}
+	ccs, uncompressedSize := cr.CompressedChunks()
+	if err := fileNode.SetCompressedChunks(ccs, uncompressedSize); err != nil {
+		return errors.Compose(errors.AddContext(err, "failed to persist compressed chunks"), removeTmp())
+	}
+	if err := tmp.Sync(); err != nil {
+		return errors.Compose(err, removeTmp())
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return errors.Compose(err, removeTmp())
+	}
+	if err := fileNode.SetLocalPath(tmp.Name()); err != nil {
+		return errors.Compose(errors.AddContext(err, "failed to set the local path of the compressed file"), removeTmp())
+	}
+
+	// Upload the compressed data in the background.
+	if err := r.tg.Add(); err != nil {
+		return errors.Compose(err, removeTmp())
+	}
+	go func() {
+		defer r.tg.Done()
+		chunks, err := r.managedPushStreamChunks(up, fileNode, tmp)
+		if err == nil {
+			err = waitChunksAvailable(chunks)
+		}
+		if err != nil {
+			r.log.Printf("WARN: unable to upload compressed file %v, it will be repaired from %v: %v", up.SiaPath, tmp.Name(), err)
+		}
+		if err := errors.Compose(fileNode.Close(), tmp.Close()); err != nil {
+			r.log.Println("WARN: unable to close compressed upload:", err)
+		}
+	}()
+	return nil
+}
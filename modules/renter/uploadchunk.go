@@ -233,7 +233,7 @@ func (uc *unfinishedUploadChunk) padAndEncryptPiece(i int) {
 		uc.logicalChunkData[i] = append(uc.logicalChunkData[i], make([]byte, short)...)
 	}
 	// Encrypt the piece.
-	key := uc.fileEntry.MasterKey().Derive(uc.staticIndex, uint64(i))
+	key := uc.fileEntry.PieceKey(uc.staticIndex, uint64(i))
 	// TODO: Switch this to perform in-place encryption.
 	uc.logicalChunkData[i] = key.EncryptBytes(uc.logicalChunkData[i])
 }
@@ -393,6 +393,9 @@ func (r *Renter) threadedFetchAndRepairChunk(chunk *unfinishedUploadChunk) {
 	// fetching, where the erasure coding occurs.
 	r.memoryManager.Return(erasureCodingMemory + pieceCompletedMemory)
 	chunk.memoryReleased += erasureCodingMemory + pieceCompletedMemory
+	// Reuse the pieces of identical chunks which are already stored on the
+	// hosts.
+	r.managedReuseDedupPieces(chunk)
 	// Swap the physical chunk data and the logical chunk data. There is
 	// probably no point to having both, given that we perform such a clean
 	// handoff here, but since the code is already written this way, it may be
@@ -497,6 +500,9 @@ func (r *Renter) staticFetchLogicalDataFromReader(uc *unfinishedUploadChunk) (er
 	if err != nil {
 		return errors.AddContext(err, "unable to read the chunk data from the source reader")
 	}
+	if err := r.staticSetConvergentKey(uc); err != nil {
+		r.repairLog.Printf("Unable to set convergent key of chunk %v of %s, the chunk won't be deduplicated: %v", uc.staticIndex, uc.staticSiaPath, err)
+	}
 
 	// Perform an integrity check on the data that was pulled from the reader.
 	err = uc.staticEncryptAndCheckIntegrity()
@@ -563,6 +569,9 @@ func (r *Renter) managedFetchLogicalChunkData(uc *unfinishedUploadChunk) error {
 			return errors.AddContext(err, "unable to read the data from the local file")
 		}
 		uc.logicalChunkData, _ = uc.fileEntry.ErasureCode().EncodeShards(dataPieces)
+		if err := r.staticSetConvergentKey(uc); err != nil {
+			r.repairLog.Printf("Unable to set convergent key of chunk %v of %s, the chunk won't be deduplicated: %v", uc.staticIndex, uc.staticSiaPath, err)
+		}
 		err = uc.staticEncryptAndCheckIntegrity()
 		if err != nil {
 			return errors.AddContext(err, "local file failed the integrity check")
@@ -666,6 +675,7 @@ func (r *Renter) managedCleanUpUploadChunk(uc *unfinishedUploadChunk) {
 	// If required, remove the chunk from the set of repairing chunks.
 	if chunkComplete && !released {
 		r.managedUpdateUploadChunkStuckStatus(uc)
+		r.managedRegisterDedupChunk(uc)
 		// Close the file entry unless disrupted.
 		if !r.deps.Disrupt("disableCloseUploadEntry") {
 			uc.fileEntry.Close()
@@ -561,6 +561,17 @@ func (r *Renter) managedBuildUnfinishedChunks(entry *filesystem.FileNode, hosts
 		return nil
 	}
 
+	// Get the policy of the file's directory. Only the hosts allowed by the
+	// policy are used for the chunks and the chunks are repaired according to
+	// the policy's repair threshold.
+	policy, err := r.managedFilePolicy(r.staticFileSystem.FileSiaPath(entry))
+	if err != nil {
+		r.log.Println("WARN: unable to get directory policy of file:", err)
+		return nil
+	}
+	hosts = policyHosts(policy, hosts)
+	repairThreshold := policyRepairThreshold(policy)
+
 	// Build a map of host public keys. We assume that all entrys are the same.
 	pks := make(map[string]types.SiaPublicKey)
 	for _, pk := range entry.HostPublicKeys() {
@@ -601,7 +612,12 @@ func (r *Renter) managedBuildUnfinishedChunks(entry *filesystem.FileNode, hosts
 		// it is likely that we can not read the file in which case it can not
 		// be used for repair.
 		repairable := chunk.health <= 1 || chunk.onDisk
-		needsRepair := chunk.health >= RepairThreshold
+		needsRepair := chunk.health >= repairThreshold
+
+		// The health of the chunk is scaled according to the repair threshold
+		// of the policy to prioritize it correctly among the chunks of other
+		// directories.
+		chunk.health = policyHealth(chunk.health, repairThreshold)
 
 		if r.deps.Disrupt("AddUnrepairableChunks") && needsRepair {
 			incompleteChunks = append(incompleteChunks, chunk)
@@ -806,13 +822,21 @@ func (r *Renter) callBuildAndPushChunks(files []*filesystem.FileNode, hosts map[
 
 		target: target,
 	}
+	// Get the repair threshold of the directory to scale the health of the
+	// files.
+	policy, err := r.managedFilePolicy(r.staticFileSystem.FileSiaPath(files[0]))
+	if err != nil {
+		r.log.Println("WARN: unable to get directory policy:", err)
+		return
+	}
+	repairThreshold := policyRepairThreshold(policy)
 	// Loop through all the files and build the temporary heap.
 	for _, file := range files {
 		// If this file has better health than other files that we have ignored,
 		// this file can be skipped. This only counts for unstuck chunks, if we
 		// are adding stuck files, we ignore health as a consideration.
 		fileMetadata := file.Metadata()
-		fileHealth := fileMetadata.CachedHealth
+		fileHealth := policyHealth(fileMetadata.CachedHealth, repairThreshold)
 		_, err := os.Stat(fileMetadata.LocalPath)
 		remoteFile := fileMetadata.LocalPath == "" || err != nil
 		if wh.canSkip(fileHealth, remoteFile) {
@@ -933,7 +957,7 @@ func (r *Renter) callBuildAndPushChunks(files []*filesystem.FileNode, hosts map[
 	}
 	// We are done with the temporary heap, reset it so the resources are closed
 	// and the memory is released.
-	err := tempChunkHeap.reset()
+	err = tempChunkHeap.reset()
 	if err != nil {
 		r.log.Println("WARN: error resetting the temporary upload heap:", err)
 	}
@@ -1011,6 +1035,13 @@ func (r *Renter) managedBuildChunkHeap(dirSiaPath modules.SiaPath, hosts map[str
 		r.log.Println("WARN: could not read directory:", err)
 		return
 	}
+	// Get the repair threshold of the directory
+	policy, err := r.managedDirectoryPolicy(dirSiaPath)
+	if err != nil {
+		r.log.Println("WARN: could not get directory policy:", err)
+		return
+	}
+	repairThreshold := policyRepairThreshold(policy)
 	// Build files from fileinfos
 	var files []*filesystem.FileNode
 	for _, fi := range fileinfos {
@@ -1049,7 +1080,7 @@ func (r *Renter) managedBuildChunkHeap(dirSiaPath modules.SiaPath, hosts map[str
 		// information updated by bubble this cached health is accurate enough
 		// to use in order to determine if a file has any chunks that need
 		// repair
-		ignore := file.NumChunks() == file.NumStuckChunks() || file.Metadata().CachedHealth < RepairThreshold
+		ignore := file.NumChunks() == file.NumStuckChunks() || file.Metadata().CachedHealth < repairThreshold
 		if target == targetUnstuckChunks && ignore {
 			file.Close()
 			continue
@@ -11,7 +11,6 @@ import (
 	"gitlab.com/scpcorp/ScPrime/crypto"
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem"
-	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
 	"gitlab.com/scpcorp/ScPrime/types"
 )
 
@@ -141,7 +140,7 @@ func (r *Renter) managedInitUploadStream(up modules.FileUploadParams) (*filesyst
 	// Check if ec was set. If not use defaults.
 	var err error
 	if ec == nil && !repair {
-		up.ErasureCode, err = siafile.NewRSSubCode(DefaultDataPieces, DefaultParityPieces, 64)
+		up.ErasureCode, err = r.managedDefaultErasureCode(siaPath)
 		if err != nil {
 			return nil, err
 		}
@@ -192,7 +191,22 @@ func (r *Renter) managedInitUploadStream(up modules.FileUploadParams) (*filesyst
 	if err != nil {
 		return nil, err
 	}
-	return r.staticFileSystem.OpenSiaFile(siaPath)
+	entry, err := r.staticFileSystem.OpenSiaFile(siaPath)
+	if err != nil {
+		return nil, err
+	}
+	// Set the compression before any data is added to the file.
+	if up.Compression != modules.CompressionNone {
+		if err := entry.SetCompression(up.Compression); err != nil {
+			return nil, errors.Compose(err, entry.Close())
+		}
+	}
+	if up.Deduplicate {
+		if err := entry.SetDeduplicate(true); err != nil {
+			return nil, errors.Compose(err, entry.Close())
+		}
+	}
+	return entry, nil
 }
 
 // callUploadStreamFromReader reads from the provided reader until io.EOF is
@@ -221,14 +235,57 @@ func (r *Renter) callUploadStreamFromReader(up modules.FileUploadParams, reader
 		}
 	}()
 
+	// If the file is compressed, the data read from the stream is compressed
+	// before it is uploaded. Compression is deterministic which means that
+	// repairing a file from a stream works the same way.
+	var cr *compressionReader
+	if ct := fileNode.CompressionType(); ct != modules.CompressionNone {
+		cr = newCompressionReader(reader, ct, fileNode.ChunkSize())
+		reader = cr
+	}
+
+	// Push the chunks of the stream to the upload heap.
+	chunks, err := r.managedPushStreamChunks(up, fileNode, reader)
+	if err != nil {
+		return nil, err
+	}
+	// Persist the offset index of the compressed chunks.
+	if cr != nil {
+		ccs, uncompressedSize := cr.CompressedChunks()
+		if err := fileNode.SetCompressedChunks(ccs, uncompressedSize); err != nil {
+			return nil, errors.AddContext(err, "failed to persist compressed chunks")
+		}
+	}
+	// Wait for all chunks to finish, then return.
+	if err := waitChunksAvailable(chunks); err != nil {
+		return nil, err
+	}
+
+	// Disrupt to force an error and ensure the fileNode is being closed
+	// correctly.
+	if r.deps.Disrupt("failUploadStreamFromReader") {
+		return nil, errors.New("disrupted by failUploadStreamFromReader")
+	}
+	return fileNode, nil
+}
+
+// managedPushStreamChunks reads the data of fileNode from reader chunk by chunk
+// and pushes the chunks to the upload heap. It returns the chunks which were
+// pushed once the whole reader has been consumed.
+func (r *Renter) managedPushStreamChunks(up modules.FileUploadParams, fileNode *filesystem.FileNode, reader io.Reader) ([]*unfinishedUploadChunk, error) {
 	// Build a map of host public keys.
 	pks := make(map[string]types.SiaPublicKey)
 	for _, pk := range fileNode.HostPublicKeys() {
 		pks[string(pk.Key)] = pk
 	}
 
-	// Get the most recent workers.
-	hosts := r.managedRefreshHostsAndWorkers()
+	// Get the most recent workers and restrict them to the hosts allowed by
+	// the directory policy.
+	policy, err := r.managedFilePolicy(up.SiaPath)
+	if err != nil {
+		return nil, errors.AddContext(err, "unable to get directory policy")
+	}
+	hosts := policyHosts(policy, r.managedRefreshHostsAndWorkers())
 
 	// Check if we currently have enough workers for the specified redundancy.
 	minWorkers := fileNode.ErasureCode().MinPieces()
@@ -321,21 +378,20 @@ func (r *Renter) callUploadStreamFromReader(up modules.FileUploadParams, reader
 			return nil, ss.err
 		}
 	}
-	// Wait for all chunks to finish, then return.
+	return chunks, nil
+}
+
+// waitChunksAvailable blocks until the data of all the provided chunks is
+// available on the network or one of the chunks failed.
+func waitChunksAvailable(chunks []*unfinishedUploadChunk) error {
 	for _, chunk := range chunks {
 		<-chunk.availableChan
 		chunk.mu.Lock()
 		err := chunk.err
 		chunk.mu.Unlock()
 		if err != nil {
-			return nil, errors.AddContext(err, "upload streamer failed to get all data available")
+			return errors.AddContext(err, "upload streamer failed to get all data available")
 		}
 	}
-
-	// Disrupt to force an error and ensure the fileNode is being closed
-	// correctly.
-	if r.deps.Disrupt("failUploadStreamFromReader") {
-		return nil, errors.New("disrupted by failUploadStreamFromReader")
-	}
-	return fileNode, nil
+	return nil
 }
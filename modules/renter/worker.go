@@ -19,6 +19,7 @@ import (
 	"time"
 	"unsafe"
 
+	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/types"
 
 	"gitlab.com/NebulousLabs/errors"
@@ -117,6 +118,15 @@ func (w *worker) managedKill() {
 	}
 }
 
+// staticRecordBenchmark adds a performance measurement of the worker's host
+// to the hostdb.
+func (w *worker) staticRecordBenchmark(b modules.HostBenchmark) {
+	err := w.renter.hostDB.RecordHostBenchmark(w.staticHostPubKey, b)
+	if err != nil {
+		w.renter.log.Debugln("worker failed to record host benchmark:", err)
+	}
+}
+
 // staticKilled is a convenience function to determine if a worker has been
 // killed or not.
 func (w *worker) staticKilled() bool {
@@ -21,6 +21,13 @@ var (
 )
 
 type (
+	// hostPerformance contains the rolling performance measurements of a
+	// worker's host as reported by the hostdb.
+	hostPerformance struct {
+		latency            time.Duration
+		downloadThroughput uint64
+	}
+
 	// workerCache contains all of the cached values for the worker. Every field
 	// must be static because this object is saved and loaded using
 	// atomic.Pointer.
@@ -29,6 +36,7 @@ type (
 		staticContractID      types.FileContractID
 		staticContractUtility modules.ContractUtility
 		staticHostVersion     string
+		staticHostPerformance hostPerformance
 		staticRenterAllowance modules.Allowance
 		staticSynced          bool
 
@@ -36,6 +44,17 @@ type (
 	}
 )
 
+// estimatedFetchTime estimates how long it takes the host to send 'size'
+// bytes. Unmeasured values are assumed to be free so that new hosts get a
+// chance to be measured.
+func (hp hostPerformance) estimatedFetchTime(size uint64) time.Duration {
+	estimate := hp.latency
+	if hp.downloadThroughput > 0 {
+		estimate += time.Duration(float64(size) / float64(hp.downloadThroughput) * float64(time.Second))
+	}
+	return estimate
+}
+
 // managedUpdateCache performs the actual worker cache update. The function is
 // managed because it calls exported functions on the hostdb and on the
 // consensus set.
@@ -74,6 +93,10 @@ func (w *worker) managedUpdateCache() {
 		staticContractID:      renterContract.ID,
 		staticContractUtility: renterContract.Utility,
 		staticHostVersion:     host.Version,
+		staticHostPerformance: hostPerformance{
+			latency:            host.Latency,
+			downloadThroughput: host.DownloadThroughput,
+		},
 		staticRenterAllowance: w.renter.hostContractor.Allowance(),
 		staticSynced:          w.renter.cs.Synced(),
 
@@ -179,6 +179,7 @@ func (w *worker) managedPerformDownloadChunkJob() {
 
 	fetchOffset, fetchLength := sectorOffsetAndLength(udc.staticFetchOffset, udc.staticFetchLength, udc.erasureCode)
 	root := udc.staticChunkMap[w.staticHostPubKey.String()].root
+	start := time.Now()
 	pieceData, err := d.Download(root, uint32(fetchOffset), uint32(fetchLength))
 	if err != nil {
 		w.renter.log.Debugln("worker failed to download sector:", err)
@@ -186,6 +187,10 @@ func (w *worker) managedPerformDownloadChunkJob() {
 		udc.managedUnregisterWorker(w)
 		return
 	}
+	w.staticRecordBenchmark(modules.HostBenchmark{
+		DownloadBytes: fetchLength,
+		DownloadTime:  time.Since(start),
+	})
 	// Reset the consecutive failures for the download cooldown.
 	w.downloadMu.Lock()
 	w.downloadConsecutiveFailures = 0
@@ -202,7 +207,7 @@ func (w *worker) managedPerformDownloadChunkJob() {
 	// a large overdrive. It shouldn't be a bottleneck though since bandwidth
 	// is usually a lot more scarce than CPU processing power.
 	pieceIndex := udc.staticChunkMap[w.staticHostPubKey.String()].index
-	key := udc.masterKey.Derive(udc.staticChunkIndex, pieceIndex)
+	key := udc.renterFile.PieceKey(udc.staticChunkIndex, pieceIndex)
 	decryptedPiece, err := key.DecryptBytesInPlace(pieceData, uint64(fetchOffset/crypto.SegmentSize))
 	if err != nil {
 		w.renter.log.Debugln("worker failed to decrypt piece:", err)
@@ -266,16 +266,12 @@ func (r *Renter) managedUploadSnapshotHost(meta modules.UploadedBackup, dotSia [
 		return r.persist.UploadedBackups[i].CreationDate > r.persist.UploadedBackups[j].CreationDate
 	})
 	r.mu.Unlock(id)
-	c, _ := crypto.NewSiaKey(crypto.TypeThreefish, secret[:])
 	for len(encoding.Marshal(entryTable)) > int(modules.SectorSize) {
 		entryTable = entryTable[:len(entryTable)-1]
 	}
 
 	// encode and encrypt the table
-	newTable := make([]byte, modules.SectorSize)
-	copy(newTable[:16], snapshotTableSpecifier[:])
-	copy(newTable[16:], encoding.Marshal(entryTable))
-	tableSector := c.EncryptBytes(newTable)
+	tableSector := encryptSnapshotTable(entryTable, secret)
 
 	// swap the new entry table into index 0 and delete the old one
 	// (unless it wasn't an entry table)
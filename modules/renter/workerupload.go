@@ -210,6 +210,7 @@ func (w *worker) managedPerformUploadChunkJob() {
 
 	// Perform the upload, and update the failure stats based on the success of
 	// the upload attempt.
+	start := time.Now()
 	root, err := e.Upload(uc.physicalChunkData[pieceIndex])
 	if err != nil {
 		failureErr := fmt.Errorf("Worker failed to upload via the editor: %v", err)
@@ -217,6 +218,10 @@ func (w *worker) managedPerformUploadChunkJob() {
 		w.managedUploadFailed(uc, pieceIndex, failureErr)
 		return
 	}
+	w.staticRecordBenchmark(modules.HostBenchmark{
+		UploadBytes: uint64(len(uc.physicalChunkData[pieceIndex])),
+		UploadTime:  time.Since(start),
+	})
 	w.mu.Lock()
 	w.uploadConsecutiveFailures = 0
 	w.mu.Unlock()
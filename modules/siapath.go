@@ -53,6 +53,10 @@ var (
 	// accessible data.
 	HomeFolder = NewGlobalSiaPath("/home")
 
+	// PackFolder is the Sia folder where the renter stores the packs which
+	// contain the data of packed small files.
+	PackFolder = NewGlobalSiaPath("/packs")
+
 	// UserFolder is the Sia folder that is used to store the renter's siafiles.
 	UserFolder = NewGlobalSiaPath("/home/user")
 )
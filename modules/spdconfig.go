@@ -19,6 +19,16 @@ type (
 		WriteBPS           int64  `json:"writebps"`
 		PacketSize         uint64 `json:"packetsize"`
 
+		// EnableMetrics enables the Prometheus /metrics endpoint of the API.
+		EnableMetrics bool `json:"enablemetrics"`
+
+		// SOCKS5Proxy is the address of a SOCKS5 proxy, e.g. Tor, that
+		// outbound gateway peer, host and Host API connections are routed
+		// through. AllowOnion enables .onion addresses for peers and hosts,
+		// which requires a proxy.
+		SOCKS5Proxy string `json:"socks5proxy"`
+		AllowOnion  bool   `json:"allowonion"`
+
 		// path of config on disk.
 		path string
 		mu   sync.Mutex
@@ -59,6 +69,23 @@ func (cfg *SpdConfig) SetRatelimit(readBPS, writeBPS int64) error {
 	return cfg.save()
 }
 
+// MetricsEnabled returns whether the Prometheus /metrics endpoint of the API is
+// enabled.
+func (cfg *SpdConfig) MetricsEnabled() bool {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	return cfg.EnableMetrics
+}
+
+// SetMetricsEnabled enables or disables the Prometheus /metrics endpoint of the
+// API and persists the setting to disk.
+func (cfg *SpdConfig) SetMetricsEnabled(enabled bool) error {
+	cfg.mu.Lock()
+	defer cfg.mu.Unlock()
+	cfg.EnableMetrics = enabled
+	return cfg.save()
+}
+
 // save saves the config to disk.
 func (cfg *SpdConfig) save() error {
 	return persist.SaveJSON(configMetadata, cfg, cfg.path)
@@ -99,5 +126,9 @@ func NewConfig(path string) (*SpdConfig, error) {
 	}
 	// Init the global ratelimit.
 	GlobalRateLimits.SetLimits(cfg.ReadBPS, cfg.WriteBPS, cfg.PacketSize)
+	// Init the global proxy.
+	if err := GlobalProxy.SetProxy(cfg.SOCKS5Proxy, cfg.AllowOnion); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
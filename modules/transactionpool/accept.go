@@ -437,5 +437,28 @@ func (tp *TransactionPool) relayTransactionSet(conn modules.PeerConn) error {
 	if err != nil {
 		return err
 	}
-	return tp.AcceptTransactionSet(ts)
+	err = tp.AcceptTransactionSet(ts)
+	if err != nil && tp.managedInvalidTransactionSet(ts) {
+		tp.gateway.PenalizePeer(conn.RPCAddr(), modules.PenaltyInvalidTransaction)
+	}
+	return err
+}
+
+// managedInvalidTransactionSet returns true if the transaction set is empty
+// or contains a transaction which is invalid regardless of the consensus
+// state. Peers relaying such sets are penalized, other errors can be caused by
+// the peer having a different view of the blockchain or the transaction pool.
+func (tp *TransactionPool) managedInvalidTransactionSet(ts []types.Transaction) bool {
+	if len(ts) == 0 {
+		return true
+	}
+	tp.mu.Lock()
+	height := tp.blockHeight
+	tp.mu.Unlock()
+	for _, txn := range ts {
+		if txn.StandaloneValid(height) != nil {
+			return true
+		}
+	}
+	return false
 }
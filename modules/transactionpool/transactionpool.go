@@ -122,6 +122,12 @@ func NewCustomTPool(cs modules.ConsensusSet, g modules.Gateway, persistDir strin
 		tp.gateway.UnregisterRPC("RelayTransactionSet")
 	})
 
+	// Provide the unconfirmed transactions for rebuilding compact blocks.
+	cs.SetUnconfirmedTransactionSource(tp)
+	tp.tg.OnStop(func() {
+		cs.SetUnconfirmedTransactionSource(nil)
+	})
+
 	// Spin up a thread to periodically dump the tpool size. (debug mode)
 	if build.DEBUG {
 		go tp.threadedLogListSize()
@@ -194,6 +194,10 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 	} else if err != nil {
 		tp.log.Critical("ERROR: Could not access recentID from tpool:", err)
 	}
+	if cc.SkipsBlocks(tp.blockHeight) {
+		recentID = cc.AppliedBlocks[0].ParentID
+		tp.blockHeight = cc.OldHeight
+	}
 
 	// Update the database of confirmed transactions.
 	for _, block := range cc.RevertedBlocks {
@@ -24,6 +24,25 @@ const (
 
 	// WalletDir is the directory that contains the wallet persistence.
 	WalletDir = "wallet"
+
+	// WebhookSignatureHeader is the header of a webhook delivery which
+	// contains the hex encoded HMAC-SHA256 of the body, keyed with the secret
+	// of the webhook.
+	WebhookSignatureHeader = "ScPrime-Signature"
+
+	// The types of webhook events. Received and spent events are sent when
+	// an output of a watched address is created or spent, confirmed events
+	// when a received output reaches the confirmation threshold of the
+	// webhook and reverted events when a received output is removed by a
+	// reorg.
+	WebhookEventReceived  = "received"
+	WebhookEventSpent     = "spent"
+	WebhookEventConfirmed = "confirmed"
+	WebhookEventReverted  = "reverted"
+
+	// The assets of webhook events.
+	WebhookAssetSiacoin = "scp"
+	WebhookAssetSiafund = "spf"
 )
 
 var (
@@ -447,6 +466,11 @@ type (
 		// the blockchain to search for transactions containing the addresses.
 		AddWatchAddresses(addrs []types.UnlockHash, unused bool) error
 
+		// AddWebhook registers a webhook which is notified about the outputs
+		// of the given addresses. If confirmations is not zero, an event is
+		// also sent when a received output reaches that many confirmations.
+		AddWebhook(url string, addrs []types.UnlockHash, confirmations types.BlockHeight) (Webhook, error)
+
 		// Close permits clean shutdown during testing and serving.
 		Close() error
 
@@ -559,10 +583,17 @@ type (
 		// address, if they are known to the wallet.
 		UnlockConditions(addr types.UnlockHash) (types.UnlockConditions, error)
 
+		// RemoveWebhook removes the webhook with the given id and drops its
+		// pending deliveries.
+		RemoveWebhook(id string) error
+
 		// WatchAddresses returns the set of addresses that the wallet is
 		// currently watching.
 		WatchAddresses() ([]types.UnlockHash, error)
 
+		// Webhooks returns the registered webhooks.
+		Webhooks() []Webhook
+
 		// IsWatchedAddress checks if the supplied unlockhash is in the list
 		// of watched addresses. Returns true only if the address is already known
 		IsWatchedAddress(types.UnlockHash) bool
@@ -591,6 +622,37 @@ type (
 	WalletSettings struct {
 		NoDefrag bool `json:"nodefrag"`
 	}
+
+	// Webhook is a registration for notifications about the outputs of a
+	// set of addresses. Confirmed events are only sent if Confirmations is
+	// not zero. FailedAttempts is the number of failed attempts to deliver
+	// the oldest pending event.
+	Webhook struct {
+		ID                string             `json:"id"`
+		URL               string             `json:"url"`
+		Addresses         []types.UnlockHash `json:"addresses"`
+		Confirmations     types.BlockHeight  `json:"confirmations"`
+		Secret            string             `json:"secret"`
+		PendingDeliveries uint64             `json:"pendingdeliveries"`
+		FailedAttempts    uint64             `json:"failedattempts"`
+	}
+
+	// WebhookEvent is the payload which is POSTed to a webhook. Deliveries
+	// are retried until they succeed, so an event might be delivered more
+	// than once. The ID of an event is unique and can be used to detect
+	// duplicates.
+	WebhookEvent struct {
+		ID            string            `json:"id"`
+		WebhookID     string            `json:"webhookid"`
+		Type          string            `json:"type"`
+		Asset         string            `json:"asset"`
+		Address       types.UnlockHash  `json:"address"`
+		OutputID      types.OutputID    `json:"outputid"`
+		Value         types.Currency    `json:"value"`
+		BlockID       types.BlockID     `json:"blockid"`
+		Height        types.BlockHeight `json:"height"`
+		Confirmations types.BlockHeight `json:"confirmations"`
+	}
 )
 
 // CalculateWalletTransactionID is a helper function for determining the id of
@@ -178,6 +178,22 @@ func (w *Wallet) initPersist() error {
 
 	// spawn a goroutine to commit the db transaction at regular intervals
 	go w.threadedDBUpdate()
+
+	// Load the webhooks and start delivering their events.
+	w.staticWebhooks, err = newWebhookManager(filepath.Join(w.persistDir, webhooksFilename), w.log)
+	if err != nil {
+		return err
+	}
+	err = w.tg.AfterStop(func() error {
+		w.staticWebhooks.mu.Lock()
+		defer w.staticWebhooks.mu.Unlock()
+		return w.staticWebhooks.save()
+	})
+	if err != nil {
+		return err
+	}
+	go w.threadedSubscribeWebhooks()
+	go w.threadedDeliverWebhooks()
 	return nil
 }
 
@@ -526,6 +526,17 @@ func (w *Wallet) applyHistory(tx *bolt.Tx, cc modules.ConsensusChange) error {
 	spentSiafundOutputs := computeSpentSiafundOutputSet(cc.SiafundOutputDiffs)
 	newDelayedSiacoinOutputs := computeDelayedSiacoinOutputDiffs(cc.DelayedSiacoinOutputDiffs)
 
+	consensusHeight, err := dbGetConsensusHeight(tx)
+	if err != nil {
+		return errors.AddContext(err, "failed to consensus height")
+	}
+	if cc.SkipsBlocks(consensusHeight) {
+		err = dbPutConsensusHeight(tx, cc.OldHeight)
+		if err != nil {
+			return errors.AddContext(err, "failed to store consensus height in database")
+		}
+	}
+
 	for _, block := range cc.AppliedBlocks {
 		consensusHeight, err := dbGetConsensusHeight(tx)
 		if err != nil {
@@ -115,6 +115,9 @@ type Wallet struct {
 	// defragDisabled determines if the wallet is set to defrag outputs once it
 	// reaches a certain threshold
 	defragDisabled bool
+
+	// staticWebhooks delivers the events of the registered webhooks.
+	staticWebhooks *webhookManager
 }
 
 // Height return the internal processed consensus height of the wallet
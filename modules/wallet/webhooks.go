@@ -0,0 +1,718 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"gitlab.com/NebulousLabs/errors"
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/persist"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// Webhooks notify external services about the outputs of a set of addresses.
+// The webhookManager is subscribed to the consensus set independently of the
+// wallet, so webhooks work while the wallet is locked. The events of every
+// consensus change are added to a persisted delivery queue. The events of
+// every webhook are delivered in order by a separate thread, so a webhook which
+// doesn't respond doesn't delay the others. A failed delivery is retried with
+// an exponential backoff until it succeeds or the webhook is removed, and the
+// following events of its webhook wait for it. The failed attempts are
+// reported together with the pending deliveries of the webhook. The consensus
+// change id is persisted as well, so no events are lost across
+// restarts. Since the manager isn't persisted after
+// every consensus change and delivery, some events might be delivered twice
+// after an unclean shutdown.
+
+const (
+	// webhooksFilename is the filename of the persisted webhooks.
+	webhooksFilename = "webhooks.json"
+
+	// webhookWarnAttempts is the number of failed attempts to deliver an
+	// event after which a warning is logged.
+	webhookWarnAttempts = 5
+
+	// webhookSaveInterval is the number of consensus changes without any
+	// events after which the webhooks are persisted.
+	webhookSaveInterval = 100
+)
+
+var (
+	// webhooksMetadata is the header of the persisted webhooks.
+	webhooksMetadata = persist.Metadata{
+		Header:  "Wallet Webhooks",
+		Version: "1.5.4",
+	}
+
+	// webhookRetryInterval is the interval after which a failed delivery is
+	// retried for the first time. It doubles with every failed attempt.
+	webhookRetryInterval = build.Select(build.Var{
+		Standard: time.Minute,
+		Dev:      10 * time.Second,
+		Testing:  100 * time.Millisecond,
+	}).(time.Duration)
+
+	// webhookMaxRetryInterval is the maximum interval between two attempts
+	// to deliver an event.
+	webhookMaxRetryInterval = build.Select(build.Var{
+		Standard: 6 * time.Hour,
+		Dev:      10 * time.Minute,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// webhookDeliverySaveInterval is the interval at which the queue is
+	// persisted after events were delivered.
+	webhookDeliverySaveInterval = build.Select(build.Var{
+		Standard: 10 * time.Second,
+		Dev:      5 * time.Second,
+		Testing:  100 * time.Millisecond,
+	}).(time.Duration)
+
+	// webhookTimeout is the timeout of a single delivery.
+	webhookTimeout = build.Select(build.Var{
+		Standard: 30 * time.Second,
+		Dev:      30 * time.Second,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
+	// errUnknownWebhook is returned when removing a webhook which doesn't
+	// exist.
+	errUnknownWebhook = errors.New("webhook not found")
+)
+
+type (
+	// webhookManager tracks the outputs of the addresses of the registered
+	// webhooks and delivers their events.
+	webhookManager struct {
+		webhooks map[string]modules.Webhook
+		watchers map[types.UnlockHash][]string
+		pending  []webhookPendingOutput
+		queue    []webhookDelivery
+		changeID modules.ConsensusChangeID
+
+		// unsavedChanges is the number of consensus changes which were
+		// processed since the webhooks were persisted. unsavedDeliveries is
+		// set if the queue was updated by a delivery since then.
+		unsavedChanges    int
+		unsavedDeliveries bool
+
+		// delivering contains the ids of the webhooks whose events are
+		// currently being delivered.
+		delivering map[string]struct{}
+
+		staticClient *http.Client
+		staticLog    *persist.Logger
+		staticPath   string
+		staticWake   chan struct{}
+		mu           sync.Mutex
+	}
+
+	// webhookPendingOutput is a received output which hasn't reached the
+	// confirmation threshold of its webhook yet.
+	webhookPendingOutput struct {
+		Event modules.WebhookEvent `json:"event"`
+	}
+
+	// webhookDelivery is a queued delivery of an event.
+	webhookDelivery struct {
+		Event       modules.WebhookEvent `json:"event"`
+		Attempts    uint64               `json:"attempts"`
+		NextAttempt time.Time            `json:"nextattempt"`
+	}
+
+	// webhookPersist is the persisted state of the webhookManager.
+	webhookPersist struct {
+		Webhooks []modules.Webhook         `json:"webhooks"`
+		Pending  []webhookPendingOutput    `json:"pending"`
+		Queue    []webhookDelivery         `json:"queue"`
+		ChangeID modules.ConsensusChangeID `json:"changeid"`
+	}
+
+	// webhookOutputDiff is a change of an output of a watched address.
+	webhookOutputDiff struct {
+		direction modules.DiffDirection
+		asset     string
+		id        types.OutputID
+		address   types.UnlockHash
+		value     types.Currency
+	}
+)
+
+// newWebhookManager loads the webhooks at the given path or creates an empty
+// webhookManager if they don't exist yet.
+func newWebhookManager(path string, log *persist.Logger) (*webhookManager, error) {
+	wm := &webhookManager{
+		webhooks: make(map[string]modules.Webhook),
+		watchers: make(map[types.UnlockHash][]string),
+		changeID: modules.ConsensusChangeRecent,
+
+		delivering: make(map[string]struct{}),
+
+		staticClient: &http.Client{Timeout: webhookTimeout},
+		staticLog:    log,
+		staticPath:   path,
+		staticWake:   make(chan struct{}, 1),
+	}
+	var p webhookPersist
+	err := persist.LoadJSON(webhooksMetadata, &p, path)
+	if os.IsNotExist(err) {
+		return wm, wm.save()
+	} else if err != nil {
+		return nil, errors.AddContext(err, "failed to load webhooks")
+	}
+	for _, wh := range p.Webhooks {
+		wm.webhooks[wh.ID] = wh
+	}
+	wm.pending = p.Pending
+	wm.queue = p.Queue
+	wm.changeID = p.ChangeID
+	wm.updateWatchers()
+	return wm, nil
+}
+
+// webhookEventID returns the id of an event. The id is derived from the
+// contents of the event, so an event which is created again after a restart
+// has the same id.
+func webhookEventID(webhookID, typ string, outputID types.OutputID, blockID types.BlockID) string {
+	return crypto.HashAll(webhookID, typ, outputID, blockID).String()
+}
+
+// webhookSignature returns the signature of a delivery.
+func webhookSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the interval after which a delivery is retried after
+// the given number of failed attempts.
+func webhookBackoff(attempts uint64) time.Duration {
+	backoff := webhookRetryInterval
+	for i := uint64(1); i < attempts && backoff < webhookMaxRetryInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxRetryInterval {
+		backoff = webhookMaxRetryInterval
+	}
+	return backoff
+}
+
+// webhookOutputDiffs returns the output diffs of a block.
+func webhookOutputDiffs(diffs modules.ConsensusChangeDiffs) []webhookOutputDiff {
+	var ods []webhookOutputDiff
+	for _, scod := range diffs.SiacoinOutputDiffs {
+		ods = append(ods, webhookOutputDiff{
+			direction: scod.Direction,
+			asset:     modules.WebhookAssetSiacoin,
+			id:        types.OutputID(scod.ID),
+			address:   scod.SiacoinOutput.UnlockHash,
+			value:     scod.SiacoinOutput.Value,
+		})
+	}
+	for _, sfod := range diffs.SiafundOutputDiffs {
+		ods = append(ods, webhookOutputDiff{
+			direction: sfod.Direction,
+			asset:     modules.WebhookAssetSiafund,
+			id:        types.OutputID(sfod.ID),
+			address:   sfod.SiafundOutput.UnlockHash,
+			value:     sfod.SiafundOutput.Value,
+		})
+	}
+	return ods
+}
+
+// updateWatchers rebuilds the map of addresses to the webhooks watching them.
+func (wm *webhookManager) updateWatchers() {
+	wm.watchers = make(map[types.UnlockHash][]string)
+	for id, wh := range wm.webhooks {
+		for _, addr := range wh.Addresses {
+			wm.watchers[addr] = append(wm.watchers[addr], id)
+		}
+	}
+}
+
+// enqueue adds an event to the delivery queue.
+func (wm *webhookManager) enqueue(e modules.WebhookEvent) {
+	e.ID = webhookEventID(e.WebhookID, e.Type, e.OutputID, e.BlockID)
+	wm.queue = append(wm.queue, webhookDelivery{Event: e})
+}
+
+// save persists the webhooks.
+func (wm *webhookManager) save() error {
+	p := webhookPersist{
+		Webhooks: make([]modules.Webhook, 0, len(wm.webhooks)),
+		Pending:  wm.pending,
+		Queue:    wm.queue,
+		ChangeID: wm.changeID,
+	}
+	for _, wh := range wm.webhooks {
+		p.Webhooks = append(p.Webhooks, wh)
+	}
+	sort.Slice(p.Webhooks, func(i, j int) bool {
+		return p.Webhooks[i].ID < p.Webhooks[j].ID
+	})
+	wm.unsavedChanges = 0
+	wm.unsavedDeliveries = false
+	return persist.SaveJSON(webhooksMetadata, p, wm.staticPath)
+}
+
+// wake wakes up the delivery thread.
+func (wm *webhookManager) wake() {
+	select {
+	case wm.staticWake <- struct{}{}:
+	default:
+	}
+}
+
+// ProcessConsensusChange creates the events of the outputs of the watched
+// addresses and queues them for delivery.
+func (wm *webhookManager) ProcessConsensusChange(cc modules.ConsensusChange) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	queued := len(wm.queue)
+	pending := len(wm.pending)
+
+	// Outputs created by reverted blocks are reverted.
+	for i, block := range cc.RevertedBlocks {
+		if i >= len(cc.RevertedDiffs) {
+			break
+		}
+		height := cc.OldHeight - types.BlockHeight(i)
+		for _, od := range webhookOutputDiffs(cc.RevertedDiffs[i]) {
+			if od.direction != modules.DiffRevert {
+				continue
+			}
+			for _, id := range wm.watchers[od.address] {
+				wm.enqueue(modules.WebhookEvent{
+					WebhookID: id,
+					Type:      modules.WebhookEventReverted,
+					Asset:     od.asset,
+					Address:   od.address,
+					OutputID:  od.id,
+					Value:     od.value,
+					BlockID:   block.ID(),
+					Height:    height,
+				})
+			}
+			// The output won't be confirmed anymore.
+			remaining := wm.pending[:0]
+			for _, p := range wm.pending {
+				if p.Event.OutputID != od.id {
+					remaining = append(remaining, p)
+				}
+			}
+			wm.pending = remaining
+		}
+	}
+
+	// Outputs created by applied blocks are received and the outputs removed
+	// by them are spent.
+	startHeight := cc.NewHeight + 1 - types.BlockHeight(len(cc.AppliedBlocks))
+	for i, block := range cc.AppliedBlocks {
+		if i >= len(cc.AppliedDiffs) {
+			break
+		}
+		height := startHeight + types.BlockHeight(i)
+		for _, od := range webhookOutputDiffs(cc.AppliedDiffs[i]) {
+			for _, id := range wm.watchers[od.address] {
+				e := modules.WebhookEvent{
+					WebhookID: id,
+					Type:      modules.WebhookEventSpent,
+					Asset:     od.asset,
+					Address:   od.address,
+					OutputID:  od.id,
+					Value:     od.value,
+					BlockID:   block.ID(),
+					Height:    height,
+				}
+				if od.direction == modules.DiffApply {
+					e.Type = modules.WebhookEventReceived
+					e.Confirmations = 1
+					if wm.webhooks[id].Confirmations > 0 {
+						wm.pending = append(wm.pending, webhookPendingOutput{Event: e})
+					}
+				}
+				wm.enqueue(e)
+			}
+		}
+	}
+
+	// Queue the confirmed events of the outputs which reached their
+	// threshold.
+	remaining := wm.pending[:0]
+	for _, p := range wm.pending {
+		wh, exists := wm.webhooks[p.Event.WebhookID]
+		if !exists {
+			continue
+		}
+		confirmations := cc.NewHeight - p.Event.Height + 1
+		if confirmations < wh.Confirmations {
+			remaining = append(remaining, p)
+			continue
+		}
+		e := p.Event
+		e.Type = modules.WebhookEventConfirmed
+		e.Confirmations = confirmations
+		wm.enqueue(e)
+	}
+	wm.pending = remaining
+
+	// Persist the webhooks if there are new events. Otherwise they are only
+	// persisted every few changes to avoid writing them for every block.
+	wm.changeID = cc.ID
+	wm.unsavedChanges++
+	changed := len(wm.queue) != queued || len(wm.pending) != pending
+	if changed || wm.unsavedChanges >= webhookSaveInterval {
+		if err := wm.save(); err != nil {
+			wm.staticLog.Println("WARN: failed to save webhooks:", err)
+		}
+	}
+	if len(wm.queue) != queued {
+		wm.wake()
+	}
+}
+
+// managedChangeID returns the id of the most recent consensus change which was
+// processed.
+func (wm *webhookManager) managedChangeID() modules.ConsensusChangeID {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	return wm.changeID
+}
+
+// managedResetChangeID resets the consensus change id of the manager to the
+// most recent change. It is used if the persisted id is no longer valid.
+func (wm *webhookManager) managedResetChangeID() {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.changeID = modules.ConsensusChangeRecent
+}
+
+// managedAdd registers a new webhook.
+func (wm *webhookManager) managedAdd(rawURL string, addrs []types.UnlockHash, confirmations types.BlockHeight) (modules.Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return modules.Webhook{}, errors.AddContext(err, "invalid webhook url")
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return modules.Webhook{}, errors.New("webhook url must be an absolute http or https url")
+	}
+	if len(addrs) == 0 {
+		return modules.Webhook{}, errors.New("webhook needs to watch at least one address")
+	}
+	// Remove duplicate addresses.
+	seen := make(map[types.UnlockHash]struct{})
+	unique := make([]types.UnlockHash, 0, len(addrs))
+	for _, addr := range addrs {
+		if _, exists := seen[addr]; !exists {
+			seen[addr] = struct{}{}
+			unique = append(unique, addr)
+		}
+	}
+	wh := modules.Webhook{
+		ID:            hex.EncodeToString(fastrand.Bytes(16)),
+		URL:           u.String(),
+		Addresses:     unique,
+		Confirmations: confirmations,
+		Secret:        hex.EncodeToString(fastrand.Bytes(32)),
+	}
+
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.webhooks[wh.ID] = wh
+	if err := wm.save(); err != nil {
+		delete(wm.webhooks, wh.ID)
+		return modules.Webhook{}, err
+	}
+	wm.updateWatchers()
+	return wh, nil
+}
+
+// managedRemove removes a webhook together with its pending events and
+// deliveries.
+func (wm *webhookManager) managedRemove(id string) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	if _, exists := wm.webhooks[id]; !exists {
+		return errUnknownWebhook
+	}
+	delete(wm.webhooks, id)
+	wm.updateWatchers()
+	pending := wm.pending[:0]
+	for _, p := range wm.pending {
+		if p.Event.WebhookID != id {
+			pending = append(pending, p)
+		}
+	}
+	wm.pending = pending
+	queue := wm.queue[:0]
+	for _, d := range wm.queue {
+		if d.Event.WebhookID != id {
+			queue = append(queue, d)
+		}
+	}
+	wm.queue = queue
+	return wm.save()
+}
+
+// managedWebhooks returns the registered webhooks sorted by their id.
+func (wm *webhookManager) managedWebhooks() []modules.Webhook {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	deliveries := make(map[string]uint64)
+	failedAttempts := make(map[string]uint64)
+	for _, d := range wm.queue {
+		if deliveries[d.Event.WebhookID] == 0 {
+			failedAttempts[d.Event.WebhookID] = d.Attempts
+		}
+		deliveries[d.Event.WebhookID]++
+	}
+	whs := make([]modules.Webhook, 0, len(wm.webhooks))
+	for _, wh := range wm.webhooks {
+		wh.PendingDeliveries = deliveries[wh.ID]
+		wh.FailedAttempts = failedAttempts[wh.ID]
+		whs = append(whs, wh)
+	}
+	sort.Slice(whs, func(i, j int) bool {
+		return whs[i].ID < whs[j].ID
+	})
+	return whs
+}
+
+// managedSaveDeliveries persists the webhooks if the queue was updated by a
+// delivery.
+func (wm *webhookManager) managedSaveDeliveries() {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	if !wm.unsavedDeliveries {
+		return
+	}
+	if err := wm.save(); err != nil {
+		wm.staticLog.Println("WARN: failed to save webhooks:", err)
+	}
+}
+
+// managedDueDeliveries returns the deliveries which are due at the given time
+// together with the time at which the next delivery is due. The events of a
+// webhook are delivered in order, so they are only due if the first queued
+// event of the webhook is due. The deliveries of webhooks whose events are
+// currently being delivered are ignored.
+func (wm *webhookManager) managedDueDeliveries(now time.Time) (due []webhookDelivery, next time.Time) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	isDue := make(map[string]bool)
+	for _, d := range wm.queue {
+		id := d.Event.WebhookID
+		if _, delivering := wm.delivering[id]; delivering {
+			continue
+		}
+		if _, exists := isDue[id]; !exists {
+			isDue[id] = !d.NextAttempt.After(now)
+			if !isDue[id] && (next.IsZero() || d.NextAttempt.Before(next)) {
+				next = d.NextAttempt
+			}
+		}
+		if isDue[id] {
+			due = append(due, d)
+		}
+	}
+	return due, next
+}
+
+// managedStartDelivery marks the events of a webhook as being delivered.
+func (wm *webhookManager) managedStartDelivery(id string) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.delivering[id] = struct{}{}
+}
+
+// managedFinishDelivery unmarks the events of a webhook as being delivered
+// and wakes up the delivery thread to schedule the next deliveries.
+func (wm *webhookManager) managedFinishDelivery(id string) {
+	wm.mu.Lock()
+	delete(wm.delivering, id)
+	wm.mu.Unlock()
+	wm.wake()
+}
+
+// managedDeliver attempts to deliver an event and updates the queue
+// according to the result. The queue is persisted by the delivery thread.
+func (wm *webhookManager) managedDeliver(d webhookDelivery) error {
+	wm.mu.Lock()
+	wh, exists := wm.webhooks[d.Event.WebhookID]
+	wm.mu.Unlock()
+	if !exists {
+		return errUnknownWebhook
+	}
+	err := wm.staticPost(wh, d.Event)
+
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	for i := range wm.queue {
+		if wm.queue[i].Event.ID != d.Event.ID || wm.queue[i].Event.WebhookID != d.Event.WebhookID {
+			continue
+		}
+		if err == nil {
+			wm.queue = append(wm.queue[:i], wm.queue[i+1:]...)
+			break
+		}
+		wm.queue[i].Attempts++
+		wm.queue[i].NextAttempt = time.Now().Add(webhookBackoff(wm.queue[i].Attempts))
+		if wm.queue[i].Attempts == webhookWarnAttempts {
+			wm.staticLog.Printf("WARN: failed to deliver webhook event %v to %v %v times, retrying until it succeeds: %v", d.Event.ID, wh.URL, webhookWarnAttempts, err)
+		}
+		break
+	}
+	wm.unsavedDeliveries = true
+	return err
+}
+
+// staticPost POSTs a signed event to a webhook.
+func (wm *webhookManager) staticPost(wh modules.Webhook, e modules.WebhookEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(modules.WebhookSignatureHeader, webhookSignature(wh.Secret, body))
+	resp, err := wm.staticClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// threadedDeliverWebhook delivers the given events of a webhook in order. It
+// stops at the first failed delivery, which is retried before the following
+// events.
+func (w *Wallet) threadedDeliverWebhook(id string, deliveries []webhookDelivery) {
+	wm := w.staticWebhooks
+	defer wm.managedFinishDelivery(id)
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	for _, d := range deliveries {
+		select {
+		case <-w.tg.StopChan():
+			return
+		default:
+		}
+		if err := wm.managedDeliver(d); err != nil {
+			return
+		}
+	}
+}
+
+// threadedDeliverWebhooks schedules the deliveries of the queued webhook
+// events and periodically persists the queue until the wallet is closed.
+func (w *Wallet) threadedDeliverWebhooks() {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	wm := w.staticWebhooks
+	saveTicker := time.NewTicker(webhookDeliverySaveInterval)
+	defer saveTicker.Stop()
+	for {
+		due, next := wm.managedDueDeliveries(time.Now())
+		deliveries := make(map[string][]webhookDelivery)
+		for _, d := range due {
+			deliveries[d.Event.WebhookID] = append(deliveries[d.Event.WebhookID], d)
+		}
+		for id, ds := range deliveries {
+			wm.managedStartDelivery(id)
+			go w.threadedDeliverWebhook(id, ds)
+		}
+
+		var retry <-chan time.Time
+		if !next.IsZero() {
+			retry = time.After(time.Until(next))
+		}
+		select {
+		case <-w.tg.StopChan():
+			return
+		case <-wm.staticWake:
+		case <-retry:
+		case <-saveTicker.C:
+			wm.managedSaveDeliveries()
+		}
+	}
+}
+
+// threadedSubscribeWebhooks subscribes the webhookManager to the consensus
+// set.
+func (w *Wallet) threadedSubscribeWebhooks() {
+	if err := w.tg.Add(); err != nil {
+		return
+	}
+	defer w.tg.Done()
+
+	wm := w.staticWebhooks
+	err := w.cs.ConsensusSetSubscribe(wm, wm.managedChangeID(), w.tg.StopChan())
+	if errors.Contains(err, modules.ErrInvalidConsensusChangeID) {
+		w.log.Println("WARN: webhook consensus change id is invalid, subscribing to new changes")
+		wm.managedResetChangeID()
+		err = w.cs.ConsensusSetSubscribe(wm, modules.ConsensusChangeRecent, w.tg.StopChan())
+	}
+	if err != nil {
+		w.log.Println("ERROR: failed to subscribe webhooks to the consensus set:", err)
+		return
+	}
+	err = w.tg.OnStop(func() error {
+		w.cs.Unsubscribe(wm)
+		return nil
+	})
+	if err != nil {
+		w.cs.Unsubscribe(wm)
+	}
+}
+
+// AddWebhook registers a webhook which is notified about the outputs of the
+// given addresses.
+func (w *Wallet) AddWebhook(url string, addrs []types.UnlockHash, confirmations types.BlockHeight) (modules.Webhook, error) {
+	if err := w.tg.Add(); err != nil {
+		return modules.Webhook{}, modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	return w.staticWebhooks.managedAdd(url, addrs, confirmations)
+}
+
+// RemoveWebhook removes the webhook with the given id.
+func (w *Wallet) RemoveWebhook(id string) error {
+	if err := w.tg.Add(); err != nil {
+		return modules.ErrWalletShutdown
+	}
+	defer w.tg.Done()
+	return w.staticWebhooks.managedRemove(id)
+}
+
+// Webhooks returns the registered webhooks.
+func (w *Wallet) Webhooks() []modules.Webhook {
+	return w.staticWebhooks.managedWebhooks()
+}
@@ -0,0 +1,464 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/NebulousLabs/fastrand"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/persist"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// webhookRecorder is a webhook endpoint which records the events it receives.
+// The first failures requests are answered with an error.
+type webhookRecorder struct {
+	secret   string
+	failures int
+	requests int
+	events   []modules.WebhookEvent
+	err      error
+	mu       sync.Mutex
+}
+
+// ServeHTTP implements http.Handler.
+func (wr *webhookRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.requests++
+	if wr.requests <= wr.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		wr.err = err
+		return
+	}
+	if req.Header.Get(modules.WebhookSignatureHeader) != webhookSignature(wr.secret, body) {
+		wr.err = errors.New("invalid signature")
+		return
+	}
+	var e modules.WebhookEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		wr.err = err
+		return
+	}
+	wr.events = append(wr.events, e)
+}
+
+// awaitEvent waits until an event of the given type was received.
+func (wr *webhookRecorder) awaitEvent(typ string) (e modules.WebhookEvent, err error) {
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		wr.mu.Lock()
+		defer wr.mu.Unlock()
+		if wr.err != nil {
+			return wr.err
+		}
+		for _, e = range wr.events {
+			if e.Type == typ {
+				return nil
+			}
+		}
+		return errors.New("no " + typ + " event received")
+	})
+	return
+}
+
+// TestWebhooks checks that the events of a watched address are delivered to a
+// webhook, even if the first attempt fails.
+func TestWebhooks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	wt, err := createWalletTester(t.Name(), modules.ProdDependencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := wt.closeWt(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Wait for the webhooks to be subscribed to the consensus set.
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if wt.wallet.staticWebhooks.managedChangeID() != modules.ConsensusChangeRecent {
+			return nil
+		}
+		if err := wt.addBlockNoPayout(); err != nil {
+			return err
+		}
+		return errors.New("webhooks aren't subscribed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid webhooks are rejected.
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	if _, err := wt.wallet.AddWebhook("ftp://example.com", []types.UnlockHash{addr}, 0); err == nil {
+		t.Fatal("expected non-http url to be rejected")
+	}
+	if _, err := wt.wallet.AddWebhook("http://example.com", nil, 0); err == nil {
+		t.Fatal("expected webhook without addresses to be rejected")
+	}
+
+	recorder := &webhookRecorder{failures: 1}
+	srv := httptest.NewServer(recorder)
+	defer srv.Close()
+	wh, err := wt.wallet.AddWebhook(srv.URL, []types.UnlockHash{addr, addr}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(wh.Addresses) != 1 || wh.Secret == "" {
+		t.Fatal("wrong webhook", wh)
+	}
+	recorder.mu.Lock()
+	recorder.secret = wh.Secret
+	recorder.mu.Unlock()
+
+	// Send coins to the address. The received event should be delivered after
+	// the failed attempt.
+	amount := types.SiacoinPrecision.Mul64(10)
+	if _, err := wt.wallet.SendSiacoins(amount, addr); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	received, err := recorder.awaitEvent(modules.WebhookEventReceived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received.WebhookID != wh.ID || received.Address != addr || received.Asset != modules.WebhookAssetSiacoin ||
+		!received.Value.Equals(amount) || received.Height != wt.cs.Height() || received.Confirmations != 1 {
+		t.Fatal("wrong received event", received)
+	}
+
+	// Mine another block. The output should be confirmed.
+	if err := wt.addBlockNoPayout(); err != nil {
+		t.Fatal(err)
+	}
+	confirmed, err := recorder.awaitEvent(modules.WebhookEventConfirmed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed.OutputID != received.OutputID || confirmed.Confirmations != 2 || confirmed.ID == received.ID {
+		t.Fatal("wrong confirmed event", confirmed)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if whs := wt.wallet.Webhooks(); len(whs) != 1 || whs[0].PendingDeliveries != 0 {
+			return errors.New("deliveries are still pending")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the webhook.
+	if err := wt.wallet.RemoveWebhook(wh.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := wt.wallet.RemoveWebhook(wh.ID); err != errUnknownWebhook {
+		t.Fatal("expected errUnknownWebhook, got", err)
+	}
+	if whs := wt.wallet.Webhooks(); len(whs) != 0 {
+		t.Fatal("webhook wasn't removed", whs)
+	}
+}
+
+// TestWebhookManagerProcessConsensusChange checks the events created for
+// spent and reverted outputs and that queued deliveries are persisted.
+func TestWebhookManagerProcessConsensusChange(t *testing.T) {
+	dir := build.TempDir(modules.WalletDir, t.Name())
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, webhooksFilename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	log, err := persist.NewLogger(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wm, err := newWebhookManager(path, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	// Use a url which doesn't accept connections. Since the delivery thread
+	// isn't running, the events stay queued anyway.
+	wh, err := wm.managedAdd("http://127.0.0.1:1", []types.UnlockHash{addr}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Apply a block which creates an output for the address and spends a
+	// siafund output of the address.
+	sco := modules.SiacoinOutputDiff{
+		Direction:     modules.DiffApply,
+		ID:            types.SiacoinOutputID{1},
+		SiacoinOutput: types.SiacoinOutput{Value: types.SiacoinPrecision, UnlockHash: addr},
+	}
+	sfo := modules.SiafundOutputDiff{
+		Direction:     modules.DiffRevert,
+		ID:            types.SiafundOutputID{2},
+		SiafundOutput: types.SiafundOutput{Value: types.NewCurrency64(5), UnlockHash: addr},
+	}
+	block := types.Block{Timestamp: 1}
+	wm.ProcessConsensusChange(modules.ConsensusChange{
+		ID:            modules.ConsensusChangeID{1},
+		AppliedBlocks: []types.Block{block},
+		AppliedDiffs: []modules.ConsensusChangeDiffs{{
+			SiacoinOutputDiffs: []modules.SiacoinOutputDiff{sco},
+			SiafundOutputDiffs: []modules.SiafundOutputDiff{sfo},
+		}},
+		OldHeight: 9,
+		NewHeight: 10,
+	})
+	if len(wm.queue) != 2 || len(wm.pending) != 1 {
+		t.Fatal("wrong number of events", len(wm.queue), len(wm.pending))
+	}
+	received, spent := wm.queue[0].Event, wm.queue[1].Event
+	if received.Type != modules.WebhookEventReceived || received.OutputID != types.OutputID(sco.ID) ||
+		received.Height != 10 || received.BlockID != block.ID() {
+		t.Fatal("wrong received event", received)
+	}
+	if spent.Type != modules.WebhookEventSpent || spent.Asset != modules.WebhookAssetSiafund ||
+		spent.OutputID != types.OutputID(sfo.ID) || !spent.Value.Equals64(5) {
+		t.Fatal("wrong spent event", spent)
+	}
+
+	// Revert the block. The received output should be reverted and never be
+	// confirmed.
+	sco.Direction = modules.DiffRevert
+	sfo.Direction = modules.DiffApply
+	wm.ProcessConsensusChange(modules.ConsensusChange{
+		ID:             modules.ConsensusChangeID{2},
+		RevertedBlocks: []types.Block{block},
+		RevertedDiffs: []modules.ConsensusChangeDiffs{{
+			SiacoinOutputDiffs: []modules.SiacoinOutputDiff{sco},
+			SiafundOutputDiffs: []modules.SiafundOutputDiff{sfo},
+		}},
+		OldHeight: 10,
+		NewHeight: 9,
+	})
+	if len(wm.queue) != 3 || len(wm.pending) != 0 {
+		t.Fatal("wrong number of events", len(wm.queue), len(wm.pending))
+	}
+	if reverted := wm.queue[2].Event; reverted.Type != modules.WebhookEventReverted || reverted.OutputID != received.OutputID {
+		t.Fatal("wrong reverted event", reverted)
+	}
+
+	// The webhook and the queue should be persisted.
+	wm2, err := newWebhookManager(path, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if whs := wm2.managedWebhooks(); len(whs) != 1 || whs[0].ID != wh.ID || whs[0].Secret != wh.Secret || whs[0].PendingDeliveries != 3 {
+		t.Fatal("webhooks weren't persisted", whs)
+	}
+	if wm2.managedChangeID() != (modules.ConsensusChangeID{2}) {
+		t.Fatal("consensus change id wasn't persisted")
+	}
+	if len(wm2.watchers[addr]) != 1 {
+		t.Fatal("watched addresses weren't restored")
+	}
+
+	// A failed delivery should be rescheduled and delay the following events
+	// of the webhook.
+	due, _ := wm2.managedDueDeliveries(time.Now())
+	if len(due) != 3 {
+		t.Fatal("expected 3 due deliveries, got", len(due))
+	}
+	if err := wm2.managedDeliver(due[0]); err == nil {
+		t.Fatal("expected delivery to fail")
+	}
+	due, next := wm2.managedDueDeliveries(time.Now())
+	if len(due) != 0 || next.IsZero() || wm2.queue[0].Attempts != 1 {
+		t.Fatal("failed delivery wasn't rescheduled", len(due), next, wm2.queue[0].Attempts)
+	}
+	if due, _ := wm2.managedDueDeliveries(next); len(due) != 3 || due[0].Event.ID != received.ID {
+		t.Fatal("events aren't due in order", due)
+	}
+
+	// Failed deliveries are never dropped.
+	wm2.queue[0].Attempts = 100
+	if err := wm2.managedDeliver(wm2.queue[0]); err == nil {
+		t.Fatal("expected delivery to fail")
+	}
+	if whs := wm2.managedWebhooks(); whs[0].PendingDeliveries != 3 || whs[0].FailedAttempts != 101 {
+		t.Fatal("failed delivery wasn't kept", whs)
+	}
+}
+
+// TestWebhookDeliveryOrder checks that the events of a webhook are delivered
+// in order if a delivery fails.
+func TestWebhookDeliveryOrder(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	dir := build.TempDir(modules.WalletDir, t.Name())
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	log, err := persist.NewLogger(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wm, err := newWebhookManager(filepath.Join(dir, webhooksFilename), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first request fails.
+	recorder := &webhookRecorder{failures: 1}
+	srv := httptest.NewServer(recorder)
+	defer srv.Close()
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	wh, err := wm.managedAdd(srv.URL, []types.UnlockHash{addr}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.mu.Lock()
+	recorder.secret = wh.Secret
+	recorder.mu.Unlock()
+
+	w := &Wallet{staticWebhooks: wm}
+	go w.threadedDeliverWebhooks()
+	defer func() {
+		if err := w.tg.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	wm.ProcessConsensusChange(modules.ConsensusChange{
+		ID:            modules.ConsensusChangeID{1},
+		AppliedBlocks: []types.Block{{}},
+		AppliedDiffs: []modules.ConsensusChangeDiffs{{
+			SiacoinOutputDiffs: []modules.SiacoinOutputDiff{{
+				Direction:     modules.DiffApply,
+				ID:            types.SiacoinOutputID{1},
+				SiacoinOutput: types.SiacoinOutput{Value: types.SiacoinPrecision, UnlockHash: addr},
+			}},
+			SiafundOutputDiffs: []modules.SiafundOutputDiff{{
+				Direction:     modules.DiffRevert,
+				ID:            types.SiafundOutputID{2},
+				SiafundOutput: types.SiafundOutput{Value: types.NewCurrency64(5), UnlockHash: addr},
+			}},
+		}},
+		NewHeight: 1,
+	})
+	if _, err := recorder.awaitEvent(modules.WebhookEventSpent); err != nil {
+		t.Fatal(err)
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if len(recorder.events) != 2 || recorder.events[0].Type != modules.WebhookEventReceived || recorder.events[1].Type != modules.WebhookEventSpent {
+		t.Fatal("events weren't delivered in order", recorder.events)
+	}
+}
+
+// TestWebhookDeliveryIndependent checks that a webhook which doesn't respond
+// doesn't delay the deliveries of other webhooks and that the queue is
+// persisted after the deliveries.
+func TestWebhookDeliveryIndependent(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	dir := build.TempDir(modules.WalletDir, t.Name())
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, webhooksFilename)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	log, err := persist.NewLogger(ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wm, err := newWebhookManager(path, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Register a webhook which blocks until the test is done and one which
+	// records the events.
+	unblock := make(chan struct{})
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-unblock
+	}))
+	defer blocking.Close()
+	recorder := &webhookRecorder{}
+	srv := httptest.NewServer(recorder)
+	defer srv.Close()
+	var addr types.UnlockHash
+	fastrand.Read(addr[:])
+	if _, err := wm.managedAdd(blocking.URL, []types.UnlockHash{addr}, 0); err != nil {
+		t.Fatal(err)
+	}
+	wh, err := wm.managedAdd(srv.URL, []types.UnlockHash{addr}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.mu.Lock()
+	recorder.secret = wh.Secret
+	recorder.mu.Unlock()
+
+	w := &Wallet{staticWebhooks: wm}
+	go w.threadedDeliverWebhooks()
+	defer func() {
+		close(unblock)
+		if err := w.tg.Stop(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	wm.ProcessConsensusChange(modules.ConsensusChange{
+		ID:            modules.ConsensusChangeID{1},
+		AppliedBlocks: []types.Block{{}},
+		AppliedDiffs: []modules.ConsensusChangeDiffs{{
+			SiacoinOutputDiffs: []modules.SiacoinOutputDiff{{
+				Direction:     modules.DiffApply,
+				ID:            types.SiacoinOutputID{1},
+				SiacoinOutput: types.SiacoinOutput{Value: types.SiacoinPrecision, UnlockHash: addr},
+			}},
+		}},
+		NewHeight: 1,
+	})
+	if _, err := recorder.awaitEvent(modules.WebhookEventReceived); err != nil {
+		t.Fatal(err)
+	}
+
+	// The delivery should be removed from the queue, which should be
+	// persisted by the delivery thread.
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		wm.mu.Lock()
+		defer wm.mu.Unlock()
+		if len(wm.queue) != 1 || wm.queue[0].Event.WebhookID == wh.ID || wm.unsavedDeliveries {
+			return errors.New("delivery wasn't persisted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -134,6 +134,11 @@ type (
 
 		staticStartTime time.Time
 
+		// staticStreamsStop is closed when the long-lived streams of the API
+		// should be terminated.
+		staticStreamsStop     chan struct{}
+		staticStreamsStopOnce sync.Once
+
 		staticDeps modules.Dependencies
 	}
 
@@ -227,6 +232,7 @@ func NewCustom(cfg *modules.SpdConfig, requiredUserAgent string, requiredPasswor
 		spdConfig:         cfg,
 		staticDeps:        a,
 		staticStartTime:   time.Now(),
+		staticStreamsStop: make(chan struct{}),
 	}
 
 	// Register API handlers
@@ -235,6 +241,15 @@ func NewCustom(cfg *modules.SpdConfig, requiredUserAgent string, requiredPasswor
 	return api
 }
 
+// CloseStreams terminates the long-lived streams of the API like /events.
+// Since these streams never finish on their own, CloseStreams needs to be
+// called when the server shuts down.
+func (api *API) CloseStreams() {
+	api.staticStreamsStopOnce.Do(func() {
+		close(api.staticStreamsStop)
+	})
+}
+
 // UnrecognizedCallHandler handles calls to disabled/not-loaded modules.
 func (api *API) UnrecognizedCallHandler(w http.ResponseWriter, req *http.Request) {
 	var errStr string
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"gitlab.com/NebulousLabs/encoding"
@@ -32,6 +33,15 @@ func (c *Client) ConsensusBlocksHeightGet(height types.BlockHeight) (cbg api.Con
 	return
 }
 
+// ConsensusSnapshotPost uses the /consensus/snapshot endpoint to export a
+// snapshot of the consensus set to the destination on the daemon's machine.
+func (c *Client) ConsensusSnapshotPost(destination string) (snap modules.ConsensusSnapshot, err error) {
+	values := url.Values{}
+	values.Set("destination", destination)
+	err = c.post("/consensus/snapshot", values.Encode(), &snap)
+	return
+}
+
 // ConsensusSubscribeSingle streams consensus changes from the
 // /consensus/subscribe endpoint to the provided subscriber. Multiple calls may
 // be required before the subscriber is fully caught up. It returns the latest
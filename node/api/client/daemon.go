@@ -18,6 +18,22 @@ func (c *Client) DaemonGlobalRateLimitPost(downloadSpeed, uploadSpeed int64) (er
 	return
 }
 
+// DaemonMetricsPost uses the /daemon/settings endpoint to enable or disable
+// the /metrics endpoint.
+func (c *Client) DaemonMetricsPost(enable bool) (err error) {
+	values := url.Values{}
+	values.Set("enablemetrics", strconv.FormatBool(enable))
+	err = c.post("/daemon/settings", values.Encode(), nil)
+	return
+}
+
+// MetricsGet requests the /metrics resource and returns the metrics in the
+// Prometheus text exposition format.
+func (c *Client) MetricsGet() (string, error) {
+	_, data, err := c.getRawResponse("/metrics")
+	return string(data), err
+}
+
 // DaemonAlertsGet requests the /daemon/alerts resource.
 func (c *Client) DaemonAlertsGet() (dag api.DaemonAlertsGet, err error) {
 	err = c.get("/daemon/alerts", &dag)
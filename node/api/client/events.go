@@ -0,0 +1,91 @@
+package client
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gitlab.com/NebulousLabs/errors"
+
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/node/api"
+)
+
+// EventStream reads the events pushed by the /events endpoint.
+type EventStream struct {
+	body io.ReadCloser
+	r    *bufio.Reader
+}
+
+// EventsGet opens a stream of events using the /events endpoint. The stream
+// starts after the consensus change with the provided id, which can be
+// modules.ConsensusChangeRecent to only receive new events. If eventTypes is
+// empty, all events are streamed.
+func (c *Client) EventsGet(since modules.ConsensusChangeID, eventTypes ...string) (*EventStream, error) {
+	values := url.Values{}
+	values.Set("since", since.String())
+	if len(eventTypes) > 0 {
+		values.Set("events", strings.Join(eventTypes, ","))
+	}
+	req, err := c.NewRequest("GET", "/events?"+values.Encode(), nil)
+	if err != nil {
+		return nil, errors.AddContext(err, "failed to construct GET request")
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.AddContext(err, "GET request failed")
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		err := readAPIError(res.Body)
+		drainAndClose(res.Body)
+		return nil, errors.AddContext(err, "GET request error")
+	}
+	return &EventStream{
+		body: res.Body,
+		r:    bufio.NewReader(res.Body),
+	}, nil
+}
+
+// Next blocks until the next event is received.
+func (es *EventStream) Next() (api.Event, error) {
+	var e api.Event
+	var data []string
+	for {
+		line, err := es.r.ReadString('\n')
+		if err != nil {
+			return api.Event{}, err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			// An empty line dispatches the event. Comments don't contain any
+			// fields and are skipped.
+			if e.Type == "" && len(data) == 0 {
+				continue
+			}
+			e.Data = []byte(strings.Join(data, "\n"))
+			return e, nil
+		case strings.HasPrefix(line, ":"):
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "id":
+			e.ID = value
+		case "event":
+			e.Type = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
+
+// Close closes the stream.
+func (es *EventStream) Close() error {
+	return es.body.Close()
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+
+	"gitlab.com/scpcorp/ScPrime/node/api"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// ExplorerAddressesGet uses the /explorer/addresses/:addr endpoint to get the
+// balances and unspent outputs of an address.
+func (c *Client) ExplorerAddressesGet(addr types.UnlockHash, offset, limit uint64) (eag api.ExplorerAddressGET, err error) {
+	values := url.Values{}
+	values.Set("offset", fmt.Sprint(offset))
+	values.Set("limit", fmt.Sprint(limit))
+	err = c.get(fmt.Sprintf("/explorer/addresses/%s?%s", addr, values.Encode()), &eag)
+	return
+}
+
+// ExplorerStatsGet uses the /explorer/stats endpoint to get the aggregate
+// statistics of the explorer and the limit addresses with the highest balance
+// of every asset.
+func (c *Client) ExplorerStatsGet(limit uint64) (esg api.ExplorerStatsGET, err error) {
+	err = c.get(fmt.Sprintf("/explorer/stats?limit=%v", limit), &esg)
+	return
+}
@@ -2,6 +2,8 @@ package client
 
 import (
 	"encoding/json"
+	"net/url"
+	"strconv"
 
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/node/api"
@@ -53,3 +55,60 @@ func (c *Client) HostDbHostsGet(pk types.SiaPublicKey) (hhg api.HostdbHostsGET,
 	err = c.get("/hostdb/hosts/"+pk.String(), &hhg)
 	return
 }
+
+// HostDbPolicyGet requests the /hostdb/policy GET endpoint
+func (c *Client) HostDbPolicyGet() (hdpg api.HostdbPolicyGET, err error) {
+	err = c.get("/hostdb/policy", &hdpg)
+	return
+}
+
+// HostDbPolicyPost requests the /hostdb/policy POST endpoint
+func (c *Client) HostDbPolicyPost(hp modules.HostPolicy) (err error) {
+	data, err := json.Marshal(hp)
+	if err != nil {
+		return err
+	}
+	err = c.post("/hostdb/policy", string(data), nil)
+	return
+}
+
+// scoringWeightsValues encodes the scoring weights as query values.
+func scoringWeightsValues(weights modules.HostScoringWeights) url.Values {
+	values := url.Values{}
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	values.Set("ageweight", formatFloat(weights.AgeWeight))
+	values.Set("collateralweight", formatFloat(weights.CollateralWeight))
+	values.Set("interactionweight", formatFloat(weights.InteractionWeight))
+	values.Set("latencyweight", formatFloat(weights.LatencyWeight))
+	values.Set("priceweight", formatFloat(weights.PriceWeight))
+	values.Set("storageremainingweight", formatFloat(weights.StorageRemainingWeight))
+	values.Set("throughputweight", formatFloat(weights.ThroughputWeight))
+	values.Set("uptimeweight", formatFloat(weights.UptimeWeight))
+	values.Set("versionweight", formatFloat(weights.VersionWeight))
+	values.Set("maxcontractprice", weights.MaxContractPrice.String())
+	values.Set("maxdownloadbandwidthprice", weights.MaxDownloadBandwidthPrice.String())
+	values.Set("maxstorageprice", weights.MaxStoragePrice.String())
+	values.Set("maxuploadbandwidthprice", weights.MaxUploadBandwidthPrice.String())
+	return values
+}
+
+// HostDbHostsWithWeightsGet requests the /hostdb/hosts/:pubkey endpoint's
+// resources including the score breakdown under the proposed weights.
+func (c *Client) HostDbHostsWithWeightsGet(pk types.SiaPublicKey, weights modules.HostScoringWeights) (hhg api.HostdbHostsGET, err error) {
+	err = c.get("/hostdb/hosts/"+pk.String()+"?"+scoringWeightsValues(weights).Encode(), &hhg)
+	return
+}
+
+// HostDbWeightsGet requests the /hostdb/weights GET endpoint
+func (c *Client) HostDbWeightsGet() (hdwg api.HostdbWeightsGET, err error) {
+	err = c.get("/hostdb/weights", &hdwg)
+	return
+}
+
+// HostDbWeightsPost requests the /hostdb/weights POST endpoint
+func (c *Client) HostDbWeightsPost(weights modules.HostScoringWeights) (err error) {
+	err = c.post("/hostdb/weights", scoringWeightsValues(weights).Encode(), nil)
+	return
+}
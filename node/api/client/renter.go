@@ -12,6 +12,7 @@ import (
 
 	"gitlab.com/NebulousLabs/errors"
 
+	"gitlab.com/scpcorp/ScPrime/crypto"
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/node/api"
 	"gitlab.com/scpcorp/ScPrime/types"
@@ -147,6 +148,15 @@ func escapeSiaPath(siaPath modules.SiaPath) string {
 	return strings.Join(escapedSegments, "/")
 }
 
+// joinHostList joins the host public keys to a comma separated list.
+func joinHostList(pks []types.SiaPublicKey) string {
+	strs := make([]string, 0, len(pks))
+	for _, pk := range pks {
+		strs = append(strs, pk.String())
+	}
+	return strings.Join(strs, ",")
+}
+
 // RenterContractorChurnStatus uses the /renter/contractorchurnstatus endpoint
 // to get the current contractor churn status.
 func (c *Client) RenterContractorChurnStatus() (churnStatus modules.ContractorChurnStatus, err error) {
@@ -154,6 +164,13 @@ func (c *Client) RenterContractorChurnStatus() (churnStatus modules.ContractorCh
 	return
 }
 
+// RenterForecastGet uses the /renter/forecast endpoint to get the spending
+// forecast of the current period.
+func (c *Client) RenterForecastGet() (forecast modules.ContractorSpendingForecast, err error) {
+	err = c.get("/renter/forecast", &forecast)
+	return
+}
+
 // RenterContractCancelPost uses the /renter/contract/cancel endpoint to cancel
 // a contract
 func (c *Client) RenterContractCancelPost(id types.FileContractID) (err error) {
@@ -163,6 +180,33 @@ func (c *Client) RenterContractCancelPost(id types.FileContractID) (err error) {
 	return
 }
 
+// RenterContractsExportPost uses the /renter/contracts/export endpoint to
+// export the contracts with the given ids to an encrypted bundle at dst. If
+// cancel is true, the exported contracts are canceled afterwards.
+func (c *Client) RenterContractsExportPost(dst string, ids []types.FileContractID, password string, cancel bool) (err error) {
+	idStrs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		idStrs = append(idStrs, id.String())
+	}
+	values := url.Values{}
+	values.Set("destination", dst)
+	values.Set("ids", strings.Join(idStrs, ","))
+	values.Set("password", password)
+	values.Set("cancel", fmt.Sprint(cancel))
+	err = c.post("/renter/contracts/export", values.Encode(), nil)
+	return
+}
+
+// RenterContractsImportPost uses the /renter/contracts/import endpoint to
+// import the contracts of a bundle created by RenterContractsExportPost.
+func (c *Client) RenterContractsImportPost(src, password string) (rcip api.RenterContractsImportPOST, err error) {
+	values := url.Values{}
+	values.Set("source", src)
+	values.Set("password", password)
+	err = c.post("/renter/contracts/import", values.Encode(), &rcip)
+	return
+}
+
 // RenterAllContractsGet requests the /renter/contracts resource with all
 // options set to true
 func (c *Client) RenterAllContractsGet() (rc api.RenterContracts, err error) {
@@ -249,6 +293,13 @@ func (c *Client) RenterCancelDownloadPost(id modules.DownloadID) (err error) {
 	return
 }
 
+// RenterResumeDownloadPost requests the /renter/download/resume/:id endpoint
+// to resume a failed download.
+func (c *Client) RenterResumeDownloadPost(id modules.DownloadID) (err error) {
+	err = c.post(fmt.Sprintf("/renter/download/resume/%s", id), "", nil)
+	return
+}
+
 // RenterFileDeleteRootPost uses the /renter/delete endpoint to delete a file.
 // It passes the `root=true` flag to indicate an absolute path.
 func (c *Client) RenterFileDeleteRootPost(siaPath modules.SiaPath) (err error) {
@@ -320,6 +371,25 @@ func (c *Client) RenterRecoverBackupPost(name string) (err error) {
 	return
 }
 
+// RenterBackupScheduleGet returns the renter's backup schedule and the
+// retained scheduled backups.
+func (c *Client) RenterBackupScheduleGet() (rbsg api.RenterBackupsScheduleGET, err error) {
+	err = c.get("/renter/backups/schedule", &rbsg)
+	return
+}
+
+// RenterBackupSchedulePost sets the renter's backup schedule.
+func (c *Client) RenterBackupSchedulePost(schedule modules.BackupSchedule) (err error) {
+	values := url.Values{}
+	values.Set("interval", schedule.Interval.String())
+	values.Set("fullinterval", strconv.FormatUint(schedule.FullInterval, 10))
+	values.Set("retainhourly", strconv.FormatUint(schedule.RetainHourly, 10))
+	values.Set("retaindaily", strconv.FormatUint(schedule.RetainDaily, 10))
+	values.Set("retainweekly", strconv.FormatUint(schedule.RetainWeekly, 10))
+	err = c.post("/renter/backups/schedule", values.Encode(), nil)
+	return
+}
+
 // RenterCreateLocalBackupPost creates a local backup of the SiaFiles of the
 // renter.
 //
@@ -490,6 +560,56 @@ func (c *Client) RenterRenamePost(siaPathOld, siaPathNew modules.SiaPath, root b
 	return
 }
 
+// RenterMigratePost uses the /renter/migrate endpoint to start migrating a
+// file to a new erasure code and cipher. A nil erasure code or an invalid
+// cipher type keep the current settings of the file.
+func (c *Client) RenterMigratePost(siaPath modules.SiaPath, ec modules.ErasureCoder, ct crypto.CipherType) (err error) {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	if ec != nil {
+		values.Set("datapieces", strconv.Itoa(ec.MinPieces()))
+		values.Set("paritypieces", strconv.Itoa(ec.NumPieces()-ec.MinPieces()))
+	}
+	if ct != crypto.TypeInvalid {
+		values.Set("ciphertype", ct.String())
+	}
+	err = c.post(fmt.Sprintf("/renter/migrate/%s", sp), values.Encode(), nil)
+	return
+}
+
+// RenterMigrateCancelPost uses the /renter/migrate endpoint to cancel the
+// migration of a file.
+func (c *Client) RenterMigrateCancelPost(siaPath modules.SiaPath) (err error) {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("cancel", "true")
+	err = c.post(fmt.Sprintf("/renter/migrate/%s", sp), values.Encode(), nil)
+	return
+}
+
+// RenterMigrationsGet requests the /renter/migrations resource.
+func (c *Client) RenterMigrationsGet() (rm api.RenterMigrations, err error) {
+	err = c.get("/renter/migrations", &rm)
+	return
+}
+
+// RenterUploadPackedPost uses the /renter/uploadpacked endpoint to upload many
+// small files to the directory at siaPath by packing them into shared chunks.
+func (c *Client) RenterUploadPackedPost(siaPath modules.SiaPath, sources []string, ec modules.ErasureCoder, force bool) (err error) {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	for _, source := range sources {
+		values.Add("source", source)
+	}
+	if ec != nil {
+		values.Set("datapieces", strconv.Itoa(ec.MinPieces()))
+		values.Set("paritypieces", strconv.Itoa(ec.NumPieces()-ec.MinPieces()))
+	}
+	values.Set("force", strconv.FormatBool(force))
+	err = c.post(fmt.Sprintf("/renter/uploadpacked/%s", sp), values.Encode(), nil)
+	return
+}
+
 // RenterSetStreamCacheSizePost uses the /renter endpoint to change the renter's
 // streamCacheSize for streaming
 func (c *Client) RenterSetStreamCacheSizePost(cacheSize uint64) (err error) {
@@ -536,6 +656,40 @@ func (c *Client) RenterStreamPartialGet(siaPath modules.SiaPath, start, end uint
 	return
 }
 
+// RenterStreamWithTokenGet uses the /renter/stream endpoint to download a file
+// using a stream token instead of the API password.
+func (c *Client) RenterStreamWithTokenGet(siaPath modules.SiaPath, token string) (resp []byte, err error) {
+	values := url.Values{}
+	values.Set("token", token)
+	sp := escapeSiaPath(siaPath)
+	_, resp, err = c.getRawResponse(fmt.Sprintf("/renter/stream/%s?%s", sp, values.Encode()))
+	return
+}
+
+// RenterStreamTokensGet requests the /renter/streamtokens endpoint to get the
+// renter's stream tokens.
+func (c *Client) RenterStreamTokensGet() (rstg api.RenterStreamTokensGET, err error) {
+	err = c.get("/renter/streamtokens", &rstg)
+	return
+}
+
+// RenterStreamTokensPost uses the /renter/streamtokens endpoint to create a
+// token which allows streaming the files within siaPath.
+func (c *Client) RenterStreamTokensPost(siaPath modules.SiaPath, validity time.Duration) (token modules.StreamToken, err error) {
+	values := url.Values{}
+	values.Set("siapath", siaPath.String())
+	values.Set("validity", validity.String())
+	err = c.post("/renter/streamtokens", values.Encode(), &token)
+	return
+}
+
+// RenterStreamTokensRevokePost uses the /renter/streamtokens/revoke endpoint
+// to revoke a stream token.
+func (c *Client) RenterStreamTokensRevokePost(id string) (err error) {
+	err = c.post(fmt.Sprintf("/renter/streamtokens/revoke/%s", id), "", nil)
+	return
+}
+
 // RenterSetRepairPathPost uses the /renter/tracking endpoint to set the repair
 // path of a file to a new location. The file at newPath must exists.
 func (c *Client) RenterSetRepairPathPost(siaPath modules.SiaPath, newPath string) (err error) {
@@ -596,6 +750,35 @@ func (c *Client) RenterUploadStreamPost(r io.Reader, siaPath modules.SiaPath, da
 	return err
 }
 
+// RenterUploadStreamCompressedPost uploads data using a stream and compresses
+// it using the provided compression type.
+func (c *Client) RenterUploadStreamCompressedPost(r io.Reader, siaPath modules.SiaPath, dataPieces, parityPieces uint64, force bool, compression modules.CompressionType) error {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("datapieces", strconv.FormatUint(dataPieces, 10))
+	values.Set("paritypieces", strconv.FormatUint(parityPieces, 10))
+	values.Set("force", strconv.FormatBool(force))
+	values.Set("compression", compression.String())
+	values.Set("stream", strconv.FormatBool(true))
+	_, _, err := c.postRawResponse(fmt.Sprintf("/renter/uploadstream/%s?%s", sp, values.Encode()), r)
+	return err
+}
+
+// RenterUploadStreamDeduplicatedPost uploads data using a stream and
+// encrypts its chunks with convergent keys to share identical chunks with
+// other deduplicated files.
+func (c *Client) RenterUploadStreamDeduplicatedPost(r io.Reader, siaPath modules.SiaPath, dataPieces, parityPieces uint64, force bool) error {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("datapieces", strconv.FormatUint(dataPieces, 10))
+	values.Set("paritypieces", strconv.FormatUint(parityPieces, 10))
+	values.Set("force", strconv.FormatBool(force))
+	values.Set("deduplicate", strconv.FormatBool(true))
+	values.Set("stream", strconv.FormatBool(true))
+	_, _, err := c.postRawResponse(fmt.Sprintf("/renter/uploadstream/%s?%s", sp, values.Encode()), r)
+	return err
+}
+
 // RenterUploadStreamRepairPost a siafile using a stream. If the data provided
 // by r is not the same as the previously uploaded data, the data will be
 // corrupted.
@@ -649,6 +832,25 @@ func (c *Client) RenterDirRenamePost(siaPath, newSiaPath modules.SiaPath) (err e
 	return
 }
 
+// RenterDirPolicyPost uses the /renter/dir/ endpoint to set the redundancy
+// and host-set policy of a directory.
+func (c *Client) RenterDirPolicyPost(siaPath modules.SiaPath, policy modules.DirectoryPolicy) (err error) {
+	sp := escapeSiaPath(siaPath)
+	values := url.Values{}
+	values.Set("action", "policy")
+	if policy.DataPieces > 0 || policy.ParityPieces > 0 {
+		values.Set("datapieces", strconv.Itoa(policy.DataPieces))
+		values.Set("paritypieces", strconv.Itoa(policy.ParityPieces))
+	}
+	if policy.RepairThreshold > 0 {
+		values.Set("repairthreshold", strconv.FormatFloat(policy.RepairThreshold, 'f', -1, 64))
+	}
+	values.Set("pinnedhosts", joinHostList(policy.PinnedHosts))
+	values.Set("excludedhosts", joinHostList(policy.ExcludedHosts))
+	err = c.post(fmt.Sprintf("/renter/dir/%s", sp), values.Encode(), nil)
+	return
+}
+
 // RenterDirRootGet uses the /renter/dir/ endpoint to query a directory,
 // starting from the root path.
 func (c *Client) RenterDirRootGet(siaPath modules.SiaPath) (rd api.RenterDirectory, err error) {
@@ -298,6 +298,34 @@ func (c *Client) WalletWatchRemovePost(addrs []types.UnlockHash, unused bool) er
 	return c.post("/wallet/watch", string(json), nil)
 }
 
+// WalletWebhooksGet requests the /wallet/webhooks endpoint and returns the
+// registered webhooks.
+func (c *Client) WalletWebhooksGet() (wwg api.WalletWebhooksGET, err error) {
+	err = c.get("/wallet/webhooks", &wwg)
+	return
+}
+
+// WalletWebhooksPost uses the /wallet/webhooks endpoint to register a webhook
+// which is notified about the outputs of the given addresses.
+func (c *Client) WalletWebhooksPost(webhookURL string, addrs []types.UnlockHash, confirmations types.BlockHeight) (wh modules.Webhook, err error) {
+	json, err := json.Marshal(api.WalletWebhooksPOST{
+		URL:           webhookURL,
+		Addresses:     addrs,
+		Confirmations: confirmations,
+	})
+	if err != nil {
+		return modules.Webhook{}, err
+	}
+	err = c.post("/wallet/webhooks", string(json), &wh)
+	return
+}
+
+// WalletWebhooksRemovePost uses the /wallet/webhooks/remove/:id endpoint to
+// remove a webhook.
+func (c *Client) WalletWebhooksRemovePost(id string) error {
+	return c.post("/wallet/webhooks/remove/"+id, "", nil)
+}
+
 // Wallet033xPost uses the /wallet/033x endpoint to load a v0.3.3.x wallet into
 // the current wallet.
 func (c *Client) Wallet033xPost(path, password string) (err error) {
@@ -6,6 +6,7 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"path/filepath"
 
 	"github.com/julienschmidt/httprouter"
 
@@ -306,6 +307,22 @@ func (api *API) consensusValidateTransactionsetHandler(w http.ResponseWriter, re
 	WriteSuccess(w)
 }
 
+// consensusSnapshotHandler handles the API calls to /consensus/snapshot.
+func (api *API) consensusSnapshotHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	destination := req.FormValue("destination")
+	// Check that the destination is absolute.
+	if !filepath.IsAbs(destination) {
+		WriteError(w, Error{"error when calling /consensus/snapshot: destination must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	snap, err := api.cs.ExportSnapshot(destination)
+	if err != nil {
+		WriteError(w, Error{"error when calling /consensus/snapshot: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, snap)
+}
+
 // consensusSubscribeHandler handles the API calls to the /consensus/subscribe
 // endpoint.
 func (api *API) consensusSubscribeHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
@@ -14,6 +14,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/inconshreveable/go-update"
@@ -167,6 +168,9 @@ type (
 	DaemonSettingsGet struct {
 		MaxDownloadSpeed int64         `json:"maxdownloadspeed"`
 		MaxUploadSpeed   int64         `json:"maxuploadspeed"`
+		EnableMetrics    bool          `json:"enablemetrics"`
+		SOCKS5Proxy      string        `json:"socks5proxy"`
+		AllowOnion       bool          `json:"allowonion"`
 		Modules          configModules `json:"modules"`
 	}
 
@@ -490,9 +494,13 @@ func (api *API) daemonStopHandler(w http.ResponseWriter, _ *http.Request, _ http
 // settings.
 func (api *API) daemonSettingsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	gmds, gmus, _ := modules.GlobalRateLimits.Limits()
+	proxy, allowOnion := modules.GlobalProxy.Settings()
 	WriteJSON(w, DaemonSettingsGet{
 		MaxDownloadSpeed: gmds,
 		MaxUploadSpeed:   gmus,
+		EnableMetrics:    api.spdConfig.MetricsEnabled(),
+		SOCKS5Proxy:      proxy,
+		AllowOnion:       allowOnion,
 		Modules:          api.staticConfigModules,
 	})
 }
@@ -519,6 +527,18 @@ func (api *API) daemonSettingsHandlerPOST(w http.ResponseWriter, req *http.Reque
 		}
 		maxUploadSpeed = uploadSpeed
 	}
+	// Scan whether the metrics endpoint should be enabled. (optional parameter)
+	if m := req.FormValue("enablemetrics"); m != "" {
+		enable, err := strconv.ParseBool(m)
+		if err != nil {
+			WriteError(w, Error{"unable to parse enablemetrics: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if err := api.spdConfig.SetMetricsEnabled(enable); err != nil {
+			WriteError(w, Error{"unable to set enablemetrics: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
 	// Set the limit.
 	if err := api.spdConfig.SetRatelimit(maxDownloadSpeed, maxUploadSpeed); err != nil {
 		WriteError(w, Error{"unable to set limits: " + err.Error()}, http.StatusBadRequest)
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/types"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// The /events endpoint pushes JSON encoded events to the client using
+// server-sent events. The stream is subscribed to the consensus set and the
+// transaction pool. Since the subscribers are called while the modules hold
+// their locks, the events are queued and written to the client by the
+// handler. Events which are derived from the consensus set carry the id of
+// their consensus change, which allows clients to resume the stream with the
+// Last-Event-ID header after reconnecting. Clients which can't keep up with
+// the events are sent an error event and disconnected once their queue is
+// full, so that they can resync from the last event they received.
+
+const (
+	// EventTypeBlock is the type of the event sent for every applied block.
+	EventTypeBlock = "block"
+
+	// EventTypeReorg is the type of the event sent when blocks are reverted.
+	// It is followed by the block events of the new blocks.
+	EventTypeReorg = "reorg"
+
+	// EventTypeTpoolAdd is the type of the event sent when a transaction set
+	// is added to the transaction pool.
+	EventTypeTpoolAdd = "tpool.add"
+
+	// EventTypeTpoolRemove is the type of the event sent when a transaction
+	// set is removed from the transaction pool.
+	EventTypeTpoolRemove = "tpool.remove"
+
+	// EventTypeWalletTransaction is the type of the event sent when a
+	// transaction of the wallet is confirmed.
+	EventTypeWalletTransaction = "wallet.transaction"
+
+	// EventTypeHostContract is the type of the event sent when the state of
+	// a storage obligation of the host changes.
+	EventTypeHostContract = "host.contract"
+
+	// EventTypeError is the type of the event sent before the stream is
+	// closed because of an error. It is always sent.
+	EventTypeError = "error"
+)
+
+var (
+	// eventTypes are all the event types which can be requested.
+	eventTypes = []string{
+		EventTypeBlock,
+		EventTypeReorg,
+		EventTypeTpoolAdd,
+		EventTypeTpoolRemove,
+		EventTypeWalletTransaction,
+		EventTypeHostContract,
+	}
+
+	// eventsKeepAliveInterval is the interval at which a comment is sent to
+	// keep the connection from timing out if there are no events.
+	eventsKeepAliveInterval = build.Select(build.Var{
+		Standard: 30 * time.Second,
+		Dev:      30 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// eventsMaxQueueLen is the maximum number of queued items of a stream.
+	// The client is disconnected if the queue grows larger.
+	eventsMaxQueueLen = build.Select(build.Var{
+		Standard: 10000,
+		Dev:      1000,
+		Testing:  10,
+	}).(int)
+
+	// eventsWalletRetries and eventsWalletRetryInterval determine how long
+	// the handler waits for the wallet to process a consensus change before
+	// the stream is closed.
+	eventsWalletRetries       = 100
+	eventsWalletRetryInterval = build.Select(build.Var{
+		Standard: 100 * time.Millisecond,
+		Dev:      100 * time.Millisecond,
+		Testing:  50 * time.Millisecond,
+	}).(time.Duration)
+
+	// errEventsWalletBehind is returned if the wallet hasn't processed a
+	// consensus change yet.
+	errEventsWalletBehind = errors.New("wallet hasn't processed the consensus change")
+
+	// errEventQueueOverflow is sent to clients which don't read the events
+	// fast enough.
+	errEventQueueOverflow = errors.New("event queue overflowed, reconnect with the Last-Event-ID header to resync")
+)
+
+type (
+	// Event is a single event of the /events stream. ID is only set for
+	// events which are derived from a consensus change.
+	Event struct {
+		ID   string          `json:"id"`
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+
+	// EventBlock is the data of a block event.
+	EventBlock struct {
+		ID        types.BlockID     `json:"id"`
+		ParentID  types.BlockID     `json:"parentid"`
+		Height    types.BlockHeight `json:"height"`
+		Timestamp types.Timestamp   `json:"timestamp"`
+	}
+
+	// EventReorg is the data of a reorg event. Height is the height of the
+	// blockchain after the blocks were reverted.
+	EventReorg struct {
+		RevertedBlocks []types.BlockID   `json:"revertedblocks"`
+		Height         types.BlockHeight `json:"height"`
+	}
+
+	// EventError is the data of an error event.
+	EventError struct {
+		Message string `json:"message"`
+	}
+
+	// EventTransactionSet is the data of the transaction pool events. The
+	// transaction ids are only set for added transaction sets.
+	EventTransactionSet struct {
+		ID             crypto.Hash           `json:"id"`
+		TransactionIDs []types.TransactionID `json:"transactionids,omitempty"`
+	}
+
+	// eventStream queues the events for a single client of /events. The
+	// queue is only capped once the initial consensus changes, which are
+	// queued while subscribing, were written.
+	eventStream struct {
+		capped     bool
+		overflowed bool
+		queue      []eventStreamItem
+
+		// obligations contains the last known state of the host's storage
+		// obligations. It is only accessed by the handler.
+		obligations map[types.FileContractID]obligationState
+
+		staticTypes  map[string]bool
+		staticNotify chan struct{}
+		mu           sync.Mutex
+	}
+
+	// eventStreamItem is a queued item of an eventStream. The wallet and host
+	// events of a consensus change are created by the handler since they
+	// require calls to the other modules.
+	eventStreamItem struct {
+		events []Event
+		change *eventStreamChange
+	}
+
+	// eventStreamChange contains the information about a consensus change
+	// which is required to create the wallet and host events.
+	eventStreamChange struct {
+		id                  modules.ConsensusChangeID
+		startHeight, height types.BlockHeight
+		applied             bool
+	}
+
+	// obligationState contains the fields of a storage obligation whose
+	// changes are reported.
+	obligationState struct {
+		status              string
+		originConfirmed     bool
+		revisionConfirmed   bool
+		proofConstructed    bool
+		proofConfirmed      bool
+		revisionConstructed bool
+	}
+)
+
+// newEventStream creates a new eventStream for the given event types.
+func newEventStream(eventTypes map[string]bool) *eventStream {
+	return &eventStream{
+		obligations:  make(map[types.FileContractID]obligationState),
+		staticTypes:  eventTypes,
+		staticNotify: make(chan struct{}, 1),
+	}
+}
+
+// newEvent creates an event with the JSON encoded data.
+func newEvent(id, typ string, data interface{}) Event {
+	b, err := json.Marshal(data)
+	if err != nil {
+		build.Critical("failed to encode event:", err)
+	}
+	return Event{ID: id, Type: typ, Data: b}
+}
+
+// writeEvent writes an event in the server-sent events format.
+func writeEvent(w io.Writer, e Event) error {
+	var err error
+	if e.ID != "" {
+		_, err = fmt.Fprintf(w, "id: %s\n", e.ID)
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, e.Data)
+	}
+	return err
+}
+
+// newObligationState returns the reported state of a storage obligation.
+func newObligationState(so modules.StorageObligation) obligationState {
+	return obligationState{
+		status:              so.ObligationStatus,
+		originConfirmed:     so.OriginConfirmed,
+		revisionConfirmed:   so.RevisionConfirmed,
+		proofConstructed:    so.ProofConstructed,
+		proofConfirmed:      so.ProofConfirmed,
+		revisionConstructed: so.RevisionConstructed,
+	}
+}
+
+// managedPush adds an item to the queue and notifies the handler. If the
+// queue is full, it is dropped and marked as overflowed.
+func (es *eventStream) managedPush(item eventStreamItem) {
+	es.mu.Lock()
+	switch {
+	case es.overflowed:
+	case es.capped && len(es.queue) >= eventsMaxQueueLen:
+		es.overflowed = true
+		es.queue = nil
+	default:
+		es.queue = append(es.queue, item)
+	}
+	es.mu.Unlock()
+	select {
+	case es.staticNotify <- struct{}{}:
+	default:
+	}
+}
+
+// managedPop removes all the items from the queue. An error is returned if
+// the queue overflowed.
+func (es *eventStream) managedPop() ([]eventStreamItem, error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.overflowed {
+		return nil, errEventQueueOverflow
+	}
+	items := es.queue
+	es.queue = nil
+	es.capped = true
+	return items, nil
+}
+
+// ProcessConsensusChange queues the block and reorg events of a consensus
+// change.
+func (es *eventStream) ProcessConsensusChange(cc modules.ConsensusChange) {
+	id := crypto.Hash(cc.ID).String()
+	startHeight := cc.NewHeight + 1 - types.BlockHeight(len(cc.AppliedBlocks))
+	var events []Event
+	if len(cc.RevertedBlocks) > 0 && es.staticTypes[EventTypeReorg] {
+		reorg := EventReorg{
+			RevertedBlocks: make([]types.BlockID, 0, len(cc.RevertedBlocks)),
+			Height:         startHeight - 1,
+		}
+		for _, b := range cc.RevertedBlocks {
+			reorg.RevertedBlocks = append(reorg.RevertedBlocks, b.ID())
+		}
+		events = append(events, newEvent(id, EventTypeReorg, reorg))
+	}
+	if es.staticTypes[EventTypeBlock] {
+		for i, b := range cc.AppliedBlocks {
+			events = append(events, newEvent(id, EventTypeBlock, EventBlock{
+				ID:        b.ID(),
+				ParentID:  b.ParentID,
+				Height:    startHeight + types.BlockHeight(i),
+				Timestamp: b.Timestamp,
+			}))
+		}
+	}
+	es.managedPush(eventStreamItem{
+		events: events,
+		change: &eventStreamChange{
+			id:          cc.ID,
+			startHeight: startHeight,
+			height:      cc.NewHeight,
+			applied:     len(cc.AppliedBlocks) > 0,
+		},
+	})
+}
+
+// ReceiveUpdatedUnconfirmedTransactions queues the transaction pool events of
+// a transaction pool diff.
+func (es *eventStream) ReceiveUpdatedUnconfirmedTransactions(diff *modules.TransactionPoolDiff) {
+	var events []Event
+	if es.staticTypes[EventTypeTpoolRemove] {
+		for _, id := range diff.RevertedTransactions {
+			events = append(events, newEvent("", EventTypeTpoolRemove, EventTransactionSet{
+				ID: crypto.Hash(id),
+			}))
+		}
+	}
+	if es.staticTypes[EventTypeTpoolAdd] {
+		for _, set := range diff.AppliedTransactions {
+			events = append(events, newEvent("", EventTypeTpoolAdd, EventTransactionSet{
+				ID:             crypto.Hash(set.ID),
+				TransactionIDs: set.IDs,
+			}))
+		}
+	}
+	if len(events) > 0 {
+		es.managedPush(eventStreamItem{events: events})
+	}
+}
+
+// managedWalletTransactions returns the wallet transactions of a consensus
+// change. The wallet only processes consensus changes while it is unlocked and
+// needs to rescan the blockchain after it was unlocked, so no transactions are
+// returned if it is locked or hasn't processed the blocks before the change.
+// Otherwise the transactions can't be retrieved until the wallet has processed
+// the change itself.
+func (api *API) managedWalletTransactions(change *eventStreamChange) (pts []modules.ProcessedTransaction, err error) {
+	err = build.Retry(eventsWalletRetries, eventsWalletRetryInterval, func() error {
+		pts = nil
+		unlocked, err := api.wallet.Unlocked()
+		if err != nil || !unlocked {
+			return err
+		}
+		height, err := api.wallet.Height()
+		if err != nil {
+			return err
+		}
+		if height+1 < change.startHeight {
+			return nil
+		} else if height < change.height {
+			return errEventsWalletBehind
+		}
+		pts, err = api.wallet.Transactions(change.startHeight, change.height)
+		return err
+	})
+	return pts, err
+}
+
+// changeEvents creates the wallet and host events of a consensus change.
+func (api *API) changeEvents(es *eventStream, change *eventStreamChange) ([]Event, error) {
+	id := crypto.Hash(change.id).String()
+	var events []Event
+	if api.wallet != nil && es.staticTypes[EventTypeWalletTransaction] && change.applied {
+		pts, err := api.managedWalletTransactions(change)
+		if err != nil {
+			return nil, errors.AddContext(err, "unable to get wallet transactions")
+		}
+		for _, pt := range pts {
+			if pt.ConfirmationHeight >= change.startHeight && pt.ConfirmationHeight <= change.height {
+				events = append(events, newEvent(id, EventTypeWalletTransaction, pt))
+			}
+		}
+	}
+	if api.host != nil && es.staticTypes[EventTypeHostContract] {
+		for _, so := range api.host.StorageObligations() {
+			state := newObligationState(so)
+			if old, exists := es.obligations[so.ObligationId]; exists && old == state {
+				continue
+			}
+			es.obligations[so.ObligationId] = state
+			events = append(events, newEvent(id, EventTypeHostContract, so))
+		}
+	}
+	return events, nil
+}
+
+// eventsHandler handles GET requests to /events.
+func (api *API) eventsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse the consensus change to resume from. The Last-Event-ID header
+	// is set by clients which reconnect.
+	ccid := modules.ConsensusChangeRecent
+	since := req.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = req.FormValue("since")
+	}
+	if since != "" {
+		if err := (*crypto.Hash)(&ccid).LoadString(since); err != nil {
+			WriteError(w, Error{"could not decode consensus change id: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Parse the requested event types. All events are sent by default.
+	filter := make(map[string]bool)
+	for _, t := range eventTypes {
+		filter[t] = true
+	}
+	if e := req.FormValue("events"); e != "" {
+		requested := make(map[string]bool)
+		for _, t := range strings.Split(e, ",") {
+			if !filter[t] {
+				WriteError(w, Error{fmt.Sprintf("unknown event type %q", t)}, http.StatusBadRequest)
+				return
+			}
+			requested[t] = true
+		}
+		filter = requested
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, Error{"streaming is not supported"}, http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe to the modules. The host's obligations are loaded first so
+	// that only subsequent changes are reported.
+	es := newEventStream(filter)
+	if api.host != nil && filter[EventTypeHostContract] {
+		for _, so := range api.host.StorageObligations() {
+			es.obligations[so.ObligationId] = newObligationState(so)
+		}
+	}
+	err := api.cs.ConsensusSetSubscribe(es, ccid, req.Context().Done())
+	if err != nil {
+		WriteError(w, Error{"unable to subscribe to the consensus set: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	defer api.cs.Unsubscribe(es)
+	if api.tpool != nil && (filter[EventTypeTpoolAdd] || filter[EventTypeTpoolRemove]) {
+		api.tpool.TransactionPoolSubscribe(es)
+		defer api.tpool.Unsubscribe(es)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+	// closeWithError sends an error event before the stream is closed.
+	closeWithError := func(err error) {
+		writeEvent(w, newEvent("", EventTypeError, EventError{Message: err.Error()}))
+		flusher.Flush()
+	}
+	for {
+		items, err := es.managedPop()
+		if err != nil {
+			closeWithError(err)
+			return
+		}
+		for _, item := range items {
+			events := item.events
+			if item.change != nil {
+				changeEvents, err := api.changeEvents(es, item.change)
+				if err != nil {
+					closeWithError(err)
+					return
+				}
+				events = append(events, changeEvents...)
+			}
+			for _, e := range events {
+				if err := writeEvent(w, e); err != nil {
+					return
+				}
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-es.staticNotify:
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case <-req.Context().Done():
+			return
+		case <-api.staticStreamsStop:
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"testing"
+
+	"gitlab.com/NebulousLabs/errors"
+)
+
+// TestEventStreamOverflow checks that the queue of an eventStream is only
+// capped after the first pop and that an overflow is reported.
+func TestEventStreamOverflow(t *testing.T) {
+	es := newEventStream(nil)
+
+	// The initial items aren't capped.
+	for i := 0; i < eventsMaxQueueLen*2; i++ {
+		es.managedPush(eventStreamItem{})
+	}
+	items, err := es.managedPop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != eventsMaxQueueLen*2 {
+		t.Fatalf("expected %v items but got %v", eventsMaxQueueLen*2, len(items))
+	}
+
+	// Fill the queue up to the cap.
+	for i := 0; i < eventsMaxQueueLen; i++ {
+		es.managedPush(eventStreamItem{})
+	}
+	items, err = es.managedPop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != eventsMaxQueueLen {
+		t.Fatalf("expected %v items but got %v", eventsMaxQueueLen, len(items))
+	}
+
+	// Overflow the queue.
+	for i := 0; i <= eventsMaxQueueLen; i++ {
+		es.managedPush(eventStreamItem{})
+	}
+	if _, err := es.managedPop(); !errors.Contains(err, errEventQueueOverflow) {
+		t.Fatalf("expected %v but got %v", errEventQueueOverflow, err)
+	}
+	// The stream stays overflowed.
+	es.managedPush(eventStreamItem{})
+	if _, err := es.managedPop(); !errors.Contains(err, errEventQueueOverflow) {
+		t.Fatalf("expected %v but got %v", errEventQueueOverflow, err)
+	}
+}
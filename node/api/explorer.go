@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/julienschmidt/httprouter"
 
@@ -12,6 +13,24 @@ import (
 	"gitlab.com/scpcorp/ScPrime/types"
 )
 
+const (
+	// explorerDefaultAddressOutputs is the default number of unspent outputs
+	// returned by /explorer/addresses/:addr.
+	explorerDefaultAddressOutputs = 100
+
+	// explorerMaxAddressOutputs is the maximum number of unspent outputs
+	// which can be requested from /explorer/addresses/:addr at once.
+	explorerMaxAddressOutputs = 1000
+
+	// explorerDefaultRichListSize is the default number of addresses per
+	// asset returned by /explorer/stats.
+	explorerDefaultRichListSize = 100
+
+	// explorerMaxRichListSize is the maximum number of addresses per asset
+	// which can be requested from /explorer/stats.
+	explorerMaxRichListSize = 1000
+)
+
 type (
 	// ExplorerBlock is a block with some extra information such as the id and
 	// height. This information is provided for programs that may not be
@@ -47,6 +66,23 @@ type (
 		SiafundClaimOutputIDs                    []types.SiacoinOutputID   `json:"siafundclaimoutputids"`
 	}
 
+	// ExplorerAddressGET is the object returned as a response to a GET
+	// request to /explorer/addresses/:addr. The unspent outputs are
+	// paginated, the counts in the balance are the total numbers of unspent
+	// outputs.
+	ExplorerAddressGET struct {
+		modules.AddressBalance
+		SiacoinOutputs []modules.AddressSiacoinOutput `json:"siacoinoutputs"`
+		SiafundOutputs []modules.AddressSiafundOutput `json:"siafundoutputs"`
+	}
+
+	// ExplorerStatsGET is the object returned as a response to a GET request
+	// to /explorer/stats.
+	ExplorerStatsGET struct {
+		modules.ExplorerStats
+		RichList modules.RichList `json:"richlist"`
+	}
+
 	// ExplorerGET is the object returned as a response to a GET request to
 	// /explorer.
 	ExplorerGET struct {
@@ -351,6 +387,60 @@ func (api *API) explorerHashHandler(w http.ResponseWriter, _ *http.Request, ps h
 	WriteError(w, Error{"unrecognized hash used as input to /explorer/hash"}, http.StatusBadRequest)
 }
 
+// explorerAddressesHandler handles GET requests to /explorer/addresses/:addr.
+func (api *API) explorerAddressesHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	addr, err := scanAddress(ps.ByName("addr"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	offset, limit := uint64(0), uint64(explorerDefaultAddressOutputs)
+	if o := req.FormValue("offset"); o != "" {
+		offset, err = strconv.ParseUint(o, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse offset: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if l := req.FormValue("limit"); l != "" {
+		limit, err = strconv.ParseUint(l, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse limit: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if limit == 0 || limit > explorerMaxAddressOutputs {
+		WriteError(w, Error{fmt.Sprintf("limit must be between 1 and %v", explorerMaxAddressOutputs)}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, ExplorerAddressGET{
+		AddressBalance: api.explorer.AddressBalance(addr),
+		SiacoinOutputs: api.explorer.AddressSiacoinOutputs(addr, offset, limit),
+		SiafundOutputs: api.explorer.AddressSiafundOutputs(addr, offset, limit),
+	})
+}
+
+// explorerStatsHandler handles GET requests to /explorer/stats.
+func (api *API) explorerStatsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	limit := uint64(explorerDefaultRichListSize)
+	if l := req.FormValue("limit"); l != "" {
+		var err error
+		limit, err = strconv.ParseUint(l, 10, 64)
+		if err != nil {
+			WriteError(w, Error{"unable to parse limit: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if limit > explorerMaxRichListSize {
+		WriteError(w, Error{fmt.Sprintf("limit can't be greater than %v", explorerMaxRichListSize)}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, ExplorerStatsGET{
+		ExplorerStats: api.explorer.Stats(),
+		RichList:      api.explorer.RichList(limit),
+	})
+}
+
 // explorerHandler handles API calls to /explorer
 func (api *API) explorerHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	facts := api.explorer.LatestBlockFacts()
@@ -17,6 +17,7 @@ type (
 		NetAddress modules.NetAddress `json:"netaddress"`
 		Peers      []modules.Peer     `json:"peers"`
 		Online     bool               `json:"online"`
+		Bans       []modules.PeerBan  `json:"bans"`
 
 		MaxDownloadSpeed int64 `json:"maxdownloadspeed"`
 		MaxUploadSpeed   int64 `json:"maxuploadspeed"`
@@ -46,6 +47,11 @@ type (
 // gatewayHandlerGET handles the API call asking for the gateway status.
 func (api *API) gatewayHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	peers := api.gateway.Peers()
+	bans, err := api.gateway.PeerBans()
+	if err != nil {
+		WriteError(w, Error{"failed to get the peer bans: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
 	mds, mus := api.gateway.RateLimits()
 	// nil slices are marshalled as 'null' in JSON, whereas 0-length slices are
 	// marshalled as '[]'. The latter is preferred, indicating that the value
@@ -53,7 +59,7 @@ func (api *API) gatewayHandlerGET(w http.ResponseWriter, _ *http.Request, _ http
 	if peers == nil {
 		peers = make([]modules.Peer, 0)
 	}
-	WriteJSON(w, GatewayGET{api.gateway.Address(), peers, api.gateway.Online(), mds, mus})
+	WriteJSON(w, GatewayGET{api.gateway.Address(), peers, api.gateway.Online(), bans, mds, mus})
 }
 
 // gatewayHandlerPOST handles the API call changing gateway specific settings.
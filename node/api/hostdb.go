@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/julienschmidt/httprouter"
 
@@ -35,6 +36,11 @@ type (
 	HostdbHostsGET struct {
 		Entry          ExtendedHostDBEntry        `json:"entry"`
 		ScoreBreakdown modules.HostScoreBreakdown `json:"scorebreakdown"`
+
+		// ProposedScoreBreakdown is the score breakdown under the scoring
+		// weights proposed in the query string. It is only set if at least
+		// one weight was proposed.
+		ProposedScoreBreakdown *modules.HostScoreBreakdown `json:"proposedscorebreakdown,omitempty"`
 	}
 
 	// HostdbGet holds information about the hostdb.
@@ -55,6 +61,16 @@ type (
 		FilterMode string               `json:"filtermode"`
 		Hosts      []types.SiaPublicKey `json:"hosts"`
 	}
+
+	// HostdbWeightsGET contains the scoring weights of the hostDB.
+	HostdbWeightsGET struct {
+		Weights modules.HostScoringWeights `json:"weights"`
+	}
+
+	// HostdbPolicyGET contains the host policy of the hostDB.
+	HostdbPolicyGET struct {
+		Policy modules.HostPolicy `json:"policy"`
+	}
 )
 
 // hostdbHandler handles the API call asking for the list of active
@@ -134,7 +150,7 @@ func (api *API) hostdbAllHandler(w http.ResponseWriter, _ *http.Request, _ httpr
 
 // hostdbHostsHandler handles the API call asking for a specific host,
 // returning detailed information about that host.
-func (api *API) hostdbHostsHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+func (api *API) hostdbHostsHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	var pk types.SiaPublicKey
 	pk.LoadString(ps.ByName("pubkey"))
 
@@ -153,14 +169,36 @@ func (api *API) hostdbHostsHandler(w http.ResponseWriter, _ *http.Request, ps ht
 		return
 	}
 
+	// Compute the score breakdown under the proposed weights.
+	var proposedBreakdown *modules.HostScoreBreakdown
+	weights, err := api.renter.ScoringWeights()
+	if err != nil {
+		WriteError(w, Error{"unable to get scoring weights: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	proposed, err := parseScoringWeights(req, &weights)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if proposed {
+		pb, err := api.renter.ScoreBreakdownWithWeights(entry, weights)
+		if err != nil {
+			WriteError(w, Error{"error calculating proposed score breakdown: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		proposedBreakdown = &pb
+	}
+
 	// Extend the hostdb entry  to have the public key string.
 	extendedEntry := ExtendedHostDBEntry{
 		HostDBEntry:     entry,
 		PublicKeyString: entry.PublicKey.String(),
 	}
 	WriteJSON(w, HostdbHostsGET{
-		Entry:          extendedEntry,
-		ScoreBreakdown: breakdown,
+		Entry:                  extendedEntry,
+		ScoreBreakdown:         breakdown,
+		ProposedScoreBreakdown: proposedBreakdown,
 	})
 }
 
@@ -208,3 +246,118 @@ func (api *API) hostdbFilterModeHandlerPOST(w http.ResponseWriter, req *http.Req
 	}
 	WriteSuccess(w)
 }
+
+// hostdbPolicyHandlerGET handles the API call to get the hostdb's host policy.
+func (api *API) hostdbPolicyHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	hp, err := api.renter.HostPolicy()
+	if err != nil {
+		WriteError(w, Error{"unable to get host policy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, HostdbPolicyGET{
+		Policy: hp,
+	})
+}
+
+// hostdbPolicyHandlerPOST handles the API call to set the hostdb's host
+// policy. The request body is a JSON encoded modules.HostPolicy.
+func (api *API) hostdbPolicyHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse parameters
+	var hp modules.HostPolicy
+	err := json.NewDecoder(req.Body).Decode(&hp)
+	if err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err := api.renter.SetHostPolicy(hp); err != nil {
+		WriteError(w, Error{"failed to set the host policy: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// parseScoringWeights updates the weights with the scoring weights found in
+// the query string of the request. It returns true if any weight was set.
+func parseScoringWeights(req *http.Request, weights *modules.HostScoringWeights) (bool, error) {
+	floats := []struct {
+		name  string
+		value *float64
+	}{
+		{"ageweight", &weights.AgeWeight},
+		{"collateralweight", &weights.CollateralWeight},
+		{"interactionweight", &weights.InteractionWeight},
+		{"latencyweight", &weights.LatencyWeight},
+		{"priceweight", &weights.PriceWeight},
+		{"storageremainingweight", &weights.StorageRemainingWeight},
+		{"throughputweight", &weights.ThroughputWeight},
+		{"uptimeweight", &weights.UptimeWeight},
+		{"versionweight", &weights.VersionWeight},
+	}
+	currencies := []struct {
+		name  string
+		value *types.Currency
+	}{
+		{"maxcontractprice", &weights.MaxContractPrice},
+		{"maxdownloadbandwidthprice", &weights.MaxDownloadBandwidthPrice},
+		{"maxstorageprice", &weights.MaxStoragePrice},
+		{"maxuploadbandwidthprice", &weights.MaxUploadBandwidthPrice},
+	}
+	var set bool
+	for _, f := range floats {
+		str := req.FormValue(f.name)
+		if str == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return false, fmt.Errorf("unable to parse %v: %v", f.name, err)
+		}
+		*f.value = v
+		set = true
+	}
+	for _, c := range currencies {
+		str := req.FormValue(c.name)
+		if str == "" {
+			continue
+		}
+		v, ok := scanAmount(str)
+		if !ok {
+			return false, fmt.Errorf("unable to parse %v", c.name)
+		}
+		*c.value = v
+		set = true
+	}
+	return set, nil
+}
+
+// hostdbWeightsHandlerGET handles the API call to get the hostdb's scoring
+// weights.
+func (api *API) hostdbWeightsHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	weights, err := api.renter.ScoringWeights()
+	if err != nil {
+		WriteError(w, Error{"unable to get scoring weights: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, HostdbWeightsGET{
+		Weights: weights,
+	})
+}
+
+// hostdbWeightsHandlerPOST handles the API call to set the hostdb's scoring
+// weights. Weights which are not specified keep their current value.
+func (api *API) hostdbWeightsHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	weights, err := api.renter.ScoringWeights()
+	if err != nil {
+		WriteError(w, Error{"unable to get scoring weights: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if _, err := parseScoringWeights(req, &weights); err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if err := api.renter.SetScoringWeights(weights); err != nil {
+		WriteError(w, Error{"failed to set the scoring weights: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+const (
+	// metricsContentType is the content type of the Prometheus text
+	// exposition format.
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// metricsPrefix is the prefix of the names of all the metrics exported
+	// by spd.
+	metricsPrefix = "spd_"
+)
+
+type (
+	// metricsWriter writes metrics in the Prometheus text exposition format.
+	metricsWriter struct {
+		buf bytes.Buffer
+	}
+
+	// metricLabel is a label of a metric sample.
+	metricLabel struct {
+		name  string
+		value string
+	}
+)
+
+// label creates a metricLabel.
+func label(name, value string) metricLabel {
+	return metricLabel{name: name, value: value}
+}
+
+// boolToFloat converts a bool into a value of a metric.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// currencyToFloat converts a currency into a value of a metric.
+func currencyToFloat(c types.Currency) float64 {
+	f, _ := c.Float64()
+	return f
+}
+
+// escapeLabelValue escapes a label value according to the Prometheus text
+// exposition format.
+func escapeLabelValue(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, "\n", `\n`, -1)
+	return strings.Replace(value, `"`, `\"`, -1)
+}
+
+// formatMetricValue formats the value of a sample.
+func formatMetricValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// header writes the HELP and TYPE lines of a metric.
+func (mw *metricsWriter) header(name, typ, help string) {
+	fmt.Fprintf(&mw.buf, "# HELP %s%s %s\n", metricsPrefix, name, help)
+	fmt.Fprintf(&mw.buf, "# TYPE %s%s %s\n", metricsPrefix, name, typ)
+}
+
+// sample writes a single sample of a metric.
+func (mw *metricsWriter) sample(name string, value float64, labels ...metricLabel) {
+	mw.buf.WriteString(metricsPrefix + name)
+	if len(labels) > 0 {
+		mw.buf.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				mw.buf.WriteByte(',')
+			}
+			fmt.Fprintf(&mw.buf, `%s="%s"`, l.name, escapeLabelValue(l.value))
+		}
+		mw.buf.WriteByte('}')
+	}
+	mw.buf.WriteByte(' ')
+	mw.buf.WriteString(formatMetricValue(value))
+	mw.buf.WriteByte('\n')
+}
+
+// gauge writes a gauge with a single sample.
+func (mw *metricsWriter) gauge(name, help string, value float64, labels ...metricLabel) {
+	mw.header(name, "gauge", help)
+	mw.sample(name, value, labels...)
+}
+
+// counter writes a counter with a single sample.
+func (mw *metricsWriter) counter(name, help string, value float64, labels ...metricLabel) {
+	mw.header(name, "counter", help)
+	mw.sample(name, value, labels...)
+}
+
+// writeConsensusMetrics writes the metrics of the consensus set.
+func (api *API) writeConsensusMetrics(mw *metricsWriter) {
+	mw.gauge("consensus_height", "Height of the current block.", float64(api.cs.Height()))
+	mw.gauge("consensus_synced", "Whether the consensus set is synced with the network.", boolToFloat(api.cs.Synced()))
+}
+
+// writeGatewayMetrics writes the metrics of the gateway.
+func (api *API) writeGatewayMetrics(mw *metricsWriter) {
+	var inbound, outbound int
+	for _, p := range api.gateway.Peers() {
+		if p.Inbound {
+			inbound++
+		} else {
+			outbound++
+		}
+	}
+	mw.header("gateway_peers", "gauge", "Number of connected peers.")
+	mw.sample("gateway_peers", float64(inbound), label("direction", "inbound"))
+	mw.sample("gateway_peers", float64(outbound), label("direction", "outbound"))
+}
+
+// writeTpoolMetrics writes the metrics of the transaction pool.
+func (api *API) writeTpoolMetrics(mw *metricsWriter) {
+	mw.gauge("tpool_transactions", "Number of transactions in the transaction pool.", float64(len(api.tpool.Transactions())))
+	min, max := api.tpool.FeeEstimation()
+	mw.header("tpool_fee_hastings", "gauge", "Estimated transaction fee per byte.")
+	mw.sample("tpool_fee_hastings", currencyToFloat(min), label("estimate", "minimum"))
+	mw.sample("tpool_fee_hastings", currencyToFloat(max), label("estimate", "maximum"))
+}
+
+// writeWalletMetrics writes the metrics of the wallet. The balances are only
+// available while the wallet is unlocked.
+func (api *API) writeWalletMetrics(mw *metricsWriter) {
+	unlocked, err := api.wallet.Unlocked()
+	if err != nil {
+		return
+	}
+	mw.gauge("wallet_unlocked", "Whether the wallet is unlocked.", boolToFloat(unlocked))
+	if !unlocked {
+		return
+	}
+	cb, err := api.wallet.ConfirmedBalance()
+	if err != nil {
+		return
+	}
+	outgoing, incoming, err := api.wallet.UnconfirmedBalance()
+	if err != nil {
+		return
+	}
+	mw.header("wallet_confirmed_siacoins_hastings", "gauge", "Confirmed siacoin balance of the wallet.")
+	mw.sample("wallet_confirmed_siacoins_hastings", currencyToFloat(cb.CoinBalance))
+	mw.header("wallet_confirmed_siafunds", "gauge", "Confirmed siafund balance of the wallet.")
+	mw.sample("wallet_confirmed_siafunds", currencyToFloat(cb.FundBalance), label("fund", "siafund"))
+	mw.sample("wallet_confirmed_siafunds", currencyToFloat(cb.FundbBalance), label("fund", "siafundb"))
+	mw.header("wallet_siafund_claim_hastings", "gauge", "Siacoin claim balance of the siafunds of the wallet.")
+	mw.sample("wallet_siafund_claim_hastings", currencyToFloat(cb.ClaimBalance), label("fund", "siafund"))
+	mw.sample("wallet_siafund_claim_hastings", currencyToFloat(cb.ClaimbBalance), label("fund", "siafundb"))
+	mw.header("wallet_unconfirmed_siacoins_hastings", "gauge", "Siacoins of unconfirmed transactions of the wallet.")
+	mw.sample("wallet_unconfirmed_siacoins_hastings", currencyToFloat(incoming), label("direction", "incoming"))
+	mw.sample("wallet_unconfirmed_siacoins_hastings", currencyToFloat(outgoing), label("direction", "outgoing"))
+}
+
+// writeRenterMetrics writes the metrics of the renter's workers and memory
+// manager.
+func (api *API) writeRenterMetrics(mw *metricsWriter) {
+	if wps, err := api.renter.WorkerPoolStatus(); err == nil {
+		mw.gauge("renter_workers", "Number of workers of the renter.", float64(wps.NumWorkers))
+		mw.header("renter_workers_on_cooldown", "gauge", "Number of workers on cooldown.")
+		mw.sample("renter_workers_on_cooldown", float64(wps.TotalDownloadCoolDown), label("queue", "download"))
+		mw.sample("renter_workers_on_cooldown", float64(wps.TotalUploadCoolDown), label("queue", "upload"))
+		mw.sample("renter_workers_on_cooldown", float64(wps.TotalMaintenanceCoolDown), label("queue", "maintenance"))
+
+		// Sort the workers to make the output deterministic.
+		workers := wps.Workers
+		sort.Slice(workers, func(i, j int) bool {
+			return workers[i].HostPubKey.String() < workers[j].HostPubKey.String()
+		})
+		mw.header("renter_worker_queue_size", "gauge", "Number of jobs in the queues of a worker.")
+		for _, ws := range workers {
+			host := label("host", ws.HostPubKey.String())
+			mw.sample("renter_worker_queue_size", float64(ws.DownloadQueueSize), host, label("queue", "download"))
+			mw.sample("renter_worker_queue_size", float64(ws.UploadQueueSize), host, label("queue", "upload"))
+			mw.sample("renter_worker_queue_size", float64(ws.ReadJobsStatus.JobQueueSize), host, label("queue", "read"))
+			mw.sample("renter_worker_queue_size", float64(ws.HasSectorJobsStatus.JobQueueSize), host, label("queue", "hassector"))
+			mw.sample("renter_worker_queue_size", float64(ws.BackupJobQueueSize), host, label("queue", "backup"))
+			mw.sample("renter_worker_queue_size", float64(ws.DownloadRootJobQueueSize), host, label("queue", "downloadroot"))
+		}
+		mw.header("renter_worker_cooldown_seconds", "gauge", "Remaining cooldown of a worker.")
+		for _, ws := range workers {
+			host := label("host", ws.HostPubKey.String())
+			mw.sample("renter_worker_cooldown_seconds", ws.DownloadCoolDownTime.Seconds(), host, label("queue", "download"))
+			mw.sample("renter_worker_cooldown_seconds", ws.UploadCoolDownTime.Seconds(), host, label("queue", "upload"))
+			mw.sample("renter_worker_cooldown_seconds", ws.MaintenanceCoolDownTime.Seconds(), host, label("queue", "maintenance"))
+		}
+	}
+	if ms, err := api.renter.MemoryStatus(); err == nil {
+		mw.header("renter_memory_bytes", "gauge", "Memory of the renter's memory manager.")
+		mw.sample("renter_memory_bytes", float64(ms.Available), label("state", "available"))
+		mw.sample("renter_memory_bytes", float64(ms.Base), label("state", "base"))
+		mw.sample("renter_memory_bytes", float64(ms.Requested), label("state", "requested"))
+		mw.header("renter_priority_memory_bytes", "gauge", "Priority memory of the renter's memory manager.")
+		mw.sample("renter_priority_memory_bytes", float64(ms.PriorityAvailable), label("state", "available"))
+		mw.sample("renter_priority_memory_bytes", float64(ms.PriorityBase), label("state", "base"))
+		mw.sample("renter_priority_memory_bytes", float64(ms.PriorityRequested), label("state", "requested"))
+		mw.sample("renter_priority_memory_bytes", float64(ms.PriorityReserve), label("state", "reserve"))
+	}
+}
+
+// writeHostMetrics writes the metrics of the host and its contract manager.
+func (api *API) writeHostMetrics(mw *metricsWriter) {
+	fm := api.host.FinancialMetrics()
+	mw.gauge("host_contracts", "Number of contracts of the host.", float64(fm.ContractCount))
+	mw.header("host_revenue_hastings", "counter", "Revenue the host earned.")
+	mw.sample("host_revenue_hastings", currencyToFloat(fm.ContractCompensation), label("type", "contract"))
+	mw.sample("host_revenue_hastings", currencyToFloat(fm.StorageRevenue), label("type", "storage"))
+	mw.sample("host_revenue_hastings", currencyToFloat(fm.DownloadBandwidthRevenue), label("type", "download"))
+	mw.sample("host_revenue_hastings", currencyToFloat(fm.UploadBandwidthRevenue), label("type", "upload"))
+	mw.header("host_potential_revenue_hastings", "gauge", "Revenue the host expects to earn from its active contracts.")
+	mw.sample("host_potential_revenue_hastings", currencyToFloat(fm.PotentialContractCompensation), label("type", "contract"))
+	mw.sample("host_potential_revenue_hastings", currencyToFloat(fm.PotentialStorageRevenue), label("type", "storage"))
+	mw.sample("host_potential_revenue_hastings", currencyToFloat(fm.PotentialDownloadBandwidthRevenue), label("type", "download"))
+	mw.sample("host_potential_revenue_hastings", currencyToFloat(fm.PotentialUploadBandwidthRevenue), label("type", "upload"))
+	mw.header("host_collateral_hastings", "gauge", "Collateral of the host.")
+	mw.sample("host_collateral_hastings", currencyToFloat(fm.LockedStorageCollateral), label("state", "locked"))
+	mw.sample("host_collateral_hastings", currencyToFloat(fm.RiskedStorageCollateral), label("state", "risked"))
+	mw.sample("host_collateral_hastings", currencyToFloat(fm.LostStorageCollateral), label("state", "lost"))
+
+	// Count the storage obligations by their status.
+	obligations := make(map[string]int)
+	for _, so := range api.host.StorageObligations() {
+		obligations[so.ObligationStatus]++
+	}
+	statuses := make([]string, 0, len(obligations))
+	for status := range obligations {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	mw.header("host_storage_obligations", "gauge", "Number of storage obligations of the host by status.")
+	for _, status := range statuses {
+		mw.sample("host_storage_obligations", float64(obligations[status]), label("status", status))
+	}
+
+	// Write the usage of the storage folders.
+	folders := api.host.StorageFolders()
+	mw.header("host_storage_folder_capacity_bytes", "gauge", "Capacity of a storage folder of the contract manager.")
+	for _, sf := range folders {
+		mw.sample("host_storage_folder_capacity_bytes", float64(sf.Capacity), label("path", sf.Path))
+	}
+	mw.header("host_storage_folder_remaining_bytes", "gauge", "Remaining capacity of a storage folder of the contract manager.")
+	for _, sf := range folders {
+		mw.sample("host_storage_folder_remaining_bytes", float64(sf.CapacityRemaining), label("path", sf.Path))
+	}
+	mw.header("host_storage_folder_failures", "counter", "Number of failed operations of a storage folder of the contract manager.")
+	for _, sf := range folders {
+		mw.sample("host_storage_folder_failures", float64(sf.FailedReads), label("path", sf.Path), label("operation", "read"))
+		mw.sample("host_storage_folder_failures", float64(sf.FailedWrites), label("path", sf.Path), label("operation", "write"))
+	}
+}
+
+// writePoolMetrics writes the metrics of the mining pool.
+func (api *API) writePoolMetrics(mw *metricsWriter) {
+	mw.gauge("pool_hashrate", "Estimated hashrate of the mining pool in hashes per second.", api.pool.Hashrate())
+	mw.gauge("pool_connections", "Number of open client connections of the mining pool.", float64(api.pool.NumConnections()))
+	mw.counter("pool_connections_opened", "Number of client connections the mining pool opened since startup.", float64(api.pool.NumConnectionsOpened()))
+}
+
+// metricsHandler handles the API call that exports the metrics of the loaded
+// modules in the Prometheus text exposition format.
+func (api *API) metricsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	if !api.spdConfig.MetricsEnabled() {
+		WriteError(w, Error{"metrics are disabled, enable them using /daemon/settings"}, http.StatusNotFound)
+		return
+	}
+	var mw metricsWriter
+	if api.cs != nil {
+		api.writeConsensusMetrics(&mw)
+	}
+	if api.gateway != nil {
+		api.writeGatewayMetrics(&mw)
+	}
+	if api.tpool != nil {
+		api.writeTpoolMetrics(&mw)
+	}
+	if api.wallet != nil {
+		api.writeWalletMetrics(&mw)
+	}
+	if api.renter != nil {
+		api.writeRenterMetrics(&mw)
+	}
+	if api.host != nil {
+		api.writeHostMetrics(&mw)
+	}
+	if api.pool != nil {
+		api.writePoolMetrics(&mw)
+	}
+	w.Header().Set("Content-Type", metricsContentType)
+	w.Write(mw.buf.Bytes())
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"math"
+	"testing"
+)
+
+// TestMetricsWriter checks the output of the metricsWriter.
+func TestMetricsWriter(t *testing.T) {
+	var mw metricsWriter
+	mw.gauge("consensus_height", "Height of the current block.", 42)
+	mw.header("gateway_peers", "gauge", "Number of connected peers.")
+	mw.sample("gateway_peers", 1, label("direction", "inbound"))
+	mw.sample("gateway_peers", 2.5, label("direction", "outbound"), label("path", "a\"b\\c\nd"))
+	mw.counter("pool_connections_opened", "Number of connections.", math.Inf(1))
+
+	expected := `# HELP spd_consensus_height Height of the current block.
+# TYPE spd_consensus_height gauge
+spd_consensus_height 42
+# HELP spd_gateway_peers Number of connected peers.
+# TYPE spd_gateway_peers gauge
+spd_gateway_peers{direction="inbound"} 1
+spd_gateway_peers{direction="outbound",path="a\"b\\c\nd"} 2.5
+# HELP spd_pool_connections_opened Number of connections.
+# TYPE spd_pool_connections_opened counter
+spd_pool_connections_opened +Inf
+`
+	if mw.buf.String() != expected {
+		t.Fatalf("unexpected output:\n%v", mw.buf.String())
+	}
+}
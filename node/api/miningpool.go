@@ -77,7 +77,7 @@ type (
 func (api *API) poolHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	pg := MiningPoolGET{
 		BlocksMined:  0,
-		PoolHashrate: 0,
+		PoolHashrate: int(api.pool.Hashrate()),
 	}
 	WriteJSON(w, pg)
 }
@@ -134,6 +134,14 @@ type (
 		BadContract bool `json:"badcontract"`
 	}
 
+	// RenterContractsImportPOST contains the ids of the contracts imported by
+	// /renter/contracts/import and the errors of the contracts which couldn't
+	// be imported.
+	RenterContractsImportPOST struct {
+		ContractIDs []types.FileContractID        `json:"contractids"`
+		Errors      []modules.ContractImportError `json:"errors"`
+	}
+
 	// RenterContracts contains the renter's contracts.
 	RenterContracts struct {
 		// Compatibility Fields
@@ -182,6 +190,11 @@ type (
 		FilesAdded []string `json:"filesadded"`
 	}
 
+	// RenterMigrations lists the renter's file migrations.
+	RenterMigrations struct {
+		Migrations []modules.FileMigration `json:"migrations"`
+	}
+
 	// RenterPricesGET lists the data that is returned when a GET call is made
 	// to /renter/prices.
 	RenterPricesGET struct {
@@ -215,6 +228,19 @@ type (
 		UnsyncedHosts []types.SiaPublicKey   `json:"unsyncedhosts"`
 	}
 
+	// RenterBackupsScheduleGET contains the renter's backup schedule and the
+	// scheduled backups which are currently retained.
+	RenterBackupsScheduleGET struct {
+		Schedule modules.BackupSchedule    `json:"schedule"`
+		Backups  []modules.ScheduledBackup `json:"backups"`
+	}
+
+	// RenterStreamTokensGET contains the renter's stream tokens which are
+	// currently valid.
+	RenterStreamTokensGET struct {
+		Tokens []modules.StreamToken `json:"tokens"`
+	}
+
 	// RenterUploadReadyGet lists the upload ready status of the renter
 	RenterUploadReadyGet struct {
 		// Ready indicates whether of not the renter is ready to successfully
@@ -313,6 +339,22 @@ func trimDownloadInfo(dis ...modules.DownloadInfo) (_ []modules.DownloadInfo, er
 	return dis, nil
 }
 
+// trimMigrations is a helper method to trim the /home/siafiles prefix from the
+// siapaths of the migrations. Migrations of files outside of /home/siafiles
+// are omitted.
+func trimMigrations(fms ...modules.FileMigration) []modules.FileMigration {
+	trimmed := make([]modules.FileMigration, 0, len(fms))
+	for _, fm := range fms {
+		siaPath, err := fm.SiaPath.Rebase(modules.UserFolder, modules.RootSiaPath())
+		if err != nil {
+			continue
+		}
+		fm.SiaPath = siaPath
+		trimmed = append(trimmed, fm)
+	}
+	return trimmed
+}
+
 // renterBackupsHandlerGET handles the API calls to /renter/backups.
 func (api *API) renterBackupsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	backups, syncedHosts, err := api.renter.UploadedBackups()
@@ -414,18 +456,6 @@ func (api *API) renterBackupsRestoreHandlerGET(w http.ResponseWriter, req *http.
 		WriteError(w, Error{"name not specified"}, http.StatusBadRequest)
 		return
 	}
-	// Write the backup to a temporary file and delete it after loading.
-	tmpDir, err := ioutil.TempDir("", "sia-backup")
-	if err != nil {
-		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
-		return
-	}
-	defer os.RemoveAll(tmpDir)
-	backupPath := filepath.Join(tmpDir, name)
-	if err := api.renter.DownloadBackup(backupPath, name); err != nil {
-		WriteError(w, Error{"failed to download backup: " + err.Error()}, http.StatusBadRequest)
-		return
-	}
 	// Get the wallet seed.
 	ws, _, err := api.wallet.PrimarySeed()
 	if err != nil {
@@ -438,9 +468,62 @@ func (api *API) renterBackupsRestoreHandlerGET(w http.ResponseWriter, req *http.
 	// Derive the secret and wipe it afterwards.
 	secret := crypto.HashAll(rs, modules.BackupKeySpecifier)
 	defer fastrand.Read(secret[:])
-	// Load the backup.
-	if err := api.renter.LoadBackup(backupPath, secret[:32]); err != nil {
-		WriteError(w, Error{"failed to load backup: " + err.Error()}, http.StatusBadRequest)
+	// Download and load the backup together with the backups it depends on.
+	if err := api.renter.RestoreBackup(name, secret[:32]); err != nil {
+		WriteError(w, Error{"failed to restore backup: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterBackupsScheduleHandlerGET handles the API calls to
+// /renter/backups/schedule
+func (api *API) renterBackupsScheduleHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	schedule, backups := api.renter.BackupSchedule()
+	if backups == nil {
+		backups = []modules.ScheduledBackup{}
+	}
+	WriteJSON(w, RenterBackupsScheduleGET{
+		Schedule: schedule,
+		Backups:  backups,
+	})
+}
+
+// renterBackupsScheduleHandlerPOST handles the API calls to
+// /renter/backups/schedule
+func (api *API) renterBackupsScheduleHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Start with the current schedule and only change the provided fields.
+	schedule, _ := api.renter.BackupSchedule()
+	if i := req.FormValue("interval"); i != "" {
+		interval, err := time.ParseDuration(i)
+		if err != nil {
+			WriteError(w, Error{"unable to parse interval: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		schedule.Interval = interval
+	}
+	for _, field := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"fullinterval", &schedule.FullInterval},
+		{"retainhourly", &schedule.RetainHourly},
+		{"retaindaily", &schedule.RetainDaily},
+		{"retainweekly", &schedule.RetainWeekly},
+	} {
+		v := req.FormValue(field.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			WriteError(w, Error{fmt.Sprintf("unable to parse %v: %v", field.name, err)}, http.StatusBadRequest)
+			return
+		}
+		*field.value = n
+	}
+	if err := api.renter.SetBackupSchedule(schedule); err != nil {
+		WriteError(w, Error{"failed to set backup schedule: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
 	WriteSuccess(w)
@@ -942,6 +1025,112 @@ func (api *API) renterContractCancelHandler(w http.ResponseWriter, req *http.Req
 	WriteSuccess(w)
 }
 
+// renterContractsExportHandlerPOST handles the API call to
+// /renter/contracts/export.
+func (api *API) renterContractsExportHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	dst := req.FormValue("destination")
+	if dst == "" {
+		WriteError(w, Error{"destination not specified"}, http.StatusBadRequest)
+		return
+	}
+	if !filepath.IsAbs(dst) {
+		WriteError(w, Error{"destination must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	password := req.FormValue("password")
+	if password == "" {
+		WriteError(w, Error{"password not specified"}, http.StatusBadRequest)
+		return
+	}
+	var ids []types.FileContractID
+	for _, idStr := range strings.Split(req.FormValue("ids"), ",") {
+		if idStr == "" {
+			continue
+		}
+		var fcid types.FileContractID
+		if err := fcid.LoadString(idStr); err != nil {
+			WriteError(w, Error{"unable to parse id: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, fcid)
+	}
+	if len(ids) == 0 {
+		WriteError(w, Error{"ids not specified"}, http.StatusBadRequest)
+		return
+	}
+	var cancel bool
+	if c := req.FormValue("cancel"); c != "" {
+		var err error
+		cancel, err = strconv.ParseBool(c)
+		if err != nil {
+			WriteError(w, Error{"unable to parse cancel: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Derive the secret and wipe it afterwards.
+	secret := crypto.HashAll(password, modules.ContractBundleKeySpecifier)
+	defer fastrand.Read(secret[:])
+	if err := api.renter.ExportContracts(dst, ids, secret[:]); err != nil {
+		WriteError(w, Error{"failed to export contracts: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Stop using the exported contracts for uploads and renewals if they are
+	// being moved to another renter.
+	if cancel {
+		for _, fcid := range ids {
+			if err := api.renter.CancelContract(fcid); err != nil {
+				WriteError(w, Error{"unable to cancel exported contract: " + err.Error()}, http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	WriteSuccess(w)
+}
+
+// renterContractsImportHandlerPOST handles the API call to
+// /renter/contracts/import.
+func (api *API) renterContractsImportHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	src := req.FormValue("source")
+	if src == "" {
+		WriteError(w, Error{"source not specified"}, http.StatusBadRequest)
+		return
+	}
+	if !filepath.IsAbs(src) {
+		WriteError(w, Error{"source must be an absolute path"}, http.StatusBadRequest)
+		return
+	}
+	password := req.FormValue("password")
+	if password == "" {
+		WriteError(w, Error{"password not specified"}, http.StatusBadRequest)
+		return
+	}
+	// Derive the secret and wipe it afterwards.
+	secret := crypto.HashAll(password, modules.ContractBundleKeySpecifier)
+	defer fastrand.Read(secret[:])
+	contracts, failed, err := api.renter.ImportContracts(src, secret[:])
+	if err != nil {
+		WriteError(w, Error{"failed to import contracts: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// The import failed if none of the bundle's contracts were imported.
+	if len(contracts) == 0 && len(failed) > 0 {
+		errs := make([]string, 0, len(failed))
+		for _, f := range failed {
+			errs = append(errs, fmt.Sprintf("%v: %v", f.ID, f.Error))
+		}
+		WriteError(w, Error{"failed to import contracts: " + strings.Join(errs, "; ")}, http.StatusBadRequest)
+		return
+	}
+	ids := make([]types.FileContractID, 0, len(contracts))
+	for _, c := range contracts {
+		ids = append(ids, c.ID)
+	}
+	WriteJSON(w, RenterContractsImportPOST{
+		ContractIDs: ids,
+		Errors:      failed,
+	})
+}
+
 // renterContractsHandler handles the API call to request the Renter's
 // contracts. Active and renewed contracts are returned by default
 //
@@ -1187,6 +1376,17 @@ func (api *API) renterContractorChurnStatus(w http.ResponseWriter, _ *http.Reque
 	WriteJSON(w, api.renter.ContractorChurnStatus())
 }
 
+// renterForecastHandler handles the API call to request the spending forecast
+// of the current period from the renter's contractor.
+func (api *API) renterForecastHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	forecast, err := api.renter.SpendingForecast()
+	if err != nil {
+		WriteError(w, Error{"unable to get the spending forecast: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, forecast)
+}
+
 // renterDownloadsHandler handles the API call to request the download queue.
 func (api *API) renterDownloadsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	var downloads []DownloadInfo
@@ -1383,6 +1583,81 @@ func (api *API) renterRenameHandler(w http.ResponseWriter, req *http.Request, ps
 	WriteSuccess(w)
 }
 
+// renterMigrateHandler handles the API call to migrate a file to a new erasure
+// code or cipher, or to cancel the migration of a file.
+func (api *API) renterMigrateHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siaPath, err := modules.NewSiaPath(ps.ByName("siapath"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	root, err := isCalledWithRootFlag(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if !root {
+		siaPath, err = rebaseInputSiaPath(siaPath)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Cancel the migration if requested.
+	if cancelStr := req.FormValue("cancel"); cancelStr != "" {
+		cancel, err := strconv.ParseBool(cancelStr)
+		if err != nil {
+			WriteError(w, Error{"unable to parse 'cancel' arg: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		if cancel {
+			if err := api.renter.CancelMigration(siaPath); err != nil {
+				WriteError(w, Error{"failed to cancel migration: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+			WriteSuccess(w)
+			return
+		}
+	}
+
+	// Parse the new erasure code and cipher type. Both are optional.
+	ec, err := parseErasureCodingParameters(req.FormValue("datapieces"), req.FormValue("paritypieces"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse erasure code settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	ct := crypto.TypeInvalid
+	if ctStr := req.FormValue("ciphertype"); ctStr != "" {
+		if err := ct.FromString(ctStr); err != nil {
+			WriteError(w, Error{"unable to parse 'ciphertype' arg: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := api.renter.MigrateFile(siaPath, ec, ct); err != nil {
+		WriteError(w, Error{"failed to start migration: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterMigrationsHandler handles the API call to request the status of the
+// renter's file migrations.
+func (api *API) renterMigrationsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	root, err := isCalledWithRootFlag(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	migrations := api.renter.Migrations()
+	if !root {
+		migrations = trimMigrations(migrations...)
+	}
+	WriteJSON(w, RenterMigrations{
+		Migrations: migrations,
+	})
+}
+
 // renterFileHandlerGET handles GET requests to the /renter/file/:siapath API endpoint.
 func (api *API) renterFileHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	// Determine the siapath that the user wants to get the file from.
@@ -1621,7 +1896,12 @@ func (api *API) renterCancelDownloadHandler(w http.ResponseWriter, req *http.Req
 	delete(api.downloads, id)
 	api.downloadMu.Unlock()
 	if !ok {
-		WriteError(w, Error{"download for id not found"}, http.StatusBadRequest)
+		// Downloads which were resumed by the renter are not part of the map.
+		if err := api.renter.CancelDownload(id); err != nil {
+			WriteError(w, Error{"download for id not found"}, http.StatusBadRequest)
+			return
+		}
+		WriteSuccess(w)
 		return
 	}
 	// Cancel download and delete it from the map.
@@ -1629,6 +1909,17 @@ func (api *API) renterCancelDownloadHandler(w http.ResponseWriter, req *http.Req
 	WriteSuccess(w)
 }
 
+// renterResumeDownloadHandler handles the API call to resume a failed
+// download.
+func (api *API) renterResumeDownloadHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	id := modules.DownloadID(ps.ByName("id"))
+	if err := api.renter.ResumeDownload(id); err != nil {
+		WriteError(w, Error{"unable to resume download: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterDownloadHandler handles the API call to download a file.
 func (api *API) renterDownloadHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	params, err := parseDownloadParameters(w, req, ps)
@@ -1774,6 +2065,11 @@ func (api *API) renterStreamHandler(w http.ResponseWriter, req *http.Request, ps
 		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
 		return
 	}
+	if !api.managedStreamAuthorized(req, siaPath) {
+		w.Header().Set("WWW-Authenticate", "Basic realm=\"SiaAPI\"")
+		WriteError(w, Error{"API authentication failed."}, http.StatusUnauthorized)
+		return
+	}
 	disablelocalfetchparam := req.FormValue("disablelocalfetch")
 	var disableLocalFetch bool
 	if disablelocalfetchparam != "" {
@@ -1784,14 +2080,98 @@ func (api *API) renterStreamHandler(w http.ResponseWriter, req *http.Request, ps
 			return
 		}
 	}
-	fileName, streamer, err := api.renter.Streamer(siaPath, disableLocalFetch)
+	info, streamer, err := api.renter.Streamer(siaPath, disableLocalFetch)
 	if err != nil {
 		WriteError(w, Error{fmt.Sprintf("failed to create download streamer: %v", err)},
 			http.StatusInternalServerError)
 		return
 	}
 	defer streamer.Close()
-	http.ServeContent(w, req, fileName, time.Time{}, streamer)
+	// Set the ETag before serving the content. ServeContent takes care of
+	// range requests, conditional requests and the Content-Type.
+	w.Header().Set("ETag", streamETag(info))
+	http.ServeContent(w, req, info.Name, info.ModTime, streamer)
+}
+
+// managedStreamAuthorized checks whether a request is allowed to stream the
+// file at siaPath. Either the API password or a stream token which grants
+// access to the file is required. The token can be provided as the 'token'
+// query parameter or as a bearer token.
+func (api *API) managedStreamAuthorized(req *http.Request, siaPath modules.SiaPath) bool {
+	if api.requiredPassword == "" {
+		return true
+	}
+	if _, pass, ok := req.BasicAuth(); ok && pass == api.requiredPassword {
+		return true
+	}
+	token := req.FormValue("token")
+	if auth := req.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return token != "" && api.renter.ValidateStreamToken(token, siaPath) == nil
+}
+
+// renterStreamTokensHandlerGET handles the API call to list the renter's
+// stream tokens.
+func (api *API) renterStreamTokensHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	tokens := api.renter.StreamTokens()
+	for i := range tokens {
+		var err error
+		tokens[i].SiaPath, err = tokens[i].SiaPath.Rebase(modules.UserFolder, modules.RootSiaPath())
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusInternalServerError)
+			return
+		}
+	}
+	WriteJSON(w, RenterStreamTokensGET{Tokens: tokens})
+}
+
+// renterStreamTokensHandlerPOST handles the API call to create a stream token.
+func (api *API) renterStreamTokensHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	siaPath, err := modules.NewSiaPath(req.FormValue("siapath"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	siaPath, err = rebaseInputSiaPath(siaPath)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	validity, err := time.ParseDuration(req.FormValue("validity"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse validity: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	token, err := api.renter.CreateStreamToken(siaPath, validity)
+	if err != nil {
+		WriteError(w, Error{"failed to create stream token: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	token.SiaPath, err = token.SiaPath.Rebase(modules.UserFolder, modules.RootSiaPath())
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteJSON(w, token)
+}
+
+// renterStreamTokensRevokeHandlerPOST handles the API call to revoke a stream
+// token.
+func (api *API) renterStreamTokensRevokeHandlerPOST(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	if err := api.renter.RevokeStreamToken(ps.ByName("id")); err != nil {
+		WriteError(w, Error{"failed to revoke stream token: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// streamETag returns the ETag of a streamed file. The content of a file can
+// only change together with its modtime which means the ETag changes whenever
+// the content does.
+func streamETag(info modules.StreamInfo) string {
+	h := crypto.HashAll(info.UID, info.ModTime.UnixNano())
+	return fmt.Sprintf("\"%x\"", h[:16])
 }
 
 // renterUploadHandler handles the API call to upload a file.
@@ -1819,6 +2199,21 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 		WriteError(w, Error{"unable to parse erasure code settings: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
+	// Parse the compression.
+	var compression modules.CompressionType
+	if err := compression.FromString(req.FormValue("compression")); err != nil {
+		WriteError(w, Error{"unable to parse 'compression' parameter: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Check whether the file should be deduplicated.
+	deduplicate := false
+	if d := req.FormValue("deduplicate"); d != "" {
+		deduplicate, err = strconv.ParseBool(d)
+		if err != nil {
+			WriteError(w, Error{"unable to parse 'deduplicate' parameter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
 
 	// Call the renter to upload the file.
 	siaPath, err := modules.NewSiaPath(ps.ByName("siapath"))
@@ -1837,6 +2232,8 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 		ErasureCode:         ec,
 		Force:               force,
 		DisablePartialChunk: true, // TODO: remove this
+		Compression:         compression,
+		Deduplicate:         deduplicate,
 
 		// NOTE: can make this an optional param.
 		CipherType: crypto.TypeDefaultRenter,
@@ -1848,6 +2245,83 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 	WriteSuccess(w)
 }
 
+// renterUploadPackedHandler handles the API call to upload many small files
+// by packing their data into shared chunks.
+func (api *API) renterUploadPackedHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	// Get the source paths.
+	if err := req.ParseForm(); err != nil {
+		WriteError(w, Error{"unable to parse form: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	sources := req.Form["source"]
+	if len(sources) == 0 {
+		WriteError(w, Error{"at least one source has to be specified"}, http.StatusBadRequest)
+		return
+	}
+	for _, source := range sources {
+		// Sources must be absolute paths.
+		if !filepath.IsAbs(source) {
+			WriteError(w, Error{"source must be an absolute path"}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Check whether existing files should be overwritten
+	var err error
+	force := false
+	if f := req.FormValue("force"); f != "" {
+		force, err = strconv.ParseBool(f)
+		if err != nil {
+			WriteError(w, Error{"unable to parse 'force' parameter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	// Parse the erasure coder.
+	ec, err := parseErasureCodingParameters(req.FormValue("datapieces"), req.FormValue("paritypieces"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse erasure code settings: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	// Parse the cipher type.
+	ct := crypto.TypeDefaultRenter
+	if ctStr := req.FormValue("ciphertype"); ctStr != "" {
+		if err := ct.FromString(ctStr); err != nil {
+			WriteError(w, Error{"unable to parse 'ciphertype' arg: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Call the renter to upload the files.
+	siaPath, err := modules.NewSiaPath(ps.ByName("siapath"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	root, err := isCalledWithRootFlag(req)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if !root {
+		siaPath, err = rebaseInputSiaPath(siaPath)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	err = api.renter.UploadPacked(modules.PackedUploadParams{
+		Sources:     sources,
+		SiaPath:     siaPath,
+		ErasureCode: ec,
+		Force:       force,
+		CipherType:  ct,
+	})
+	if err != nil {
+		WriteError(w, Error{"packed upload failed: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterUploadReadyHandler handles the API call to check whether or not the
 // renter is ready to upload files
 func (api *API) renterUploadReadyHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
@@ -1950,6 +2424,29 @@ func (api *API) renterUploadStreamHandler(w http.ResponseWriter, req *http.Reque
 		WriteError(w, Error{"can't provide erasure code settings when doing a repair"}, http.StatusBadRequest)
 		return
 	}
+	// Parse the compression.
+	var compression modules.CompressionType
+	if err := compression.FromString(queryForm.Get("compression")); err != nil {
+		WriteError(w, Error{"unable to parse 'compression' parameter: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if repair && compression != modules.CompressionNone {
+		WriteError(w, Error{"can't provide compression settings when doing a repair"}, http.StatusBadRequest)
+		return
+	}
+	// Check whether the file should be deduplicated.
+	deduplicate := false
+	if d := queryForm.Get("deduplicate"); d != "" {
+		deduplicate, err = strconv.ParseBool(d)
+		if err != nil {
+			WriteError(w, Error{"unable to parse 'deduplicate' parameter: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if repair && deduplicate {
+		WriteError(w, Error{"can't enable deduplication when doing a repair"}, http.StatusBadRequest)
+		return
+	}
 
 	// Call the renter to upload the file.
 	siaPath, err := modules.NewSiaPath(ps.ByName("siapath"))
@@ -1967,6 +2464,8 @@ func (api *API) renterUploadStreamHandler(w http.ResponseWriter, req *http.Reque
 		ErasureCode: ec,
 		Force:       force,
 		Repair:      repair,
+		Compression: compression,
+		Deduplicate: deduplicate,
 
 		// NOTE: can make this an optional param.
 		CipherType: crypto.TypeDefaultRenter,
@@ -2134,12 +2633,75 @@ func (api *API) renterDirHandlerPOST(w http.ResponseWriter, req *http.Request, p
 		WriteSuccess(w)
 		return
 	}
+	if action == "policy" {
+		policy, err := parseDirectoryPolicy(req)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		err = api.renter.SetDirPolicy(siaPath, policy)
+		if err != nil {
+			WriteError(w, Error{"failed to set directory policy: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		WriteSuccess(w)
+		return
+	}
 
 	// Report that no calls were made
 	WriteError(w, Error{"no calls were made, please check your submission and try again"}, http.StatusInternalServerError)
 	return
 }
 
+// parseDirectoryPolicy parses the directory policy of a request to the
+// /renter/dir endpoint.
+func parseDirectoryPolicy(req *http.Request) (modules.DirectoryPolicy, error) {
+	var policy modules.DirectoryPolicy
+	if req.FormValue("datapieces") != "" || req.FormValue("paritypieces") != "" {
+		ec, err := parseErasureCodingParameters(req.FormValue("datapieces"), req.FormValue("paritypieces"))
+		if err != nil {
+			return modules.DirectoryPolicy{}, err
+		}
+		if ec != nil {
+			policy.DataPieces = ec.MinPieces()
+			policy.ParityPieces = ec.NumPieces() - ec.MinPieces()
+		}
+	}
+	if rt := req.FormValue("repairthreshold"); rt != "" {
+		threshold, err := strconv.ParseFloat(rt, 64)
+		if err != nil {
+			return modules.DirectoryPolicy{}, errors.AddContext(err, "unable to parse 'repairthreshold'")
+		}
+		policy.RepairThreshold = threshold
+	}
+	var err error
+	policy.PinnedHosts, err = parseHostList(req.FormValue("pinnedhosts"))
+	if err != nil {
+		return modules.DirectoryPolicy{}, errors.AddContext(err, "unable to parse 'pinnedhosts'")
+	}
+	policy.ExcludedHosts, err = parseHostList(req.FormValue("excludedhosts"))
+	if err != nil {
+		return modules.DirectoryPolicy{}, errors.AddContext(err, "unable to parse 'excludedhosts'")
+	}
+	return policy, policy.Validate()
+}
+
+// parseHostList parses a comma separated list of host public keys.
+func parseHostList(str string) ([]types.SiaPublicKey, error) {
+	if str == "" {
+		return nil, nil
+	}
+	var pks []types.SiaPublicKey
+	for _, s := range strings.Split(str, ",") {
+		var pk types.SiaPublicKey
+		if err := pk.LoadString(strings.TrimSpace(s)); err != nil {
+			return nil, err
+		}
+		pks = append(pks, pk)
+	}
+	return pks, nil
+}
+
 // renterContractStatusHandler  handles the API call to check the status of a
 // contract monitored by the renter.
 func (api *API) renterContractStatusHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
@@ -43,6 +43,9 @@ func (api *API) buildHTTPRoutes() {
 	router.POST("/daemon/update", api.daemonUpdateHandlerPOST)
 	router.GET("/daemon/version", api.daemonVersionHandler)
 
+	// Metrics API Calls
+	router.GET("/metrics", RequirePassword(api.metricsHandler, requiredPassword))
+
 	// Consensus API Calls
 	if api.cs != nil {
 		router.GET("/consensus", api.consensusHandler)
@@ -50,13 +53,17 @@ func (api *API) buildHTTPRoutes() {
 		router.GET("/consensus/subscribe/:id", api.consensusSubscribeHandler)
 		router.POST("/consensus/validate/transactionset", api.consensusValidateTransactionsetHandler)
 		router.GET("/consensus/blocks/:height", api.consensusBlocksHandlerSanasol)
+		router.POST("/consensus/snapshot", RequirePassword(api.consensusSnapshotHandler, requiredPassword))
+		router.GET("/events", RequirePassword(api.eventsHandler, requiredPassword))
 	}
 
 	// Explorer API Calls
 	if api.explorer != nil {
 		router.GET("/explorer", api.explorerHandler)
+		router.GET("/explorer/addresses/:addr", api.explorerAddressesHandler)
 		router.GET("/explorer/blocks/:height", api.explorerBlocksHandler)
 		router.GET("/explorer/hashes/:hash", api.explorerHashHandler)
+		router.GET("/explorer/stats", api.explorerStatsHandler)
 	}
 
 	// Gateway API Calls
@@ -122,9 +129,14 @@ func (api *API) buildHTTPRoutes() {
 		router.GET("/renter/backups", RequirePassword(api.renterBackupsHandlerGET, requiredPassword))
 		router.POST("/renter/backups/create", RequirePassword(api.renterBackupsCreateHandlerPOST, requiredPassword))
 		router.POST("/renter/backups/restore", RequirePassword(api.renterBackupsRestoreHandlerGET, requiredPassword))
+		router.GET("/renter/backups/schedule", RequirePassword(api.renterBackupsScheduleHandlerGET, requiredPassword))
+		router.POST("/renter/backups/schedule", RequirePassword(api.renterBackupsScheduleHandlerPOST, requiredPassword))
 		router.POST("/renter/contract/cancel", RequirePassword(api.renterContractCancelHandler, requiredPassword))
 		router.GET("/renter/contracts", api.renterContractsHandler)
+		router.POST("/renter/contracts/export", RequirePassword(api.renterContractsExportHandlerPOST, requiredPassword))
+		router.POST("/renter/contracts/import", RequirePassword(api.renterContractsImportHandlerPOST, requiredPassword))
 		router.GET("/renter/contractorchurnstatus", api.renterContractorChurnStatus)
+		router.GET("/renter/forecast", api.renterForecastHandler)
 
 		router.GET("/renter/downloadinfo/*uid", api.renterDownloadByUIDHandlerGET)
 		router.GET("/renter/downloads", api.renterDownloadsHandler)
@@ -142,13 +154,20 @@ func (api *API) buildHTTPRoutes() {
 		router.POST("/renter/delete/*siapath", RequirePassword(api.renterDeleteHandler, requiredPassword))
 		router.GET("/renter/download/*siapath", RequirePassword(api.renterDownloadHandler, requiredPassword))
 		router.POST("/renter/download/cancel", RequirePassword(api.renterCancelDownloadHandler, requiredPassword))
+		router.POST("/renter/download/resume/:id", RequirePassword(api.renterResumeDownloadHandler, requiredPassword))
 		router.GET("/renter/downloadasync/*siapath", RequirePassword(api.renterDownloadAsyncHandler, requiredPassword))
+		router.POST("/renter/migrate/*siapath", RequirePassword(api.renterMigrateHandler, requiredPassword))
+		router.GET("/renter/migrations", api.renterMigrationsHandler)
 		router.POST("/renter/rename/*siapath", RequirePassword(api.renterRenameHandler, requiredPassword))
 		router.GET("/renter/stream/*siapath", api.renterStreamHandler)
+		router.GET("/renter/streamtokens", RequirePassword(api.renterStreamTokensHandlerGET, requiredPassword))
+		router.POST("/renter/streamtokens", RequirePassword(api.renterStreamTokensHandlerPOST, requiredPassword))
+		router.POST("/renter/streamtokens/revoke/:id", RequirePassword(api.renterStreamTokensRevokeHandlerPOST, requiredPassword))
 		router.POST("/renter/upload/*siapath", RequirePassword(api.renterUploadHandler, requiredPassword))
 		router.GET("/renter/uploadready", api.renterUploadReadyHandler)
 		router.POST("/renter/uploads/pause", RequirePassword(api.renterUploadsPauseHandler, requiredPassword))
 		router.POST("/renter/uploads/resume", RequirePassword(api.renterUploadsResumeHandler, requiredPassword))
+		router.POST("/renter/uploadpacked/*siapath", RequirePassword(api.renterUploadPackedHandler, requiredPassword))
 		router.POST("/renter/uploadstream/*siapath", RequirePassword(api.renterUploadStreamHandler, requiredPassword))
 		router.POST("/renter/validatesiapath/*siapath", RequirePassword(api.renterValidateSiaPathHandler, requiredPassword))
 		router.GET("/renter/workers", api.renterWorkersHandler)
@@ -164,6 +183,10 @@ func (api *API) buildHTTPRoutes() {
 		router.GET("/hostdb/hosts/:pubkey", api.hostdbHostsHandler)
 		router.GET("/hostdb/filtermode", api.hostdbFilterModeHandlerGET)
 		router.POST("/hostdb/filtermode", RequirePassword(api.hostdbFilterModeHandlerPOST, requiredPassword))
+		router.GET("/hostdb/policy", api.hostdbPolicyHandlerGET)
+		router.POST("/hostdb/policy", RequirePassword(api.hostdbPolicyHandlerPOST, requiredPassword))
+		router.GET("/hostdb/weights", api.hostdbWeightsHandlerGET)
+		router.POST("/hostdb/weights", RequirePassword(api.hostdbWeightsHandlerPOST, requiredPassword))
 
 		// Renter watchdog endpoints.
 		router.GET("/renter/contractstatus", api.renterContractStatusHandler)
@@ -220,15 +243,32 @@ func (api *API) buildHTTPRoutes() {
 		router.POST("/wallet/sign", RequirePassword(api.walletSignHandler, requiredPassword))
 		router.GET("/wallet/watch", RequirePassword(api.walletWatchHandlerGET, requiredPassword))
 		router.POST("/wallet/watch", RequirePassword(api.walletWatchHandlerPOST, requiredPassword))
+		router.GET("/wallet/webhooks", RequirePassword(api.walletWebhooksHandlerGET, requiredPassword))
+		router.POST("/wallet/webhooks", RequirePassword(api.walletWebhooksHandlerPOST, requiredPassword))
+		router.POST("/wallet/webhooks/remove/:id", RequirePassword(api.walletWebhooksRemoveHandlerPOST, requiredPassword))
 	}
 
 	// Apply UserAgent middleware and return the Router
 	api.routerMu.Lock()
-	api.router = http.TimeoutHandler(RequireUserAgent(router, requiredUserAgent), httpServerTimeout, fmt.Sprintf("HTTP call exceeded the timeout of %v", httpServerTimeout))
+	api.router = withTimeout(RequireUserAgent(router, requiredUserAgent), httpServerTimeout)
 	api.routerMu.Unlock()
 	return
 }
 
+// withTimeout is middleware that applies a timeout to all requests except for
+// long-lived streams. The response of a request with a timeout is buffered,
+// which would prevent streams from being flushed.
+func withTimeout(h http.Handler, timeout time.Duration) http.Handler {
+	th := http.TimeoutHandler(h, timeout, fmt.Sprintf("HTTP call exceeded the timeout of %v", timeout))
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if isStream(req) {
+			h.ServeHTTP(w, req)
+			return
+		}
+		th.ServeHTTP(w, req)
+	})
+}
+
 // RequireUserAgent is middleware that requires all requests to set a
 // UserAgent that contains the specified string.
 func RequireUserAgent(h http.Handler, ua string) http.Handler {
@@ -260,7 +300,12 @@ func RequirePassword(h httprouter.Handle, password string) httprouter.Handle {
 	}
 }
 
+// isStream checks if a request is for a long-lived stream.
+func isStream(req *http.Request) bool {
+	return req.URL.Path == "/events"
+}
+
 // isUnrestricted checks if a request may bypass the useragent check.
 func isUnrestricted(req *http.Request) bool {
-	return strings.HasPrefix(req.URL.Path, "/renter/stream/")
+	return strings.HasPrefix(req.URL.Path, "/renter/stream/") || req.URL.Path == "/metrics"
 }
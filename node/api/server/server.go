@@ -212,6 +212,10 @@ func NewAsync(APIaddr string, requiredUserAgent string, requiredPassword string,
 		// Set the shutdown method to allow the api to shutdown the server.
 		api.Shutdown = srv.Close
 
+		// Terminate the long-lived streams when shutting down the server.
+		// Otherwise the server would wait for them to finish.
+		srv.apiServer.RegisterOnShutdown(api.CloseStreams)
+
 		// Spin up a goroutine that serves the API and closes srv.done when
 		// finished.
 		go func() {
@@ -169,6 +169,18 @@ type (
 	WalletWatchGET struct {
 		Addresses []types.UnlockHash `json:"addresses"`
 	}
+
+	// WalletWebhooksPOST contains the parameters of a new webhook.
+	WalletWebhooksPOST struct {
+		URL           string             `json:"url"`
+		Addresses     []types.UnlockHash `json:"addresses"`
+		Confirmations types.BlockHeight  `json:"confirmations"`
+	}
+
+	// WalletWebhooksGET contains the registered webhooks.
+	WalletWebhooksGET struct {
+		Webhooks []modules.Webhook `json:"webhooks"`
+	}
 )
 
 // encryptionKeys enumerates the possible encryption keys that can be derived
@@ -1016,3 +1028,37 @@ func (api *API) walletWatchHandlerPOST(w http.ResponseWriter, req *http.Request,
 	}
 	WriteSuccess(w)
 }
+
+// walletWebhooksHandlerGET handles GET calls to /wallet/webhooks.
+func (api *API) walletWebhooksHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, WalletWebhooksGET{
+		Webhooks: api.wallet.Webhooks(),
+	})
+}
+
+// walletWebhooksHandlerPOST handles POST calls to /wallet/webhooks.
+func (api *API) walletWebhooksHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var wwp WalletWebhooksPOST
+	err := json.NewDecoder(req.Body).Decode(&wwp)
+	if err != nil {
+		WriteError(w, Error{"invalid parameters: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	wh, err := api.wallet.AddWebhook(wwp.URL, wwp.Addresses, wwp.Confirmations)
+	if err != nil {
+		WriteError(w, Error{"failed to add webhook: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, wh)
+}
+
+// walletWebhooksRemoveHandlerPOST handles POST calls to
+// /wallet/webhooks/remove/:id.
+func (api *API) walletWebhooksRemoveHandlerPOST(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	err := api.wallet.RemoveWebhook(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{"failed to remove webhook: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -12,6 +12,7 @@ package node
 import (
 	"fmt"
 	"net"
+	"os"
 	"path/filepath"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 	"gitlab.com/scpcorp/ScPrime/modules/transactionpool"
 	"gitlab.com/scpcorp/ScPrime/modules/wallet"
 	"gitlab.com/scpcorp/ScPrime/persist"
+	"gitlab.com/scpcorp/ScPrime/types"
 )
 
 // NodeParams contains a bunch of parameters for creating a new test node. As
@@ -114,6 +116,23 @@ type NodeParams struct {
 	// Initialize node from existing seed.
 	PrimarySeed string
 
+	// ConsensusSnapshot is the path of a consensus snapshot which is imported
+	// if the node doesn't have a consensus database yet. The snapshot needs
+	// to match ConsensusSnapshotCommitment.
+	ConsensusSnapshot           string
+	ConsensusSnapshotCommitment crypto.Hash
+
+	// ConsensusPruneDepth enables the pruned mode of the consensus set, which
+	// only keeps the most recent ConsensusPruneDepth blocks. Pruning is
+	// disabled if it is 0.
+	ConsensusPruneDepth types.BlockHeight
+
+	// SOCKS5Proxy overrides the SOCKS5 proxy of the spd config for outbound
+	// gateway peer, host and Host API connections. AllowOnion additionally
+	// enables .onion addresses for peers and hosts.
+	SOCKS5Proxy string
+	AllowOnion  bool
+
 	// The following fields are used to skip parts of the node set up
 	SkipSetAllowance     bool
 	SkipHostDiscovery    bool
@@ -203,6 +222,29 @@ func printfRelease(format string, a ...interface{}) (int, error) {
 	return 0, nil
 }
 
+// importConsensusSnapshot imports the consensus snapshot at path into the
+// consensus directory. Nothing is imported if a consensus database already
+// exists.
+func importConsensusSnapshot(dir, path string, commitment crypto.Hash) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.AddContext(err, "unable to open consensus snapshot")
+	}
+	defer f.Close()
+	start := time.Now()
+	printfRelease("Importing consensus snapshot %v...", path)
+	snap, err := consensus.ImportSnapshot(dir, f, commitment)
+	if errors.Contains(err, consensus.ErrConsensusDatabaseExists) {
+		printlnRelease(" skipped, consensus database already exists.")
+		return nil
+	} else if err != nil {
+		printlnRelease(" failed.")
+		return err
+	}
+	printlnRelease(" imported height", snap.Height, "in", time.Since(start).Seconds(), "seconds.")
+	return nil
+}
+
 // Close will call close on every module within the node, combining and
 // returning the errors.
 func (n *Node) Close() (err error) {
@@ -268,6 +310,17 @@ func New(params NodeParams, loadStartTime time.Time) (*Node, <-chan error) {
 		return nil, errChan
 	}
 
+	// Override the proxy of the spd config before any module starts dialing.
+	if params.SOCKS5Proxy != "" {
+		if err := modules.GlobalProxy.SetProxy(params.SOCKS5Proxy, params.AllowOnion); err != nil {
+			errChan <- errors.AddContext(err, "unable to set SOCKS5 proxy")
+			return nil, errChan
+		}
+	} else if params.AllowOnion {
+		errChan <- errors.New("onion addresses require a SOCKS5 proxy")
+		return nil, errChan
+	}
+
 	// Gateway.
 	loadStart := time.Now()
 	g, err := func() (modules.Gateway, error) {
@@ -314,13 +367,32 @@ func New(params NodeParams, loadStartTime time.Time) (*Node, <-chan error) {
 		if !params.CreateConsensusSet {
 			return nil, c
 		}
+		if params.ConsensusSnapshot != "" {
+			err := importConsensusSnapshot(filepath.Join(dir, modules.ConsensusDir), params.ConsensusSnapshot, params.ConsensusSnapshotCommitment)
+			if err != nil {
+				c <- err
+				return nil, c
+			}
+		}
 		i++
 		printfRelease("(%d/%d) Loading consensus...", i, numModules)
 		consensusSetDeps := params.ConsensusSetDeps
 		if consensusSetDeps == nil {
 			consensusSetDeps = modules.ProdDependencies
 		}
-		return consensus.NewCustomConsensusSet(g, params.Bootstrap, filepath.Join(dir, modules.ConsensusDir), consensusSetDeps)
+		cs, errChan := consensus.NewCustomConsensusSet(g, params.Bootstrap, filepath.Join(dir, modules.ConsensusDir), consensusSetDeps)
+		if params.ConsensusPruneDepth == 0 {
+			return cs, errChan
+		}
+		if err := modules.PeekErr(errChan); err != nil {
+			c <- err
+			return nil, c
+		}
+		if err := cs.SetPruneDepth(params.ConsensusPruneDepth); err != nil {
+			c <- errors.Compose(err, cs.Close())
+			return nil, c
+		}
+		return cs, errChan
 	}()
 	if err := modules.PeekErr(errChanCS); err != nil {
 		errChan <- errors.Extend(err, errors.New("unable to create consensus set"))
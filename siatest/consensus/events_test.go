@@ -0,0 +1,223 @@
+package consensus
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/node"
+	"gitlab.com/scpcorp/ScPrime/node/api"
+	"gitlab.com/scpcorp/ScPrime/node/api/client"
+	"gitlab.com/scpcorp/ScPrime/siatest"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// eventReader reads the events of an EventStream in the background so they
+// can be awaited with a timeout. Events of different types may arrive in any
+// order, so events which aren't awaited yet are kept.
+type eventReader struct {
+	stream  *client.EventStream
+	events  chan api.Event
+	pending []api.Event
+}
+
+// newEventReader starts reading the events of the stream.
+func newEventReader(stream *client.EventStream) *eventReader {
+	er := &eventReader{
+		stream: stream,
+		events: make(chan api.Event, 100),
+	}
+	go func() {
+		defer close(er.events)
+		for {
+			e, err := stream.Next()
+			if err != nil {
+				return
+			}
+			er.events <- e
+		}
+	}()
+	return er
+}
+
+// await returns the next event of the given type and decodes its data into
+// val.
+func (er *eventReader) await(t *testing.T, typ string, val interface{}) api.Event {
+	t.Helper()
+	decode := func(e api.Event) api.Event {
+		if err := json.Unmarshal(e.Data, val); err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	for i, e := range er.pending {
+		if e.Type == typ {
+			er.pending = append(er.pending[:i], er.pending[i+1:]...)
+			return decode(e)
+		}
+	}
+	timeout := time.After(time.Minute)
+	for {
+		select {
+		case e, ok := <-er.events:
+			if !ok {
+				t.Fatal("stream was closed while waiting for", typ)
+			}
+			if e.Type != typ {
+				er.pending = append(er.pending, e)
+				continue
+			}
+			return decode(e)
+		case <-timeout:
+			t.Fatal("timeout while waiting for", typ)
+		}
+	}
+}
+
+// TestEvents tests the /events endpoint.
+func TestEvents(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	groupParams := siatest.GroupParams{
+		Miners: 1,
+	}
+	testDir := consensusTestDir(t.Name())
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal("Failed to create group: ", err)
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	testNode := tg.Miners()[0]
+
+	// Unknown event types are rejected.
+	if _, err := testNode.EventsGet(modules.ConsensusChangeRecent, "foo"); err == nil {
+		t.Fatal("expected unknown event type to be rejected")
+	}
+
+	stream, err := testNode.EventsGet(modules.ConsensusChangeRecent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	er := newEventReader(stream)
+
+	// Send coins to the wallet. The transaction should be added to the
+	// transaction pool.
+	wag, err := testNode.WalletAddressGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsp, err := testNode.WalletSiacoinsPost(types.SiacoinPrecision, wag.Address, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txid := wsp.TransactionIDs[len(wsp.TransactionIDs)-1]
+	var added api.EventTransactionSet
+	er.await(t, api.EventTypeTpoolAdd, &added)
+	if added.TransactionIDs[len(added.TransactionIDs)-1] != txid {
+		t.Fatal("wrong transaction set", added.TransactionIDs, txid)
+	}
+
+	// Mine a block. The transaction should be confirmed and removed from the
+	// transaction pool.
+	if err := testNode.MineBlock(); err != nil {
+		t.Fatal(err)
+	}
+	cg, err := testNode.ConsensusGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var block api.EventBlock
+	blockEvent := er.await(t, api.EventTypeBlock, &block)
+	if block.ID != cg.CurrentBlock || block.Height != cg.Height {
+		t.Fatal("wrong block", block, cg.CurrentBlock, cg.Height)
+	}
+	if blockEvent.ID == "" {
+		t.Fatal("block event has no id")
+	}
+	// The block contains other transactions of the wallet as well, e.g. the
+	// miner payouts.
+	for {
+		var pt modules.ProcessedTransaction
+		er.await(t, api.EventTypeWalletTransaction, &pt)
+		if pt.ConfirmationHeight != cg.Height {
+			t.Fatal("wrong confirmation height", pt.ConfirmationHeight, cg.Height)
+		}
+		if pt.TransactionID == txid {
+			break
+		}
+	}
+	var removed api.EventTransactionSet
+	er.await(t, api.EventTypeTpoolRemove, &removed)
+	if removed.ID != added.ID {
+		t.Fatal("wrong transaction set removed", removed.ID, added.ID)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mine more blocks and resume the stream from the last block event. The
+	// missed blocks should be streamed.
+	for i := 0; i < 2; i++ {
+		if err := testNode.MineBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var since modules.ConsensusChangeID
+	if err := (*crypto.Hash)(&since).LoadString(blockEvent.ID); err != nil {
+		t.Fatal(err)
+	}
+	stream, err = testNode.EventsGet(since, api.EventTypeBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	er = newEventReader(stream)
+	for i := types.BlockHeight(1); i <= 2; i++ {
+		er.await(t, api.EventTypeBlock, &block)
+		if block.Height != cg.Height+i {
+			t.Fatal("wrong block height", block.Height, cg.Height+i)
+		}
+	}
+
+	// Create a node whose wallet was never unlocked. Its stream should stay
+	// open without wallet events.
+	walletParams := node.Wallet(filepath.Join(testDir, "locked"))
+	walletParams.SkipWalletInit = true
+	lockedNode, err := siatest.NewCleanNode(walletParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := lockedNode.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if err := lockedNode.GatewayConnectPost(testNode.GatewayAddress()); err != nil {
+		t.Fatal(err)
+	}
+	lockedStream, err := lockedNode.EventsGet(modules.ConsensusChangeRecent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lockedStream.Close()
+	er = newEventReader(lockedStream)
+	for i := 0; i < 2; i++ {
+		if err := testNode.MineBlock(); err != nil {
+			t.Fatal(err)
+		}
+		er.await(t, api.EventTypeBlock, &block)
+	}
+	for _, e := range er.pending {
+		if e.Type == api.EventTypeError || e.Type == api.EventTypeWalletTransaction {
+			t.Fatal("unexpected event", e.Type, string(e.Data))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package consensus
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/scpcorp/ScPrime/node"
+	"gitlab.com/scpcorp/ScPrime/siatest"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestConsensusSnapshot checks that a node can be bootstrapped from a
+// consensus snapshot exported by another node.
+func TestConsensusSnapshot(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	testDir := consensusTestDir(t.Name())
+	groupParams := siatest.GroupParams{
+		Miners: 1,
+	}
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal("Failed to create group: ", err)
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	miner := tg.Miners()[0]
+
+	// Relative destinations are rejected.
+	if _, err := miner.ConsensusSnapshotPost("consensus.snapshot"); err == nil {
+		t.Fatal("expected relative destination to be rejected")
+	}
+
+	// Export a snapshot.
+	snap, err := miner.ConsensusSnapshotPost(filepath.Join(testDir, "consensus.snapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cg, err := miner.ConsensusGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Height != cg.Height || snap.BlockID != cg.CurrentBlock {
+		t.Fatal("wrong snapshot", snap, cg.Height, cg.CurrentBlock)
+	}
+
+	// Mine a few more blocks and bootstrap a new node from the snapshot. It
+	// should sync the remaining blocks from the miner.
+	for i := 0; i < 3; i++ {
+		if err := miner.MineBlock(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	np := node.Wallet(filepath.Join(testDir, "bootstrapped"))
+	np.ConsensusSnapshot = filepath.Join(testDir, "consensus.snapshot")
+	np.ConsensusSnapshotCommitment = snap.Commitment
+	nodes, err := tg.AddNodes(np)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bootstrapped := nodes[0]
+	if _, err := bootstrapped.ConsensusBlocksHeightGet(snap.Height); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bootstrapped.ConsensusBlocksHeightGet(types.BlockHeight(1)); err == nil {
+		t.Fatal("block before the snapshot shouldn't be available")
+	}
+
+	// The bootstrapped node was funded by the miner.
+	wg, err := bootstrapped.WalletGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wg.ConfirmedSiacoinBalance.IsZero() {
+		t.Fatal("bootstrapped node wasn't funded")
+	}
+}
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"encoding/hex"
+	"strings"
 	"testing"
 	"time"
 
@@ -374,3 +375,55 @@ func TestDaemonStack(t *testing.T) {
 		t.Fatal("Stack is empt")
 	}
 }
+
+// TestDaemonMetrics tests enabling and scraping the /metrics endpoint.
+func TestDaemonMetrics(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	testDir := daemonTestDir(t.Name())
+
+	// Create a new server
+	testNode, err := siatest.NewCleanNode(node.Gateway(testDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testNode.Close()
+
+	// The metrics are disabled by default.
+	if _, err := testNode.MetricsGet(); err == nil {
+		t.Fatal("metrics should be disabled by default")
+	}
+
+	// Enable them.
+	if err := testNode.DaemonMetricsPost(true); err != nil {
+		t.Fatal(err)
+	}
+	dsg, err := testNode.DaemonSettingsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dsg.EnableMetrics {
+		t.Fatal("metrics should be enabled")
+	}
+
+	// Scrape the metrics without setting the user agent.
+	c := testNode.Client
+	c.UserAgent = ""
+	metrics, err := c.MetricsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(metrics, "# TYPE spd_gateway_peers gauge") {
+		t.Fatal("gateway metrics are missing", metrics)
+	}
+	if strings.Contains(metrics, "spd_wallet") {
+		t.Fatal("metrics of modules which aren't loaded shouldn't be exported", metrics)
+	}
+
+	// Scraping the metrics still requires the password.
+	c.Password = "wrong"
+	if _, err := c.MetricsGet(); err == nil {
+		t.Fatal("scraping the metrics should require the API password")
+	}
+}
@@ -201,6 +201,12 @@ func NewDependencyDisruptUploadStream(numChunks int) *DependencyInterruptAfterNC
 	return newDependencyInterruptAfterNCalls("DisruptUploadStream", numChunks)
 }
 
+// NewDependencyInterruptDownloadChunk creates a new dependency that fails a
+// chunk of a download after numChunks chunks were recovered.
+func NewDependencyInterruptDownloadChunk(numChunks int) *DependencyInterruptAfterNCalls {
+	return newDependencyInterruptAfterNCalls("InterruptDownloadChunk", numChunks)
+}
+
 // NewDependencyDisableCommitPaymentIntent creates a new dependency that
 // prevents the contractor for committing a payment intent, this essentially
 // ensures the renter's revision is not in sync with the host's revision.
@@ -597,3 +597,62 @@ func TestRemoteBackup(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// TestBackupSchedule tests configuring scheduled backups with the
+// /renter/backups/schedule endpoint.
+func TestBackupSchedule(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a renter.
+	testDir := renterTestDir(t.Name())
+	r, err := siatest.NewCleanNode(node.Renter(testDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Scheduled backups are disabled by default.
+	rbsg, err := r.RenterBackupScheduleGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rbsg.Schedule != modules.DefaultBackupSchedule {
+		t.Fatal("expected default schedule", rbsg.Schedule)
+	}
+	if len(rbsg.Backups) != 0 {
+		t.Fatal("expected no scheduled backups", rbsg.Backups)
+	}
+
+	// Intervals below the minimum are rejected.
+	schedule := modules.BackupSchedule{
+		Interval:     modules.MinBackupInterval / 2,
+		FullInterval: 10,
+		RetainDaily:  3,
+	}
+	if err := r.RenterBackupSchedulePost(schedule); err == nil {
+		t.Fatal("expected setting a short interval to fail")
+	}
+	schedule.Interval = time.Hour
+	if err := r.RenterBackupSchedulePost(schedule); err != nil {
+		t.Fatal(err)
+	}
+
+	// The schedule should be persisted.
+	if err := r.RestartNode(); err != nil {
+		t.Fatal(err)
+	}
+	rbsg, err = r.RenterBackupScheduleGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rbsg.Schedule != schedule {
+		t.Fatal("schedule wasn't persisted", rbsg.Schedule)
+	}
+}
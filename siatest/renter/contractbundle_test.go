@@ -0,0 +1,140 @@
+package renter
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/scpcorp/ScPrime/node"
+	"gitlab.com/scpcorp/ScPrime/siatest"
+	"gitlab.com/scpcorp/ScPrime/types"
+)
+
+// TestExportImportContracts tests moving a contract and the siafiles using it
+// from one renter to another with the /renter/contracts/export and
+// /renter/contracts/import endpoints.
+func TestExportImportContracts(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a testgroup.
+	groupParams := siatest.GroupParams{
+		Hosts:   2,
+		Miners:  1,
+		Renters: 1,
+	}
+	testDir := renterTestDir(t.Name())
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal("Failed to create group: ", err)
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Upload a file which can be recovered from a single host.
+	r := tg.Renters()[0]
+	_, rf, err := r.UploadNewFileBlocking(100, 1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := r.RenterContractsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rc.ActiveContracts) != len(tg.Hosts()) {
+		t.Fatalf("expected %v active contracts but got %v", len(tg.Hosts()), len(rc.ActiveContracts))
+	}
+	exported := rc.ActiveContracts[0]
+
+	// Add a second renter without an allowance.
+	rt := node.RenterTemplate
+	rt.SkipSetAllowance = true
+	nodes, err := tg.AddNodes(rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := nodes[0]
+
+	// Exporting without a password or without contracts should fail.
+	bundlePath := filepath.Join(r.Dir, "contracts.bundle")
+	if err := r.RenterContractsExportPost(bundlePath, []types.FileContractID{exported.ID}, "", false); err == nil {
+		t.Fatal("export without password should fail")
+	}
+	if err := r.RenterContractsExportPost(bundlePath, nil, "foo", false); err == nil {
+		t.Fatal("export without contracts should fail")
+	}
+	// Export the first contract and cancel it.
+	if err := r.RenterContractsExportPost(bundlePath, []types.FileContractID{exported.ID}, "foo", true); err != nil {
+		t.Fatal(err)
+	}
+	rc, err = r.RenterContractsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range rc.ActiveContracts {
+		if c.ID == exported.ID {
+			t.Fatal("exported contract should have been canceled")
+		}
+	}
+
+	// Importing with the wrong password should fail.
+	if _, err := r2.RenterContractsImportPost(bundlePath, "bar"); err == nil {
+		t.Fatal("import with wrong password should fail")
+	}
+	rcip, err := r2.RenterContractsImportPost(bundlePath, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rcip.ContractIDs) != 1 || rcip.ContractIDs[0] != exported.ID {
+		t.Fatal("unexpected imported contracts", rcip.ContractIDs)
+	}
+	// The second renter should know about the contract and the file.
+	rc2, err := r2.RenterAllContractsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, c := range append(rc2.ActiveContracts, rc2.PassiveContracts...) {
+		if c.ID == exported.ID {
+			found = c.HostPublicKey.String() == exported.HostPublicKey.String() && c.Size == exported.Size
+		}
+	}
+	if !found {
+		t.Fatal("imported contract not found or doesn't match")
+	}
+	if _, err := r2.File(rf); err != nil {
+		t.Fatal("siafile wasn't imported", err)
+	}
+	// The file should be downloadable using the imported contract.
+	if _, _, err := r2.DownloadByStream(rf); err != nil {
+		t.Fatal(err)
+	}
+	// Importing the bundle a second time shouldn't import the contract again.
+	if _, err := r2.RenterContractsImportPost(bundlePath, "foo"); err == nil {
+		t.Fatal("importing the same contract twice should fail")
+	}
+
+	// Export the already imported contract together with the other contract.
+	// Only the other contract is imported and the error of the first one is
+	// reported.
+	other := rc.ActiveContracts[0]
+	bundlePath = filepath.Join(r.Dir, "contracts2.bundle")
+	ids := []types.FileContractID{exported.ID, other.ID}
+	if err := r.RenterContractsExportPost(bundlePath, ids, "foo", false); err != nil {
+		t.Fatal(err)
+	}
+	rcip, err = r2.RenterContractsImportPost(bundlePath, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rcip.ContractIDs) != 1 || rcip.ContractIDs[0] != other.ID {
+		t.Fatal("unexpected imported contracts", rcip.ContractIDs)
+	}
+	if len(rcip.Errors) != 1 || rcip.Errors[0].ID != exported.ID || rcip.Errors[0].Error == "" {
+		t.Fatal("unexpected import errors", rcip.Errors)
+	}
+}
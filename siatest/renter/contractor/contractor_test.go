@@ -2830,3 +2830,120 @@ func TestLargeRenewWindow(t *testing.T) {
 		t.Errorf("Expected NextPeriod to be %v but was %v", originalNextPeriod+allowance.Period, rg.NextPeriod)
 	}
 }
+
+// TestSpendingForecast checks the spending forecast of the renter and that an
+// alert is registered if the forecast exceeds the allowance.
+func TestSpendingForecast(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a group for testing.
+	groupParams := siatest.GroupParams{
+		Hosts:   2,
+		Renters: 1,
+		Miners:  1,
+	}
+	tg, err := siatest.NewGroupFromTemplate(contractorTestDir(t.Name()), groupParams)
+	if err != nil {
+		t.Fatal(errors.AddContext(err, "failed to create group"))
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	r := tg.Renters()[0]
+	m := tg.Miners()[0]
+
+	// Upload a file and mine a block to have some spending to extrapolate.
+	if _, _, err := r.UploadNewFileBlocking(int(modules.SectorSize), 1, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.MineBlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The forecast should contain both hosts and project at least the spent
+	// money.
+	forecast, err := r.RenterForecastGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forecast.Hosts) != groupParams.Hosts {
+		t.Fatalf("expected %v hosts but got %v", groupParams.Hosts, len(forecast.Hosts))
+	}
+	if forecast.Spent.IsZero() || forecast.Projected.Cmp(forecast.Spent) < 0 {
+		t.Fatal("invalid forecast", forecast.Spent, forecast.Projected)
+	}
+	if forecast.Hosts[0].Projected.Cmp(forecast.Hosts[1].Projected) < 0 {
+		t.Fatal("hosts aren't sorted by their projected spending")
+	}
+	if forecast.ExceedsAllowance {
+		t.Fatal("forecast shouldn't exceed the allowance")
+	}
+
+	// hasAlert checks whether the forecast alert is registered.
+	hasAlert := func() (bool, error) {
+		dag, err := r.DaemonAlertsGet()
+		if err != nil {
+			return false, err
+		}
+		for _, alert := range dag.Alerts {
+			if alert.Msg == contractor.AlertMSGSpendingForecast {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	// Lower the allowance funds below the spent money. The alert should be
+	// registered with the next block.
+	rg, err := r.RenterGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowance := rg.Settings.Allowance
+	lowAllowance := allowance
+	lowAllowance.Funds = types.NewCurrency64(1)
+	if err := r.RenterPostAllowance(lowAllowance); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if err := m.MineBlock(); err != nil {
+			return err
+		}
+		if registered, err := hasAlert(); err != nil || !registered {
+			return errors.Compose(err, errors.New("forecast alert isn't registered"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast, err = r.RenterForecastGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !forecast.ExceedsAllowance {
+		t.Fatal("forecast should exceed the allowance")
+	}
+
+	// Restore the allowance. The alert should be unregistered again.
+	if err := r.RenterPostAllowance(allowance); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		if err := m.MineBlock(); err != nil {
+			return err
+		}
+		if registered, err := hasAlert(); err != nil || registered {
+			return errors.Compose(err, errors.New("forecast alert is still registered"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
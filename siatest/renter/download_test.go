@@ -0,0 +1,128 @@
+package renter
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/scpcorp/ScPrime/build"
+	"gitlab.com/scpcorp/ScPrime/crypto"
+	"gitlab.com/scpcorp/ScPrime/modules"
+	"gitlab.com/scpcorp/ScPrime/node"
+	"gitlab.com/scpcorp/ScPrime/node/api"
+	"gitlab.com/scpcorp/ScPrime/siatest"
+	"gitlab.com/scpcorp/ScPrime/siatest/dependencies"
+)
+
+// TestResumeDownload tests that a failed download is persisted across restarts
+// and can be resumed with the /renter/download/resume endpoint.
+func TestResumeDownload(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a testgroup.
+	groupParams := siatest.GroupParams{
+		Hosts:  2,
+		Miners: 1,
+	}
+	testDir := renterTestDir(t.Name())
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal("Failed to create group: ", err)
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// Add a renter which fails the second chunk of a download.
+	dep := dependencies.NewDependencyInterruptDownloadChunk(1)
+	renterParams := node.Renter(filepath.Join(testDir, "renter"))
+	renterParams.RenterDeps = dep
+	nodes, err := tg.AddNodes(renterParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := nodes[0]
+
+	// Upload a file with multiple chunks.
+	chunkSize := siatest.ChunkSize(1, crypto.TypeDefaultRenter)
+	lf, rf, err := r.UploadNewFileBlocking(int(3*chunkSize), 1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := lf.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waitForDownload is a helper that waits for the download to complete.
+	waitForDownload := func(id modules.DownloadID) (di api.DownloadInfo) {
+		err := build.Retry(100, 100*time.Millisecond, func() (err error) {
+			di, err = r.RenterDownloadInfoGet(id)
+			if err != nil {
+				return err
+			}
+			if !di.Completed {
+				return errors.New("download not completed yet")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	// Download the file. The download should fail.
+	dep.Fail()
+	dst := filepath.Join(r.Dir, "download")
+	id, err := r.RenterDownloadGet(rf.SiaPath(), dst, 0, 0, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if di := waitForDownload(id); di.Error == "" {
+		t.Fatal("download should have failed", di)
+	}
+	// Resuming a download that doesn't exist should fail.
+	if err := r.RenterResumeDownloadPost("foo"); err == nil {
+		t.Fatal("resuming an unknown download should fail")
+	}
+
+	// The failed download should still be known after a restart.
+	if err := r.RestartNode(); err != nil {
+		t.Fatal(err)
+	}
+	di, err := r.RenterDownloadInfoGet(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !di.Completed || di.Error == "" || di.Destination != dst {
+		t.Fatal("failed download wasn't persisted", di)
+	}
+
+	// Resume the download. It should succeed this time.
+	if err := r.RenterResumeDownloadPost(id); err != nil {
+		t.Fatal(err)
+	}
+	if di := waitForDownload(id); di.Error != "" {
+		t.Fatal("resumed download failed", di.Error)
+	}
+	downloaded, err := ioutil.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded data doesn't match uploaded data")
+	}
+	// The download can't be resumed anymore.
+	if err := r.RenterResumeDownloadPost(id); err == nil {
+		t.Fatal("resuming a completed download should fail")
+	}
+}
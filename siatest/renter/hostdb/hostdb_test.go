@@ -983,3 +983,233 @@ func testFilterMode(tg *siatest.TestGroup, renter *siatest.TestNode, fm modules.
 
 	return nil
 }
+
+// TestHostPolicy tests setting and clearing a host policy through the API.
+func TestHostPolicy(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a group for testing
+	groupParams := siatest.GroupParams{
+		Hosts:   2,
+		Renters: 1,
+		Miners:  1,
+	}
+	testDir := hostdbTestDir(t.Name())
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal(errors.AddContext(err, "failed to create group"))
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	renter := tg.Renters()[0]
+	hostPK, err := tg.Hosts()[0].HostPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An invalid policy is rejected.
+	if err := renter.HostDbPolicyPost(modules.HostPolicy{BlockedNets: []string{"invalid"}}); err == nil {
+		t.Fatal("expected invalid policy to be rejected")
+	}
+
+	// Block all the local hosts.
+	hp := modules.HostPolicy{BlockedNets: []string{"127.0.0.0/8", "::1/128"}}
+	if err := renter.HostDbPolicyPost(hp); err != nil {
+		t.Fatal(err)
+	}
+	hdpg, err := renter.HostDbPolicyGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hdpg.Policy.BlockedNets) != 2 {
+		t.Fatal("policy wasn't set", hdpg.Policy)
+	}
+	hdag, err := renter.HostDbActiveGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hdag.Hosts) != 0 {
+		t.Fatal("expected all hosts to be filtered but got", len(hdag.Hosts))
+	}
+	hhg, err := renter.HostDbHostsGet(hostPK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hhg.Entry.Filtered {
+		t.Fatal("host should be filtered")
+	}
+
+	// Clear the policy again.
+	if err := renter.HostDbPolicyPost(modules.HostPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	hdag, err = renter.HostDbActiveGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hdag.Hosts) != len(tg.Hosts()) {
+		t.Fatalf("expected %v active hosts but got %v", len(tg.Hosts()), len(hdag.Hosts))
+	}
+}
+
+// TestScoringWeights tests setting the scoring weights and previewing the
+// score of a host under proposed weights through the API.
+func TestScoringWeights(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a group for testing
+	groupParams := siatest.GroupParams{
+		Hosts:   1,
+		Renters: 1,
+		Miners:  1,
+	}
+	testDir := hostdbTestDir(t.Name())
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal(errors.AddContext(err, "failed to create group"))
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	renter := tg.Renters()[0]
+	hostPK, err := tg.Hosts()[0].HostPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default weights are used initially.
+	hdwg, err := renter.HostDbWeightsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdwg.Weights.PriceWeight != 1 || hdwg.Weights.UptimeWeight != 1 || !hdwg.Weights.MaxStoragePrice.IsZero() {
+		t.Fatal("expected default weights", hdwg.Weights)
+	}
+
+	// Without proposed weights there is no proposed breakdown.
+	hhg, err := renter.HostDbHostsGet(hostPK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hhg.ProposedScoreBreakdown != nil {
+		t.Fatal("didn't expect a proposed breakdown")
+	}
+
+	// Propose a configuration which ignores the price.
+	weights := modules.DefaultHostScoringWeights
+	weights.PriceWeight = 0
+	weights.UptimeWeight = 2
+	hhg, err = renter.HostDbHostsWithWeightsGet(hostPK, weights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hhg.ProposedScoreBreakdown == nil {
+		t.Fatal("expected a proposed breakdown")
+	}
+	if hhg.ProposedScoreBreakdown.PriceAdjustment != 1 {
+		t.Fatal("price shouldn't affect the proposed score", hhg.ProposedScoreBreakdown.PriceAdjustment)
+	}
+
+	// Invalid weights are rejected.
+	invalid := weights
+	invalid.AgeWeight = -1
+	if err := renter.HostDbWeightsPost(invalid); err == nil {
+		t.Fatal("expected invalid weights to be rejected")
+	}
+
+	// Set the weights and restart the renter to check that they are
+	// persisted.
+	if err := renter.HostDbWeightsPost(weights); err != nil {
+		t.Fatal(err)
+	}
+	if err := renter.RestartNode(); err != nil {
+		t.Fatal(err)
+	}
+	hdwg, err = renter.HostDbWeightsGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdwg.Weights.PriceWeight != weights.PriceWeight || hdwg.Weights.UptimeWeight != weights.UptimeWeight {
+		t.Fatal("weights weren't persisted", hdwg.Weights)
+	}
+	hhg, err = renter.HostDbHostsGet(hostPK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hhg.ScoreBreakdown.PriceAdjustment != 1 {
+		t.Fatal("weights weren't applied", hhg.ScoreBreakdown.PriceAdjustment)
+	}
+}
+
+// TestHostBenchmarks checks that the hostdb measures the latency of hosts
+// during scans and their throughput during uploads and downloads.
+func TestHostBenchmarks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Create a group for testing
+	groupParams := siatest.GroupParams{
+		Hosts:   2,
+		Renters: 1,
+		Miners:  1,
+	}
+	testDir := hostdbTestDir(t.Name())
+	tg, err := siatest.NewGroupFromTemplate(testDir, groupParams)
+	if err != nil {
+		t.Fatal(errors.AddContext(err, "failed to create group"))
+	}
+	defer func() {
+		if err := tg.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	renter := tg.Renters()[0]
+
+	// Upload and download a file.
+	_, rf, err := renter.UploadNewFileBlocking(int(modules.SectorSize), 1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := renter.DownloadByStream(rf); err != nil {
+		t.Fatal(err)
+	}
+
+	// All hosts should have been scanned and used for the upload. The
+	// download needs at least one of them.
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		hdag, err := renter.HostDbAllGet()
+		if err != nil {
+			return err
+		}
+		var downloaded bool
+		for _, host := range hdag.Hosts {
+			if host.Latency <= 0 {
+				return fmt.Errorf("host %v has no latency", host.PublicKeyString)
+			}
+			if host.UploadThroughput == 0 {
+				return fmt.Errorf("host %v has no upload throughput", host.PublicKeyString)
+			}
+			downloaded = downloaded || host.DownloadThroughput > 0
+		}
+		if !downloaded {
+			return errors.New("no host has a download throughput")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"gitlab.com/scpcorp/ScPrime/modules/host/contractmanager"
 	"gitlab.com/scpcorp/ScPrime/modules/renter"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/contractor"
+	"gitlab.com/scpcorp/ScPrime/modules/renter/filesystem/siafile"
 	"gitlab.com/scpcorp/ScPrime/modules/renter/proto"
 	"gitlab.com/scpcorp/ScPrime/node"
 	"gitlab.com/scpcorp/ScPrime/node/api"
@@ -279,6 +280,8 @@ func TestRenterFour(t *testing.T) {
 		{Name: "TestPauseAndResumeRepairAndUploads", Test: testPauseAndResumeRepairAndUploads},
 		{Name: "TestDownloadServedFromDisk", Test: testDownloadServedFromDisk},
 		{Name: "TestDirMode", Test: testDirMode},
+		{Name: "TestMigrateFile", Test: testMigrateFile},
+		{Name: "TestUploadPacked", Test: testUploadPacked},
 		{Name: "TestEscapeSiaPath", Test: testEscapeSiaPath}, // Runs last because it uploads many files
 	}
 
@@ -4575,6 +4578,174 @@ func testDownloadServedFromDisk(t *testing.T, tg *siatest.TestGroup) {
 	}
 }
 
+// testMigrateFile tests migrating a file to a new cipher.
+func testMigrateFile(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+
+	// Upload a file.
+	dataPieces := uint64(1)
+	parityPieces := uint64(len(tg.Hosts())) - dataPieces
+	fileSize := int(modules.SectorSize) + siatest.Fuzz()
+	lf, rf, err := r.UploadNewFileBlocking(fileSize, dataPieces, parityPieces, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Upload another file with a name that resembles a temporary migration
+	// file. It shouldn't be touched by the migration.
+	otherLF, err := r.FilesDir().NewFile(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSiaPath, err := modules.NewSiaPath(rf.SiaPath().String() + ".migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRF, err := r.Upload(otherLF, otherSiaPath, dataPieces, parityPieces, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Migrate the file and wait for the migration to finish.
+	if err := r.RenterMigratePost(rf.SiaPath(), nil, crypto.TypeXChaCha20); err != nil {
+		t.Fatal(err)
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		rm, err := r.RenterMigrationsGet()
+		if err != nil {
+			return err
+		}
+		for _, m := range rm.Migrations {
+			if !m.SiaPath.Equals(rf.SiaPath()) {
+				continue
+			}
+			if m.Error != "" {
+				t.Fatal("migration failed", m.Error)
+			}
+			if !m.Completed {
+				return errors.New("migration not completed yet")
+			}
+			if m.CipherType != crypto.TypeXChaCha20.String() || m.Migrated != uint64(fileSize) {
+				t.Fatal("unexpected migration status", m)
+			}
+			return nil
+		}
+		return errors.New("migration not found")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The temporary file shouldn't exist anymore and the migrated file should
+	// still contain the same data.
+	rd, err := r.RenterDirGet(modules.RootSiaPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range rd.Files {
+		if strings.Contains(fi.SiaPath.String(), ".migrate-") {
+			t.Fatal("temporary file wasn't removed", fi.SiaPath)
+		}
+	}
+	if _, err := r.File(otherRF); err != nil {
+		t.Fatal("unrelated file was removed by the migration", err)
+	}
+	if err := r.WaitForUploadHealth(rf); err != nil {
+		t.Fatal(err)
+	}
+	_, data, err := r.DownloadByStream(rf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := lf.Equal(data); err != nil {
+		t.Fatal(err)
+	}
+
+	// Canceling a finished migration fails.
+	if err := r.RenterMigrateCancelPost(rf.SiaPath()); err == nil {
+		t.Fatal("canceling a finished migration should fail")
+	}
+}
+
+// testUploadPacked tests uploading many small files by packing them into a
+// shared pack.
+func testUploadPacked(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+
+	// Create a few small files.
+	var lfs []*siatest.LocalFile
+	var sources []string
+	for i := 0; i < 5; i++ {
+		lf, err := r.FilesDir().NewFile(100 + fastrand.Intn(1000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lfs = append(lfs, lf)
+		sources = append(sources, lf.Path())
+	}
+
+	// Upload them packed.
+	dirSiaPath := modules.RandomSiaPath()
+	ec, err := siafile.NewRSSubCode(1, len(tg.Hosts())-1, crypto.SegmentSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RenterUploadPackedPost(dirSiaPath, sources, ec, false); err != nil {
+		t.Fatal(err)
+	}
+	// Uploading them again without force fails.
+	if err := r.RenterUploadPackedPost(dirSiaPath, sources, ec, false); err == nil {
+		t.Fatal("uploading existing files without force should fail")
+	}
+
+	// The packed files should be downloadable and streamable.
+	for _, lf := range lfs {
+		siaPath, err := dirSiaPath.Join(lf.FileName())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rf, err := r.RenterFileGet(siaPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rf.File.Packed || !rf.File.Available || rf.File.Filesize != uint64(lf.Size()) {
+			t.Fatal("unexpected file info", rf.File)
+		}
+		_, data, err := r.RenterDownloadHTTPResponseGet(siaPath, 0, rf.File.Filesize, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := lf.Equal(data); err != nil {
+			t.Fatal(err)
+		}
+		data, err = r.RenterStreamGet(siaPath, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := lf.Equal(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deleting all the packed files deletes the pack.
+	for _, lf := range lfs {
+		siaPath, err := dirSiaPath.Join(lf.FileName())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.RenterFileDeletePost(siaPath); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rd, err := r.RenterDirRootGet(modules.PackFolder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rd.Files) != 0 {
+		t.Fatal("pack wasn't deleted", len(rd.Files))
+	}
+}
+
 // testDirMode is a subtest that makes sure that various ways of creating a dir
 // all set the correct permissions.
 func testDirMode(t *testing.T, tg *siatest.TestGroup) {
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
 	"path/filepath"
 	"testing"
 	"time"
@@ -15,6 +18,7 @@ import (
 	"gitlab.com/scpcorp/ScPrime/crypto"
 	"gitlab.com/scpcorp/ScPrime/modules"
 	"gitlab.com/scpcorp/ScPrime/node"
+	"gitlab.com/scpcorp/ScPrime/node/api/client"
 	"gitlab.com/scpcorp/ScPrime/siatest"
 	"gitlab.com/scpcorp/ScPrime/siatest/dependencies"
 )
@@ -183,6 +187,8 @@ func TestRenterStream(t *testing.T) {
 		{Name: "TestStreamRepair", Test: testStreamRepair},
 		{Name: "TestUploadStreaming", Test: testUploadStreaming},
 		{Name: "TestUploadStreamingWithBadDeps", Test: testUploadStreamingWithBadDeps},
+		{Name: "TestStreamConditionalRequests", Test: testStreamConditionalRequests},
+		{Name: "TestStreamTokens", Test: testStreamTokens},
 	}
 
 	// Run tests
@@ -386,3 +392,211 @@ func testUploadStreamingWithBadDeps(t *testing.T, tg *siatest.TestGroup) {
 		t.Fatal("dependency injection should have caused the upload to fail")
 	}
 }
+
+// testStreamConditionalRequests tests that /renter/stream supports ETags,
+// conditional requests and multi-range requests.
+func testStreamConditionalRequests(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+
+	// Upload a file.
+	lf, rf, err := r.UploadNewFileBlocking(1000, 1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := lf.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stream is a helper which streams the file with the given headers.
+	stream := func(headers map[string]string) (*http.Response, []byte) {
+		req, err := r.NewRequest("GET", fmt.Sprintf("/renter/stream/%s", rf.SiaPath().String()), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+
+	// A regular request returns the whole file together with an ETag and the
+	// modtime.
+	resp, body := stream(nil)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatal("unexpected response", resp.StatusCode)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag missing")
+	}
+	lastModified := resp.Header.Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("Last-Modified missing")
+	}
+	if resp.Header.Get("Content-Type") == "" {
+		t.Fatal("Content-Type missing")
+	}
+	// The ETag is stable.
+	if resp, _ := stream(nil); resp.Header.Get("ETag") != etag {
+		t.Fatal("ETag changed", resp.Header.Get("ETag"), etag)
+	}
+
+	// Conditional requests for an unchanged file return 304.
+	resp, _ = stream(map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatal("expected 304 but got", resp.StatusCode)
+	}
+	resp, _ = stream(map[string]string{"If-Modified-Since": lastModified})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatal("expected 304 but got", resp.StatusCode)
+	}
+	// A different ETag returns the file.
+	resp, body = stream(map[string]string{"If-None-Match": `"foo"`})
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatal("unexpected response", resp.StatusCode)
+	}
+
+	// A multi-range request returns a multipart response.
+	resp, body = stream(map[string]string{"Range": "bytes=0-9,500-599"})
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatal("expected 206 but got", resp.StatusCode)
+	}
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "multipart/byteranges" {
+		t.Fatal("unexpected media type", mediaType)
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for _, rng := range [][2]int{{0, 10}, {500, 600}} {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		partData, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(partData, data[rng[0]:rng[1]]) {
+			t.Fatal("range data doesn't match", rng)
+		}
+	}
+
+	// A range request with a matching If-Range returns the range.
+	resp, body = stream(map[string]string{"Range": "bytes=10-19", "If-Range": etag})
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, data[10:20]) {
+		t.Fatal("unexpected response", resp.StatusCode)
+	}
+}
+
+// testStreamTokens tests streaming files with stream tokens instead of the API
+// password.
+func testStreamTokens(t *testing.T, tg *siatest.TestGroup) {
+	r := tg.Renters()[0]
+
+	// Upload two files to different directories.
+	dir1, err := r.UploadNewDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir2, err := r.UploadNewDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lf, rf, err := r.UploadNewFileBlocking(1000, 1, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := lf.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp1, err := dir1.SiaPath().Join(rf.SiaPath().Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp2, err := dir2.SiaPath().Join(rf.SiaPath().Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sp := range []modules.SiaPath{sp1, sp2} {
+		if err := r.RenterUploadForcePost(lf.Path(), sp, 1, 1, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Create a client without the API password.
+	c := client.New(client.Options{
+		Address:   r.Address,
+		UserAgent: r.UserAgent,
+	})
+	if _, err := c.RenterStreamGet(sp1, false); err == nil {
+		t.Fatal("streaming without password or token should fail")
+	}
+
+	// Create a token for the first directory. It should only grant access to
+	// the files within that directory.
+	token, err := r.RenterStreamTokensPost(dir1.SiaPath(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !token.SiaPath.Equals(dir1.SiaPath()) {
+		t.Fatal("wrong siapath", token.SiaPath, dir1.SiaPath())
+	}
+	if _, err := r.RenterStreamTokensPost(dir1.SiaPath(), 0); err == nil {
+		t.Fatal("creating a token without validity should fail")
+	}
+	err = build.Retry(100, 100*time.Millisecond, func() error {
+		_, err := c.RenterStreamWithTokenGet(sp1, token.Token)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := c.RenterStreamWithTokenGet(sp1, token.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(streamed, data) {
+		t.Fatal("streamed data doesn't match uploaded data")
+	}
+	if _, err := c.RenterStreamWithTokenGet(sp2, token.Token); err == nil {
+		t.Fatal("token shouldn't grant access to other directories")
+	}
+	// The token can't be used for other endpoints.
+	if _, err := c.RenterStreamTokensGet(); err == nil {
+		t.Fatal("listing tokens without password should fail")
+	}
+
+	// The token should be listed.
+	rstg, err := r.RenterStreamTokensGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, tok := range rstg.Tokens {
+		found = found || tok.ID == token.ID
+	}
+	if !found {
+		t.Fatal("token wasn't listed", rstg.Tokens)
+	}
+
+	// Revoke the token. It can't be used anymore.
+	if err := r.RenterStreamTokensRevokePost(token.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RenterStreamWithTokenGet(sp1, token.Token); err == nil {
+		t.Fatal("revoked token shouldn't grant access")
+	}
+}
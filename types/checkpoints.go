@@ -0,0 +1,49 @@
+package types
+
+// checkpoints.go contains the IDs of blocks which are known to be part of the
+// canonical blockchain. The consensus set rejects any block which conflicts
+// with a checkpoint, which prevents peers from feeding a node an alternative
+// history that doesn't include the checkpointed blocks.
+//
+// For now the only checkpoint is the genesis block, which is added in
+// types/constants.go on every network. No later mainnet blocks are
+// checkpointed yet, so a peer can still feed a node a chain which forks after
+// the genesis block. Such a chain is only downloaded if it has more cumulative
+// work than the current chain, see modules/consensus/headersfirst.go.
+//
+// CONTRIBUTE: Mainnet checkpoints should be added with releases, for a block
+// that is buried deep enough to never be reorged, taken from a synced node.
+// They must never be removed or changed.
+
+import (
+	"gitlab.com/scpcorp/ScPrime/build"
+)
+
+// BlockCheckpoints maps block heights to the IDs of the blocks of the
+// canonical blockchain at these heights.
+type BlockCheckpoints map[BlockHeight]BlockID
+
+var (
+	// Checkpoints contains the checkpoints which are compiled into the
+	// binary. The genesis block is added as the first checkpoint once its id
+	// is calculated. The maps are empty until later checkpoints are added.
+	Checkpoints = build.Select(build.Var{
+		Standard: BlockCheckpoints{},
+		Dev:      BlockCheckpoints{},
+		Testing:  BlockCheckpoints{},
+	}).(BlockCheckpoints)
+)
+
+// Last returns the height of the highest checkpoint at or below the provided
+// height. The bool is false if there is no such checkpoint.
+func (bc BlockCheckpoints) Last(height BlockHeight) (BlockHeight, bool) {
+	var last BlockHeight
+	found := false
+	for h := range bc {
+		if h <= height && (!found || h > last) {
+			last = h
+			found = true
+		}
+	}
+	return last, found
+}
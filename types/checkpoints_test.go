@@ -0,0 +1,35 @@
+package types
+
+import (
+	"testing"
+)
+
+// TestBlockCheckpointsLast probes the Last method of BlockCheckpoints.
+func TestBlockCheckpointsLast(t *testing.T) {
+	bc := BlockCheckpoints{
+		10: BlockID{1},
+		20: BlockID{2},
+		30: BlockID{3},
+	}
+	tests := []struct {
+		height BlockHeight
+		last   BlockHeight
+		found  bool
+	}{
+		{0, 0, false},
+		{9, 0, false},
+		{10, 10, true},
+		{19, 10, true},
+		{20, 20, true},
+		{1000, 30, true},
+	}
+	for _, test := range tests {
+		last, found := bc.Last(test.height)
+		if last != test.last || found != test.found {
+			t.Errorf("Last(%v): expected (%v, %v), got (%v, %v)", test.height, test.last, test.found, last, found)
+		}
+	}
+	if _, found := (BlockCheckpoints{}).Last(1000); found {
+		t.Error("empty checkpoints shouldn't contain a checkpoint")
+	}
+}
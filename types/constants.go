@@ -969,4 +969,7 @@ func init() {
 	}
 	// Calculate the genesis ID.
 	GenesisID = GenesisBlock.ID()
+
+	// The genesis block is the first checkpoint.
+	Checkpoints[0] = GenesisID
 }